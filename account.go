@@ -0,0 +1,80 @@
+package betfair
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// Account Types
+type DeveloperApp struct {
+	AppName     string                `json:"appName"`
+	AppID       int64                 `json:"appId"`
+	AppVersions []DeveloperAppVersion `json:"appVersions"`
+}
+
+type DeveloperAppVersion struct {
+	Owner                string `json:"owner"`
+	VersionID            int64  `json:"versionId"`
+	Version              string `json:"version"`
+	ApplicationKey       string `json:"applicationKey"`
+	DelayData            bool   `json:"delayData"`
+	SubscriptionRequired bool   `json:"subscriptionRequired"`
+	OwnerManaged         bool   `json:"ownerManaged"`
+	Active               bool   `json:"active"`
+}
+
+// AppKeyInfo describes how an application key is provisioned
+type AppKeyInfo struct {
+	AppName string
+	Version string
+	Delayed bool
+	Active  bool
+}
+
+// Account API Methods
+func (c *RESTClient) GetDeveloperAppKeys(ctx context.Context) ([]DeveloperApp, error) {
+	params := map[string]interface{}{}
+
+	resp, err := c.makeAccountAPIRequest(ctx, "getDeveloperAppKeys", params)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []DeveloperApp
+	resultBytes, err := json.Marshal(resp.Result)
+	if err != nil {
+		return nil, fmt.Errorf("marshal result: %w", err)
+	}
+
+	if err := json.Unmarshal(resultBytes, &results); err != nil {
+		return nil, fmt.Errorf("unmarshal developer app keys: %w", err)
+	}
+
+	return results, nil
+}
+
+// ValidateAppKey looks up the client's app key among the account's developer keys and reports
+// whether it is a delayed or live key
+func (c *RESTClient) ValidateAppKey(ctx context.Context) (*AppKeyInfo, error) {
+	apps, err := c.GetDeveloperAppKeys(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get developer app keys: %w", err)
+	}
+
+	for _, app := range apps {
+		for _, version := range app.AppVersions {
+			if version.ApplicationKey != c.appKey {
+				continue
+			}
+			return &AppKeyInfo{
+				AppName: app.AppName,
+				Version: version.Version,
+				Delayed: version.DelayData,
+				Active:  version.Active,
+			}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("app key not found among developer app keys")
+}
@@ -0,0 +1,61 @@
+package betfair
+
+import (
+	"context"
+	"testing"
+)
+
+func developerAppKeysHandler(t *testing.T) func(method string, params map[string]interface{}) interface{} {
+	return func(method string, params map[string]interface{}) interface{} {
+		if method != "getDeveloperAppKeys" {
+			t.Errorf("Unexpected method %s", method)
+		}
+		return []map[string]interface{}{
+			{
+				"appName": "recorder",
+				"appId":   1,
+				"appVersions": []map[string]interface{}{
+					{"version": "1.0-DELAY", "applicationKey": "delayed-key", "delayData": true, "active": true},
+					{"version": "1.0", "applicationKey": "live-key", "delayData": false, "active": true},
+				},
+			},
+		}
+	}
+}
+
+func TestValidateAppKey(t *testing.T) {
+	tests := []struct {
+		name        string
+		appKey      string
+		wantDelayed bool
+		wantErr     bool
+	}{
+		{name: "Delayed key", appKey: "delayed-key", wantDelayed: true},
+		{name: "Live key", appKey: "live-key", wantDelayed: false},
+		{name: "Unknown key", appKey: "other-key", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestRESTClient(t, developerAppKeysHandler(t))
+			client.appKey = tt.appKey
+
+			info, err := client.ValidateAppKey(context.Background())
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Expected error for unknown app key")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ValidateAppKey failed: %v", err)
+			}
+			if info.Delayed != tt.wantDelayed {
+				t.Errorf("Expected delayed=%v, got %v", tt.wantDelayed, info.Delayed)
+			}
+			if info.AppName != "recorder" {
+				t.Errorf("Expected app name 'recorder', got %s", info.AppName)
+			}
+		})
+	}
+}
@@ -7,12 +7,16 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
 	"time"
 )
 
 type Authenticator struct {
 	appKey   string
 	username string
+	loginURL string
+
+	mu       sync.Mutex
 	password string
 }
 
@@ -21,15 +25,32 @@ func NewAuthenticator(appKey, username, password string) *Authenticator {
 		appKey:   appKey,
 		username: username,
 		password: password,
+		loginURL: DefaultEndpoints().InteractiveLogin,
 	}
 }
 
+// SetEndpoints switches interactive login to a jurisdiction's identity SSO host
+func (a *Authenticator) SetEndpoints(endpoints Endpoints) {
+	a.loginURL = endpoints.InteractiveLogin
+}
+
+// SetPassword replaces the password used by later logins, such as after it is rotated
+func (a *Authenticator) SetPassword(password string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.password = password
+}
+
 func (a *Authenticator) Login() (string, error) {
+	a.mu.Lock()
+	password := a.password
+	a.mu.Unlock()
+
 	form := url.Values{}
 	form.Set("username", a.username)
-	form.Set("password", a.password)
+	form.Set("password", password)
 
-	req, err := http.NewRequest(http.MethodPost, "https://identitysso.betfair.com/api/login", strings.NewReader(form.Encode()))
+	req, err := http.NewRequest(http.MethodPost, a.loginURL, strings.NewReader(form.Encode()))
 	if err != nil {
 		return "", fmt.Errorf("create login request: %w", err)
 	}
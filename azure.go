@@ -0,0 +1,309 @@
+package betfair
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// azureAPIVersion is the Blob service REST version requests are made against
+const azureAPIVersion = "2021-08-06"
+
+// AzureStorage stores market files in an Azure Blob Storage container through the REST API
+type AzureStorage struct {
+	client    *http.Client
+	endpoint  string
+	account   string
+	key       []byte
+	sas       url.Values
+	container string
+	basePath  string
+	now       func() time.Time
+}
+
+// NewAzureStorage opens a container. The account defaults to AZURE_STORAGE_ACCOUNT and requests
+// are signed with AZURE_STORAGE_KEY, or authorised with AZURE_STORAGE_SAS_TOKEN; without either
+// only public containers can be read. AZURE_STORAGE_ENDPOINT overrides the service URL, such as
+// for the Azurite emulator.
+func NewAzureStorage(ctx context.Context, account, container, basePath string) (*AzureStorage, error) {
+	if account == "" {
+		account = strings.TrimSpace(getenv("AZURE_STORAGE_ACCOUNT"))
+	}
+	if account == "" {
+		return nil, fmt.Errorf("AZURE_STORAGE_ACCOUNT not configured")
+	}
+	if container == "" {
+		return nil, fmt.Errorf("Azure container not configured")
+	}
+
+	storage := &AzureStorage{
+		client:    &http.Client{Timeout: 5 * time.Minute},
+		endpoint:  "https://" + account + azureBlobHostSuffix,
+		account:   account,
+		container: container,
+		basePath:  basePath,
+		now:       time.Now,
+	}
+	if endpoint := strings.TrimSpace(getenv("AZURE_STORAGE_ENDPOINT")); endpoint != "" {
+		storage.endpoint = strings.TrimRight(endpoint, "/")
+	}
+
+	if key := strings.TrimSpace(getenv("AZURE_STORAGE_KEY")); key != "" {
+		decoded, err := base64.StdEncoding.DecodeString(key)
+		if err != nil {
+			return nil, fmt.Errorf("decode AZURE_STORAGE_KEY: %w", err)
+		}
+		storage.key = decoded
+	} else if token := strings.TrimSpace(getenv("AZURE_STORAGE_SAS_TOKEN")); token != "" {
+		sas, err := url.ParseQuery(strings.TrimPrefix(token, "?"))
+		if err != nil {
+			return nil, fmt.Errorf("parse AZURE_STORAGE_SAS_TOKEN: %w", err)
+		}
+		storage.sas = sas
+	}
+	return storage, nil
+}
+
+func (s *AzureStorage) Upload(ctx context.Context, filePath, key string) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return fmt.Errorf("open file: %w", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("stat file: %w", err)
+	}
+
+	req, err := s.newRequest(ctx, http.MethodPut, key, nil, file)
+	if err != nil {
+		return fmt.Errorf("build Azure upload request: %w", err)
+	}
+	req.ContentLength = info.Size()
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("x-ms-blob-type", "BlockBlob")
+
+	resp, err := s.do(req)
+	if err != nil {
+		return fmt.Errorf("upload to Azure: %w", err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Download opens a blob for reading; the caller closes it
+func (s *AzureStorage) Download(ctx context.Context, key string) (io.ReadCloser, error) {
+	req, err := s.newRequest(ctx, http.MethodGet, key, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("build Azure download request: %w", err)
+	}
+	resp, err := s.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("get Azure blob: %w", err)
+	}
+	return resp.Body, nil
+}
+
+// azureBlobList is the List Blobs response body
+type azureBlobList struct {
+	Blobs []struct {
+		Name       string `xml:"Name"`
+		Properties struct {
+			ContentLength int64  `xml:"Content-Length"`
+			ContentMD5    string `xml:"Content-MD5"`
+			ETag          string `xml:"Etag"`
+		} `xml:"Properties"`
+	} `xml:"Blobs>Blob"`
+	NextMarker string `xml:"NextMarker"`
+}
+
+// List returns every blob whose name starts with prefix
+func (s *AzureStorage) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	var objects []ObjectInfo
+	marker := ""
+	for {
+		query := url.Values{"restype": {"container"}, "comp": {"list"}, "prefix": {NormalizeKey(prefix)}}
+		if marker != "" {
+			query.Set("marker", marker)
+		}
+		req, err := s.newRequest(ctx, http.MethodGet, "", query, nil)
+		if err != nil {
+			return nil, fmt.Errorf("build Azure list request: %w", err)
+		}
+		resp, err := s.do(req)
+		if err != nil {
+			return nil, fmt.Errorf("list Azure blobs: %w", err)
+		}
+
+		var page azureBlobList
+		err = xml.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("decode Azure blob list: %w", err)
+		}
+		for _, blob := range page.Blobs {
+			objects = append(objects, ObjectInfo{
+				Key:  blob.Name,
+				Size: blob.Properties.ContentLength,
+				MD5:  azureMD5(blob.Properties.ContentMD5),
+				ETag: strings.Trim(blob.Properties.ETag, `"`),
+			})
+		}
+		if page.NextMarker == "" {
+			return objects, nil
+		}
+		marker = page.NextMarker
+	}
+}
+
+func (s *AzureStorage) Head(ctx context.Context, key string) (ObjectInfo, error) {
+	req, err := s.newRequest(ctx, http.MethodHead, key, nil, nil)
+	if err != nil {
+		return ObjectInfo{}, fmt.Errorf("build Azure properties request: %w", err)
+	}
+	resp, err := s.do(req)
+	if err != nil {
+		return ObjectInfo{}, fmt.Errorf("head Azure blob: %w", err)
+	}
+	resp.Body.Close()
+
+	size, _ := strconv.ParseInt(resp.Header.Get("Content-Length"), 10, 64)
+	return ObjectInfo{Key: NormalizeKey(key), Size: size, MD5: azureMD5(resp.Header.Get("Content-MD5")),
+		ETag: strings.Trim(resp.Header.Get("ETag"), `"`)}, nil
+}
+
+// Verify checks the stored blob's size, and its MD5 when Azure recorded one
+func (s *AzureStorage) Verify(ctx context.Context, key string, size int64, md5Hex string) error {
+	info, err := s.Head(ctx, key)
+	if err != nil {
+		return err
+	}
+	return verifyObject(info, size, md5Hex)
+}
+
+func (s *AzureStorage) BuildKey(eventInfo *EventInfo, filename string) string {
+	return buildObjectKey(s.basePath, eventInfo, filename)
+}
+
+// azureMD5 converts Azure's base64 Content-MD5 to hex; blocks uploaded in pieces may have none
+func azureMD5(value string) string {
+	sum, err := base64.StdEncoding.DecodeString(value)
+	if err != nil || len(sum) == 0 {
+		return ""
+	}
+	return hex.EncodeToString(sum)
+}
+
+func (s *AzureStorage) newRequest(ctx context.Context, method, key string, query url.Values, body io.Reader) (*http.Request, error) {
+	path := "/" + s.container
+	if key = NormalizeKey(key); key != "" {
+		path += "/" + key
+	}
+	target, err := url.Parse(s.endpoint)
+	if err != nil {
+		return nil, err
+	}
+	target.Path += path
+
+	if query == nil {
+		query = url.Values{}
+	}
+	for name, values := range s.sas {
+		query[name] = values
+	}
+	target.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("x-ms-version", azureAPIVersion)
+	req.Header.Set("x-ms-date", s.now().UTC().Format(http.TimeFormat))
+	return req, nil
+}
+
+// do signs a request when an account key is configured and returns the response if it succeeded
+func (s *AzureStorage) do(req *http.Request) (*http.Response, error) {
+	if s.key != nil {
+		req.Header.Set("Authorization", "SharedKey "+s.account+":"+s.signature(req))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return resp, nil
+}
+
+// signature computes the Shared Key signature of a request
+func (s *AzureStorage) signature(req *http.Request) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(azureStringToSign(s.account, req)))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// azureStringToSign builds the Shared Key string to sign for the Blob service
+func azureStringToSign(account string, req *http.Request) string {
+	contentLength := ""
+	if req.ContentLength > 0 {
+		contentLength = strconv.FormatInt(req.ContentLength, 10)
+	}
+
+	var msHeaders []string
+	for name := range req.Header {
+		if lower := strings.ToLower(name); strings.HasPrefix(lower, "x-ms-") {
+			msHeaders = append(msHeaders, lower)
+		}
+	}
+	sort.Strings(msHeaders)
+	var canonicalHeaders strings.Builder
+	for _, name := range msHeaders {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(req.Header.Get(name)) + "\n")
+	}
+
+	canonicalResource := "/" + account + req.URL.EscapedPath()
+	query := req.URL.Query()
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		values := append([]string(nil), query[name]...)
+		sort.Strings(values)
+		canonicalResource += "\n" + strings.ToLower(name) + ":" + strings.Join(values, ",")
+	}
+
+	return strings.Join([]string{
+		req.Method,
+		req.Header.Get("Content-Encoding"),
+		req.Header.Get("Content-Language"),
+		contentLength,
+		req.Header.Get("Content-MD5"),
+		req.Header.Get("Content-Type"),
+		"", // Date, superseded by x-ms-date
+		req.Header.Get("If-Modified-Since"),
+		req.Header.Get("If-Match"),
+		req.Header.Get("If-None-Match"),
+		req.Header.Get("If-Unmodified-Since"),
+		req.Header.Get("Range"),
+	}, "\n") + "\n" + canonicalHeaders.String() + canonicalResource
+}
@@ -0,0 +1,205 @@
+package betfair
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeAzure serves the parts of the Blob service REST API the storage backend uses, checking
+// Shared Key signatures when it has a key
+type fakeAzure struct {
+	mu       sync.Mutex
+	key      []byte
+	objects  map[string][]byte
+	pageSize int
+	queries  []string
+}
+
+func newFakeAzure(t *testing.T, key []byte) (*fakeAzure, *httptest.Server) {
+	fake := &fakeAzure{key: key, objects: make(map[string][]byte), pageSize: 1000}
+	server := httptest.NewServer(http.HandlerFunc(fake.serve))
+	t.Cleanup(server.Close)
+	return fake, server
+}
+
+func (f *fakeAzure) serve(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.queries = append(f.queries, r.URL.RawQuery)
+
+	if f.key != nil {
+		mac := hmac.New(sha256.New, f.key)
+		mac.Write([]byte(azureStringToSign("acct", r)))
+		expected := "SharedKey acct:" + base64.StdEncoding.EncodeToString(mac.Sum(nil))
+		if r.Header.Get("Authorization") != expected {
+			http.Error(w, "signature mismatch", http.StatusForbidden)
+			return
+		}
+	}
+
+	name := strings.TrimPrefix(r.URL.Path, "/markets/")
+	switch {
+	case r.Method == http.MethodPut && r.Header.Get("x-ms-blob-type") == "BlockBlob":
+		data, _ := io.ReadAll(r.Body)
+		f.objects[name] = data
+		w.WriteHeader(http.StatusCreated)
+
+	case r.Method == http.MethodGet && r.URL.Query().Get("comp") == "list":
+		var names []string
+		for name := range f.objects {
+			if strings.HasPrefix(name, r.URL.Query().Get("prefix")) {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		start := 0
+		if marker := r.URL.Query().Get("marker"); marker != "" {
+			fmt.Sscan(marker, &start)
+		}
+		end := start + f.pageSize
+		next := ""
+		if end < len(names) {
+			next = fmt.Sprint(end)
+		} else {
+			end = len(names)
+		}
+		fmt.Fprint(w, `<?xml version="1.0" encoding="utf-8"?><EnumerationResults><Blobs>`)
+		for _, name := range names[start:end] {
+			fmt.Fprintf(w, "<Blob><Name>%s</Name><Properties><Content-Length>%d</Content-Length><Content-MD5>%s</Content-MD5></Properties></Blob>",
+				name, len(f.objects[name]), f.md5(name))
+		}
+		fmt.Fprintf(w, "</Blobs><NextMarker>%s</NextMarker></EnumerationResults>", next)
+
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		data, ok := f.objects[name]
+		if !ok {
+			http.Error(w, "BlobNotFound", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Length", fmt.Sprint(len(data)))
+		w.Header().Set("Content-MD5", f.md5(name))
+		if r.Method == http.MethodGet {
+			w.Write(data)
+		}
+
+	default:
+		http.Error(w, "unexpected request", http.StatusBadRequest)
+	}
+}
+
+func (f *fakeAzure) md5(name string) string {
+	sum := md5.Sum(f.objects[name])
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func TestAzureStorageRoundTrip(t *testing.T) {
+	key := []byte("account-key")
+	fake, server := newFakeAzure(t, key)
+	fake.pageSize = 1
+	t.Setenv("AZURE_STORAGE_ENDPOINT", server.URL)
+	t.Setenv("AZURE_STORAGE_KEY", base64.StdEncoding.EncodeToString(key))
+
+	storage, err := NewAzureStorage(context.Background(), "acct", "markets", "")
+	if err != nil {
+		t.Fatalf("NewAzureStorage failed: %v", err)
+	}
+	ctx := context.Background()
+
+	file := filepath.Join(t.TempDir(), "1.1.bz2")
+	if err := os.WriteFile(file, []byte("market data"), 0644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	for _, key := range []string{"PRO/2025/Oct/1/99/1.1.bz2", "PRO/2025/Oct/1/99/1.2.bz2", "other/1.3.bz2"} {
+		if err := storage.Upload(ctx, file, key); err != nil {
+			t.Fatalf("Upload failed: %v", err)
+		}
+	}
+
+	objects, err := storage.List(ctx, "PRO/")
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(objects) != 2 || objects[0].Key != "PRO/2025/Oct/1/99/1.1.bz2" || objects[1].Key != "PRO/2025/Oct/1/99/1.2.bz2" {
+		t.Fatalf("Expected both PRO blobs across pages, got %+v", objects)
+	}
+
+	sum := md5.Sum([]byte("market data"))
+	info, err := storage.Head(ctx, "PRO/2025/Oct/1/99/1.1.bz2")
+	if err != nil {
+		t.Fatalf("Head failed: %v", err)
+	}
+	if info.Size != 11 || info.MD5 != hex.EncodeToString(sum[:]) {
+		t.Errorf("Unexpected blob info %+v", info)
+	}
+	if err := storage.Verify(ctx, "PRO/2025/Oct/1/99/1.1.bz2", 11, "00000000000000000000000000000000"); !errors.Is(err, errVerificationMismatch) {
+		t.Errorf("Expected MD5 mismatch, got %v", err)
+	}
+
+	body, err := storage.Download(ctx, "PRO/2025/Oct/1/99/1.2.bz2")
+	if err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+	data, _ := io.ReadAll(body)
+	body.Close()
+	if string(data) != "market data" {
+		t.Errorf("Expected downloaded content, got %q", data)
+	}
+
+	if _, err := storage.Download(ctx, "missing"); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("Expected not found error, got %v", err)
+	}
+
+	storage.key = []byte("wrong-key")
+	if _, err := storage.Head(ctx, "PRO/2025/Oct/1/99/1.1.bz2"); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("Expected a bad signature to be rejected, got %v", err)
+	}
+}
+
+func TestAzureStorageSASToken(t *testing.T) {
+	fake, server := newFakeAzure(t, nil)
+	t.Setenv("AZURE_STORAGE_ENDPOINT", server.URL)
+	t.Setenv("AZURE_STORAGE_ACCOUNT", "acct")
+	t.Setenv("AZURE_STORAGE_KEY", "")
+	t.Setenv("AZURE_STORAGE_SAS_TOKEN", "?sv=2021-08-06&sig=abc%2Bdef")
+
+	storage, err := NewObjectStorage(context.Background(), "az://markets/archive", "")
+	if err != nil {
+		t.Fatalf("NewObjectStorage failed: %v", err)
+	}
+	azure, ok := storage.(*AzureStorage)
+	if !ok {
+		t.Fatalf("Expected Azure storage, got %T", storage)
+	}
+	if azure.account != "acct" || azure.key != nil {
+		t.Errorf("Expected SAS-only storage for the environment account, got %+v", azure)
+	}
+
+	file := filepath.Join(t.TempDir(), "1.1.bz2")
+	if err := os.WriteFile(file, []byte("market data"), 0644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	if err := storage.Upload(context.Background(), file, "archive/1.1.bz2"); err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+	if len(fake.queries) != 1 || !strings.Contains(fake.queries[0], "sig=abc%2Bdef") {
+		t.Errorf("Expected the SAS token on the request, got %v", fake.queries)
+	}
+	if _, ok := fake.objects["archive/1.1.bz2"]; !ok {
+		t.Errorf("Expected blob to be stored, got %v", fake.objects)
+	}
+}
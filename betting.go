@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sync"
 	"time"
 )
 
@@ -86,7 +87,7 @@ type PlaceInstruction struct {
 }
 
 type LimitOrder struct {
-	Size            float64         `json:"size"`
+	Size            float64         `json:"size,omitempty"`
 	Price           float64         `json:"price"`
 	PersistenceType PersistenceType `json:"persistenceType"`
 	TimeInForce     *string         `json:"timeInForce,omitempty"`
@@ -95,6 +96,11 @@ type LimitOrder struct {
 	BetTargetSize   *float64        `json:"betTargetSize,omitempty"`
 }
 
+const (
+	BetTargetTypeBackersProfit = "BACKERS_PROFIT"
+	BetTargetTypePayout        = "PAYOUT"
+)
+
 type LimitOnCloseOrder struct {
 	Size  float64 `json:"size"`
 	Price float64 `json:"price"`
@@ -325,6 +331,45 @@ type UpdateInstructionReport struct {
 	Instruction UpdateInstruction           `json:"instruction"`
 }
 
+// Current Order Types
+type OrderStatus string
+
+const (
+	OrderStatusPending           OrderStatus = "PENDING"
+	OrderStatusExecutionComplete OrderStatus = "EXECUTION_COMPLETE"
+	OrderStatusExecutable        OrderStatus = "EXECUTABLE"
+	OrderStatusExpired           OrderStatus = "EXPIRED"
+)
+
+type CurrentOrderSummary struct {
+	BetID               string          `json:"betId"`
+	MarketID            string          `json:"marketId"`
+	SelectionID         int64           `json:"selectionId"`
+	Handicap            float64         `json:"handicap"`
+	PriceSize           PriceSize       `json:"priceSize"`
+	BspLiability        float64         `json:"bspLiability"`
+	Side                Side            `json:"side"`
+	Status              OrderStatus     `json:"status"`
+	PersistenceType     PersistenceType `json:"persistenceType"`
+	OrderType           OrderType       `json:"orderType"`
+	PlacedDate          time.Time       `json:"placedDate"`
+	MatchedDate         *time.Time      `json:"matchedDate,omitempty"`
+	AveragePriceMatched float64         `json:"averagePriceMatched"`
+	SizeMatched         float64         `json:"sizeMatched"`
+	SizeRemaining       float64         `json:"sizeRemaining"`
+	SizeLapsed          float64         `json:"sizeLapsed"`
+	SizeCancelled       float64         `json:"sizeCancelled"`
+	SizeVoided          float64         `json:"sizeVoided"`
+	RegulatorCode       string          `json:"regulatorCode,omitempty"`
+	CustomerOrderRef    string          `json:"customerOrderRef,omitempty"`
+	CustomerStrategyRef string          `json:"customerStrategyRef,omitempty"`
+}
+
+type CurrentOrderSummaryReport struct {
+	CurrentOrders []CurrentOrderSummary `json:"currentOrders"`
+	MoreAvailable bool                  `json:"moreAvailable"`
+}
+
 // Betting API Methods
 func (c *RESTClient) ListMarketBook(ctx context.Context, marketIDs []string, priceProjection *PriceProjection, orderProjection *OrderProjection, matchProjection *string, includeOverallPosition *bool, partitionMatchedByStrategyRef *bool, customerStrategyRefs []string, currencyCode *string, locale *string, matchedSince *time.Time, betIDs []string) ([]MarketBook, error) {
 	params := map[string]interface{}{
@@ -351,6 +396,8 @@ func (c *RESTClient) ListMarketBook(ctx context.Context, marketIDs []string, pri
 	}
 	if currencyCode != nil {
 		params["currencyCode"] = *currencyCode
+	} else if c.currency != "" {
+		params["currencyCode"] = c.currency
 	}
 	if locale != nil {
 		params["locale"] = *locale
@@ -382,6 +429,116 @@ func (c *RESTClient) ListMarketBook(ctx context.Context, marketIDs []string, pri
 	return results, nil
 }
 
+const (
+	// MaxMarketBookWeight is the data weight Betfair allows per listMarketBook request
+	MaxMarketBookWeight = 200
+	// maxConcurrentMarketBookRequests bounds in-flight batches issued by ListMarketBookBatched
+	maxConcurrentMarketBookRequests = 4
+)
+
+var priceDataWeights = map[PriceData]int{
+	PriceDataSPAvailable:  3,
+	PriceDataSPTraded:     7,
+	PriceDataEXBestOffers: 5,
+	PriceDataEXAllOffers:  17,
+	PriceDataEXTraded:     17,
+}
+
+// MarketBookWeight returns the per-market data weight of a listMarketBook price projection
+func MarketBookWeight(priceProjection *PriceProjection) int {
+	if priceProjection == nil || len(priceProjection.PriceData) == 0 {
+		return 2
+	}
+
+	hasOffers, hasTraded := false, false
+	weight := 0
+	for _, pd := range priceProjection.PriceData {
+		w, ok := priceDataWeights[pd]
+		if !ok {
+			continue
+		}
+		if pd == PriceDataEXBestOffers && priceProjection.ExBestOffersOverrides != nil {
+			if depth := priceProjection.ExBestOffersOverrides.BestPricesDepth; depth != nil && *depth > 3 {
+				w = w * *depth / 3
+			}
+		}
+		hasOffers = hasOffers || pd == PriceDataEXAllOffers || pd == PriceDataEXBestOffers
+		hasTraded = hasTraded || pd == PriceDataEXTraded
+		weight += w
+	}
+
+	// Betfair discounts either offer ladder combined with traded volume
+	if hasOffers && hasTraded {
+		weight -= 2
+	}
+	if weight <= 0 {
+		return 2
+	}
+	return weight
+}
+
+// ChunkMarketIDs splits market IDs into batches whose combined weight stays within MaxMarketBookWeight
+func ChunkMarketIDs(marketIDs []string, weight int) [][]string {
+	if len(marketIDs) == 0 {
+		return nil
+	}
+
+	size := 1
+	if weight > 0 && weight < MaxMarketBookWeight {
+		size = MaxMarketBookWeight / weight
+	}
+
+	chunks := make([][]string, 0, (len(marketIDs)+size-1)/size)
+	for start := 0; start < len(marketIDs); start += size {
+		end := start + size
+		if end > len(marketIDs) {
+			end = len(marketIDs)
+		}
+		chunks = append(chunks, marketIDs[start:end])
+	}
+	return chunks
+}
+
+// ListMarketBookBatched fetches market books for any number of markets by splitting the request
+// into weight-compliant batches, issuing them concurrently and merging the results in batch order
+func (c *RESTClient) ListMarketBookBatched(ctx context.Context, marketIDs []string, priceProjection *PriceProjection, orderProjection *OrderProjection, matchProjection *string) ([]MarketBook, error) {
+	chunks := ChunkMarketIDs(marketIDs, MarketBookWeight(priceProjection))
+	if len(chunks) == 0 {
+		return nil, nil
+	}
+
+	results := make([][]MarketBook, len(chunks))
+	errs := make([]error, len(chunks))
+	sem := make(chan struct{}, maxConcurrentMarketBookRequests)
+
+	var wg sync.WaitGroup
+	for i, chunk := range chunks {
+		wg.Add(1)
+		go func(i int, chunk []string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			books, err := c.ListMarketBook(ctx, chunk, priceProjection, orderProjection, matchProjection, nil, nil, nil, nil, nil, nil, nil)
+			if err != nil {
+				errs[i] = fmt.Errorf("market book batch %d: %w", i, err)
+				return
+			}
+			results[i] = books
+		}(i, chunk)
+	}
+	wg.Wait()
+
+	var merged []MarketBook
+	for i := range chunks {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		merged = append(merged, results[i]...)
+	}
+	return merged, nil
+}
+
 func (c *RESTClient) PlaceOrders(ctx context.Context, marketID string, instructions []PlaceInstruction, customerRef *string, marketVersion *int64, customerStrategyRef *string, async *bool) (*PlaceExecutionReport, error) {
 	params := map[string]interface{}{
 		"marketId":     marketID,
@@ -420,6 +577,131 @@ func (c *RESTClient) PlaceOrders(ctx context.Context, marketID string, instructi
 	return &result, nil
 }
 
+func (c *RESTClient) ListCurrentOrders(ctx context.Context, betIDs []string, marketIDs []string, orderProjection *OrderProjection, customerOrderRefs []string, customerStrategyRefs []string, dateRange *TimeRange, orderBy *OrderBy, sortDir *SortDir, fromRecord int, recordCount int) (*CurrentOrderSummaryReport, error) {
+	params := map[string]interface{}{}
+
+	if len(betIDs) > 0 {
+		params["betIds"] = betIDs
+	}
+	if len(marketIDs) > 0 {
+		params["marketIds"] = marketIDs
+	}
+	if orderProjection != nil {
+		params["orderProjection"] = *orderProjection
+	}
+	if len(customerOrderRefs) > 0 {
+		params["customerOrderRefs"] = customerOrderRefs
+	}
+	if len(customerStrategyRefs) > 0 {
+		params["customerStrategyRefs"] = customerStrategyRefs
+	}
+	if dateRange != nil {
+		params["dateRange"] = dateRange
+	}
+	if orderBy != nil {
+		params["orderBy"] = *orderBy
+	}
+	if sortDir != nil {
+		params["sortDir"] = *sortDir
+	}
+	if fromRecord > 0 {
+		params["fromRecord"] = fromRecord
+	}
+	if recordCount > 0 {
+		params["recordCount"] = recordCount
+	}
+
+	resp, err := c.makeBettingAPIRequest(ctx, "listCurrentOrders", params)
+	if err != nil {
+		return nil, err
+	}
+
+	var result CurrentOrderSummaryReport
+	resultBytes, err := json.Marshal(resp.Result)
+	if err != nil {
+		return nil, fmt.Errorf("marshal result: %w", err)
+	}
+
+	if err := json.Unmarshal(resultBytes, &result); err != nil {
+		return nil, fmt.Errorf("unmarshal current orders: %w", err)
+	}
+
+	return &result, nil
+}
+
+// PlaceOrderResult is a simplified view of a single placed limit order
+type PlaceOrderResult struct {
+	MarketID            string
+	SelectionID         int64
+	BetID               string
+	Status              InstructionReportStatus
+	ErrorCode           string
+	Price               float64
+	Size                float64
+	SizeMatched         float64
+	AveragePriceMatched float64
+	PlacedDate          *time.Time
+	UsedBetTarget       bool
+}
+
+// PlaceLimitOrder validates, rounds and places a single LAPSE limit order. Stakes below
+// MinimumStake are placed using a BACKERS_PROFIT bet target so small bets are still accepted.
+func (c *RESTClient) PlaceLimitOrder(ctx context.Context, marketID string, selectionID int64, side Side, price, size float64) (*PlaceOrderResult, error) {
+	price = RoundToValidPrice(price)
+	size = RoundStake(size)
+	if err := ValidateOrderParameters(marketID, selectionID, price, size); err != nil {
+		return nil, err
+	}
+
+	instruction := CreatePlaceInstruction(selectionID, side, price, size, PersistenceLapse)
+	usedBetTarget := false
+	if size < MinimumStake {
+		targetType := BetTargetTypeBackersProfit
+		targetSize := RoundStake(CalculateBackProfit(size, price))
+		instruction.LimitOrder.Size = 0
+		instruction.LimitOrder.BetTargetType = &targetType
+		instruction.LimitOrder.BetTargetSize = &targetSize
+		usedBetTarget = true
+	}
+
+	report, err := c.PlaceOrders(ctx, marketID, []PlaceInstruction{instruction}, nil, nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &PlaceOrderResult{
+		MarketID:      marketID,
+		SelectionID:   selectionID,
+		Price:         price,
+		Size:          size,
+		UsedBetTarget: usedBetTarget,
+	}
+
+	if len(report.InstructionReports) > 0 {
+		ir := report.InstructionReports[0]
+		result.BetID = ir.BetID
+		result.Status = ir.Status
+		result.SizeMatched = ir.SizeMatched
+		result.PlacedDate = ir.PlacedDate
+		if ir.AveragePriceMatched != nil {
+			result.AveragePriceMatched = *ir.AveragePriceMatched
+		}
+		if ir.ErrorCode != nil {
+			result.ErrorCode = string(*ir.ErrorCode)
+		}
+	}
+
+	if report.Status != ExecutionReportStatusSuccess {
+		errCode := result.ErrorCode
+		if errCode == "" && report.ErrorCode != nil {
+			errCode = string(*report.ErrorCode)
+		}
+		return result, fmt.Errorf("place order %s: %s", report.Status, firstNonEmpty(errCode, "unknown error"))
+	}
+
+	return result, nil
+}
+
 func (c *RESTClient) CancelOrders(ctx context.Context, marketID string, instructions []CancelInstruction, customerRef *string) (*CancelExecutionReport, error) {
 	if len(instructions) == 0 {
 		return nil, fmt.Errorf("cancel instructions are required")
@@ -456,6 +738,35 @@ func (c *RESTClient) CancelOrders(ctx context.Context, marketID string, instruct
 	return &result, nil
 }
 
+// CancelAllOrders cancels every unmatched order, optionally restricted to a single market,
+// by sending cancelOrders without instructions
+func (c *RESTClient) CancelAllOrders(ctx context.Context, marketID *string) (*CancelExecutionReport, error) {
+	params := map[string]interface{}{
+		"locale": c.locale,
+	}
+
+	if marketID != nil {
+		params["marketId"] = *marketID
+	}
+
+	resp, err := c.makeBettingAPIRequest(ctx, "cancelOrders", params)
+	if err != nil {
+		return nil, err
+	}
+
+	var result CancelExecutionReport
+	resultBytes, err := json.Marshal(resp.Result)
+	if err != nil {
+		return nil, fmt.Errorf("marshal result: %w", err)
+	}
+
+	if err := json.Unmarshal(resultBytes, &result); err != nil {
+		return nil, fmt.Errorf("unmarshal cancel execution report: %w", err)
+	}
+
+	return &result, nil
+}
+
 func (c *RESTClient) ReplaceOrders(ctx context.Context, marketID string, instructions []ReplaceInstruction, customerRef *string, marketVersion *int64, async *bool) (*ReplaceExecutionReport, error) {
 	if len(instructions) == 0 {
 		return nil, fmt.Errorf("replace instructions are required")
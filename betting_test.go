@@ -0,0 +1,351 @@
+package betfair
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// newTestRESTClient returns a client whose JSON-RPC calls are answered by handler
+func newTestRESTClient(t *testing.T, handler func(method string, params map[string]interface{}) interface{}) *RESTClient {
+	t.Helper()
+
+	client := NewRESTClient("test-app-key", "test-session", "en")
+	client.httpClient = &http.Client{
+		Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			var rpcReq struct {
+				Method string                 `json:"method"`
+				Params map[string]interface{} `json:"params"`
+				ID     int64                  `json:"id"`
+			}
+			if err := json.NewDecoder(req.Body).Decode(&rpcReq); err != nil {
+				t.Errorf("failed to decode request: %v", err)
+			}
+
+			method := rpcReq.Method[strings.LastIndex(rpcReq.Method, "/")+1:]
+			body, err := json.Marshal(JSONRPCResponse{
+				JSONRPC: "2.0",
+				Result:  handler(method, rpcReq.Params),
+				ID:      rpcReq.ID,
+			})
+			if err != nil {
+				t.Fatalf("failed to encode response: %v", err)
+			}
+
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{"Content-Type": []string{"application/json"}},
+				Body:       io.NopCloser(bytes.NewReader(body)),
+			}, nil
+		}),
+	}
+	return client
+}
+
+func TestMarketBookWeight(t *testing.T) {
+	depth := 6
+
+	tests := []struct {
+		name       string
+		projection *PriceProjection
+		expected   int
+	}{
+		{
+			name:       "Nil projection",
+			projection: nil,
+			expected:   2,
+		},
+		{
+			name:       "Best offers",
+			projection: CreatePriceProjection([]PriceData{PriceDataEXBestOffers}),
+			expected:   5,
+		},
+		{
+			name:       "Best offers with depth override",
+			projection: CreatePriceProjection([]PriceData{PriceDataEXBestOffers}).WithBestOffersOverrides(&ExBestOffersOverrides{BestPricesDepth: &depth}),
+			expected:   10,
+		},
+		{
+			name:       "All offers and traded",
+			projection: CreatePriceProjection([]PriceData{PriceDataEXAllOffers, PriceDataEXTraded}),
+			expected:   32,
+		},
+		{
+			name:       "Best offers and traded",
+			projection: CreatePriceProjection([]PriceData{PriceDataEXBestOffers, PriceDataEXTraded}),
+			expected:   20,
+		},
+		{
+			name:       "Best offers and SP",
+			projection: CreatePriceProjection([]PriceData{PriceDataEXBestOffers, PriceDataSPAvailable, PriceDataSPTraded}),
+			expected:   15,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MarketBookWeight(tt.projection); got != tt.expected {
+				t.Errorf("Expected weight %d, got %d", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestChunkMarketIDs(t *testing.T) {
+	ids := make([]string, 0, 50)
+	for i := 0; i < 50; i++ {
+		ids = append(ids, "1."+strings.Repeat("1", i+1))
+	}
+
+	chunks := ChunkMarketIDs(ids, 17)
+	if len(chunks) != 5 {
+		t.Fatalf("Expected 5 chunks for weight 17, got %d", len(chunks))
+	}
+	for i, chunk := range chunks[:4] {
+		if len(chunk) != 11 {
+			t.Errorf("Chunk %d: expected 11 markets, got %d", i, len(chunk))
+		}
+	}
+	if len(chunks[4]) != 6 {
+		t.Errorf("Expected final chunk of 6 markets, got %d", len(chunks[4]))
+	}
+
+	if chunks := ChunkMarketIDs(ids, 500); len(chunks) != 50 {
+		t.Errorf("Expected one market per chunk when weight exceeds limit, got %d chunks", len(chunks))
+	}
+
+	bestOffersAndTraded := MarketBookWeight(CreatePriceProjection([]PriceData{PriceDataEXBestOffers, PriceDataEXTraded}))
+	if chunks := ChunkMarketIDs(ids[:10], bestOffersAndTraded); len(chunks) != 1 {
+		t.Errorf("Expected 10 markets of best offers and traded to fit one request, got %d chunks", len(chunks))
+	}
+
+	if chunks := ChunkMarketIDs(nil, 5); chunks != nil {
+		t.Errorf("Expected nil chunks for no markets, got %v", chunks)
+	}
+}
+
+func TestListMarketBookBatched(t *testing.T) {
+	var mu sync.Mutex
+	requests := 0
+
+	client := newTestRESTClient(t, func(method string, params map[string]interface{}) interface{} {
+		if method != "listMarketBook" {
+			t.Errorf("Unexpected method %s", method)
+		}
+
+		mu.Lock()
+		requests++
+		mu.Unlock()
+
+		ids, _ := params["marketIds"].([]interface{})
+		if len(ids) > MaxMarketBookWeight/32 {
+			t.Errorf("Batch of %d markets exceeds weight limit", len(ids))
+		}
+
+		books := make([]map[string]interface{}, 0, len(ids))
+		for _, id := range ids {
+			books = append(books, map[string]interface{}{"marketId": id, "status": "OPEN"})
+		}
+		return books
+	})
+
+	ids := make([]string, 0, 20)
+	for i := 0; i < 20; i++ {
+		ids = append(ids, "1.10000"+strings.Repeat("0", i))
+	}
+
+	projection := CreatePriceProjection([]PriceData{PriceDataEXAllOffers, PriceDataEXTraded})
+	books, err := client.ListMarketBookBatched(context.Background(), ids, projection, nil, nil)
+	if err != nil {
+		t.Fatalf("ListMarketBookBatched failed: %v", err)
+	}
+
+	if requests != 4 {
+		t.Errorf("Expected 4 batched requests, got %d", requests)
+	}
+	if len(books) != len(ids) {
+		t.Fatalf("Expected %d market books, got %d", len(ids), len(books))
+	}
+	for i, book := range books {
+		if book.MarketID != ids[i] {
+			t.Errorf("Book %d: expected market %s, got %s", i, ids[i], book.MarketID)
+		}
+	}
+}
+
+func TestPlaceLimitOrder(t *testing.T) {
+	var lastParams map[string]interface{}
+
+	client := newTestRESTClient(t, func(method string, params map[string]interface{}) interface{} {
+		if method != "placeOrders" {
+			t.Errorf("Unexpected method %s", method)
+		}
+		lastParams = params
+		return map[string]interface{}{
+			"status":   "SUCCESS",
+			"marketId": params["marketId"],
+			"instructionReports": []map[string]interface{}{
+				{
+					"status":              "SUCCESS",
+					"betId":               "123456",
+					"sizeMatched":         2.5,
+					"averagePriceMatched": 3.05,
+				},
+			},
+		}
+	})
+
+	result, err := client.PlaceLimitOrder(context.Background(), "1.123456", 42, SideBack, 3.07, 10)
+	if err != nil {
+		t.Fatalf("PlaceLimitOrder failed: %v", err)
+	}
+
+	if result.BetID != "123456" {
+		t.Errorf("Expected bet ID 123456, got %s", result.BetID)
+	}
+	if result.Price != 3.05 {
+		t.Errorf("Expected price rounded to 3.05, got %f", result.Price)
+	}
+	if result.SizeMatched != 2.5 || result.AveragePriceMatched != 3.05 {
+		t.Errorf("Unexpected match details: %+v", result)
+	}
+	if result.UsedBetTarget {
+		t.Error("Expected standard stake for size above minimum")
+	}
+
+	instructions := lastParams["instructions"].([]interface{})
+	limitOrder := instructions[0].(map[string]interface{})["limitOrder"].(map[string]interface{})
+	if limitOrder["price"] != 3.05 || limitOrder["size"] != 10.0 {
+		t.Errorf("Unexpected limit order sent: %v", limitOrder)
+	}
+
+	// Stakes below the minimum fall back to a bet target
+	result, err = client.PlaceLimitOrder(context.Background(), "1.123456", 42, SideBack, 4.0, 2)
+	if err != nil {
+		t.Fatalf("PlaceLimitOrder failed: %v", err)
+	}
+	if !result.UsedBetTarget {
+		t.Error("Expected bet target for stake below minimum")
+	}
+
+	instructions = lastParams["instructions"].([]interface{})
+	limitOrder = instructions[0].(map[string]interface{})["limitOrder"].(map[string]interface{})
+	if _, hasSize := limitOrder["size"]; hasSize {
+		t.Errorf("Expected size to be omitted when using a bet target: %v", limitOrder)
+	}
+	if limitOrder["betTargetType"] != BetTargetTypeBackersProfit || limitOrder["betTargetSize"] != 6.0 {
+		t.Errorf("Unexpected bet target: %v", limitOrder)
+	}
+}
+
+func TestPlaceLimitOrderValidation(t *testing.T) {
+	client := newTestRESTClient(t, func(method string, params map[string]interface{}) interface{} {
+		t.Errorf("No request expected for invalid order, got %s", method)
+		return nil
+	})
+
+	if _, err := client.PlaceLimitOrder(context.Background(), "invalid", 42, SideBack, 2.0, 10); err == nil {
+		t.Error("Expected error for invalid market ID")
+	}
+	if _, err := client.PlaceLimitOrder(context.Background(), "1.123456", 0, SideBack, 2.0, 10); err == nil {
+		t.Error("Expected error for invalid selection ID")
+	}
+}
+
+func TestPlaceLimitOrderFailure(t *testing.T) {
+	client := newTestRESTClient(t, func(method string, params map[string]interface{}) interface{} {
+		return map[string]interface{}{
+			"status":    "FAILURE",
+			"errorCode": "INSUFFICIENT_FUNDS",
+			"marketId":  params["marketId"],
+			"instructionReports": []map[string]interface{}{
+				{"status": "FAILURE", "errorCode": "ERROR_IN_ORDER"},
+			},
+		}
+	})
+
+	result, err := client.PlaceLimitOrder(context.Background(), "1.123456", 42, SideLay, 2.0, 10)
+	if err == nil {
+		t.Fatal("Expected error for failed placement")
+	}
+	if result == nil || result.ErrorCode != "ERROR_IN_ORDER" {
+		t.Errorf("Expected instruction error code in result, got %+v", result)
+	}
+}
+
+func TestCancelAllOrders(t *testing.T) {
+	var lastParams map[string]interface{}
+
+	client := newTestRESTClient(t, func(method string, params map[string]interface{}) interface{} {
+		if method != "cancelOrders" {
+			t.Errorf("Unexpected method %s", method)
+		}
+		lastParams = params
+		return map[string]interface{}{"status": "SUCCESS", "instructionReports": []interface{}{}}
+	})
+
+	report, err := client.CancelAllOrders(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("CancelAllOrders failed: %v", err)
+	}
+	if report.Status != ExecutionReportStatusSuccess {
+		t.Errorf("Expected SUCCESS status, got %s", report.Status)
+	}
+	if _, ok := lastParams["instructions"]; ok {
+		t.Errorf("Expected no instructions in cancel-all request: %v", lastParams)
+	}
+	if _, ok := lastParams["marketId"]; ok {
+		t.Errorf("Expected no market ID when cancelling across all markets: %v", lastParams)
+	}
+
+	marketID := "1.123456"
+	if _, err := client.CancelAllOrders(context.Background(), &marketID); err != nil {
+		t.Fatalf("CancelAllOrders for market failed: %v", err)
+	}
+	if lastParams["marketId"] != marketID {
+		t.Errorf("Expected market ID %s, got %v", marketID, lastParams["marketId"])
+	}
+}
+
+func TestClientCurrencyAndLocaleDefaults(t *testing.T) {
+	var params map[string]interface{}
+	client := newTestRESTClient(t, func(method string, p map[string]interface{}) interface{} {
+		params = p
+		return []MarketBook{}
+	})
+	client.SetLocale("es")
+	client.SetCurrency("AUD")
+
+	if _, err := client.ListMarketBook(context.Background(), []string{"1.1"}, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil); err != nil {
+		t.Fatalf("ListMarketBook: %v", err)
+	}
+	if params["currencyCode"] != "AUD" || params["locale"] != "es" {
+		t.Errorf("expected the client's currency and locale, got %v", params)
+	}
+
+	gbp := "GBP"
+	if _, err := client.ListMarketBook(context.Background(), []string{"1.1"}, nil, nil, nil, nil, nil, nil, &gbp, nil, nil, nil); err != nil {
+		t.Fatalf("ListMarketBook: %v", err)
+	}
+	if params["currencyCode"] != "GBP" {
+		t.Errorf("expected a call's currency to win, got %v", params["currencyCode"])
+	}
+
+	if code, err := ParseCurrencyCode(" aud "); err != nil || code != "AUD" {
+		t.Errorf("ParseCurrencyCode = %q, %v", code, err)
+	}
+	if _, err := ParseCurrencyCode("dollars"); err == nil {
+		t.Errorf("expected an error for an unknown currency code")
+	}
+}
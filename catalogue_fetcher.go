@@ -0,0 +1,195 @@
+package betfair
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	DefaultCatalogueWorkers = 4
+	DefaultCatalogueWait    = 5 * time.Second
+
+	catalogueFetchRetries  = 3
+	catalogueRetryBackoff  = 500 * time.Millisecond
+	catalogueNegativeTTL   = 10 * time.Minute
+	catalogueRequestBuffer = 256
+)
+
+var errCatalogueNotFound = errors.New("market catalogue not found")
+
+var catalogueProjection = []MarketProjection{
+	MarketProjectionEvent,
+	MarketProjectionMarketDescription,
+	MarketProjectionRunnerDescription,
+	MarketProjectionEventType,
+	MarketProjectionCompetition,
+}
+
+// fetchCatalogue looks up the catalogue for a single market
+func fetchCatalogue(ctx context.Context, restClient *RESTClient, marketID string) (*MarketCatalogue, error) {
+	filter := CreateMarketFilter().WithMarketIDs([]string{marketID})
+	catalogues, err := restClient.ListMarketCatalogue(ctx, *filter, catalogueProjection, MarketSortFirstToStart, 1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch market catalogue for %s: %w", marketID, err)
+	}
+	if len(catalogues) == 0 {
+		return nil, fmt.Errorf("no market catalogue found for market %s: %w", marketID, errCatalogueNotFound)
+	}
+	return &catalogues[0], nil
+}
+
+// CatalogueFetcher fetches market catalogues on a bounded pool of background workers so
+// catalogue lookups never block the stream reader. Markets without a catalogue, or whose
+// lookups keep failing, are negatively cached for a while instead of being retried per message.
+type CatalogueFetcher struct {
+	restClient  *RESTClient
+	workers     int
+	retries     int
+	backoff     time.Duration
+	negativeTTL time.Duration
+	logger      zerolog.Logger
+	now         func() time.Time
+	requests    chan string
+
+	mu         sync.Mutex
+	catalogues map[string]*MarketCatalogue
+	missing    map[string]time.Time
+	inFlight   map[string]bool
+}
+
+func NewCatalogueFetcher(restClient *RESTClient, workers int, logger zerolog.Logger) *CatalogueFetcher {
+	if workers <= 0 {
+		workers = DefaultCatalogueWorkers
+	}
+	return &CatalogueFetcher{
+		restClient:  restClient,
+		workers:     workers,
+		retries:     catalogueFetchRetries,
+		backoff:     catalogueRetryBackoff,
+		negativeTTL: catalogueNegativeTTL,
+		logger:      logger,
+		now:         time.Now,
+		requests:    make(chan string, catalogueRequestBuffer),
+		catalogues:  make(map[string]*MarketCatalogue),
+		missing:     make(map[string]time.Time),
+		inFlight:    make(map[string]bool),
+	}
+}
+
+// Start launches the workers; they stop when ctx is cancelled
+func (f *CatalogueFetcher) Start(ctx context.Context) {
+	for i := 0; i < f.workers; i++ {
+		go f.work(ctx)
+	}
+}
+
+// Request queues a catalogue lookup unless the market is already cached, negatively cached or queued.
+// It never blocks; if the queue is full the request is dropped and retried on the next message.
+func (f *CatalogueFetcher) Request(marketID string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.catalogues[marketID] != nil || f.inFlight[marketID] || f.isMissing(marketID) {
+		return
+	}
+
+	select {
+	case f.requests <- marketID:
+		f.inFlight[marketID] = true
+	default:
+	}
+}
+
+// Get returns the cached catalogue for a market
+func (f *CatalogueFetcher) Get(marketID string) (*MarketCatalogue, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	catalogue, ok := f.catalogues[marketID]
+	return catalogue, ok
+}
+
+// Resolved reports whether the lookup for a market has finished, with or without a catalogue
+func (f *CatalogueFetcher) Resolved(marketID string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.catalogues[marketID] != nil || f.isMissing(marketID)
+}
+
+// Forget drops everything cached for a market
+func (f *CatalogueFetcher) Forget(marketID string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.catalogues, marketID)
+	delete(f.missing, marketID)
+}
+
+// isMissing must be called with f.mu held
+func (f *CatalogueFetcher) isMissing(marketID string) bool {
+	expiry, ok := f.missing[marketID]
+	if !ok {
+		return false
+	}
+	if f.now().After(expiry) {
+		delete(f.missing, marketID)
+		return false
+	}
+	return true
+}
+
+func (f *CatalogueFetcher) work(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case marketID := <-f.requests:
+			catalogue, err := f.fetchWithRetry(ctx, marketID)
+
+			f.mu.Lock()
+			delete(f.inFlight, marketID)
+			if err != nil {
+				f.missing[marketID] = f.now().Add(f.negativeTTL)
+			} else {
+				f.catalogues[marketID] = catalogue
+			}
+			f.mu.Unlock()
+
+			if err != nil {
+				f.logger.Error().Err(err).Str("market_id", marketID).Msg("failed to fetch market catalogue")
+			} else {
+				f.logger.Info().Str("market_id", marketID).Str("market_name", catalogue.MarketName).Msg("cached market catalogue")
+			}
+		}
+	}
+}
+
+func (f *CatalogueFetcher) fetchWithRetry(ctx context.Context, marketID string) (*MarketCatalogue, error) {
+	backoff := f.backoff
+	var lastErr error
+
+	for attempt := 1; attempt <= f.retries; attempt++ {
+		catalogue, err := fetchCatalogue(ctx, f.restClient, marketID)
+		if err == nil {
+			return catalogue, nil
+		}
+		if errors.Is(err, errCatalogueNotFound) {
+			return nil, err
+		}
+		lastErr = err
+
+		if attempt < f.retries {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+	}
+
+	return nil, fmt.Errorf("after %d attempts: %w", f.retries, lastErr)
+}
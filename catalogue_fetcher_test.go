@@ -0,0 +1,142 @@
+package betfair
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestCatalogueFetcherRetriesAndNegativeCaching(t *testing.T) {
+	var mu sync.Mutex
+	calls := make(map[string]int)
+
+	client := newTestRESTClient(t, func(method string, params map[string]interface{}) interface{} {
+		filter := params["filter"].(map[string]interface{})
+		marketID := filter["marketIds"].([]interface{})[0].(string)
+
+		mu.Lock()
+		calls[marketID]++
+		attempt := calls[marketID]
+		mu.Unlock()
+
+		switch marketID {
+		case "1.flaky":
+			if attempt == 1 {
+				// Not a list, so decoding the catalogue fails like a transient API error
+				return map[string]interface{}{"errorCode": "TOO_MUCH_DATA"}
+			}
+			return []map[string]interface{}{{"marketId": marketID, "marketName": "Flaky"}}
+		default:
+			return []map[string]interface{}{}
+		}
+	})
+
+	fetcher := NewCatalogueFetcher(client, 2, zerolog.New(zerolog.NewTestWriter(t)))
+	fetcher.backoff = time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	fetcher.Start(ctx)
+
+	fetcher.Request("1.flaky")
+	fetcher.Request("1.missing")
+
+	waitFor(t, func() bool { return fetcher.Resolved("1.flaky") && fetcher.Resolved("1.missing") })
+
+	if catalogue, ok := fetcher.Get("1.flaky"); !ok || catalogue.MarketName != "Flaky" {
+		t.Errorf("Expected flaky market to be fetched after a retry, got %+v", catalogue)
+	}
+	if _, ok := fetcher.Get("1.missing"); ok {
+		t.Error("Expected no catalogue for missing market")
+	}
+
+	// Negatively cached markets are not requested again
+	fetcher.Request("1.missing")
+	time.Sleep(20 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if calls["1.flaky"] != 2 {
+		t.Errorf("Expected 2 attempts for flaky market, got %d", calls["1.flaky"])
+	}
+	if calls["1.missing"] != 1 {
+		t.Errorf("Expected missing market to be looked up once, got %d", calls["1.missing"])
+	}
+}
+
+func TestMarketRecorderBuffersUntilCatalogueArrives(t *testing.T) {
+	tempDir := t.TempDir()
+	logger := zerolog.New(zerolog.NewTestWriter(t))
+	fetcher := NewCatalogueFetcher(nil, 1, logger)
+
+	recorder := &MarketRecorder{
+		config:           &Config{CatalogueWait: time.Hour},
+		logger:           logger,
+		fileManager:      NewFileManager(tempDir),
+		marketCatalogues: make(map[string]*MarketCatalogue),
+		catalogueFetcher: fetcher,
+	}
+
+	messages := strings.Join([]string{
+		`{"op":"mcm","pt":1,"clk":"a","mc":[{"id":"1.1","img":true,"marketDefinition":{"status":"OPEN","runners":[]}}]}`,
+		`{"op":"mcm","pt":2,"clk":"b","mc":[{"id":"1.1","rc":[{"id":1,"ltp":2.5}]}]}`,
+	}, "\n") + "\n"
+	stream := &StreamConn{reader: bufio.NewReader(strings.NewReader(messages))}
+
+	writers := make(map[string]*bufio.Writer)
+	files := make(map[string]*os.File)
+	defer func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}()
+	statuses := make(map[string]string)
+
+	for i := 0; i < 2; i++ {
+		if err := recorder.readMessage(context.Background(), stream, writers, files, statuses); err != nil {
+			t.Fatalf("readMessage failed: %v", err)
+		}
+	}
+
+	path := filepath.Join(tempDir, "1.1")
+	if content, _ := os.ReadFile(path); len(content) != 0 {
+		t.Fatalf("Expected messages to be held back while catalogue is pending, got %q", content)
+	}
+
+	fetcher.mu.Lock()
+	fetcher.catalogues["1.1"] = &MarketCatalogue{MarketID: "1.1", MarketName: "R1 500m"}
+	fetcher.mu.Unlock()
+
+	recorder.flushReadyBuffers()
+	recorder.stopMarketWriters()
+
+	content, _ := os.ReadFile(path)
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected both buffered messages, got %q", content)
+	}
+	if !strings.Contains(lines[0], `"marketName":"R1 500m"`) {
+		t.Errorf("Expected image to be enriched once the catalogue arrived: %s", lines[0])
+	}
+	if !strings.Contains(lines[1], `"ltp":2.5`) {
+		t.Errorf("Expected messages to stay in order: %s", lines[1])
+	}
+}
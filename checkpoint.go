@@ -0,0 +1,139 @@
+package betfair
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// DefaultCheckpointEvery is how many stream messages pass between checkpoints when
+// CheckpointEvery is not set
+const DefaultCheckpointEvery = 100
+
+// Checkpoint is the stream position and market state persisted between runs so a restarted
+// recorder can resume the subscription from where it stopped
+type Checkpoint struct {
+	InitialClk     string            `json:"initialClk"`
+	Clk            string            `json:"clk"`
+	MarketStatuses map[string]string `json:"marketStatuses"`
+	SavedAt        time.Time         `json:"savedAt"`
+}
+
+func (c *Config) checkpointEvery() int {
+	if c.CheckpointEvery <= 0 {
+		return DefaultCheckpointEvery
+	}
+	return c.CheckpointEvery
+}
+
+// LoadCheckpoint reads a checkpoint file, returning nil without error when none exists yet
+func LoadCheckpoint(path string) (*Checkpoint, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read checkpoint: %w", err)
+	}
+
+	var checkpoint Checkpoint
+	if err := json.Unmarshal(data, &checkpoint); err != nil {
+		return nil, fmt.Errorf("decode checkpoint: %w", err)
+	}
+	if checkpoint.MarketStatuses == nil {
+		checkpoint.MarketStatuses = make(map[string]string)
+	}
+	return &checkpoint, nil
+}
+
+// SaveCheckpoint writes the checkpoint to a temporary file and renames it into place so a crash
+// mid-write never leaves a truncated checkpoint behind
+func SaveCheckpoint(path string, checkpoint *Checkpoint) error {
+	data, err := json.Marshal(checkpoint)
+	if err != nil {
+		return fmt.Errorf("encode checkpoint: %w", err)
+	}
+
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("create checkpoint directory: %w", err)
+		}
+	}
+
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("write checkpoint: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("replace checkpoint: %w", err)
+	}
+	return nil
+}
+
+// checkpointRequest is a checkpoint taken on the stream goroutine along with the market writers
+// written to since the previous one, which must reach disk before it is saved
+type checkpointRequest struct {
+	checkpoint Checkpoint
+	writers    []*marketWriter
+}
+
+// save syncs the request's writers and then persists the checkpoint, saving nothing if a file
+// cannot be synced
+func (req checkpointRequest) save(path string, logger zerolog.Logger) {
+	for _, mw := range req.writers {
+		if err := mw.Sync(); err != nil {
+			logger.Error().Err(err).Str("market_id", mw.marketID).Msg("failed to sync market file; not saving checkpoint")
+			return
+		}
+	}
+	if err := SaveCheckpoint(path, &req.checkpoint); err != nil {
+		logger.Error().Err(err).Str("path", path).Msg("failed to save checkpoint")
+	}
+}
+
+// checkpointer saves checkpoints in the background so syncing market files never holds up the
+// stream reader. Requests are saved in the order they are submitted.
+type checkpointer struct {
+	path    string
+	logger  zerolog.Logger
+	pending chan checkpointRequest
+	done    chan struct{}
+}
+
+func startCheckpointer(path string, logger zerolog.Logger) *checkpointer {
+	c := &checkpointer{
+		path:    path,
+		logger:  logger,
+		pending: make(chan checkpointRequest, 1),
+		done:    make(chan struct{}),
+	}
+	go func() {
+		defer close(c.done)
+		for req := range c.pending {
+			req.save(c.path, c.logger)
+		}
+	}()
+	return c
+}
+
+// Ready reports whether a request can be submitted without blocking. Only the stream goroutine
+// submits, so a true result holds until it does.
+func (c *checkpointer) Ready() bool {
+	return len(c.pending) < cap(c.pending)
+}
+
+// Submit queues a checkpoint to be saved in the background
+func (c *checkpointer) Submit(req checkpointRequest) {
+	c.pending <- req
+}
+
+// Stop waits for queued checkpoints to be saved
+func (c *checkpointer) Stop() {
+	close(c.pending)
+	<-c.done
+}
@@ -0,0 +1,126 @@
+package betfair
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestCheckpointRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "checkpoint.json")
+
+	checkpoint, err := LoadCheckpoint(path)
+	if err != nil || checkpoint != nil {
+		t.Fatalf("Expected no checkpoint before first save, got %v, %v", checkpoint, err)
+	}
+
+	saved := &Checkpoint{
+		InitialClk:     "initial-abc",
+		Clk:            "clk-123",
+		MarketStatuses: map[string]string{"1.1": "OPEN", "1.2": "CLOSED"},
+	}
+	if err := SaveCheckpoint(path, saved); err != nil {
+		t.Fatalf("SaveCheckpoint failed: %v", err)
+	}
+
+	loaded, err := LoadCheckpoint(path)
+	if err != nil {
+		t.Fatalf("LoadCheckpoint failed: %v", err)
+	}
+	if loaded.InitialClk != "initial-abc" || loaded.Clk != "clk-123" {
+		t.Errorf("Unexpected clocks: %+v", loaded)
+	}
+	if loaded.MarketStatuses["1.2"] != "CLOSED" || len(loaded.MarketStatuses) != 2 {
+		t.Errorf("Unexpected market statuses: %v", loaded.MarketStatuses)
+	}
+}
+
+func TestMarketRecorderRestoresCheckpoint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+	if err := SaveCheckpoint(path, &Checkpoint{
+		InitialClk:     "initial-abc",
+		Clk:            "clk-123",
+		MarketStatuses: map[string]string{"1.1": "SUSPENDED"},
+	}); err != nil {
+		t.Fatalf("SaveCheckpoint failed: %v", err)
+	}
+
+	recorder := &MarketRecorder{
+		config: &Config{CheckpointPath: path},
+		logger: zerolog.New(zerolog.NewTestWriter(t)),
+	}
+
+	statuses := make(map[string]string)
+	recorder.restoreCheckpoint(statuses)
+
+	if recorder.initialClk != "initial-abc" || recorder.clk != "clk-123" {
+		t.Errorf("Expected clocks to be restored, got %q/%q", recorder.initialClk, recorder.clk)
+	}
+	if statuses["1.1"] != "SUSPENDED" {
+		t.Errorf("Expected market statuses to be restored, got %v", statuses)
+	}
+
+	recorder.clk = "clk-456"
+	statuses["1.1"] = "CLOSED"
+	recorder.saveCheckpoint(statuses)
+
+	loaded, err := LoadCheckpoint(path)
+	if err != nil {
+		t.Fatalf("LoadCheckpoint failed: %v", err)
+	}
+	if loaded.Clk != "clk-456" || loaded.MarketStatuses["1.1"] != "CLOSED" {
+		t.Errorf("Expected latest state to be saved, got %+v", loaded)
+	}
+}
+
+func TestMarketRecorderCheckpointsOnlyRecordedData(t *testing.T) {
+	tempDir := t.TempDir()
+	path := filepath.Join(tempDir, "checkpoint.json")
+	logger := zerolog.New(zerolog.NewTestWriter(t))
+	recorder := &MarketRecorder{
+		config:           &Config{CheckpointPath: path, CheckpointEvery: 1, EnrichmentMode: EnrichmentOff, FlushEveryMessages: 1000},
+		logger:           logger,
+		fileManager:      NewFileManager(tempDir),
+		marketCatalogues: make(map[string]*MarketCatalogue),
+		checkpointer:     startCheckpointer(path, logger),
+	}
+	defer recorder.stopMarketWriters()
+
+	message := `{"op":"mcm","pt":1,"clk":"a","mc":[{"id":"1.1","marketDefinition":{"eventId":"1","openDate":"2025-10-01T10:00:00Z","status":"OPEN"}}]}` + "\n"
+	stream := &StreamConn{reader: bufio.NewReader(strings.NewReader(message))}
+	if err := recorder.readMessage(context.Background(), stream, make(map[string]*bufio.Writer), make(map[string]*os.File), make(map[string]string)); err != nil {
+		t.Fatalf("readMessage failed: %v", err)
+	}
+	if len(recorder.unsynced) != 0 {
+		t.Error("Expected the checkpoint to take the written market's writer")
+	}
+	if req, _ := recorder.takeCheckpoint(make(map[string]string)); len(req.writers) != 0 {
+		t.Errorf("Expected only writers written to since the last checkpoint to be synced, got %d", len(req.writers))
+	}
+	recorder.checkpointer.Stop()
+	recorder.checkpointer = nil
+
+	loaded, err := LoadCheckpoint(path)
+	if err != nil || loaded == nil || loaded.Clk != "a" {
+		t.Fatalf("Expected checkpoint at clk a, got %+v, %v", loaded, err)
+	}
+	// The writer's flush policy would hold the line back; the checkpoint must not
+	content, _ := os.ReadFile(recorder.fileManager.GetMarketFilePath("1.1"))
+	if !strings.Contains(string(content), `"status":"OPEN"`) {
+		t.Errorf("Expected the checkpointed message on disk, got %q", content)
+	}
+
+	recorder.catalogueBuffers = map[string]*catalogueBuffer{"1.2": {messages: []bufferedMessage{{payload: []byte("{}")}}}}
+	if recorder.saveCheckpoint(make(map[string]string)) {
+		t.Error("Expected no checkpoint while messages wait for their catalogue")
+	}
+
+	if every := (&Config{}).checkpointEvery(); every != DefaultCheckpointEvery {
+		t.Errorf("Expected an unset interval to default to %d messages, got %d", DefaultCheckpointEvery, every)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// runDiscover lists the markets the recorder's filter currently matches, without connecting to
+// the stream or recording anything
+func runDiscover(args []string) {
+	flags := flag.NewFlagSet("discover", flag.ExitOnError)
+	var (
+		envFile = flags.String("env", ".env", "File of environment variables to load before reading the configuration")
+		asJSON  = flags.Bool("json", false, "Write the markets as JSON instead of a table")
+	)
+	flags.Parse(args)
+
+	_, recorder := newRecorder(*envFile, "market-discovery", nil)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	report, err := recorder.ResolveMarkets(ctx)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to discover markets")
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report.Markets); err != nil {
+			log.Fatal().Err(err).Msg("failed to write markets")
+		}
+		return
+	}
+
+	table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "MARKET\tSTART\tEVENT\tMARKET NAME\tARCHIVE")
+	for _, market := range report.Markets {
+		start := ""
+		if market.StartTime != nil {
+			start = market.StartTime.UTC().Format(time.RFC3339)
+		}
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\n", market.MarketID, start, market.EventName, market.MarketName, market.ArchivePath)
+	}
+	table.Flush()
+}
@@ -0,0 +1,8 @@
+//go:build duckdb
+
+// Builds with -tags duckdb include the DuckDB driver -format duckdb writes through. It needs cgo
+// and isn't a requirement of the module, so add it first: go get github.com/marcboeker/go-duckdb
+
+package main
+
+import _ "github.com/marcboeker/go-duckdb"
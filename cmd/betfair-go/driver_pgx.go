@@ -0,0 +1,8 @@
+//go:build pgx
+
+// Builds with -tags pgx include the PostgreSQL driver -format database uses by default. It isn't a
+// requirement of the module, so add it first: go get github.com/jackc/pgx/v5
+
+package main
+
+import _ "github.com/jackc/pgx/v5/stdlib"
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"database/sql"
+	"log"
+	"slices"
+)
+
+// driverTags are the build tags that compile in the database/sql drivers the process command
+// knows about
+var driverTags = map[string]string{
+	"duckdb": "duckdb",
+	"pgx":    "pgx",
+}
+
+// requireDriver stops with a clear error, before any input is read, when the database/sql driver
+// an output format writes through isn't compiled into this binary
+func requireDriver(driver, format string) {
+	if slices.Contains(sql.Drivers(), driver) {
+		return
+	}
+	if tag, ok := driverTags[driver]; ok {
+		log.Fatalf("-format %s needs the %s database/sql driver; rebuild with -tags %s", format, driver, tag)
+	}
+	log.Fatalf("-format %s needs the %s database/sql driver, which this binary doesn't include", format, driver)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	betfair "github.com/felixmccuaig/betfair-go"
+	"github.com/rs/zerolog"
+)
+
+// logFlags adds the -log-level, -log-format and -log-file flags, defaulting to LOG_LEVEL,
+// LOG_FORMAT and LOG_FILE as the recorder reads them, and returns a function building the logger
+// they describe once the flags are parsed
+func logFlags(flags *flag.FlagSet, level string) func() zerolog.Logger {
+	logLevel := flags.String("log-level", envOr("LOG_LEVEL", level), "Log level: debug, info, warn or error")
+	logFormat := flags.String("log-format", envOr("LOG_FORMAT", string(betfair.DefaultLogFormat)), "Log format: console or json")
+	logFile := flags.String("log-file", os.Getenv("LOG_FILE"), "File to append logs to instead of stderr")
+
+	return func() zerolog.Logger {
+		level, err := zerolog.ParseLevel(*logLevel)
+		if err != nil {
+			log.Fatalf("Invalid -log-level: %v", err)
+		}
+		format, err := betfair.ParseLogFormat(*logFormat)
+		if err != nil {
+			log.Fatalf("Invalid -log-format: %v", err)
+		}
+		logger, err := betfair.NewLogger(level, format, *logFile)
+		if err != nil {
+			log.Fatalf("Invalid -log-file: %v", err)
+		}
+		useLogger(logger)
+		return logger
+	}
+}
+
+// useLogger routes the standard library's log output, such as the commands' own messages,
+// through logger
+func useLogger(logger zerolog.Logger) {
+	log.SetFlags(0)
+	log.SetOutput(logger)
+}
+
+// envOr returns an environment variable's value, or fallback when it isn't set
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
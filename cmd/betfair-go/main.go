@@ -0,0 +1,98 @@
+// Command betfair-go records Betfair market streams and processes the recordings. Each job is a
+// subcommand with its own flags:
+//
+//	betfair-go record                    # record markets, configured from the environment or .env
+//	betfair-go discover                  # list the markets the recorder's filter matches
+//	betfair-go process -path data/ ...   # summarise recordings to CSV, Parquet, DuckDB or a database
+//	betfair-go replay -path data/        # summarise recordings to stdout as JSON lines
+//	betfair-go verify -path data/        # check recordings for corruption and gaps
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+	_ "time/tzdata" // Timezones for -bucket-tz and venue partitions on systems without a zoneinfo database
+)
+
+// command is a subcommand, run with the arguments after its name
+type command struct {
+	name    string
+	summary string
+	run     func(args []string)
+}
+
+var commands = []command{
+	{"record", "Record market streams, configured from the environment or a .env file", runRecord},
+	{"discover", "List the markets the recorder's filter matches and where they would be recorded", runDiscover},
+	{"process", "Summarise recorded market files to CSV, Parquet, DuckDB or a database", runProcess},
+	{"replay", "Summarise recorded market files to stdout as JSON lines, as each market finishes", runReplay},
+	{"verify", "Check recorded files decompress cleanly, have no clk or publish time gaps, and settle", runVerify},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	name := os.Args[1]
+	switch name {
+	case "help", "-h", "-help", "--help":
+		usage()
+		return
+	}
+	for _, cmd := range commands {
+		if cmd.name == name {
+			cmd.run(os.Args[2:])
+			return
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "betfair-go: unknown command %q\n\n", name)
+	usage()
+	os.Exit(2)
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: betfair-go <command> [flags]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-9s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Run 'betfair-go <command> -h' for a command's flags.")
+}
+
+// parseDay parses a YYYY-MM-DD date, returning the zero time for an empty value
+func parseDay(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+// splitList splits a comma-separated flag value, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// stringList is a flag that can be given more than once, each value also split on commas
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, splitList(value)...)
+	return nil
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"regexp"
+	"time"
+
+	"github.com/felixmccuaig/betfair-go/processor"
+)
+
+// runProcess summarises recorded market files into CSV, Parquet, DuckDB or a database
+func runProcess(args []string) {
+	flags := flag.NewFlagSet("process", flag.ExitOnError)
+	var (
+		s3Path       = flags.String("s3", "", "S3 path to process (e.g., s3://bucket/prefix/)")
+		localPath    = flags.String("path", "", "Local file or directory path to process")
+		outputPath   = flags.String("output", "", "Output file path. Can use {date} placeholder (e.g., s3://bucket/summary-{date}.csv)")
+		outputFormat = flags.String("format", "csv", "Output format: csv, parquet, duckdb or database (duckdb needs a binary built with -tags duckdb, and database one with its -db-driver, such as -tags pgx)")
+		dateFormat   = flags.String("date-format", "2006-01-02", "Date format for filename (Go time format)")
+		fileLimit    = flags.Int("limit", 0, "Maximum number of files to process (0 = no limit)")
+		workers      = flags.Int("workers", 0, "Number of worker goroutines (0 = use CPU count)")
+		autoDate     = flags.Bool("auto-date", false, "Automatically extract date from input path for output filename")
+		profile      = flags.String("profile", "greyhounds", "Sport profile: greyhounds, horse-racing or generic (any sport and market type)")
+		eventTypes   = flags.String("event-types", "", "Comma-separated event type IDs to keep (overrides the profile)")
+		marketTypes  = flags.String("market-types", "", "Comma-separated market types to keep (overrides the profile)")
+		mode         = flags.String("mode", "summary", "Output mode: summary, ticks for one Parquet row per runner update, or ladder for order book snapshots")
+		ladderDepth  = flags.Int("ladder-depth", 3, "Price levels per side in ladder snapshots")
+		interval     = flags.Duration("snapshot-interval", time.Second, "Time between ladder snapshots")
+		window       = flags.Duration("snapshot-window", 10*time.Minute, "How long before the scheduled off ladder snapshots start")
+		joinPlace    = flags.Bool("join-place", false, "Also process PLACE markets and join them onto WIN rows per selection")
+		names        = flags.String("names", "", "JSON or CSV (kind,from,to) file of venue and runner name aliases and runner_rule regular expressions")
+		manifest     = flags.String("manifest", "", "Manifest of processed files; files already in it are skipped, for incremental runs")
+		from         = flags.String("from", "", "Only process files and markets dated on or after this day (YYYY-MM-DD)")
+		to           = flags.String("to", "", "Only process files and markets dated on or before this day (YYYY-MM-DD)")
+		bucketTZ     = flags.String("bucket-tz", "", "Timezone race days are dated in (e.g., Australia/Sydney), or 'market' for each market's own (default UTC)")
+		include      = flags.String("include", "", "Comma-separated glob patterns a file's path or name must match (e.g., '1.24*.bz2')")
+		match        = flags.String("match", "", "Regular expression a file's path must match (e.g., '/2025/Sep/')")
+		partition    = flags.Bool("partition", false, "Write Parquet partitioned into year=/month=/day= directories under the output directory")
+		byVenue      = flags.Bool("partition-venue", false, "Also partition by venue (with -partition)")
+		stream       = flags.Bool("stream", false, "Write summary rows to one file as markets finish instead of holding them in memory (CSV or Parquet summaries)")
+		perMarket    = flags.Bool("per-market", false, "Write one file per market (plus ticks or ladders in those modes), mirroring the input folders under the output directory")
+		reportPath   = flags.String("report", "", "Write a data-quality report to this path (.csv for one row per issue, otherwise JSON)")
+		marketTable  = flags.Bool("market-table", false, "Also write a market-level table (<name>_markets) of runner counts, overrounds and favourite and winner BSPs")
+		compression  = flags.String("compression", "snappy", "Parquet compression: snappy, zstd, gzip or none")
+		rowGroupSize = flags.Int64("row-group-size", 0, "Maximum rows per Parquet row group (0 = writer default)")
+		dictionary   = flags.Bool("dictionary", false, "Dictionary-encode Parquet string columns")
+		databaseDSN  = flags.String("dsn", "", "Database connection string for -format database (e.g., postgres://user@host/betfair)")
+		dbDriver     = flags.String("db-driver", "pgx", "database/sql driver for -dsn (pgx is included by building with -tags pgx)")
+		dbTable      = flags.String("db-table", "summary", "Table summaries are upserted into")
+		dbBatchSize  = flags.Int("db-batch-size", 500, "Rows per INSERT statement")
+		downloads    = flags.Int("download-concurrency", 4, "Remote objects downloaded at once, ahead of the parse workers")
+		prefetchMB   = flags.Int64("prefetch-mb", 256, "Memory in MiB downloaded objects may hold until parsed; larger objects are streamed")
+		cacheDir     = flags.String("cache-dir", "", "Keep remote inputs in this directory by ETag so re-processing them skips the download")
+		errorPolicy  = flags.String("error-policy", "continue", "On a file that fails: continue (report it and carry on) or fail-fast")
+		quarantine   = flags.String("quarantine", "", "Copy files that fail to this directory, with failures.json saying why")
+		bzip2Decoder = flags.String("bzip2", "stdlib", "bzip2 decoder: stdlib, dsnet (faster) or parallel (decodes each file's blocks on every core)")
+		maxLineMB    = flags.Int("max-line-mb", 16, "Longest message line in MiB; a file with a longer line fails")
+		showProgress = flags.Bool("progress", false, "Report progress: a bar on a terminal, otherwise a log line every 10s")
+		traceMarkets stringList
+	)
+	flags.Var(&traceMarkets, "trace-market", "Log every message of this market ID in detail (repeatable)")
+	newLogger := logFlags(flags, "info")
+	flags.Parse(args)
+
+	// Validate input
+	if *s3Path == "" && *localPath == "" {
+		log.Fatal("Please specify either -s3 or -path")
+	}
+
+	if *s3Path != "" && *localPath != "" {
+		log.Fatal("Please specify only one of -s3 or -path")
+	}
+
+	if *outputPath == "" && *outputFormat != "database" {
+		log.Fatal("Please specify -output")
+	}
+
+	// Validate output format
+	var format processor.OutputFormat
+	switch *outputFormat {
+	case "csv":
+		format = processor.OutputFormatCSV
+	case "parquet":
+		format = processor.OutputFormatParquet
+	case "duckdb":
+		format = processor.OutputFormatDuckDB
+		requireDriver("duckdb", *outputFormat)
+	case "database":
+		format = processor.OutputFormatDatabase
+		if *databaseDSN == "" {
+			log.Fatal("-format database requires -dsn")
+		}
+		requireDriver(*dbDriver, *outputFormat)
+	default:
+		log.Fatalf("Invalid output format: %s (must be 'csv', 'parquet', 'duckdb' or 'database')", *outputFormat)
+	}
+
+	// Validate output mode
+	outputMode := processor.OutputMode(*mode)
+	switch outputMode {
+	case processor.OutputModeSummary, processor.OutputModeTicks, processor.OutputModeLadder:
+	default:
+		log.Fatalf("Invalid output mode: %s (must be 'summary', 'ticks' or 'ladder')", *mode)
+	}
+
+	switch processor.ParquetCompression(*compression) {
+	case processor.ParquetCompressionSnappy, processor.ParquetCompressionZstd, processor.ParquetCompressionGzip, processor.ParquetCompressionNone:
+	default:
+		log.Fatalf("Invalid compression: %s (must be 'snappy', 'zstd', 'gzip' or 'none')", *compression)
+	}
+
+	switch processor.ErrorPolicy(*errorPolicy) {
+	case processor.ErrorPolicyContinue, processor.ErrorPolicyFailFast:
+	default:
+		log.Fatalf("Invalid error policy: %s (must be 'continue' or 'fail-fast')", *errorPolicy)
+	}
+
+	switch processor.Bzip2Decoder(*bzip2Decoder) {
+	case processor.Bzip2DecoderStdlib, processor.Bzip2DecoderDsnet, processor.Bzip2DecoderParallel:
+	default:
+		log.Fatalf("Invalid bzip2 decoder: %s (must be 'stdlib', 'dsnet' or 'parallel')", *bzip2Decoder)
+	}
+
+	if *marketTable && outputMode != processor.OutputModeSummary {
+		log.Fatal("-market-table is written with summaries and requires -mode summary")
+	}
+	if *partition && format != processor.OutputFormatParquet {
+		log.Fatal("-partition requires -format parquet")
+	}
+	if *stream && (*partition || *perMarket || *joinPlace || outputMode != processor.OutputModeSummary ||
+		format == processor.OutputFormatDuckDB || format == processor.OutputFormatDatabase) {
+		log.Fatal("-stream writes CSV or Parquet summaries to one file and can't be used with -partition, -per-market or -join-place")
+	}
+	if *perMarket && (*partition || format == processor.OutputFormatDuckDB || format == processor.OutputFormatDatabase) {
+		log.Fatal("-per-market requires -format csv or parquet and can't be used with -partition")
+	}
+
+	// Validate input filters
+	fromDate, err := parseDay(*from)
+	if err != nil {
+		log.Fatalf("Invalid -from date: %v", err)
+	}
+	toDate, err := parseDay(*to)
+	if err != nil {
+		log.Fatalf("Invalid -to date: %v", err)
+	}
+	if *bucketTZ != "" && *bucketTZ != processor.BucketTimezoneMarket {
+		if _, err := time.LoadLocation(*bucketTZ); err != nil {
+			log.Fatalf("Invalid -bucket-tz: %v", err)
+		}
+	}
+	var pathPattern *regexp.Regexp
+	if *match != "" {
+		if pathPattern, err = regexp.Compile(*match); err != nil {
+			log.Fatalf("Invalid -match pattern: %v", err)
+		}
+	}
+
+	logger := newLogger()
+
+	// Determine input path
+	inputPath := *s3Path
+	if inputPath == "" {
+		inputPath = *localPath
+	}
+
+	// Create processor config
+	config := processor.ProcessorConfig{
+		OutputPath:   *outputPath,
+		OutputFormat: format,
+		FileLimit:    *fileLimit,
+		Workers:      *workers,
+		DateFormat:   *dateFormat,
+		Profile:      *profile,
+		EventTypeIDs: splitList(*eventTypes),
+		MarketTypes:  splitList(*marketTypes),
+		JoinWinPlace: *joinPlace,
+		Mode:         outputMode,
+		ManifestPath: *manifest,
+		TraceMarkets: traceMarkets,
+		NameMapPath:  *names,
+
+		ErrorPolicy:   processor.ErrorPolicy(*errorPolicy),
+		QuarantineDir: *quarantine,
+
+		Logger:   &logger,
+		Progress: *showProgress,
+
+		DownloadConcurrency: *downloads,
+		PrefetchBytes:       *prefetchMB << 20,
+		CacheDir:            *cacheDir,
+
+		Bzip2Decoder: processor.Bzip2Decoder(*bzip2Decoder),
+		MaxLineBytes: *maxLineMB << 20,
+
+		From:        fromDate,
+		To:          toDate,
+		Include:     splitList(*include),
+		PathPattern: pathPattern,
+
+		BucketTimezone: *bucketTZ,
+
+		Partitioned:      *partition,
+		PartitionByVenue: *byVenue,
+		PerMarket:        *perMarket,
+
+		Streaming: *stream,
+
+		ReportPath:  *reportPath,
+		MarketTable: *marketTable,
+
+		ParquetCompression:  processor.ParquetCompression(*compression),
+		ParquetRowGroupSize: *rowGroupSize,
+		ParquetDictionary:   *dictionary,
+
+		DatabaseDSN:       *databaseDSN,
+		DatabaseDriver:    *dbDriver,
+		DatabaseTable:     *dbTable,
+		DatabaseBatchSize: *dbBatchSize,
+
+		LadderDepth:      *ladderDepth,
+		SnapshotInterval: *interval,
+		SnapshotWindow:   *window,
+	}
+
+	// Create market data processor
+	mp := processor.NewMarketDataProcessorWithConfig(config)
+
+	// If auto-date is enabled, generate the output path
+	finalOutputPath := *outputPath
+	if *autoDate {
+		generatedPath, err := mp.GenerateOutputPath(inputPath)
+		if err != nil {
+			log.Printf("Warning: could not auto-generate date-based path: %v", err)
+			log.Printf("Using provided output path: %s", *outputPath)
+		} else {
+			finalOutputPath = generatedPath
+			mp.OutputFile = generatedPath
+			log.Printf("Auto-generated output path: %s", generatedPath)
+		}
+	}
+
+	log.Printf("Input: %s", inputPath)
+	log.Printf("Output: %s", finalOutputPath)
+	log.Printf("Format: %s", format)
+	log.Printf("Profile: %s", mp.Profile.Name)
+	if *fileLimit > 0 {
+		log.Printf("File limit: %d", *fileLimit)
+	}
+	if *workers > 0 {
+		log.Printf("Workers: %d", *workers)
+	}
+
+	// Process the input path
+	if err := mp.ProcessPath(inputPath); err != nil {
+		log.Fatalf("Failed to process path: %v", err)
+	}
+
+	// Finalize and generate output
+	if err := mp.FinalizeProcessing(); err != nil {
+		log.Fatalf("Failed to finalize processing: %v", err)
+	}
+
+	fmt.Println("Market data processing completed successfully")
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"os"
+	"os/signal"
+	"syscall"
+
+	betfair "github.com/felixmccuaig/betfair-go"
+	"github.com/joho/godotenv"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// envFlags are the record flags that override an environment variable, for ad-hoc recordings
+// without editing the .env file
+var envFlags = []struct {
+	name, env, usage string
+}{
+	{"market-ids", "MARKET_IDS", "Comma-separated market IDs to record (overrides MARKET_IDS)"},
+	{"event-type", "EVENT_TYPE_ID", "Comma-separated event type IDs to discover markets for (overrides EVENT_TYPE_ID)"},
+	{"market-type", "MARKET_TYPE", "Comma-separated market types, such as WIN,PLACE (overrides MARKET_TYPE)"},
+	{"country", "COUNTRY_CODE", "Comma-separated country codes (overrides COUNTRY_CODE)"},
+	{"output", "OUTPUT_PATH", "Directory to record into (overrides OUTPUT_PATH)"},
+	{"s3-bucket", "S3_BUCKET", "S3 bucket to upload finished markets to (overrides S3_BUCKET)"},
+	{"heartbeat-ms", "HEARTBEAT_MS", "Stream heartbeat in milliseconds (overrides HEARTBEAT_MS)"},
+}
+
+// runRecord records market streams until interrupted. SIGHUP re-reads MARKET_IDS and
+// EVENT_TYPE_ID (including from the .env file) and resubscribes.
+func runRecord(args []string) {
+	flags := flag.NewFlagSet("record", flag.ExitOnError)
+	var (
+		envFile = flags.String("env", ".env", "File of environment variables to load before reading the configuration")
+		dryRun  = flags.Bool("dry-run", false, "Resolve markets and check the stream connects, without recording (as DRY_RUN=true)")
+	)
+	for _, f := range envFlags {
+		flags.String(f.name, "", f.usage)
+	}
+	flags.Parse(args)
+
+	overrides := make(map[string]string)
+	flags.Visit(func(f *flag.Flag) {
+		for _, envFlag := range envFlags {
+			if envFlag.name == f.Name {
+				overrides[envFlag.env] = f.Value.String()
+			}
+		}
+	})
+
+	cfg, recorder := newRecorder(*envFile, "market-recorder", overrides)
+	logger := log.With().Str("component", "market-recorder").Logger()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if *dryRun || cfg.DryRun {
+		report, err := recorder.DryRun(ctx)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("dry run failed")
+		}
+		report.Log(logger)
+		return
+	}
+
+	logger.Info().Strs("market_ids", cfg.MarketIDs).Msg("starting market recorder")
+
+	recorder.ReloadOnSignal(ctx, func() betfair.FilterUpdate {
+		if err := godotenv.Overload(*envFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+			logger.Warn().Err(err).Msg("failed to reload .env file")
+		}
+		return cfg.FilterUpdate()
+	})
+
+	if err := recorder.Run(ctx); err != nil {
+		logger.Fatal().Err(err).Msg("recorder terminated")
+	}
+}
+
+// newRecorder loads the recorder's configuration from the environment, after the variables of
+// envFile, with overrides taking precedence over both, and creates the recorder, exiting when either fails
+func newRecorder(envFile, component string, overrides map[string]string) (*betfair.Config, *betfair.MarketRecorder) {
+	// Configure logging early so configuration errors are readable
+	zerolog.SetGlobalLevel(zerolog.InfoLevel)
+	log.Logger = log.Output(os.Stderr)
+
+	if err := godotenv.Load(envFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Warn().Err(err).Str("file", envFile).Msg("failed to load .env file")
+	}
+
+	cfg := betfair.NewConfig()
+	cfg.SetOverrides(overrides)
+	if err := cfg.LoadFromEnv(); err != nil {
+		log.Fatal().Err(err).Msg("failed to load configuration")
+	}
+	logger, err := cfg.Logger()
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to set up logging")
+	}
+	zerolog.SetGlobalLevel(cfg.LogLevel)
+	log.Logger = logger
+	useLogger(logger)
+	if cfg.Environment != "" {
+		log.Info().Str("environment", cfg.Environment).Msg("using environment settings")
+	}
+
+	recorder, err := betfair.NewMarketRecorder(cfg, log.With().Str("component", component).Logger())
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to create market recorder")
+	}
+	return cfg, recorder
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/felixmccuaig/betfair-go/processor"
+)
+
+// runVerify checks the integrity of recorded files and writes a JSON report of each, exiting 1 when
+// any file fails a check
+func runVerify(args []string) {
+	flags := flag.NewFlagSet("verify", flag.ExitOnError)
+	var (
+		s3Path     = flags.String("s3", "", "S3 path to verify (e.g., s3://bucket/prefix/)")
+		localPath  = flags.String("path", "", "Local file or directory path to verify")
+		reportPath = flags.String("report", "", "Write the JSON report to this file (default stdout)")
+		workers    = flags.Int("workers", 0, "Number of files checked at once (0 = use CPU count)")
+		maxLineMB  = flags.Int("max-line-mb", 16, "Longest message line in MiB; longer lines are counted as bad")
+		cacheDir   = flags.String("cache-dir", "", "Keep remote inputs in this directory by ETag so re-verifying them skips the download")
+	)
+	newLogger := logFlags(flags, "info")
+	flags.Parse(args)
+
+	if (*s3Path == "") == (*localPath == "") {
+		log.Fatal("Please specify one of -s3 or -path")
+	}
+	inputPath := *s3Path
+	if inputPath == "" {
+		inputPath = *localPath
+	}
+
+	logger := newLogger()
+	mp := processor.NewMarketDataProcessorWithConfig(processor.ProcessorConfig{
+		Workers:      *workers,
+		MaxLineBytes: *maxLineMB << 20,
+		CacheDir:     *cacheDir,
+		Logger:       &logger,
+	})
+	report, err := mp.Verify(inputPath)
+	if err != nil {
+		log.Fatalf("Failed to verify path: %v", err)
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		log.Fatalf("Failed to encode report: %v", err)
+	}
+	if *reportPath == "" {
+		fmt.Println(string(data))
+	} else if err := os.WriteFile(*reportPath, data, 0644); err != nil {
+		log.Fatalf("Failed to write report: %v", err)
+	}
+	if report.Failed > 0 {
+		os.Exit(1)
+	}
+}
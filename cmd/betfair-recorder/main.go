@@ -3,9 +3,12 @@ package main
 import (
 	"context"
 	"errors"
+	stdlog "log"
 	"os"
 	"os/signal"
 	"syscall"
+	// Venue timezones for PARTITION_TIMEZONE=venue, even in images without zoneinfo
+	_ "time/tzdata"
 
 	betfair "github.com/felixmccuaig/betfair-go"
 	"github.com/joho/godotenv"
@@ -26,6 +29,14 @@ func main() {
 	if err := cfg.LoadFromEnv(); err != nil {
 		log.Fatal().Err(err).Msg("failed to load configuration")
 	}
+	configuredLogger, err := cfg.Logger()
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to set up logging")
+	}
+	zerolog.SetGlobalLevel(cfg.LogLevel)
+	log.Logger = configuredLogger
+	stdlog.SetFlags(0)
+	stdlog.SetOutput(configuredLogger)
 
 	logger := log.With().Str("component", "market-recorder").Logger()
 
@@ -37,8 +48,25 @@ func main() {
 		logger.Fatal().Err(err).Msg("failed to create market recorder")
 	}
 
+	if cfg.DryRun {
+		report, err := recorder.DryRun(ctx)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("dry run failed")
+		}
+		report.Log(logger)
+		return
+	}
+
 	logger.Info().Strs("market_ids", cfg.MarketIDs).Msg("starting market recorder")
 
+	// SIGHUP re-reads MARKET_IDS and EVENT_TYPE_ID (including from .env) and resubscribes
+	recorder.ReloadOnSignal(ctx, func() betfair.FilterUpdate {
+		if err := godotenv.Overload(); err != nil && !errors.Is(err, os.ErrNotExist) {
+			logger.Warn().Err(err).Msg("failed to reload .env file")
+		}
+		return cfg.FilterUpdate()
+	})
+
 	if err := recorder.Run(ctx); err != nil {
 		logger.Fatal().Err(err).Msg("recorder terminated")
 	}
@@ -0,0 +1,160 @@
+package betfair
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/dsnet/compress/bzip2"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression is the format settled market files are compressed with
+type Compression string
+
+const (
+	CompressionBzip2 Compression = "bzip2"
+	CompressionGzip  Compression = "gzip"
+	CompressionZstd  Compression = "zstd"
+)
+
+// ParseCompression parses "bzip2", "gzip" or "zstd"
+func ParseCompression(value string) (Compression, error) {
+	compression := Compression(strings.ToLower(strings.TrimSpace(value)))
+	switch compression {
+	case CompressionBzip2, CompressionGzip, CompressionZstd:
+		return compression, nil
+	case "bz2":
+		return CompressionBzip2, nil
+	case "gz":
+		return CompressionGzip, nil
+	case "zst":
+		return CompressionZstd, nil
+	}
+	return "", fmt.Errorf("unknown compression %q", value)
+}
+
+// Extension returns the file suffix for the format, including the dot
+func (c Compression) Extension() string {
+	switch c {
+	case CompressionGzip:
+		return ".gz"
+	case CompressionZstd:
+		return ".zst"
+	}
+	return ".bz2"
+}
+
+var errStreamCompressionUnsupported = errors.New("streaming compression needs gzip or zstd")
+
+// streamCompressor is a compressing writer that can push everything written so far through to
+// the underlying file, so a streamed market file stays readable up to the last flush
+type streamCompressor interface {
+	io.WriteCloser
+	Flush() error
+}
+
+// newStreamCompressor wraps w in a flushable compressing writer; bzip2 cannot be flushed mid-stream
+func newStreamCompressor(w io.Writer, compression Compression, level int) (streamCompressor, error) {
+	if compression != CompressionGzip && compression != CompressionZstd {
+		return nil, errStreamCompressionUnsupported
+	}
+	compressor, err := newCompressor(w, compression, level)
+	if err != nil {
+		return nil, err
+	}
+	return compressor.(streamCompressor), nil
+}
+
+// newDecompressor reads a file written in the given format. Concatenated gzip members and zstd
+// frames, as left by appending to a streamed file, are read as one stream.
+func newDecompressor(r io.Reader, compression Compression) (io.Reader, error) {
+	switch compression {
+	case CompressionGzip:
+		return gzip.NewReader(r)
+	case CompressionZstd:
+		return zstd.NewReader(r)
+	case CompressionBzip2, "":
+		return bzip2.NewReader(r, nil)
+	}
+	return nil, fmt.Errorf("unknown compression %q", compression)
+}
+
+// newCompressor wraps w in a compressing writer. Level zero picks the format's default; otherwise
+// it is the format's native level (bzip2 and gzip 1-9, zstd 1-4 from fastest to best).
+func newCompressor(w io.Writer, compression Compression, level int) (io.WriteCloser, error) {
+	switch compression {
+	case CompressionGzip:
+		if level == 0 {
+			level = gzip.DefaultCompression
+		}
+		return gzip.NewWriterLevel(w, level)
+	case CompressionZstd:
+		encoderLevel := zstd.SpeedDefault
+		if level != 0 {
+			encoderLevel = zstd.EncoderLevel(level)
+		}
+		return zstd.NewWriter(w, zstd.WithEncoderLevel(encoderLevel))
+	case CompressionBzip2, "":
+		if level == 0 {
+			level = bzip2.DefaultCompression
+		}
+		return bzip2.NewWriter(w, &bzip2.WriterConfig{Level: level})
+	}
+	return nil, fmt.Errorf("unknown compression %q", compression)
+}
+
+// compressFile writes a compressed copy of inputFile to outputFile. The output is written to a
+// temporary file and renamed into place once complete, so a crash never leaves a truncated archive
+// that could be mistaken for a finished one.
+func compressFile(inputFile, outputFile string, compression Compression, level int) error {
+	input, err := os.Open(inputFile)
+	if err != nil {
+		return fmt.Errorf("open input file: %w", err)
+	}
+	defer input.Close()
+
+	tmpFile := outputFile + ".tmp"
+	output, err := os.Create(tmpFile)
+	if err != nil {
+		return fmt.Errorf("create output file: %w", err)
+	}
+
+	if err := writeCompressed(output, input, compression, level); err != nil {
+		output.Close()
+		os.Remove(tmpFile)
+		return err
+	}
+	if err := output.Close(); err != nil {
+		os.Remove(tmpFile)
+		return fmt.Errorf("close output file: %w", err)
+	}
+	if err := os.Rename(tmpFile, outputFile); err != nil {
+		os.Remove(tmpFile)
+		return fmt.Errorf("rename output file: %w", err)
+	}
+	return nil
+}
+
+// writeCompressed compresses input into output and syncs it to disk
+func writeCompressed(output *os.File, input io.Reader, compression Compression, level int) error {
+	compressor, err := newCompressor(output, compression, level)
+	if err != nil {
+		return fmt.Errorf("create %s writer: %w", compression, err)
+	}
+
+	if _, err := io.Copy(compressor, input); err != nil {
+		compressor.Close()
+		return fmt.Errorf("compress data: %w", err)
+	}
+	if err := compressor.Close(); err != nil {
+		return fmt.Errorf("finish %s stream: %w", compression, err)
+	}
+	if err := output.Sync(); err != nil {
+		return fmt.Errorf("sync output file: %w", err)
+	}
+	return nil
+}
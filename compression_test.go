@@ -0,0 +1,165 @@
+package betfair
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dsnet/compress/bzip2"
+	"github.com/klauspost/compress/zstd"
+	"github.com/rs/zerolog"
+)
+
+func TestParseCompression(t *testing.T) {
+	for input, want := range map[string]Compression{"bzip2": CompressionBzip2, "GZ": CompressionGzip, " zstd ": CompressionZstd, "zst": CompressionZstd} {
+		got, err := ParseCompression(input)
+		if err != nil || got != want {
+			t.Errorf("ParseCompression(%q) = %q, %v; want %q", input, got, err, want)
+		}
+	}
+	if _, err := ParseCompression("lz4"); err == nil {
+		t.Error("Expected unsupported compression to fail")
+	}
+}
+
+func TestFileManagerCompressFile(t *testing.T) {
+	content := strings.Repeat(`{"op":"mcm","mc":[{"rc":[{"id":1,"ltp":3.5}]}]}`+"\n", 100)
+
+	readers := map[Compression]func(io.Reader) (io.Reader, error){
+		CompressionBzip2: func(r io.Reader) (io.Reader, error) { return bzip2.NewReader(r, nil) },
+		CompressionGzip:  func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+		CompressionZstd:  func(r io.Reader) (io.Reader, error) { return zstd.NewReader(r) },
+	}
+
+	for compression, newReader := range readers {
+		t.Run(string(compression), func(t *testing.T) {
+			tempDir := t.TempDir()
+			fm := NewFileManager(tempDir)
+			fm.SetCompression(compression, 0)
+
+			input := fm.GetMarketFilePath("1.1")
+			if err := os.WriteFile(input, []byte(content), 0644); err != nil {
+				t.Fatalf("write input: %v", err)
+			}
+
+			output := fm.GetCompressedFilePath("1.1")
+			if filepath.Ext(output) != compression.Extension() {
+				t.Errorf("Expected %s suffix, got %s", compression.Extension(), output)
+			}
+			if err := fm.CompressFile(input, output); err != nil {
+				t.Fatalf("CompressFile failed: %v", err)
+			}
+
+			data, err := os.ReadFile(output)
+			if err != nil {
+				t.Fatalf("read output: %v", err)
+			}
+			reader, err := newReader(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("open %s stream: %v", compression, err)
+			}
+			decoded, err := io.ReadAll(reader)
+			if err != nil {
+				t.Fatalf("decode %s stream: %v", compression, err)
+			}
+			if string(decoded) != content {
+				t.Error("Round-tripped content does not match")
+			}
+		})
+	}
+}
+
+func TestCompressFileLeavesNoPartialOutput(t *testing.T) {
+	tempDir := t.TempDir()
+	input := filepath.Join(tempDir, "1.1")
+	if err := os.WriteFile(input, []byte("data\n"), 0644); err != nil {
+		t.Fatalf("write input: %v", err)
+	}
+	output := input + ".gz"
+	if err := os.WriteFile(output, []byte("previous"), 0644); err != nil {
+		t.Fatalf("write output: %v", err)
+	}
+
+	// gzip rejects levels above 9, failing after the temporary file is created
+	if err := compressFile(input, output, CompressionGzip, 42); err == nil {
+		t.Fatal("Expected invalid level to fail")
+	}
+	if data, err := os.ReadFile(output); err != nil || string(data) != "previous" {
+		t.Errorf("Expected existing output to be left untouched, got %q, %v", data, err)
+	}
+	if _, err := os.Stat(output + ".tmp"); !os.IsNotExist(err) {
+		t.Error("Expected temporary file to be removed")
+	}
+
+	if err := compressFile(input, output, CompressionGzip, 0); err != nil {
+		t.Fatalf("compressFile failed: %v", err)
+	}
+	if _, err := os.Stat(output + ".tmp"); !os.IsNotExist(err) {
+		t.Error("Expected temporary file to be renamed into place")
+	}
+}
+
+func TestMarketRecorderStreamCompression(t *testing.T) {
+	tempDir := t.TempDir()
+	fileManager := NewFileManager(tempDir)
+	fileManager.SetCompression(CompressionGzip, 0)
+	fileManager.SetStreamCompression(true)
+
+	recorder := &MarketRecorder{
+		config:           &Config{EnrichmentMode: EnrichmentOff},
+		logger:           zerolog.New(zerolog.NewTestWriter(t)),
+		fileManager:      fileManager,
+		marketCatalogues: make(map[string]*MarketCatalogue),
+	}
+
+	messages := []string{
+		`{"op":"mcm","pt":1,"mc":[{"id":"1.1","marketDefinition":{"status":"OPEN","eventId":"100","openDate":"2025-10-01T12:00:00.000Z"}}]}`,
+		`{"op":"mcm","pt":2,"mc":[{"id":"1.1","rc":[{"id":7,"ltp":3.5}]}]}`,
+	}
+	stream := &StreamConn{reader: bufio.NewReader(strings.NewReader(strings.Join(messages, "\n") + "\n"))}
+	writers := make(map[string]*bufio.Writer)
+	files := make(map[string]*os.File)
+	for range messages {
+		if err := recorder.readMessage(context.Background(), stream, writers, files, make(map[string]string)); err != nil {
+			t.Fatalf("readMessage failed: %v", err)
+		}
+	}
+
+	if fileManager.MarketFileExists("1.1") {
+		t.Error("Expected no uncompressed market file while streaming")
+	}
+
+	recorder.stopMarketWriters()
+
+	definition, err := fileManager.LastMarketDefinition("1.1")
+	if err != nil {
+		t.Fatalf("LastMarketDefinition failed: %v", err)
+	}
+	if !strings.Contains(string(definition), `"eventId":"100"`) {
+		t.Errorf("Unexpected definition %s", definition)
+	}
+
+	// Settlement hands over the streamed file as the archive, renamed when incomplete
+	if err := recorder.archiveMarketFile(context.Background(), "1.1", definition, true, nil, marketCounts{}); err != nil {
+		t.Fatalf("archiveMarketFile failed: %v", err)
+	}
+	archive := fileManager.GetCompressedFilePath("1.1" + IncompleteSuffix)
+	data, err := os.ReadFile(archive)
+	if err != nil {
+		t.Fatalf("Expected incomplete archive: %v", err)
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("open archive: %v", err)
+	}
+	decoded, _ := io.ReadAll(reader)
+	if lines := strings.Split(strings.TrimSpace(string(decoded)), "\n"); len(lines) != 2 {
+		t.Errorf("Expected 2 recorded lines, got %d", len(lines))
+	}
+}
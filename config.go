@@ -1,97 +1,568 @@
 package betfair
 
 import (
-	"os"
+	"errors"
+	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 )
 
 type Config struct {
 	AppKey       string
-	SessionToken string
+	SessionToken string // The token at startup; the recorder's TokenStore holds the current one
+	password     string
 	MarketIDs    []string
-	EventTypeID  string
-	CountryCode  string
-	MarketType   string
+	EventTypeID  string // Comma-separated, such as 4339,7 for greyhounds and horses
+	CountryCode  string // Comma-separated
+	MarketType   string // Comma-separated, such as WIN,PLACE
 	OutputPath   string
 	S3Bucket     string
 	S3BasePath   string
+	StorageURL   string
+	S3Upload     S3UploadOptions
+	S3ObjectTags bool
 	HeartbeatMs  int
+	Jurisdiction Jurisdiction
+	StreamHost   string // Overrides the jurisdiction's stream host, such as BetfairIntegrationStreamHost
+	Environment  string
+	Locale       string // Of catalogue names, such as en
+	CurrencyCode string // Of market book amounts, such as AUD; empty is the account's currency
+	LogLevel     zerolog.Level
+	LogFormat    LogFormat
+	LogFile      string
+
+	DiscoveryInterval   time.Duration
+	DiscoveryLookahead  time.Duration
+	MarketStartWindow   StartWindow
+	KeepAliveInterval   time.Duration
+	CancelAllOnShutdown bool
+	ValidateAppKey      bool
+	DryRun              bool
+	AppKeyDelayed       bool
+
+	CheckpointPath  string
+	CheckpointEvery int
+
+	ExistingFilePolicy ExistingFilePolicy
+	Compression        Compression
+	CompressionLevel   int
+	StreamCompression  bool
+	OutputLayout       OutputLayout
+	Partition          PartitionScheme
+
+	RecordingMode    RecordingMode
+	EnrichmentMode   EnrichmentMode
+	EnrichmentFields []EnrichmentField
+	CatalogueWorkers int
+	CatalogueWait    time.Duration
+	WriterQueueSize  int
+
+	FlushInterval      time.Duration
+	FlushEveryMessages int
+	FsyncOnSettle      bool
+
+	IdleMarketTimeout time.Duration
+	RecordTimeline    bool
+	SessionLog        bool
+
+	DiskUsageThreshold float64
+	DiskCheckInterval  time.Duration
+
+	RetentionPeriod time.Duration
+	RetentionMode   RetentionMode
+
+	// MaxConcurrentMarkets caps how many markets are recorded at once. Recording markets keep their
+	// slot until they close; free slots go to the discovered markets starting soonest, and explicit
+	// MARKET_IDS are admitted in the order they are listed, the rest waiting for a free slot.
+	MaxConcurrentMarkets int
+	RecordBeforeStart    time.Duration
+	StopAfterSettlement  bool
+
+	RotateBytes    int64
+	RotateInterval time.Duration
+	RecordingIndex bool
+
+	Profiles []RecordingProfile
+
+	AppKeySecret          SecretSource
+	PasswordSecret        SecretSource
+	SecretRefreshInterval time.Duration
+
+	overrides map[string]string
 }
 
 func NewConfig() *Config {
 	return &Config{}
 }
 
+// LoadFromEnv reads the configuration from the environment, logging in with BETFAIR_USERNAME and
+// BETFAIR_PASSWORD when no session token is set. Every missing or invalid setting is returned in a
+// *ValidationError, before any login is attempted.
 func (c *Config) LoadFromEnv() error {
-	c.AppKey = strings.TrimSpace(os.Getenv("BETFAIR_APP_KEY"))
-	username := strings.TrimSpace(os.Getenv("BETFAIR_USERNAME"))
-	password := strings.TrimSpace(os.Getenv("BETFAIR_PASSWORD"))
-	c.SessionToken = strings.TrimSpace(os.Getenv("BETFAIR_SESSION_TOKEN"))
-	c.S3Bucket = strings.TrimSpace(os.Getenv("S3_BUCKET"))
-	c.S3BasePath = strings.TrimSpace(os.Getenv("S3_BASE_PATH"))
-
-	markets := strings.TrimSpace(os.Getenv("MARKET_IDS"))
-	c.EventTypeID = strings.TrimSpace(os.Getenv("EVENT_TYPE_ID"))
-	c.CountryCode = strings.TrimSpace(os.Getenv("COUNTRY_CODE"))
-	c.MarketType = strings.TrimSpace(os.Getenv("MARKET_TYPE"))
-	c.OutputPath = strings.TrimSpace(os.Getenv("OUTPUT_PATH"))
+	problems := &ValidationError{}
+
+	c.Environment = Environment()
+	c.LogLevel = zerolog.InfoLevel
+	if v := strings.TrimSpace(c.getenv("LOG_LEVEL")); v != "" {
+		level, err := zerolog.ParseLevel(strings.ToLower(v))
+		if err != nil {
+			problems.add("LOG_LEVEL", err)
+		}
+		c.LogLevel = level
+	}
+	c.LogFormat = DefaultLogFormat
+	if v := strings.TrimSpace(c.getenv("LOG_FORMAT")); v != "" {
+		format, err := ParseLogFormat(v)
+		if err != nil {
+			problems.add("LOG_FORMAT", err)
+		}
+		c.LogFormat = format
+	}
+	c.LogFile = strings.TrimSpace(c.getenv("LOG_FILE"))
+
+	c.AppKey = strings.TrimSpace(c.getenv("BETFAIR_APP_KEY"))
+	username := strings.TrimSpace(c.getenv("BETFAIR_USERNAME"))
+	password := strings.TrimSpace(c.getenv("BETFAIR_PASSWORD"))
+	c.SessionToken = strings.TrimSpace(c.getenv("BETFAIR_SESSION_TOKEN"))
+	c.S3Bucket = strings.TrimSpace(c.getenv("S3_BUCKET"))
+	c.S3BasePath = strings.TrimSpace(c.getenv("S3_BASE_PATH"))
+	c.StorageURL = strings.TrimSpace(c.getenv("STORAGE_URL"))
+
+	uploadOptions, err := ParseS3UploadOptions(c.getenv("S3_SSE"), c.getenv("S3_SSE_KMS_KEY_ID"), c.getenv("S3_STORAGE_CLASS"))
+	if err != nil {
+		problems.add("S3_SSE, S3_SSE_KMS_KEY_ID, S3_STORAGE_CLASS", err)
+	}
+	c.S3Upload = uploadOptions
+
+	if ref := strings.TrimSpace(c.getenv("BETFAIR_APP_KEY_SECRET")); ref != "" {
+		if c.AppKeySecret, c.AppKey, err = loadSecret(ref); err != nil {
+			problems.add("BETFAIR_APP_KEY_SECRET", err)
+		}
+	}
+	if ref := strings.TrimSpace(c.getenv("BETFAIR_PASSWORD_SECRET")); ref != "" {
+		if c.PasswordSecret, password, err = loadSecret(ref); err != nil {
+			problems.add("BETFAIR_PASSWORD_SECRET", err)
+		}
+	}
+	c.password = password
+	c.SecretRefreshInterval = DefaultSecretRefreshInterval
+	if parsed, ok := c.intSetting(problems, "SECRET_REFRESH_MINUTES", 1); ok {
+		c.SecretRefreshInterval = time.Duration(parsed) * time.Minute
+	}
+
+	if parsed, ok := c.boolSetting(problems, "S3_OBJECT_TAGS"); ok {
+		c.S3ObjectTags = parsed
+	}
+
+	markets := strings.TrimSpace(c.getenv("MARKET_IDS"))
+	c.EventTypeID = strings.TrimSpace(c.getenv("EVENT_TYPE_ID"))
+	c.CountryCode = strings.TrimSpace(c.getenv("COUNTRY_CODE"))
+	c.MarketType = strings.TrimSpace(c.getenv("MARKET_TYPE"))
+	c.OutputPath = strings.TrimSpace(c.getenv("OUTPUT_PATH"))
 
 	c.HeartbeatMs = 5000
-	if h := strings.TrimSpace(os.Getenv("HEARTBEAT_MS")); h != "" {
-		if parsed, err := strconv.Atoi(h); err == nil && parsed > 0 {
-			c.HeartbeatMs = parsed
+	if parsed, ok := c.intSetting(problems, "HEARTBEAT_MS", 1); ok {
+		c.HeartbeatMs = parsed
+	}
+
+	if parsed, ok := c.intSetting(problems, "DISCOVERY_INTERVAL_SECONDS", 1); ok {
+		c.DiscoveryInterval = time.Duration(parsed) * time.Second
+	}
+
+	c.DiscoveryLookahead = DefaultDiscoveryLookahead
+	if parsed, ok := c.intSetting(problems, "DISCOVERY_LOOKAHEAD_MINUTES", 1); ok {
+		c.DiscoveryLookahead = time.Duration(parsed) * time.Minute
+	}
+
+	if c.MarketStartWindow.From, err = ParseStartTimeBound(c.getenv("MARKET_START_FROM")); err != nil {
+		problems.add("MARKET_START_FROM", err)
+	}
+	if c.MarketStartWindow.To, err = ParseStartTimeBound(c.getenv("MARKET_START_TO")); err != nil {
+		problems.add("MARKET_START_TO", err)
+	}
+	if c.MarketStartWindow.IsSet() && c.DiscoveryInterval == 0 {
+		c.DiscoveryInterval = DefaultStartWindowDiscoveryInterval
+	}
+
+	c.KeepAliveInterval = DefaultKeepAliveInterval
+	if parsed, ok := c.intSetting(problems, "SESSION_KEEPALIVE_MINUTES", 0); ok {
+		c.KeepAliveInterval = time.Duration(parsed) * time.Minute
+	}
+
+	if parsed, ok := c.boolSetting(problems, "CANCEL_ALL_ON_SHUTDOWN"); ok {
+		c.CancelAllOnShutdown = parsed
+	}
+
+	if j := strings.TrimSpace(c.getenv("BETFAIR_JURISDICTION")); j != "" {
+		jurisdiction, err := ParseJurisdiction(j)
+		if err != nil {
+			problems.add("BETFAIR_JURISDICTION", err)
+		}
+		c.Jurisdiction = jurisdiction
+	}
+	c.StreamHost = strings.TrimSpace(c.getenv("STREAM_HOST"))
+
+	c.Locale = DefaultLocale
+	if v := strings.TrimSpace(c.getenv("LOCALE")); v != "" {
+		c.Locale = v
+	}
+	if v := strings.TrimSpace(c.getenv("CURRENCY_CODE")); v != "" {
+		currency, err := ParseCurrencyCode(v)
+		if err != nil {
+			problems.add("CURRENCY_CODE", err)
+		}
+		c.CurrencyCode = currency
+	}
+
+	c.CheckpointPath = strings.TrimSpace(c.getenv("CHECKPOINT_PATH"))
+	c.CheckpointEvery = DefaultCheckpointEvery
+	if parsed, ok := c.intSetting(problems, "CHECKPOINT_EVERY_MESSAGES", 1); ok {
+		c.CheckpointEvery = parsed
+	}
+
+	c.ExistingFilePolicy = ExistingFileAppend
+	if v := strings.TrimSpace(c.getenv("EXISTING_FILE_POLICY")); v != "" {
+		policy, err := ParseExistingFilePolicy(v)
+		if err != nil {
+			problems.add("EXISTING_FILE_POLICY", err)
+		}
+		c.ExistingFilePolicy = policy
+	}
+
+	if v := strings.TrimSpace(c.getenv("COMPRESSION")); v != "" {
+		compression, err := ParseCompression(v)
+		if err != nil {
+			problems.add("COMPRESSION", err)
+		}
+		c.Compression = compression
+	}
+
+	if parsed, ok := c.intSetting(problems, "COMPRESSION_LEVEL", 1); ok {
+		c.CompressionLevel = parsed
+	}
+
+	if parsed, ok := c.boolSetting(problems, "STREAM_COMPRESSION"); ok {
+		c.StreamCompression = parsed
+	}
+	if c.StreamCompression && c.Compression != CompressionGzip && c.Compression != CompressionZstd {
+		problems.add("STREAM_COMPRESSION", errStreamCompressionUnsupported)
+	}
+
+	if v := strings.TrimSpace(c.getenv("OUTPUT_LAYOUT")); v != "" {
+		layout, err := ParseOutputLayout(v)
+		if err != nil {
+			problems.add("OUTPUT_LAYOUT", err)
+		}
+		c.OutputLayout = layout
+	}
+
+	partition, err := ParsePartitionScheme(c.getenv("PARTITION_MONTH_FORMAT"), c.getenv("PARTITION_DATE"), c.getenv("PARTITION_TIMEZONE"))
+	if err != nil {
+		problems.add("PARTITION_MONTH_FORMAT, PARTITION_DATE, PARTITION_TIMEZONE", err)
+	}
+	if partition.Layout, err = ParseLayoutVersion(c.getenv("LAYOUT_VERSION")); err != nil {
+		problems.add("LAYOUT_VERSION", err)
+	}
+	c.Partition = partition
+
+	if v := strings.TrimSpace(c.getenv("RECORDING_MODE")); v != "" {
+		mode, err := ParseRecordingMode(v)
+		if err != nil {
+			problems.add("RECORDING_MODE", err)
 		}
+		c.RecordingMode = mode
+	}
+
+	if v := strings.TrimSpace(c.getenv("ENRICHMENT_MODE")); v != "" {
+		mode, err := ParseEnrichmentMode(v)
+		if err != nil {
+			problems.add("ENRICHMENT_MODE", err)
+		}
+		c.EnrichmentMode = mode
+	}
+
+	if v := strings.TrimSpace(c.getenv("ENRICHMENT_FIELDS")); v != "" {
+		fields, err := ParseEnrichmentFields(v)
+		if err != nil {
+			problems.add("ENRICHMENT_FIELDS", err)
+		}
+		c.EnrichmentFields = fields
+	}
+
+	c.CatalogueWorkers = DefaultCatalogueWorkers
+	if parsed, ok := c.intSetting(problems, "CATALOGUE_WORKERS", 1); ok {
+		c.CatalogueWorkers = parsed
+	}
+
+	c.CatalogueWait = DefaultCatalogueWait
+	if parsed, ok := c.intSetting(problems, "CATALOGUE_WAIT_SECONDS", 1); ok {
+		c.CatalogueWait = time.Duration(parsed) * time.Second
+	}
+
+	c.WriterQueueSize = DefaultWriterQueueSize
+	if parsed, ok := c.intSetting(problems, "WRITER_QUEUE_SIZE", 1); ok {
+		c.WriterQueueSize = parsed
+	}
+
+	if parsed, ok := c.intSetting(problems, "FLUSH_INTERVAL_MS", 1); ok {
+		c.FlushInterval = time.Duration(parsed) * time.Millisecond
+	}
+
+	if parsed, ok := c.intSetting(problems, "FLUSH_EVERY_MESSAGES", 1); ok {
+		c.FlushEveryMessages = parsed
+	}
+	// Flushing a gzip or zstd encoder ends a block, so flushing every message ruins the ratio
+	if c.StreamCompression && c.FlushInterval == 0 && c.FlushEveryMessages == 0 {
+		c.FlushInterval = DefaultStreamFlushInterval
+	}
+
+	if parsed, ok := c.boolSetting(problems, "FSYNC_ON_SETTLE"); ok {
+		c.FsyncOnSettle = parsed
+	}
+
+	if parsed, ok := c.intSetting(problems, "IDLE_MARKET_TIMEOUT_MINUTES", 1); ok {
+		c.IdleMarketTimeout = time.Duration(parsed) * time.Minute
+	}
+
+	c.ValidateAppKey = true
+	if parsed, ok := c.boolSetting(problems, "VALIDATE_APP_KEY"); ok {
+		c.ValidateAppKey = parsed
+	}
+
+	if parsed, ok := c.boolSetting(problems, "DRY_RUN"); ok {
+		c.DryRun = parsed
 	}
 
 	if c.AppKey == "" {
-		log.Fatal().Msg("BETFAIR_APP_KEY environment variable is required")
+		problems.add("BETFAIR_APP_KEY", errRequired)
 	}
 
-	if c.SessionToken == "" {
-		if username == "" || password == "" {
-			log.Fatal().Msg("BETFAIR_USERNAME and BETFAIR_PASSWORD must be set or provide BETFAIR_SESSION_TOKEN")
+	if c.SessionToken == "" && (username == "" || password == "") {
+		problems.add("BETFAIR_SESSION_TOKEN", errors.New("required unless BETFAIR_USERNAME and BETFAIR_PASSWORD are set"))
+	}
+
+	if parsed, ok := c.boolSetting(problems, "RECORD_STATUS_TIMELINE"); ok {
+		c.RecordTimeline = parsed
+	}
+
+	if parsed, ok := c.boolSetting(problems, "SESSION_LOG"); ok {
+		c.SessionLog = parsed
+	}
+
+	if v := strings.TrimSpace(c.getenv("DISK_USAGE_THRESHOLD")); v != "" {
+		parsed, err := strconv.ParseFloat(v, 64)
+		if err != nil || parsed <= 0 || parsed > 1 {
+			problems.add("DISK_USAGE_THRESHOLD", fmt.Errorf("%q is not a fraction between 0 and 1", v))
+		} else {
+			c.DiskUsageThreshold = parsed
 		}
-		auth := NewAuthenticator(c.AppKey, username, password)
-		var err error
-		c.SessionToken, err = auth.Login()
+	}
+
+	c.DiskCheckInterval = DefaultDiskCheckInterval
+	if parsed, ok := c.intSetting(problems, "DISK_CHECK_INTERVAL_SECONDS", 1); ok {
+		c.DiskCheckInterval = time.Duration(parsed) * time.Second
+	}
+
+	if parsed, ok := c.intSetting(problems, "RETENTION_DAYS", 1); ok {
+		c.RetentionPeriod = time.Duration(parsed) * 24 * time.Hour
+	}
+
+	if v := strings.TrimSpace(c.getenv("RETENTION_MODE")); v != "" {
+		mode, err := ParseRetentionMode(v)
 		if err != nil {
-			log.Fatal().Err(err).Msg("interactive Betfair login failed")
+			problems.add("RETENTION_MODE", err)
 		}
-		log.Info().Msg("obtained session token via interactive login")
+		c.RetentionMode = mode
+	}
+
+	if parsed, ok := c.intSetting(problems, "MAX_CONCURRENT_MARKETS", 1); ok {
+		c.MaxConcurrentMarkets = parsed
 	}
 
-	_ = os.Setenv("BETFAIR_SESSION_TOKEN", c.SessionToken)
+	if parsed, ok := c.intSetting(problems, "RECORD_FROM_MINUTES_BEFORE_START", 1); ok {
+		c.RecordBeforeStart = time.Duration(parsed) * time.Minute
+	}
+
+	c.StopAfterSettlement = true
+	if parsed, ok := c.boolSetting(problems, "STOP_AFTER_SETTLEMENT"); ok {
+		c.StopAfterSettlement = parsed
+	}
+
+	if parsed, ok := c.intSetting(problems, "ROTATE_SIZE_MB", 1); ok {
+		c.RotateBytes = int64(parsed) << 20
+	}
+
+	if parsed, ok := c.intSetting(problems, "ROTATE_INTERVAL_MINUTES", 1); ok {
+		c.RotateInterval = time.Duration(parsed) * time.Minute
+	}
+
+	if parsed, ok := c.boolSetting(problems, "RECORDING_INDEX"); ok {
+		c.RecordingIndex = parsed
+	}
+
+	if v := strings.TrimSpace(c.getenv("RECORDING_PROFILES")); v != "" {
+		profiles, err := ParseRecordingProfiles(v)
+		if err != nil {
+			problems.add("RECORDING_PROFILES", err)
+		}
+		c.Profiles = profiles
+	}
 
 	if markets != "" {
 		c.MarketIDs = splitAndClean(markets)
-	} else if c.EventTypeID == "" {
-		log.Fatal().Msg("either MARKET_IDS or EVENT_TYPE_ID environment variable must be provided")
+	} else if c.EventTypeID == "" && len(c.Profiles) == 0 {
+		problems.add("MARKET_IDS", errors.New("MARKET_IDS, EVENT_TYPE_ID or RECORDING_PROFILES must be set"))
 	}
 
 	if c.HeartbeatMs <= 0 {
 		c.HeartbeatMs = 5000
 	}
 
+	if len(problems.Problems) > 0 {
+		return problems
+	}
+
+	if c.SessionToken == "" {
+		auth := NewAuthenticator(c.AppKey, username, password)
+		if c.Jurisdiction != "" || c.StreamHost != "" {
+			auth.SetEndpoints(c.Endpoints())
+		}
+		var err error
+		c.SessionToken, err = auth.Login()
+		if err != nil {
+			return fmt.Errorf("interactive Betfair login failed: %w", err)
+		}
+		log.Info().Msg("obtained session token via interactive login")
+	}
+
 	return nil
 }
 
+// intSetting reads a whole-number setting of at least least, reporting any other value. It returns
+// false when the setting is unset or invalid, so the default stays in place.
+func (c *Config) intSetting(problems *ValidationError, key string, least int) (int, bool) {
+	v := strings.TrimSpace(c.getenv(key))
+	if v == "" {
+		return 0, false
+	}
+	parsed, err := strconv.Atoi(v)
+	if err != nil {
+		problems.add(key, fmt.Errorf("%q is not a whole number", v))
+		return 0, false
+	}
+	if parsed < least {
+		problems.add(key, fmt.Errorf("must be at least %d, got %d", least, parsed))
+		return 0, false
+	}
+	return parsed, true
+}
+
+// boolSetting reads a true/false setting, reporting any other value. It returns false when the
+// setting is unset or invalid, so the default stays in place.
+func (c *Config) boolSetting(problems *ValidationError, key string) (bool, bool) {
+	v := strings.TrimSpace(c.getenv(key))
+	if v == "" {
+		return false, false
+	}
+	parsed, err := strconv.ParseBool(v)
+	if err != nil {
+		problems.add(key, fmt.Errorf("%q is not true or false", v))
+		return false, false
+	}
+	return parsed, true
+}
+
+// errRequired is the problem with a required setting that isn't set
+var errRequired = errors.New("required")
+
+// SettingError is a missing or invalid setting
+type SettingError struct {
+	Setting string // The environment variable, or the variables read together
+	Err     error
+}
+
+func (e *SettingError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Setting, e.Err)
+}
+
+func (e *SettingError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationError is returned by LoadFromEnv with every missing or invalid setting, so they can
+// all be fixed at once
+type ValidationError struct {
+	Problems []*SettingError
+}
+
+func (e *ValidationError) add(setting string, err error) {
+	e.Problems = append(e.Problems, &SettingError{Setting: setting, Err: err})
+}
+
+func (e *ValidationError) Error() string {
+	problems := make([]string, len(e.Problems))
+	for i, problem := range e.Problems {
+		problems[i] = problem.Error()
+	}
+	return "invalid configuration: " + strings.Join(problems, "; ")
+}
+
+// Unwrap lets errors.Is and errors.As match the error of any setting
+func (e *ValidationError) Unwrap() []error {
+	errs := make([]error, len(e.Problems))
+	for i, problem := range e.Problems {
+		errs[i] = problem
+	}
+	return errs
+}
+
+// Endpoints returns the endpoints for the configured jurisdiction, defaulting to Australia, with
+// the stream host overridden when set
+func (c *Config) Endpoints() Endpoints {
+	endpoints, err := EndpointsFor(c.Jurisdiction)
+	if err != nil {
+		endpoints = DefaultEndpoints()
+	}
+	if c.StreamHost != "" {
+		endpoints.StreamHost = c.StreamHost
+	}
+	return endpoints
+}
+
+// storageLocation returns where finished files are uploaded: STORAGE_URL when set, such as
+// gs://bucket/path or az://container/path, otherwise the S3 bucket
+func (c *Config) storageLocation() string {
+	return firstNonEmpty(c.StorageURL, c.S3Bucket)
+}
+
+// FlushPolicy returns how market writers flush and sync their files
+func (c *Config) FlushPolicy() FlushPolicy {
+	return FlushPolicy{
+		Interval:      c.FlushInterval,
+		EveryN:        c.FlushEveryMessages,
+		FsyncOnSettle: c.FsyncOnSettle,
+	}
+}
+
 func (c *Config) GetMarketFilter() MarketFilter {
 	filter := MarketFilter{
 		MarketIds: c.MarketIDs,
 	}
 
 	if c.EventTypeID != "" {
-		filter.EventTypeIds = []string{c.EventTypeID}
+		filter.EventTypeIds = splitAndClean(c.EventTypeID)
 	}
 	if c.CountryCode != "" {
-		filter.MarketCountries = []string{c.CountryCode}
+		filter.MarketCountries = splitAndClean(c.CountryCode)
 	}
 	if c.MarketType != "" {
-		filter.MarketTypeCodes = []string{c.MarketType}
+		filter.MarketTypeCodes = splitAndClean(c.MarketType)
 	}
+	filter.MarketStartTime = c.MarketStartWindow.Range(time.Now())
 
 	return filter
 }
@@ -1,8 +1,14 @@
 package betfair
 
 import (
+	"errors"
 	"os"
+	"sort"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
 )
 
 func TestConfigLoadFromEnvBasic(t *testing.T) {
@@ -213,4 +219,214 @@ func TestConfigValidation(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+
+func TestLoadFromEnvListsEveryProblem(t *testing.T) {
+	for _, key := range []string{"BETFAIR_APP_KEY", "BETFAIR_SESSION_TOKEN", "BETFAIR_USERNAME", "BETFAIR_PASSWORD", "MARKET_IDS", "EVENT_TYPE_ID", "RECORDING_PROFILES"} {
+		t.Setenv(key, "")
+	}
+	t.Setenv("COMPRESSION", "lzma")
+	t.Setenv("RETENTION_MODE", "shred")
+	malformed := map[string]string{
+		"SECRET_REFRESH_MINUTES":           "hourly",
+		"S3_OBJECT_TAGS":                   "maybe",
+		"HEARTBEAT_MS":                     "0",
+		"DISCOVERY_INTERVAL_SECONDS":       "-5",
+		"DISCOVERY_LOOKAHEAD_MINUTES":      "1.5",
+		"SESSION_KEEPALIVE_MINUTES":        "-1",
+		"CANCEL_ALL_ON_SHUTDOWN":           "yes please",
+		"CHECKPOINT_EVERY_MESSAGES":        "many",
+		"COMPRESSION_LEVEL":                "max",
+		"STREAM_COMPRESSION":               "on",
+		"CATALOGUE_WORKERS":                "0",
+		"CATALOGUE_WAIT_SECONDS":           "soon",
+		"WRITER_QUEUE_SIZE":                "big",
+		"FLUSH_INTERVAL_MS":                "-100",
+		"FLUSH_EVERY_MESSAGES":             "x",
+		"FSYNC_ON_SETTLE":                  "always",
+		"IDLE_MARKET_TIMEOUT_MINUTES":      "0",
+		"VALIDATE_APP_KEY":                 "sure",
+		"DRY_RUN":                          "nope",
+		"RECORD_STATUS_TIMELINE":           "2",
+		"SESSION_LOG":                      "enabled",
+		"DISK_USAGE_THRESHOLD":             "90",
+		"DISK_CHECK_INTERVAL_SECONDS":      "0",
+		"RETENTION_DAYS":                   "week",
+		"MAX_CONCURRENT_MARKETS":           "-2",
+		"RECORD_FROM_MINUTES_BEFORE_START": "ten",
+		"STOP_AFTER_SETTLEMENT":            "never",
+		"ROTATE_SIZE_MB":                   "1GB",
+		"ROTATE_INTERVAL_MINUTES":          "0",
+		"RECORDING_INDEX":                  "y",
+	}
+	for key, value := range malformed {
+		t.Setenv(key, value)
+	}
+
+	err := NewConfig().LoadFromEnv()
+	var validation *ValidationError
+	if !errors.As(err, &validation) {
+		t.Fatalf("expected a *ValidationError, got %v", err)
+	}
+
+	var settings []string
+	for _, problem := range validation.Problems {
+		settings = append(settings, problem.Setting)
+	}
+	want := []string{"COMPRESSION", "BETFAIR_APP_KEY", "BETFAIR_SESSION_TOKEN", "RETENTION_MODE", "MARKET_IDS"}
+	for key := range malformed {
+		want = append(want, key)
+	}
+	sort.Strings(settings)
+	sort.Strings(want)
+	if strings.Join(settings, ",") != strings.Join(want, ",") {
+		t.Fatalf("expected problems with %v, got %v", want, settings)
+	}
+	if !errors.Is(err, errRequired) {
+		t.Errorf("expected errors.Is to match a missing setting")
+	}
+	if !strings.Contains(err.Error(), "COMPRESSION:") || !strings.Contains(err.Error(), "RETENTION_MODE:") {
+		t.Errorf("expected the message to name every setting, got %q", err.Error())
+	}
+}
+
+func TestMarketFilterWithSeveralEventAndMarketTypes(t *testing.T) {
+	t.Setenv("BETFAIR_APP_KEY", "test-app-key")
+	t.Setenv("BETFAIR_SESSION_TOKEN", "test-session-token")
+	t.Setenv("MARKET_IDS", "")
+	t.Setenv("EVENT_TYPE_ID", "4339, 7")
+	t.Setenv("MARKET_TYPE", "WIN,PLACE")
+
+	cfg := NewConfig()
+	if err := cfg.LoadFromEnv(); err != nil {
+		t.Fatalf("LoadFromEnv: %v", err)
+	}
+	filter := cfg.GetMarketFilter()
+	if strings.Join(filter.EventTypeIds, ",") != "4339,7" {
+		t.Errorf("expected event types 4339 and 7, got %v", filter.EventTypeIds)
+	}
+	if strings.Join(filter.MarketTypeCodes, ",") != "WIN,PLACE" {
+		t.Errorf("expected market types WIN and PLACE, got %v", filter.MarketTypeCodes)
+	}
+}
+
+func TestMarketStartWindowFromEnv(t *testing.T) {
+	t.Setenv("BETFAIR_APP_KEY", "test-app-key")
+	t.Setenv("BETFAIR_SESSION_TOKEN", "test-session-token")
+	t.Setenv("MARKET_IDS", "")
+	t.Setenv("EVENT_TYPE_ID", "4339")
+	t.Setenv("DISCOVERY_INTERVAL_SECONDS", "")
+	t.Setenv("MARKET_START_FROM", "-10m")
+	t.Setenv("MARKET_START_TO", "2h")
+
+	cfg := NewConfig()
+	if err := cfg.LoadFromEnv(); err != nil {
+		t.Fatalf("LoadFromEnv: %v", err)
+	}
+	if cfg.DiscoveryInterval != DefaultStartWindowDiscoveryInterval {
+		t.Errorf("expected a start window to enable discovery, got interval %v", cfg.DiscoveryInterval)
+	}
+	window := cfg.GetMarketFilter().MarketStartTime
+	if window == nil || window.From == nil || window.To == nil || window.To.Sub(*window.From) != 130*time.Minute {
+		t.Errorf("expected a 130 minute start window in the filter, got %+v", window)
+	}
+
+	t.Setenv("MARKET_START_TO", "soon")
+	if err := NewConfig().LoadFromEnv(); err == nil || !strings.Contains(err.Error(), "MARKET_START_TO") {
+		t.Errorf("expected an invalid MARKET_START_TO to be reported, got %v", err)
+	}
+}
+
+func TestStreamCompressionDefaultsToIntervalFlush(t *testing.T) {
+	t.Setenv("BETFAIR_APP_KEY", "test-app-key")
+	t.Setenv("BETFAIR_SESSION_TOKEN", "test-session-token")
+	t.Setenv("MARKET_IDS", "1.1")
+	t.Setenv("COMPRESSION", "gzip")
+	t.Setenv("STREAM_COMPRESSION", "true")
+	t.Setenv("FLUSH_INTERVAL_MS", "")
+	t.Setenv("FLUSH_EVERY_MESSAGES", "")
+
+	cfg := NewConfig()
+	if err := cfg.LoadFromEnv(); err != nil {
+		t.Fatalf("LoadFromEnv: %v", err)
+	}
+	if policy := cfg.FlushPolicy(); policy.Interval != DefaultStreamFlushInterval || policy.flushEachMessage() {
+		t.Errorf("expected streamed files to flush every %v, got %+v", DefaultStreamFlushInterval, policy)
+	}
+
+	t.Setenv("FLUSH_EVERY_MESSAGES", "50")
+	cfg = NewConfig()
+	if err := cfg.LoadFromEnv(); err != nil {
+		t.Fatalf("LoadFromEnv: %v", err)
+	}
+	if cfg.FlushInterval != 0 || cfg.FlushEveryMessages != 50 {
+		t.Errorf("expected an explicit flush policy to be kept, got %+v", cfg.FlushPolicy())
+	}
+}
+
+func TestEnvironmentSettingsTakePrecedence(t *testing.T) {
+	t.Setenv("BETFAIR_APP_KEY", "test-app-key")
+	t.Setenv("BETFAIR_SESSION_TOKEN", "test-session-token")
+	t.Setenv("MARKET_IDS", "1.100")
+	t.Setenv("S3_BUCKET", "recordings")
+	t.Setenv("STAGING_S3_BUCKET", "recordings-staging")
+	t.Setenv("STAGING_STREAM_HOST", BetfairIntegrationStreamHost)
+	t.Setenv("STAGING_LOG_LEVEL", "debug")
+	t.Setenv("LOG_LEVEL", "")
+	t.Setenv("STREAM_HOST", "")
+
+	t.Setenv("ENVIRONMENT", "staging")
+	cfg := NewConfig()
+	if err := cfg.LoadFromEnv(); err != nil {
+		t.Fatalf("LoadFromEnv: %v", err)
+	}
+	if cfg.Environment != "staging" || cfg.S3Bucket != "recordings-staging" || cfg.LogLevel != zerolog.DebugLevel {
+		t.Errorf("expected the staging settings, got environment %q, bucket %q, log level %v", cfg.Environment, cfg.S3Bucket, cfg.LogLevel)
+	}
+	if host := cfg.Endpoints().StreamHost; host != BetfairIntegrationStreamHost {
+		t.Errorf("expected the integration stream, got %q", host)
+	}
+	if cfg.Endpoints().Betting != DefaultEndpoints().Betting {
+		t.Errorf("expected the other endpoints to be the jurisdiction's")
+	}
+
+	t.Setenv("ENVIRONMENT", "prod")
+	cfg = NewConfig()
+	if err := cfg.LoadFromEnv(); err != nil {
+		t.Fatalf("LoadFromEnv: %v", err)
+	}
+	if cfg.S3Bucket != "recordings" || cfg.LogLevel != zerolog.InfoLevel || cfg.Endpoints().StreamHost != BetfairStreamHost {
+		t.Errorf("expected the shared settings in prod, got bucket %q, log level %v, stream %q", cfg.S3Bucket, cfg.LogLevel, cfg.Endpoints().StreamHost)
+	}
+
+	t.Setenv("LOG_LEVEL", "chatty")
+	if err := NewConfig().LoadFromEnv(); err == nil || !strings.Contains(err.Error(), "LOG_LEVEL") {
+		t.Errorf("expected an invalid LOG_LEVEL to be reported, got %v", err)
+	}
+}
+
+func TestLocaleAndCurrencyFromEnv(t *testing.T) {
+	t.Setenv("BETFAIR_APP_KEY", "test-app-key")
+	t.Setenv("BETFAIR_SESSION_TOKEN", "test-session-token")
+	t.Setenv("MARKET_IDS", "1.100")
+	t.Setenv("LOCALE", "")
+	t.Setenv("CURRENCY_CODE", "")
+
+	cfg := NewConfig()
+	if err := cfg.LoadFromEnv(); err != nil {
+		t.Fatalf("LoadFromEnv: %v", err)
+	}
+	if cfg.Locale != DefaultLocale || cfg.CurrencyCode != "" {
+		t.Errorf("expected the default locale and the account's currency, got %q and %q", cfg.Locale, cfg.CurrencyCode)
+	}
+
+	t.Setenv("LOCALE", "it")
+	t.Setenv("CURRENCY_CODE", "eur")
+	cfg = NewConfig()
+	if err := cfg.LoadFromEnv(); err != nil {
+		t.Fatalf("LoadFromEnv: %v", err)
+	}
+	if cfg.Locale != "it" || cfg.CurrencyCode != "EUR" {
+		t.Errorf("expected it and EUR, got %q and %q", cfg.Locale, cfg.CurrencyCode)
+	}
+}
@@ -0,0 +1,256 @@
+package betfair
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	DefaultDiscoveryLookahead  = 60 * time.Minute
+	maxDiscoveryCatalogueCount = 1000
+)
+
+// MarketDiscoverer periodically lists markets matching a filter that start within a lookahead
+// window and tracks the set of markets that should be subscribed to
+type MarketDiscoverer struct {
+	restClient *RESTClient
+	filter     MarketFilter
+	window     StartWindow
+	interval   time.Duration
+	lookahead  time.Duration
+	logger     zerolog.Logger
+	now        func() time.Time
+	paused     func() bool
+	maxMarkets int
+
+	mu         sync.Mutex
+	active     map[string]bool
+	admitted   map[string]bool
+	settled    map[string]bool
+	startTimes map[string]time.Time
+	eventTypes map[string]string
+}
+
+func NewMarketDiscoverer(restClient *RESTClient, filter MarketFilter, interval, lookahead time.Duration, logger zerolog.Logger) *MarketDiscoverer {
+	if lookahead <= 0 {
+		lookahead = DefaultDiscoveryLookahead
+	}
+	return &MarketDiscoverer{
+		restClient: restClient,
+		filter:     filter,
+		interval:   interval,
+		lookahead:  lookahead,
+		logger:     logger,
+		now:        time.Now,
+		active:     make(map[string]bool),
+		admitted:   make(map[string]bool),
+		settled:    make(map[string]bool),
+		startTimes: make(map[string]time.Time),
+		eventTypes: make(map[string]string),
+	}
+}
+
+// SetFilter replaces the filter used for future discovery runs and stops tracking markets of event
+// types it no longer selects, so they drop out of the next subscription
+func (d *MarketDiscoverer) SetFilter(filter MarketFilter) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.filter = filter
+
+	if len(filter.EventTypeIds) == 0 {
+		return
+	}
+	for id := range d.active {
+		if eventType, known := d.eventTypes[id]; known && !slices.Contains(filter.EventTypeIds, eventType) {
+			d.forget(id)
+		}
+	}
+}
+
+// forget stops tracking a market without marking it settled, so a later filter may find it again
+func (d *MarketDiscoverer) forget(marketID string) {
+	delete(d.active, marketID)
+	delete(d.admitted, marketID)
+	delete(d.startTimes, marketID)
+	delete(d.eventTypes, marketID)
+}
+
+// SetStartWindow limits discovery to markets starting within the window. Its bounds replace the
+// default window of now to the lookahead where set.
+func (d *MarketDiscoverer) SetStartWindow(window StartWindow) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.window = window
+}
+
+// SetMaxMarkets caps how many markets MarketIDs returns. Markets already returned stay until they
+// settle; free slots go to the newly discovered markets starting soonest and the rest wait.
+// Zero means no limit.
+func (d *MarketDiscoverer) SetMaxMarkets(max int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.maxMarkets = max
+}
+
+// Discover lists markets starting within the lookahead window, or the start window when set,
+// and returns the IDs not seen before
+func (d *MarketDiscoverer) Discover(ctx context.Context) ([]string, error) {
+	d.mu.Lock()
+	filter := d.filter
+	window := d.window
+	d.mu.Unlock()
+	filter.MarketIds = nil
+	now := d.now()
+	from, to := now, now.Add(d.lookahead)
+	if window.From.IsSet() {
+		from = window.From.Resolve(now)
+	}
+	if window.To.IsSet() {
+		to = window.To.Resolve(now)
+	}
+	if to.Before(from) {
+		return nil, nil
+	}
+	filter.MarketStartTime = CreateTimeRange(&from, &to)
+
+	catalogues, err := d.restClient.ListMarketCatalogue(ctx, filter, []MarketProjection{MarketProjectionMarketStartTime, MarketProjectionEventType}, MarketSortFirstToStart, maxDiscoveryCatalogueCount)
+	if err != nil {
+		return nil, err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var added []string
+	for _, catalogue := range catalogues {
+		if d.active[catalogue.MarketID] || d.settled[catalogue.MarketID] {
+			continue
+		}
+		if catalogue.MarketStartTime != nil {
+			d.startTimes[catalogue.MarketID] = *catalogue.MarketStartTime
+		}
+		if catalogue.EventType != nil {
+			d.eventTypes[catalogue.MarketID] = catalogue.EventType.ID
+		}
+		d.active[catalogue.MarketID] = true
+		added = append(added, catalogue.MarketID)
+	}
+	sort.Strings(added)
+	return added, nil
+}
+
+// MarketIDs returns the markets currently tracked for subscription. With a cap set, markets
+// returned before are kept until they settle and only the free slots are filled, soonest first.
+func (d *MarketDiscoverer) MarketIDs() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.maxMarkets > 0 {
+		d.admitSoonest(d.maxMarkets - len(d.admitted))
+	}
+
+	ids := make([]string, 0, len(d.active))
+	for id := range d.active {
+		if d.maxMarkets <= 0 || d.admitted[id] {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// admitSoonest admits up to free of the waiting markets, starting with the soonest
+func (d *MarketDiscoverer) admitSoonest(free int) {
+	if free <= 0 {
+		return
+	}
+
+	var waiting []string
+	for id := range d.active {
+		if !d.admitted[id] {
+			waiting = append(waiting, id)
+		}
+	}
+	sort.Slice(waiting, func(i, j int) bool {
+		ti, tj := d.startTimes[waiting[i]], d.startTimes[waiting[j]]
+		if !ti.Equal(tj) {
+			return ti.Before(tj)
+		}
+		return waiting[i] < waiting[j]
+	})
+	if len(waiting) > free {
+		waiting = waiting[:free]
+	}
+	for _, id := range waiting {
+		d.admitted[id] = true
+	}
+}
+
+// Pending returns how many tracked markets are held back by the market cap
+func (d *MarketDiscoverer) Pending() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.maxMarkets <= 0 {
+		return 0
+	}
+	waiting := len(d.active) - len(d.admitted)
+	if free := d.maxMarkets - len(d.admitted); free > 0 {
+		waiting -= free
+	}
+	if waiting < 0 {
+		return 0
+	}
+	return waiting
+}
+
+// MarkSettled stops tracking a market so it is dropped from the next subscription and never rediscovered
+func (d *MarketDiscoverer) MarkSettled(marketID string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.active[marketID] {
+		return false
+	}
+	d.forget(marketID)
+	d.settled[marketID] = true
+	return true
+}
+
+// SetPauseCheck makes Run skip discovery while paused returns true
+func (d *MarketDiscoverer) SetPauseCheck(paused func() bool) {
+	d.paused = paused
+}
+
+// Run rediscovers markets every interval and calls onChange with the full market set when new markets appear
+func (d *MarketDiscoverer) Run(ctx context.Context, onChange func(marketIDs []string)) {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if d.paused != nil && d.paused() {
+				d.logger.Warn().Msg("market discovery paused")
+				continue
+			}
+			added, err := d.Discover(ctx)
+			if err != nil {
+				d.logger.Error().Err(err).Msg("market discovery failed")
+				continue
+			}
+			if len(added) == 0 {
+				continue
+			}
+			d.logger.Info().Strs("market_ids", added).Int("pending", d.Pending()).Msg("discovered new markets")
+			onChange(d.MarketIDs())
+		}
+	}
+}
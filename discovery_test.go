@@ -0,0 +1,186 @@
+package betfair
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func TestMarketDiscovererDiscover(t *testing.T) {
+	now := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+	available := []string{"1.100", "1.200"}
+
+	var lastFilter map[string]interface{}
+	client := newTestRESTClient(t, func(method string, params map[string]interface{}) interface{} {
+		if method != "listMarketCatalogue" {
+			t.Errorf("Unexpected method %s", method)
+		}
+		lastFilter = params["filter"].(map[string]interface{})
+
+		catalogues := make([]map[string]interface{}, 0, len(available))
+		for _, id := range available {
+			catalogues = append(catalogues, map[string]interface{}{"marketId": id, "marketName": "R1"})
+		}
+		return catalogues
+	})
+
+	filter := *CreateMarketFilter().WithEventTypeIDs([]string{"4339"}).WithMarketCountries([]string{"AU"})
+	discoverer := NewMarketDiscoverer(client, filter, time.Minute, 30*time.Minute, zerolog.Nop())
+	discoverer.now = func() time.Time { return now }
+
+	added, err := discoverer.Discover(context.Background())
+	if err != nil {
+		t.Fatalf("Discover failed: %v", err)
+	}
+	if len(added) != 2 {
+		t.Fatalf("Expected 2 new markets, got %v", added)
+	}
+
+	startTime, ok := lastFilter["marketStartTime"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected market start time window in filter: %v", lastFilter)
+	}
+	if startTime["to"] != now.Add(30*time.Minute).Format(time.RFC3339) {
+		t.Errorf("Expected window to end at lookahead, got %v", startTime["to"])
+	}
+
+	// Already-tracked markets are not reported again
+	available = []string{"1.100", "1.200", "1.300"}
+	added, err = discoverer.Discover(context.Background())
+	if err != nil {
+		t.Fatalf("Discover failed: %v", err)
+	}
+	if len(added) != 1 || added[0] != "1.300" {
+		t.Errorf("Expected only 1.300 to be new, got %v", added)
+	}
+
+	// Settled markets are dropped and never rediscovered
+	if !discoverer.MarkSettled("1.100") {
+		t.Error("Expected 1.100 to be tracked")
+	}
+	if discoverer.MarkSettled("1.999") {
+		t.Error("Expected untracked market to report false")
+	}
+	added, _ = discoverer.Discover(context.Background())
+	if len(added) != 0 {
+		t.Errorf("Expected no new markets after settlement, got %v", added)
+	}
+
+	ids := discoverer.MarketIDs()
+	if len(ids) != 2 || ids[0] != "1.200" || ids[1] != "1.300" {
+		t.Errorf("Unexpected tracked markets: %v", ids)
+	}
+}
+
+func TestMarketRecorderSubscriptionFilterUsesDiscoveredMarkets(t *testing.T) {
+	client := newTestRESTClient(t, func(method string, params map[string]interface{}) interface{} {
+		return []map[string]interface{}{{"marketId": "1.500"}}
+	})
+
+	cfg := &Config{EventTypeID: "4339", CountryCode: "AU"}
+	recorder := &MarketRecorder{
+		config: cfg,
+		logger: zerolog.Nop(),
+	}
+
+	filter := recorder.subscriptionFilter()
+	if len(filter.EventTypeIds) != 1 || len(filter.MarketIds) != 0 {
+		t.Errorf("Expected configured filter without discovery, got %+v", filter)
+	}
+
+	recorder.discoverer = NewMarketDiscoverer(client, cfg.GetMarketFilter(), time.Minute, time.Hour, zerolog.Nop())
+	if filter := recorder.subscriptionFilter(); len(filter.EventTypeIds) != 0 || len(filter.MarketIds) != 0 {
+		t.Errorf("Expected no markets before any is discovered, got %+v", filter)
+	}
+	subscription := NewStreamClient("app-key", "token", 500, zerolog.Nop(), nil).buildSubscription(recorder.subscriptionFilter())
+	if ids, ok := subscription["marketFilter"].(map[string]any)["marketIds"].([]string); !ok || len(ids) != 0 {
+		t.Errorf("Expected an empty market ID list rather than a subscription to every market, got %v", subscription["marketFilter"])
+	}
+
+	recorder.discoverer.Discover(context.Background())
+	filter = recorder.subscriptionFilter()
+	if len(filter.MarketIds) != 1 || filter.MarketIds[0] != "1.500" || len(filter.EventTypeIds) != 0 {
+		t.Errorf("Expected discovered market IDs only, got %+v", filter)
+	}
+}
+
+func TestMarketDiscovererLimitsToSoonestMarkets(t *testing.T) {
+	now := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+	client := newTestRESTClient(t, func(method string, params map[string]interface{}) interface{} {
+		return []map[string]interface{}{
+			{"marketId": "1.100", "marketStartTime": now.Add(40 * time.Minute).Format(time.RFC3339)},
+			{"marketId": "1.200", "marketStartTime": now.Add(10 * time.Minute).Format(time.RFC3339)},
+			{"marketId": "1.300", "marketStartTime": now.Add(20 * time.Minute).Format(time.RFC3339)},
+		}
+	})
+
+	discoverer := NewMarketDiscoverer(client, MarketFilter{}, time.Minute, time.Hour, zerolog.Nop())
+	discoverer.now = func() time.Time { return now }
+	discoverer.SetMaxMarkets(2)
+
+	if _, err := discoverer.Discover(context.Background()); err != nil {
+		t.Fatalf("Discover failed: %v", err)
+	}
+
+	ids := discoverer.MarketIDs()
+	if len(ids) != 2 || ids[0] != "1.200" || ids[1] != "1.300" {
+		t.Errorf("Expected the two soonest markets, got %v", ids)
+	}
+	if pending := discoverer.Pending(); pending != 1 {
+		t.Errorf("Expected 1 pending market, got %d", pending)
+	}
+
+	// Settling a market frees a slot for the next one
+	discoverer.MarkSettled("1.200")
+	ids = discoverer.MarketIDs()
+	if len(ids) != 2 || ids[0] != "1.100" || ids[1] != "1.300" {
+		t.Errorf("Expected 1.100 to be promoted, got %v", ids)
+	}
+	if pending := discoverer.Pending(); pending != 0 {
+		t.Errorf("Expected no pending markets, got %d", pending)
+	}
+}
+
+func TestMarketDiscovererKeepsAdmittedMarketsWhenSoonerOnesAppear(t *testing.T) {
+	now := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+	available := []map[string]interface{}{
+		{"marketId": "1.100", "marketStartTime": now.Add(40 * time.Minute).Format(time.RFC3339)},
+		{"marketId": "1.300", "marketStartTime": now.Add(20 * time.Minute).Format(time.RFC3339)},
+	}
+	client := newTestRESTClient(t, func(method string, params map[string]interface{}) interface{} {
+		return available
+	})
+
+	discoverer := NewMarketDiscoverer(client, MarketFilter{}, time.Minute, time.Hour, zerolog.Nop())
+	discoverer.now = func() time.Time { return now }
+	discoverer.SetMaxMarkets(2)
+
+	if _, err := discoverer.Discover(context.Background()); err != nil {
+		t.Fatalf("Discover failed: %v", err)
+	}
+	if ids := discoverer.MarketIDs(); len(ids) != 2 {
+		t.Fatalf("Expected both markets to fill the cap, got %v", ids)
+	}
+
+	// A market starting sooner than both arrives once the cap is full
+	available = append(available, map[string]interface{}{"marketId": "1.200", "marketStartTime": now.Add(10 * time.Minute).Format(time.RFC3339)})
+	if _, err := discoverer.Discover(context.Background()); err != nil {
+		t.Fatalf("Discover failed: %v", err)
+	}
+
+	ids := discoverer.MarketIDs()
+	if len(ids) != 2 || ids[0] != "1.100" || ids[1] != "1.300" {
+		t.Errorf("Expected recording markets to keep their slots, got %v", ids)
+	}
+	if pending := discoverer.Pending(); pending != 1 {
+		t.Errorf("Expected the sooner market to wait, got %d pending", pending)
+	}
+
+	discoverer.MarkSettled("1.300")
+	ids = discoverer.MarketIDs()
+	if len(ids) != 2 || ids[0] != "1.100" || ids[1] != "1.200" {
+		t.Errorf("Expected 1.200 to take the freed slot, got %v", ids)
+	}
+}
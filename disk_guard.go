@@ -0,0 +1,97 @@
+package betfair
+
+import (
+	"context"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const DefaultDiskCheckInterval = 30 * time.Second
+
+// DiskUsage is the size and available space of a filesystem in bytes
+type DiskUsage struct {
+	Total uint64
+	Free  uint64
+}
+
+// UsedFraction returns the share of the filesystem in use, from 0 to 1
+func (u DiskUsage) UsedFraction() float64 {
+	if u.Total == 0 {
+		return 0
+	}
+	return 1 - float64(u.Free)/float64(u.Total)
+}
+
+// DiskGuard watches the output volume and reports when usage crosses a threshold so the recorder
+// can stop taking on new markets before the disk fills up mid-race. A nil *DiskGuard never trips.
+type DiskGuard struct {
+	path      string
+	threshold float64
+	interval  time.Duration
+	logger    zerolog.Logger
+	usage     func(path string) (DiskUsage, error)
+	exceeded  atomic.Bool
+}
+
+func NewDiskGuard(path string, threshold float64, interval time.Duration, logger zerolog.Logger) *DiskGuard {
+	if interval <= 0 {
+		interval = DefaultDiskCheckInterval
+	}
+	return &DiskGuard{
+		path:      path,
+		threshold: threshold,
+		interval:  interval,
+		logger:    logger,
+		usage:     diskUsage,
+	}
+}
+
+// Exceeded reports whether the last check found usage above the threshold
+func (g *DiskGuard) Exceeded() bool {
+	return g != nil && g.exceeded.Load()
+}
+
+// Check samples disk usage and updates the exceeded state, warning on every check above the threshold
+func (g *DiskGuard) Check() {
+	if err := os.MkdirAll(g.path, 0755); err != nil {
+		g.logger.Error().Err(err).Str("path", g.path).Msg("failed to create output directory for disk check")
+		return
+	}
+
+	usage, err := g.usage(g.path)
+	if err != nil {
+		g.logger.Error().Err(err).Str("path", g.path).Msg("failed to check disk usage")
+		return
+	}
+
+	used := usage.UsedFraction()
+	over := used >= g.threshold
+	was := g.exceeded.Swap(over)
+
+	switch {
+	case over:
+		g.logger.Warn().Float64("used", used).Float64("threshold", g.threshold).Uint64("free_bytes", usage.Free).Msg("output volume above disk usage threshold; not recording new markets")
+	case was:
+		g.logger.Info().Float64("used", used).Float64("threshold", g.threshold).Msg("disk usage back under threshold; recording new markets again")
+	}
+}
+
+// Run checks disk usage every interval until ctx is cancelled
+func (g *DiskGuard) Run(ctx context.Context) {
+	g.Check()
+
+	ticker := time.NewTicker(g.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			g.Check()
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package betfair
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestDiskGuardThreshold(t *testing.T) {
+	guard := NewDiskGuard(t.TempDir(), 0.9, 0, zerolog.New(zerolog.NewTestWriter(t)))
+
+	usage := DiskUsage{Total: 100, Free: 50}
+	guard.usage = func(string) (DiskUsage, error) { return usage, nil }
+
+	guard.Check()
+	if guard.Exceeded() {
+		t.Error("Expected 50% usage to be under the threshold")
+	}
+
+	usage.Free = 5
+	guard.Check()
+	if !guard.Exceeded() {
+		t.Error("Expected 95% usage to trip the guard")
+	}
+
+	usage.Free = 20
+	guard.Check()
+	if guard.Exceeded() {
+		t.Error("Expected guard to reset once usage drops")
+	}
+
+	var disabled *DiskGuard
+	if disabled.Exceeded() {
+		t.Error("Expected nil guard never to trip")
+	}
+}
+
+func TestDiskUsageOfOutputDirectory(t *testing.T) {
+	usage, err := diskUsage(t.TempDir())
+	if err != nil {
+		t.Skipf("disk usage unavailable: %v", err)
+	}
+	if usage.Total == 0 || usage.Free > usage.Total {
+		t.Errorf("Unexpected disk usage %+v", usage)
+	}
+}
+
+func TestMarketRecorderSkipsNewMarketsWhenDiskFull(t *testing.T) {
+	tempDir := t.TempDir()
+	guard := NewDiskGuard(tempDir, 0.5, 0, zerolog.Nop())
+	guard.usage = func(string) (DiskUsage, error) { return DiskUsage{Total: 100, Free: 10}, nil }
+	guard.Check()
+
+	recorder := &MarketRecorder{
+		config:           &Config{EnrichmentMode: EnrichmentOff},
+		logger:           zerolog.New(zerolog.NewTestWriter(t)),
+		fileManager:      NewFileManager(tempDir),
+		marketCatalogues: make(map[string]*MarketCatalogue),
+		diskGuard:        guard,
+	}
+
+	message := `{"op":"mcm","pt":1,"mc":[{"id":"1.1","marketDefinition":{"status":"OPEN"}}]}` + "\n"
+	stream := &StreamConn{reader: bufio.NewReader(strings.NewReader(message))}
+	writers := make(map[string]*bufio.Writer)
+	files := make(map[string]*os.File)
+
+	if err := recorder.readMessage(context.Background(), stream, writers, files, make(map[string]string)); err != nil {
+		t.Fatalf("readMessage failed: %v", err)
+	}
+	recorder.stopMarketWriters()
+
+	if len(writers) != 0 {
+		t.Error("Expected no writer to be opened while the disk is above the threshold")
+	}
+	if _, err := os.Stat(recorder.fileManager.GetMarketFilePath("1.1")); err == nil {
+		t.Error("Expected no market file to be created")
+	}
+}
+
+func TestMarketRecorderRespectsConcurrentMarketLimit(t *testing.T) {
+	tempDir := t.TempDir()
+	recorder := &MarketRecorder{
+		config:           &Config{EnrichmentMode: EnrichmentOff, MaxConcurrentMarkets: 1},
+		logger:           zerolog.New(zerolog.NewTestWriter(t)),
+		fileManager:      NewFileManager(tempDir),
+		marketCatalogues: make(map[string]*MarketCatalogue),
+	}
+
+	message := `{"op":"mcm","pt":1,"mc":[{"id":"1.1","marketDefinition":{"status":"OPEN"}},{"id":"1.2","marketDefinition":{"status":"OPEN"}}]}` + "\n"
+	stream := &StreamConn{reader: bufio.NewReader(strings.NewReader(message))}
+	writers := make(map[string]*bufio.Writer)
+	files := make(map[string]*os.File)
+
+	if err := recorder.readMessage(context.Background(), stream, writers, files, make(map[string]string)); err != nil {
+		t.Fatalf("readMessage failed: %v", err)
+	}
+	recorder.stopMarketWriters()
+
+	if _, ok := writers["1.1"]; !ok || len(writers) != 1 {
+		t.Errorf("Expected only the first market to be recorded, got %d writers", len(writers))
+	}
+	if !recorder.skipped["1.2"] {
+		t.Error("Expected the second market to be skipped")
+	}
+}
+
+func TestConfiguredMarketIDsRespectConcurrentMarketLimit(t *testing.T) {
+	tempDir := t.TempDir()
+	recorder := &MarketRecorder{
+		config:           &Config{EnrichmentMode: EnrichmentOff, MarketIDs: []string{"1.1", "1.2", "1.3"}, MaxConcurrentMarkets: 2},
+		logger:           zerolog.New(zerolog.NewTestWriter(t)),
+		fileManager:      NewFileManager(tempDir),
+		marketCatalogues: make(map[string]*MarketCatalogue),
+	}
+
+	writers, files, closeFn, err := recorder.openWriters()
+	if err != nil {
+		t.Fatalf("openWriters failed: %v", err)
+	}
+	defer closeFn()
+	if len(writers) != 2 || !recorder.skipped["1.3"] {
+		t.Fatalf("Expected two of three configured markets to be admitted, got %d writers and skipped %v", len(writers), recorder.skipped)
+	}
+
+	message := `{"op":"mcm","pt":1,"mc":[{"id":"1.3","img":true,"marketDefinition":{"status":"OPEN"}}]}` + "\n"
+	stream := &StreamConn{reader: bufio.NewReader(strings.NewReader(message))}
+	if err := recorder.readMessage(context.Background(), stream, writers, files, make(map[string]string)); err != nil {
+		t.Fatalf("readMessage failed: %v", err)
+	}
+	recorder.stopMarketWriters()
+
+	if _, ok := writers["1.3"]; ok || len(writers) != 2 {
+		t.Errorf("Expected the configured market over the limit not to be recorded, got %d writers", len(writers))
+	}
+	if recorder.fileManager.MarketFileExists("1.3") {
+		t.Error("Expected no file for the market over the limit")
+	}
+}
+
+func TestSkippedMarketWaitsForImage(t *testing.T) {
+	recorder := &MarketRecorder{
+		config:           &Config{EnrichmentMode: EnrichmentOff, MaxConcurrentMarkets: 1},
+		logger:           zerolog.New(zerolog.NewTestWriter(t)),
+		fileManager:      NewFileManager(t.TempDir()),
+		marketCatalogues: make(map[string]*MarketCatalogue),
+	}
+
+	messages := []string{
+		`{"op":"mcm","pt":1,"mc":[{"id":"1.1","img":true,"marketDefinition":{"status":"OPEN"}},{"id":"1.2","img":true,"marketDefinition":{"status":"OPEN"}}]}`,
+		`{"op":"mcm","pt":2,"mc":[{"id":"1.2","rc":[{"id":7,"ltp":3.5}]}]}`,
+		`{"op":"mcm","pt":3,"mc":[{"id":"1.2","img":true,"marketDefinition":{"status":"OPEN"},"rc":[{"id":7,"atb":[[3.5,10]]}]}]}`,
+	}
+	stream := &StreamConn{reader: bufio.NewReader(strings.NewReader(strings.Join(messages, "\n") + "\n"))}
+	writers := make(map[string]*bufio.Writer)
+	files := make(map[string]*os.File)
+	read := func() {
+		t.Helper()
+		if err := recorder.readMessage(context.Background(), stream, writers, files, make(map[string]string)); err != nil {
+			t.Fatalf("readMessage failed: %v", err)
+		}
+	}
+
+	read()
+	// Free the slot as if the first market had been finalized
+	delete(writers, "1.1")
+	delete(files, "1.1")
+
+	read()
+	if _, ok := writers["1.2"]; ok {
+		t.Fatal("Expected a refused market not to start its file from a delta")
+	}
+	if !recorder.imagesRequested {
+		t.Error("Expected fresh images to be requested for the waiting market")
+	}
+
+	read()
+	recorder.stopMarketWriters()
+	if _, ok := writers["1.2"]; !ok {
+		t.Fatal("Expected the market to be recorded from its image")
+	}
+	content, err := os.ReadFile(recorder.fileManager.GetMarketFilePath("1.2"))
+	if err != nil {
+		t.Fatalf("read market file: %v", err)
+	}
+	if lines := strings.Split(strings.TrimSpace(string(content)), "\n"); len(lines) != 1 || !strings.Contains(lines[0], `"img":true`) {
+		t.Errorf("Expected the file to start with the image, got %q", content)
+	}
+}
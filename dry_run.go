@@ -0,0 +1,146 @@
+package betfair
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+var errNoMarketsMatched = errors.New("market filter matches no markets")
+
+var dryRunProjection = []MarketProjection{
+	MarketProjectionEvent,
+	MarketProjectionMarketStartTime,
+}
+
+// DryRunMarket is a market the recorder would record and where its output would go
+type DryRunMarket struct {
+	MarketID    string
+	MarketName  string
+	EventName   string
+	StartTime   *time.Time
+	FilePath    string
+	ArchivePath string
+	S3Key       string
+}
+
+// DryRunReport is the outcome of a dry run
+type DryRunReport struct {
+	Filter  MarketFilter
+	Markets []DryRunMarket
+}
+
+// Log logs each market the dry run would record and where its recording would go
+func (report *DryRunReport) Log(logger zerolog.Logger) {
+	for _, market := range report.Markets {
+		logger.Info().
+			Str("market_id", market.MarketID).
+			Str("market_name", market.MarketName).
+			Str("event_name", market.EventName).
+			Str("file", market.FilePath).
+			Str("archive", market.ArchivePath).
+			Str("s3_key", market.S3Key).
+			Msg("would record market")
+	}
+	logger.Info().Int("markets", len(report.Markets)).Msg("dry run succeeded")
+}
+
+// DryRun checks a deployment without recording: it resolves the market filter, confirms it matches
+// at least one market, and authenticates and subscribes to the stream before disconnecting again.
+// Nothing is written to disk or uploaded.
+func (r *MarketRecorder) DryRun(ctx context.Context) (*DryRunReport, error) {
+	if len(r.profiles) > 0 {
+		report := &DryRunReport{}
+		for i, child := range r.profiles {
+			childReport, err := child.DryRun(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("profile %s: %w", r.config.Profiles[i].Name, err)
+			}
+			report.Markets = append(report.Markets, childReport.Markets...)
+		}
+		return report, nil
+	}
+
+	report, err := r.ResolveMarkets(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	stream, err := r.establishConnection(ctx)
+	if err != nil {
+		return nil, err
+	}
+	r.streamMu.Lock()
+	r.currentStream = nil
+	r.streamMu.Unlock()
+	r.stats.connected.Store(false)
+	stream.Close()
+
+	return report, nil
+}
+
+// ResolveMarkets lists the markets the filter currently matches and where their recordings would
+// go, without connecting to the stream. It fails when no market matches.
+func (r *MarketRecorder) ResolveMarkets(ctx context.Context) (*DryRunReport, error) {
+	if len(r.profiles) > 0 {
+		report := &DryRunReport{}
+		for i, child := range r.profiles {
+			childReport, err := child.ResolveMarkets(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("profile %s: %w", r.config.Profiles[i].Name, err)
+			}
+			report.Markets = append(report.Markets, childReport.Markets...)
+		}
+		return report, nil
+	}
+
+	if r.discoverer != nil {
+		if _, err := r.discoverer.Discover(ctx); err != nil {
+			return nil, fmt.Errorf("discover markets: %w", err)
+		}
+	}
+
+	filter := r.subscriptionFilter()
+	if r.discoverer != nil && len(filter.MarketIds) == 0 {
+		return nil, errNoMarketsMatched
+	}
+	catalogues, err := r.restClient.ListMarketCatalogue(ctx, filter, dryRunProjection, MarketSortFirstToStart, maxDiscoveryCatalogueCount)
+	if err != nil {
+		return nil, fmt.Errorf("list markets: %w", err)
+	}
+	if len(catalogues) == 0 {
+		return nil, errNoMarketsMatched
+	}
+
+	report := &DryRunReport{Filter: filter}
+	for _, catalogue := range catalogues {
+		report.Markets = append(report.Markets, r.dryRunMarket(catalogue))
+	}
+	return report, nil
+}
+
+func (r *MarketRecorder) dryRunMarket(catalogue MarketCatalogue) DryRunMarket {
+	market := DryRunMarket{
+		MarketID:    catalogue.MarketID,
+		MarketName:  catalogue.MarketName,
+		StartTime:   catalogue.MarketStartTime,
+		FilePath:    r.fileManager.RecordingFilePath(catalogue.MarketID),
+		ArchivePath: r.fileManager.GetCompressedFilePath(catalogue.MarketID),
+	}
+	if catalogue.Event != nil {
+		market.EventName = catalogue.Event.Name
+		if catalogue.Event.OpenDate != nil {
+			eventInfo := NewEventInfo(catalogue.Event.ID, *catalogue.Event.OpenDate)
+			eventInfo.Timezone = catalogue.Event.Timezone
+			r.config.Partition.Apply(eventInfo)
+			market.ArchivePath = r.fileManager.GetArchivePath(eventInfo, catalogue.MarketID)
+			if r.storage != nil {
+				market.S3Key = r.storage.BuildKey(eventInfo, catalogue.MarketID+r.fileManager.CompressedExtension())
+			}
+		}
+	}
+	return market
+}
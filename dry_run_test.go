@@ -0,0 +1,84 @@
+package betfair
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func TestMarketRecorderDryRunRequiresMatchingMarkets(t *testing.T) {
+	var markets []map[string]interface{}
+	client := newTestRESTClient(t, func(method string, params map[string]interface{}) interface{} {
+		if method != "listMarketCatalogue" {
+			t.Errorf("Unexpected method %s", method)
+		}
+		return markets
+	})
+
+	streamClient := NewStreamClient("app-key", "token", 0, zerolog.Nop(), nil)
+	streamClient.SetEndpoints(Endpoints{StreamHost: "127.0.0.1"})
+
+	recorder := &MarketRecorder{
+		config:       &Config{EventTypeID: "4339"},
+		logger:       zerolog.Nop(),
+		restClient:   client,
+		streamClient: streamClient,
+		fileManager:  NewFileManager(t.TempDir()),
+	}
+
+	if _, err := recorder.DryRun(context.Background()); !errors.Is(err, errNoMarketsMatched) {
+		t.Errorf("Expected an empty filter to fail, got %v", err)
+	}
+
+	// Markets resolve, but the stream cannot be reached
+	markets = []map[string]interface{}{{"marketId": "1.1", "marketName": "R1"}}
+	if _, err := recorder.DryRun(context.Background()); err == nil {
+		t.Error("Expected dry run to fail when the stream is unreachable")
+	}
+	// Resolving markets alone never touches the stream
+	report, err := recorder.ResolveMarkets(context.Background())
+	if err != nil || len(report.Markets) != 1 || report.Markets[0].MarketID != "1.1" {
+		t.Errorf("Expected 1.1 resolved without the stream, got %+v, %v", report, err)
+	}
+}
+
+func TestDryRunMarketOutputPaths(t *testing.T) {
+	tempDir := t.TempDir()
+	recorder := &MarketRecorder{
+		config:      &Config{},
+		fileManager: NewFileManager(tempDir),
+		storage:     &S3Storage{basePath: "raw"},
+	}
+
+	openDate := time.Date(2025, 10, 1, 9, 0, 0, 0, time.UTC)
+	market := recorder.dryRunMarket(MarketCatalogue{
+		MarketID:   "1.1",
+		MarketName: "R1",
+		Event:      &Event{ID: "100", Name: "Sandown", OpenDate: &openDate},
+	})
+
+	if market.FilePath != filepath.Join(tempDir, "1.1") || market.ArchivePath != filepath.Join(tempDir, "1.1.bz2") {
+		t.Errorf("Unexpected local paths %s, %s", market.FilePath, market.ArchivePath)
+	}
+	if market.S3Key != "raw/PRO/2025/Oct/1/100/1.1.bz2" {
+		t.Errorf("Unexpected S3 key %s", market.S3Key)
+	}
+	if market.EventName != "Sandown" {
+		t.Errorf("Unexpected event name %s", market.EventName)
+	}
+
+	// 23:00 UTC on 30 September is already 1 October in Sydney
+	recorder.config.Partition = PartitionScheme{Month: MonthNumber, Timezone: PartitionVenueTime}
+	openDate = time.Date(2025, 9, 30, 23, 0, 0, 0, time.UTC)
+	market = recorder.dryRunMarket(MarketCatalogue{
+		MarketID: "1.2",
+		Event:    &Event{ID: "100", Timezone: "Australia/Sydney", OpenDate: &openDate},
+	})
+	if market.S3Key != "raw/PRO/2025/10/1/100/1.2.bz2" {
+		t.Errorf("Expected venue-local numeric partition, got %s", market.S3Key)
+	}
+}
@@ -0,0 +1,86 @@
+package betfair
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Jurisdiction string
+
+const (
+	JurisdictionGlobal    Jurisdiction = "GLOBAL"
+	JurisdictionAustralia Jurisdiction = "AU"
+	JurisdictionSpain     Jurisdiction = "ES"
+	JurisdictionItaly     Jurisdiction = "IT"
+	JurisdictionRomania   Jurisdiction = "RO"
+	JurisdictionSweden    Jurisdiction = "SE"
+)
+
+// Endpoints groups the identity, API and stream hosts used by a Betfair jurisdiction
+type Endpoints struct {
+	InteractiveLogin string
+	BotLogin         string
+	Logout           string
+	KeepAlive        string
+	Betting          string
+	Account          string
+	StreamHost       string
+}
+
+func identityEndpoints(identityHost, certHost, apiHost, streamHost string) Endpoints {
+	return Endpoints{
+		InteractiveLogin: "https://" + identityHost + "/api/login",
+		BotLogin:         "https://" + certHost + "/api/certlogin",
+		Logout:           "https://" + identityHost + "/api/logout",
+		KeepAlive:        "https://" + identityHost + "/api/keepAlive",
+		Betting:          "https://" + apiHost + "/exchange/betting/json-rpc/v1",
+		Account:          "https://" + apiHost + "/exchange/account/json-rpc/v1",
+		StreamHost:       streamHost,
+	}
+}
+
+var jurisdictionEndpoints = map[Jurisdiction]Endpoints{
+	JurisdictionGlobal: identityEndpoints("identitysso.betfair.com", "identitysso-cert.betfair.com", "api.betfair.com", "stream-api.betfair.com"),
+	JurisdictionAustralia: {
+		InteractiveLogin: AuthURLInteractiveLogin,
+		BotLogin:         AuthURLBotLogin,
+		Logout:           AuthURLLogout,
+		KeepAlive:        AuthURLKeepAlive,
+		Betting:          BettingURLExchange,
+		Account:          AccountURLAccounts,
+		StreamHost:       BetfairStreamHost,
+	},
+	JurisdictionSpain:   identityEndpoints("identitysso.betfair.es", "identitysso-cert.betfair.es", "api.betfair.es", "stream-api.betfair.es"),
+	JurisdictionItaly:   identityEndpoints("identitysso.betfair.it", "identitysso-cert.betfair.it", "api.betfair.it", "stream-api.betfair.it"),
+	JurisdictionRomania: identityEndpoints("identitysso.betfair.ro", "identitysso-cert.betfair.ro", "api.betfair.ro", "stream-api.betfair.com"),
+	JurisdictionSweden:  identityEndpoints("identitysso.betfair.se", "identitysso-cert.betfair.se", "api.betfair.se", "stream-api.betfair.com"),
+}
+
+// DefaultEndpoints are the Australian endpoints the clients have always used
+func DefaultEndpoints() Endpoints {
+	return jurisdictionEndpoints[JurisdictionAustralia]
+}
+
+// EndpointsFor returns the endpoints for a jurisdiction
+func EndpointsFor(j Jurisdiction) (Endpoints, error) {
+	endpoints, ok := jurisdictionEndpoints[j]
+	if !ok {
+		return Endpoints{}, fmt.Errorf("unknown jurisdiction %q", j)
+	}
+	return endpoints, nil
+}
+
+// ParseJurisdiction parses a jurisdiction code such as "AU", "global" or "es"
+func ParseJurisdiction(value string) (Jurisdiction, error) {
+	j := Jurisdiction(strings.ToUpper(strings.TrimSpace(value)))
+	switch j {
+	case "COM", "UK", "INT":
+		j = JurisdictionGlobal
+	case "AUS":
+		j = JurisdictionAustralia
+	}
+	if _, ok := jurisdictionEndpoints[j]; !ok {
+		return "", fmt.Errorf("unknown jurisdiction %q", value)
+	}
+	return j, nil
+}
@@ -0,0 +1,106 @@
+package betfair
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestParseJurisdiction(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Jurisdiction
+		wantErr  bool
+	}{
+		{input: "AU", expected: JurisdictionAustralia},
+		{input: "global", expected: JurisdictionGlobal},
+		{input: " uk ", expected: JurisdictionGlobal},
+		{input: "es", expected: JurisdictionSpain},
+		{input: "IT", expected: JurisdictionItaly},
+		{input: "ro", expected: JurisdictionRomania},
+		{input: "se", expected: JurisdictionSweden},
+		{input: "US", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseJurisdiction(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error for %q", tt.input)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestEndpointsFor(t *testing.T) {
+	au, err := EndpointsFor(JurisdictionAustralia)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if au.Betting != BettingURLExchange || au.InteractiveLogin != AuthURLInteractiveLogin {
+		t.Errorf("Australian endpoints should match the legacy constants: %+v", au)
+	}
+	if DefaultEndpoints() != au {
+		t.Error("Default endpoints should be the Australian endpoints")
+	}
+
+	es, err := EndpointsFor(JurisdictionSpain)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if es.Betting != "https://api.betfair.es/exchange/betting/json-rpc/v1" {
+		t.Errorf("Unexpected Spanish betting endpoint: %s", es.Betting)
+	}
+	if es.KeepAlive != "https://identitysso.betfair.es/api/keepAlive" {
+		t.Errorf("Unexpected Spanish keep alive endpoint: %s", es.KeepAlive)
+	}
+
+	if _, err := EndpointsFor("XX"); err == nil {
+		t.Error("Expected error for unknown jurisdiction")
+	}
+}
+
+func TestAuthenticatorLoginURLFollowsJurisdiction(t *testing.T) {
+	auth := NewAuthenticator("app-key", "user", "pass")
+	if auth.loginURL != AuthURLInteractiveLogin {
+		t.Errorf("Expected the Australian login URL by default, got %s", auth.loginURL)
+	}
+
+	global, err := EndpointsFor(JurisdictionGlobal)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	auth.SetEndpoints(global)
+	if auth.loginURL != "https://identitysso.betfair.com/api/login" {
+		t.Errorf("Expected the global login URL, got %s", auth.loginURL)
+	}
+}
+
+func TestRESTClientUsesConfiguredEndpoints(t *testing.T) {
+	var requestedHost string
+
+	client := NewRESTClient("app-key", "session", "en")
+	client.httpClient = &http.Client{
+		Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			requestedHost = req.URL.Host
+			return nil, context.Canceled
+		}),
+	}
+
+	it, _ := EndpointsFor(JurisdictionItaly)
+	client.SetEndpoints(it)
+	client.ListEventTypes(context.Background(), MarketFilter{})
+
+	if requestedHost != "api.betfair.it" {
+		t.Errorf("Expected request to api.betfair.it, got %s", requestedHost)
+	}
+}
@@ -0,0 +1,91 @@
+package betfair
+
+import (
+	"fmt"
+	"strings"
+)
+
+// EnrichmentMode controls which recorded messages get market catalogue data injected
+type EnrichmentMode string
+
+const (
+	// EnrichmentAll enriches every message carrying a market definition
+	EnrichmentAll EnrichmentMode = "all"
+	// EnrichmentFirstImage enriches only the first image of each market
+	EnrichmentFirstImage EnrichmentMode = "first_image"
+	// EnrichmentOff records messages exactly as streamed, matching Betfair's historical files
+	EnrichmentOff EnrichmentMode = "off"
+)
+
+// EnrichmentField is a group of catalogue fields injected into the market definition
+type EnrichmentField string
+
+const (
+	EnrichMarketName  EnrichmentField = "market_name"
+	EnrichEventName   EnrichmentField = "event_name"
+	EnrichVenue       EnrichmentField = "venue"
+	EnrichEventType   EnrichmentField = "event_type"
+	EnrichCompetition EnrichmentField = "competition"
+	EnrichRunnerNames EnrichmentField = "runner_names"
+)
+
+var enrichmentFields = []EnrichmentField{
+	EnrichMarketName,
+	EnrichEventName,
+	EnrichVenue,
+	EnrichEventType,
+	EnrichCompetition,
+	EnrichRunnerNames,
+}
+
+// ParseEnrichmentMode parses "all", "first_image" or "off"
+func ParseEnrichmentMode(value string) (EnrichmentMode, error) {
+	mode := EnrichmentMode(strings.ToLower(strings.TrimSpace(value)))
+	switch mode {
+	case EnrichmentAll, EnrichmentFirstImage, EnrichmentOff:
+		return mode, nil
+	case "none", "false":
+		return EnrichmentOff, nil
+	}
+	return "", fmt.Errorf("unknown enrichment mode %q", value)
+}
+
+// ParseEnrichmentFields parses a comma separated list of enrichment fields
+func ParseEnrichmentFields(csv string) ([]EnrichmentField, error) {
+	var fields []EnrichmentField
+	for _, name := range splitAndClean(csv) {
+		field := EnrichmentField(strings.ToLower(name))
+		known := false
+		for _, f := range enrichmentFields {
+			if f == field {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown enrichment field %q", name)
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// enrichesField reports whether a field should be injected; no selection means every field
+func (c *Config) enrichesField(field EnrichmentField) bool {
+	if c == nil || len(c.EnrichmentFields) == 0 {
+		return true
+	}
+	for _, f := range c.EnrichmentFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Config) enrichmentMode() EnrichmentMode {
+	if c == nil || c.EnrichmentMode == "" {
+		return EnrichmentAll
+	}
+	return c.EnrichmentMode
+}
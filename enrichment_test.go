@@ -0,0 +1,100 @@
+package betfair
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestParseEnrichmentSettings(t *testing.T) {
+	if mode, err := ParseEnrichmentMode("First_Image"); err != nil || mode != EnrichmentFirstImage {
+		t.Errorf("Expected first_image, got %q (%v)", mode, err)
+	}
+	if mode, err := ParseEnrichmentMode("none"); err != nil || mode != EnrichmentOff {
+		t.Errorf("Expected none to mean off, got %q (%v)", mode, err)
+	}
+	if _, err := ParseEnrichmentMode("sometimes"); err == nil {
+		t.Error("Expected unknown mode to be rejected")
+	}
+
+	fields, err := ParseEnrichmentFields("runner_names, VENUE")
+	if err != nil || len(fields) != 2 || fields[1] != EnrichVenue {
+		t.Errorf("Unexpected fields %v (%v)", fields, err)
+	}
+	if _, err := ParseEnrichmentFields("runner_names,jockey"); err == nil {
+		t.Error("Expected unknown field to be rejected")
+	}
+}
+
+func TestMarketRecorderEnrichSelectedFields(t *testing.T) {
+	recorder := &MarketRecorder{
+		config: &Config{EnrichmentFields: []EnrichmentField{EnrichVenue}},
+		logger: zerolog.New(zerolog.NewTestWriter(t)),
+		marketCatalogues: map[string]*MarketCatalogue{"1.1": {
+			MarketName: "R1 500m",
+			Event:      &Event{Name: "Sandown", Venue: "Sandown Park"},
+			Runners:    []RunnerCatalog{{SelectionID: 10, RunnerName: "Fast Dog"}},
+		}},
+	}
+
+	payload := []byte(`{"op":"mcm","mc":[{"marketDefinition":{"runners":[{"id":10}]}}]}`)
+	enriched, err := recorder.enrichMarketData("1.1", payload)
+	if err != nil {
+		t.Fatalf("enrichMarketData failed: %v", err)
+	}
+
+	var data struct {
+		MC []struct {
+			MarketDefinition map[string]interface{} `json:"marketDefinition"`
+		} `json:"mc"`
+	}
+	if err := json.Unmarshal(enriched, &data); err != nil {
+		t.Fatalf("Failed to decode enriched payload: %v", err)
+	}
+
+	def := data.MC[0].MarketDefinition
+	if def["venue"] != "Sandown Park" {
+		t.Errorf("Expected venue to be injected, got %v", def["venue"])
+	}
+	if _, ok := def["marketName"]; ok {
+		t.Error("Expected market name to be left out")
+	}
+	runner := def["runners"].([]interface{})[0].(map[string]interface{})
+	if _, ok := runner["name"]; ok {
+		t.Error("Expected runner names to be left out")
+	}
+}
+
+func TestMarketRecorderShouldEnrichModes(t *testing.T) {
+	image := map[string]interface{}{"img": true}
+	delta := map[string]interface{}{}
+
+	recorder := &MarketRecorder{
+		config:           &Config{EnrichmentMode: EnrichmentFirstImage},
+		marketCatalogues: map[string]*MarketCatalogue{"1.1": {MarketName: "R1"}},
+	}
+
+	if recorder.shouldEnrich("1.1", delta) {
+		t.Error("Expected deltas not to be enriched in first_image mode")
+	}
+	if !recorder.shouldEnrich("1.1", image) {
+		t.Error("Expected first image to be enriched")
+	}
+	if recorder.shouldEnrich("1.1", image) {
+		t.Error("Expected later images not to be enriched")
+	}
+	if recorder.shouldEnrich("1.2", image) {
+		t.Error("Expected images without a catalogue to wait for one")
+	}
+
+	recorder.config.EnrichmentMode = EnrichmentOff
+	if recorder.shouldEnrich("1.1", image) {
+		t.Error("Expected nothing to be enriched when enrichment is off")
+	}
+
+	recorder.config = nil
+	if !recorder.shouldEnrich("1.1", delta) {
+		t.Error("Expected every message to be enriched by default")
+	}
+}
@@ -0,0 +1,43 @@
+package betfair
+
+import (
+	"os"
+	"strings"
+)
+
+// Environment returns the deployment environment named by ENVIRONMENT, such as prod or staging
+func Environment() string {
+	return strings.TrimSpace(os.Getenv("ENVIRONMENT"))
+}
+
+// getenv reads a setting for the current environment: with ENVIRONMENT=staging, STAGING_S3_BUCKET
+// takes precedence over S3_BUCKET, so one .env file can hold the settings of every environment
+func getenv(key string) string {
+	if environment := Environment(); environment != "" {
+		if value, ok := os.LookupEnv(environmentKey(environment, key)); ok {
+			return value
+		}
+	}
+	return os.Getenv(key)
+}
+
+// environmentKey is the name of a setting's variable for an environment
+func environmentKey(environment, key string) string {
+	prefix := strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(environment))
+	return prefix + "_" + key
+}
+
+// SetOverrides gives settings that take precedence over both their plain and environment-prefixed
+// variables, such as command-line flags. LoadFromEnv reads them in place of the environment, which
+// is left untouched.
+func (c *Config) SetOverrides(settings map[string]string) {
+	c.overrides = settings
+}
+
+// getenv reads a setting, preferring an override to the environment
+func (c *Config) getenv(key string) string {
+	if value, ok := c.overrides[key]; ok {
+		return value
+	}
+	return getenv(key)
+}
@@ -0,0 +1,50 @@
+package betfair
+
+import (
+	"os"
+	"testing"
+)
+
+func TestOverridesBeatEnvironmentVariables(t *testing.T) {
+	t.Setenv("ENVIRONMENT", "staging")
+	t.Setenv("OUTPUT_PATH", "/data")
+	t.Setenv("STAGING_OUTPUT_PATH", "/data/staging")
+
+	cfg := NewConfig()
+	if got := cfg.getenv("OUTPUT_PATH"); got != "/data/staging" {
+		t.Fatalf("expected the staging setting, got %q", got)
+	}
+	cfg.SetOverrides(map[string]string{"OUTPUT_PATH": "/tmp/adhoc"})
+	if got := cfg.getenv("OUTPUT_PATH"); got != "/tmp/adhoc" {
+		t.Errorf("expected the override, got %q", got)
+	}
+	if os.Getenv("OUTPUT_PATH") != "/data" || os.Getenv("STAGING_OUTPUT_PATH") != "/data/staging" {
+		t.Error("expected the environment to be left untouched")
+	}
+}
+
+func TestLoadFromEnvAppliesOverrides(t *testing.T) {
+	t.Setenv("BETFAIR_APP_KEY", "app-key")
+	t.Setenv("BETFAIR_SESSION_TOKEN", "token")
+	t.Setenv("MARKET_IDS", "")
+	t.Setenv("EVENT_TYPE_ID", "")
+	t.Setenv("HEARTBEAT_MS", "3000")
+
+	cfg := NewConfig()
+	cfg.SetOverrides(map[string]string{"MARKET_IDS": "1.1,1.2", "HEARTBEAT_MS": "1000"})
+	if err := cfg.LoadFromEnv(); err != nil {
+		t.Fatalf("LoadFromEnv failed: %v", err)
+	}
+	if len(cfg.MarketIDs) != 2 || cfg.MarketIDs[1] != "1.2" {
+		t.Errorf("expected the overridden market IDs, got %v", cfg.MarketIDs)
+	}
+	if cfg.HeartbeatMs != 1000 {
+		t.Errorf("expected the overridden heartbeat, got %d", cfg.HeartbeatMs)
+	}
+	if update := cfg.FilterUpdate(); len(update.MarketIDs) != 2 {
+		t.Errorf("expected a reload to keep the overridden market IDs, got %v", update.MarketIDs)
+	}
+	if os.Getenv("MARKET_IDS") != "" {
+		t.Error("expected MARKET_IDS to stay unset in the environment")
+	}
+}
@@ -2,16 +2,86 @@ package betfair
 
 import (
 	"bufio"
+	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RecordingSuffix marks a compressed market file that is still being recorded, keeping it apart
+// from finished archives of the same market in the output directory
+const RecordingSuffix = ".recording"
+
+// ExistingFilePolicy decides what CreateMarketWriter does when a market file already holds data
+type ExistingFilePolicy string
+
+const (
+	ExistingFileError     ExistingFilePolicy = "error"
+	ExistingFileAppend    ExistingFilePolicy = "append"
+	ExistingFileOverwrite ExistingFilePolicy = "overwrite"
+)
+
+// ParseExistingFilePolicy parses "error", "append" or "overwrite"
+func ParseExistingFilePolicy(value string) (ExistingFilePolicy, error) {
+	policy := ExistingFilePolicy(strings.ToLower(strings.TrimSpace(value)))
+	switch policy {
+	case ExistingFileError, ExistingFileAppend, ExistingFileOverwrite:
+		return policy, nil
+	}
+	return "", fmt.Errorf("unknown existing file policy %q", value)
+}
+
+// OutputLayout decides where finished market archives are placed under the output directory
+type OutputLayout string
 
-	"github.com/dsnet/compress/bzip2"
+const (
+	// LayoutFlat keeps archives directly in the output directory
+	LayoutFlat OutputLayout = "flat"
+	// LayoutPartitioned mirrors the S3 layout: PRO/<year>/<month>/<day>/<eventId>/
+	LayoutPartitioned OutputLayout = "partitioned"
 )
 
+// ParseOutputLayout parses "flat" or "partitioned"
+func ParseOutputLayout(value string) (OutputLayout, error) {
+	layout := OutputLayout(strings.ToLower(strings.TrimSpace(value)))
+	switch layout {
+	case LayoutFlat, LayoutPartitioned:
+		return layout, nil
+	}
+	return "", fmt.Errorf("unknown output layout %q", value)
+}
+
+// MarketFileExistsError is returned by CreateMarketWriter when opening the file would discard recorded data
+type MarketFileExistsError struct {
+	MarketID string
+	Path     string
+	Size     int64
+}
+
+func (e *MarketFileExistsError) Error() string {
+	return fmt.Sprintf("market file %s already exists with %d bytes", e.Path, e.Size)
+}
+
+// RecoveryMarker is the op of the line written before appending to a file from an earlier run,
+// so readers can tell where recording resumed
+const RecoveryMarker = "recovery"
+
 type FileManager struct {
-	outputPath string
+	outputPath        string
+	existingFile      ExistingFilePolicy
+	compression       Compression
+	compressionLevel  int
+	streamCompression bool
+	layout            OutputLayout
+
+	streamsMu sync.Mutex
+	streams   map[string]streamCompressor
 }
 
 func NewFileManager(outputPath string) *FileManager {
@@ -19,16 +89,89 @@ func NewFileManager(outputPath string) *FileManager {
 		outputPath = "market_files"
 	}
 	return &FileManager{
-		outputPath: outputPath,
+		outputPath:   outputPath,
+		existingFile: ExistingFileError,
+		compression:  CompressionBzip2,
+		layout:       LayoutFlat,
+		streams:      make(map[string]streamCompressor),
+	}
+}
+
+// SetExistingFilePolicy changes how CreateMarketWriter treats market files that already hold data
+func (fm *FileManager) SetExistingFilePolicy(policy ExistingFilePolicy) {
+	fm.existingFile = policy
+}
+
+// SetCompression selects the format and level used by CompressFile; level zero uses the format's default
+func (fm *FileManager) SetCompression(compression Compression, level int) {
+	fm.compression = compression
+	fm.compressionLevel = level
+}
+
+// CompressedExtension returns the suffix of compressed market files, such as ".bz2"
+func (fm *FileManager) CompressedExtension() string {
+	return fm.compression.Extension()
+}
+
+// SetLayout changes where finished archives are placed; files being recorded always stay at the top level
+func (fm *FileManager) SetLayout(layout OutputLayout) {
+	fm.layout = layout
+}
+
+// ArchiveDir returns the directory finished files for an event are placed in
+func (fm *FileManager) ArchiveDir(eventInfo *EventInfo) string {
+	if fm.layout == LayoutPartitioned && eventInfo != nil {
+		return BuildEventPath(fm.outputPath, eventInfo)
+	}
+	return fm.outputPath
+}
+
+// GetArchivePath returns where the compressed archive of a finished market is placed
+func (fm *FileManager) GetArchivePath(eventInfo *EventInfo, name string) string {
+	return filepath.Join(fm.ArchiveDir(eventInfo), name+fm.CompressedExtension())
+}
+
+// SetStreamCompression makes CreateMarketWriter compress market files as they are written, in
+// the configured format, instead of compressing them once the market settles
+func (fm *FileManager) SetStreamCompression(enabled bool) {
+	fm.streamCompression = enabled
+}
+
+// StreamsCompressed reports whether market files are compressed while recording
+func (fm *FileManager) StreamsCompressed() bool {
+	return fm.streamCompression
+}
+
+// RecordingFilePath returns the file a market is recorded into: a compressed file marked with
+// RecordingSuffix when streaming compression is on, otherwise the uncompressed market file
+func (fm *FileManager) RecordingFilePath(marketID string) string {
+	if fm.streamCompression {
+		return filepath.Join(fm.outputPath, marketID+RecordingSuffix+fm.CompressedExtension())
 	}
+	return fm.GetMarketFilePath(marketID)
 }
 
+// CreateMarketWriter opens a new market file. If the file already holds data it is appended to,
+// overwritten, or a *MarketFileExistsError is returned depending on the existing file policy.
 func (fm *FileManager) CreateMarketWriter(marketID string) (*bufio.Writer, *os.File, error) {
 	if err := os.MkdirAll(fm.outputPath, 0755); err != nil {
 		return nil, nil, fmt.Errorf("create market_files directory: %w", err)
 	}
+	if fm.streamCompression {
+		return fm.createStreamingWriter(marketID, fm.existingFile)
+	}
 
 	filePath := filepath.Join(fm.outputPath, marketID)
+	if info, err := os.Stat(filePath); err == nil && info.Size() > 0 {
+		switch fm.existingFile {
+		case ExistingFileAppend:
+			return fm.AppendMarketWriter(marketID)
+		case ExistingFileOverwrite:
+		default:
+			return nil, nil, &MarketFileExistsError{MarketID: marketID, Path: filePath, Size: info.Size()}
+		}
+	}
+
 	file, err := os.Create(filePath)
 	if err != nil {
 		return nil, nil, err
@@ -38,48 +181,290 @@ func (fm *FileManager) CreateMarketWriter(marketID string) (*bufio.Writer, *os.F
 	return writer, file, nil
 }
 
-func (fm *FileManager) GetMarketFilePath(marketID string) string {
-	return filepath.Join(fm.outputPath, marketID)
+// createStreamingWriter opens a compressed market file. Appending starts a new gzip member or zstd
+// frame, which standard readers decode as a continuation of the same stream; a member cut short
+// by a crash is repaired first so the file stays readable past it.
+func (fm *FileManager) createStreamingWriter(marketID string, existing ExistingFilePolicy) (*bufio.Writer, *os.File, error) {
+	filePath := fm.RecordingFilePath(marketID)
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	resumed := false
+	if info, err := os.Stat(filePath); err == nil && info.Size() > 0 {
+		switch existing {
+		case ExistingFileAppend:
+			if err := fm.repairStream(filePath); err != nil {
+				return nil, nil, fmt.Errorf("repair compressed market file: %w", err)
+			}
+			flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+			resumed = true
+		case ExistingFileOverwrite:
+		default:
+			return nil, nil, &MarketFileExistsError{MarketID: marketID, Path: filePath, Size: info.Size()}
+		}
+	}
+
+	file, err := os.OpenFile(filePath, flags, 0644)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	compressor, err := newStreamCompressor(file, fm.compression, fm.compressionLevel)
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+
+	writer := bufio.NewWriter(compressor)
+	if resumed {
+		marker := fmt.Sprintf("{\"op\":%q,\"pt\":%d}\n", RecoveryMarker, time.Now().UnixMilli())
+		if _, err := writer.WriteString(marker); err != nil {
+			file.Close()
+			return nil, nil, fmt.Errorf("write recovery marker: %w", err)
+		}
+	}
+
+	fm.streamsMu.Lock()
+	fm.streams[marketID] = compressor
+	fm.streamsMu.Unlock()
+	return writer, file, nil
 }
 
-func (fm *FileManager) GetCompressedFilePath(marketID string) string {
-	return filepath.Join(fm.outputPath, marketID+".bz2")
+// repairStream rewrites a compressed market file whose last gzip member or zstd frame was cut
+// short by a crash, keeping everything that can still be decoded and ending it on a full line.
+// Intact files are left alone.
+func (fm *FileManager) repairStream(filePath string) error {
+	if err := fm.decodeStream(filePath, io.Discard); err == nil {
+		return nil
+	}
+
+	tmpPath := filePath + ".tmp"
+	tmp, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpPath)
+
+	compressor, err := newCompressor(tmp, fm.compression, fm.compressionLevel)
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	lines := &lineEndWriter{w: compressor}
+	// The error is the truncation being repaired; everything decoded before it has been written.
+	// A file nothing can be decoded from is left for someone to look at.
+	decodeErr := fm.decodeStream(filePath, lines)
+	if lines.written && !lines.atLineEnd {
+		lines.Write([]byte{'\n'})
+	}
+	if !lines.written || lines.err != nil {
+		compressor.Close()
+		tmp.Close()
+		return errors.Join(decodeErr, lines.err)
+	}
+	if err := compressor.Close(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, filePath)
 }
 
-func (fm *FileManager) CompressToBzip2(inputFile, outputFile string) error {
-	input, err := os.Open(inputFile)
+// decodeStream decompresses a market file written in the configured format into w
+func (fm *FileManager) decodeStream(filePath string, w io.Writer) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader, err := newDecompressor(file, fm.compression)
+	if err != nil {
+		return err
+	}
+	if closer, ok := reader.(interface{ Close() }); ok {
+		defer closer.Close()
+	}
+	_, err = io.Copy(w, reader)
+	return err
+}
+
+// lineEndWriter remembers whether the data written so far ends on a full line
+type lineEndWriter struct {
+	w         io.Writer
+	written   bool
+	atLineEnd bool
+	err       error
+}
+
+func (l *lineEndWriter) Write(p []byte) (int, error) {
+	n, err := l.w.Write(p)
+	if n > 0 {
+		l.written = true
+		l.atLineEnd = p[n-1] == '\n'
+	}
+	if err != nil {
+		l.err = err
+	}
+	return n, err
+}
+
+// takeMarketStream hands over the compressor of a streamed market file; the caller must flush
+// and close it. It returns nil for uncompressed files.
+func (fm *FileManager) takeMarketStream(marketID string) streamCompressor {
+	fm.streamsMu.Lock()
+	defer fm.streamsMu.Unlock()
+	stream := fm.streams[marketID]
+	delete(fm.streams, marketID)
+	return stream
+}
+
+// AppendMarketWriter opens a market file for appending, creating it if needed, so data recorded
+// by a previous run is kept. A recovery marker line is written first when the file is not empty.
+func (fm *FileManager) AppendMarketWriter(marketID string) (*bufio.Writer, *os.File, error) {
+	if err := os.MkdirAll(fm.outputPath, 0755); err != nil {
+		return nil, nil, fmt.Errorf("create market_files directory: %w", err)
+	}
+	if fm.streamCompression {
+		return fm.createStreamingWriter(marketID, ExistingFileAppend)
+	}
+
+	file, err := os.OpenFile(fm.GetMarketFilePath(marketID), os.O_CREATE|os.O_RDWR|os.O_APPEND, 0644)
 	if err != nil {
-		return fmt.Errorf("open input file: %w", err)
+		return nil, nil, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, nil, fmt.Errorf("stat market file: %w", err)
+	}
+
+	writer := bufio.NewWriter(file)
+	if info.Size() > 0 {
+		// Terminate a line cut short by a crash so the marker starts on its own line
+		last := make([]byte, 1)
+		if _, err := file.ReadAt(last, info.Size()-1); err == nil && last[0] != '\n' {
+			writer.WriteByte('\n')
+		}
+		marker := fmt.Sprintf("{\"op\":%q,\"pt\":%d}\n", RecoveryMarker, time.Now().UnixMilli())
+		if _, err := writer.WriteString(marker); err != nil {
+			file.Close()
+			return nil, nil, fmt.Errorf("write recovery marker: %w", err)
+		}
 	}
-	defer input.Close()
 
-	output, err := os.Create(outputFile)
+	return writer, file, nil
+}
+
+// MarketFileExists reports whether an uncompressed file for the market is already on disk
+func (fm *FileManager) MarketFileExists(marketID string) bool {
+	info, err := os.Stat(fm.GetMarketFilePath(marketID))
+	return err == nil && !info.IsDir()
+}
+
+// ListMarketFiles returns the IDs of markets whose recording files are left in the output
+// directory: uncompressed files, or files marked with RecordingSuffix when streaming compression is on
+func (fm *FileManager) ListMarketFiles() ([]string, error) {
+	entries, err := os.ReadDir(fm.outputPath)
 	if err != nil {
-		return fmt.Errorf("create output file: %w", err)
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read output directory: %w", err)
+	}
+
+	var suffix string
+	if fm.streamCompression {
+		suffix = RecordingSuffix + fm.CompressedExtension()
 	}
-	defer output.Close()
 
-	bz2Writer, err := bzip2.NewWriter(output, &bzip2.WriterConfig{Level: bzip2.DefaultCompression})
+	var marketIDs []string
+	for _, entry := range entries {
+		marketID, found := strings.CutSuffix(entry.Name(), suffix)
+		if entry.IsDir() || !found || !ValidateMarketID(marketID) {
+			continue
+		}
+		marketIDs = append(marketIDs, marketID)
+	}
+	sort.Strings(marketIDs)
+	return marketIDs, nil
+}
+
+// LastMarketDefinition returns the last recorded line of a market file carrying a market definition
+func (fm *FileManager) LastMarketDefinition(marketID string) ([]byte, error) {
+	file, err := os.Open(fm.RecordingFilePath(marketID))
 	if err != nil {
-		return fmt.Errorf("create bzip2 writer: %w", err)
+		return nil, fmt.Errorf("open market file: %w", err)
+	}
+	defer file.Close()
+
+	var reader io.Reader = file
+	if fm.streamCompression {
+		if reader, err = newDecompressor(file, fm.compression); err != nil {
+			return nil, fmt.Errorf("open compressed market file: %w", err)
+		}
+		if closer, ok := reader.(interface{ Close() }); ok {
+			defer closer.Close()
+		}
 	}
-	defer bz2Writer.Close()
 
-	if _, err := io.Copy(bz2Writer, input); err != nil {
-		return fmt.Errorf("compress data: %w", err)
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	var last []byte
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if bytes.Contains(line, []byte(`"marketDefinition"`)) {
+			last = append(last[:0], line...)
+		}
+	}
+	// A streamed file may end mid-block; everything decoded up to that point is still usable
+	if err := scanner.Err(); err != nil && (last == nil || !fm.streamCompression) {
+		return nil, fmt.Errorf("scan market file: %w", err)
 	}
+	if last == nil {
+		return nil, fmt.Errorf("no market definition recorded for market %s", marketID)
+	}
+	return last, nil
+}
+
+func (fm *FileManager) GetMarketFilePath(marketID string) string {
+	return filepath.Join(fm.outputPath, marketID)
+}
 
-	return nil
+func (fm *FileManager) GetCompressedFilePath(marketID string) string {
+	return filepath.Join(fm.outputPath, marketID+fm.CompressedExtension())
+}
+
+// RecordingIndexPath returns the recording index kept in the output directory
+func (fm *FileManager) RecordingIndexPath() string {
+	return filepath.Join(fm.outputPath, RecordingIndexName)
+}
+
+// CompressFile compresses a market file with the configured format
+func (fm *FileManager) CompressFile(inputFile, outputFile string) error {
+	return compressFile(inputFile, outputFile, fm.compression, fm.compressionLevel)
+}
+
+func (fm *FileManager) CompressToBzip2(inputFile, outputFile string) error {
+	return compressFile(inputFile, outputFile, CompressionBzip2, 0)
 }
 
-func (fm *FileManager) CleanupFiles(files ...string) {
+// CleanupFiles removes files, returning an error for each one that could not be removed. Files
+// that are already gone are not an error.
+func (fm *FileManager) CleanupFiles(files ...string) []error {
+	var errs []error
 	for _, file := range files {
-		if err := os.Remove(file); err != nil {
-			// Silently ignore cleanup errors
+		if err := os.Remove(file); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, fmt.Errorf("remove %s: %w", file, err))
 		}
 	}
+	return errs
 }
 
+// BuildEventPath returns the directory under basePath an event's files are filed in, in the
+// event's layout version
 func BuildEventPath(basePath string, eventInfo *EventInfo) string {
-	return filepath.Join(basePath, "PRO", eventInfo.Year, eventInfo.Month, eventInfo.Day, eventInfo.EventID)
+	return filepath.Join(append([]string{basePath}, eventPathSegments(eventInfo)...)...)
 }
\ No newline at end of file
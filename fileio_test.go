@@ -1,12 +1,16 @@
 package betfair
 
 import (
+	"context"
+	"errors"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/dsnet/compress/bzip2"
+	"github.com/rs/zerolog"
 )
 
 func TestFileManagerCreateMarketWriter(t *testing.T) {
@@ -154,7 +158,9 @@ func TestFileManagerCleanupFiles(t *testing.T) {
 	}
 
 	// Clean up files
-	fm.CleanupFiles(file1, file2, file3)
+	if errs := fm.CleanupFiles(file1, file2, file3); len(errs) != 0 {
+		t.Errorf("Expected no cleanup errors, got %v", errs)
+	}
 
 	// Verify files are removed
 	for _, file := range []string{file1, file2, file3} {
@@ -175,11 +181,33 @@ func TestFileManagerCleanupNonexistentFiles(t *testing.T) {
 		filepath.Join(tempDir, "nonexistent3.txt"),
 	}
 
-	// This should not panic or error
-	fm.CleanupFiles(nonexistentFiles...)
+	// Files that are already gone should not be reported
+	if errs := fm.CleanupFiles(nonexistentFiles...); len(errs) != 0 {
+		t.Errorf("Expected no cleanup errors, got %v", errs)
+	}
+}
+
+func TestFileManagerCleanupReportsErrors(t *testing.T) {
+	tempDir := t.TempDir()
+	fm := NewFileManager(tempDir)
+
+	// A non-empty directory cannot be removed with os.Remove
+	dir := filepath.Join(tempDir, "dir")
+	if err := os.MkdirAll(filepath.Join(dir, "child"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	file := filepath.Join(tempDir, "file")
+	if err := os.WriteFile(file, []byte("data"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
 
-	// Test passes if we reach this point without error
-	t.Log("Cleanup of nonexistent files completed without error")
+	errs := fm.CleanupFiles(dir, file)
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), dir) {
+		t.Fatalf("Expected one error naming the directory, got %v", errs)
+	}
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Error("Expected remaining files to be removed after an error")
+	}
 }
 
 func TestBuildEventPath(t *testing.T) {
@@ -372,4 +400,221 @@ func TestFileManagerWithOutputPathSet(t *testing.T) {
 	}
 
 	t.Log("✅ OUTPUT_PATH=market_files functionality verified: directory auto-created, files saved correctly")
-}
\ No newline at end of file
+}
+
+func TestFileManagerListMarketFilesAndAppend(t *testing.T) {
+	tempDir := t.TempDir()
+	fm := NewFileManager(tempDir)
+
+	for name, content := range map[string]string{
+		"1.200":     `{"op":"mcm","mc":[{"marketDefinition":{"eventId":"1","status":"OPEN"}}]}` + "\n" + `{"op":"mcm","mc":[{"rc":[]}]}` + "\n",
+		"1.100":     "",
+		"1.100.bz2": "",
+		"notes.txt": "",
+	} {
+		if err := os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	ids, err := fm.ListMarketFiles()
+	if err != nil {
+		t.Fatalf("ListMarketFiles failed: %v", err)
+	}
+	if strings.Join(ids, ",") != "1.100,1.200" {
+		t.Errorf("Expected only uncompressed market files, got %v", ids)
+	}
+
+	definition, err := fm.LastMarketDefinition("1.200")
+	if err != nil {
+		t.Fatalf("LastMarketDefinition failed: %v", err)
+	}
+	if !strings.Contains(string(definition), `"eventId":"1"`) {
+		t.Errorf("Unexpected market definition line: %s", definition)
+	}
+	if _, err := fm.LastMarketDefinition("1.100"); err == nil {
+		t.Error("Expected error for file without a market definition")
+	}
+
+	writer, file, err := fm.AppendMarketWriter("1.200")
+	if err != nil {
+		t.Fatalf("AppendMarketWriter failed: %v", err)
+	}
+	writer.WriteString("appended\n")
+	writer.Flush()
+	file.Close()
+
+	content, _ := os.ReadFile(fm.GetMarketFilePath("1.200"))
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if len(lines) != 4 || ExtractOp([]byte(lines[2])) != RecoveryMarker || lines[3] != "appended" {
+		t.Errorf("Expected existing data to be kept after a recovery marker, got %q", content)
+	}
+}
+
+func TestFileManagerRecoversStreamedGzipRecording(t *testing.T) {
+	tempDir := t.TempDir()
+	streaming := func() *FileManager {
+		fm := NewFileManager(tempDir)
+		fm.SetCompression(CompressionGzip, 0)
+		fm.SetStreamCompression(true)
+		return fm
+	}
+
+	fm := streaming()
+	writer, file, err := fm.CreateMarketWriter("1.300")
+	if err != nil {
+		t.Fatalf("CreateMarketWriter failed: %v", err)
+	}
+	writer.WriteString(`{"op":"mcm","mc":[{"marketDefinition":{"eventId":"7","status":"OPEN"}}]}` + "\n")
+	writer.WriteString(`{"op":"mcm","mc":[{"rc":[{"id":1,"ltp":2.5}]}]}` + "\n")
+	writer.WriteString(`{"op":"mcm","mc":[{"rc":[`)
+	writer.Flush()
+	// A crash after a flush leaves a gzip member with no trailer and a line cut short
+	if err := fm.takeMarketStream("1.300").Flush(); err != nil {
+		t.Fatalf("flush stream: %v", err)
+	}
+	file.Close()
+
+	// A finished archive of another market is not a recording to resume
+	if err := os.WriteFile(filepath.Join(tempDir, "1.100.gz"), []byte("done"), 0644); err != nil {
+		t.Fatalf("write archive: %v", err)
+	}
+
+	recovered := streaming()
+	ids, err := recovered.ListMarketFiles()
+	if err != nil {
+		t.Fatalf("ListMarketFiles failed: %v", err)
+	}
+	if strings.Join(ids, ",") != "1.300" {
+		t.Fatalf("Expected the streamed recording to be found, got %v", ids)
+	}
+
+	definition, err := recovered.LastMarketDefinition("1.300")
+	if err != nil {
+		t.Fatalf("LastMarketDefinition failed: %v", err)
+	}
+	if !strings.Contains(string(definition), `"eventId":"7"`) {
+		t.Errorf("Unexpected market definition line: %s", definition)
+	}
+
+	writer, file, err = recovered.AppendMarketWriter("1.300")
+	if err != nil {
+		t.Fatalf("AppendMarketWriter failed: %v", err)
+	}
+	writer.WriteString("appended\n")
+	writer.Flush()
+	if err := recovered.takeMarketStream("1.300").Close(); err != nil {
+		t.Fatalf("close stream: %v", err)
+	}
+	file.Close()
+
+	var decoded strings.Builder
+	if err := recovered.decodeStream(recovered.RecordingFilePath("1.300"), &decoded); err != nil {
+		t.Fatalf("Expected the resumed recording to decode in full: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(decoded.String()), "\n")
+	if len(lines) != 5 || ExtractOp([]byte(lines[3])) != RecoveryMarker || lines[4] != "appended" {
+		t.Errorf("Expected recorded data, the cut line, a recovery marker and new data, got %q", lines)
+	}
+}
+
+func TestFileManagerExistingFilePolicy(t *testing.T) {
+	tempDir := t.TempDir()
+	fm := NewFileManager(tempDir)
+	path := fm.GetMarketFilePath("1.300")
+
+	// A crash left a partially written line behind
+	if err := os.WriteFile(path, []byte("{\"op\":\"mcm\"}\n{\"op\":\"mc"), 0644); err != nil {
+		t.Fatalf("Failed to write market file: %v", err)
+	}
+
+	_, _, err := fm.CreateMarketWriter("1.300")
+	var existsErr *MarketFileExistsError
+	if !errors.As(err, &existsErr) {
+		t.Fatalf("Expected MarketFileExistsError, got %v", err)
+	}
+	if existsErr.MarketID != "1.300" || existsErr.Size == 0 {
+		t.Errorf("Unexpected error details: %+v", existsErr)
+	}
+
+	fm.SetExistingFilePolicy(ExistingFileAppend)
+	writer, file, err := fm.CreateMarketWriter("1.300")
+	if err != nil {
+		t.Fatalf("CreateMarketWriter in append mode failed: %v", err)
+	}
+	writer.WriteString("{\"op\":\"mcm\",\"pt\":2}\n")
+	writer.Flush()
+	file.Close()
+
+	content, _ := os.ReadFile(path)
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("Expected original lines, marker and new line, got %q", content)
+	}
+	if ExtractOp([]byte(lines[2])) != RecoveryMarker {
+		t.Errorf("Expected recovery marker on its own line, got %q", lines[2])
+	}
+
+	fm.SetExistingFilePolicy(ExistingFileOverwrite)
+	writer, file, err = fm.CreateMarketWriter("1.300")
+	if err != nil {
+		t.Fatalf("CreateMarketWriter in overwrite mode failed: %v", err)
+	}
+	file.Close()
+	if info, _ := os.Stat(path); info.Size() != 0 {
+		t.Errorf("Expected file to be truncated, got %d bytes", info.Size())
+	}
+
+	if _, err := ParseExistingFilePolicy("Append"); err != nil {
+		t.Errorf("Expected policy to parse case-insensitively: %v", err)
+	}
+	if _, err := ParseExistingFilePolicy("keep"); err == nil {
+		t.Error("Expected unknown policy to be rejected")
+	}
+}
+
+func TestFileManagerPartitionedArchives(t *testing.T) {
+	tempDir := t.TempDir()
+	fm := NewFileManager(tempDir)
+	eventInfo := NewEventInfo("100", time.Date(2025, 10, 1, 9, 0, 0, 0, time.UTC))
+
+	if got := fm.GetArchivePath(eventInfo, "1.1"); got != filepath.Join(tempDir, "1.1.bz2") {
+		t.Errorf("Expected flat archive path, got %s", got)
+	}
+
+	fm.SetLayout(LayoutPartitioned)
+	want := filepath.Join(tempDir, "PRO", "2025", "Oct", "1", "100", "1.1.bz2")
+	if got := fm.GetArchivePath(eventInfo, "1.1"); got != want {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+	if got := fm.RecordingFilePath("1.1"); got != filepath.Join(tempDir, "1.1") {
+		t.Errorf("Expected files being recorded to stay at the top level, got %s", got)
+	}
+
+	if _, err := ParseOutputLayout("nested"); err == nil {
+		t.Error("Expected unknown layout to fail")
+	}
+}
+
+func TestMarketRecorderArchivesIntoPartitions(t *testing.T) {
+	tempDir := t.TempDir()
+	fm := NewFileManager(tempDir)
+	fm.SetLayout(LayoutPartitioned)
+	recorder := &MarketRecorder{config: &Config{}, logger: zerolog.Nop(), fileManager: fm}
+
+	payload := []byte(`{"op":"mcm","mc":[{"marketDefinition":{"eventId":"100","openDate":"2025-10-01T09:00:00Z","status":"CLOSED"}}]}`)
+	if err := os.WriteFile(fm.GetMarketFilePath("1.1"), append(payload, '\n'), 0644); err != nil {
+		t.Fatalf("write market file: %v", err)
+	}
+
+	if err := recorder.archiveMarketFile(context.Background(), "1.1", payload, false, &MarketTimeline{MarketID: "1.1"}, marketCounts{}); err != nil {
+		t.Fatalf("archiveMarketFile failed: %v", err)
+	}
+
+	dir := filepath.Join(tempDir, "PRO", "2025", "Oct", "1", "100")
+	for _, name := range []string{"1.1.bz2", "1.1" + TimelineSuffix} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("Expected %s in the event directory: %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package betfair
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const gcsEndpoint = "https://storage.googleapis.com"
+
+// GCSStorage stores market files in a Google Cloud Storage bucket through the JSON API
+type GCSStorage struct {
+	client   *http.Client
+	endpoint string
+	tokens   gcsTokenSource
+	bucket   string
+	basePath string
+}
+
+// NewGCSStorage opens a bucket using Application Default Credentials: GOOGLE_OAUTH_ACCESS_TOKEN,
+// the service account key named by GOOGLE_APPLICATION_CREDENTIALS, or the GCE metadata server.
+// STORAGE_EMULATOR_HOST points it at an unauthenticated emulator instead.
+func NewGCSStorage(ctx context.Context, bucket, basePath string) (*GCSStorage, error) {
+	if bucket == "" {
+		return nil, fmt.Errorf("GCS bucket not configured")
+	}
+
+	client := &http.Client{Timeout: 5 * time.Minute}
+	storage := &GCSStorage{
+		client:   client,
+		endpoint: gcsEndpoint,
+		bucket:   bucket,
+		basePath: basePath,
+	}
+
+	if host := strings.TrimSpace(getenv("STORAGE_EMULATOR_HOST")); host != "" {
+		if !strings.Contains(host, "://") {
+			host = "http://" + host
+		}
+		storage.endpoint = strings.TrimRight(host, "/")
+		return storage, nil
+	}
+
+	tokens, err := defaultGCSTokenSource(client)
+	if err != nil {
+		return nil, err
+	}
+	storage.tokens = tokens
+	return storage, nil
+}
+
+func (s *GCSStorage) Upload(ctx context.Context, filePath, key string) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return fmt.Errorf("open file: %w", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("stat file: %w", err)
+	}
+
+	query := url.Values{"uploadType": {"media"}, "name": {NormalizeKey(key)}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint+"/upload/storage/v1/b/"+url.PathEscape(s.bucket)+"/o?"+query.Encode(), file)
+	if err != nil {
+		return fmt.Errorf("build GCS upload request: %w", err)
+	}
+	req.ContentLength = info.Size()
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	resp, err := s.do(req)
+	if err != nil {
+		return fmt.Errorf("upload to GCS: %w", err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Download opens an object for reading; the caller closes it
+func (s *GCSStorage) Download(ctx context.Context, key string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.objectURL(key)+"?alt=media", nil)
+	if err != nil {
+		return nil, fmt.Errorf("build GCS download request: %w", err)
+	}
+	resp, err := s.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("get GCS object: %w", err)
+	}
+	return resp.Body, nil
+}
+
+// gcsObject is the object resource returned by the JSON API
+type gcsObject struct {
+	Name    string `json:"name"`
+	Size    string `json:"size"`
+	MD5Hash string `json:"md5Hash"`
+	ETag    string `json:"etag"`
+}
+
+func (o gcsObject) info() ObjectInfo {
+	size, _ := strconv.ParseInt(o.Size, 10, 64)
+	info := ObjectInfo{Key: o.Name, Size: size, ETag: o.ETag}
+	// Composite objects carry no MD5
+	if sum, err := base64.StdEncoding.DecodeString(o.MD5Hash); err == nil && len(sum) > 0 {
+		info.MD5 = hex.EncodeToString(sum)
+	}
+	return info
+}
+
+// List returns every object whose key starts with prefix
+func (s *GCSStorage) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	var objects []ObjectInfo
+	pageToken := ""
+	for {
+		query := url.Values{"prefix": {NormalizeKey(prefix)}}
+		if pageToken != "" {
+			query.Set("pageToken", pageToken)
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.endpoint+"/storage/v1/b/"+url.PathEscape(s.bucket)+"/o?"+query.Encode(), nil)
+		if err != nil {
+			return nil, fmt.Errorf("build GCS list request: %w", err)
+		}
+
+		var page struct {
+			Items         []gcsObject `json:"items"`
+			NextPageToken string      `json:"nextPageToken"`
+		}
+		if err := s.doJSON(req, &page); err != nil {
+			return nil, fmt.Errorf("list GCS objects: %w", err)
+		}
+		for _, item := range page.Items {
+			objects = append(objects, item.info())
+		}
+		if page.NextPageToken == "" {
+			return objects, nil
+		}
+		pageToken = page.NextPageToken
+	}
+}
+
+func (s *GCSStorage) Head(ctx context.Context, key string) (ObjectInfo, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.objectURL(key), nil)
+	if err != nil {
+		return ObjectInfo{}, fmt.Errorf("build GCS metadata request: %w", err)
+	}
+	var object gcsObject
+	if err := s.doJSON(req, &object); err != nil {
+		return ObjectInfo{}, fmt.Errorf("head GCS object: %w", err)
+	}
+	return object.info(), nil
+}
+
+// Verify checks the stored object's size and MD5
+func (s *GCSStorage) Verify(ctx context.Context, key string, size int64, md5Hex string) error {
+	info, err := s.Head(ctx, key)
+	if err != nil {
+		return err
+	}
+	return verifyObject(info, size, md5Hex)
+}
+
+func (s *GCSStorage) BuildKey(eventInfo *EventInfo, filename string) string {
+	return buildObjectKey(s.basePath, eventInfo, filename)
+}
+
+func (s *GCSStorage) objectURL(key string) string {
+	return s.endpoint + "/storage/v1/b/" + url.PathEscape(s.bucket) + "/o/" + url.PathEscape(NormalizeKey(key))
+}
+
+// do sends an authorised request and returns the response if it succeeded
+func (s *GCSStorage) do(req *http.Request) (*http.Response, error) {
+	if s.tokens != nil {
+		token, err := s.tokens.Token(req.Context())
+		if err != nil {
+			return nil, fmt.Errorf("get GCS access token: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return resp, nil
+}
+
+func (s *GCSStorage) doJSON(req *http.Request, out interface{}) error {
+	resp, err := s.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,216 @@
+package betfair
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	gcsScope         = "https://www.googleapis.com/auth/devstorage.read_write"
+	googleTokenURL   = "https://oauth2.googleapis.com/token"
+	gceMetadataToken = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+
+	// tokenExpiryMargin renews access tokens this long before they expire
+	tokenExpiryMargin = time.Minute
+)
+
+// gcsTokenSource supplies OAuth access tokens for Google Cloud Storage requests
+type gcsTokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+type staticToken string
+
+func (t staticToken) Token(context.Context) (string, error) {
+	return string(t), nil
+}
+
+// cachedToken reuses a fetched token until shortly before it expires
+type cachedToken struct {
+	fetch func(ctx context.Context) (string, time.Duration, error)
+	now   func() time.Time
+
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+func newCachedToken(fetch func(ctx context.Context) (string, time.Duration, error)) *cachedToken {
+	return &cachedToken{fetch: fetch, now: time.Now}
+}
+
+func (c *cachedToken) Token(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token != "" && c.now().Before(c.expiry.Add(-tokenExpiryMargin)) {
+		return c.token, nil
+	}
+	token, lifetime, err := c.fetch(ctx)
+	if err != nil {
+		return "", err
+	}
+	c.token = token
+	c.expiry = c.now().Add(lifetime)
+	return token, nil
+}
+
+// defaultGCSTokenSource picks credentials the way Google's client libraries do
+func defaultGCSTokenSource(client *http.Client) (gcsTokenSource, error) {
+	if token := strings.TrimSpace(getenv("GOOGLE_OAUTH_ACCESS_TOKEN")); token != "" {
+		return staticToken(token), nil
+	}
+	if path := strings.TrimSpace(getenv("GOOGLE_APPLICATION_CREDENTIALS")); path != "" {
+		account, err := loadServiceAccount(path)
+		if err != nil {
+			return nil, err
+		}
+		return newCachedToken(account.fetcher(client)), nil
+	}
+	return newCachedToken(metadataFetcher(client, gceMetadataToken)), nil
+}
+
+// tokenResponse is the OAuth token endpoint and metadata server reply
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+func decodeTokenResponse(resp *http.Response) (string, time.Duration, error) {
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", 0, fmt.Errorf("token request failed: %s", resp.Status)
+	}
+	var token tokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", 0, fmt.Errorf("decode token response: %w", err)
+	}
+	if token.AccessToken == "" {
+		return "", 0, errors.New("token response has no access token")
+	}
+	return token.AccessToken, time.Duration(token.ExpiresIn) * time.Second, nil
+}
+
+func metadataFetcher(client *http.Client, endpoint string) func(ctx context.Context) (string, time.Duration, error) {
+	return func(ctx context.Context) (string, time.Duration, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+		if err != nil {
+			return "", 0, fmt.Errorf("build metadata token request: %w", err)
+		}
+		req.Header.Set("Metadata-Flavor", "Google")
+		resp, err := client.Do(req)
+		if err != nil {
+			return "", 0, fmt.Errorf("request token from metadata server: %w", err)
+		}
+		return decodeTokenResponse(resp)
+	}
+}
+
+// serviceAccount is the subset of a service account key file needed to mint tokens
+type serviceAccount struct {
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+
+	key *rsa.PrivateKey
+}
+
+func loadServiceAccount(path string) (*serviceAccount, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read service account key: %w", err)
+	}
+	var account serviceAccount
+	if err := json.Unmarshal(data, &account); err != nil {
+		return nil, fmt.Errorf("decode service account key: %w", err)
+	}
+	if account.ClientEmail == "" {
+		return nil, errors.New("service account key has no client_email")
+	}
+	if account.TokenURI == "" {
+		account.TokenURI = googleTokenURL
+	}
+
+	block, _ := pem.Decode([]byte(account.PrivateKey))
+	if block == nil {
+		return nil, errors.New("service account key has no PEM private key")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		if account.key, err = x509.ParsePKCS1PrivateKey(block.Bytes); err != nil {
+			return nil, fmt.Errorf("parse service account private key: %w", err)
+		}
+		return &account, nil
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("service account private key is not RSA")
+	}
+	account.key = key
+	return &account, nil
+}
+
+// assertion builds the signed JWT exchanged for an access token
+func (a *serviceAccount) assertion(now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]interface{}{
+		"iss":   a.ClientEmail,
+		"scope": gcsScope,
+		"aud":   a.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	encoding := base64.RawURLEncoding
+	unsigned := encoding.EncodeToString(header) + "." + encoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, a.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("sign token assertion: %w", err)
+	}
+	return unsigned + "." + encoding.EncodeToString(signature), nil
+}
+
+func (a *serviceAccount) fetcher(client *http.Client) func(ctx context.Context) (string, time.Duration, error) {
+	return func(ctx context.Context) (string, time.Duration, error) {
+		assertion, err := a.assertion(time.Now())
+		if err != nil {
+			return "", 0, err
+		}
+		form := url.Values{
+			"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+			"assertion":  {assertion},
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.TokenURI, strings.NewReader(form.Encode()))
+		if err != nil {
+			return "", 0, fmt.Errorf("build token request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		resp, err := client.Do(req)
+		if err != nil {
+			return "", 0, fmt.Errorf("request service account token: %w", err)
+		}
+		return decodeTokenResponse(resp)
+	}
+}
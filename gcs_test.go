@@ -0,0 +1,280 @@
+package betfair
+
+import (
+	"context"
+	"crypto"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeGCS serves the parts of the GCS JSON API the storage backend uses
+type fakeGCS struct {
+	mu       sync.Mutex
+	objects  map[string][]byte
+	pageSize int
+	auth     []string
+}
+
+func newFakeGCS(t *testing.T) (*fakeGCS, *httptest.Server) {
+	fake := &fakeGCS{objects: make(map[string][]byte), pageSize: 1000}
+	server := httptest.NewServer(http.HandlerFunc(fake.serve))
+	t.Cleanup(server.Close)
+	return fake, server
+}
+
+func (f *fakeGCS) resource(name string, data []byte) map[string]string {
+	sum := md5.Sum(data)
+	return map[string]string{
+		"name":    name,
+		"size":    fmt.Sprint(len(data)),
+		"md5Hash": base64.StdEncoding.EncodeToString(sum[:]),
+	}
+}
+
+func (f *fakeGCS) serve(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.auth = append(f.auth, r.Header.Get("Authorization"))
+
+	const bucketPath = "/storage/v1/b/bucket/o"
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/upload"+bucketPath:
+		data, _ := io.ReadAll(r.Body)
+		name := r.URL.Query().Get("name")
+		f.objects[name] = data
+		json.NewEncoder(w).Encode(f.resource(name, data))
+
+	case r.Method == http.MethodGet && r.URL.Path == bucketPath:
+		var names []string
+		for name := range f.objects {
+			if strings.HasPrefix(name, r.URL.Query().Get("prefix")) {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		start := 0
+		if token := r.URL.Query().Get("pageToken"); token != "" {
+			fmt.Sscan(token, &start)
+		}
+		end := start + f.pageSize
+		page := map[string]interface{}{}
+		if end < len(names) {
+			page["nextPageToken"] = fmt.Sprint(end)
+		} else {
+			end = len(names)
+		}
+		var items []map[string]string
+		for _, name := range names[start:end] {
+			items = append(items, f.resource(name, f.objects[name]))
+		}
+		page["items"] = items
+		json.NewEncoder(w).Encode(page)
+
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, bucketPath+"/"):
+		name := strings.TrimPrefix(r.URL.Path, bucketPath+"/")
+		data, ok := f.objects[name]
+		if !ok {
+			http.Error(w, "No such object", http.StatusNotFound)
+			return
+		}
+		if r.URL.Query().Get("alt") == "media" {
+			w.Write(data)
+			return
+		}
+		json.NewEncoder(w).Encode(f.resource(name, data))
+
+	default:
+		http.Error(w, "unexpected request", http.StatusBadRequest)
+	}
+}
+
+func TestGCSStorageRoundTrip(t *testing.T) {
+	fake, server := newFakeGCS(t)
+	fake.pageSize = 1
+	storage := &GCSStorage{client: server.Client(), endpoint: server.URL, bucket: "bucket", tokens: staticToken("secret")}
+	ctx := context.Background()
+
+	file := filepath.Join(t.TempDir(), "1.1.bz2")
+	if err := os.WriteFile(file, []byte("market data"), 0644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	for _, key := range []string{"PRO/2025/Oct/1/99/1.1.bz2", "PRO/2025/Oct/1/99/1.2.bz2", "other/1.3.bz2"} {
+		if err := storage.Upload(ctx, file, key); err != nil {
+			t.Fatalf("Upload failed: %v", err)
+		}
+	}
+
+	objects, err := storage.List(ctx, "PRO/")
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(objects) != 2 || objects[0].Key != "PRO/2025/Oct/1/99/1.1.bz2" || objects[1].Key != "PRO/2025/Oct/1/99/1.2.bz2" {
+		t.Fatalf("Expected both PRO objects across pages, got %+v", objects)
+	}
+
+	sum := md5.Sum([]byte("market data"))
+	info, err := storage.Head(ctx, "PRO/2025/Oct/1/99/1.1.bz2")
+	if err != nil {
+		t.Fatalf("Head failed: %v", err)
+	}
+	if info.Size != 11 || info.MD5 != hex.EncodeToString(sum[:]) {
+		t.Errorf("Unexpected object info %+v", info)
+	}
+	if err := storage.Verify(ctx, "PRO/2025/Oct/1/99/1.1.bz2", 11, hex.EncodeToString(sum[:])); err != nil {
+		t.Errorf("Verify failed: %v", err)
+	}
+	if err := storage.Verify(ctx, "PRO/2025/Oct/1/99/1.1.bz2", 12, hex.EncodeToString(sum[:])); !errors.Is(err, errVerificationMismatch) {
+		t.Errorf("Expected size mismatch, got %v", err)
+	}
+
+	body, err := storage.Download(ctx, "PRO/2025/Oct/1/99/1.2.bz2")
+	if err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+	data, _ := io.ReadAll(body)
+	body.Close()
+	if string(data) != "market data" {
+		t.Errorf("Expected downloaded content, got %q", data)
+	}
+
+	if _, err := storage.Download(ctx, "missing"); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("Expected not found error, got %v", err)
+	}
+
+	for _, header := range fake.auth {
+		if header != "Bearer secret" {
+			t.Fatalf("Expected every request to be authorised, got %q", header)
+		}
+	}
+}
+
+func TestNewObjectStorageSelectsBackendByScheme(t *testing.T) {
+	_, server := newFakeGCS(t)
+	t.Setenv("STORAGE_EMULATOR_HOST", server.URL)
+
+	storage, err := NewObjectStorage(context.Background(), "gs://bucket/archive", "")
+	if err != nil {
+		t.Fatalf("NewObjectStorage failed: %v", err)
+	}
+	gcs, ok := storage.(*GCSStorage)
+	if !ok {
+		t.Fatalf("Expected GCS storage, got %T", storage)
+	}
+	if gcs.endpoint != server.URL || gcs.tokens != nil {
+		t.Errorf("Expected unauthenticated emulator endpoint, got %s", gcs.endpoint)
+	}
+
+	eventInfo := NewEventInfo("99", time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC))
+	if key := storage.BuildKey(eventInfo, "1.1.bz2"); key != "archive/PRO/2025/Oct/1/99/1.1.bz2" {
+		t.Errorf("Expected URL path as base path, got %s", key)
+	}
+
+	storage, err = NewObjectStorage(context.Background(), "gs://bucket/archive", "profile")
+	if err != nil {
+		t.Fatalf("NewObjectStorage failed: %v", err)
+	}
+	if key := storage.BuildKey(eventInfo, "1.1.bz2"); key != "profile/PRO/2025/Oct/1/99/1.1.bz2" {
+		t.Errorf("Expected explicit base path to win, got %s", key)
+	}
+}
+
+func TestServiceAccountToken(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	encoded, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("encode key: %v", err)
+	}
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		r.ParseForm()
+		parts := strings.Split(r.Form.Get("assertion"), ".")
+		if r.Form.Get("grant_type") != "urn:ietf:params:oauth:grant-type:jwt-bearer" || len(parts) != 3 {
+			http.Error(w, "bad assertion", http.StatusBadRequest)
+			return
+		}
+		signature, _ := base64.RawURLEncoding.DecodeString(parts[2])
+		digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], signature); err != nil {
+			http.Error(w, "bad signature", http.StatusUnauthorized)
+			return
+		}
+		claims, _ := base64.RawURLEncoding.DecodeString(parts[1])
+		if !strings.Contains(string(claims), `"iss":"recorder@example.iam.gserviceaccount.com"`) {
+			http.Error(w, "bad issuer", http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"access_token": "minted", "expires_in": 3600})
+	}))
+	defer server.Close()
+
+	keyFile := filepath.Join(t.TempDir(), "key.json")
+	data, _ := json.Marshal(map[string]string{
+		"type":         "service_account",
+		"client_email": "recorder@example.iam.gserviceaccount.com",
+		"private_key":  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: encoded})),
+		"token_uri":    server.URL,
+	})
+	if err := os.WriteFile(keyFile, data, 0600); err != nil {
+		t.Fatalf("write key file: %v", err)
+	}
+
+	account, err := loadServiceAccount(keyFile)
+	if err != nil {
+		t.Fatalf("loadServiceAccount failed: %v", err)
+	}
+	tokens := newCachedToken(account.fetcher(server.Client()))
+	for i := 0; i < 2; i++ {
+		token, err := tokens.Token(context.Background())
+		if err != nil || token != "minted" {
+			t.Fatalf("Expected minted token, got %q, %v", token, err)
+		}
+	}
+	if requests != 1 {
+		t.Errorf("Expected token to be cached, got %d requests", requests)
+	}
+
+	tokens.now = func() time.Time { return time.Now().Add(time.Hour) }
+	if _, err := tokens.Token(context.Background()); err != nil || requests != 2 {
+		t.Errorf("Expected expired token to be renewed, got %d requests, %v", requests, err)
+	}
+}
+
+func TestMetadataServerToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata-Flavor") != "Google" {
+			http.Error(w, "missing header", http.StatusForbidden)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"access_token": "from-metadata", "expires_in": 300})
+	}))
+	defer server.Close()
+
+	token, lifetime, err := metadataFetcher(server.Client(), server.URL)(context.Background())
+	if err != nil || token != "from-metadata" || lifetime != 5*time.Minute {
+		t.Errorf("Unexpected metadata token %q, %v, %v", token, lifetime, err)
+	}
+}
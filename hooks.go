@@ -0,0 +1,94 @@
+package betfair
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// MarketEvent describes a market state transition seen on the stream
+type MarketEvent struct {
+	MarketID       string
+	PublishTime    time.Time
+	PreviousStatus string
+	Definition     *MarketDefinition
+}
+
+// MarketHooks are called by the recorder as markets change state. Hooks run on the stream reader
+// goroutine, so they should return quickly and hand any slow work off to another goroutine.
+type MarketHooks struct {
+	OnMarketOpen func(MarketEvent)
+	OnInPlay     func(MarketEvent)
+	OnSuspend    func(MarketEvent)
+	OnSettled    func(MarketEvent)
+}
+
+func (h MarketHooks) empty() bool {
+	return h.OnMarketOpen == nil && h.OnInPlay == nil && h.OnSuspend == nil && h.OnSettled == nil
+}
+
+// SetHooks registers callbacks for market open, in-play, suspend and settle transitions
+func (r *MarketRecorder) SetHooks(hooks MarketHooks) {
+	r.hooks = hooks
+	for _, profile := range r.profiles {
+		profile.SetHooks(hooks)
+	}
+}
+
+// parseMarketDefinition decodes a raw stream market definition
+func parseMarketDefinition(raw map[string]interface{}) (*MarketDefinition, error) {
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	var definition MarketDefinition
+	if err := json.Unmarshal(data, &definition); err != nil {
+		return nil, err
+	}
+	return &definition, nil
+}
+
+// publishTime converts a stream pt value in epoch milliseconds
+func publishTime(pt interface{}) time.Time {
+	if ms, ok := pt.(float64); ok {
+		return time.UnixMilli(int64(ms)).UTC()
+	}
+	return time.Now().UTC()
+}
+
+// fireHooks compares a market definition with the market's previous state and calls the matching hooks
+func (r *MarketRecorder) fireHooks(marketID string, at time.Time, definition *MarketDefinition, oldStatus string) {
+	if r.hooks.empty() {
+		return
+	}
+
+	event := MarketEvent{
+		MarketID:       marketID,
+		PublishTime:    at,
+		PreviousStatus: oldStatus,
+		Definition:     definition,
+	}
+
+	wasInPlay := r.marketInPlay[marketID]
+	if r.marketInPlay == nil {
+		r.marketInPlay = make(map[string]bool)
+	}
+	r.marketInPlay[marketID] = definition.InPlay
+
+	statusChanged := definition.Status != "" && definition.Status != oldStatus
+	if statusChanged && definition.Status == "OPEN" && r.hooks.OnMarketOpen != nil {
+		r.hooks.OnMarketOpen(event)
+	}
+	if definition.InPlay && !wasInPlay && r.hooks.OnInPlay != nil {
+		r.hooks.OnInPlay(event)
+	}
+	if statusChanged && definition.Status == "SUSPENDED" && r.hooks.OnSuspend != nil {
+		r.hooks.OnSuspend(event)
+	}
+	if statusChanged && IsMarketSettled(definition.Status) && r.hooks.OnSettled != nil {
+		r.hooks.OnSettled(event)
+	}
+
+	if IsMarketSettled(definition.Status) {
+		delete(r.marketInPlay, marketID)
+	}
+}
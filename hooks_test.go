@@ -0,0 +1,66 @@
+package betfair
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func TestMarketRecorderFiresHooksOnTransitions(t *testing.T) {
+	var events []string
+	var lastEvent MarketEvent
+	record := func(name string) func(MarketEvent) {
+		return func(e MarketEvent) {
+			events = append(events, name)
+			lastEvent = e
+		}
+	}
+
+	recorder := &MarketRecorder{logger: zerolog.New(zerolog.NewTestWriter(t))}
+	recorder.SetHooks(MarketHooks{
+		OnMarketOpen: record("open"),
+		OnInPlay:     record("inplay"),
+		OnSuspend:    record("suspend"),
+		OnSettled:    record("settled"),
+	})
+
+	steps := []struct {
+		oldStatus string
+		def       map[string]interface{}
+	}{
+		{"", map[string]interface{}{"status": "OPEN", "inPlay": false, "venue": "Sandown"}},
+		{"OPEN", map[string]interface{}{"status": "OPEN", "inPlay": false}},
+		{"OPEN", map[string]interface{}{"status": "SUSPENDED", "inPlay": false}},
+		{"SUSPENDED", map[string]interface{}{"status": "OPEN", "inPlay": true}},
+		{"OPEN", map[string]interface{}{"status": "OPEN", "inPlay": true}},
+		{"OPEN", map[string]interface{}{"status": "CLOSED", "inPlay": true}},
+	}
+	for _, step := range steps {
+		definition, err := parseMarketDefinition(step.def)
+		if err != nil {
+			t.Fatalf("parseMarketDefinition failed: %v", err)
+		}
+		recorder.fireHooks("1.1", publishTime(float64(1759312800000)), definition, step.oldStatus)
+	}
+
+	expected := []string{"open", "suspend", "open", "inplay", "settled"}
+	if len(events) != len(expected) {
+		t.Fatalf("Expected events %v, got %v", expected, events)
+	}
+	for i := range expected {
+		if events[i] != expected[i] {
+			t.Errorf("Event %d: expected %s, got %s", i, expected[i], events[i])
+		}
+	}
+
+	if lastEvent.MarketID != "1.1" || lastEvent.PreviousStatus != "OPEN" || lastEvent.Definition.Status != "CLOSED" {
+		t.Errorf("Unexpected settled event: %+v", lastEvent)
+	}
+	if !lastEvent.PublishTime.Equal(time.UnixMilli(1759312800000)) {
+		t.Errorf("Unexpected publish time %v", lastEvent.PublishTime)
+	}
+	if _, tracked := recorder.marketInPlay["1.1"]; tracked {
+		t.Error("Expected in-play state to be dropped after settlement")
+	}
+}
@@ -0,0 +1,51 @@
+package betfair
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// LayoutVersion numbers the schema of archive paths, locally and in object storage. It is recorded
+// in the recording index so readers can tell the layouts of older recordings apart.
+type LayoutVersion int
+
+const (
+	// LayoutV1 files events as PRO/<year>/<month>/<day>/<eventId>/, after Betfair's historical data
+	LayoutV1 LayoutVersion = 1
+	// LayoutV2 files events as v2/year=<year>/month=<MM>/day=<day>/event=<eventId>/, which query
+	// engines such as Athena and DuckDB read as partition columns. Months are always numbers.
+	LayoutV2 LayoutVersion = 2
+)
+
+// DefaultLayoutVersion is the layout used when LAYOUT_VERSION is unset
+const DefaultLayoutVersion = LayoutV1
+
+// ParseLayoutVersion parses a layout version such as "1" or "v2"; an empty value is the default
+func ParseLayoutVersion(value string) (LayoutVersion, error) {
+	trimmed := strings.TrimPrefix(strings.ToLower(strings.TrimSpace(value)), "v")
+	if trimmed == "" {
+		return DefaultLayoutVersion, nil
+	}
+	version, err := strconv.Atoi(trimmed)
+	if err != nil || (LayoutVersion(version) != LayoutV1 && LayoutVersion(version) != LayoutV2) {
+		return 0, fmt.Errorf("unknown layout version %q", value)
+	}
+	return LayoutVersion(version), nil
+}
+
+// orDefault returns the version, or the default for the zero value
+func (v LayoutVersion) orDefault() LayoutVersion {
+	if v == 0 {
+		return DefaultLayoutVersion
+	}
+	return v
+}
+
+// eventPathSegments returns the directories an event's files are filed under
+func eventPathSegments(eventInfo *EventInfo) []string {
+	if eventInfo.Layout == LayoutV2 {
+		return []string{"v2", "year=" + eventInfo.Year, "month=" + eventInfo.Month, "day=" + eventInfo.Day, "event=" + eventInfo.EventID}
+	}
+	return []string{"PRO", eventInfo.Year, eventInfo.Month, eventInfo.Day, eventInfo.EventID}
+}
@@ -0,0 +1,57 @@
+package betfair
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLayoutVersions(t *testing.T) {
+	for value, want := range map[string]LayoutVersion{"": LayoutV1, "1": LayoutV1, "v2": LayoutV2, " V2 ": LayoutV2} {
+		if got, err := ParseLayoutVersion(value); err != nil || got != want {
+			t.Errorf("ParseLayoutVersion(%q) = %v, %v, want %v", value, got, err, want)
+		}
+	}
+	if _, err := ParseLayoutVersion("3"); err == nil {
+		t.Errorf("expected an error for an unknown layout version")
+	}
+
+	openDate := time.Date(2025, 9, 30, 10, 0, 0, 0, time.UTC)
+	v1 := NewEventInfo("34567", openDate)
+	PartitionScheme{}.Apply(v1)
+	v2 := NewEventInfo("34567", openDate)
+	PartitionScheme{Layout: LayoutV2}.Apply(v2)
+
+	if v1.Layout != LayoutV1 || v2.Layout != LayoutV2 {
+		t.Fatalf("expected Apply to record the layout, got %v and %v", v1.Layout, v2.Layout)
+	}
+	if got := BuildEventPath("data", v1); got != filepath.Join("data", "PRO", "2025", "Sep", "30", "34567") {
+		t.Errorf("unexpected version 1 path %q", got)
+	}
+	if got := BuildEventPath("data", v2); got != filepath.Join("data", "v2", "year=2025", "month=09", "day=30", "event=34567") {
+		t.Errorf("unexpected version 2 path %q", got)
+	}
+	if got := buildObjectKey("archive", v2, "1.1.bz2"); got != "archive/v2/year=2025/month=09/day=30/event=34567/1.1.bz2" {
+		t.Errorf("unexpected version 2 key %q", got)
+	}
+}
+
+func TestLayoutVersionFromEnv(t *testing.T) {
+	t.Setenv("BETFAIR_APP_KEY", "test-app-key")
+	t.Setenv("BETFAIR_SESSION_TOKEN", "test-session-token")
+	t.Setenv("MARKET_IDS", "1.100")
+	t.Setenv("LAYOUT_VERSION", "2")
+
+	cfg := NewConfig()
+	if err := cfg.LoadFromEnv(); err != nil {
+		t.Fatalf("LoadFromEnv: %v", err)
+	}
+	if cfg.Partition.Layout != LayoutV2 {
+		t.Errorf("expected layout version 2, got %v", cfg.Partition.Layout)
+	}
+
+	t.Setenv("LAYOUT_VERSION", "next")
+	if err := NewConfig().LoadFromEnv(); err == nil {
+		t.Errorf("expected an unknown LAYOUT_VERSION to be reported")
+	}
+}
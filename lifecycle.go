@@ -0,0 +1,168 @@
+package betfair
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const throughputWindow = 5 * time.Second
+
+var (
+	errRecorderStarted    = errors.New("recorder already started")
+	errRecorderNotStarted = errors.New("recorder not started")
+)
+
+// RecorderStatus is a point-in-time snapshot of a running recorder
+type RecorderStatus struct {
+	Running           bool
+	Connected         bool
+	StartedAt         time.Time
+	MarketsRecording  int
+	InitialClk        string
+	Clk               string
+	Messages          uint64
+	MessagesPerSecond float64
+}
+
+// recorderStats holds the counters behind Status; they are updated by the stream reader and
+// read from any goroutine
+type recorderStats struct {
+	connected atomic.Bool
+	recording atomic.Int64
+	messages  atomic.Uint64
+
+	mu          sync.Mutex
+	windowStart time.Time
+	windowCount uint64
+	rate        float64
+}
+
+// countMessage records a stream message for the throughput estimate
+func (s *recorderStats) countMessage(now time.Time) {
+	s.messages.Add(1)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.windowStart.IsZero() {
+		s.windowStart = now
+	}
+	s.windowCount++
+	if elapsed := now.Sub(s.windowStart); elapsed >= throughputWindow {
+		s.rate = float64(s.windowCount) / elapsed.Seconds()
+		s.windowStart = now
+		s.windowCount = 0
+	}
+}
+
+func (s *recorderStats) messagesPerSecond() float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rate
+}
+
+// lifecycle tracks a recorder started with Start
+type lifecycle struct {
+	mu        sync.Mutex
+	cancel    context.CancelFunc
+	done      chan struct{}
+	err       error
+	stopped   bool
+	startedAt time.Time
+}
+
+// Start runs the recorder in the background until Stop is called or ctx is cancelled
+func (r *MarketRecorder) Start(ctx context.Context) error {
+	r.lifecycle.mu.Lock()
+	defer r.lifecycle.mu.Unlock()
+
+	if r.lifecycle.done != nil {
+		return errRecorderStarted
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	r.lifecycle.cancel = cancel
+	r.lifecycle.done = done
+	r.lifecycle.startedAt = time.Now()
+
+	go func() {
+		defer close(done)
+		err := r.Run(ctx)
+
+		r.lifecycle.mu.Lock()
+		if r.lifecycle.stopped && errors.Is(err, context.Canceled) {
+			err = nil
+		}
+		r.lifecycle.err = err
+		r.lifecycle.mu.Unlock()
+	}()
+	return nil
+}
+
+// Stop cancels a recorder started with Start and waits for it to flush and close its files
+func (r *MarketRecorder) Stop() error {
+	r.lifecycle.mu.Lock()
+	cancel := r.lifecycle.cancel
+	if cancel != nil {
+		r.lifecycle.stopped = true
+	}
+	r.lifecycle.mu.Unlock()
+
+	if cancel == nil {
+		return errRecorderNotStarted
+	}
+	cancel()
+	return r.Wait()
+}
+
+// Wait blocks until a recorder started with Start has finished and returns the error it stopped
+// with; a recorder stopped through Stop returns nil
+func (r *MarketRecorder) Wait() error {
+	r.lifecycle.mu.Lock()
+	done := r.lifecycle.done
+	r.lifecycle.mu.Unlock()
+
+	if done == nil {
+		return errRecorderNotStarted
+	}
+	<-done
+
+	r.lifecycle.mu.Lock()
+	defer r.lifecycle.mu.Unlock()
+	return r.lifecycle.err
+}
+
+// Status returns a snapshot of the recorder; profile recorders are aggregated
+func (r *MarketRecorder) Status() RecorderStatus {
+	r.lifecycle.mu.Lock()
+	status := RecorderStatus{StartedAt: r.lifecycle.startedAt}
+	if r.lifecycle.done != nil {
+		select {
+		case <-r.lifecycle.done:
+		default:
+			status.Running = true
+		}
+	}
+	r.lifecycle.mu.Unlock()
+
+	r.streamMu.Lock()
+	status.InitialClk, status.Clk = r.initialClk, r.clk
+	r.streamMu.Unlock()
+
+	status.Connected = r.stats.connected.Load()
+	status.MarketsRecording = int(r.stats.recording.Load())
+	status.Messages = r.stats.messages.Load()
+	status.MessagesPerSecond = r.stats.messagesPerSecond()
+
+	for _, profile := range r.profiles {
+		child := profile.Status()
+		status.Connected = status.Connected || child.Connected
+		status.MarketsRecording += child.MarketsRecording
+		status.Messages += child.Messages
+		status.MessagesPerSecond += child.MessagesPerSecond
+	}
+	return status
+}
@@ -0,0 +1,61 @@
+package betfair
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func TestMarketRecorderStartStop(t *testing.T) {
+	streamClient := NewStreamClient("app-key", "token", 0, zerolog.Nop(), nil)
+	streamClient.SetEndpoints(Endpoints{StreamHost: "127.0.0.1"})
+
+	recorder := &MarketRecorder{
+		config:           &Config{EventTypeID: "4339", EnrichmentMode: EnrichmentOff},
+		logger:           zerolog.Nop(),
+		streamClient:     streamClient,
+		fileManager:      NewFileManager(t.TempDir()),
+		marketCatalogues: make(map[string]*MarketCatalogue),
+		maxRetries:       1,
+		retryDelay:       10 * time.Millisecond,
+	}
+
+	if err := recorder.Wait(); !errors.Is(err, errRecorderNotStarted) {
+		t.Errorf("Expected Wait before Start to fail, got %v", err)
+	}
+	if err := recorder.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	if err := recorder.Start(context.Background()); !errors.Is(err, errRecorderStarted) {
+		t.Errorf("Expected second Start to fail, got %v", err)
+	}
+
+	status := recorder.Status()
+	if !status.Running || status.Connected || status.StartedAt.IsZero() {
+		t.Errorf("Unexpected status while retrying connection: %+v", status)
+	}
+
+	if err := recorder.Stop(); err != nil {
+		t.Errorf("Expected clean stop, got %v", err)
+	}
+	if recorder.Status().Running {
+		t.Error("Expected recorder to report stopped")
+	}
+}
+
+func TestRecorderStatsThroughput(t *testing.T) {
+	var stats recorderStats
+	start := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i <= 50; i++ {
+		stats.countMessage(start.Add(time.Duration(i) * 100 * time.Millisecond))
+	}
+	if rate := stats.messagesPerSecond(); rate < 9.9 || rate > 10.3 {
+		t.Errorf("Expected about 10 messages per second, got %.2f", rate)
+	}
+	if stats.messages.Load() != 51 {
+		t.Errorf("Expected 51 messages counted, got %d", stats.messages.Load())
+	}
+}
@@ -0,0 +1,54 @@
+package betfair
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// LogFormat is how log lines are written
+type LogFormat string
+
+const (
+	LogFormatJSON    LogFormat = "json"    // One JSON object per line, for log shippers
+	LogFormatConsole LogFormat = "console" // Human-readable lines
+)
+
+// DefaultLogFormat is the format used when LOG_FORMAT is unset, by the recorder and every command
+const DefaultLogFormat = LogFormatJSON
+
+// ParseLogFormat parses json or console
+func ParseLogFormat(value string) (LogFormat, error) {
+	format := LogFormat(strings.ToLower(strings.TrimSpace(value)))
+	switch format {
+	case LogFormatJSON, LogFormatConsole:
+		return format, nil
+	}
+	return "", fmt.Errorf("unknown log format %q", value)
+}
+
+// NewLogger builds a timestamped logger at level, appending to file or writing to stderr when file
+// is empty. The file stays open for the life of the process.
+func NewLogger(level zerolog.Level, format LogFormat, file string) (zerolog.Logger, error) {
+	var out io.Writer = os.Stderr
+	if file != "" {
+		f, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return zerolog.Logger{}, fmt.Errorf("open log file: %w", err)
+		}
+		out = f
+	}
+	if format == LogFormatConsole {
+		out = zerolog.ConsoleWriter{Out: out, TimeFormat: time.TimeOnly, NoColor: file != ""}
+	}
+	return zerolog.New(out).Level(level).With().Timestamp().Logger(), nil
+}
+
+// Logger builds the logger LOG_LEVEL, LOG_FORMAT and LOG_FILE describe
+func (c *Config) Logger() (zerolog.Logger, error) {
+	return NewLogger(c.LogLevel, c.LogFormat, c.LogFile)
+}
@@ -0,0 +1,71 @@
+package betfair
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestNewLoggerFormatsAndFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "recorder.log")
+
+	logger, err := NewLogger(zerolog.InfoLevel, LogFormatJSON, path)
+	if err != nil {
+		t.Fatalf("NewLogger: %v", err)
+	}
+	logger.Debug().Msg("hidden")
+	logger.Info().Str("market_id", "1.100").Msg("recording")
+
+	console, err := NewLogger(zerolog.InfoLevel, LogFormatConsole, path)
+	if err != nil {
+		t.Fatalf("NewLogger: %v", err)
+	}
+	console.Warn().Msg("appended")
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected two lines below the level cut, got %q", data)
+	}
+	var entry map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil || entry["market_id"] != "1.100" || entry["level"] != "info" {
+		t.Errorf("expected a JSON line, got %q", lines[0])
+	}
+	if !strings.Contains(lines[1], "WRN") || !strings.Contains(lines[1], "appended") || strings.Contains(lines[1], "\x1b[") {
+		t.Errorf("expected an uncoloured console line, got %q", lines[1])
+	}
+}
+
+func TestLogSettingsFromEnv(t *testing.T) {
+	t.Setenv("BETFAIR_APP_KEY", "test-app-key")
+	t.Setenv("BETFAIR_SESSION_TOKEN", "test-session-token")
+	t.Setenv("MARKET_IDS", "1.100")
+	t.Setenv("LOG_FORMAT", "Console")
+	t.Setenv("LOG_FILE", "/var/log/recorder.log")
+
+	cfg := NewConfig()
+	if err := cfg.LoadFromEnv(); err != nil {
+		t.Fatalf("LoadFromEnv: %v", err)
+	}
+	if cfg.LogFormat != LogFormatConsole || cfg.LogFile != "/var/log/recorder.log" {
+		t.Errorf("expected console logs to the file, got %q to %q", cfg.LogFormat, cfg.LogFile)
+	}
+
+	t.Setenv("LOG_FORMAT", "")
+	cfg = NewConfig()
+	if err := cfg.LoadFromEnv(); err != nil || cfg.LogFormat != LogFormatJSON {
+		t.Errorf("expected JSON logs by default, got %q, %v", cfg.LogFormat, err)
+	}
+
+	t.Setenv("LOG_FORMAT", "xml")
+	if err := NewConfig().LoadFromEnv(); err == nil || !strings.Contains(err.Error(), "LOG_FORMAT") {
+		t.Errorf("expected an unknown LOG_FORMAT to be reported, got %v", err)
+	}
+}
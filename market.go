@@ -8,11 +8,18 @@ import (
 )
 
 type EventInfo struct {
-	EventID string
-	Date    time.Time
-	Year    string
-	Month   string
-	Day     string
+	EventID     string
+	EventTypeID string
+	Venue       string
+	Timezone    string
+	SettledTime time.Time
+	// Date is the time the event is filed under: its open date unless a PartitionScheme chose otherwise
+	Date  time.Time
+	Year  string
+	Month string
+	Day   string
+	// Layout is the schema of the event's archive paths; zero is the default
+	Layout LayoutVersion
 }
 
 type MarketProcessor struct{}
@@ -76,8 +83,12 @@ func ExtractEventInfo(raw []byte) (*EventInfo, error) {
 	var mcm struct {
 		MC []struct {
 			MarketDefinition struct {
-				EventID  string    `json:"eventId"`
-				OpenDate time.Time `json:"openDate"`
+				EventID     string     `json:"eventId"`
+				EventTypeID string     `json:"eventTypeId"`
+				Venue       string     `json:"venue"`
+				Timezone    string     `json:"timezone"`
+				OpenDate    time.Time  `json:"openDate"`
+				SettledTime *time.Time `json:"settledTime"`
 			} `json:"marketDefinition"`
 		} `json:"mc"`
 	}
@@ -90,16 +101,45 @@ func ExtractEventInfo(raw []byte) (*EventInfo, error) {
 		return nil, fmt.Errorf("no event information found")
 	}
 
-	info := &EventInfo{
-		EventID: mcm.MC[0].MarketDefinition.EventID,
-		Date:    mcm.MC[0].MarketDefinition.OpenDate,
+	definition := mcm.MC[0].MarketDefinition
+	eventInfo := NewEventInfo(definition.EventID, definition.OpenDate)
+	eventInfo.EventTypeID = definition.EventTypeID
+	eventInfo.Venue = definition.Venue
+	eventInfo.Timezone = definition.Timezone
+	if definition.SettledTime != nil {
+		eventInfo.SettledTime = *definition.SettledTime
 	}
+	return eventInfo, nil
+}
+
+// sportNames maps common Betfair event type IDs to readable sport names
+var sportNames = map[string]string{
+	"1":     "soccer",
+	"2":     "tennis",
+	"4":     "cricket",
+	"7":     "horse-racing",
+	"4339":  "greyhound-racing",
+	"7522":  "basketball",
+	"61420": "australian-rules",
+}
 
-	info.Year = strconv.Itoa(info.Date.Year())
-	info.Month = info.Date.Format("Jan")
-	info.Day = strconv.Itoa(info.Date.Day())
+// sportName returns the sport an event type ID belongs to, or the ID itself when it is not a common one
+func sportName(eventTypeID string) string {
+	if name, ok := sportNames[eventTypeID]; ok {
+		return name
+	}
+	return eventTypeID
+}
 
-	return info, nil
+// NewEventInfo builds the event details used to lay out archived market files
+func NewEventInfo(eventID string, openDate time.Time) *EventInfo {
+	return &EventInfo{
+		EventID: eventID,
+		Date:    openDate,
+		Year:    strconv.Itoa(openDate.Year()),
+		Month:   openDate.Format("Jan"),
+		Day:     strconv.Itoa(openDate.Day()),
+	}
 }
 
 func ExtractAndStoreClock(raw []byte) (initialClk, clk string) {
@@ -125,4 +165,39 @@ func RemoveIDField(raw []byte) ([]byte, error) {
 
 	delete(msg, "id")
 	return json.Marshal(msg)
-}
\ No newline at end of file
+}
+// MarketDefinition is the market definition carried by stream market changes
+type MarketDefinition struct {
+	EventID               string             `json:"eventId"`
+	EventTypeID           string             `json:"eventTypeId"`
+	MarketType            string             `json:"marketType"`
+	BettingType           string             `json:"bettingType"`
+	Venue                 string             `json:"venue,omitempty"`
+	CountryCode           string             `json:"countryCode"`
+	Timezone              string             `json:"timezone"`
+	Status                string             `json:"status"`
+	InPlay                bool               `json:"inPlay"`
+	TurnInPlayEnabled     bool               `json:"turnInPlayEnabled"`
+	BspMarket             bool               `json:"bspMarket"`
+	BspReconciled         bool               `json:"bspReconciled"`
+	Complete              bool               `json:"complete"`
+	BetDelay              int                `json:"betDelay"`
+	NumberOfWinners       int                `json:"numberOfWinners"`
+	NumberOfActiveRunners int                `json:"numberOfActiveRunners"`
+	MarketTime            time.Time          `json:"marketTime"`
+	OpenDate              time.Time          `json:"openDate"`
+	SuspendTime           *time.Time         `json:"suspendTime,omitempty"`
+	SettledTime           *time.Time         `json:"settledTime,omitempty"`
+	Version               int64              `json:"version"`
+	Runners               []RunnerDefinition `json:"runners"`
+}
+
+type RunnerDefinition struct {
+	ID               int64      `json:"id"`
+	Status           string     `json:"status"`
+	SortPriority     int        `json:"sortPriority"`
+	AdjustmentFactor float64    `json:"adjustmentFactor,omitempty"`
+	BSP              *float64   `json:"bsp,omitempty"`
+	RemovalDate      *time.Time `json:"removalDate,omitempty"`
+	Name             string     `json:"name,omitempty"`
+}
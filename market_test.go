@@ -160,12 +160,14 @@ func TestExtractEventInfo(t *testing.T) {
 	}{
 		{
 			name: "Valid event info",
-			json: `{"op":"mcm","mc":[{"marketDefinition":{"eventId":"34773181","openDate":"2025-09-26T00:40:00.000Z"}}]}`,
+			json: `{"op":"mcm","mc":[{"marketDefinition":{"eventId":"34773181","eventTypeId":"4339","venue":"Sandown Park","openDate":"2025-09-26T00:40:00.000Z"}}]}`,
 			expected: &EventInfo{
-				EventID: "34773181",
-				Year:    "2025",
-				Month:   "Sep",
-				Day:     "26",
+				EventID:     "34773181",
+				EventTypeID: "4339",
+				Venue:       "Sandown Park",
+				Year:        "2025",
+				Month:       "Sep",
+				Day:         "26",
 			},
 		},
 		{
@@ -213,6 +215,9 @@ func TestExtractEventInfo(t *testing.T) {
 			if result.Day != tt.expected.Day {
 				t.Errorf("Expected Day '%s', got '%s'", tt.expected.Day, result.Day)
 			}
+			if result.EventTypeID != tt.expected.EventTypeID || result.Venue != tt.expected.Venue {
+				t.Errorf("Expected event type '%s' at '%s', got '%s' at '%s'", tt.expected.EventTypeID, tt.expected.Venue, result.EventTypeID, result.Venue)
+			}
 		})
 	}
 }
@@ -0,0 +1,179 @@
+package betfair
+
+import (
+	"bufio"
+	"os"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const DefaultWriterQueueSize = 1024
+
+// DefaultStreamFlushInterval is how often market files compressed while recording are flushed
+// when no flush policy is configured
+const DefaultStreamFlushInterval = time.Second
+
+// FlushPolicy controls when buffered market data reaches the file. With neither Interval nor
+// EveryN set every message is flushed as soon as it is written.
+type FlushPolicy struct {
+	Interval      time.Duration
+	EveryN        int
+	FsyncOnSettle bool
+}
+
+func (p FlushPolicy) flushEachMessage() bool {
+	return p.Interval <= 0 && p.EveryN <= 0
+}
+
+// marketWriter owns a single market's file. Lines are queued on a bounded channel and written by
+// a dedicated goroutine so a slow disk only back-pressures the stream once the queue is full.
+type marketWriter struct {
+	marketID string
+	writer   *bufio.Writer
+	file     *os.File
+	stream   streamCompressor
+	queue    chan []byte
+	syncs    chan chan error
+	done     chan struct{}
+	closed   chan struct{}
+	policy   FlushPolicy
+	logger   zerolog.Logger
+}
+
+// startMarketWriter starts the writer goroutine for a market. stream is the compressor between
+// writer and file when the file is compressed while recording, otherwise nil.
+func startMarketWriter(marketID string, writer *bufio.Writer, file *os.File, stream streamCompressor, queueSize int, policy FlushPolicy, logger zerolog.Logger) *marketWriter {
+	if queueSize <= 0 {
+		queueSize = DefaultWriterQueueSize
+	}
+	w := &marketWriter{
+		marketID: marketID,
+		writer:   writer,
+		file:     file,
+		stream:   stream,
+		queue:    make(chan []byte, queueSize),
+		syncs:    make(chan chan error),
+		done:     make(chan struct{}),
+		closed:   make(chan struct{}),
+		policy:   policy,
+		logger:   logger,
+	}
+	go w.run()
+	return w
+}
+
+func (w *marketWriter) run() {
+	defer close(w.done)
+
+	var tick <-chan time.Time
+	if w.policy.Interval > 0 {
+		ticker := time.NewTicker(w.policy.Interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	unflushed := 0
+	for {
+		select {
+		case line, ok := <-w.queue:
+			if !ok {
+				return
+			}
+			if !w.write(line) {
+				continue
+			}
+			unflushed++
+			if w.policy.flushEachMessage() || (w.policy.EveryN > 0 && unflushed >= w.policy.EveryN) {
+				w.flush()
+				unflushed = 0
+			}
+		case reply := <-w.syncs:
+			// Every line queued before Sync was called is already waiting in the queue
+			for pending := len(w.queue); pending > 0; pending-- {
+				w.write(<-w.queue)
+			}
+			reply <- w.sync()
+			unflushed = 0
+		case <-tick:
+			if unflushed > 0 {
+				w.flush()
+				unflushed = 0
+			}
+		}
+	}
+}
+
+func (w *marketWriter) write(line []byte) bool {
+	if _, err := w.writer.Write(line); err != nil {
+		w.logger.Error().Err(err).Str("market_id", w.marketID).Msg("failed to write to file")
+		return false
+	}
+	return true
+}
+
+func (w *marketWriter) sync() error {
+	if err := w.writer.Flush(); err != nil {
+		return err
+	}
+	if w.stream != nil {
+		if err := w.stream.Flush(); err != nil {
+			return err
+		}
+	}
+	if w.file == nil {
+		return nil
+	}
+	return w.file.Sync()
+}
+
+func (w *marketWriter) flush() {
+	err := w.writer.Flush()
+	if err == nil && w.stream != nil {
+		err = w.stream.Flush()
+	}
+	if err != nil {
+		w.logger.Error().Err(err).Str("market_id", w.marketID).Msg("failed to flush file")
+	}
+}
+
+// Write queues a line for the market's file
+func (w *marketWriter) Write(line []byte) {
+	w.queue <- line
+}
+
+// Sync writes every line queued so far, then flushes and fsyncs the file. A writer being closed
+// instead waits for Close, which writes everything queued.
+func (w *marketWriter) Sync() error {
+	reply := make(chan error)
+	select {
+	case w.syncs <- reply:
+		return <-reply
+	case <-w.closed:
+		return nil
+	}
+}
+
+// Close drains the queue, then flushes and closes the file. When settled is set and the policy
+// asks for it the file is fsynced first so the finished recording survives a crash.
+func (w *marketWriter) Close(settled bool) error {
+	defer close(w.closed)
+	close(w.queue)
+	<-w.done
+
+	flushErr := w.writer.Flush()
+	if w.stream != nil {
+		if err := w.stream.Close(); err != nil && flushErr == nil {
+			flushErr = err
+		}
+	}
+	if flushErr == nil && settled && w.policy.FsyncOnSettle && w.file != nil {
+		flushErr = w.file.Sync()
+	}
+	if w.file != nil {
+		if err := w.file.Close(); err != nil && flushErr == nil {
+			return err
+		}
+	}
+	return flushErr
+}
@@ -0,0 +1,181 @@
+package betfair
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dsnet/compress/bzip2"
+	"github.com/rs/zerolog"
+)
+
+func TestMarketWriterDrainsQueueOnClose(t *testing.T) {
+	fm := NewFileManager(t.TempDir())
+	writer, file, err := fm.CreateMarketWriter("1.1")
+	if err != nil {
+		t.Fatalf("CreateMarketWriter failed: %v", err)
+	}
+
+	mw := startMarketWriter("1.1", writer, file, nil, 2, FlushPolicy{}, zerolog.New(zerolog.NewTestWriter(t)))
+	for i := 0; i < 10; i++ {
+		mw.Write([]byte("line\n"))
+	}
+	if err := mw.Close(false); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	content, _ := os.ReadFile(fm.GetMarketFilePath("1.1"))
+	if got := strings.Count(string(content), "line\n"); got != 10 {
+		t.Errorf("Expected all 10 queued lines to be written, got %d", got)
+	}
+}
+
+func TestMarketWriterFlushPolicy(t *testing.T) {
+	fm := NewFileManager(t.TempDir())
+	writer, file, err := fm.CreateMarketWriter("1.2")
+	if err != nil {
+		t.Fatalf("CreateMarketWriter failed: %v", err)
+	}
+
+	policy := FlushPolicy{EveryN: 3, Interval: 20 * time.Millisecond, FsyncOnSettle: true}
+	mw := startMarketWriter("1.2", writer, file, nil, 10, policy, zerolog.New(zerolog.NewTestWriter(t)))
+
+	mw.Write([]byte("a\n"))
+	mw.Write([]byte("b\n"))
+	mw.Write([]byte("c\n"))
+	waitFor(t, func() bool {
+		content, _ := os.ReadFile(fm.GetMarketFilePath("1.2"))
+		return string(content) == "a\nb\nc\n"
+	})
+
+	// Below the message threshold the interval flushes what is pending
+	mw.Write([]byte("d\n"))
+	waitFor(t, func() bool {
+		content, _ := os.ReadFile(fm.GetMarketFilePath("1.2"))
+		return strings.HasSuffix(string(content), "d\n")
+	})
+
+	if err := mw.Close(true); err != nil {
+		t.Fatalf("Close with fsync failed: %v", err)
+	}
+}
+
+func TestMarketRecorderFinalizesSettledMarketInBackground(t *testing.T) {
+	tempDir := t.TempDir()
+	recorder := &MarketRecorder{
+		config:           &Config{EnrichmentMode: EnrichmentOff},
+		logger:           zerolog.New(zerolog.NewTestWriter(t)),
+		fileManager:      NewFileManager(tempDir),
+		marketCatalogues: make(map[string]*MarketCatalogue),
+	}
+
+	messages := strings.Join([]string{
+		`{"op":"mcm","pt":1,"clk":"a","mc":[{"id":"1.1","marketDefinition":{"eventId":"1","openDate":"2025-10-01T10:00:00Z","status":"OPEN"}}]}`,
+		`{"op":"mcm","pt":2,"clk":"b","mc":[{"id":"1.1","marketDefinition":{"eventId":"1","openDate":"2025-10-01T10:00:00Z","status":"CLOSED"}}]}`,
+	}, "\n") + "\n"
+	stream := &StreamConn{reader: bufio.NewReader(strings.NewReader(messages))}
+
+	writers := make(map[string]*bufio.Writer)
+	files := make(map[string]*os.File)
+	statuses := make(map[string]string)
+	for i := 0; i < 2; i++ {
+		if err := recorder.readMessage(context.Background(), stream, writers, files, statuses); err != nil {
+			t.Fatalf("readMessage failed: %v", err)
+		}
+	}
+
+	if _, exists := writers["1.1"]; exists {
+		t.Error("Expected settled market to be handed off to its writer")
+	}
+
+	recorder.stopMarketWriters()
+
+	compressed, err := os.Open(filepath.Join(tempDir, "1.1.bz2"))
+	if err != nil {
+		t.Fatalf("Expected compressed market file: %v", err)
+	}
+	defer compressed.Close()
+
+	reader, err := bzip2.NewReader(compressed, nil)
+	if err != nil {
+		t.Fatalf("Failed to open bzip2 reader: %v", err)
+	}
+	var lines []string
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if len(lines) != 2 || !strings.Contains(lines[1], `"status":"CLOSED"`) {
+		t.Errorf("Expected both messages with settlement last, got %v", lines)
+	}
+}
+
+func TestMarketRecorderFinalizesIdleMarketsAsIncomplete(t *testing.T) {
+	tempDir := t.TempDir()
+	recorder := &MarketRecorder{
+		config:           &Config{EnrichmentMode: EnrichmentOff, IdleMarketTimeout: time.Hour},
+		logger:           zerolog.New(zerolog.NewTestWriter(t)),
+		fileManager:      NewFileManager(tempDir),
+		marketCatalogues: make(map[string]*MarketCatalogue),
+	}
+
+	message := `{"op":"mcm","pt":1,"clk":"a","mc":[{"id":"1.1","marketDefinition":{"eventId":"1","openDate":"2025-10-01T10:00:00Z","status":"SUSPENDED"}}]}` + "\n"
+	stream := &StreamConn{reader: bufio.NewReader(strings.NewReader(message))}
+
+	writers := make(map[string]*bufio.Writer)
+	files := make(map[string]*os.File)
+	if err := recorder.readMessage(context.Background(), stream, writers, files, make(map[string]string)); err != nil {
+		t.Fatalf("readMessage failed: %v", err)
+	}
+
+	recorder.finalizeIdleMarkets(context.Background(), time.Now().Add(30*time.Minute), writers, files)
+	if _, exists := writers["1.1"]; !exists {
+		t.Fatal("Expected market within the timeout to keep recording")
+	}
+
+	recorder.finalizeIdleMarkets(context.Background(), time.Now().Add(2*time.Hour), writers, files)
+	if _, exists := writers["1.1"]; exists {
+		t.Error("Expected idle market to be finalized")
+	}
+
+	recorder.stopMarketWriters()
+
+	if _, err := os.Stat(filepath.Join(tempDir, "1.1"+IncompleteSuffix+".bz2")); err != nil {
+		t.Errorf("Expected incomplete compressed file: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, "1.1.bz2")); err == nil {
+		t.Error("Expected idle market not to be stored as a complete recording")
+	}
+}
+
+func TestMarketWriterSyncWritesQueuedLines(t *testing.T) {
+	fm := NewFileManager(t.TempDir())
+	writer, file, err := fm.CreateMarketWriter("1.3")
+	if err != nil {
+		t.Fatalf("CreateMarketWriter failed: %v", err)
+	}
+
+	mw := startMarketWriter("1.3", writer, file, nil, 10, FlushPolicy{EveryN: 100}, zerolog.New(zerolog.NewTestWriter(t)))
+
+	for i := 0; i < 5; i++ {
+		mw.Write([]byte("line\n"))
+	}
+	if err := mw.Sync(); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+
+	content, _ := os.ReadFile(fm.GetMarketFilePath("1.3"))
+	if got := strings.Count(string(content), "line\n"); got != 5 {
+		t.Errorf("Expected all 5 queued lines on disk after Sync, got %d", got)
+	}
+
+	// A checkpoint may sync a writer the stream has since handed over for closing
+	mw.Close(false)
+	if err := mw.Sync(); err != nil {
+		t.Errorf("Expected Sync of a closed writer to return once it is closed, got %v", err)
+	}
+}
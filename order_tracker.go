@@ -0,0 +1,200 @@
+package betfair
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const defaultOrderPollInterval = 500 * time.Millisecond
+
+// maxOrderPolls is how many times an order is polled for before tracking gives up on it. Rejected
+// and lapsed async orders never appear in listCurrentOrders, so without a limit they would be
+// polled for forever.
+const maxOrderPolls = 20
+
+// ErrOrderNotFound is reported for an order that never appeared in listCurrentOrders
+var ErrOrderNotFound = errors.New("order not found")
+
+// OrderStatusUpdate reports a status change for an order identified by its customer order ref
+type OrderStatusUpdate struct {
+	CustomerOrderRef string
+	BetID            string
+	Status           OrderStatus
+	Order            *CurrentOrderSummary
+	Err              error
+}
+
+// IsOrderPlaced reports whether an async order has left the PENDING state on the exchange
+func IsOrderPlaced(status OrderStatus) bool {
+	return status == OrderStatusExecutable || status == OrderStatusExecutionComplete
+}
+
+// PlaceOrdersAsync places orders with async=true and tracks them until they are live on the exchange.
+// Instructions without a CustomerOrderRef are assigned one so they can be followed up via listCurrentOrders;
+// the caller's instructions are left unchanged.
+func (c *RESTClient) PlaceOrdersAsync(ctx context.Context, marketID string, instructions []PlaceInstruction, pollInterval time.Duration) (*PlaceExecutionReport, <-chan OrderStatusUpdate, error) {
+	if len(instructions) == 0 {
+		return nil, nil, fmt.Errorf("place instructions are required")
+	}
+
+	instructions = append([]PlaceInstruction(nil), instructions...)
+	refs := make([]string, len(instructions))
+	prefix := fmt.Sprintf("%x", time.Now().UnixNano())
+	for i := range instructions {
+		if instructions[i].CustomerOrderRef == "" {
+			instructions[i].CustomerOrderRef = fmt.Sprintf("%s-%d", prefix, i)
+		}
+		refs[i] = instructions[i].CustomerOrderRef
+	}
+
+	async := true
+	report, err := c.PlaceOrders(ctx, marketID, instructions, nil, nil, nil, &async)
+	if err != nil {
+		return nil, nil, err
+	}
+	if report.Status == ExecutionReportStatusFailure {
+		errCode := "unknown error"
+		if report.ErrorCode != nil {
+			errCode = string(*report.ErrorCode)
+		}
+		return report, nil, fmt.Errorf("async place orders failed: %s", errCode)
+	}
+
+	// Instructions the exchange rejected outright are reported at once instead of being polled for
+	var failed []OrderStatusUpdate
+	for _, instructionReport := range report.InstructionReports {
+		if instructionReport.Status != InstructionReportStatusFailure {
+			continue
+		}
+		errCode := "unknown error"
+		if instructionReport.ErrorCode != nil {
+			errCode = string(*instructionReport.ErrorCode)
+		}
+		failed = append(failed, OrderStatusUpdate{
+			CustomerOrderRef: instructionReport.Instruction.CustomerOrderRef,
+			Err:              fmt.Errorf("place order %s failed: %s", instructionReport.Instruction.CustomerOrderRef, errCode),
+		})
+		for i, ref := range refs {
+			if ref == instructionReport.Instruction.CustomerOrderRef {
+				refs = append(refs[:i], refs[i+1:]...)
+				break
+			}
+		}
+	}
+
+	return report, c.trackOrders(ctx, marketID, refs, pollInterval, failed), nil
+}
+
+// TrackOrders polls listCurrentOrders until every customer order ref is EXECUTABLE or EXECUTION_COMPLETE.
+// An update is sent whenever an order's status changes. An order still not placed after maxOrderPolls
+// polls gets a final update with an error, ErrOrderNotFound when it never appeared. The channel is closed
+// once every order is placed or given up on, or the context is cancelled.
+func (c *RESTClient) TrackOrders(ctx context.Context, marketID string, customerOrderRefs []string, pollInterval time.Duration) <-chan OrderStatusUpdate {
+	return c.trackOrders(ctx, marketID, customerOrderRefs, pollInterval, nil)
+}
+
+// trackOrders is TrackOrders, sending the failed updates first
+func (c *RESTClient) trackOrders(ctx context.Context, marketID string, customerOrderRefs []string, pollInterval time.Duration, failed []OrderStatusUpdate) <-chan OrderStatusUpdate {
+	if pollInterval <= 0 {
+		pollInterval = defaultOrderPollInterval
+	}
+
+	updates := make(chan OrderStatusUpdate, len(customerOrderRefs)+len(failed))
+	for _, update := range failed {
+		updates <- update
+	}
+
+	go func() {
+		defer close(updates)
+
+		pending := make(map[string]OrderStatus, len(customerOrderRefs))
+		for _, ref := range customerOrderRefs {
+			pending[ref] = OrderStatusPending
+		}
+		seen := make(map[string]bool, len(customerOrderRefs))
+
+		send := func(update OrderStatusUpdate) bool {
+			select {
+			case updates <- update:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+
+		for polls := 1; len(pending) > 0; polls++ {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			refs := make([]string, 0, len(pending))
+			for ref := range pending {
+				refs = append(refs, ref)
+			}
+
+			var marketIDs []string
+			if marketID != "" {
+				marketIDs = []string{marketID}
+			}
+
+			report, err := c.ListCurrentOrders(ctx, nil, marketIDs, nil, refs, nil, nil, nil, nil, 0, 0)
+			if err != nil {
+				if !send(OrderStatusUpdate{Err: fmt.Errorf("poll current orders: %w", err)}) {
+					return
+				}
+				// A failed poll still counts towards giving up
+				report = &CurrentOrderSummaryReport{}
+			}
+
+			for i := range report.CurrentOrders {
+				order := report.CurrentOrders[i]
+				lastStatus, tracked := pending[order.CustomerOrderRef]
+				if !tracked {
+					continue
+				}
+				seen[order.CustomerOrderRef] = true
+				if order.Status == lastStatus {
+					continue
+				}
+
+				if IsOrderPlaced(order.Status) {
+					delete(pending, order.CustomerOrderRef)
+				} else {
+					pending[order.CustomerOrderRef] = order.Status
+				}
+
+				if !send(OrderStatusUpdate{
+					CustomerOrderRef: order.CustomerOrderRef,
+					BetID:            order.BetID,
+					Status:           order.Status,
+					Order:            &order,
+				}) {
+					return
+				}
+			}
+
+			if polls < maxOrderPolls {
+				continue
+			}
+			for ref, status := range pending {
+				err := ErrOrderNotFound
+				if seen[ref] {
+					err = fmt.Errorf("order still %s after %d polls", status, polls)
+				}
+				if !send(OrderStatusUpdate{CustomerOrderRef: ref, Status: status, Err: err}) {
+					return
+				}
+			}
+			return
+		}
+	}()
+
+	return updates
+}
@@ -0,0 +1,181 @@
+package betfair
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPlaceOrdersAsyncTracksUntilPlaced(t *testing.T) {
+	var mu sync.Mutex
+	polls := 0
+	var placedRefs []string
+
+	client := newTestRESTClient(t, func(method string, params map[string]interface{}) interface{} {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch method {
+		case "placeOrders":
+			if params["async"] != true {
+				t.Errorf("Expected async placement, got %v", params["async"])
+			}
+			for _, raw := range params["instructions"].([]interface{}) {
+				placedRefs = append(placedRefs, raw.(map[string]interface{})["customerOrderRef"].(string))
+			}
+			return map[string]interface{}{"status": "SUCCESS", "marketId": params["marketId"]}
+		case "listCurrentOrders":
+			polls++
+			orders := []map[string]interface{}{}
+			if polls == 1 {
+				orders = append(orders, map[string]interface{}{"betId": "1", "customerOrderRef": placedRefs[0], "status": "EXECUTABLE"})
+				orders = append(orders, map[string]interface{}{"betId": "", "customerOrderRef": placedRefs[1], "status": "PENDING"})
+			} else {
+				orders = append(orders, map[string]interface{}{"betId": "2", "customerOrderRef": placedRefs[1], "status": "EXECUTION_COMPLETE"})
+			}
+			return map[string]interface{}{"currentOrders": orders, "moreAvailable": false}
+		}
+
+		t.Errorf("Unexpected method %s", method)
+		return nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	instructions := []PlaceInstruction{
+		CreatePlaceInstruction(11, SideBack, 2.0, 10, PersistenceLapse),
+		CreatePlaceInstruction(12, SideLay, 3.0, 10, PersistenceLapse),
+	}
+
+	_, updates, err := client.PlaceOrdersAsync(ctx, "1.123456", instructions, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("PlaceOrdersAsync failed: %v", err)
+	}
+
+	var received []OrderStatusUpdate
+	for update := range updates {
+		if update.Err != nil {
+			t.Fatalf("Unexpected tracking error: %v", update.Err)
+		}
+		received = append(received, update)
+	}
+
+	if len(received) != 2 {
+		t.Fatalf("Expected 2 status updates, got %d: %+v", len(received), received)
+	}
+	if received[0].BetID != "1" || received[0].Status != OrderStatusExecutable {
+		t.Errorf("Unexpected first update: %+v", received[0])
+	}
+	if received[1].BetID != "2" || received[1].Status != OrderStatusExecutionComplete {
+		t.Errorf("Unexpected second update: %+v", received[1])
+	}
+	if len(placedRefs) != 2 || placedRefs[0] == "" || placedRefs[0] == placedRefs[1] {
+		t.Errorf("Expected unique customer order refs to be assigned, got %v", placedRefs)
+	}
+}
+
+func TestTrackOrdersStopsOnContextCancel(t *testing.T) {
+	client := newTestRESTClient(t, func(method string, params map[string]interface{}) interface{} {
+		return map[string]interface{}{"currentOrders": []interface{}{}, "moreAvailable": false}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	updates := client.TrackOrders(ctx, "1.123456", []string{"never-placed"}, 5*time.Millisecond)
+
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+
+	select {
+	case _, ok := <-updates:
+		if ok {
+			t.Error("Expected no updates for an order that never appears")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected update channel to close after context cancellation")
+	}
+}
+
+func TestTrackOrdersGivesUpOnOrdersThatNeverAppear(t *testing.T) {
+	client := newTestRESTClient(t, func(method string, params map[string]interface{}) interface{} {
+		return map[string]interface{}{"currentOrders": []interface{}{}, "moreAvailable": false}
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var received []OrderStatusUpdate
+	for update := range client.TrackOrders(ctx, "1.123456", []string{"lapsed"}, time.Millisecond) {
+		received = append(received, update)
+	}
+
+	if ctx.Err() != nil {
+		t.Fatal("Expected tracking to give up before the context expired")
+	}
+	if len(received) != 1 || received[0].CustomerOrderRef != "lapsed" || !errors.Is(received[0].Err, ErrOrderNotFound) {
+		t.Fatalf("Expected a single not found update, got %+v", received)
+	}
+}
+
+func TestPlaceOrdersAsyncReportsFailedInstructions(t *testing.T) {
+	client := newTestRESTClient(t, func(method string, params map[string]interface{}) interface{} {
+		switch method {
+		case "placeOrders":
+			var reports []map[string]interface{}
+			for i, raw := range params["instructions"].([]interface{}) {
+				report := map[string]interface{}{"status": "SUCCESS", "instruction": raw}
+				if i == 0 {
+					report["status"] = "FAILURE"
+					report["errorCode"] = "INVALID_ODDS"
+				}
+				reports = append(reports, report)
+			}
+			return map[string]interface{}{"status": "SUCCESS", "marketId": params["marketId"], "instructionReports": reports}
+		case "listCurrentOrders":
+			var orders []map[string]interface{}
+			for _, ref := range params["customerOrderRefs"].([]interface{}) {
+				orders = append(orders, map[string]interface{}{"betId": "2", "customerOrderRef": ref, "status": "EXECUTABLE"})
+			}
+			return map[string]interface{}{"currentOrders": orders, "moreAvailable": false}
+		}
+
+		t.Errorf("Unexpected method %s", method)
+		return nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	instructions := []PlaceInstruction{
+		CreatePlaceInstruction(11, SideBack, 2.01, 10, PersistenceLapse),
+		CreatePlaceInstruction(12, SideLay, 3.0, 10, PersistenceLapse),
+	}
+
+	_, updates, err := client.PlaceOrdersAsync(ctx, "1.123456", instructions, time.Millisecond)
+	if err != nil {
+		t.Fatalf("PlaceOrdersAsync failed: %v", err)
+	}
+
+	var received []OrderStatusUpdate
+	for update := range updates {
+		received = append(received, update)
+	}
+
+	if len(received) != 2 {
+		t.Fatalf("Expected 2 updates, got %d: %+v", len(received), received)
+	}
+	if received[0].Err == nil || !strings.Contains(received[0].Err.Error(), "INVALID_ODDS") {
+		t.Errorf("Expected the rejected instruction to be reported first, got %+v", received[0])
+	}
+	if received[1].Err != nil || received[1].Status != OrderStatusExecutable || received[1].CustomerOrderRef == received[0].CustomerOrderRef {
+		t.Errorf("Expected the other order to be tracked until placed, got %+v", received[1])
+	}
+	for _, instruction := range instructions {
+		if instruction.CustomerOrderRef != "" {
+			t.Errorf("Expected the caller's instructions to be left unchanged, got ref %q", instruction.CustomerOrderRef)
+		}
+	}
+}
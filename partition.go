@@ -0,0 +1,95 @@
+package betfair
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// MonthFormat is how the month directory of archive paths and storage keys is written
+type MonthFormat string
+
+const (
+	// MonthName writes Sep, matching Betfair's historical data downloads
+	MonthName MonthFormat = "name"
+	// MonthNumber writes 09, which sorts and partitions cleanly in tools such as Athena
+	MonthNumber MonthFormat = "number"
+)
+
+// PartitionDate is which time of a market its archive is filed under
+type PartitionDate string
+
+const (
+	PartitionByOpenDate   PartitionDate = "open"
+	PartitionBySettlement PartitionDate = "settled"
+)
+
+// PartitionTimezone is the timezone the partition date is taken in
+type PartitionTimezone string
+
+const (
+	PartitionUTC       PartitionTimezone = "utc"
+	PartitionVenueTime PartitionTimezone = "venue"
+)
+
+// PartitionScheme decides the year/month/day directories archives and uploads are filed under.
+// The zero value files by open date in UTC with month names.
+type PartitionScheme struct {
+	Month    MonthFormat
+	Date     PartitionDate
+	Timezone PartitionTimezone
+	Layout   LayoutVersion
+}
+
+// ParsePartitionScheme parses the month format ("name" or "number"), date ("open" or "settled")
+// and timezone ("utc" or "venue"); empty values keep the defaults
+func ParsePartitionScheme(month, date, timezone string) (PartitionScheme, error) {
+	scheme := PartitionScheme{
+		Month:    MonthFormat(strings.ToLower(strings.TrimSpace(month))),
+		Date:     PartitionDate(strings.ToLower(strings.TrimSpace(date))),
+		Timezone: PartitionTimezone(strings.ToLower(strings.TrimSpace(timezone))),
+	}
+	switch scheme.Month {
+	case "", MonthName, MonthNumber:
+	default:
+		return PartitionScheme{}, fmt.Errorf("unknown month format %q", month)
+	}
+	switch scheme.Date {
+	case "", PartitionByOpenDate, PartitionBySettlement:
+	default:
+		return PartitionScheme{}, fmt.Errorf("unknown partition date %q", date)
+	}
+	switch scheme.Timezone {
+	case "", PartitionUTC, PartitionVenueTime:
+	default:
+		return PartitionScheme{}, fmt.Errorf("unknown partition timezone %q", timezone)
+	}
+	return scheme, nil
+}
+
+// Apply files an event under the scheme's date. Markets without a settlement time, such as
+// incomplete recordings and segments rotated before the off, fall back to the open date, and an
+// unknown venue timezone falls back to UTC.
+func (s PartitionScheme) Apply(eventInfo *EventInfo) {
+	date := eventInfo.Date
+	if s.Date == PartitionBySettlement && !eventInfo.SettledTime.IsZero() {
+		date = eventInfo.SettledTime
+	}
+
+	location := time.UTC
+	if s.Timezone == PartitionVenueTime && eventInfo.Timezone != "" {
+		if venue, err := time.LoadLocation(eventInfo.Timezone); err == nil {
+			location = venue
+		}
+	}
+	date = date.In(location)
+
+	eventInfo.Date = date
+	eventInfo.Year = date.Format("2006")
+	eventInfo.Month = date.Format("Jan")
+	if s.Month == MonthNumber || s.Layout == LayoutV2 {
+		eventInfo.Month = date.Format("01")
+	}
+	eventInfo.Day = date.Format("2")
+	eventInfo.Layout = s.Layout.orDefault()
+}
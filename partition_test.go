@@ -0,0 +1,64 @@
+package betfair
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParsePartitionScheme(t *testing.T) {
+	scheme, err := ParsePartitionScheme(" Number ", "settled", "VENUE")
+	if err != nil {
+		t.Fatalf("ParsePartitionScheme failed: %v", err)
+	}
+	if scheme != (PartitionScheme{Month: MonthNumber, Date: PartitionBySettlement, Timezone: PartitionVenueTime}) {
+		t.Errorf("Unexpected scheme %+v", scheme)
+	}
+
+	if scheme, err := ParsePartitionScheme("", "", ""); err != nil || scheme != (PartitionScheme{}) {
+		t.Errorf("Expected empty values to give the default scheme, got %+v, %v", scheme, err)
+	}
+
+	for _, values := range [][3]string{{"roman", "", ""}, {"", "closed", ""}, {"", "", "local"}} {
+		if _, err := ParsePartitionScheme(values[0], values[1], values[2]); err == nil {
+			t.Errorf("Expected %v to be rejected", values)
+		}
+	}
+}
+
+func TestPartitionSchemeApply(t *testing.T) {
+	// A Sydney meeting opening on 31 December UTC, already New Year's Day locally, and settling after midnight UTC
+	payload := []byte(`{"op":"mcm","mc":[{"marketDefinition":{"eventId":"99","timezone":"Australia/Sydney",` +
+		`"openDate":"2025-12-31T15:30:00.000Z","settledTime":"2026-01-01T00:10:00.000Z"}}]}`)
+
+	tests := []struct {
+		name     string
+		scheme   PartitionScheme
+		expected [3]string
+	}{
+		{name: "default", scheme: PartitionScheme{}, expected: [3]string{"2025", "Dec", "31"}},
+		{name: "numeric month", scheme: PartitionScheme{Month: MonthNumber}, expected: [3]string{"2025", "12", "31"}},
+		{name: "settlement", scheme: PartitionScheme{Date: PartitionBySettlement}, expected: [3]string{"2026", "Jan", "1"}},
+		{name: "venue time", scheme: PartitionScheme{Month: MonthNumber, Timezone: PartitionVenueTime}, expected: [3]string{"2026", "01", "1"}},
+		{name: "venue settlement", scheme: PartitionScheme{Date: PartitionBySettlement, Timezone: PartitionVenueTime}, expected: [3]string{"2026", "Jan", "1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			eventInfo, err := ExtractEventInfo(payload)
+			if err != nil {
+				t.Fatalf("ExtractEventInfo failed: %v", err)
+			}
+			tt.scheme.Apply(eventInfo)
+			if got := [3]string{eventInfo.Year, eventInfo.Month, eventInfo.Day}; got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+
+	// Without a settlement time or a known timezone the open date in UTC is used
+	eventInfo := NewEventInfo("99", time.Date(2025, 12, 31, 15, 30, 0, 0, time.UTC))
+	eventInfo.Timezone = "Mars/Olympus_Mons"
+	PartitionScheme{Date: PartitionBySettlement, Timezone: PartitionVenueTime}.Apply(eventInfo)
+	if eventInfo.Day != "31" || eventInfo.Date.Location() != time.UTC {
+		t.Errorf("Expected open date in UTC, got %s", eventInfo.Date)
+	}
+}
@@ -0,0 +1,99 @@
+package betfair
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// MinPrice and MaxPrice are the ends of Betfair's price ladder
+const (
+	MinPrice = 1.01
+	MaxPrice = 1000.0
+)
+
+// priceBands are the increments of Betfair's price ladder in hundredths: each band's step applies
+// to prices below its upper bound
+var priceBands = []struct {
+	below, step int
+}{
+	{200, 1},
+	{300, 2},
+	{400, 5},
+	{600, 10},
+	{1000, 20},
+	{2000, 50},
+	{3000, 100},
+	{5000, 200},
+	{10000, 500},
+	{100000, 1000},
+}
+
+// priceTicks is every valid price in hundredths, from 1.01 to 1000, built once from the bands so
+// tick arithmetic never accumulates floating point error
+var priceTicks = buildPriceTicks()
+
+func buildPriceTicks() []int {
+	ticks := make([]int, 0, 350)
+	price := int(MinPrice * 100)
+	for _, band := range priceBands {
+		for ; price < band.below; price += band.step {
+			ticks = append(ticks, price)
+		}
+	}
+	return append(ticks, int(MaxPrice*100))
+}
+
+// PriceTicks returns every valid price of the ladder in ascending order
+func PriceTicks() []float64 {
+	prices := make([]float64, len(priceTicks))
+	for i, tick := range priceTicks {
+		prices[i] = float64(tick) / 100
+	}
+	return prices
+}
+
+// tickIndex returns a price's position on the ladder, and false when it isn't a valid price
+func tickIndex(price float64) (int, bool) {
+	hundredths := int(math.Round(price * 100))
+	if math.Abs(price*100-float64(hundredths)) > 1e-6 {
+		return 0, false
+	}
+	i := sort.SearchInts(priceTicks, hundredths)
+	return i, i < len(priceTicks) && priceTicks[i] == hundredths
+}
+
+// IsValidPrice reports whether a price is on the ladder, such as 2.02 but not 2.01
+func IsValidPrice(price float64) bool {
+	_, ok := tickIndex(price)
+	return ok
+}
+
+// PriceAdd moves a valid price up the ladder by ticks, or down for negative ticks. Moves past
+// either end stop at MinPrice or MaxPrice.
+func PriceAdd(price float64, ticks int) (float64, error) {
+	i, ok := tickIndex(price)
+	if !ok {
+		return 0, fmt.Errorf("price %v is not on the ladder", price)
+	}
+	i = min(max(i+ticks, 0), len(priceTicks)-1)
+	return float64(priceTicks[i]) / 100, nil
+}
+
+// PriceSubtract moves a valid price down the ladder by ticks, stopping at MinPrice
+func PriceSubtract(price float64, ticks int) (float64, error) {
+	return PriceAdd(price, -ticks)
+}
+
+// TicksBetween returns how many ticks b is above a, negative when it is below
+func TicksBetween(a, b float64) (int, error) {
+	i, ok := tickIndex(a)
+	if !ok {
+		return 0, fmt.Errorf("price %v is not on the ladder", a)
+	}
+	j, ok := tickIndex(b)
+	if !ok {
+		return 0, fmt.Errorf("price %v is not on the ladder", b)
+	}
+	return j - i, nil
+}
@@ -0,0 +1,70 @@
+package betfair
+
+import "testing"
+
+func TestPriceTicks(t *testing.T) {
+	ticks := PriceTicks()
+	if len(ticks) != 350 || ticks[0] != MinPrice || ticks[len(ticks)-1] != MaxPrice {
+		t.Fatalf("expected 350 ticks from 1.01 to 1000, got %d from %v to %v", len(ticks), ticks[0], ticks[len(ticks)-1])
+	}
+	for i := 1; i < len(ticks); i++ {
+		if ticks[i] <= ticks[i-1] {
+			t.Fatalf("ticks out of order at %d: %v after %v", i, ticks[i], ticks[i-1])
+		}
+	}
+
+	for _, price := range []float64{1.01, 1.99, 2.02, 3.05, 4.1, 6.2, 10.5, 20, 32, 55, 110, 1000} {
+		if !IsValidPrice(price) {
+			t.Errorf("expected %v to be valid", price)
+		}
+	}
+	for _, price := range []float64{1, 1.015, 2.01, 3.01, 4.05, 6.1, 10.2, 21.5, 31, 52, 105, 1010} {
+		if IsValidPrice(price) {
+			t.Errorf("expected %v to be invalid", price)
+		}
+	}
+}
+
+func TestPriceTickArithmetic(t *testing.T) {
+	tests := []struct {
+		price float64
+		ticks int
+		want  float64
+	}{
+		{1.99, 1, 2},
+		{2, 1, 2.02},
+		{2, -1, 1.99},
+		{2.98, 1, 3},
+		{3.95, 1, 4},
+		{9.8, 1, 10},
+		{19.5, 1, 20},
+		{29, 1, 30},
+		{48, 1, 50},
+		{95, 1, 100},
+		{990, 5, 1000},
+		{1.02, -5, 1.01},
+	}
+	for _, tt := range tests {
+		got, err := PriceAdd(tt.price, tt.ticks)
+		if err != nil || got != tt.want {
+			t.Errorf("PriceAdd(%v, %d) = %v, %v, want %v", tt.price, tt.ticks, got, err, tt.want)
+		}
+	}
+
+	if got, err := PriceSubtract(3, 2); err != nil || got != 2.96 {
+		t.Errorf("PriceSubtract(3, 2) = %v, %v, want 2.96", got, err)
+	}
+	if _, err := PriceAdd(2.01, 1); err == nil {
+		t.Errorf("expected an error moving a price off the ladder")
+	}
+
+	if n, err := TicksBetween(1.01, 1000); err != nil || n != 349 {
+		t.Errorf("TicksBetween(1.01, 1000) = %d, %v, want 349", n, err)
+	}
+	if n, err := TicksBetween(4, 3.9); err != nil || n != -2 {
+		t.Errorf("TicksBetween(4, 3.9) = %d, %v, want -2", n, err)
+	}
+	if _, err := TicksBetween(4, 3.91); err == nil {
+		t.Errorf("expected an error for a price off the ladder")
+	}
+}
@@ -0,0 +1,133 @@
+package processor
+
+import "sort"
+
+// runnerBook is a runner's price ladder rebuilt from stream deltas
+type runnerBook struct {
+	bestBack map[int][2]float64  // batb: level -> price, size
+	bestLay  map[int][2]float64  // batl: level -> price, size
+	back     map[float64]float64 // atb: price -> size
+	lay      map[float64]float64 // atl: price -> size
+	traded   map[float64]float64 // trd: price -> volume matched
+
+	tradedTotal float64 // Sum of traded
+}
+
+// apply folds a runner update into the book; a zero size removes a level
+func (b *runnerBook) apply(update RunnerUpdate) {
+	b.bestBack = applyLevels(b.bestBack, update.BATB)
+	b.bestLay = applyLevels(b.bestLay, update.BATL)
+	b.back = applyPrices(b.back, update.ATB)
+	b.lay = applyPrices(b.lay, update.ATL)
+	for _, delta := range update.TRD {
+		if len(delta) < 2 {
+			continue
+		}
+		if b.traded == nil {
+			b.traded = make(map[float64]float64)
+		}
+		b.tradedTotal += delta[1] - b.traded[delta[0]]
+		if delta[1] == 0 {
+			delete(b.traded, delta[0])
+		} else {
+			b.traded[delta[0]] = delta[1]
+		}
+	}
+}
+
+func applyLevels(levels map[int][2]float64, deltas [][]float64) map[int][2]float64 {
+	if len(deltas) == 0 {
+		return levels
+	}
+	if levels == nil {
+		levels = make(map[int][2]float64)
+	}
+	for _, delta := range deltas {
+		if len(delta) < 3 {
+			continue
+		}
+		if delta[2] == 0 {
+			delete(levels, int(delta[0]))
+		} else {
+			levels[int(delta[0])] = [2]float64{delta[1], delta[2]}
+		}
+	}
+	return levels
+}
+
+func applyPrices(prices map[float64]float64, deltas [][]float64) map[float64]float64 {
+	if len(deltas) == 0 {
+		return prices
+	}
+	if prices == nil {
+		prices = make(map[float64]float64)
+	}
+	for _, delta := range deltas {
+		if len(delta) < 2 {
+			continue
+		}
+		if delta[1] == 0 {
+			delete(prices, delta[0])
+		} else {
+			prices[delta[0]] = delta[1]
+		}
+	}
+	return prices
+}
+
+// backLevels returns up to depth back prices, best first, preferring the best-available ladder
+func (b *runnerBook) backLevels(depth int) [][2]float64 {
+	if len(b.bestBack) > 0 {
+		return sortedLevels(b.bestBack, depth)
+	}
+	return sortedPrices(b.back, depth, true)
+}
+
+// layLevels returns up to depth lay prices, best first, preferring the best-available ladder
+func (b *runnerBook) layLevels(depth int) [][2]float64 {
+	if len(b.bestLay) > 0 {
+		return sortedLevels(b.bestLay, depth)
+	}
+	return sortedPrices(b.lay, depth, false)
+}
+
+// tradedVolume is the total volume matched on the runner
+func (b *runnerBook) tradedVolume() float64 {
+	return b.tradedTotal
+}
+
+func sortedLevels(levels map[int][2]float64, depth int) [][2]float64 {
+	keys := make([]int, 0, len(levels))
+	for level := range levels {
+		keys = append(keys, level)
+	}
+	sort.Ints(keys)
+	if len(keys) > depth {
+		keys = keys[:depth]
+	}
+	result := make([][2]float64, 0, len(keys))
+	for _, level := range keys {
+		result = append(result, levels[level])
+	}
+	return result
+}
+
+func sortedPrices(prices map[float64]float64, depth int, descending bool) [][2]float64 {
+	keys := make([]float64, 0, len(prices))
+	for price := range prices {
+		keys = append(keys, price)
+	}
+	if descending {
+		sort.Sort(sort.Reverse(sort.Float64Slice(keys)))
+	} else {
+		sort.Float64s(keys)
+	}
+	if len(keys) > depth {
+		keys = keys[:depth]
+	}
+	result := make([][2]float64, 0, len(keys))
+	for _, price := range keys {
+		result = append(result, [2]float64{price, prices[price]})
+	}
+	return result
+}
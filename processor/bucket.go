@@ -0,0 +1,57 @@
+package processor
+
+import (
+	"sync"
+	"time"
+)
+
+// BucketTimezoneMarket buckets each market's day in the timezone of its market definition
+const BucketTimezoneMarket = "market"
+
+// marketZones caches the locations of market definition timezones by name
+var marketZones sync.Map
+
+// bucketLocation returns the location race days are bucketed in for Config.BucketTimezone: UTC
+// when unset, or nil for BucketTimezoneMarket
+func bucketLocation(timezone string) (*time.Location, error) {
+	switch timezone {
+	case "":
+		return time.UTC, nil
+	case BucketTimezoneMarket:
+		return nil, nil
+	}
+	return time.LoadLocation(timezone)
+}
+
+// raceDay is the day a market is bucketed in, as midnight UTC of its date in the bucketing
+// timezone. An Australian evening meeting is one race day although it spans two UTC dates.
+func (p *MarketDataProcessor) raceDay(marketTime time.Time, timezone string) time.Time {
+	location := p.bucketZone
+	if location == nil {
+		location = marketZone(timezone)
+	}
+	year, month, day := marketTime.In(location).Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// marketZone returns the location of a market definition's timezone, or UTC when it is missing
+// or unknown
+func marketZone(timezone string) *time.Location {
+	if timezone == "" {
+		return time.UTC
+	}
+	if location, ok := marketZones.Load(timezone); ok {
+		return location.(*time.Location)
+	}
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		location = time.UTC
+	}
+	marketZones.Store(timezone, location)
+	return location
+}
+
+// bucketsInUTC reports whether race days are UTC dates, as the dates in Betfair's file paths are
+func (p *MarketDataProcessor) bucketsInUTC() bool {
+	return p.bucketZone == time.UTC
+}
@@ -0,0 +1,160 @@
+package processor
+
+import (
+	"bytes"
+	"compress/bzip2"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"sync"
+
+	dsbzip2 "github.com/dsnet/compress/bzip2"
+)
+
+// Bzip2Decoder is the implementation .bz2 inputs are decompressed with
+type Bzip2Decoder string
+
+const (
+	Bzip2DecoderStdlib   Bzip2Decoder = "stdlib"   // compress/bzip2, streaming on one core
+	Bzip2DecoderDsnet    Bzip2Decoder = "dsnet"    // github.com/dsnet/compress/bzip2, a faster streaming decoder
+	Bzip2DecoderParallel Bzip2Decoder = "parallel" // Decodes a file's blocks on every core, holding the file in memory
+)
+
+const (
+	bzip2BlockMagic = 0x314159265359 // π, starts each compressed block
+	bzip2EndMagic   = 0x177245385090 // √π, ends a stream
+)
+
+// bzip2Reader returns a reader decompressing r with Config.Bzip2Decoder
+func (p *MarketDataProcessor) bzip2Reader(r io.Reader) (io.Reader, error) {
+	switch p.Config.Bzip2Decoder {
+	case Bzip2DecoderDsnet:
+		return dsbzip2.NewReader(r, nil)
+	case Bzip2DecoderParallel:
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		decoded, err := decodeBzip2Parallel(data, runtime.GOMAXPROCS(0))
+		if err != nil {
+			// Such as a block magic number occurring by chance inside a block
+			p.logger.Debug().Err(err).Msg("parallel bzip2 decode failed; decoding sequentially")
+			return bzip2.NewReader(bytes.NewReader(data)), nil
+		}
+		return bytes.NewReader(decoded), nil
+	default:
+		return bzip2.NewReader(r), nil
+	}
+}
+
+// decodeBzip2Parallel decompresses bzip2 data the way pbzip2 does. Compressed blocks are
+// independent but bit-aligned, so each is found by its magic number, shifted into a one-block
+// stream of its own and decoded on one of workers goroutines.
+func decodeBzip2Parallel(data []byte, workers int) ([]byte, error) {
+	if len(data) < 4 || !bytes.HasPrefix(data, []byte("BZh")) || data[3] < '1' || data[3] > '9' {
+		return nil, errors.New("bzip2: missing stream header")
+	}
+
+	type marker struct {
+		bit int
+		end bool
+	}
+	var markers []marker
+	var window uint64
+	for i := range len(data) * 8 {
+		window = (window<<1 | uint64(data[i/8]>>(7-i%8)&1)) & (1<<48 - 1)
+		switch {
+		case i < 47:
+		case window == bzip2BlockMagic:
+			markers = append(markers, marker{bit: i - 47})
+		case window == bzip2EndMagic:
+			markers = append(markers, marker{bit: i - 47, end: true})
+		}
+	}
+
+	var streams [][]byte
+	for i, m := range markers {
+		if m.end {
+			continue
+		}
+		if i+1 == len(markers) || markers[i+1].bit-m.bit < 80 {
+			return nil, fmt.Errorf("bzip2: truncated block at bit %d", m.bit)
+		}
+		streams = append(streams, bzip2BlockStream(data, m.bit, markers[i+1].bit))
+	}
+	if len(streams) == 0 {
+		return nil, errors.New("bzip2: no blocks found")
+	}
+
+	decoded := make([][]byte, len(streams))
+	errs := make([]error, len(streams))
+	next := make(chan int, len(streams))
+	for i := range streams {
+		next <- i
+	}
+	close(next)
+
+	var wg sync.WaitGroup
+	for range min(max(workers, 1), len(streams)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				decoded[i], errs[i] = io.ReadAll(bzip2.NewReader(bytes.NewReader(streams[i])))
+			}
+		}()
+	}
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return bytes.Join(decoded, nil), nil
+}
+
+// bzip2BlockStream wraps the block in bits [start, end) of data in a stream of its own. A
+// one-block stream's CRC is the block's CRC, which follows the block magic.
+func bzip2BlockStream(data []byte, start, end int) []byte {
+	var w bitWriter
+	w.buf = make([]byte, 0, (end-start)/8+16)
+	w.buf = append(w.buf, "BZh9"...)
+	var crc uint64
+	for i := start; i < end; i++ {
+		bit := uint64(data[i/8] >> (7 - i%8) & 1)
+		if i >= start+48 && i < start+80 {
+			crc = crc<<1 | bit
+		}
+		w.writeBits(bit, 1)
+	}
+	w.writeBits(bzip2EndMagic, 48)
+	w.writeBits(crc, 32)
+	return w.flush()
+}
+
+// bitWriter appends bits most significant first, as bzip2 packs them
+type bitWriter struct {
+	buf  []byte
+	acc  byte
+	bits uint
+}
+
+func (w *bitWriter) writeBits(value uint64, n uint) {
+	for n > 0 {
+		n--
+		w.acc = w.acc<<1 | byte(value>>n&1)
+		if w.bits++; w.bits == 8 {
+			w.buf = append(w.buf, w.acc)
+			w.acc, w.bits = 0, 0
+		}
+	}
+}
+
+// flush pads the last byte with zero bits and returns the bytes written
+func (w *bitWriter) flush() []byte {
+	if w.bits > 0 {
+		w.buf = append(w.buf, w.acc<<(8-w.bits))
+		w.acc, w.bits = 0, 0
+	}
+	return w.buf
+}
@@ -0,0 +1,117 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	betfair "github.com/felixmccuaig/betfair-go"
+)
+
+// cachePath is where a remote object is kept in Config.CacheDir: under its scheme, bucket and key,
+// suffixed with its ETag so a changed object is downloaded again. It is "" when caching is off or
+// the object's ETag isn't known.
+func (p *MarketDataProcessor) cachePath(remotePath, etag string) string {
+	if p.Config.CacheDir == "" || etag == "" {
+		return ""
+	}
+	location, err := betfair.ParseStorageLocation(remotePath)
+	if err != nil {
+		return ""
+	}
+	// ETags may be quoted or hold characters that don't belong in file names
+	etag = strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == '"' || r == ':' {
+			return '_'
+		}
+		return r
+	}, etag)
+	return filepath.Join(p.Config.CacheDir, location.Scheme, location.Bucket, filepath.FromSlash(location.Path)) + "." + etag
+}
+
+// sourceETag is the ETag of a remote object noted when it was listed
+func (p *MarketDataProcessor) sourceETag(remotePath string) string {
+	if p.parent != nil {
+		return p.parent.sourceETag(remotePath)
+	}
+	return p.sources[remotePath].etag
+}
+
+// cached reports whether a listed remote object is already in the cache
+func (p *MarketDataProcessor) cached(remotePath string) bool {
+	path := p.cachePath(remotePath, p.sourceETag(remotePath))
+	if path == "" {
+		return false
+	}
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// openRemote opens a remote object for reading. With Config.CacheDir set the object is read from
+// the cache, downloading it there first when it isn't cached at its current ETag.
+func (p *MarketDataProcessor) openRemote(ctx context.Context, remotePath string) (io.ReadCloser, error) {
+	storage, key, err := p.objectStorage(remotePath)
+	if err != nil {
+		return nil, err
+	}
+	if p.Config.CacheDir == "" {
+		return storage.Download(ctx, key)
+	}
+
+	etag := p.sourceETag(remotePath)
+	if etag == "" {
+		// Not listed, such as a single object passed to ProcessPath
+		info, err := storage.Head(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		etag = info.ETag
+	}
+	path := p.cachePath(remotePath, etag)
+	if path == "" {
+		return storage.Download(ctx, key)
+	}
+
+	if file, err := os.Open(path); err == nil {
+		p.logger.Debug().Str("path", remotePath).Str("cache", path).Msg("reading cached object")
+		return file, nil
+	}
+	if err := downloadToCache(ctx, storage, key, path); err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
+
+// downloadToCache downloads an object to a temporary file beside path and renames it into place,
+// so an interrupted download never leaves a partial object in the cache
+func downloadToCache(ctx context.Context, storage betfair.ObjectStorage, key, path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	body, err := storage.Download(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	tmpFile, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create cache file: %w", err)
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if _, err := io.Copy(tmpFile, body); err != nil {
+		tmpFile.Close()
+		return fmt.Errorf("failed to download to cache: %w", err)
+	}
+	if err := tmpFile.Close(); err != nil {
+		return fmt.Errorf("failed to write cache file: %w", err)
+	}
+	if err := os.Rename(tmpFile.Name(), path); err != nil {
+		return fmt.Errorf("failed to move object into cache: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,270 @@
+package processor
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// duckDBDriver is the database/sql driver name DuckDB drivers such as github.com/marcboeker/go-duckdb
+// register. The processor does not import one, so binaries writing DuckDB output must, as
+// betfair-go does when built with -tags duckdb.
+const duckDBDriver = "duckdb"
+
+const (
+	defaultDatabaseDriver    = "pgx" // Registered by github.com/jackc/pgx/v5/stdlib, in betfair-go built with -tags pgx
+	defaultDatabaseTable     = "summary"
+	defaultDatabaseBatchSize = 500
+)
+
+// sqlColumn is a column of a table the processor writes to a database
+type sqlColumn struct {
+	name    string
+	sqlType string
+}
+
+// sqlTable describes a table: its columns, the columns indexed, and how a row maps to its values
+type sqlTable[T any] struct {
+	name    string
+	columns []sqlColumn
+	key     []string // Primary key; when set, inserts update the existing row with the same key
+	indexes [][]string
+	values  func(row T) []any // In column order; nil pointers are written as NULL
+}
+
+var summaryTable = sqlTable[SummaryRow]{
+	name: "summary",
+	columns: []sqlColumn{
+		{"market_id", "VARCHAR"}, {"selection_id", "BIGINT"}, {"event_id", "VARCHAR"},
+		{"event_name", "VARCHAR"}, {"venue", "VARCHAR"}, {"greyhound_name", "VARCHAR"},
+		{"market_time", "TIMESTAMPTZ"}, {"bsp", "DOUBLE PRECISION"}, {"ltp", "DOUBLE PRECISION"},
+		{"price_30s_before_start", "DOUBLE PRECISION"}, {"total_traded_volume", "DOUBLE PRECISION"},
+		{"max_traded_price", "DOUBLE PRECISION"}, {"min_traded_price", "DOUBLE PRECISION"},
+		{"year", "INTEGER"}, {"month", "INTEGER"}, {"day", "INTEGER"}, {"win", "BOOLEAN"},
+		{"event_type_id", "VARCHAR"}, {"market_type", "VARCHAR"}, {"number_of_winners", "INTEGER"},
+		{"placed", "BOOLEAN"}, {"place_market_id", "VARCHAR"}, {"place_bsp", "DOUBLE PRECISION"},
+		{"place_ltp", "DOUBLE PRECISION"}, {"number_of_places", "INTEGER"}, {"vwap", "DOUBLE PRECISION"},
+		{"volume_last_60s", "DOUBLE PRECISION"}, {"volume_last_5m", "DOUBLE PRECISION"},
+		{"in_play_volume_pct", "DOUBLE PRECISION"}, {"sp_near", "DOUBLE PRECISION"},
+		{"sp_far", "DOUBLE PRECISION"}, {"bsp_reconciled", "BOOLEAN"}, {"bsp_reconciled_time", "TIMESTAMPTZ"},
+		{"in_play_high", "DOUBLE PRECISION"}, {"in_play_low", "DOUBLE PRECISION"},
+		{"in_play_volume", "DOUBLE PRECISION"}, {"seconds_in_play", "DOUBLE PRECISION"},
+		{"scratched", "BOOLEAN"}, {"removal_date", "TIMESTAMPTZ"}, {"adjustment_factor", "DOUBLE PRECISION"},
+		{"box", "INTEGER"},
+	},
+	indexes: [][]string{{"market_id", "selection_id"}, {"market_time"}, {"venue"}},
+	values: func(row SummaryRow) []any {
+		r := newSummaryParquetRow(row)
+		return []any{
+			r.MarketID, r.SelectionID, r.EventID, r.EventName, r.Venue, r.GreyhoundName,
+			r.MarketTime, r.BSP, r.LTP, r.Price30sBeforeStart, r.TotalTradedVolume,
+			r.MaxTradedPrice, r.MinTradedPrice, r.Year, r.Month, r.Day, r.Win,
+			r.EventTypeID, r.MarketType, r.NumberOfWinners, r.Placed, r.PlaceMarketID,
+			r.PlaceBSP, r.PlaceLTP, r.NumberOfPlaces, r.VWAP, r.VolumeLast60s, r.VolumeLast5m,
+			r.InPlayVolumePct, r.SPNear, r.SPFar, r.BSPReconciled, r.BSPReconciledTime,
+			r.InPlayHigh, r.InPlayLow, r.InPlayVolume, r.SecondsInPlay,
+			r.Scratched, r.RemovalDate, r.AdjustmentFactor, r.Box,
+		}
+	},
+}
+
+var ticksTable = sqlTable[TickRow]{
+	name: "ticks",
+	columns: []sqlColumn{
+		{"market_id", "VARCHAR"}, {"selection_id", "BIGINT"}, {"pt", "TIMESTAMPTZ"},
+		{"ltp", "DOUBLE PRECISION"}, {"best_back_price", "DOUBLE PRECISION"},
+		{"best_back_size", "DOUBLE PRECISION"}, {"best_lay_price", "DOUBLE PRECISION"},
+		{"best_lay_size", "DOUBLE PRECISION"}, {"traded_volume", "DOUBLE PRECISION"},
+	},
+	indexes: [][]string{{"market_id", "selection_id", "pt"}},
+	values: func(row TickRow) []any {
+		return []any{
+			row.MarketID, row.SelectionID, row.PublishTime,
+			optional(row.LTP, row.LTP != 0), optional(row.BackPrice, row.BackPrice != 0),
+			optional(row.BackSize, row.BackSize != 0), optional(row.LayPrice, row.LayPrice != 0),
+			optional(row.LaySize, row.LaySize != 0), row.TradedVolume,
+		}
+	},
+}
+
+// createSQL returns the statements creating the table and its indexes if they don't exist
+func (t sqlTable[T]) createSQL() []string {
+	columns := make([]string, len(t.columns))
+	for i, column := range t.columns {
+		columns[i] = column.name + " " + column.sqlType
+	}
+	if len(t.key) > 0 {
+		columns = append(columns, fmt.Sprintf("PRIMARY KEY (%s)", strings.Join(t.key, ", ")))
+	}
+	statements := []string{fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s)", t.name, strings.Join(columns, ", "))}
+
+	// Index names can't be schema-qualified, so analytics.summary's indexes are analytics_summary_*
+	prefix := strings.ReplaceAll(t.name, ".", "_")
+	for _, index := range t.indexes {
+		statements = append(statements, fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s_%s_idx ON %s (%s)",
+			prefix, strings.Join(index, "_"), t.name, strings.Join(index, ", ")))
+	}
+	return statements
+}
+
+// insertSQL returns the statement inserting rows rows, with $n placeholders, updating rows whose
+// key already exists
+func (t sqlTable[T]) insertSQL(rows int) string {
+	names := make([]string, len(t.columns))
+	for i, column := range t.columns {
+		names[i] = column.name
+	}
+
+	values := make([]string, rows)
+	placeholders := make([]string, len(t.columns))
+	for row := range values {
+		for i := range t.columns {
+			placeholders[i] = fmt.Sprintf("$%d", row*len(t.columns)+i+1)
+		}
+		values[row] = "(" + strings.Join(placeholders, ", ") + ")"
+	}
+
+	statement := fmt.Sprintf("INSERT INTO %s (%s) VALUES %s", t.name, strings.Join(names, ", "), strings.Join(values, ", "))
+	if len(t.key) == 0 {
+		return statement
+	}
+
+	var updates []string
+	for _, name := range names {
+		if !matchesAny(t.key, name) {
+			updates = append(updates, name+" = EXCLUDED."+name)
+		}
+	}
+	return fmt.Sprintf("%s ON CONFLICT (%s) DO UPDATE SET %s", statement, strings.Join(t.key, ", "), strings.Join(updates, ", "))
+}
+
+// writeTable creates the table if needed and inserts the rows in batches of batchSize in one transaction
+func writeTable[T any](db *sql.DB, table sqlTable[T], data []T, batchSize int) error {
+	for _, statement := range table.createSQL() {
+		if _, err := db.Exec(statement); err != nil {
+			return fmt.Errorf("failed to create table %s: %w", table.name, err)
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for start := 0; start < len(data); start += batchSize {
+		batch := data[start:min(start+batchSize, len(data))]
+		args := make([]any, 0, len(batch)*len(table.columns))
+		for _, row := range batch {
+			args = append(args, table.values(row)...)
+		}
+		if _, err := tx.Exec(table.insertSQL(len(batch)), args...); err != nil {
+			return fmt.Errorf("failed to insert into %s: %w", table.name, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit %s: %w", table.name, err)
+	}
+	return nil
+}
+
+// latestByKey drops all but the last row for each key, as one statement can't upsert a key twice
+func latestByKey[R any, K comparable](data []R, key func(R) K) []R {
+	last := make(map[K]int, len(data))
+	for i, row := range data {
+		last[key(row)] = i
+	}
+	if len(last) == len(data) {
+		return data
+	}
+
+	latest := make([]R, 0, len(last))
+	for i, row := range data {
+		if last[key(row)] == i {
+			latest = append(latest, row)
+		}
+	}
+	return latest
+}
+
+// duckDBPath is the DuckDB file output goes to: the output file, or <prefix>.duckdb in the output directory
+func (p *MarketDataProcessor) duckDBPath() (string, error) {
+	outputPath := p.OutputFile
+	if outputPath == "" {
+		outputPath = filepath.Join(p.OutputDir, p.Profile.FilePrefix+".duckdb")
+	}
+	if isRemotePath(outputPath) {
+		return "", fmt.Errorf("duckdb output must be a local file: %s", outputPath)
+	}
+	return outputPath, nil
+}
+
+// saveDuckDB writes rows to a table in the DuckDB output file, creating it with its indexes if needed
+func saveDuckDB[T any](p *MarketDataProcessor, table sqlTable[T], data []T) error {
+	outputPath, err := p.duckDBPath()
+	if err != nil {
+		return err
+	}
+
+	db, err := sql.Open(duckDBDriver, outputPath)
+	if err != nil {
+		return fmt.Errorf("failed to open duckdb %s (is a DuckDB driver imported?): %w", outputPath, err)
+	}
+	defer db.Close()
+
+	if err := writeTable(db, table, data, p.Config.DatabaseBatchSize); err != nil {
+		return err
+	}
+	p.logger.Info().Int("rows", len(data)).Str("table", table.name).Str("path", outputPath).Msg("wrote rows")
+	return nil
+}
+
+// summaryKey identifies a runner's summary row
+type summaryKey struct {
+	marketID    string
+	selectionID int64
+}
+
+// saveDatabase upserts summary rows into Config.DatabaseTable, keyed on market and selection, so
+// re-processing a market replaces its rows
+func (p *MarketDataProcessor) saveDatabase(data []SummaryRow) error {
+	db, err := sql.Open(p.Config.DatabaseDriver, p.Config.DatabaseDSN)
+	if err != nil {
+		return fmt.Errorf("failed to open %s database (is its driver imported?): %w", p.Config.DatabaseDriver, err)
+	}
+	defer db.Close()
+
+	table := summaryTable
+	table.name = p.Config.DatabaseTable
+	table.key = []string{"market_id", "selection_id"}
+	table.indexes = [][]string{{"market_time"}, {"venue"}}
+
+	data = latestByKey(data, func(row SummaryRow) summaryKey { return summaryKey{row.MarketID, row.SelectionID} })
+	if err := writeTable(db, table, data, p.Config.DatabaseBatchSize); err != nil {
+		return err
+	}
+	p.logger.Info().Int("rows", len(data)).Str("table", table.name).Msg("upserted rows")
+	return nil
+}
+
+// saveDatabaseMarkets upserts market rows into <DatabaseTable>_markets, keyed on market
+func (p *MarketDataProcessor) saveDatabaseMarkets(data []MarketRow) error {
+	db, err := sql.Open(p.Config.DatabaseDriver, p.Config.DatabaseDSN)
+	if err != nil {
+		return fmt.Errorf("failed to open %s database (is its driver imported?): %w", p.Config.DatabaseDriver, err)
+	}
+	defer db.Close()
+
+	table := marketsTable
+	table.name = p.Config.DatabaseTable + "_markets"
+	table.key = []string{"market_id"}
+	table.indexes = [][]string{{"market_time"}, {"venue"}}
+
+	data = latestByKey(data, func(row MarketRow) string { return row.MarketID })
+	if err := writeTable(db, table, data, p.Config.DatabaseBatchSize); err != nil {
+		return err
+	}
+	p.logger.Info().Int("rows", len(data)).Str("table", table.name).Msg("upserted rows")
+	return nil
+}
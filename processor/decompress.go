@@ -0,0 +1,41 @@
+package processor
+
+import (
+	"compress/gzip"
+	"io"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// compressionSuffixes are the compressed inputs read, as the recorder's compression options name them
+var compressionSuffixes = []string{".bz2", ".gz", ".zst"}
+
+// trimCompression removes a compression suffix from a file name
+func trimCompression(name string) string {
+	for _, suffix := range compressionSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return strings.TrimSuffix(name, suffix)
+		}
+	}
+	return name
+}
+
+// isTarFile reports whether a file is a tar archive, plain or compressed, such as a.tar or a.tar.gz
+func isTarFile(name string) bool {
+	return strings.HasSuffix(trimCompression(name), ".tar") || strings.HasSuffix(name, ".tgz")
+}
+
+// decompress returns a reader decompressing r by the compression suffix of its name, or r itself
+// when the name has none. Concatenated gzip members and zstd frames are read as one stream.
+func (p *MarketDataProcessor) decompress(r io.Reader, name string) (io.Reader, error) {
+	switch {
+	case strings.HasSuffix(name, ".bz2"):
+		return p.bzip2Reader(r)
+	case strings.HasSuffix(name, ".gz"), strings.HasSuffix(name, ".tgz"):
+		return gzip.NewReader(r)
+	case strings.HasSuffix(name, ".zst"):
+		return zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+	}
+	return r, nil
+}
@@ -0,0 +1,132 @@
+package processor
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// Duplicate kinds
+const (
+	IssueDuplicateMarket  = "duplicate_market"  // A market's changes were also found in another source and skipped there
+	IssueRepeatedMessages = "repeated_messages" // Identical changes for the same publish time were skipped
+)
+
+// duplicateKey is a market found in a source that doesn't own it
+type duplicateKey struct {
+	marketID string
+	source   string
+}
+
+// marketClaims records which source each market's changes are taken from. A market's own file
+// (one named after it) always owns it; any other market belongs to the first source it is found
+// in. Changes for a market in other sources are skipped and reported as duplicates, so no market
+// is built from several files, and one with its own file is built from it whatever order the
+// workers reach the files in. Several files named after the same market, such as its rotated
+// segments, are stitched into one source before they are read (see stitchGroups).
+type marketClaims struct {
+	ownFiles   map[string]string // Market ID to the listed file named after it
+	owners     map[string]string // Market ID to the source its changes are taken from
+	duplicates map[duplicateKey]int
+}
+
+// noteOwnFiles records the files of a run that are named after their market
+func (p *MarketDataProcessor) noteOwnFiles(paths []string) {
+	p.claimsMu.Lock()
+	defer p.claimsMu.Unlock()
+	if p.claims.ownFiles == nil {
+		p.claims.ownFiles = make(map[string]string)
+	}
+	for _, path := range paths {
+		if marketID := p.extractMarketIDFromPath(path); marketID != "" {
+			p.claims.ownFiles[marketID] = path
+		}
+	}
+}
+
+// claimMarket reports whether a source's changes for a market are used, claiming the market for
+// the source when it is the first to find it
+func (p *MarketDataProcessor) claimMarket(marketID, source string) bool {
+	if p.parent != nil {
+		return p.parent.claimMarket(marketID, source)
+	}
+	p.claimsMu.Lock()
+	defer p.claimsMu.Unlock()
+
+	if owner, ok := p.claims.owners[marketID]; ok {
+		return owner == source
+	}
+	// Leave a market with its own file in this run to that file
+	if ownFile, ok := p.claims.ownFiles[marketID]; ok && ownFile != source {
+		return false
+	}
+	if p.claims.owners == nil {
+		p.claims.owners = make(map[string]string)
+	}
+	p.claims.owners[marketID] = source
+	return true
+}
+
+// noteDuplicates records the changes a source had for markets other sources own
+func (p *MarketDataProcessor) noteDuplicates(source string, skipped map[string]int) {
+	if len(skipped) == 0 {
+		return
+	}
+	if p.parent != nil {
+		p.parent.noteDuplicates(source, skipped)
+		return
+	}
+	p.claimsMu.Lock()
+	defer p.claimsMu.Unlock()
+	if p.claims.duplicates == nil {
+		p.claims.duplicates = make(map[duplicateKey]int)
+	}
+	for marketID, changes := range skipped {
+		p.claims.duplicates[duplicateKey{marketID, source}] += changes
+	}
+}
+
+// reportDuplicates adds the duplicates found so far to the quality issues, once each
+func (p *MarketDataProcessor) reportDuplicates() {
+	p.claimsMu.Lock()
+	keys := make([]duplicateKey, 0, len(p.claims.duplicates))
+	for key := range p.claims.duplicates {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].marketID != keys[j].marketID {
+			return keys[i].marketID < keys[j].marketID
+		}
+		return keys[i].source < keys[j].source
+	})
+	issues := make([]QualityIssue, len(keys))
+	for i, key := range keys {
+		owner := p.claims.owners[key.marketID]
+		if owner == "" {
+			owner = p.claims.ownFiles[key.marketID]
+		}
+		issues[i] = QualityIssue{Kind: IssueDuplicateMarket, MarketID: key.marketID, Source: key.source,
+			Detail: fmt.Sprintf("%d changes skipped; kept %s", p.claims.duplicates[key], owner)}
+	}
+	p.claims.duplicates = nil
+	p.claimsMu.Unlock()
+
+	if len(issues) == 0 {
+		return
+	}
+	p.logger.Warn().Int("markets", len(issues)).Msg("skipped changes for markets found in more than one source")
+	p.mu.Lock()
+	p.QualityIssues = append(p.QualityIssues, issues...)
+	p.mu.Unlock()
+}
+
+// repeatedChange reports whether a change repeats the market's last one for the same publish
+// time, as when a recorder writes a message twice, and otherwise remembers it
+func (marketState *MarketState) repeatedChange(marketChange *MarketChange, timestamp int64) bool {
+	if timestamp == marketState.lastPt && marketState.lastChange != nil && reflect.DeepEqual(marketState.lastChange, marketChange) {
+		marketState.RepeatedChanges++
+		return true
+	}
+	marketState.lastPt, marketState.lastChange = timestamp, marketChange
+	return false
+}
@@ -0,0 +1,70 @@
+package processor
+
+import (
+	"path/filepath"
+	"time"
+)
+
+// inDateRange reports whether a day falls within the configured From and To dates, inclusive
+func (p *MarketDataProcessor) inDateRange(day time.Time) bool {
+	day = day.UTC().Truncate(24 * time.Hour)
+	if !p.Config.From.IsZero() && day.Before(p.Config.From.UTC().Truncate(24*time.Hour)) {
+		return false
+	}
+	if !p.Config.To.IsZero() && day.After(p.Config.To.UTC().Truncate(24*time.Hour)) {
+		return false
+	}
+	return true
+}
+
+// sourceInDateRange reports whether a source path may hold markets in the date range. Paths
+// without a date, such as monthly tar archives, are kept and their markets filtered as they are read.
+// Path dates are UTC, so when days are bucketed in another timezone the neighbouring days are kept too.
+func (p *MarketDataProcessor) sourceInDateRange(path string) bool {
+	if p.Config.From.IsZero() && p.Config.To.IsZero() {
+		return true
+	}
+	date, err := p.ExtractDateFromPath(path)
+	if err != nil || p.inDateRange(date) {
+		return true
+	}
+	return !p.bucketsInUTC() && (p.inDateRange(date.AddDate(0, 0, -1)) || p.inDateRange(date.AddDate(0, 0, 1)))
+}
+
+// wantSource reports whether a listed source file passes the date range and path filters
+func (p *MarketDataProcessor) wantSource(path string) bool {
+	if !p.sourceInDateRange(path) {
+		return false
+	}
+	if p.Config.PathPattern != nil && !p.Config.PathPattern.MatchString(path) {
+		return false
+	}
+	if len(p.Config.Include) == 0 {
+		return true
+	}
+	for _, pattern := range p.Config.Include {
+		if matchGlob(pattern, path) || matchGlob(pattern, filepath.Base(path)) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterSources keeps the listed source files that pass the filters
+func (p *MarketDataProcessor) filterSources(paths []string) []string {
+	var kept []string
+	for _, path := range paths {
+		if p.wantSource(path) {
+			kept = append(kept, path)
+		}
+	}
+	if skipped := len(paths) - len(kept); skipped > 0 {
+		p.logger.Info().Int("skipped", skipped).Int("files", len(paths)).Msg("filtered out files")
+	}
+	return kept
+}
+
+func matchGlob(pattern, name string) bool {
+	matched, err := filepath.Match(pattern, name)
+	return err == nil && matched
+}
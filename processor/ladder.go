@@ -0,0 +1,103 @@
+package processor
+
+import (
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const (
+	defaultLadderDepth      = 3
+	defaultSnapshotInterval = time.Second
+	defaultSnapshotWindow   = 10 * time.Minute
+)
+
+// LadderSnapshot is a runner's best-N ladder at a point before the off
+type LadderSnapshot struct {
+	MarketID     string    `parquet:"market_id"`
+	SelectionID  int64     `parquet:"selection_id"`
+	SnapshotTime time.Time `parquet:"snapshot_time,timestamp(millisecond)"`
+	SecondsToOff float64   `parquet:"seconds_to_off"`
+	LTP          float64   `parquet:"ltp,optional"`
+	TradedVolume float64   `parquet:"traded_volume"`
+	BackPrices   []float64 `parquet:"back_prices,list"` // Best first
+	BackSizes    []float64 `parquet:"back_sizes,list"`
+	LayPrices    []float64 `parquet:"lay_prices,list"` // Best first
+	LaySizes     []float64 `parquet:"lay_sizes,list"`
+}
+
+// snapshotLadders records every runner's ladder at each snapshot time before timestamp (epoch
+// millis), so a snapshot holds the book after every update published at or before it. Snapshots
+// are taken every SnapshotInterval in the SnapshotWindow before the scheduled off, stopping early
+// if the market turns in-play.
+func (p *MarketDataProcessor) snapshotLadders(marketID string, marketState *MarketState, timestamp int64) {
+	if p.Config.Mode != OutputModeLadder || marketState.MarketTime.IsZero() {
+		return
+	}
+
+	now := time.UnixMilli(timestamp)
+	end := marketState.MarketTime
+	if !marketState.InPlayTime.IsZero() && !marketState.InPlayTime.After(end) {
+		end = marketState.InPlayTime.Add(-time.Millisecond)
+	}
+
+	var selectionIDs []int64
+	for marketState.nextSnapshot.Before(now) && !marketState.nextSnapshot.After(end) {
+		at := marketState.nextSnapshot
+		marketState.nextSnapshot = at.Add(p.Config.SnapshotInterval)
+
+		if selectionIDs == nil {
+			for selectionID := range marketState.Runners {
+				selectionIDs = append(selectionIDs, selectionID)
+			}
+			sort.Slice(selectionIDs, func(i, j int) bool { return selectionIDs[i] < selectionIDs[j] })
+		}
+		for _, selectionID := range selectionIDs {
+			runner := marketState.Runners[selectionID]
+			snapshot := LadderSnapshot{
+				MarketID:     marketID,
+				SelectionID:  selectionID,
+				SnapshotTime: at.UTC(),
+				SecondsToOff: marketState.MarketTime.Sub(at).Seconds(),
+				LTP:          runner.LatestLTP,
+				TradedVolume: runner.book.tradedVolume(),
+			}
+			for _, level := range runner.book.backLevels(p.Config.LadderDepth) {
+				snapshot.BackPrices = append(snapshot.BackPrices, level[0])
+				snapshot.BackSizes = append(snapshot.BackSizes, level[1])
+			}
+			for _, level := range runner.book.layLevels(p.Config.LadderDepth) {
+				snapshot.LayPrices = append(snapshot.LayPrices, level[0])
+				snapshot.LaySizes = append(snapshot.LaySizes, level[1])
+			}
+			p.LadderData = append(p.LadderData, snapshot)
+		}
+	}
+}
+
+// firstSnapshotTime is when a market first seen at seen is first snapshotted: the start of the
+// window, or the next whole interval for recordings that start inside it
+func (p *MarketDataProcessor) firstSnapshotTime(marketTime, seen time.Time) time.Time {
+	start := marketTime.Add(-p.Config.SnapshotWindow)
+	if first := seen.Truncate(p.Config.SnapshotInterval); first.After(start) {
+		start = first
+		if start.Before(seen) {
+			start = start.Add(p.Config.SnapshotInterval)
+		}
+	}
+	return start
+}
+
+// saveLadders writes ladder snapshots to the output file, or to <prefix>_ladders.parquet in the
+// output directory
+func (p *MarketDataProcessor) saveLadders(data []LadderSnapshot) error {
+	outputPath := p.OutputFile
+	if outputPath == "" {
+		outputPath = filepath.Join(p.OutputDir, p.Profile.FilePrefix+"_ladders.parquet")
+	}
+	if err := writeParquetRows(p, outputPath, data); err != nil {
+		return err
+	}
+	p.logger.Info().Str("path", outputPath).Int("snapshots", len(data)).Msg("created ladder output")
+	return nil
+}
@@ -0,0 +1,75 @@
+package processor
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+)
+
+const defaultMaxLineBytes = 16 << 20
+
+// lineMarketID finds the first market ID in a message, which is all a truncated line may have
+var lineMarketID = regexp.MustCompile(`"id"\s*:\s*"([^"]+)"`)
+
+// lineReader reads the newline-delimited messages of a market file. Unlike bufio.Scanner it
+// allows lines up to a configurable size, and reports a longer line rather than stopping at it.
+type lineReader struct {
+	reader   *bufio.Reader
+	maxBytes int
+	line     []byte
+	tooLong  bool
+	err      error
+}
+
+func newLineReader(r io.Reader, maxBytes int) *lineReader {
+	if maxBytes <= 0 {
+		maxBytes = defaultMaxLineBytes
+	}
+	return &lineReader{reader: bufio.NewReaderSize(r, 64<<10), maxBytes: maxBytes}
+}
+
+// next reads the next line, without its line ending, returning false at the end of the input
+// or on an error. A line over maxBytes is cut short and flagged by truncated.
+func (l *lineReader) next() bool {
+	l.line, l.tooLong = l.line[:0], false
+	for {
+		fragment, err := l.reader.ReadSlice('\n')
+		if room := l.maxBytes - len(l.line); len(fragment) > room {
+			l.line = append(l.line, fragment[:max(room, 0)]...)
+			l.tooLong = true
+		} else if !l.tooLong {
+			l.line = append(l.line, fragment...)
+		}
+
+		switch err {
+		case bufio.ErrBufferFull:
+			continue
+		case nil:
+			l.line = bytes.TrimRight(l.line, "\r\n")
+			return true
+		case io.EOF:
+			return len(l.line) > 0 || l.tooLong
+		default:
+			l.err = err
+			return false
+		}
+	}
+}
+
+func (l *lineReader) bytes() []byte {
+	return l.line
+}
+
+// truncated returns an error naming the market of the line just read when it was over maxBytes
+func (l *lineReader) truncated(lineNumber int) error {
+	if !l.tooLong {
+		return nil
+	}
+	marketID := "unknown"
+	if match := lineMarketID.FindSubmatch(l.line); match != nil {
+		marketID = string(match[1])
+	}
+	return fmt.Errorf("line %d for market %s is longer than the %d byte line limit (MaxLineBytes)", lineNumber, marketID, l.maxBytes)
+}
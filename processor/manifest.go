@@ -0,0 +1,122 @@
+package processor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Manifest records the source files already processed so a re-run over a growing input, such as
+// a nightly job over an S3 prefix, only processes new or changed files
+type Manifest struct {
+	Files map[string]ManifestEntry `json:"files"`
+}
+
+// ManifestEntry is a processed source file
+type ManifestEntry struct {
+	Version     string    `json:"version"` // MD5 ETag for object storage, modification time for local files
+	Size        int64     `json:"size"`
+	Rows        int       `json:"rows"` // Summary rows finalized while the file was read
+	ProcessedAt time.Time `json:"processedAt"`
+}
+
+// sourceVersion identifies the content of a source file when it was listed
+type sourceVersion struct {
+	version string
+	etag    string // Remote objects only; keys the input cache
+	size    int64
+}
+
+// LoadManifest reads a manifest file, returning an empty manifest when none exists yet
+func LoadManifest(path string) (*Manifest, error) {
+	manifest := &Manifest{Files: make(map[string]ManifestEntry)}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return manifest, nil
+		}
+		return nil, fmt.Errorf("read manifest: %w", err)
+	}
+
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("decode manifest: %w", err)
+	}
+	if manifest.Files == nil {
+		manifest.Files = make(map[string]ManifestEntry)
+	}
+	return manifest, nil
+}
+
+// SaveManifest writes the manifest to a temporary file and renames it into place so a crash
+// mid-write never leaves a truncated manifest behind
+func SaveManifest(path string, manifest *Manifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode manifest: %w", err)
+	}
+
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("create manifest directory: %w", err)
+		}
+	}
+
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("write manifest: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("replace manifest: %w", err)
+	}
+	return nil
+}
+
+// noteSource records the version and size of a source file as it is listed, for the manifest
+// and the prefetcher
+func (p *MarketDataProcessor) noteSource(path string, source sourceVersion) {
+	if p.sources == nil {
+		p.sources = make(map[string]sourceVersion)
+	}
+	p.sources[path] = source
+}
+
+// unprocessed filters out the files the manifest shows were already processed at their listed version
+func (p *MarketDataProcessor) unprocessed(paths []string) []string {
+	if p.manifest == nil {
+		return paths
+	}
+
+	var pending []string
+	for _, path := range paths {
+		entry, ok := p.manifest.Files[path]
+		source := p.sources[path]
+		if ok && entry.Version == source.version && entry.Size == source.size {
+			continue
+		}
+		pending = append(pending, path)
+	}
+	if skipped := len(paths) - len(pending); skipped > 0 {
+		p.logger.Info().Int("skipped", skipped).Msg("skipping files already in the manifest")
+	}
+	return pending
+}
+
+// recordSource adds a processed file to the manifest
+func (p *MarketDataProcessor) recordSource(path string, rows int) {
+	if p.manifest == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	source := p.sources[path]
+	p.manifest.Files[path] = ManifestEntry{
+		Version:     source.version,
+		Size:        source.size,
+		Rows:        rows,
+		ProcessedAt: time.Now().UTC(),
+	}
+}
@@ -2,14 +2,11 @@ package processor
 
 import (
 	"archive/tar"
-	"bufio"
-	"compress/bzip2"
 	"context"
 	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -18,47 +15,19 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
-	awsconfig "github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	betfair "github.com/felixmccuaig/betfair-go"
 	"github.com/parquet-go/parquet-go"
+	"github.com/rs/zerolog"
 )
 
-type MCMMessage struct {
-	Op string `json:"op"`
-	Pt int64  `json:"pt"`
-	Mc []struct {
-		ID               string `json:"id"`
-		MarketDefinition *struct {
-			EventTypeID  string    `json:"eventTypeId"`
-			MarketType   string    `json:"marketType"`
-			BettingType  string    `json:"bettingType"`
-			EventName    string    `json:"eventName"`
-			MarketTime   time.Time `json:"marketTime"`
-			Runners      []struct {
-				ID   int64   `json:"id"`
-				Name string  `json:"name"`
-				BSP  float64 `json:"bsp"`
-			} `json:"runners"`
-		} `json:"marketDefinition"`
-		RC []struct {
-			ID   int64                  `json:"id"`
-			LTP  float64                `json:"ltp"`
-			TV   float64                `json:"tv"`
-			BATB [][]float64            `json:"batb"`
-			ATB  [][]float64            `json:"atb"`
-			SPB  [][]float64            `json:"spb"`
-			TRD  [][]float64            `json:"trd"`
-			Raw  map[string]interface{} `json:"-"`
-		} `json:"rc"`
-	} `json:"mc"`
-}
-
 type RunnerState struct {
 	Name              string
+	Box               int // Trap or box number from the runner name; 0 when the name has none
 	BSP               float64
-	Updates           []RunnerUpdate
 	MaxTV             float64
 	LatestLTP         float64
 	MaxTradedPrice    float64
@@ -66,6 +35,17 @@ type RunnerState struct {
 	HasMaxTraded      bool
 	HasMinTraded      bool
 	Status            string
+	SPNear            float64 // Last projected near SP before BSP reconciliation
+	SPFar             float64 // Last projected far SP before BSP reconciliation
+
+	RemovalDate      time.Time // When a REMOVED runner was taken out of the market
+	AdjustmentFactor float64   // Reduction factor, in percent, from the market definition
+	HasAdjustment    bool
+
+	book     runnerBook   // Price ladder as of the latest update
+	price30s priceTracker // Price nearest 30s before the scheduled start
+	volume   volumeTracker
+	inPlay   InPlayStats
 }
 
 type RunnerUpdate struct {
@@ -74,6 +54,8 @@ type RunnerUpdate struct {
 	TV        float64
 	BATB      [][]float64
 	ATB       [][]float64
+	BATL      [][]float64
+	ATL       [][]float64
 	SPB       [][]float64
 	TRD       [][]float64
 	HasLTP    bool
@@ -84,8 +66,25 @@ type MarketState struct {
 	Venue       string
 	EventID     string
 	EventName   string
-	MarketDef   interface{}
+	EventTypeID string
+	MarketType  string
+	Timezone    string    // The market definition's timezone, such as Australia/Sydney
+	Winners     int       // numberOfWinners; 1 for WIN markets, the places paid for PLACE markets
+	InPlayTime  time.Time // When the market first turned in-play; zero if it never did
+	InPlayEnd   time.Time // When the market was first suspended or closed after turning in-play
+	Closed      bool      // The market definition reported CLOSED, so no more changes will follow
+	Reconciled  time.Time // When bspReconciled was first seen; zero if BSP never reconciled
+	MarketDef   *MarketDefinition
 	Runners     map[int64]*RunnerState
+
+	RepeatedChanges int // Changes skipped as repeats of the previous one
+
+	nextSnapshot time.Time     // Time of the next ladder snapshot
+	lastPt       int64         // Publish time of the last change applied
+	lastChange   *MarketChange // Last change applied, to skip repeats of it
+
+	overrounds      [len(overroundOffsets)]float64 // Overround at each of overroundOffsets before the off
+	overroundsTaken int                            // Offsets passed so far
 }
 
 type SummaryRow struct {
@@ -106,26 +105,108 @@ type SummaryRow struct {
 	Month                 int       `parquet:"month"`
 	Day                   int       `parquet:"day"`
 	Win                   bool      `parquet:"win"`
+	EventTypeID           string    `parquet:"event_type_id"`
+	MarketType            string    `parquet:"market_type"`
+	NumberOfWinners       int       `parquet:"number_of_winners"`
+	Placed                bool      `parquet:"placed"`
+	PlaceMarketID         string    `parquet:"place_market_id,optional"` // Set on WIN rows joined with their PLACE market
+	PlaceBSP              float64   `parquet:"place_bsp,optional"`
+	PlaceLTP              float64   `parquet:"place_ltp,optional"`
+	NumberOfPlaces        int       `parquet:"number_of_places,optional"`
+	VWAP                  float64   `parquet:"vwap,optional"`
+	VolumeLast60s         float64   `parquet:"volume_last_60s,optional"`
+	VolumeLast5m          float64   `parquet:"volume_last_5m,optional"`
+	InPlayVolumePct       float64   `parquet:"in_play_volume_pct,optional"`
+	SPNear                float64   `parquet:"sp_near,optional"` // Projected BSP when the market reconciled
+	SPFar                 float64   `parquet:"sp_far,optional"`
+	BSPReconciled         bool      `parquet:"bsp_reconciled"`
+	BSPReconciledTime     time.Time `parquet:"bsp_reconciled_time,optional,timestamp(millisecond)"`
+	InPlayHigh            float64   `parquet:"in_play_high,optional"`
+	InPlayLow             float64   `parquet:"in_play_low,optional"`
+	InPlayVolume          float64   `parquet:"in_play_volume,optional"`
+	SecondsInPlay         float64   `parquet:"seconds_in_play,optional"` // From the in-play turn to the first suspension or close
+	Scratched             bool      `parquet:"scratched"` // The runner was REMOVED
+	RemovalDate           time.Time `parquet:"removal_date,optional,timestamp(millisecond)"`
+	AdjustmentFactor      float64   `parquet:"adjustment_factor,optional"` // Reduction factor, in percent, applied to the other runners' prices if this one is removed
+	Box                   int       `parquet:"box,optional"` // Trap or box number, such as the 1 of "1. Fast Dog"; null when the name has none
 	HasBSP                bool      `parquet:"-"` // Don't include in parquet
 	HasLTP                bool      `parquet:"-"` // Don't include in parquet
 	HasPrice30sBefore     bool      `parquet:"-"` // Don't include in parquet
 	HasMaxTradedPrice     bool      `parquet:"-"` // Don't include in parquet
 	HasMinTradedPrice     bool      `parquet:"-"` // Don't include in parquet
+	HasPlaceBSP           bool      `parquet:"-"` // Don't include in parquet
+	HasPlaceLTP           bool      `parquet:"-"` // Don't include in parquet
+	HasVolumeProfile      bool      `parquet:"-"` // Don't include in parquet
+	HasInPlayTrades       bool      `parquet:"-"` // Don't include in parquet
+	HasSecondsInPlay      bool      `parquet:"-"` // Don't include in parquet
+	HasAdjustmentFactor   bool      `parquet:"-"` // Don't include in parquet
 }
 
 type OutputFormat string
 
 const (
-	OutputFormatCSV     OutputFormat = "csv"
-	OutputFormatParquet OutputFormat = "parquet"
+	OutputFormatCSV      OutputFormat = "csv"
+	OutputFormatParquet  OutputFormat = "parquet"
+	OutputFormatDuckDB   OutputFormat = "duckdb"   // Tables in a DuckDB file; needs a DuckDB database/sql driver
+	OutputFormatDatabase OutputFormat = "database" // Upserts into the DatabaseDSN database, such as PostgreSQL or TimescaleDB
 )
 
 type ProcessorConfig struct {
-	OutputPath   string       // Base output path (can be S3 or local)
-	OutputFormat OutputFormat // csv or parquet
+	OutputPath   string       // Base output path (can be S3, GCS, Azure or local)
+	OutputFormat OutputFormat // csv, parquet, duckdb or database
 	FileLimit    int          // Maximum files to process
 	Workers      int          // Number of parallel workers
 	DateFormat   string       // Date format for filename (e.g., "2006-01-02", "02-01-2006")
+	Profile      string       // Sport profile: greyhounds (default), horse-racing or generic
+	EventTypeIDs []string     // Overrides the profile's event types; empty keeps the profile's
+	MarketTypes  []string     // Overrides the profile's market types; empty keeps the profile's
+	JoinWinPlace bool         // Also process PLACE markets and join them onto WIN rows per selection
+	Mode         OutputMode   // summary (default), ticks or ladder
+	ManifestPath string       // Local manifest of processed files; files in it are skipped and only new rows are written
+	TraceMarkets []string     // Market IDs whose messages are logged in detail
+	NameMapPath  string       // JSON or CSV file of venue and runner name aliases and runner cleanup rules
+
+	BucketTimezone string // IANA timezone Year, Month and Day are taken in, or "market" for each market's own; UTC by default
+
+	ErrorPolicy   ErrorPolicy // continue (default) records failed files in FileErrors; fail-fast stops at the first
+	QuarantineDir string      // Failed files are copied here, with failures.json saying why
+
+	Logger   *zerolog.Logger // Where the processor logs; nil logs JSON to stderr at info level
+	Progress bool            // Report files done, rows, rates and ETA while processing; a bar when stderr is a terminal
+
+	DownloadConcurrency int    // Remote objects downloaded at once, ahead of the parse workers (default 4)
+	PrefetchBytes       int64  // Memory downloaded objects may hold until parsed (default 256 MiB); larger objects are streamed
+	CacheDir            string // Local directory remote inputs are kept in by ETag, so re-processing them doesn't download them again
+
+	Bzip2Decoder Bzip2Decoder // stdlib (default), dsnet or parallel
+	MaxLineBytes int          // Longest message line read (default 16 MiB); a file with a longer one fails
+
+	From        time.Time      // Skip files and markets dated before this day; zero for no limit
+	To          time.Time      // Skip files and markets dated after this day; zero for no limit
+	Include     []string       // Glob patterns a listed file's path or name must match; empty keeps all
+	PathPattern *regexp.Regexp // Regular expression a listed file's path must match; nil keeps all
+
+	Partitioned      bool // Write summaries as Parquet in year=/month=/day= directories under the output directory
+	PartitionByVenue bool // Also partition by venue=, below day=
+	PerMarket        bool // Write each market to files of its own, in the folders of its source under the output directory
+
+	Streaming bool // Write summary rows to one file (OutputFile or <prefix>.csv/.parquet) as markets finalize rather than holding them all in memory
+
+	ReportPath  string // Where the run's data-quality report is written: CSV for a .csv path, JSON otherwise
+	MarketTable bool   // Also write a market-level table of runner counts, overrounds and favourite and winner BSPs with summaries
+
+	ParquetCompression  ParquetCompression // snappy (default), zstd, gzip or none
+	ParquetRowGroupSize int64              // Maximum rows per Parquet row group; 0 keeps the writer's default
+	ParquetDictionary   bool               // Dictionary-encode string columns
+
+	DatabaseDSN       string // Connection string of the database summaries are upserted into with OutputFormatDatabase
+	DatabaseDriver    string // database/sql driver for DatabaseDSN, which the binary must import (default pgx)
+	DatabaseTable     string // Table summaries are upserted into (default summary)
+	DatabaseBatchSize int    // Rows per INSERT statement for database and DuckDB output (default 500)
+
+	LadderDepth      int           // Price levels per side in ladder snapshots (default 3)
+	SnapshotInterval time.Duration // Time between ladder snapshots (default 1s)
+	SnapshotWindow   time.Duration // How long before the scheduled off ladder snapshots start (default 10m)
 }
 
 type MarketDataProcessor struct {
@@ -136,12 +217,41 @@ type MarketDataProcessor struct {
 	FilesProcessed  int
 	MarketStates    map[string]*MarketState
 	ProcessedData   []SummaryRow
+	QualityIssues   []QualityIssue   // Data-quality problems found so far, for the report
+	FileErrors      []FileError      // Files that failed to process
+	TickData        []TickRow        // Runner updates collected in ticks mode
+	LadderData      []LadderSnapshot // Snapshots collected in ladder mode
+	MarketData      []MarketRow      // Market-level rows collected with Config.MarketTable
 	VenueRegex      *regexp.Regexp
 	GreyhoundRegex  *regexp.Regexp
+	Profile         SportProfile // Markets to summarise and how their names are parsed
 	Workers         int
-	S3Client        *s3.Client
+	S3Client        *s3.Client // Optional client for s3:// paths; by default one is built from the environment
 	CurrentSource   string // Track current source file being processed
 	mu              sync.RWMutex
+	logger          zerolog.Logger
+
+	parent    *MarketDataProcessor // Set on workers; the processor whose file count and storage clients they share
+	prefetch  *prefetcher              // Set on workers while remote files are downloaded ahead of them
+	manifest  *Manifest                // Loaded from Config.ManifestPath
+	names     *NameMap                 // Loaded from Config.NameMapPath
+	sources   map[string]sourceVersion // Versions of the listed source files
+	storageMu sync.Mutex
+	storages  map[string]betfair.ObjectStorage // Object storage clients by bucket URL
+	claimsMu  sync.Mutex
+	claims    marketClaims // Which source each market is built from
+
+	quarantineMu sync.Mutex // Held while a failed file's quarantine name is picked
+
+	inputRoots []string       // Paths given to ProcessPath, whose layout per-market output mirrors
+	bucketZone *time.Location // Config.BucketTimezone; nil buckets each market in its own timezone
+
+	streamMu     sync.Mutex
+	stream       *rowStream // Summary output being written with Config.Streaming
+	rowsStreamed int        // Summary rows this processor has written to the stream
+
+	sink    chan<- SummaryRow // Set while Process runs; finalized rows are sent to it
+	sinkCtx context.Context   // Process's context, which stops processing when done
 }
 
 func NewMarketDataProcessor(outputPath string, fileLimit int, workers int) *MarketDataProcessor {
@@ -156,6 +266,11 @@ func NewMarketDataProcessor(outputPath string, fileLimit int, workers int) *Mark
 }
 
 func NewMarketDataProcessorWithConfig(config ProcessorConfig) *MarketDataProcessor {
+	logger := zerolog.New(os.Stderr).Level(zerolog.InfoLevel).With().Timestamp().Logger()
+	if config.Logger != nil {
+		logger = *config.Logger
+	}
+
 	if config.Workers <= 0 {
 		config.Workers = runtime.NumCPU()
 	}
@@ -164,17 +279,79 @@ func NewMarketDataProcessorWithConfig(config ProcessorConfig) *MarketDataProcess
 		config.DateFormat = "2006-01-02" // Default: YYYY-MM-DD
 	}
 
+	if config.Mode == "" {
+		config.Mode = OutputModeSummary
+	}
+	if config.LadderDepth <= 0 {
+		config.LadderDepth = defaultLadderDepth
+	}
+	if config.SnapshotInterval <= 0 {
+		config.SnapshotInterval = defaultSnapshotInterval
+	}
+	if config.SnapshotWindow <= 0 {
+		config.SnapshotWindow = defaultSnapshotWindow
+	}
+	if config.DatabaseDriver == "" {
+		config.DatabaseDriver = defaultDatabaseDriver
+	}
+	if config.DatabaseTable == "" {
+		config.DatabaseTable = defaultDatabaseTable
+	}
+	if config.DatabaseBatchSize <= 0 {
+		config.DatabaseBatchSize = defaultDatabaseBatchSize
+	}
+	if config.MaxLineBytes <= 0 {
+		config.MaxLineBytes = defaultMaxLineBytes
+	}
+	if config.DownloadConcurrency <= 0 {
+		config.DownloadConcurrency = defaultDownloadConcurrency
+	}
+	if config.PrefetchBytes <= 0 {
+		config.PrefetchBytes = defaultPrefetchBytes
+	}
+	switch config.ErrorPolicy {
+	case "":
+		config.ErrorPolicy = ErrorPolicyContinue
+	case ErrorPolicyContinue, ErrorPolicyFailFast:
+	default:
+		logger.Warn().Str("policy", string(config.ErrorPolicy)).Msg("unknown error policy, using continue")
+		config.ErrorPolicy = ErrorPolicyContinue
+	}
+	switch config.Bzip2Decoder {
+	case "":
+		config.Bzip2Decoder = Bzip2DecoderStdlib
+	case Bzip2DecoderStdlib, Bzip2DecoderDsnet, Bzip2DecoderParallel:
+	default:
+		logger.Warn().Str("decoder", string(config.Bzip2Decoder)).Msg("unknown bzip2 decoder, using stdlib")
+		config.Bzip2Decoder = Bzip2DecoderStdlib
+	}
+	if config.PerMarket && (config.OutputFormat == OutputFormatDuckDB || config.OutputFormat == OutputFormatDatabase) {
+		logger.Warn().Str("format", string(config.OutputFormat)).Msg("per-market output is written as CSV or Parquet files; ignoring PerMarket")
+		config.PerMarket = false
+	}
+	if config.Streaming && !config.canStream() {
+		logger.Warn().Msg("streaming output needs CSV or Parquet summaries without joins, partitions or per-market files; buffering rows")
+		config.Streaming = false
+	}
+	if config.ParquetCompression == "" {
+		config.ParquetCompression = ParquetCompressionSnappy
+	}
+	if _, err := config.ParquetCompression.codec(); err != nil {
+		logger.Warn().Err(err).Msg("using snappy")
+		config.ParquetCompression = ParquetCompressionSnappy
+	}
+
 	// Determine if outputPath is a file or directory
 	var outputDir, outputFile string
 	if config.OutputPath != "" {
 		ext := strings.ToLower(filepath.Ext(config.OutputPath))
-		if ext == ".csv" || ext == ".parquet" {
+		if ext == ".csv" || ext == ".parquet" || ext == ".duckdb" {
 			outputFile = config.OutputPath
 			outputDir = filepath.Dir(config.OutputPath)
 		} else {
 			outputDir = config.OutputPath
 		}
-		if !strings.HasPrefix(config.OutputPath, "s3://") {
+		if !isRemotePath(config.OutputPath) {
 			os.MkdirAll(outputDir, 0755)
 		}
 	} else {
@@ -182,17 +359,43 @@ func NewMarketDataProcessorWithConfig(config ProcessorConfig) *MarketDataProcess
 		os.MkdirAll(outputDir, 0755)
 	}
 
-	// Initialize S3 client
-	awsCfg, err := awsconfig.LoadDefaultConfig(context.Background())
-	var s3Client *s3.Client
-	if err == nil {
-		s3Client = s3.NewFromConfig(awsCfg)
-	} else {
-		log.Printf("Warning: failed to load AWS config: %v", err)
+	profile, err := ProfileByName(config.Profile)
+	if err != nil {
+		logger.Warn().Err(err).Msg("using greyhounds")
+		profile = GreyhoundProfile
+	}
+	if len(config.EventTypeIDs) > 0 {
+		profile.EventTypeIDs = config.EventTypeIDs
+	}
+	if len(config.MarketTypes) > 0 {
+		profile.MarketTypes = config.MarketTypes
+	}
+	if config.JoinWinPlace && len(profile.MarketTypes) > 0 && !matchesAny(profile.MarketTypes, "PLACE") {
+		profile.MarketTypes = append(append([]string(nil), profile.MarketTypes...), "PLACE")
+	}
+
+	var manifest *Manifest
+	if config.ManifestPath != "" {
+		if manifest, err = LoadManifest(config.ManifestPath); err != nil {
+			logger.Warn().Err(err).Msg("starting a new manifest")
+			manifest = &Manifest{Files: make(map[string]ManifestEntry)}
+		}
 	}
 
-	venueRegex := regexp.MustCompile(`\s*\([A-Z]{2,3}\)\s*\d+\w*\s*\w+`)
-	greyhoundRegex := regexp.MustCompile(`^\d+\.\s*`)
+	bucketZone, err := bucketLocation(config.BucketTimezone)
+	if err != nil {
+		logger.Warn().Err(err).Msg("bucketing days in UTC")
+		config.BucketTimezone = ""
+		bucketZone = time.UTC
+	}
+
+	var names *NameMap
+	if config.NameMapPath != "" {
+		if names, err = LoadNameMap(config.NameMapPath); err != nil {
+			logger.Warn().Err(err).Msg("leaving names as they are")
+			names = nil
+		}
+	}
 
 	return &MarketDataProcessor{
 		Config:         config,
@@ -201,20 +404,37 @@ func NewMarketDataProcessorWithConfig(config ProcessorConfig) *MarketDataProcess
 		FileLimit:      config.FileLimit,
 		Workers:        config.Workers,
 		MarketStates:   make(map[string]*MarketState),
-		VenueRegex:     venueRegex,
-		GreyhoundRegex: greyhoundRegex,
-		S3Client:       s3Client,
+		VenueRegex:     profile.VenueRegex,
+		GreyhoundRegex: profile.RunnerRegex,
+		Profile:        profile,
+		manifest:       manifest,
+		names:          names,
+		bucketZone:     bucketZone,
+		logger:         logger,
 	}
 }
 
-// ExtractDateFromPath attempts to extract a date from an S3 or file path
+// ExtractDateFromPath attempts to extract a date from an object storage or file path
 // Examples:
 //   - s3://bucket/PRO/2025/Sep/30/ -> 2025-09-30
+//   - s3://bucket/v2/year=2025/month=09/day=30/ -> 2025-09-30
 //   - s3://bucket/2025/09/30/ -> 2025-09-30
 //   - /path/2025/09/30 -> 2025-09-30
 func (p *MarketDataProcessor) ExtractDateFromPath(path string) (time.Time, error) {
-	// Remove s3:// prefix if present
-	path = strings.TrimPrefix(path, "s3://")
+	// Remove s3://, gs:// or az:// prefix if present
+	for _, prefix := range []string{"s3://", "gs://", "az://"} {
+		path = strings.TrimPrefix(path, prefix)
+	}
+
+	// Try to find the recorder's version 2 partitions (e.g., year=2025/month=09/day=30)
+	partitionPattern := regexp.MustCompile(`year=(\d{4})/month=(\d{1,2})/day=(\d{1,2})`)
+	if matches := partitionPattern.FindStringSubmatch(path); len(matches) == 4 {
+		dateStr := fmt.Sprintf("%s-%s-%s", matches[1], matches[2], matches[3])
+		t, err := time.Parse("2006-1-2", dateStr)
+		if err == nil {
+			return t, nil
+		}
+	}
 
 	// Try to find YYYY/MMM/DD pattern (e.g., 2025/Sep/30)
 	monthNamePattern := regexp.MustCompile(`(\d{4})/(Jan|Feb|Mar|Apr|May|Jun|Jul|Aug|Sep|Oct|Nov|Dec)/(\d{1,2})`)
@@ -285,158 +505,148 @@ func (p *MarketDataProcessor) extractVenueFromEventName(eventName string) string
 	return clean
 }
 
-func (p *MarketDataProcessor) extractGreyhoundName(runnerName string) string {
-	name := p.GreyhoundRegex.ReplaceAllString(runnerName, "")
-	return strings.TrimSpace(name)
+// runnerNumber matches the trap, box or saddle cloth number Betfair starts runner names with, such
+// as the 1 of "1. Fast Dog"
+var runnerNumber = regexp.MustCompile(`^\s*(\d+)\.\s`)
+
+// runnerBox returns the number a runner name starts with, or 0 when it has none
+func runnerBox(runnerName string) int {
+	match := runnerNumber.FindStringSubmatch(runnerName)
+	if match == nil {
+		return 0
+	}
+	box, _ := strconv.Atoi(match[1])
+	return box
 }
 
-func (p *MarketDataProcessor) isGreyhoundWinMarket(marketDef map[string]interface{}) bool {
-	eventTypeID, ok := marketDef["eventTypeId"].(string)
-	if !ok || eventTypeID != "4339" {
-		return false
+func (p *MarketDataProcessor) extractGreyhoundName(runnerName string) string {
+	name := runnerName
+	if p.GreyhoundRegex != nil {
+		name = strings.TrimSpace(p.GreyhoundRegex.ReplaceAllString(runnerName, ""))
 	}
+	return p.names.runner(name)
+}
 
-	marketType, ok := marketDef["marketType"].(string)
-	if !ok || marketType != "WIN" {
+// isTargetMarket reports whether a market definition passes the profile's filter
+func (p *MarketDataProcessor) isTargetMarket(marketDef *MarketDefinition) bool {
+	if !p.Profile.Matches(marketDef) {
 		return false
 	}
+	if p.Config.From.IsZero() && p.Config.To.IsZero() {
+		return true
+	}
+	marketTime, err := time.Parse(time.RFC3339, marketDef.MarketTime)
+	return err != nil || p.inDateRange(p.raceDay(marketTime, marketDef.Timezone))
+}
 
-	bettingType, ok := marketDef["bettingType"].(string)
-	if !ok || bettingType != "ODDS" {
-		return false
+// updatePrice is the price an update shows: its LTP, else the first back, SP or traded price
+func updatePrice(update RunnerUpdate) (float64, bool) {
+	switch {
+	case update.HasLTP:
+		return update.LTP, true
+	case len(update.BATB) > 0 && len(update.BATB[0]) > 0:
+		return update.BATB[0][0], true
+	case len(update.ATB) > 0 && len(update.ATB[0]) > 0:
+		return update.ATB[0][0], true
+	case len(update.SPB) > 0 && len(update.SPB[0]) > 0:
+		return update.SPB[0][0], true
+	case len(update.TRD) > 0 && len(update.TRD[len(update.TRD)-1]) > 0:
+		return update.TRD[len(update.TRD)-1][0], true
 	}
+	return 0, false
+}
 
-	return true
+// trackedPrice is a price and how far its update was from the target time
+type trackedPrice struct {
+	price    float64
+	timeDiff int64
+	hasPrice bool
 }
 
-func (p *MarketDataProcessor) getPrice30sBeforeStart(updates []RunnerUpdate, marketTime time.Time) (float64, bool) {
-	targetTimestamp := marketTime.Add(-30 * time.Second).UnixMilli()
-
-	var bestBefore struct {
-		price    float64
-		timeDiff int64
-		hasPrice bool
-	}
-	bestBefore.timeDiff = int64(^uint64(0) >> 1) // max int64
-
-	var bestAfter struct {
-		price    float64
-		timeDiff int64
-		hasPrice bool
-	}
-	bestAfter.timeDiff = int64(^uint64(0) >> 1) // max int64
-
-	for _, update := range updates {
-		var price float64
-		var hasPrice bool
-
-		if update.HasLTP {
-			price = update.LTP
-			hasPrice = true
-		} else if len(update.BATB) > 0 && len(update.BATB[0]) > 0 {
-			price = update.BATB[0][0]
-			hasPrice = true
-		} else if len(update.ATB) > 0 && len(update.ATB[0]) > 0 {
-			price = update.ATB[0][0]
-			hasPrice = true
-		} else if len(update.SPB) > 0 && len(update.SPB[0]) > 0 {
-			price = update.SPB[0][0]
-			hasPrice = true
-		} else if len(update.TRD) > 0 && len(update.TRD[len(update.TRD)-1]) > 0 {
-			price = update.TRD[len(update.TRD)-1][0]
-			hasPrice = true
-		}
+// priceTracker keeps the price nearest a target time as updates arrive: the last one at or before
+// it, or failing that the first one after it
+type priceTracker struct {
+	target     int64
+	bestBefore trackedPrice
+	bestAfter  trackedPrice
+}
 
-		if !hasPrice {
-			continue
-		}
+// observe considers a price published at timestamp; a new target, such as after the start time
+// moves, discards what was seen for the old one
+func (t *priceTracker) observe(timestamp int64, price float64, target int64) {
+	if target != t.target {
+		*t = priceTracker{target: target}
+	}
 
-		diff := targetTimestamp - update.Timestamp
-		if diff >= 0 {
-			if diff < bestBefore.timeDiff {
-				bestBefore.price = price
-				bestBefore.timeDiff = diff
-				bestBefore.hasPrice = true
-			}
-		} else {
-			absDiff := -diff
-			if absDiff < bestAfter.timeDiff {
-				bestAfter.price = price
-				bestAfter.timeDiff = absDiff
-				bestAfter.hasPrice = true
-			}
+	diff := target - timestamp
+	if diff >= 0 {
+		if !t.bestBefore.hasPrice || diff < t.bestBefore.timeDiff {
+			t.bestBefore = trackedPrice{price: price, timeDiff: diff, hasPrice: true}
 		}
+	} else if !t.bestAfter.hasPrice || -diff < t.bestAfter.timeDiff {
+		t.bestAfter = trackedPrice{price: price, timeDiff: -diff, hasPrice: true}
 	}
+}
 
-	if bestBefore.hasPrice {
-		return bestBefore.price, true
+func (t *priceTracker) price() (float64, bool) {
+	if t.bestBefore.hasPrice {
+		return t.bestBefore.price, true
 	}
-	if bestAfter.hasPrice {
-		return bestAfter.price, true
+	if t.bestAfter.hasPrice {
+		return t.bestAfter.price, true
 	}
 	return 0, false
 }
 
-func (p *MarketDataProcessor) processMCMMessage(mcmData map[string]interface{}) {
-	mc, ok := mcmData["mc"].([]interface{})
-	if !ok {
-		return
-	}
-
-	timestamp, _ := mcmData["pt"].(float64)
+func (p *MarketDataProcessor) processMCMMessage(message *MCMMessage) {
+	timestamp := message.Pt
 
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	for _, marketChangeRaw := range mc {
-		marketChange, ok := marketChangeRaw.(map[string]interface{})
-		if !ok {
-			continue
-		}
-
-		marketID, ok := marketChange["id"].(string)
-		if !ok {
+	for i := range message.Mc {
+		marketChange := &message.Mc[i]
+		marketID := marketChange.ID
+		if marketID == "" {
 			continue
 		}
 
-		// Check if this is a new market definition
-		if marketDefRaw, exists := marketChange["marketDefinition"]; exists {
-			marketDef, ok := marketDefRaw.(map[string]interface{})
-			if !ok {
+		// Snapshot ladders due before this change is applied
+		if marketState, exists := p.MarketStates[marketID]; exists {
+			if marketState.repeatedChange(marketChange, timestamp) {
 				continue
 			}
+			p.snapshotLadders(marketID, marketState, timestamp)
+			marketState.takeOverrounds(timestamp)
+		}
 
-			// Only process greyhound WIN markets for new markets or full definitions
+		// Check if this is a new market definition
+		if marketDef := marketChange.MarketDefinition; marketDef != nil {
+			// Only process the profile's markets for new markets or full definitions
 			_, marketExists := p.MarketStates[marketID]
-			hasEventTypeId := marketDef["eventTypeId"] != nil
-			if !marketExists && hasEventTypeId && !p.isGreyhoundWinMarket(marketDef) {
+			hasEventTypeId := marketDef.EventTypeID != ""
+			if !marketExists && hasEventTypeId && !p.isTargetMarket(marketDef) {
 				continue
 			}
 
 			// Extract market info (for full market definitions)
 			var marketTime time.Time
 			var venue string
-			var eventID string
-			var eventName string
+			eventID := marketDef.EventID
+			eventName := marketDef.EventName
 
-			// Extract eventName, eventID, and venue if present
-			if en, ok := marketDef["eventName"].(string); ok {
-				eventName = en
-			}
-			if eid, ok := marketDef["eventId"].(string); ok {
-				eventID = eid
-			}
 			// Venue can come from either the venue field or extracted from eventName
-			if v, ok := marketDef["venue"].(string); ok {
-				venue = v
-			} else if eventName != "" {
+			if marketDef.Venue != nil {
+				venue = *marketDef.Venue
+			} else if eventName != "" && p.VenueRegex != nil {
 				venue = p.extractVenueFromEventName(eventName)
 			}
+			venue = p.names.venue(venue)
 
 			// Extract marketTime if present
-			if marketTimeStr, ok := marketDef["marketTime"].(string); ok {
+			if marketDef.MarketTime != "" {
 				var err error
-				marketTime, err = time.Parse(time.RFC3339, marketTimeStr)
+				marketTime, err = time.Parse(time.RFC3339, marketDef.MarketTime)
 				if err != nil {
 					continue
 				}
@@ -444,51 +654,39 @@ func (p *MarketDataProcessor) processMCMMessage(mcmData map[string]interface{})
 
 			if _, exists := p.MarketStates[marketID]; !exists {
 				// First time seeing this market - only create if we have full market info
-				if _, ok := marketDef["marketTime"].(string); ok {
+				if marketDef.MarketTime != "" {
 					p.MarketStates[marketID] = &MarketState{
-						MarketTime: marketTime,
-						Venue:      venue,
-						EventID:    eventID,
-						EventName:  eventName,
-						MarketDef:  marketDef,
-						Runners:    make(map[int64]*RunnerState),
+						MarketTime:  marketTime,
+						Venue:       venue,
+						EventID:     eventID,
+						EventName:   eventName,
+						EventTypeID: marketDef.EventTypeID,
+						MarketType:  marketDef.MarketType,
+						Timezone:    marketDef.Timezone,
+						Winners:     marketDef.NumberOfWinners,
+						MarketDef:   marketDef,
+						Runners:     make(map[int64]*RunnerState),
 					}
+					p.MarketStates[marketID].nextSnapshot = p.firstSnapshotTime(marketTime, time.UnixMilli(timestamp))
 
-					// Debug print when creating market 1.248394060
-					if marketID == "1.248394060" {
-						log.Printf("DEBUG: CREATED market 1.248394060 in file %s - EventID=%s, EventName=%q, Venue=%q",
-							p.CurrentSource, eventID, eventName, venue)
-					}
+					p.tracef(marketID, "created from %s: eventId=%s eventName=%q venue=%q",
+						p.CurrentSource, eventID, eventName, venue)
 				} else {
 					// Skip partial market definition for non-existing markets
 					continue
 				}
 
-				runnersRaw, ok := marketDef["runners"].([]interface{})
-				if ok {
-					for _, runnerRaw := range runnersRaw {
-						runner, ok := runnerRaw.(map[string]interface{})
-						if !ok {
-							continue
-						}
-
-						runnerIDFloat, ok := runner["id"].(float64)
-						if !ok {
-							continue
-						}
-						runnerID := int64(runnerIDFloat)
-
-						runnerName, _ := runner["name"].(string)
-						bsp, _ := runner["bsp"].(float64)
-						status, _ := runner["status"].(string)
-
-						p.MarketStates[marketID].Runners[runnerID] = &RunnerState{
-							Name:    p.extractGreyhoundName(runnerName),
-							BSP:     bsp,
-							Updates: make([]RunnerUpdate, 0),
-							Status:  status,
-						}
+				for _, runner := range marketDef.Runners {
+					if runner.ID == 0 {
+						continue
+					}
+					p.MarketStates[marketID].Runners[runner.ID] = &RunnerState{
+						Name:   p.extractGreyhoundName(runner.Name),
+						Box:    runnerBox(runner.Name),
+						BSP:    runner.BSP.Value,
+						Status: runner.Status,
 					}
+					p.MarketStates[marketID].Runners[runner.ID].applyRemoval(runner)
 				}
 			} else {
 				// Update existing market
@@ -507,159 +705,170 @@ func (p *MarketDataProcessor) processMCMMessage(mcmData map[string]interface{})
 				if eventName != "" {
 					marketState.EventName = eventName
 				}
+				if marketDef.EventTypeID != "" {
+					marketState.EventTypeID = marketDef.EventTypeID
+				}
+				if marketDef.MarketType != "" {
+					marketState.MarketType = marketDef.MarketType
+				}
+				if marketDef.Timezone != "" {
+					marketState.Timezone = marketDef.Timezone
+				}
+				if marketDef.NumberOfWinners > 0 {
+					marketState.Winners = marketDef.NumberOfWinners
+				}
 				marketState.MarketDef = marketDef
 
-				runnersRaw, ok := marketDef["runners"].([]interface{})
-				if ok {
-					for _, runnerRaw := range runnersRaw {
-						runner, ok := runnerRaw.(map[string]interface{})
-						if !ok {
-							continue
-						}
+				for _, runner := range marketDef.Runners {
+					if runner.ID == 0 {
+						continue
+					}
 
-						runnerIDFloat, ok := runner["id"].(float64)
-						if !ok {
-							continue
+					runnerState, exists := marketState.Runners[runner.ID]
+					if !exists {
+						marketState.Runners[runner.ID] = &RunnerState{
+							Name:   p.extractGreyhoundName(runner.Name),
+							Box:    runnerBox(runner.Name),
+							BSP:    runner.BSP.Value,
+							Status: runner.Status,
+						}
+					} else {
+						if runner.Name != "" {
+							runnerState.Name = p.extractGreyhoundName(runner.Name)
+						}
+						// Keep the box when a later name, such as an enriched one, has no number
+						if box := runnerBox(runner.Name); box > 0 {
+							runnerState.Box = box
 						}
-						runnerID := int64(runnerIDFloat)
-
-						runnerState, exists := marketState.Runners[runnerID]
-						if !exists {
-							runnerName, _ := runner["name"].(string)
-							bsp, _ := runner["bsp"].(float64)
-							status, _ := runner["status"].(string)
-							marketState.Runners[runnerID] = &RunnerState{
-								Name:    p.extractGreyhoundName(runnerName),
-								BSP:     bsp,
-								Updates: make([]RunnerUpdate, 0),
-								Status:  status,
-							}
-						} else {
-							runnerName, _ := runner["name"].(string)
-							if runnerName != "" {
-								runnerState.Name = p.extractGreyhoundName(runnerName)
-							}
 
-							if bsp, ok := runner["bsp"].(float64); ok {
-								runnerState.BSP = bsp
-							}
+						if runner.BSP.Set {
+							runnerState.BSP = runner.BSP.Value
+						}
 
-							if status, ok := runner["status"].(string); ok {
-								runnerState.Status = status
-							}
+						if runner.Status != "" {
+							runnerState.Status = runner.Status
 						}
 					}
+					marketState.Runners[runner.ID].applyRemoval(runner)
 				}
 			}
-		}
 
-		// Process runner changes
-		if marketState, exists := p.MarketStates[marketID]; exists {
-			if rcRaw, exists := marketChange["rc"]; exists {
-				rc, ok := rcRaw.([]interface{})
-				if !ok {
-					continue
+			if marketDef.BSPReconciled {
+				if marketState, ok := p.MarketStates[marketID]; ok && marketState.Reconciled.IsZero() {
+					marketState.Reconciled = time.UnixMilli(timestamp).UTC()
 				}
+			}
 
-				for _, runnerChangeRaw := range rc {
-					runnerChange, ok := runnerChangeRaw.(map[string]interface{})
-					if !ok {
-						continue
+			if marketDef.InPlay {
+				if marketState, ok := p.MarketStates[marketID]; ok && marketState.InPlayTime.IsZero() {
+					marketState.InPlayTime = time.UnixMilli(timestamp).UTC()
+					for _, runnerState := range marketState.Runners {
+						runnerState.volume.turnInPlay(marketState.InPlayTime)
 					}
+				}
+			}
 
-					runnerIDFloat, ok := runnerChange["id"].(float64)
-					if !ok {
-						continue
-					}
-					runnerID := int64(runnerIDFloat)
+			if marketDef.Status == "CLOSED" {
+				if marketState, ok := p.MarketStates[marketID]; ok {
+					marketState.Closed = true
+				}
+			}
 
-					if runnerState, exists := marketState.Runners[runnerID]; exists {
-						update := RunnerUpdate{
-							Timestamp: int64(timestamp),
-						}
+			if status := marketDef.Status; status == "SUSPENDED" || status == "CLOSED" {
+				if marketState, ok := p.MarketStates[marketID]; ok && !marketState.InPlayTime.IsZero() && marketState.InPlayEnd.IsZero() {
+					marketState.InPlayEnd = time.UnixMilli(timestamp).UTC()
+				}
+			}
+		}
 
-						if ltp, ok := runnerChange["ltp"].(float64); ok {
-							update.LTP = ltp
-							update.HasLTP = true
-							runnerState.LatestLTP = ltp
-						}
+		// Process runner changes
+		if marketState, exists := p.MarketStates[marketID]; exists {
+			for j := range marketChange.RC {
+				runnerChange := &marketChange.RC[j]
+				runnerID := runnerChange.ID
+
+				if runnerState, exists := marketState.Runners[runnerID]; exists {
+					update := RunnerUpdate{
+						Timestamp: timestamp,
+						BATB:      runnerChange.BATB,
+						ATB:       runnerChange.ATB,
+						BATL:      runnerChange.BATL,
+						ATL:       runnerChange.ATL,
+						SPB:       runnerChange.SPB,
+						TRD:       runnerChange.TRD,
+					}
 
-						if tv, ok := runnerChange["tv"].(float64); ok {
-							update.TV = tv
-							if tv > runnerState.MaxTV {
-								runnerState.MaxTV = tv
-							}
-						}
+					if runnerChange.LTP.Set {
+						update.LTP = runnerChange.LTP.Value
+						update.HasLTP = true
+						runnerState.LatestLTP = runnerChange.LTP.Value
+					}
 
-						// Handle BATB, ATB, SPB, TRD arrays
-						if batb, ok := runnerChange["batb"].([]interface{}); ok {
-							update.BATB = convertToFloat64Array(batb)
+					// Projected SPs are kept as they stood when BSP reconciled
+					if marketState.Reconciled.IsZero() {
+						if runnerChange.SPN.Set {
+							runnerState.SPNear = runnerChange.SPN.Value
 						}
-
-						if atb, ok := runnerChange["atb"].([]interface{}); ok {
-							update.ATB = convertToFloat64Array(atb)
+						if runnerChange.SPF.Set {
+							runnerState.SPFar = runnerChange.SPF.Value
 						}
+					}
 
-						if spb, ok := runnerChange["spb"].([]interface{}); ok {
-							update.SPB = convertToFloat64Array(spb)
+					if runnerChange.TV.Set {
+						update.TV = runnerChange.TV.Value
+						if update.TV > runnerState.MaxTV {
+							runnerState.MaxTV = update.TV
 						}
+					}
 
-						if trd, ok := runnerChange["trd"].([]interface{}); ok {
-							update.TRD = convertToFloat64Array(trd)
-
-							// Update max/min traded prices
-							for _, trade := range update.TRD {
-								if len(trade) > 0 {
-									price := trade[0]
-									if !runnerState.HasMaxTraded || price > runnerState.MaxTradedPrice {
-										runnerState.MaxTradedPrice = price
-										runnerState.HasMaxTraded = true
-									}
-									if !runnerState.HasMinTraded || price < runnerState.MinTradedPrice {
-										runnerState.MinTradedPrice = price
-										runnerState.HasMinTraded = true
-									}
+					if len(update.TRD) > 0 {
+						// Update max/min traded prices
+						for _, trade := range update.TRD {
+							if len(trade) > 0 {
+								price := trade[0]
+								if !runnerState.HasMaxTraded || price > runnerState.MaxTradedPrice {
+									runnerState.MaxTradedPrice = price
+									runnerState.HasMaxTraded = true
+								}
+								if !runnerState.HasMinTraded || price < runnerState.MinTradedPrice {
+									runnerState.MinTradedPrice = price
+									runnerState.HasMinTraded = true
 								}
 							}
+						}
 
-							// Calculate total volume from trades if TV not present
-							if _, hasTv := runnerChange["tv"]; !hasTv {
-								tradedTotal := 0.0
-								for _, trade := range update.TRD {
-									if len(trade) > 1 {
-										tradedTotal += trade[1]
-									}
-								}
-								if tradedTotal > runnerState.MaxTV {
-									runnerState.MaxTV = tradedTotal
+						// Calculate total volume from trades if TV not present
+						if !runnerChange.TV.Set {
+							tradedTotal := 0.0
+							for _, trade := range update.TRD {
+								if len(trade) > 1 {
+									tradedTotal += trade[1]
 								}
 							}
+							if tradedTotal > runnerState.MaxTV {
+								runnerState.MaxTV = tradedTotal
+							}
 						}
+					}
 
-						runnerState.Updates = append(runnerState.Updates, update)
+					if price, ok := updatePrice(update); ok {
+						runnerState.price30s.observe(timestamp, price, marketState.MarketTime.Add(-30*time.Second).UnixMilli())
+					}
+					if !marketState.InPlayTime.IsZero() {
+						runnerState.inPlay.observe(&runnerState.book, update.TRD)
+					}
+					runnerState.book.apply(update)
+					if len(update.TRD) > 0 {
+						runnerState.volume.observe(timestamp, runnerState.book.tradedVolume(), marketState.MarketTime.UnixMilli())
 					}
-				}
-			}
-		}
-	}
-}
 
-func convertToFloat64Array(arr []interface{}) [][]float64 {
-	result := make([][]float64, 0, len(arr))
-	for _, item := range arr {
-		if subArr, ok := item.([]interface{}); ok {
-			subResult := make([]float64, 0, len(subArr))
-			for _, subItem := range subArr {
-				if val, ok := subItem.(float64); ok {
-					subResult = append(subResult, val)
+					if p.Config.Mode == OutputModeTicks {
+						p.TickData = append(p.TickData, newTickRow(marketID, runnerID, update.Timestamp, runnerState))
+					}
 				}
 			}
-			if len(subResult) > 0 {
-				result = append(result, subResult)
-			}
 		}
 	}
-	return result
 }
 
 func (p *MarketDataProcessor) finalizeMarket(marketID string) []SummaryRow {
@@ -668,10 +877,19 @@ func (p *MarketDataProcessor) finalizeMarket(marketID string) []SummaryRow {
 		return nil
 	}
 
+	p.checkMarketQuality(marketID, marketState)
+
 	var summaryRows []SummaryRow
+	raceDay := p.raceDay(marketState.MarketTime, marketState.Timezone)
 
 	for runnerID, runnerData := range marketState.Runners {
-		price30sBefore, hasPrice30sBefore := p.getPrice30sBeforeStart(runnerData.Updates, marketState.MarketTime)
+		price30sBefore, hasPrice30sBefore := runnerData.price30s.price()
+		volume := runnerData.volume.profile(&runnerData.book, marketState.MarketTime.UnixMilli())
+		inPlay := runnerData.inPlay
+		secondsInPlay := 0.0
+		if !marketState.InPlayEnd.IsZero() {
+			secondsInPlay = marketState.InPlayEnd.Sub(marketState.InPlayTime).Seconds()
+		}
 
 		row := SummaryRow{
 			MarketID:              marketID,
@@ -687,46 +905,110 @@ func (p *MarketDataProcessor) finalizeMarket(marketID string) []SummaryRow {
 			TotalTradedVolume:     runnerData.MaxTV,
 			MaxTradedPrice:        runnerData.MaxTradedPrice,
 			MinTradedPrice:        runnerData.MinTradedPrice,
-			Year:                  marketState.MarketTime.Year(),
-			Month:                 int(marketState.MarketTime.Month()),
-			Day:                   marketState.MarketTime.Day(),
-			Win:                   runnerData.Status == "WINNER",
+			Year:                  raceDay.Year(),
+			Month:                 int(raceDay.Month()),
+			Day:                   raceDay.Day(),
+			Win:                   runnerData.Status == "WINNER" && marketState.Winners <= 1,
+			EventTypeID:           marketState.EventTypeID,
+			MarketType:            marketState.MarketType,
+			NumberOfWinners:       marketState.Winners,
+			Placed:                runnerData.Status == "WINNER",
+			VWAP:                  volume.VWAP,
+			VolumeLast60s:         volume.VolumeLast60s,
+			VolumeLast5m:          volume.VolumeLast5m,
+			InPlayVolumePct:       volume.InPlayVolumePct,
+			SPNear:                runnerData.SPNear,
+			SPFar:                 runnerData.SPFar,
+			BSPReconciled:         !marketState.Reconciled.IsZero(),
+			BSPReconciledTime:     marketState.Reconciled,
+			InPlayHigh:            inPlay.High,
+			InPlayLow:             inPlay.Low,
+			InPlayVolume:          inPlay.Volume,
+			SecondsInPlay:         secondsInPlay,
 			HasBSP:                runnerData.BSP != 0,
 			HasLTP:                runnerData.LatestLTP != 0,
 			HasPrice30sBefore:     hasPrice30sBefore,
 			HasMaxTradedPrice:     runnerData.HasMaxTraded,
 			HasMinTradedPrice:     runnerData.HasMinTraded,
+			HasVolumeProfile:      volume.HasVolume,
+			HasInPlayTrades:       inPlay.HasTraded,
+			HasSecondsInPlay:      !marketState.InPlayEnd.IsZero(),
+			Scratched:             runnerData.Status == "REMOVED",
+			RemovalDate:           runnerData.RemovalDate,
+			AdjustmentFactor:      runnerData.AdjustmentFactor,
+			HasAdjustmentFactor:   runnerData.HasAdjustment,
+			Box:                   runnerData.Box,
 		}
 
-		// Debug print for specific market
-		if marketID == "1.248394060" {
-			log.Printf("DEBUG: Market 1.248394060 - EventID=%s, EventName=%s, Venue=%s, Runner=%s",
-				marketState.EventID, marketState.EventName, marketState.Venue, runnerData.Name)
-		}
+		p.tracef(marketID, "finalized runner %d %q status=%s bsp=%g ltp=%g tv=%g",
+			runnerID, runnerData.Name, runnerData.Status, runnerData.BSP, runnerData.LatestLTP, runnerData.MaxTV)
 
 		summaryRows = append(summaryRows, row)
 	}
 
+	if p.Config.MarketTable {
+		p.MarketData = append(p.MarketData, p.marketRow(marketID, marketState, raceDay))
+	}
 	delete(p.MarketStates, marketID)
 	return summaryRows
 }
 
+// joinWinPlace merges each PLACE market row onto the WIN market row of the same selection in the
+// same race, leaving one row per selection. PLACE rows without a WIN market are kept as they are.
+func joinWinPlace(rows []SummaryRow) []SummaryRow {
+	type selectionKey struct {
+		eventID     string
+		marketTime  time.Time
+		selectionID int64
+	}
+	keyOf := func(row SummaryRow) selectionKey {
+		return selectionKey{eventID: row.EventID, marketTime: row.MarketTime.UTC(), selectionID: row.SelectionID}
+	}
+
+	joined := make([]SummaryRow, 0, len(rows))
+	winRows := make(map[selectionKey]int) // Index of each WIN row in joined
+	var places []SummaryRow
+	for _, row := range rows {
+		switch row.MarketType {
+		case "PLACE":
+			places = append(places, row)
+			continue
+		case "WIN":
+			winRows[keyOf(row)] = len(joined)
+		}
+		joined = append(joined, row)
+	}
+
+	for _, place := range places {
+		i, ok := winRows[keyOf(place)]
+		if !ok {
+			joined = append(joined, place)
+			continue
+		}
+		win := &joined[i]
+		win.PlaceMarketID = place.MarketID
+		win.PlaceBSP = place.BSP
+		win.HasPlaceBSP = place.HasBSP
+		win.PlaceLTP = place.LTP
+		win.HasPlaceLTP = place.HasLTP
+		win.NumberOfPlaces = place.NumberOfWinners
+		win.Placed = place.Placed
+	}
+	return joined
+}
+
 func (p *MarketDataProcessor) ProcessFile(filePath string) error {
 	// Thread-safe check for file limit
-	p.mu.RLock()
-	filesProcessed := p.FilesProcessed
-	p.mu.RUnlock()
-
-	if p.FileLimit > 0 && filesProcessed >= p.FileLimit {
-		log.Printf("File limit reached (%d); skipping %s", p.FileLimit, filePath)
+	if p.FileLimit > 0 && p.filesDone() >= p.FileLimit {
+		p.logger.Info().Int("limit", p.FileLimit).Str("file", filePath).Msg("file limit reached; skipping")
 		return nil
 	}
 
-	log.Printf("Processing file: %s", filePath)
+	p.logger.Debug().Str("file", filePath).Msg("processing file")
 
-	// Check if this is an S3 path
-	if strings.HasPrefix(filePath, "s3://") {
-		return p.processS3File(filePath)
+	// Check if this is an object storage path
+	if isRemotePath(filePath) {
+		return p.processRemoteFile(filePath)
 	}
 
 	file, err := os.Open(filePath)
@@ -735,17 +1017,45 @@ func (p *MarketDataProcessor) ProcessFile(filePath string) error {
 	}
 	defer file.Close()
 
-	var reader io.Reader = file
+	return p.processSource(file, filePath)
+}
 
-	// Handle bz2 compression
-	if strings.HasSuffix(filePath, ".bz2") {
-		reader = bzip2.NewReader(file)
+// processSource processes an opened file, decompressing it by its .bz2, .gz or .zst suffix and
+// reading it as a tar archive when it is one
+func (p *MarketDataProcessor) processSource(file io.Reader, filePath string) error {
+	reader, err := p.decompress(file, filePath)
+	if err != nil {
+		return fmt.Errorf("failed to decompress %s: %w", filePath, err)
+	}
+	if isTarFile(filePath) {
+		return p.ProcessTar(reader, filePath, nil)
 	}
-
 	return p.processReader(reader, filePath)
 }
 
 func (p *MarketDataProcessor) processReader(reader io.Reader, sourceName string) error {
+	markets, err := p.processMarketStream(reader, sourceName)
+	if err != nil {
+		return err
+	}
+
+	// Closed markets will see no more changes, so summarise them now rather than holding their
+	// state until FinalizeProcessing
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for marketID := range markets {
+		if marketState, ok := p.MarketStates[marketID]; ok && marketState.Closed {
+			if err := p.emitRows(p.finalizeMarket(marketID)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// processMarketStream processes the stream messages read from reader and returns the IDs of the
+// markets they contained
+func (p *MarketDataProcessor) processMarketStream(reader io.Reader, sourceName string) (map[string]bool, error) {
 	// Store current source for debug purposes
 	p.mu.Lock()
 	p.CurrentSource = sourceName
@@ -758,76 +1068,85 @@ func (p *MarketDataProcessor) processReader(reader io.Reader, sourceName string)
 	foundMarketIDs := make(map[string]bool)
 	mismatchCount := 0
 
-	scanner := bufio.NewScanner(reader)
-	lineCount := 0
+	// Markets whose changes this source provides, and the changes skipped for markets other
+	// sources provide
+	accepted := make(map[string]bool)
+	skipped := make(map[string]int)
+
+	lines := newLineReader(reader, p.Config.MaxLineBytes)
+	lineCount, badLines := 0, 0
+	var readErr error
 
-	for scanner.Scan() {
+	for lines.next() {
 		lineCount++
-		line := scanner.Text()
+		if readErr = lines.truncated(lineCount); readErr != nil {
+			break
+		}
 
-		var mcmData map[string]interface{}
-		if err := json.Unmarshal([]byte(line), &mcmData); err != nil {
+		var message MCMMessage
+		if err := json.Unmarshal(lines.bytes(), &message); err != nil {
+			badLines++
 			continue
 		}
 
-		if op, ok := mcmData["op"].(string); ok && op == "mcm" {
-			// Validate that markets in this file match the expected market ID
-			if expectedMarketID != "" {
-				if mc, ok := mcmData["mc"].([]interface{}); ok {
-					for _, marketChangeRaw := range mc {
-						if marketChange, ok := marketChangeRaw.(map[string]interface{}); ok {
-							if marketID, ok := marketChange["id"].(string); ok {
-								// Track this market ID
-								if !foundMarketIDs[marketID] {
-									foundMarketIDs[marketID] = true
-									// Log first occurrence of each unique market ID
-									if marketID != expectedMarketID {
-										log.Printf("⚠️  CONTAMINATION: File %s contains market %s (expected %s) at line %d",
-											filepath.Base(sourceName), marketID, expectedMarketID, lineCount)
-									}
-								}
+		if message.Op == "mcm" {
+			// Track the markets in this file and validate that they match the expected market ID
+			changes := message.Mc[:0]
+			for _, marketChange := range message.Mc {
+				marketID := marketChange.ID
+				if marketID == "" {
+					continue
+				}
 
-								// Count mismatches
-								if marketID != expectedMarketID {
-									mismatchCount++
-								}
-							}
-						}
+				// Track this market ID
+				if !foundMarketIDs[marketID] {
+					foundMarketIDs[marketID] = true
+					// Log first occurrence of each unique market ID
+					if expectedMarketID != "" && marketID != expectedMarketID {
+						p.logger.Warn().Str("file", filepath.Base(sourceName)).Str("market_id", marketID).
+							Str("expected", expectedMarketID).Int("line", lineCount).Msg("file contains another market")
 					}
 				}
-			}
 
-			// Check if this message contains market 1.248394060 (debug)
-			if mc, ok := mcmData["mc"].([]interface{}); ok {
-				for _, marketChangeRaw := range mc {
-					if marketChange, ok := marketChangeRaw.(map[string]interface{}); ok {
-						if marketID, ok := marketChange["id"].(string); ok && marketID == "1.248394060" {
-							log.Printf("DEBUG: Found market 1.248394060 in source: %s at line %d", sourceName, lineCount)
-							if marketDef, ok := marketChange["marketDefinition"].(map[string]interface{}); ok {
-								log.Printf("DEBUG: Market definition present: eventId=%v, eventName=%v",
-									marketDef["eventId"], marketDef["eventName"])
-							}
-						}
-					}
+				// Count mismatches
+				if expectedMarketID != "" && marketID != expectedMarketID {
+					mismatchCount++
 				}
+
+				used, claimed := accepted[marketID]
+				if !claimed {
+					used = p.claimMarket(marketID, sourceName)
+					accepted[marketID] = used
+				}
+				if !used {
+					skipped[marketID]++
+					continue
+				}
+
+				p.traceChange(&marketChange, message.Pt, sourceName, lineCount)
+				changes = append(changes, marketChange)
 			}
-			p.processMCMMessage(mcmData)
+			message.Mc = changes
+			p.processMCMMessage(&message)
 		}
 
 		if lineCount%10000 == 0 {
-			log.Printf("Processed %d lines from %s", lineCount, sourceName)
+			p.logger.Debug().Int("lines", lineCount).Str("source", sourceName).Msg("processed lines")
 		}
 	}
 
-	if err := scanner.Err(); err != nil {
-		log.Printf("Warning: error reading %s: %v", sourceName, err)
+	if readErr == nil {
+		readErr = lines.err
+	}
+	if readErr == nil && lineCount > 0 && badLines == lineCount {
+		readErr = fmt.Errorf("none of its %d lines are JSON messages", lineCount)
 	}
 
 	// Report contamination summary for this file
 	if expectedMarketID != "" && len(foundMarketIDs) > 0 {
 		if len(foundMarketIDs) == 1 && foundMarketIDs[expectedMarketID] {
 			// Clean file - only contains expected market
-			log.Printf("✅ File %s is clean (market %s only)", filepath.Base(sourceName), expectedMarketID)
+			p.logger.Debug().Str("file", filepath.Base(sourceName)).Str("market_id", expectedMarketID).Msg("file is clean")
 		} else {
 			// Contaminated file
 			var otherMarkets []string
@@ -836,19 +1155,36 @@ func (p *MarketDataProcessor) processReader(reader io.Reader, sourceName string)
 					otherMarkets = append(otherMarkets, marketID)
 				}
 			}
-			log.Printf("❌ File %s is CONTAMINATED: contains %d unique markets, %d mismatch instances. Other markets: %v",
-				filepath.Base(sourceName), len(foundMarketIDs), mismatchCount, otherMarkets)
+			p.logger.Warn().Str("file", filepath.Base(sourceName)).Int("markets", len(foundMarketIDs)).
+				Int("mismatches", mismatchCount).Strs("other_markets", otherMarkets).Msg("file is contaminated")
+
+			sort.Strings(otherMarkets)
+			p.mu.Lock()
+			p.QualityIssues = append(p.QualityIssues, QualityIssue{
+				Kind:     IssueContaminatedFile,
+				MarketID: expectedMarketID,
+				Source:   sourceName,
+				Detail:   fmt.Sprintf("also holds %s in %d messages", strings.Join(otherMarkets, ", "), mismatchCount),
+			})
+			p.mu.Unlock()
 		}
 	}
 
-	log.Printf("Completed processing %d lines from %s", lineCount, sourceName)
+	p.logger.Debug().Int("lines", lineCount).Str("source", sourceName).Msg("completed source")
 
-	// Thread-safe increment of FilesProcessed
-	p.mu.Lock()
-	p.FilesProcessed++
-	p.mu.Unlock()
+	p.countFile()
+	p.noteDuplicates(sourceName, skipped)
 
-	return nil
+	markets := make(map[string]bool)
+	for marketID, used := range accepted {
+		if used {
+			markets[marketID] = true
+		}
+	}
+	if readErr != nil {
+		return markets, fmt.Errorf("failed to read %s after %d lines: %w", sourceName, lineCount, readErr)
+	}
+	return markets, nil
 }
 
 // extractMarketIDFromPath extracts the market ID from a file path like "1.248394055.bz2"
@@ -856,11 +1192,14 @@ func (p *MarketDataProcessor) extractMarketIDFromPath(path string) string {
 	// Extract filename from path
 	filename := filepath.Base(path)
 
-	// Remove extensions (.bz2, .json, .jsonl, etc)
-	filename = strings.TrimSuffix(filename, ".bz2")
+	// Remove extensions (.bz2, .gz, .zst, .json, .jsonl, etc)
+	filename = trimCompression(filename)
 	filename = strings.TrimSuffix(filename, ".json")
 	filename = strings.TrimSuffix(filename, ".jsonl")
 
+	// A rotated segment, such as 1.248394055.part001, is part of its market's recording
+	filename = partSuffix.ReplaceAllString(filename, "")
+
 	// Check if it looks like a market ID (starts with "1.")
 	if strings.HasPrefix(filename, "1.") {
 		return filename
@@ -870,34 +1209,46 @@ func (p *MarketDataProcessor) extractMarketIDFromPath(path string) string {
 }
 
 func (p *MarketDataProcessor) processPath(inputPath string) error {
-	// Check if this is an S3 path
-	if strings.HasPrefix(inputPath, "s3://") {
-		return p.processS3Path(inputPath)
+	files, err := p.listPath(inputPath)
+	if err != nil || len(files) == 0 {
+		return err
+	}
+	return p.processFilesParallel(files)
+}
+
+// listPath returns the supported files of a local or object storage path, noting their versions.
+// A directory or prefix is filtered by the configured dates and patterns; a file given by itself isn't.
+func (p *MarketDataProcessor) listPath(inputPath string) ([]string, error) {
+	// Check if this is an object storage path
+	if isRemotePath(inputPath) {
+		return p.listRemotePath(inputPath)
 	}
 
 	info, err := os.Stat(inputPath)
 	if err != nil {
-		return fmt.Errorf("path does not exist: %s", inputPath)
+		return nil, fmt.Errorf("path does not exist: %s", inputPath)
 	}
 
 	if info.IsDir() {
-		return p.processDirectory(inputPath)
+		return p.listDirectory(inputPath)
 	}
 
 	if p.isSupportedFile(inputPath) {
-		return p.ProcessFile(inputPath)
+		p.noteSource(inputPath, sourceVersion{version: info.ModTime().UTC().Format(time.RFC3339Nano), size: info.Size()})
+		return []string{inputPath}, nil
 	}
 
-	log.Printf("Warning: skipping unsupported file type: %s", inputPath)
-	return nil
+	p.logger.Warn().Str("path", inputPath).Msg("skipping unsupported file type")
+	return nil, nil
 }
 
-// ProcessPath is the main entry point for processing any path (local or S3)
+// ProcessPath is the main entry point for processing any path (local, S3, GCS or Azure)
 func (p *MarketDataProcessor) ProcessPath(inputPath string) error {
+	p.noteInputRoot(inputPath)
 	return p.processPath(inputPath)
 }
 
-func (p *MarketDataProcessor) processDirectory(dirPath string) error {
+func (p *MarketDataProcessor) listDirectory(dirPath string) ([]string, error) {
 	var supportedFiles []string
 
 	err := filepath.Walk(dirPath, func(path string, info os.FileInfo, err error) error {
@@ -907,56 +1258,98 @@ func (p *MarketDataProcessor) processDirectory(dirPath string) error {
 
 		if !info.IsDir() && p.isSupportedFile(path) {
 			supportedFiles = append(supportedFiles, path)
+			p.noteSource(path, sourceVersion{version: info.ModTime().UTC().Format(time.RFC3339Nano), size: info.Size()})
 		}
 
 		return nil
 	})
 
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	sort.Strings(supportedFiles)
+	supportedFiles = p.filterSources(supportedFiles)
 
 	if len(supportedFiles) == 0 {
-		log.Printf("Warning: no supported files found under %s", dirPath)
-		return nil
+		p.logger.Warn().Str("path", dirPath).Msg("no supported files found")
 	}
-
-	return p.processFilesParallel(supportedFiles)
+	return supportedFiles, nil
 }
 
 func (p *MarketDataProcessor) processFilesParallel(filePaths []string) error {
-	// Create a channel for file paths
-	filesCh := make(chan string, len(filePaths))
+	// Create a channel for the files, grouped so a market's files are processed together
 	errorsCh := make(chan error, len(filePaths))
 
 	// Add files to channel, respecting file limit
-	filesToProcess := filePaths
-	if p.FileLimit > 0 && len(filePaths) > p.FileLimit {
-		filesToProcess = filePaths[:p.FileLimit]
+	filesToProcess := p.unprocessed(filePaths)
+	if p.FileLimit > 0 && len(filesToProcess) > p.FileLimit {
+		filesToProcess = filesToProcess[:p.FileLimit]
 	}
 
-	for _, filePath := range filesToProcess {
-		filesCh <- filePath
+	groups := p.stitchGroups(filesToProcess)
+	groupsCh := make(chan []string, len(groups))
+	primaries := make([]string, len(groups))
+	for i, group := range groups {
+		groupsCh <- group
+		primaries[i] = group[0]
 	}
-	close(filesCh)
+	close(groupsCh)
 
 	// Create wait group for workers
 	var wg sync.WaitGroup
-
-	// Start worker goroutines
-	for i := 0; i < p.Workers; i++ {
+	var failed atomic.Bool
+	progress := p.startProgress(len(filesToProcess))
+	p.noteOwnFiles(primaries)
+	prefetch := p.startPrefetch(filesToProcess)
+	defer prefetch.stop()
+
+	// Start worker goroutines, each with its own market state so they never contend on it
+	workers := make([]*MarketDataProcessor, min(p.Workers, max(len(groups), 1)))
+	for i := range workers {
+		worker := p.newWorker()
+		worker.prefetch = prefetch
+		workers[i] = worker
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			for filePath := range filesCh {
-				if err := p.ProcessFile(filePath); err != nil {
-					log.Printf("Error processing file %s: %v", filePath, err)
-					errorsCh <- err
+			for group := range groupsCh {
+				if p.cancelled() || (failed.Load() && p.Config.ErrorPolicy == ErrorPolicyFailFast) {
+					continue // Drain the files left after the first failure, or once Process is cancelled
+				}
+				filePath := group[0]
+				rows, emitted := worker.summaryRows(), worker.rowsEmitted()
+				var err error
+				if len(group) == 1 {
+					err = worker.ProcessFile(filePath)
 				} else {
+					err = worker.processStitched(group)
+				}
+				switch {
+				case err != nil && p.cancelled():
+					// Stopped by Process's context rather than failed
+				case err != nil:
+					p.logger.Error().Err(err).Strs("files", group).Msg("error processing file")
+					for _, groupFile := range group {
+						p.recordFailure(groupFile, err)
+					}
+					failed.Store(true)
+					errorsCh <- fmt.Errorf("%s: %w", filePath, err)
+				default:
+					// A stitched market's rows are counted against its first file
+					p.recordSource(filePath, worker.summaryRows()-rows)
+					for _, groupFile := range group[1:] {
+						p.recordSource(groupFile, 0)
+					}
 					errorsCh <- nil
 				}
+				progress.fileDone(worker.rowsEmitted() - emitted)
+				for _, groupFile := range group {
+					prefetch.done(groupFile)
+					if groupFile != filePath {
+						progress.fileDone(0)
+					}
+				}
 			}
 		}()
 	}
@@ -964,16 +1357,33 @@ func (p *MarketDataProcessor) processFilesParallel(filePaths []string) error {
 	// Wait for all workers to complete
 	wg.Wait()
 	close(errorsCh)
+	progress.finish()
+
+	for _, worker := range workers {
+		p.merge(worker)
+	}
+	p.reportDuplicates()
 
-	// Check for any errors
-	var lastError error
+	var errs []error
 	for err := range errorsCh {
 		if err != nil {
-			lastError = err
+			errs = append(errs, err)
 		}
 	}
+	if len(errs) == 0 {
+		return nil
+	}
 
-	return lastError
+	if p.Config.QuarantineDir != "" {
+		if err := p.saveQuarantineReport(); err != nil {
+			p.logger.Warn().Err(err).Msg("failed to save quarantine report")
+		}
+	}
+	if p.Config.ErrorPolicy == ErrorPolicyFailFast {
+		return errs[0]
+	}
+	p.logger.Warn().Int("failed", len(errs)).Int("files", len(filesToProcess)).Msg("some files failed to process")
+	return nil
 }
 
 func (p *MarketDataProcessor) isSupportedFile(filePath string) bool {
@@ -982,15 +1392,22 @@ func (p *MarketDataProcessor) isSupportedFile(filePath string) bool {
 	}
 
 	ext := filepath.Ext(filePath)
-	return ext == ".bz2" || ext == ".jsonl" || ext == ".json" || ext == ""
+	if ext == ".bz2" || ext == ".gz" || ext == ".zst" || ext == ".jsonl" || ext == ".json" || ext == ".tar" || ext == ".tgz" || ext == "" {
+		return true
+	}
+
+	// Uncompressed market files are named after the market, such as 1.248231892
+	return marketFilePattern.MatchString(filepath.Base(filePath))
 }
 
+var marketFilePattern = regexp.MustCompile(`^\d+\.\d+$`)
+
 func (p *MarketDataProcessor) saveMonthlyData(year, month int, data []SummaryRow) error {
 	if len(data) == 0 {
 		return nil
 	}
 
-	filename := fmt.Sprintf("greyhound_win_markets_%d_%02d.csv", year, month)
+	filename := fmt.Sprintf("%s_%d_%02d.csv", p.Profile.FilePrefix, year, month)
 	outputPath := filepath.Join(p.OutputDir, filename)
 
 	// Check if file exists to determine if we need to write header
@@ -1011,51 +1428,102 @@ func (p *MarketDataProcessor) saveMonthlyData(year, month int, data []SummaryRow
 
 	// Write header only if file is new
 	if !fileExists {
-		header := []string{
-			"market_id", "selection_id", "event_id", "event_name", "venue", "greyhound_name", "market_time",
-			"bsp", "ltp", "price_30s_before_start", "total_traded_volume",
-			"max_traded_price", "min_traded_price", "year", "month", "day", "win",
-		}
-		if err := writer.Write(header); err != nil {
+		if err := writer.Write(summaryHeader); err != nil {
 			return err
 		}
 	}
 
 	// Write data
 	for _, row := range data {
-		record := []string{
-			row.MarketID,
-			strconv.FormatInt(row.SelectionID, 10),
-			row.EventID,
-			row.EventName,
-			row.Venue,
-			row.GreyhoundName,
-			row.MarketTime.Format(time.RFC3339),
-			formatFloat(row.BSP, row.HasBSP),
-			formatFloat(row.LTP, row.HasLTP),
-			formatFloat(row.Price30sBeforeStart, row.HasPrice30sBefore),
-			strconv.FormatFloat(row.TotalTradedVolume, 'f', -1, 64),
-			formatFloat(row.MaxTradedPrice, row.HasMaxTradedPrice),
-			formatFloat(row.MinTradedPrice, row.HasMinTradedPrice),
-			strconv.Itoa(row.Year),
-			strconv.Itoa(row.Month),
-			strconv.Itoa(row.Day),
-			strconv.FormatBool(row.Win),
-		}
-
-		if err := writer.Write(record); err != nil {
+		if err := writer.Write(summaryRecord(row)); err != nil {
 			return err
 		}
 	}
 
 	if fileExists {
-		log.Printf("Appended %d records to %s", len(data), outputPath)
+		p.logger.Info().Int("records", len(data)).Str("path", outputPath).Msg("appended records")
 	} else {
-		log.Printf("Created %s with %d records", outputPath, len(data))
+		p.logger.Info().Str("path", outputPath).Int("records", len(data)).Msg("created output")
 	}
 	return nil
 }
 
+// summaryHeader is the CSV header of summary files
+var summaryHeader = []string{
+	"market_id", "selection_id", "event_id", "event_name", "venue", "greyhound_name", "market_time",
+	"bsp", "ltp", "price_30s_before_start", "total_traded_volume",
+	"max_traded_price", "min_traded_price", "year", "month", "day", "win",
+	"event_type_id", "market_type", "number_of_winners", "placed",
+	"place_market_id", "place_bsp", "place_ltp", "number_of_places",
+	"vwap", "volume_last_60s", "volume_last_5m", "in_play_volume_pct",
+	"sp_near", "sp_far", "bsp_reconciled", "bsp_reconciled_time",
+	"in_play_high", "in_play_low", "in_play_volume", "seconds_in_play",
+	"scratched", "removal_date", "adjustment_factor", "box",
+}
+
+// summaryRecord formats a summary row in summaryHeader order
+func summaryRecord(row SummaryRow) []string {
+	numberOfPlaces := ""
+	if row.NumberOfPlaces > 0 {
+		numberOfPlaces = strconv.Itoa(row.NumberOfPlaces)
+	}
+	reconciledTime := ""
+	if row.BSPReconciled {
+		reconciledTime = row.BSPReconciledTime.Format(time.RFC3339)
+	}
+	removalDate := ""
+	if !row.RemovalDate.IsZero() {
+		removalDate = row.RemovalDate.Format(time.RFC3339)
+	}
+	box := ""
+	if row.Box > 0 {
+		box = strconv.Itoa(row.Box)
+	}
+	return []string{
+		row.MarketID,
+		strconv.FormatInt(row.SelectionID, 10),
+		row.EventID,
+		row.EventName,
+		row.Venue,
+		row.GreyhoundName,
+		row.MarketTime.Format(time.RFC3339),
+		formatFloat(row.BSP, row.HasBSP),
+		formatFloat(row.LTP, row.HasLTP),
+		formatFloat(row.Price30sBeforeStart, row.HasPrice30sBefore),
+		strconv.FormatFloat(row.TotalTradedVolume, 'f', -1, 64),
+		formatFloat(row.MaxTradedPrice, row.HasMaxTradedPrice),
+		formatFloat(row.MinTradedPrice, row.HasMinTradedPrice),
+		strconv.Itoa(row.Year),
+		strconv.Itoa(row.Month),
+		strconv.Itoa(row.Day),
+		strconv.FormatBool(row.Win),
+		row.EventTypeID,
+		row.MarketType,
+		strconv.Itoa(row.NumberOfWinners),
+		strconv.FormatBool(row.Placed),
+		row.PlaceMarketID,
+		formatFloat(row.PlaceBSP, row.HasPlaceBSP),
+		formatFloat(row.PlaceLTP, row.HasPlaceLTP),
+		numberOfPlaces,
+		formatFloat(row.VWAP, row.HasVolumeProfile),
+		formatVolume(row.VolumeLast60s, row.HasVolumeProfile),
+		formatVolume(row.VolumeLast5m, row.HasVolumeProfile),
+		formatVolume(row.InPlayVolumePct, row.HasVolumeProfile),
+		formatFloat(row.SPNear, true),
+		formatFloat(row.SPFar, true),
+		strconv.FormatBool(row.BSPReconciled),
+		reconciledTime,
+		formatFloat(row.InPlayHigh, row.HasInPlayTrades),
+		formatFloat(row.InPlayLow, row.HasInPlayTrades),
+		formatFloat(row.InPlayVolume, row.HasInPlayTrades),
+		formatVolume(row.SecondsInPlay, row.HasSecondsInPlay),
+		strconv.FormatBool(row.Scratched),
+		removalDate,
+		formatVolume(row.AdjustmentFactor, row.HasAdjustmentFactor),
+		box,
+	}
+}
+
 func formatFloat(value float64, hasValue bool) string {
 	if !hasValue || value == 0 {
 		return ""
@@ -1063,8 +1531,56 @@ func formatFloat(value float64, hasValue bool) string {
 	return strconv.FormatFloat(value, 'f', -1, 64)
 }
 
+// formatVolume writes a volume that is empty when nothing traded but may be a real zero otherwise
+func formatVolume(value float64, hasValue bool) string {
+	if !hasValue {
+		return ""
+	}
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
 func (p *MarketDataProcessor) FinalizeProcessing() error {
-	log.Println("Finalizing processing...")
+	if err := p.saveOutput(); err != nil {
+		return err
+	}
+
+	if p.Config.ReportPath != "" {
+		if err := p.saveQualityReport(); err != nil {
+			return err
+		}
+	}
+
+	// The manifest is only saved once the rows it accounts for have been written
+	if p.manifest != nil {
+		if err := SaveManifest(p.Config.ManifestPath, p.manifest); err != nil {
+			return err
+		}
+		p.logger.Info().Str("path", p.Config.ManifestPath).Int("files", len(p.manifest.Files)).Msg("saved manifest")
+	}
+	return nil
+}
+
+// saveOutput writes the collected data in the configured mode and format
+func (p *MarketDataProcessor) saveOutput() error {
+	p.logger.Info().Msg("finalizing processing")
+
+	if p.Config.Mode == OutputModeTicks && !p.Config.PerMarket {
+		if len(p.TickData) == 0 {
+			p.logger.Info().Msg("no data to save")
+			return nil
+		}
+		if p.Config.OutputFormat == OutputFormatDuckDB {
+			return saveDuckDB(p, ticksTable, p.TickData)
+		}
+		return p.saveTicks(p.TickData)
+	}
+	if p.Config.Mode == OutputModeLadder && !p.Config.PerMarket {
+		if len(p.LadderData) == 0 {
+			p.logger.Info().Msg("no data to save")
+			return nil
+		}
+		return p.saveLadders(p.LadderData)
+	}
 
 	// Collect all data
 	var allData []SummaryRow
@@ -1080,11 +1596,38 @@ func (p *MarketDataProcessor) FinalizeProcessing() error {
 	// Add previously processed data
 	allData = append(allData, p.ProcessedData...)
 
+	if p.Config.JoinWinPlace {
+		allData = joinWinPlace(allData)
+	}
+
+	if p.Config.MarketTable {
+		if err := p.saveMarketTable(p.MarketData); err != nil {
+			return err
+		}
+	}
+
+	if p.Config.Streaming {
+		return p.finishStream(allData)
+	}
+	if p.Config.PerMarket {
+		return p.savePerMarket(allData)
+	}
+
 	if len(allData) == 0 {
-		log.Println("No data to save")
+		p.logger.Info().Msg("no data to save")
 		return nil
 	}
 
+	if p.Config.OutputFormat == OutputFormatDuckDB {
+		return saveDuckDB(p, summaryTable, allData)
+	}
+	if p.Config.OutputFormat == OutputFormatDatabase {
+		return p.saveDatabase(allData)
+	}
+	if p.Config.Partitioned {
+		return p.savePartitions(allData)
+	}
+
 	// If single output file is specified, write all data to one file
 	if p.OutputFile != "" {
 		if p.Config.OutputFormat == OutputFormatParquet {
@@ -1111,7 +1654,7 @@ func (p *MarketDataProcessor) FinalizeProcessing() error {
 		}
 	}
 
-	log.Printf("Processing complete. Generated %d monthly files.", len(monthlyData))
+	p.logger.Info().Int("files", len(monthlyData)).Msg("generated monthly files")
 	return nil
 }
 
@@ -1120,9 +1663,9 @@ func (p *MarketDataProcessor) saveSingleCSV(outputPath string, data []SummaryRow
 		return nil
 	}
 
-	// Check if output is S3
-	if strings.HasPrefix(outputPath, "s3://") {
-		return p.writeCSVToS3(outputPath, data)
+	// Check if output is in object storage
+	if isRemotePath(outputPath) {
+		return p.writeCSVToStorage(outputPath, data)
 	}
 
 	// Ensure directory exists
@@ -1141,47 +1684,22 @@ func (p *MarketDataProcessor) saveSingleCSV(outputPath string, data []SummaryRow
 	defer writer.Flush()
 
 	// Write header
-	header := []string{
-		"market_id", "selection_id", "event_id", "event_name", "venue", "greyhound_name", "market_time",
-		"bsp", "ltp", "price_30s_before_start", "total_traded_volume",
-		"max_traded_price", "min_traded_price", "year", "month", "day", "win",
-	}
-	if err := writer.Write(header); err != nil {
+	if err := writer.Write(summaryHeader); err != nil {
 		return err
 	}
 
 	// Write data
 	for _, row := range data {
-		record := []string{
-			row.MarketID,
-			strconv.FormatInt(row.SelectionID, 10),
-			row.EventID,
-			row.EventName,
-			row.Venue,
-			row.GreyhoundName,
-			row.MarketTime.Format(time.RFC3339),
-			formatFloat(row.BSP, row.HasBSP),
-			formatFloat(row.LTP, row.HasLTP),
-			formatFloat(row.Price30sBeforeStart, row.HasPrice30sBefore),
-			strconv.FormatFloat(row.TotalTradedVolume, 'f', -1, 64),
-			formatFloat(row.MaxTradedPrice, row.HasMaxTradedPrice),
-			formatFloat(row.MinTradedPrice, row.HasMinTradedPrice),
-			strconv.Itoa(row.Year),
-			strconv.Itoa(row.Month),
-			strconv.Itoa(row.Day),
-			strconv.FormatBool(row.Win),
-		}
-
-		if err := writer.Write(record); err != nil {
+		if err := writer.Write(summaryRecord(row)); err != nil {
 			return err
 		}
 	}
 
-	log.Printf("Created %s with %d records", outputPath, len(data))
+	p.logger.Info().Str("path", outputPath).Int("records", len(data)).Msg("created output")
 	return nil
 }
 
-func (p *MarketDataProcessor) writeCSVToS3(s3Path string, data []SummaryRow) error {
+func (p *MarketDataProcessor) writeCSVToStorage(remotePath string, data []SummaryRow) error {
 	// Create a temporary file
 	tmpFile, err := os.CreateTemp("", "csv-*.csv")
 	if err != nil {
@@ -1194,38 +1712,13 @@ func (p *MarketDataProcessor) writeCSVToS3(s3Path string, data []SummaryRow) err
 	writer := csv.NewWriter(tmpFile)
 
 	// Write header
-	header := []string{
-		"market_id", "selection_id", "event_id", "event_name", "venue", "greyhound_name", "market_time",
-		"bsp", "ltp", "price_30s_before_start", "total_traded_volume",
-		"max_traded_price", "min_traded_price", "year", "month", "day", "win",
-	}
-	if err := writer.Write(header); err != nil {
+	if err := writer.Write(summaryHeader); err != nil {
 		return err
 	}
 
 	// Write data
 	for _, row := range data {
-		record := []string{
-			row.MarketID,
-			strconv.FormatInt(row.SelectionID, 10),
-			row.EventID,
-			row.EventName,
-			row.Venue,
-			row.GreyhoundName,
-			row.MarketTime.Format(time.RFC3339),
-			formatFloat(row.BSP, row.HasBSP),
-			formatFloat(row.LTP, row.HasLTP),
-			formatFloat(row.Price30sBeforeStart, row.HasPrice30sBefore),
-			strconv.FormatFloat(row.TotalTradedVolume, 'f', -1, 64),
-			formatFloat(row.MaxTradedPrice, row.HasMaxTradedPrice),
-			formatFloat(row.MinTradedPrice, row.HasMinTradedPrice),
-			strconv.Itoa(row.Year),
-			strconv.Itoa(row.Month),
-			strconv.Itoa(row.Day),
-			strconv.FormatBool(row.Win),
-		}
-
-		if err := writer.Write(record); err != nil {
+		if err := writer.Write(summaryRecord(row)); err != nil {
 			return err
 		}
 	}
@@ -1235,11 +1728,8 @@ func (p *MarketDataProcessor) writeCSVToS3(s3Path string, data []SummaryRow) err
 		return fmt.Errorf("failed to flush CSV writer: %w", err)
 	}
 
-	// Reopen file for reading
-	tmpFile.Seek(0, 0)
-
-	// Upload to S3
-	return p.uploadToS3(s3Path, tmpFile)
+	// Upload to object storage
+	return p.uploadToStorage(remotePath, tmpFile.Name())
 }
 
 func (p *MarketDataProcessor) saveSingleParquet(outputPath string, data []SummaryRow) error {
@@ -1247,9 +1737,9 @@ func (p *MarketDataProcessor) saveSingleParquet(outputPath string, data []Summar
 		return nil
 	}
 
-	// Check if output is S3
-	if strings.HasPrefix(outputPath, "s3://") {
-		return p.writeParquetToS3(outputPath, data)
+	// Check if output is in object storage
+	if isRemotePath(outputPath) {
+		return p.writeParquetToStorage(outputPath, data)
 	}
 
 	// Ensure directory exists
@@ -1266,19 +1756,19 @@ func (p *MarketDataProcessor) saveSingleParquet(outputPath string, data []Summar
 	defer file.Close()
 
 	// Create parquet writer
-	writer := parquet.NewGenericWriter[SummaryRow](file)
+	writer := parquet.NewGenericWriter[summaryParquetRow](file, append(p.parquetWriterOptions(), summarySchema)...)
 	defer writer.Close()
 
 	// Write all rows
-	if _, err := writer.Write(data); err != nil {
+	if _, err := writer.Write(newSummaryParquetRows(data)); err != nil {
 		return fmt.Errorf("failed to write parquet data: %w", err)
 	}
 
-	log.Printf("Created %s with %d records", outputPath, len(data))
+	p.logger.Info().Str("path", outputPath).Int("records", len(data)).Msg("created output")
 	return nil
 }
 
-func (p *MarketDataProcessor) writeParquetToS3(s3Path string, data []SummaryRow) error {
+func (p *MarketDataProcessor) writeParquetToStorage(remotePath string, data []SummaryRow) error {
 	// Create a temporary file
 	tmpFile, err := os.CreateTemp("", "parquet-*.parquet")
 	if err != nil {
@@ -1288,161 +1778,163 @@ func (p *MarketDataProcessor) writeParquetToS3(s3Path string, data []SummaryRow)
 	defer tmpFile.Close()
 
 	// Write parquet to temp file
-	writer := parquet.NewGenericWriter[SummaryRow](tmpFile)
-	if _, err := writer.Write(data); err != nil {
+	writer := parquet.NewGenericWriter[summaryParquetRow](tmpFile, append(p.parquetWriterOptions(), summarySchema)...)
+	if _, err := writer.Write(newSummaryParquetRows(data)); err != nil {
 		writer.Close()
 		return fmt.Errorf("failed to write parquet data: %w", err)
 	}
 	writer.Close()
 
-	// Reopen file for reading
-	tmpFile.Seek(0, 0)
-
-	// Upload to S3
-	return p.uploadToS3(s3Path, tmpFile)
+	// Upload to object storage
+	return p.uploadToStorage(remotePath, tmpFile.Name())
 }
 
-func (p *MarketDataProcessor) uploadToS3(s3Path string, file io.Reader) error {
-	if p.S3Client == nil {
-		return fmt.Errorf("S3 client not initialized")
-	}
-
-	// Parse S3 path
-	bucket, key, err := parseS3Path(s3Path)
+func (p *MarketDataProcessor) uploadToStorage(remotePath, filePath string) error {
+	storage, key, err := p.objectStorage(remotePath)
 	if err != nil {
 		return err
 	}
 
-	// Read file content
-	content, err := io.ReadAll(file)
-	if err != nil {
-		return fmt.Errorf("failed to read file: %w", err)
-	}
-
-	// Upload to S3
-	ctx := context.Background()
-	input := &s3.PutObjectInput{
-		Bucket: &bucket,
-		Key:    &key,
-		Body:   strings.NewReader(string(content)),
-	}
-
-	if _, err := p.S3Client.PutObject(ctx, input); err != nil {
-		return fmt.Errorf("failed to upload to S3: %w", err)
+	if err := storage.Upload(context.Background(), filePath, key); err != nil {
+		return fmt.Errorf("failed to upload %s: %w", remotePath, err)
 	}
 
-	log.Printf("Uploaded %s to S3 with %d bytes", s3Path, len(content))
+	p.logger.Info().Str("path", remotePath).Msg("uploaded")
 	return nil
 }
 
-// ProcessTarFile processes a tar archive by streaming through it and processing each .bz2 file
+// ProcessTarFile processes a tar archive, such as a Betfair historical data download, calling
+// progressCallback with the summary rows of each market file as it is read
 func ProcessTarFile(reader io.Reader, progressCallback func(filename string, records []SummaryRow)) error {
+	processor := NewMarketDataProcessor("", 0, 1)
+	return processor.ProcessTar(reader, "tar", progressCallback)
+}
+
+// ProcessTar streams a tar archive, processing each market file straight from the archive without
+// extracting it. Entries may be JSON lines, .bz2, .gz or .zst compressed, or archives themselves,
+// and can sit in any folder layout, such as the BASIC, ADVANCED and PRO trees of Betfair's
+// historical data. Each entry's markets are finalized once it has been read: they are handed to
+// progress when it is set and otherwise kept for FinalizeProcessing.
+func (p *MarketDataProcessor) ProcessTar(reader io.Reader, sourceName string, progress func(entry string, records []SummaryRow)) error {
 	tarReader := tar.NewReader(reader)
 
 	for {
 		header, err := tarReader.Next()
 		if err == io.EOF {
-			break
+			return nil
 		}
 		if err != nil {
-			return err
+			return fmt.Errorf("failed to read tar %s: %w", sourceName, err)
 		}
 
-		if header.Typeflag != tar.TypeReg {
+		if header.Typeflag != tar.TypeReg || !p.isSupportedFile(header.Name) {
 			continue
 		}
-
-		// Only process .bz2 files
-		if !strings.HasSuffix(header.Name, ".bz2") {
+		if !p.sourceInDateRange(header.Name) {
 			continue
 		}
 
-		// Create a new processor for each file to avoid memory issues
-		processor := NewMarketDataProcessor("", 0, 1)
+		if p.FileLimit > 0 && p.filesDone() >= p.FileLimit {
+			p.logger.Info().Int("limit", p.FileLimit).Str("source", sourceName).Msg("file limit reached; stopping")
+			return nil
+		}
 
-		// Process the file directly from the tar stream
-		err = processor.ProcessFile(header.Name)
-		if err != nil {
-			log.Printf("Warning: failed to process %s: %v", header.Name, err)
+		entry, err := p.decompress(tarReader, header.Name)
+		if err == nil && isTarFile(header.Name) {
+			// An archive within the archive, such as a day's .tar.gz in a month's .tar
+			if err := p.ProcessTar(entry, sourceName+"!"+header.Name, progress); err != nil {
+				return err
+			}
 			continue
 		}
 
-		// Finalize and get records
-		records := processor.ProcessedData
+		var markets map[string]bool
+		if err == nil {
+			markets, err = p.processMarketStream(entry, sourceName+"!"+header.Name)
+		}
 		if err != nil {
-			log.Printf("Warning: failed to process %s: %v", header.Name, err)
+			p.logger.Warn().Err(err).Str("entry", header.Name).Str("source", sourceName).Msg("failed to process archive entry")
 			continue
 		}
 
-		// Call progress callback if provided
-		if progressCallback != nil {
-			progressCallback(header.Name, records)
+		var records []SummaryRow
+		p.mu.Lock()
+		for marketID := range markets {
+			records = append(records, p.finalizeMarket(marketID)...)
 		}
-	}
+		if progress == nil {
+			if err := p.emitRows(records); err != nil {
+				p.mu.Unlock()
+				return err
+			}
+		}
+		p.mu.Unlock()
 
-	return nil
+		if progress != nil {
+			progress(header.Name, records)
+		}
+	}
 }
 
-// parseS3Path parses an S3 path into bucket and key
-func parseS3Path(s3Path string) (bucket, key string, err error) {
-	if !strings.HasPrefix(s3Path, "s3://") {
-		return "", "", fmt.Errorf("invalid S3 path: %s", s3Path)
-	}
+// isRemotePath reports whether a path is in object storage rather than on local disk
+func isRemotePath(path string) bool {
+	return betfair.IsStorageURL(path)
+}
 
-	path := strings.TrimPrefix(s3Path, "s3://")
-	parts := strings.SplitN(path, "/", 2)
-	if len(parts) < 1 {
-		return "", "", fmt.Errorf("invalid S3 path format: %s", s3Path)
+// objectStorage returns the bucket a remote path such as s3://bucket/key, gs://bucket/key or
+// az://container/key lives in, together with the key within it. Buckets are opened through the
+// recorder's storage backends, so they share its credentials, endpoint settings and retries.
+func (p *MarketDataProcessor) objectStorage(remotePath string) (betfair.ObjectStorage, string, error) {
+	location, err := betfair.ParseStorageLocation(remotePath)
+	if err != nil || !isRemotePath(remotePath) {
+		return nil, "", fmt.Errorf("invalid storage path: %s", remotePath)
 	}
 
-	bucket = parts[0]
-	if len(parts) > 1 {
-		key = parts[1]
+	if p.parent != nil {
+		return p.parent.objectStorage(remotePath)
 	}
 
-	return bucket, key, nil
-}
+	p.storageMu.Lock()
+	defer p.storageMu.Unlock()
 
-// processS3File processes a single S3 file
-func (p *MarketDataProcessor) processS3File(s3Path string) error {
-	if p.S3Client == nil {
-		return fmt.Errorf("S3 client not initialized")
+	bucketURL := location.ObjectURL("")
+	if storage, ok := p.storages[bucketURL]; ok {
+		return storage, location.Path, nil
 	}
 
-	bucket, key, err := parseS3Path(s3Path)
-	if err != nil {
-		return err
+	var storage betfair.ObjectStorage
+	if location.Scheme == "s3" && p.S3Client != nil {
+		storage = betfair.NewS3StorageWithClient(p.S3Client, location.Bucket, "")
+	} else if storage, err = betfair.NewObjectStorage(context.Background(), bucketURL, ""); err != nil {
+		return nil, "", fmt.Errorf("failed to open %s: %w", bucketURL, err)
 	}
 
-	ctx := context.Background()
-	result, err := p.S3Client.GetObject(ctx, &s3.GetObjectInput{
-		Bucket: &bucket,
-		Key:    &key,
-	})
-	if err != nil {
-		return fmt.Errorf("failed to get S3 object %s: %w", s3Path, err)
+	if p.storages == nil {
+		p.storages = make(map[string]betfair.ObjectStorage)
 	}
-	defer result.Body.Close()
-
-	var reader io.Reader = result.Body
+	p.storages[bucketURL] = storage
+	return storage, location.Path, nil
+}
 
-	// Handle bz2 compression
-	if strings.HasSuffix(key, ".bz2") {
-		reader = bzip2.NewReader(result.Body)
+// processRemoteFile processes a single S3, GCS or Azure file
+func (p *MarketDataProcessor) processRemoteFile(remotePath string) error {
+	body, prefetched, err := p.prefetch.open(remotePath)
+	if !prefetched {
+		body, err = p.openRemote(context.Background(), remotePath)
 	}
+	if err != nil {
+		return fmt.Errorf("failed to get object %s: %w", remotePath, err)
+	}
+	defer body.Close()
 
-	return p.processReader(reader, s3Path)
+	return p.processSource(body, remotePath)
 }
 
-// processS3Path processes an S3 path (can be a file or a "directory" prefix)
-func (p *MarketDataProcessor) processS3Path(s3Path string) error {
-	if p.S3Client == nil {
-		return fmt.Errorf("S3 client not initialized")
-	}
-
-	bucket, prefix, err := parseS3Path(s3Path)
+// listRemotePath lists the supported files of an S3, GCS or Azure path (can be a file or a "directory" prefix)
+func (p *MarketDataProcessor) listRemotePath(remotePath string) ([]string, error) {
+	storage, prefix, err := p.objectStorage(remotePath)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Add trailing slash to prefix if not empty and doesn't have one
@@ -1450,45 +1942,33 @@ func (p *MarketDataProcessor) processS3Path(s3Path string) error {
 		prefix = prefix + "/"
 	}
 
-	// List objects with the prefix
-	ctx := context.Background()
-	var supportedFiles []string
-
-	paginator := s3.NewListObjectsV2Paginator(p.S3Client, &s3.ListObjectsV2Input{
-		Bucket: &bucket,
-		Prefix: &prefix,
-	})
+	objects, err := storage.List(context.Background(), prefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list objects: %w", err)
+	}
 
-	for paginator.HasMorePages() {
-		page, err := paginator.NextPage(ctx)
-		if err != nil {
-			return fmt.Errorf("failed to list S3 objects: %w", err)
+	location, _ := betfair.ParseStorageLocation(remotePath)
+	var supportedFiles []string
+	for _, object := range objects {
+		// Skip directories
+		if strings.HasSuffix(object.Key, "/") {
+			continue
 		}
 
-		for _, obj := range page.Contents {
-			if obj.Key == nil {
-				continue
-			}
-
-			key := *obj.Key
-			// Skip directories
-			if strings.HasSuffix(key, "/") {
-				continue
-			}
-
-			// Check if supported file type
-			if p.isSupportedFile(key) {
-				fullPath := fmt.Sprintf("s3://%s/%s", bucket, key)
-				supportedFiles = append(supportedFiles, fullPath)
-			}
+		// Check if supported file type
+		if p.isSupportedFile(object.Key) {
+			path := location.ObjectURL(object.Key)
+			supportedFiles = append(supportedFiles, path)
+			p.noteSource(path, sourceVersion{version: object.MD5, etag: object.ETag, size: object.Size})
 		}
 	}
+	supportedFiles = p.filterSources(supportedFiles)
 
 	if len(supportedFiles) == 0 {
-		log.Printf("Warning: no supported files found in %s", s3Path)
-		return nil
+		p.logger.Warn().Str("path", remotePath).Msg("no supported files found")
+		return nil, nil
 	}
 
-	log.Printf("Found %d files to process in %s", len(supportedFiles), s3Path)
-	return p.processFilesParallel(supportedFiles)
+	p.logger.Info().Int("files", len(supportedFiles)).Str("path", remotePath).Msg("found files to process")
+	return supportedFiles, nil
 }
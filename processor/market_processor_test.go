@@ -1,9 +1,36 @@
 package processor
 
 import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/md5"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
 	"time"
+
+	"github.com/dsnet/compress/bzip2"
+	betfair "github.com/felixmccuaig/betfair-go"
+	"github.com/klauspost/compress/zstd"
+	"github.com/parquet-go/parquet-go"
+	"github.com/rs/zerolog"
 )
 
 func TestNewMarketDataProcessor(t *testing.T) {
@@ -106,7 +133,7 @@ func TestExtractGreyhoundName(t *testing.T) {
 	}
 }
 
-func TestIsGreyhoundWinMarket(t *testing.T) {
+func TestIsTargetMarket(t *testing.T) {
 	processor := NewMarketDataProcessor("", 0, 1)
 
 	tests := []struct {
@@ -161,7 +188,7 @@ func TestIsGreyhoundWinMarket(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := processor.isGreyhoundWinMarket(tt.marketDef)
+			result := processor.isTargetMarket(decodeJSON[MarketDefinition](t, tt.marketDef))
 			if result != tt.expected {
 				t.Errorf("Expected %v, got %v", tt.expected, result)
 			}
@@ -169,56 +196,71 @@ func TestIsGreyhoundWinMarket(t *testing.T) {
 	}
 }
 
-func TestGetPrice30sBeforeStart(t *testing.T) {
-	processor := NewMarketDataProcessor("", 0, 1)
+// decodeJSON round-trips a generic message through JSON into a stream type
+func decodeJSON[T any](t *testing.T, value interface{}) *T {
+	data, err := json.Marshal(value)
+	if err != nil {
+		t.Fatalf("encode message: %v", err)
+	}
+	var decoded T
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("decode message: %v", err)
+	}
+	return &decoded
+}
 
+func TestPriceTracker(t *testing.T) {
 	marketTime := time.Date(2025, 9, 29, 12, 0, 0, 0, time.UTC)
 
 	// 30 seconds before market time
 	targetTime := marketTime.Add(-30 * time.Second).UnixMilli()
 
 	tests := []struct {
-		name        string
-		updates     []RunnerUpdate
+		name          string
+		updates       []RunnerUpdate
 		expectedPrice float64
-		expectedHas bool
+		expectedHas   bool
 	}{
 		{
-			name: "Exact match 30s before",
-			updates: []RunnerUpdate{
-				{
-					Timestamp: targetTime,
-					LTP:       2.5,
-					HasLTP:    true,
-				},
-			},
+			name:          "Exact match 30s before",
+			updates:       []RunnerUpdate{{Timestamp: targetTime, LTP: 2.5, HasLTP: true}},
 			expectedPrice: 2.5,
 			expectedHas:   true,
 		},
 		{
-			name: "Use BATB when no LTP",
+			name:          "Use BATB when no LTP",
+			updates:       []RunnerUpdate{{Timestamp: targetTime, BATB: [][]float64{{3.0, 100.0}}}},
+			expectedPrice: 3.0,
+			expectedHas:   true,
+		},
+		{
+			name: "Latest before the target beats a closer one after",
 			updates: []RunnerUpdate{
-				{
-					Timestamp: targetTime,
-					BATB:      [][]float64{{3.0, 100.0}},
-				},
+				{Timestamp: targetTime - 10000, LTP: 2.0, HasLTP: true},
+				{Timestamp: targetTime - 5000, LTP: 2.2, HasLTP: true},
+				{Timestamp: targetTime + 1000, LTP: 2.4, HasLTP: true},
 			},
-			expectedPrice: 3.0,
+			expectedPrice: 2.2,
 			expectedHas:   true,
 		},
 		{
-			name: "No price data",
+			name: "First after the target when none before",
 			updates: []RunnerUpdate{
-				{
-					Timestamp: targetTime,
-				},
+				{Timestamp: targetTime + 5000, LTP: 2.6, HasLTP: true},
+				{Timestamp: targetTime + 1000, LTP: 2.8, HasLTP: true},
 			},
+			expectedPrice: 2.8,
+			expectedHas:   true,
+		},
+		{
+			name:          "No price data",
+			updates:       []RunnerUpdate{{Timestamp: targetTime}},
 			expectedPrice: 0,
 			expectedHas:   false,
 		},
 		{
-			name:        "No updates",
-			updates:     []RunnerUpdate{},
+			name:          "No updates",
+			updates:       []RunnerUpdate{},
 			expectedPrice: 0,
 			expectedHas:   false,
 		},
@@ -226,7 +268,13 @@ func TestGetPrice30sBeforeStart(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			price, has := processor.getPrice30sBeforeStart(tt.updates, marketTime)
+			var tracker priceTracker
+			for _, update := range tt.updates {
+				if price, ok := updatePrice(update); ok {
+					tracker.observe(update.Timestamp, price, targetTime)
+				}
+			}
+			price, has := tracker.price()
 			if price != tt.expectedPrice {
 				t.Errorf("Expected price %f, got %f", tt.expectedPrice, price)
 			}
@@ -235,6 +283,14 @@ func TestGetPrice30sBeforeStart(t *testing.T) {
 			}
 		})
 	}
+
+	// A moved start time discards prices seen for the old target
+	var tracker priceTracker
+	tracker.observe(targetTime, 2.5, targetTime)
+	tracker.observe(targetTime+60000, 4.0, targetTime+60000)
+	if price, _ := tracker.price(); price != 4.0 {
+		t.Errorf("Expected the price for the new target, got %f", price)
+	}
 }
 
 func TestProcessMCMMessage(t *testing.T) {
@@ -266,7 +322,7 @@ func TestProcessMCMMessage(t *testing.T) {
 		},
 	}
 
-	processor.processMCMMessage(mcmData)
+	processor.processMCMMessage(decodeJSON[MCMMessage](t, mcmData))
 
 	if len(processor.MarketStates) != 1 {
 		t.Errorf("Expected 1 market state, got %d", len(processor.MarketStates))
@@ -378,7 +434,6 @@ func TestFinalizeMarket(t *testing.T) {
 				LatestLTP: 2.4,
 				MaxTV:     1000.0,
 				Status:    "WINNER",
-				Updates:   []RunnerUpdate{},
 			},
 			456: {
 				Name:      "Test Loser",
@@ -386,7 +441,6 @@ func TestFinalizeMarket(t *testing.T) {
 				LatestLTP: 4.8,
 				MaxTV:     500.0,
 				Status:    "LOSER",
-				Updates:   []RunnerUpdate{},
 			},
 		},
 	}
@@ -429,67 +483,6 @@ func TestFinalizeMarket(t *testing.T) {
 	}
 }
 
-func TestConvertToFloat64Array(t *testing.T) {
-	tests := []struct {
-		name     string
-		input    []interface{}
-		expected [][]float64
-	}{
-		{
-			name: "Valid price data",
-			input: []interface{}{
-				[]interface{}{float64(2.5), float64(100.0)},
-				[]interface{}{float64(2.4), float64(50.0)},
-			},
-			expected: [][]float64{
-				{2.5, 100.0},
-				{2.4, 50.0},
-			},
-		},
-		{
-			name:     "Empty input",
-			input:    []interface{}{},
-			expected: [][]float64{},
-		},
-		{
-			name: "Mixed invalid data",
-			input: []interface{}{
-				[]interface{}{float64(2.5), float64(100.0)},
-				"invalid",
-				[]interface{}{float64(2.4), "invalid"},
-			},
-			expected: [][]float64{
-				{2.5, 100.0},
-				{2.4},
-			},
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			result := convertToFloat64Array(tt.input)
-
-			if len(result) != len(tt.expected) {
-				t.Errorf("Expected %d arrays, got %d", len(tt.expected), len(result))
-				return
-			}
-
-			for i, expectedArray := range tt.expected {
-				if len(result[i]) != len(expectedArray) {
-					t.Errorf("Array %d: expected length %d, got %d", i, len(expectedArray), len(result[i]))
-					continue
-				}
-
-				for j, expectedVal := range expectedArray {
-					if result[i][j] != expectedVal {
-						t.Errorf("Array %d, index %d: expected %f, got %f", i, j, expectedVal, result[i][j])
-					}
-				}
-			}
-		})
-	}
-}
-
 func TestFormatFloat(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -824,7 +817,7 @@ func TestVenueExtractionPriority(t *testing.T) {
 			processor.MarketStates = make(map[string]*MarketState)
 
 			// Process the message
-			processor.processMCMMessage(mcmData)
+			processor.processMCMMessage(decodeJSON[MCMMessage](t, mcmData))
 
 			// Verify venue extraction
 			market, exists := processor.MarketStates["1.test"]
@@ -842,3 +835,2181 @@ func TestVenueExtractionPriority(t *testing.T) {
 		})
 	}
 }
+
+func TestProcessPathFromGCS(t *testing.T) {
+	market := strings.Join([]string{
+		`{"op":"mcm","pt":1633024800000,"mc":[{"id":"1.test","marketDefinition":{"eventTypeId":"4339","marketType":"WIN","bettingType":"ODDS","eventName":"Test Track R1","marketTime":"2025-09-29T12:00:00Z","runners":[{"id":123,"name":"1. Test Dog","bsp":2.5,"status":"ACTIVE"}]}}]}`,
+		`{"op":"mcm","pt":1633024801000,"mc":[{"id":"1.test","rc":[{"id":123,"ltp":2.4,"tv":100.5}]}]}`,
+	}, "\n") + "\n"
+	objects := map[string]string{
+		"PRO/2025/Sep/29/1/1.test.json": market,
+		"PRO/2025/Sep/29/1/notes.txt":   "not market data",
+	}
+
+	var mu sync.Mutex
+	uploads := make(map[string]string)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/upload/storage/v1/b/bucket/o":
+			data, _ := io.ReadAll(r.Body)
+			uploads[r.URL.Query().Get("name")] = string(data)
+			w.Write([]byte(`{}`))
+		case r.URL.Path == "/storage/v1/b/bucket/o":
+			var items []map[string]string
+			for name := range objects {
+				if strings.HasPrefix(name, r.URL.Query().Get("prefix")) {
+					items = append(items, map[string]string{"name": name})
+				}
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"items": items})
+		case strings.HasPrefix(r.URL.Path, "/storage/v1/b/bucket/o/") && r.URL.Query().Get("alt") == "media":
+			w.Write([]byte(objects[strings.TrimPrefix(r.URL.Path, "/storage/v1/b/bucket/o/")]))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	t.Setenv("STORAGE_EMULATOR_HOST", server.URL)
+
+	processor := NewMarketDataProcessor("gs://bucket/summary.csv", 0, 1)
+	if err := processor.ProcessPath("gs://bucket/PRO/2025"); err != nil {
+		t.Fatalf("ProcessPath failed: %v", err)
+	}
+	if _, exists := processor.MarketStates["1.test"]; !exists {
+		t.Fatalf("Expected market read from GCS, got %d markets", len(processor.MarketStates))
+	}
+
+	rows := processor.finalizeMarket("1.test")
+	if err := processor.saveSingleCSV("gs://bucket/summary.csv", rows); err != nil {
+		t.Fatalf("saveSingleCSV failed: %v", err)
+	}
+	if !strings.Contains(uploads["summary.csv"], "1.test") {
+		t.Errorf("Expected summary uploaded to GCS, got %v", uploads)
+	}
+}
+
+func TestProcessPathFromS3CompatibleEndpoint(t *testing.T) {
+	market := `{"op":"mcm","pt":1633024800000,"mc":[{"id":"1.minio","marketDefinition":{"eventTypeId":"4339","marketType":"WIN","bettingType":"ODDS","eventName":"Test Track R1","marketTime":"2025-09-29T12:00:00Z","runners":[{"id":123,"name":"1. Test Dog","bsp":2.5,"status":"ACTIVE"}]}}]}` + "\n"
+
+	var mu sync.Mutex
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		paths = append(paths, r.URL.Path)
+		switch {
+		case r.URL.Path == "/markets" && r.URL.Query().Get("list-type") == "2":
+			fmt.Fprintf(w, `<ListBucketResult><Name>markets</Name><Prefix>%s</Prefix><IsTruncated>false</IsTruncated>`+
+				`<Contents><Key>PRO/2025/Sep/29/1/1.minio.json</Key><Size>%d</Size></Contents></ListBucketResult>`, r.URL.Query().Get("prefix"), len(market))
+		case r.URL.Path == "/markets/PRO/2025/Sep/29/1/1.minio.json":
+			w.Write([]byte(market))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	t.Setenv("AWS_ACCESS_KEY_ID", "minio")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "minio-secret")
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(t.TempDir(), "config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(t.TempDir(), "credentials"))
+	t.Setenv("S3_ENDPOINT", server.URL)
+	t.Setenv("S3_FORCE_PATH_STYLE", "true")
+
+	processor := NewMarketDataProcessor(t.TempDir(), 0, 1)
+	if err := processor.ProcessPath("s3://markets/PRO/2025"); err != nil {
+		t.Fatalf("ProcessPath failed: %v", err)
+	}
+	if _, exists := processor.MarketStates["1.minio"]; !exists {
+		t.Fatalf("Expected market read from the S3-compatible endpoint, got requests %v", paths)
+	}
+}
+
+// historicMarket is a minimal recorded greyhound WIN market as found in Betfair historical data
+func historicMarket(marketID string) string {
+	return `{"op":"mcm","pt":1633024800000,"mc":[{"id":"` + marketID + `","marketDefinition":{"eventTypeId":"4339","marketType":"WIN","bettingType":"ODDS","eventName":"Test Track R1","marketTime":"2025-09-29T12:00:00Z","runners":[{"id":123,"name":"1. Test Dog","bsp":2.5,"status":"ACTIVE"}]}}]}` + "\n" +
+		`{"op":"mcm","pt":1633024801000,"mc":[{"id":"` + marketID + `","rc":[{"id":123,"ltp":2.4,"tv":100.5}]}]}` + "\n"
+}
+
+// buildHistoricTar lays markets out the way Betfair's historical data downloads do
+func buildHistoricTar(t *testing.T) []byte {
+	var compressed bytes.Buffer
+	writer, err := bzip2.NewWriter(&compressed, nil)
+	if err != nil {
+		t.Fatalf("create bzip2 writer: %v", err)
+	}
+	writer.Write([]byte(historicMarket("1.101")))
+	writer.Close()
+
+	entries := []struct {
+		name string
+		data []byte
+	}{
+		{name: "data/xds/historic/PRO/2025/Sep/29/34773181/1.101.bz2", data: compressed.Bytes()},
+		{name: "data/xds/historic/BASIC/2025/Sep/29/34773182/1.102", data: []byte(historicMarket("1.102"))},
+		{name: "data/xds/historic/ADVANCED/2025/Sep/29/34773183/1.103.json", data: []byte(historicMarket("1.103"))},
+		{name: "README.txt", data: []byte("not market data")},
+	}
+
+	var archive bytes.Buffer
+	tw := tar.NewWriter(&archive)
+	tw.WriteHeader(&tar.Header{Name: "data/xds/historic/", Typeflag: tar.TypeDir, Mode: 0755})
+	for _, entry := range entries {
+		if err := tw.WriteHeader(&tar.Header{Name: entry.name, Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(entry.data))}); err != nil {
+			t.Fatalf("write tar header: %v", err)
+		}
+		tw.Write(entry.data)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("close tar: %v", err)
+	}
+	return archive.Bytes()
+}
+
+func TestProcessTarFileStreamsEntries(t *testing.T) {
+	archive := buildHistoricTar(t)
+
+	results := make(map[string][]SummaryRow)
+	err := ProcessTarFile(bytes.NewReader(archive), func(filename string, records []SummaryRow) {
+		results[filename] = records
+	})
+	if err != nil {
+		t.Fatalf("ProcessTarFile failed: %v", err)
+	}
+
+	expected := map[string]string{
+		"data/xds/historic/PRO/2025/Sep/29/34773181/1.101.bz2":       "1.101",
+		"data/xds/historic/BASIC/2025/Sep/29/34773182/1.102":         "1.102",
+		"data/xds/historic/ADVANCED/2025/Sep/29/34773183/1.103.json": "1.103",
+	}
+	if len(results) != len(expected) {
+		t.Fatalf("Expected %d market entries, got %v", len(expected), results)
+	}
+	for entry, marketID := range expected {
+		records := results[entry]
+		if len(records) != 1 || records[0].MarketID != marketID {
+			t.Errorf("Expected entry %s to summarise market %s, got %+v", entry, marketID, records)
+		}
+	}
+}
+
+func TestProcessPathLocalTar(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "2025_Sep.tar")
+	if err := os.WriteFile(path, buildHistoricTar(t), 0644); err != nil {
+		t.Fatalf("write tar: %v", err)
+	}
+
+	processor := NewMarketDataProcessor(t.TempDir(), 2, 1)
+	if err := processor.ProcessPath(path); err != nil {
+		t.Fatalf("ProcessPath failed: %v", err)
+	}
+	if len(processor.ProcessedData) != 2 || len(processor.MarketStates) != 0 {
+		t.Errorf("Expected the file limit to stop after 2 finalized markets, got %d rows and %d open markets",
+			len(processor.ProcessedData), len(processor.MarketStates))
+	}
+}
+
+func horseMarketMessage(t *testing.T, marketType string) *MCMMessage {
+	line := `{"op":"mcm","pt":1633024800000,"mc":[{"id":"1.horse","marketDefinition":{"eventTypeId":"7","marketType":"` + marketType + `","bettingType":"ODDS","eventName":"Ascot 5th Oct","marketTime":"2025-10-05T13:30:00Z","runners":[{"id":7,"name":"Frankel","bsp":3.2,"status":"WINNER"}]}}]}`
+	var message MCMMessage
+	if err := json.Unmarshal([]byte(line), &message); err != nil {
+		t.Fatalf("decode message: %v", err)
+	}
+	return &message
+}
+
+func TestHorseRacingProfile(t *testing.T) {
+	greyhounds := NewMarketDataProcessor("", 0, 1)
+	greyhounds.processMCMMessage(horseMarketMessage(t, "WIN"))
+	if len(greyhounds.MarketStates) != 0 {
+		t.Errorf("Expected the greyhound profile to skip horse markets, got %d", len(greyhounds.MarketStates))
+	}
+
+	horses := NewMarketDataProcessorWithConfig(ProcessorConfig{Workers: 1, Profile: "horse-racing"})
+	horses.processMCMMessage(horseMarketMessage(t, "WIN"))
+	rows := horses.finalizeMarket("1.horse")
+	if len(rows) != 1 {
+		t.Fatalf("Expected one summary row, got %d", len(rows))
+	}
+	if rows[0].Venue != "Ascot" || rows[0].GreyhoundName != "Frankel" || !rows[0].Win || rows[0].BSP != 3.2 {
+		t.Errorf("Unexpected horse summary row %+v", rows[0])
+	}
+	if horses.Profile.FilePrefix != "horse_racing_win_markets" {
+		t.Errorf("Expected horse racing file prefix, got %s", horses.Profile.FilePrefix)
+	}
+
+	horses.processMCMMessage(horseMarketMessage(t, "PLACE"))
+	if len(horses.MarketStates) != 0 {
+		t.Errorf("Expected PLACE markets to be skipped by default")
+	}
+}
+
+func TestHorseRacingVenueExtraction(t *testing.T) {
+	processor := NewMarketDataProcessorWithConfig(ProcessorConfig{Workers: 1, Profile: "horses"})
+
+	tests := map[string]string{
+		"Ascot 5th Oct":         "Ascot",
+		"Flem (AUS) 1st Nov":    "Flem",
+		"Kempton (AW) 16th Oct": "Kempton",
+		"Newmarket 22nd Sep":    "Newmarket",
+		"Royal Ascot":           "Royal Ascot",
+	}
+	for eventName, expected := range tests {
+		if venue := processor.extractVenueFromEventName(eventName); venue != expected {
+			t.Errorf("Expected venue %q from %q, got %q", expected, eventName, venue)
+		}
+	}
+}
+
+func TestMarketFilterOverrides(t *testing.T) {
+	processor := NewMarketDataProcessorWithConfig(ProcessorConfig{
+		Workers:     1,
+		Profile:     "horse-racing",
+		MarketTypes: []string{"WIN", "PLACE"},
+	})
+	processor.processMCMMessage(horseMarketMessage(t, "PLACE"))
+	if _, ok := processor.MarketStates["1.horse"]; !ok {
+		t.Errorf("Expected overridden market types to keep PLACE markets")
+	}
+
+	if _, err := ProfileByName("cricket"); err == nil {
+		t.Errorf("Expected unknown profile to be rejected")
+	}
+	fallback := NewMarketDataProcessorWithConfig(ProcessorConfig{Workers: 1, Profile: "cricket"})
+	if fallback.Profile.Name != GreyhoundProfile.Name {
+		t.Errorf("Expected unknown profile to fall back to greyhounds, got %s", fallback.Profile.Name)
+	}
+}
+
+func TestPlaceMarketsAndWinPlaceJoin(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "summary.csv")
+	processor := NewMarketDataProcessorWithConfig(ProcessorConfig{
+		OutputPath:   output,
+		OutputFormat: OutputFormatCSV,
+		Workers:      1,
+		Profile:      "horse-racing",
+		JoinWinPlace: true,
+	})
+
+	lines := []string{
+		`{"op":"mcm","pt":1633024800000,"mc":[{"id":"1.win","marketDefinition":{"eventTypeId":"7","eventId":"99","marketType":"WIN","bettingType":"ODDS","numberOfWinners":1,"eventName":"Ascot 5th Oct","marketTime":"2025-10-05T13:30:00Z","runners":[{"id":1,"name":"Frankel","bsp":3.2,"status":"LOSER"},{"id":2,"name":"Enable","bsp":2.1,"status":"WINNER"}]}}]}`,
+		`{"op":"mcm","pt":1633024800000,"mc":[{"id":"1.place","marketDefinition":{"eventTypeId":"7","eventId":"99","marketType":"PLACE","bettingType":"ODDS","numberOfWinners":2,"eventName":"Ascot 5th Oct","marketTime":"2025-10-05T13:30:00Z","runners":[{"id":1,"name":"Frankel","bsp":1.4,"status":"WINNER"},{"id":2,"name":"Enable","bsp":1.2,"status":"WINNER"}]}}]}`,
+	}
+	for _, line := range lines {
+		var message MCMMessage
+		if err := json.Unmarshal([]byte(line), &message); err != nil {
+			t.Fatalf("decode message: %v", err)
+		}
+		processor.processMCMMessage(&message)
+	}
+
+	place := processor.finalizeMarket("1.place")
+	for _, row := range place {
+		if !row.Placed || row.Win || row.NumberOfWinners != 2 || row.MarketType != "PLACE" {
+			t.Errorf("Expected placed runner in a two-place market, got %+v", row)
+		}
+	}
+	processor.ProcessedData = append(processor.ProcessedData, place...)
+
+	if err := processor.FinalizeProcessing(); err != nil {
+		t.Fatalf("FinalizeProcessing failed: %v", err)
+	}
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("read output: %v", err)
+	}
+	lines = strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected a header and one joined row per selection, got:\n%s", data)
+	}
+	if !strings.Contains(lines[0], ",event_type_id,market_type,number_of_winners,placed,place_market_id,place_bsp,place_ltp,number_of_places") {
+		t.Errorf("Unexpected header %s", lines[0])
+	}
+	for _, line := range lines[1:] {
+		if !strings.Contains(line, ",7,WIN,1,true,1.place,") {
+			t.Errorf("Expected WIN row joined with its place result, got %s", line)
+		}
+	}
+	if !strings.Contains(string(data), "Enable,2025-10-05T13:30:00Z,2.1,,,0,,,2025,10,5,true,7,WIN,1,true,1.place,1.2,,2") {
+		t.Errorf("Expected the winner's place BSP on its row, got:\n%s", data)
+	}
+}
+
+func TestGenericProfilePassesThroughAnySport(t *testing.T) {
+	processor := NewMarketDataProcessorWithConfig(ProcessorConfig{Workers: 1, Profile: "generic"})
+
+	line := `{"op":"mcm","pt":1633024800000,"mc":[{"id":"1.football","marketDefinition":{"eventTypeId":"1","eventId":"55","marketType":"ASIAN_HANDICAP","bettingType":"ASIAN_HANDICAP_DOUBLE_LINE","eventName":"Arsenal v Chelsea (Live)","marketTime":"2025-10-05T15:00:00Z","runners":[{"id":47999,"name":"1. Arsenal","status":"WINNER"}]}}]}`
+	var message MCMMessage
+	if err := json.Unmarshal([]byte(line), &message); err != nil {
+		t.Fatalf("decode message: %v", err)
+	}
+	processor.processMCMMessage(&message)
+
+	rows := processor.finalizeMarket("1.football")
+	if len(rows) != 1 {
+		t.Fatalf("Expected one summary row, got %d", len(rows))
+	}
+	row := rows[0]
+	if row.EventTypeID != "1" || row.MarketType != "ASIAN_HANDICAP" {
+		t.Errorf("Expected event and market type to be carried through, got %+v", row)
+	}
+	if row.GreyhoundName != "1. Arsenal" || row.EventName != "Arsenal v Chelsea (Live)" || row.Venue != "" {
+		t.Errorf("Expected raw names without venue parsing, got %+v", row)
+	}
+	if processor.Profile.FilePrefix != "markets" {
+		t.Errorf("Expected generic file prefix, got %s", processor.Profile.FilePrefix)
+	}
+}
+
+func TestVolumeProfile(t *testing.T) {
+	off := time.Date(2025, 10, 5, 13, 30, 0, 0, time.UTC)
+	at := func(before time.Duration) int64 { return off.Add(-before).UnixMilli() }
+
+	updates := []RunnerUpdate{
+		{Timestamp: at(10 * time.Minute), TRD: [][]float64{{3.0, 100}}},
+		{Timestamp: at(2 * time.Minute), TRD: [][]float64{{3.0, 150}, {3.5, 50}}},
+		{Timestamp: at(30 * time.Second), LTP: 3.2, HasLTP: true},
+		{Timestamp: at(20 * time.Second), TRD: [][]float64{{3.5, 100}}},
+		{Timestamp: at(-time.Minute), TRD: [][]float64{{2.0, 100}}},
+	}
+
+	var book runnerBook
+	var tracker volumeTracker
+	for _, update := range updates {
+		book.apply(update)
+		if len(update.TRD) > 0 {
+			tracker.observe(update.Timestamp, book.tradedVolume(), off.UnixMilli())
+		}
+	}
+
+	profile := tracker.profile(&book, off.UnixMilli())
+	if !profile.HasVolume {
+		t.Fatal("Expected a volume profile")
+	}
+	// Final ladder: 150@3.0, 100@3.5, 100@2.0
+	if math.Abs(profile.VWAP-(150*3.0+100*3.5+100*2.0)/350) > 1e-9 {
+		t.Errorf("Unexpected VWAP %f", profile.VWAP)
+	}
+	if profile.VolumeLast60s != 50 {
+		t.Errorf("Expected 50 matched in the last minute, got %f", profile.VolumeLast60s)
+	}
+	if profile.VolumeLast5m != 150 {
+		t.Errorf("Expected 150 matched in the last five minutes, got %f", profile.VolumeLast5m)
+	}
+	if math.Abs(profile.InPlayVolumePct-100.0/350*100) > 1e-9 {
+		t.Errorf("Unexpected in-play percentage %f", profile.InPlayVolumePct)
+	}
+
+	var empty volumeTracker
+	if profile := empty.profile(&runnerBook{}, off.UnixMilli()); profile.HasVolume {
+		t.Errorf("Expected no profile without trades, got %+v", profile)
+	}
+}
+
+func TestVolumeTrackerKeepsBoundedHistory(t *testing.T) {
+	off := time.Date(2025, 10, 5, 13, 30, 0, 0, time.UTC).UnixMilli()
+	var tracker volumeTracker
+	// A trade every second for an hour before the off
+	for i := int64(3600); i > 0; i-- {
+		tracker.observe(off-i*1000, float64(3600-i), off)
+	}
+	if len(tracker.history) > 302 {
+		t.Errorf("Expected at most five minutes of samples, got %d", len(tracker.history))
+	}
+	volumes := tracker.at(off)
+	if volumes.atOff-volumes.at60s != 59 || volumes.atOff-volumes.at5m != 299 {
+		t.Errorf("Unexpected volumes at the off %+v", volumes)
+	}
+}
+
+func TestProcessReaderFinalizesClosedMarkets(t *testing.T) {
+	processor := NewMarketDataProcessor("", 0, 1)
+	stream := strings.Join([]string{
+		`{"op":"mcm","pt":1759670400000,"mc":[{"id":"1.closed","marketDefinition":{"eventTypeId":"4339","marketType":"WIN","bettingType":"ODDS","eventName":"Romford (GB) 5th Oct","marketTime":"2025-10-05T13:30:00Z","status":"OPEN","runners":[{"id":1,"name":"1. Swift","status":"ACTIVE"}]}}]}`,
+		`{"op":"mcm","pt":1759670400000,"mc":[{"id":"1.open","marketDefinition":{"eventTypeId":"4339","marketType":"WIN","bettingType":"ODDS","eventName":"Romford (GB) 5th Oct","marketTime":"2025-10-05T13:45:00Z","status":"OPEN","runners":[{"id":2,"name":"2. Dash","status":"ACTIVE"}]}}]}`,
+		`{"op":"mcm","pt":1759671000000,"mc":[{"id":"1.closed","rc":[{"id":1,"ltp":3.5}]}]}`,
+		`{"op":"mcm","pt":1759671100000,"mc":[{"id":"1.closed","marketDefinition":{"status":"CLOSED","runners":[{"id":1,"name":"1. Swift","status":"WINNER","bsp":3.4}]}}]}`,
+	}, "\n")
+
+	if err := processor.processReader(strings.NewReader(stream), "stream"); err != nil {
+		t.Fatalf("processReader failed: %v", err)
+	}
+
+	if _, ok := processor.MarketStates["1.closed"]; ok {
+		t.Error("Expected the closed market's state to be released")
+	}
+	if _, ok := processor.MarketStates["1.open"]; !ok {
+		t.Error("Expected the open market to be kept until finalization")
+	}
+	if len(processor.ProcessedData) != 1 || processor.ProcessedData[0].MarketID != "1.closed" {
+		t.Fatalf("Expected the closed market to be summarised, got %+v", processor.ProcessedData)
+	}
+	if row := processor.ProcessedData[0]; !row.Win || row.Price30sBeforeStart != 3.5 {
+		t.Errorf("Unexpected summary row %+v", row)
+	}
+}
+
+func TestVolumeProfileUsesInPlayTime(t *testing.T) {
+	processor := NewMarketDataProcessor("", 0, 1)
+	lines := []string{
+		`{"op":"mcm","pt":1759670400000,"mc":[{"id":"1.vol","marketDefinition":{"eventTypeId":"4339","marketType":"WIN","bettingType":"ODDS","eventName":"Romford (GB) 5th Oct","marketTime":"2025-10-05T13:30:00Z","inPlay":false,"runners":[{"id":1,"name":"1. Swift","status":"ACTIVE"}]}}]}`,
+		`{"op":"mcm","pt":1759671010000,"mc":[{"id":"1.vol","rc":[{"id":1,"trd":[[4.0,60]]}]}]}`,
+		`{"op":"mcm","pt":1759671060000,"mc":[{"id":"1.vol","marketDefinition":{"inPlay":true}}]}`,
+		`{"op":"mcm","pt":1759671070000,"mc":[{"id":"1.vol","rc":[{"id":1,"trd":[[4.0,60],[2.0,40]]}]}]}`,
+	}
+	for _, line := range lines {
+		var message MCMMessage
+		if err := json.Unmarshal([]byte(line), &message); err != nil {
+			t.Fatalf("decode message: %v", err)
+		}
+		processor.processMCMMessage(&message)
+	}
+
+	rows := processor.finalizeMarket("1.vol")
+	if len(rows) != 1 {
+		t.Fatalf("Expected one summary row, got %d", len(rows))
+	}
+	// The race went in-play a minute late, so the trade at 13:30:10 was still before the off
+	if rows[0].VolumeLast60s != 60 || rows[0].InPlayVolumePct != 40 || rows[0].VWAP != 3.2 {
+		t.Errorf("Unexpected volume profile %+v", rows[0])
+	}
+}
+
+func TestTicksOutputMode(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "ticks.parquet")
+	processor := NewMarketDataProcessorWithConfig(ProcessorConfig{OutputPath: output, Workers: 1, Mode: OutputModeTicks})
+
+	lines := []string{
+		`{"op":"mcm","pt":1759670400000,"mc":[{"id":"1.ticks","marketDefinition":{"eventTypeId":"4339","marketType":"WIN","bettingType":"ODDS","eventName":"Romford (GB) 5th Oct","marketTime":"2025-10-05T13:30:00Z","runners":[{"id":1,"name":"1. Swift","status":"ACTIVE"}]}}]}`,
+		`{"op":"mcm","pt":1759670401000,"mc":[{"id":"1.ticks","rc":[{"id":1,"batb":[[0,3.0,20],[1,2.9,5]],"batl":[[0,3.2,15]]}]}]}`,
+		`{"op":"mcm","pt":1759670402000,"mc":[{"id":"1.ticks","rc":[{"id":1,"ltp":3.1,"trd":[[3.1,12]],"batb":[[0,3.05,8]]}]}]}`,
+		`{"op":"mcm","pt":1759670403000,"mc":[{"id":"1.ticks","rc":[{"id":1,"atl":[[3.3,4]],"batl":[[0,3.2,0]]}]}]}`,
+	}
+	for _, line := range lines {
+		var message MCMMessage
+		if err := json.Unmarshal([]byte(line), &message); err != nil {
+			t.Fatalf("decode message: %v", err)
+		}
+		processor.processMCMMessage(&message)
+	}
+
+	if err := processor.FinalizeProcessing(); err != nil {
+		t.Fatalf("FinalizeProcessing failed: %v", err)
+	}
+	ticks, err := parquet.ReadFile[TickRow](output)
+	if err != nil {
+		t.Fatalf("read ticks: %v", err)
+	}
+	if len(ticks) != 3 {
+		t.Fatalf("Expected one tick per runner update, got %d", len(ticks))
+	}
+
+	first, second, third := ticks[0], ticks[1], ticks[2]
+	if first.BackPrice != 3.0 || first.BackSize != 20 || first.LayPrice != 3.2 || first.LaySize != 15 || first.LTP != 0 {
+		t.Errorf("Unexpected first tick %+v", first)
+	}
+	if second.LTP != 3.1 || second.TradedVolume != 12 || second.BackPrice != 3.05 || !second.PublishTime.Equal(time.UnixMilli(1759670402000)) {
+		t.Errorf("Unexpected second tick %+v", second)
+	}
+	// The best-available lay was withdrawn, so the full ladder supplies the best lay
+	if third.LayPrice != 3.3 || third.LaySize != 4 || third.LTP != 3.1 {
+		t.Errorf("Unexpected third tick %+v", third)
+	}
+}
+
+func TestLadderSnapshots(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "ladders.parquet")
+	processor := NewMarketDataProcessorWithConfig(ProcessorConfig{
+		OutputPath:     output,
+		Workers:        1,
+		Mode:           OutputModeLadder,
+		LadderDepth:    2,
+		SnapshotWindow: 5 * time.Second,
+	})
+
+	// The market is scheduled off at 13:30:00 and turns in-play at 13:30:02
+	lines := []string{
+		`{"op":"mcm","pt":1759670400000,"mc":[{"id":"1.ladder","marketDefinition":{"eventTypeId":"4339","marketType":"WIN","bettingType":"ODDS","eventName":"Romford (GB) 5th Oct","marketTime":"2025-10-05T13:30:00Z","runners":[{"id":2,"name":"2. Bolt","status":"ACTIVE"},{"id":1,"name":"1. Swift","status":"ACTIVE"}]}}]}`,
+		`{"op":"mcm","pt":1759670996500,"mc":[{"id":"1.ladder","rc":[{"id":1,"atb":[[3.0,20],[2.9,5],[2.8,1]],"atl":[[3.2,15]]}]}]}`,
+		`{"op":"mcm","pt":1759670998000,"mc":[{"id":"1.ladder","rc":[{"id":1,"ltp":3.1,"trd":[[3.1,12]],"atb":[[3.0,0]]}]}]}`,
+		`{"op":"mcm","pt":1759671002000,"mc":[{"id":"1.ladder","marketDefinition":{"inPlay":true}}]}`,
+		`{"op":"mcm","pt":1759671010000,"mc":[{"id":"1.ladder","rc":[{"id":1,"ltp":1.5}]}]}`,
+	}
+	for _, line := range lines {
+		var message MCMMessage
+		if err := json.Unmarshal([]byte(line), &message); err != nil {
+			t.Fatalf("decode message: %v", err)
+		}
+		processor.processMCMMessage(&message)
+	}
+
+	if err := processor.FinalizeProcessing(); err != nil {
+		t.Fatalf("FinalizeProcessing failed: %v", err)
+	}
+	snapshots, err := parquet.ReadFile[LadderSnapshot](output)
+	if err != nil {
+		t.Fatalf("read snapshots: %v", err)
+	}
+
+	// 13:29:55 to 13:30:00 for both runners, the scheduled off coming before the in-play turn
+	if len(snapshots) != 12 {
+		t.Fatalf("Expected 6 snapshots per runner, got %d", len(snapshots))
+	}
+	byTime := make(map[int64]LadderSnapshot)
+	for _, snapshot := range snapshots {
+		if snapshot.SelectionID == 1 {
+			byTime[snapshot.SnapshotTime.Unix()] = snapshot
+		}
+	}
+
+	early := byTime[1759670996]
+	if early.SecondsToOff != 4 || len(early.BackPrices) != 0 {
+		t.Errorf("Expected an empty book before the first update, got %+v", early)
+	}
+	mid := byTime[1759670997]
+	if len(mid.BackPrices) != 2 || mid.BackPrices[0] != 3.0 || mid.BackPrices[1] != 2.9 || mid.BackSizes[0] != 20 {
+		t.Errorf("Expected the two best backs, got %+v", mid)
+	}
+	if len(mid.LayPrices) != 1 || mid.LayPrices[0] != 3.2 || mid.LaySizes[0] != 15 {
+		t.Errorf("Expected the best lay, got %+v", mid)
+	}
+	// An update published exactly on a snapshot time is included in it
+	last := byTime[1759670998]
+	if last.LTP != 3.1 || last.TradedVolume != 12 || last.BackPrices[0] != 2.9 {
+		t.Errorf("Expected the withdrawn back and trade to show, got %+v", last)
+	}
+	if _, ok := byTime[1759671001]; ok {
+		t.Errorf("Expected no snapshots after the scheduled off")
+	}
+}
+
+func TestBSPReconciliation(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "summary.parquet")
+	processor := NewMarketDataProcessorWithConfig(ProcessorConfig{OutputPath: output, OutputFormat: OutputFormatParquet, Workers: 1})
+
+	lines := []string{
+		`{"op":"mcm","pt":1759670400000,"mc":[{"id":"1.sp","marketDefinition":{"eventTypeId":"4339","marketType":"WIN","bettingType":"ODDS","eventName":"Romford (GB) 5th Oct","marketTime":"2025-10-05T13:30:00Z","bspReconciled":false,"runners":[{"id":1,"name":"1. Swift","status":"ACTIVE"}]}}]}`,
+		`{"op":"mcm","pt":1759670990000,"mc":[{"id":"1.sp","rc":[{"id":1,"spn":3.4,"spf":3.1}]}]}`,
+		`{"op":"mcm","pt":1759670995000,"mc":[{"id":"1.sp","rc":[{"id":1,"spn":3.55}]}]}`,
+		`{"op":"mcm","pt":1759671001000,"mc":[{"id":"1.sp","marketDefinition":{"bspReconciled":true,"runners":[{"id":1,"bsp":3.62}]}}]}`,
+		`{"op":"mcm","pt":1759671002000,"mc":[{"id":"1.sp","rc":[{"id":1,"spn":3.62,"spf":3.62}]}]}`,
+	}
+	for _, line := range lines {
+		var message MCMMessage
+		if err := json.Unmarshal([]byte(line), &message); err != nil {
+			t.Fatalf("decode message: %v", err)
+		}
+		processor.processMCMMessage(&message)
+	}
+
+	if err := processor.FinalizeProcessing(); err != nil {
+		t.Fatalf("FinalizeProcessing failed: %v", err)
+	}
+	rows, err := parquet.ReadFile[SummaryRow](output)
+	if err != nil {
+		t.Fatalf("read summary: %v", err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("Expected one summary row, got %d", len(rows))
+	}
+	row := rows[0]
+	if row.SPNear != 3.55 || row.SPFar != 3.1 || row.BSP != 3.62 {
+		t.Errorf("Expected projected SPs from before reconciliation alongside the BSP, got %+v", row)
+	}
+	if !row.BSPReconciled || !row.BSPReconciledTime.Equal(time.UnixMilli(1759671001000)) {
+		t.Errorf("Expected the reconciliation time, got %v %v", row.BSPReconciled, row.BSPReconciledTime)
+	}
+}
+
+func TestInPlayStatistics(t *testing.T) {
+	processor := NewMarketDataProcessor("", 0, 1)
+
+	lines := []string{
+		`{"op":"mcm","pt":1759670400000,"mc":[{"id":"1.ip","marketDefinition":{"eventTypeId":"4339","marketType":"WIN","bettingType":"ODDS","eventName":"Romford (GB) 5th Oct","marketTime":"2025-10-05T13:30:00Z","status":"OPEN","inPlay":false,"runners":[{"id":1,"name":"1. Swift","status":"ACTIVE"},{"id":2,"name":"2. Bolt","status":"ACTIVE"}]}}]}`,
+		`{"op":"mcm","pt":1759670990000,"mc":[{"id":"1.ip","rc":[{"id":1,"trd":[[3.0,50],[5.0,10]]}]}]}`,
+		`{"op":"mcm","pt":1759671000000,"mc":[{"id":"1.ip","marketDefinition":{"status":"OPEN","inPlay":true}}]}`,
+		`{"op":"mcm","pt":1759671005000,"mc":[{"id":"1.ip","rc":[{"id":1,"trd":[[3.0,50],[5.0,15],[1.8,20]]}]}]}`,
+		`{"op":"mcm","pt":1759671010000,"mc":[{"id":"1.ip","rc":[{"id":1,"trd":[[1.5,30]]}]}]}`,
+		`{"op":"mcm","pt":1759671032500,"mc":[{"id":"1.ip","marketDefinition":{"status":"SUSPENDED","inPlay":true}}]}`,
+		`{"op":"mcm","pt":1759671090000,"mc":[{"id":"1.ip","marketDefinition":{"status":"CLOSED","inPlay":true}}]}`,
+	}
+	for _, line := range lines {
+		var message MCMMessage
+		if err := json.Unmarshal([]byte(line), &message); err != nil {
+			t.Fatalf("decode message: %v", err)
+		}
+		processor.processMCMMessage(&message)
+	}
+
+	rows := processor.finalizeMarket("1.ip")
+	if len(rows) != 2 {
+		t.Fatalf("Expected two summary rows, got %d", len(rows))
+	}
+	for _, row := range rows {
+		if !row.HasSecondsInPlay || row.SecondsInPlay != 32.5 {
+			t.Errorf("Expected 32.5s in-play to the suspension, got %v", row.SecondsInPlay)
+		}
+		switch row.SelectionID {
+		case 1:
+			// 5.0 traded 5 more in-play; 3.0 only pre-off
+			if !row.HasInPlayTrades || row.InPlayHigh != 5.0 || row.InPlayLow != 1.5 || row.InPlayVolume != 55 {
+				t.Errorf("Unexpected in-play stats %+v", row)
+			}
+		case 2:
+			if row.HasInPlayTrades || row.InPlayVolume != 0 {
+				t.Errorf("Expected no in-play trades for an untraded runner, got %+v", row)
+			}
+		}
+	}
+}
+
+func TestTypedDecodingToleratesNonNumericPrices(t *testing.T) {
+	processor := NewMarketDataProcessor("", 0, 1)
+	stream := historicMarket("1.nan") +
+		`{"op":"mcm","pt":1633024802000,"mc":[{"id":"1.nan","rc":[{"id":123,"ltp":2.6,"spn":"NaN","spf":"Infinity"}]}]}` + "\n" +
+		`{"op":"connection","connectionId":"abc"}` + "\n" +
+		"not json\n"
+
+	if err := processor.processReader(strings.NewReader(stream), "stream"); err != nil {
+		t.Fatalf("processReader failed: %v", err)
+	}
+	runner := processor.MarketStates["1.nan"].Runners[123]
+	if runner.LatestLTP != 2.6 || runner.SPNear != 0 || runner.SPFar != 0 {
+		t.Errorf("Expected the update to apply without projected SPs, got %+v", runner)
+	}
+}
+
+func BenchmarkProcessMarketStream(b *testing.B) {
+	var stream strings.Builder
+	stream.WriteString(`{"op":"mcm","pt":1759670400000,"mc":[{"id":"1.bench","marketDefinition":{"eventTypeId":"4339","marketType":"WIN","bettingType":"ODDS","eventName":"Romford (GB) 5th Oct","marketTime":"2025-10-05T13:30:00Z","runners":[{"id":1,"name":"1. Swift","status":"ACTIVE"},{"id":2,"name":"2. Bolt","status":"ACTIVE"}]}}]}` + "\n")
+	for i := 0; i < 1000; i++ {
+		fmt.Fprintf(&stream, `{"op":"mcm","pt":%d,"mc":[{"id":"1.bench","rc":[{"id":1,"ltp":3.1,"tv":%d,"batb":[[0,3.0,20],[1,2.9,5]],"batl":[[0,3.2,15]],"trd":[[3.1,%d]]},{"id":2,"atb":[[4.0,10]],"atl":[[4.2,8]]}]}]}`+"\n",
+			1759670400000+int64(i)*100, i, i)
+	}
+	data := stream.String()
+
+	logger := zerolog.Nop()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		processor := NewMarketDataProcessorWithConfig(ProcessorConfig{OutputPath: b.TempDir(), Workers: 1, Logger: &logger})
+		processor.processReader(strings.NewReader(data), "bench")
+	}
+}
+
+func TestParallelWorkersMergeResults(t *testing.T) {
+	dir := t.TempDir()
+	for i := 0; i < 8; i++ {
+		marketID := fmt.Sprintf("1.%d", 100+i)
+		lines := []string{
+			`{"op":"mcm","pt":1759670400000,"mc":[{"id":"` + marketID + `","marketDefinition":{"eventTypeId":"4339","marketType":"WIN","bettingType":"ODDS","eventName":"Romford (GB) 5th Oct","marketTime":"2025-10-05T13:30:00Z","status":"OPEN","runners":[{"id":1,"name":"1. Swift","status":"ACTIVE"},{"id":2,"name":"2. Dash","status":"ACTIVE"}]}}]}`,
+			`{"op":"mcm","pt":1759671000000,"mc":[{"id":"` + marketID + `","rc":[{"id":1,"ltp":3.5},{"id":2,"ltp":1.5}]}]}`,
+		}
+		if i%2 == 0 {
+			lines = append(lines, `{"op":"mcm","pt":1759671100000,"mc":[{"id":"`+marketID+`","marketDefinition":{"status":"CLOSED","runners":[{"id":1,"name":"1. Swift","status":"WINNER"},{"id":2,"name":"2. Dash","status":"LOSER"}]}}]}`)
+		}
+		if err := os.WriteFile(filepath.Join(dir, marketID), []byte(strings.Join(lines, "\n")), 0644); err != nil {
+			t.Fatalf("write market file: %v", err)
+		}
+	}
+
+	processor := NewMarketDataProcessor(t.TempDir(), 0, 4)
+	if err := processor.ProcessPath(dir); err != nil {
+		t.Fatalf("ProcessPath failed: %v", err)
+	}
+	if processor.FilesProcessed != 8 {
+		t.Errorf("Expected 8 files counted across workers, got %d", processor.FilesProcessed)
+	}
+	// Closed markets are finalized by their worker; the rest wait for FinalizeProcessing
+	if len(processor.ProcessedData) != 8 || len(processor.MarketStates) != 4 {
+		t.Errorf("Expected 8 merged rows and 4 open markets, got %d rows and %d markets",
+			len(processor.ProcessedData), len(processor.MarketStates))
+	}
+
+	limited := NewMarketDataProcessor(t.TempDir(), 3, 4)
+	if err := limited.ProcessPath(dir); err != nil {
+		t.Fatalf("ProcessPath failed: %v", err)
+	}
+	if limited.FilesProcessed != 3 {
+		t.Errorf("Expected the file limit to hold across workers, got %d files", limited.FilesProcessed)
+	}
+}
+
+func TestManifestSkipsProcessedFiles(t *testing.T) {
+	dir := t.TempDir()
+	manifestPath := filepath.Join(t.TempDir(), "manifest.json")
+	writeMarket := func(marketID, winner string) {
+		lines := []string{
+			`{"op":"mcm","pt":1759670400000,"mc":[{"id":"` + marketID + `","marketDefinition":{"eventTypeId":"4339","marketType":"WIN","bettingType":"ODDS","eventName":"Romford (GB) 5th Oct","marketTime":"2025-10-05T13:30:00Z","status":"OPEN","runners":[{"id":1,"name":"1. Swift","status":"ACTIVE"}]}}]}`,
+			`{"op":"mcm","pt":1759671100000,"mc":[{"id":"` + marketID + `","marketDefinition":{"status":"CLOSED","runners":[{"id":1,"name":"1. Swift","status":"` + winner + `"}]}}]}`,
+		}
+		if err := os.WriteFile(filepath.Join(dir, marketID), []byte(strings.Join(lines, "\n")), 0644); err != nil {
+			t.Fatalf("write market file: %v", err)
+		}
+	}
+	run := func() *MarketDataProcessor {
+		processor := NewMarketDataProcessorWithConfig(ProcessorConfig{OutputPath: t.TempDir(), Workers: 2, ManifestPath: manifestPath})
+		if err := processor.ProcessPath(dir); err != nil {
+			t.Fatalf("ProcessPath failed: %v", err)
+		}
+		if err := processor.FinalizeProcessing(); err != nil {
+			t.Fatalf("FinalizeProcessing failed: %v", err)
+		}
+		return processor
+	}
+
+	writeMarket("1.201", "WINNER")
+	writeMarket("1.202", "LOSER")
+	if first := run(); len(first.ProcessedData) != 2 {
+		t.Fatalf("Expected both markets on the first run, got %d rows", len(first.ProcessedData))
+	}
+
+	manifest, err := LoadManifest(manifestPath)
+	if err != nil {
+		t.Fatalf("LoadManifest failed: %v", err)
+	}
+	entry, ok := manifest.Files[filepath.Join(dir, "1.201")]
+	if len(manifest.Files) != 2 || !ok || entry.Rows != 1 || entry.Version == "" || entry.Size == 0 {
+		t.Fatalf("Unexpected manifest %+v", manifest.Files)
+	}
+
+	// Only the new file is processed on a re-run, and a file that changed is processed again
+	writeMarket("1.203", "WINNER")
+	changed := time.Now().Add(time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, "1.202"), changed, changed); err != nil {
+		t.Fatalf("touch market file: %v", err)
+	}
+	second := run()
+	if second.FilesProcessed != 2 || len(second.ProcessedData) != 2 {
+		t.Errorf("Expected only the new and changed files, got %d files and %d rows", second.FilesProcessed, len(second.ProcessedData))
+	}
+	for _, row := range second.ProcessedData {
+		if row.MarketID == "1.201" {
+			t.Errorf("Expected 1.201 to be skipped, got %+v", row)
+		}
+	}
+}
+
+func TestSourceFilters(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"2025/Sep/29/1.301": "2025-09-29T13:30:00Z",
+		"2025/Sep/30/1.302": "2025-09-30T13:30:00Z",
+		"2025/Oct/1/1.303":  "2025-10-01T13:30:00Z",
+		"undated/1.304":     "2025-10-02T13:30:00Z",
+	}
+	for name, marketTime := range files {
+		marketID := filepath.Base(name)
+		line := `{"op":"mcm","pt":1759670400000,"mc":[{"id":"` + marketID + `","marketDefinition":{"eventTypeId":"4339","marketType":"WIN","bettingType":"ODDS","eventName":"Romford (GB)","marketTime":"` + marketTime + `","status":"CLOSED","runners":[{"id":1,"name":"1. Swift","status":"WINNER"}]}}]}`
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("create market directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(line), 0644); err != nil {
+			t.Fatalf("write market file: %v", err)
+		}
+	}
+
+	process := func(config ProcessorConfig) []string {
+		config.OutputPath = t.TempDir()
+		config.Workers = 1
+		processor := NewMarketDataProcessorWithConfig(config)
+		if err := processor.ProcessPath(dir); err != nil {
+			t.Fatalf("ProcessPath failed: %v", err)
+		}
+		var markets []string
+		for _, row := range processor.ProcessedData {
+			markets = append(markets, row.MarketID)
+		}
+		sort.Strings(markets)
+		return markets
+	}
+
+	september := ProcessorConfig{
+		From: time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC),
+		To:   time.Date(2025, 9, 30, 0, 0, 0, 0, time.UTC),
+	}
+	// The undated file is read, but its market is outside the range
+	if markets := process(september); !reflect.DeepEqual(markets, []string{"1.301", "1.302"}) {
+		t.Errorf("Expected September's markets, got %v", markets)
+	}
+	if markets := process(ProcessorConfig{Include: []string{"1.30[34]"}}); !reflect.DeepEqual(markets, []string{"1.303", "1.304"}) {
+		t.Errorf("Expected the glob to match file names, got %v", markets)
+	}
+	if markets := process(ProcessorConfig{PathPattern: regexp.MustCompile(`/Sep/`)}); !reflect.DeepEqual(markets, []string{"1.301", "1.302"}) {
+		t.Errorf("Expected the pattern to match paths, got %v", markets)
+	}
+}
+
+func TestHivePartitionedOutput(t *testing.T) {
+	outputDir := t.TempDir()
+	processor := NewMarketDataProcessorWithConfig(ProcessorConfig{
+		OutputPath:       outputDir,
+		OutputFormat:     OutputFormatParquet,
+		Partitioned:      true,
+		PartitionByVenue: true,
+	})
+	processor.ProcessedData = []SummaryRow{
+		{MarketID: "1.1", Venue: "Romford", Year: 2025, Month: 9, Day: 30},
+		{MarketID: "1.2", Venue: "Romford", Year: 2025, Month: 9, Day: 30},
+		{MarketID: "1.3", Venue: "Sale/Warragul", Year: 2025, Month: 10, Day: 1},
+		{MarketID: "1.4", Year: 2025, Month: 10, Day: 1},
+	}
+	if err := processor.FinalizeProcessing(); err != nil {
+		t.Fatalf("FinalizeProcessing failed: %v", err)
+	}
+
+	expected := map[string]int{
+		"year=2025/month=09/day=30/venue=Romford":                    2,
+		"year=2025/month=10/day=01/venue=Sale%2FWarragul":            1,
+		"year=2025/month=10/day=01/venue=__HIVE_DEFAULT_PARTITION__": 1,
+	}
+	for dir, count := range expected {
+		rows, err := parquet.ReadFile[SummaryRow](filepath.Join(outputDir, filepath.FromSlash(dir), "greyhound_win_markets.parquet"))
+		if err != nil {
+			t.Fatalf("read partition %s: %v", dir, err)
+		}
+		if len(rows) != count {
+			t.Errorf("Expected %d rows in %s, got %d", count, dir, len(rows))
+		}
+	}
+}
+
+func TestParquetWriterOptions(t *testing.T) {
+	outputFile := filepath.Join(t.TempDir(), "summary.parquet")
+	processor := NewMarketDataProcessorWithConfig(ProcessorConfig{
+		OutputPath:          outputFile,
+		OutputFormat:        OutputFormatParquet,
+		ParquetCompression:  ParquetCompressionZstd,
+		ParquetRowGroupSize: 2,
+		ParquetDictionary:   true,
+	})
+	for i := 0; i < 5; i++ {
+		processor.ProcessedData = append(processor.ProcessedData, SummaryRow{MarketID: "1.1", SelectionID: int64(i), Venue: "Romford", Year: 2025, Month: 9, Day: 30})
+	}
+	if err := processor.FinalizeProcessing(); err != nil {
+		t.Fatalf("FinalizeProcessing failed: %v", err)
+	}
+
+	file, err := os.Open(outputFile)
+	if err != nil {
+		t.Fatalf("open parquet: %v", err)
+	}
+	defer file.Close()
+	info, _ := file.Stat()
+	parquetFile, err := parquet.OpenFile(file, info.Size())
+	if err != nil {
+		t.Fatalf("read parquet: %v", err)
+	}
+
+	metadata := parquetFile.Metadata()
+	if len(metadata.RowGroups) != 3 {
+		t.Errorf("Expected 3 row groups of at most 2 rows, got %d", len(metadata.RowGroups))
+	}
+	column := metadata.RowGroups[0].Columns[0].MetaData
+	if column.Codec.String() != "ZSTD" {
+		t.Errorf("Expected zstd compression, got %s", column.Codec)
+	}
+	dictionary := false
+	for _, encoding := range column.Encoding {
+		dictionary = dictionary || encoding.String() == "RLE_DICTIONARY"
+	}
+	if !dictionary {
+		t.Errorf("Expected market_id to be dictionary encoded, got %v", column.Encoding)
+	}
+
+	if defaults := NewMarketDataProcessorWithConfig(ProcessorConfig{ParquetCompression: "lzma"}); defaults.Config.ParquetCompression != ParquetCompressionSnappy {
+		t.Errorf("Expected an unknown codec to fall back to snappy, got %s", defaults.Config.ParquetCompression)
+	}
+}
+
+func TestParquetNullsFollowHasFlags(t *testing.T) {
+	outputFile := filepath.Join(t.TempDir(), "summary.parquet")
+	processor := NewMarketDataProcessorWithConfig(ProcessorConfig{OutputPath: outputFile, OutputFormat: OutputFormatParquet})
+	processor.ProcessedData = []SummaryRow{
+		// Nothing matched in the last minute, which is a real zero rather than a missing value
+		{MarketID: "1.1", BSP: 3.2, HasBSP: true, VWAP: 3.1, TotalTradedVolume: 100, HasVolumeProfile: true, InPlayVolumePct: 0, VolumeLast60s: 0, VolumeLast5m: 20},
+		// A BSP without its flag is not written
+		{MarketID: "1.2", BSP: 5},
+	}
+	if err := processor.FinalizeProcessing(); err != nil {
+		t.Fatalf("FinalizeProcessing failed: %v", err)
+	}
+
+	rows, err := parquet.ReadFile[summaryParquetRow](outputFile)
+	if err != nil {
+		t.Fatalf("read parquet: %v", err)
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].MarketID < rows[j].MarketID })
+	if rows[0].VolumeLast60s == nil || *rows[0].VolumeLast60s != 0 || rows[0].InPlayVolumePct == nil {
+		t.Errorf("Expected zero volumes to be written as zero, got %v", rows[0].VolumeLast60s)
+	}
+	if rows[0].BSP == nil || *rows[0].BSP != 3.2 || rows[0].LTP != nil || rows[0].BSPReconciledTime != nil {
+		t.Errorf("Unexpected nullable columns %+v", rows[0])
+	}
+	if rows[1].BSP != nil || rows[1].VolumeLast60s != nil {
+		t.Errorf("Expected values without their flags to be null, got %+v", rows[1])
+	}
+
+	// The file keeps SummaryRow's schema, so it still reads back into summary rows
+	summaries, err := parquet.ReadFile[SummaryRow](outputFile)
+	if err != nil || len(summaries) != 2 {
+		t.Fatalf("read summary rows: %v", err)
+	}
+	data, err := os.ReadFile(outputFile)
+	if err != nil {
+		t.Fatalf("read parquet: %v", err)
+	}
+	file, err := parquet.OpenFile(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("open parquet: %v", err)
+	}
+	if !parquet.EqualNodes(file.Schema(), summarySchema) {
+		t.Errorf("Expected the summary schema, got %s", file.Schema())
+	}
+}
+
+// recordingDriver is a database/sql driver that records the statements executed through it
+type recordingDriver struct {
+	mu         sync.Mutex
+	dsn        string
+	statements []string
+	args       [][]driver.Value
+}
+
+func (d *recordingDriver) Open(dsn string) (driver.Conn, error) {
+	d.mu.Lock()
+	d.dsn = dsn
+	d.mu.Unlock()
+	return recordingConn{d}, nil
+}
+
+func (d *recordingDriver) reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.dsn, d.statements, d.args = "", nil, nil
+}
+
+type recordingConn struct{ driver *recordingDriver }
+
+func (c recordingConn) Prepare(query string) (driver.Stmt, error) {
+	return recordingStmt{c.driver, query}, nil
+}
+func (c recordingConn) Close() error              { return nil }
+func (c recordingConn) Begin() (driver.Tx, error) { return recordingTx{}, nil }
+
+type recordingStmt struct {
+	driver *recordingDriver
+	query  string
+}
+
+func (s recordingStmt) Close() error  { return nil }
+func (s recordingStmt) NumInput() int { return -1 }
+func (s recordingStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.driver.mu.Lock()
+	defer s.driver.mu.Unlock()
+	s.driver.statements = append(s.driver.statements, s.query)
+	s.driver.args = append(s.driver.args, args)
+	return driver.RowsAffected(1), nil
+}
+func (s recordingStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return nil, fmt.Errorf("queries are not supported")
+}
+
+type recordingTx struct{}
+
+func (recordingTx) Commit() error   { return nil }
+func (recordingTx) Rollback() error { return nil }
+
+var testDuckDB = func() *recordingDriver {
+	d := &recordingDriver{}
+	sql.Register("duckdb", d)
+	return d
+}()
+
+func TestDuckDBOutput(t *testing.T) {
+	testDuckDB.reset()
+	outputFile := filepath.Join(t.TempDir(), "2025-09-30.duckdb")
+	processor := NewMarketDataProcessorWithConfig(ProcessorConfig{OutputPath: outputFile, OutputFormat: OutputFormatDuckDB})
+	processor.ProcessedData = []SummaryRow{
+		{MarketID: "1.1", SelectionID: 7, Venue: "Romford", BSP: 3.2, HasBSP: true, LTP: 3.0, Year: 2025, Month: 9, Day: 30},
+	}
+	if err := processor.FinalizeProcessing(); err != nil {
+		t.Fatalf("FinalizeProcessing failed: %v", err)
+	}
+
+	if testDuckDB.dsn != outputFile {
+		t.Errorf("Expected the output file to be opened, got %q", testDuckDB.dsn)
+	}
+	statements := testDuckDB.statements
+	if len(statements) != 5 || !strings.HasPrefix(statements[0], "CREATE TABLE IF NOT EXISTS summary (market_id VARCHAR") {
+		t.Fatalf("Expected the table, three indexes and one insert, got %v", statements)
+	}
+	if statements[1] != "CREATE INDEX IF NOT EXISTS summary_market_id_selection_id_idx ON summary (market_id, selection_id)" {
+		t.Errorf("Unexpected index statement %q", statements[1])
+	}
+	if !strings.HasPrefix(statements[4], "INSERT INTO summary (market_id, selection_id") {
+		t.Errorf("Unexpected insert %q", statements[4])
+	}
+
+	args := testDuckDB.args[4]
+	if len(args) != len(summaryTable.columns) || args[0] != "1.1" || args[1] != int64(7) || args[7] != 3.2 {
+		t.Errorf("Unexpected insert values %v", args)
+	}
+	// The LTP has no HasLTP flag, so it is written as NULL
+	if args[8] != nil {
+		t.Errorf("Expected a NULL ltp, got %v", args[8])
+	}
+}
+
+var testWarehouse = func() *recordingDriver {
+	d := &recordingDriver{}
+	sql.Register("warehouse", d)
+	return d
+}()
+
+func TestDatabaseSinkUpsertsInBatches(t *testing.T) {
+	testWarehouse.reset()
+	processor := NewMarketDataProcessorWithConfig(ProcessorConfig{
+		OutputPath:        t.TempDir(),
+		OutputFormat:      OutputFormatDatabase,
+		DatabaseDriver:    "warehouse",
+		DatabaseDSN:       "postgres://localhost/betfair",
+		DatabaseTable:     "analytics.summary",
+		DatabaseBatchSize: 2,
+	})
+	processor.ProcessedData = []SummaryRow{
+		{MarketID: "1.1", SelectionID: 1, LTP: 2.0, HasLTP: true},
+		{MarketID: "1.1", SelectionID: 2},
+		{MarketID: "1.2", SelectionID: 1},
+		{MarketID: "1.1", SelectionID: 1, LTP: 2.5, HasLTP: true}, // Re-processed; replaces the first row
+	}
+	if err := processor.FinalizeProcessing(); err != nil {
+		t.Fatalf("FinalizeProcessing failed: %v", err)
+	}
+
+	if testWarehouse.dsn != "postgres://localhost/betfair" {
+		t.Errorf("Expected the DSN to be used, got %q", testWarehouse.dsn)
+	}
+	statements := testWarehouse.statements
+	if len(statements) != 5 {
+		t.Fatalf("Expected the table, two indexes and two batches, got %v", statements)
+	}
+	if !strings.HasSuffix(statements[0], ", PRIMARY KEY (market_id, selection_id))") {
+		t.Errorf("Expected a primary key on market and selection, got %q", statements[0])
+	}
+	if statements[1] != "CREATE INDEX IF NOT EXISTS analytics_summary_market_time_idx ON analytics.summary (market_time)" {
+		t.Errorf("Unexpected index statement %q", statements[1])
+	}
+
+	columns := len(summaryTable.columns)
+	if len(testWarehouse.args[3]) != 2*columns || len(testWarehouse.args[4]) != columns {
+		t.Errorf("Expected batches of 2 and 1 rows, got %d and %d values", len(testWarehouse.args[3]), len(testWarehouse.args[4]))
+	}
+	insert := statements[3]
+	if !strings.Contains(insert, fmt.Sprintf("), ($%d, $%d", columns+1, columns+2)) {
+		t.Errorf("Expected numbered placeholders across rows, got %q", insert)
+	}
+	if !strings.Contains(insert, "ON CONFLICT (market_id, selection_id) DO UPDATE SET event_id = EXCLUDED.event_id") {
+		t.Errorf("Expected an upsert on market and selection, got %q", insert)
+	}
+	// The duplicate keeps its later position, so it lands in the second batch
+	if ltp := testWarehouse.args[4][8]; ltp != 2.5 {
+		t.Errorf("Expected the re-processed row to win, got ltp %v", ltp)
+	}
+}
+
+func TestQualityReport(t *testing.T) {
+	outputDir := t.TempDir()
+	reportPath := filepath.Join(outputDir, "quality.json")
+	processor := NewMarketDataProcessorWithConfig(ProcessorConfig{OutputPath: outputDir, ReportPath: reportPath})
+	// Named after the first of the three markets it holds
+	contaminated, err := os.ReadFile("testdata/contaminated_multi_market.json")
+	if err != nil {
+		t.Fatalf("read test data: %v", err)
+	}
+	marketFile := filepath.Join(t.TempDir(), "1.248394055")
+	if err := os.WriteFile(marketFile, contaminated, 0644); err != nil {
+		t.Fatalf("write market file: %v", err)
+	}
+	if err := processor.ProcessFile(marketFile); err != nil {
+		t.Fatalf("ProcessFile failed: %v", err)
+	}
+	for _, line := range []string{
+		`{"op":"mcm","pt":1759670400000,"mc":[{"id":"1.settled","marketDefinition":{"eventTypeId":"4339","marketType":"WIN","bettingType":"ODDS","eventName":"Romford (GB)","marketTime":"2025-10-05T13:30:00Z","status":"CLOSED","runners":[{"id":1,"name":"1. Swift","status":"WINNER","bsp":2.5},{"id":2,"name":"2. Dash","status":"LOSER","bsp":4.1}]}}]}`,
+		`{"op":"mcm","pt":1759670500000,"mc":[{"id":"1.settled","rc":[{"id":1,"tv":120},{"id":2,"tv":80}]}]}`,
+	} {
+		var message MCMMessage
+		if err := json.Unmarshal([]byte(line), &message); err != nil {
+			t.Fatalf("decode message: %v", err)
+		}
+		processor.processMCMMessage(&message)
+	}
+	if err := processor.FinalizeProcessing(); err != nil {
+		t.Fatalf("FinalizeProcessing failed: %v", err)
+	}
+
+	data, err := os.ReadFile(reportPath)
+	if err != nil {
+		t.Fatalf("read report: %v", err)
+	}
+	var report QualityReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatalf("decode report: %v", err)
+	}
+	if report.Counts[IssueContaminatedFile] != 1 {
+		t.Errorf("Expected one contaminated file, got %+v", report.Counts)
+	}
+	for _, issue := range report.Issues {
+		if issue.MarketID == "1.settled" {
+			t.Errorf("Expected the settled market to be clean, got %+v", issue)
+		}
+		if issue.Kind == IssueContaminatedFile && (issue.MarketID != "1.248394055" || !strings.Contains(issue.Detail, "1.248394060, 1.248394065")) {
+			t.Errorf("Unexpected contamination issue %+v", issue)
+		}
+	}
+	// 1.248394065 never traded and has no winner
+	if report.Counts[IssueMissingWinner] != 1 || report.Counts[IssueZeroVolume] != 1 {
+		t.Errorf("Expected the unsettled market to be reported, got %+v", report.Counts)
+	}
+	if issue := report.Issues[1]; issue.Kind != IssueMissingWinner || issue.MarketID != "1.248394065" {
+		t.Errorf("Unexpected missing winner issue %+v", issue)
+	}
+
+	// A .csv path writes one row per issue
+	processor.Config.ReportPath = filepath.Join(outputDir, "quality.csv")
+	if err := processor.saveQualityReport(); err != nil {
+		t.Fatalf("saveQualityReport failed: %v", err)
+	}
+	csvData, err := os.ReadFile(processor.Config.ReportPath)
+	if err != nil {
+		t.Fatalf("read csv report: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(csvData)), "\n")
+	if lines[0] != "kind,market_id,source,detail" || len(lines) != len(report.Issues)+1 {
+		t.Errorf("Unexpected CSV report %q", csvData)
+	}
+}
+
+func TestTraceMarkets(t *testing.T) {
+	var logs bytes.Buffer
+	logger := zerolog.New(&logs)
+
+	processor := NewMarketDataProcessorWithConfig(ProcessorConfig{OutputPath: t.TempDir(), TraceMarkets: []string{"1.248394060"}, Logger: &logger})
+	if err := processor.ProcessFile("testdata/contaminated_multi_market.json"); err != nil {
+		t.Fatalf("ProcessFile failed: %v", err)
+	}
+
+	var traced []string
+	for _, line := range strings.Split(logs.String(), "\n") {
+		if strings.Contains(line, `"message":"trace: `) {
+			traced = append(traced, line)
+		}
+	}
+	if len(traced) == 0 {
+		t.Fatal("Expected trace lines for 1.248394060")
+	}
+	for _, line := range traced {
+		if !strings.Contains(line, `"market_id":"1.248394060"`) {
+			t.Errorf("Traced a market that wasn't asked for: %s", line)
+		}
+	}
+	for _, want := range []string{"contaminated_multi_market.json line 1,", "definition eventId=", "created from "} {
+		if !strings.Contains(strings.Join(traced, "\n"), want) {
+			t.Errorf("Expected a trace line containing %q", want)
+		}
+	}
+
+	if got := describeRunnerChange(RunnerChange{LTP: optionalFloat{2.5, true}, TRD: [][]float64{{2.5, 10}}}); got != "ltp=2.5 trd=[[2.5 10]]" {
+		t.Errorf("describeRunnerChange = %q", got)
+	}
+	if got := describeRunnerChange(RunnerChange{ID: 1}); got != "(no changes)" {
+		t.Errorf("describeRunnerChange = %q", got)
+	}
+}
+
+func TestProgressReporter(t *testing.T) {
+	clock := time.Date(2025, 10, 5, 12, 0, 0, 0, time.UTC)
+	now := func() time.Time { return clock }
+
+	var bar, logs bytes.Buffer
+	drawn := newProgress(4, zerolog.New(&logs), &bar, now)
+	for _, rows := range []int{10, 20} {
+		clock = clock.Add(time.Second)
+		drawn.fileDone(rows)
+	}
+	want := "\r[===============>              ] 2/4 files  50% | 30 rows | 1.0 files/s 15 rows/s | ETA 2s"
+	if got := bar.String(); !strings.HasSuffix(got, want) {
+		t.Errorf("Expected the bar to end with %q, got %q", want, got)
+	}
+	drawn.finish()
+	if !strings.HasSuffix(bar.String(), "\n") || !strings.Contains(logs.String(), `"rows":30`) {
+		t.Errorf("Expected finish to end the bar and log totals, got %q and %q", bar.String(), logs.String())
+	}
+
+	// Without a terminal, progress is logged every progressLogInterval
+	logs.Reset()
+	logged := newProgress(10, zerolog.New(&logs), nil, now)
+	for i := 0; i < 5; i++ {
+		clock = clock.Add(3 * time.Second)
+		logged.fileDone(1)
+	}
+	lines := strings.Split(strings.TrimSpace(logs.String()), "\n")
+	if len(lines) != 1 || !strings.Contains(lines[0], `"files":4`) || !strings.Contains(lines[0], `"eta":18000`) {
+		t.Errorf("Expected one progress line after 12s, got %q", logs.String())
+	}
+
+	// A nil reporter, as Config.Progress off gives, does nothing
+	processor := NewMarketDataProcessorWithConfig(ProcessorConfig{OutputPath: t.TempDir()})
+	disabled := processor.startProgress(3)
+	disabled.fileDone(1)
+	disabled.finish()
+	if disabled != nil {
+		t.Error("Expected no reporter without Config.Progress")
+	}
+}
+
+// slowStorage serves objects from memory after a delay, recording how many downloads overlap
+type slowStorage struct {
+	betfair.ObjectStorage // Only List and Download are used
+	objects               map[string]string
+	delay                 time.Duration
+
+	mu           sync.Mutex
+	active, peak int
+	downloads    int
+}
+
+func (s *slowStorage) List(ctx context.Context, prefix string) ([]betfair.ObjectInfo, error) {
+	var objects []betfair.ObjectInfo
+	for key, data := range s.objects {
+		objects = append(objects, betfair.ObjectInfo{Key: key, Size: int64(len(data)), ETag: fmt.Sprintf("%x", md5.Sum([]byte(data)))})
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+	return objects, nil
+}
+
+func (s *slowStorage) Download(ctx context.Context, key string) (io.ReadCloser, error) {
+	s.mu.Lock()
+	s.active++
+	s.downloads++
+	s.peak = max(s.peak, s.active)
+	s.mu.Unlock()
+
+	time.Sleep(s.delay)
+
+	s.mu.Lock()
+	s.active--
+	s.mu.Unlock()
+	return io.NopCloser(strings.NewReader(s.objects[key])), nil
+}
+
+func TestPrefetchDownloadsAheadOfWorkers(t *testing.T) {
+	objects := make(map[string]string)
+	for i := 0; i < 8; i++ {
+		marketID := fmt.Sprintf("1.%d", 100+i)
+		objects["PRO/2025/Sep/29/1/"+marketID+".json"] = historicMarket(marketID)
+	}
+	size := int64(len(historicMarket("1.100")))
+
+	for _, test := range []struct {
+		name     string
+		budget   int64
+		wantPeak int
+	}{
+		{"parallel", 1 << 20, 4},
+		{"budget of one object", size, 1},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			storage := &slowStorage{objects: objects, delay: 20 * time.Millisecond}
+			processor := NewMarketDataProcessorWithConfig(ProcessorConfig{
+				OutputPath:          t.TempDir(),
+				Workers:             1,
+				DownloadConcurrency: 4,
+				PrefetchBytes:       test.budget,
+			})
+			processor.storages = map[string]betfair.ObjectStorage{"s3://markets/": storage}
+
+			if err := processor.ProcessPath("s3://markets/PRO/2025"); err != nil {
+				t.Fatalf("ProcessPath failed: %v", err)
+			}
+			if len(processor.MarketStates) != len(objects) || storage.downloads != len(objects) {
+				t.Errorf("Expected %d markets from %d downloads, got %d from %d",
+					len(objects), len(objects), len(processor.MarketStates), storage.downloads)
+			}
+			if storage.peak != test.wantPeak {
+				t.Errorf("Expected %d downloads at once, got %d", test.wantPeak, storage.peak)
+			}
+		})
+	}
+
+	// Objects larger than the budget are streamed by the worker instead
+	storage := &slowStorage{objects: objects}
+	processor := NewMarketDataProcessorWithConfig(ProcessorConfig{OutputPath: t.TempDir(), Workers: 1, PrefetchBytes: size - 1})
+	processor.storages = map[string]betfair.ObjectStorage{"s3://markets/": storage}
+	if err := processor.ProcessPath("s3://markets/PRO/2025"); err != nil {
+		t.Fatalf("ProcessPath failed: %v", err)
+	}
+	if len(processor.MarketStates) != len(objects) || storage.peak != 1 {
+		t.Errorf("Expected every market streamed one at a time, got %d markets and %d at once", len(processor.MarketStates), storage.peak)
+	}
+}
+
+func TestCacheDirSkipsDownloadsOfUnchangedObjects(t *testing.T) {
+	objects := map[string]string{
+		"PRO/2025/Sep/29/1/1.100.json": historicMarket("1.100"),
+		"PRO/2025/Sep/29/1/1.101.json": historicMarket("1.101"),
+	}
+	storage := &slowStorage{objects: objects}
+	cacheDir := t.TempDir()
+
+	run := func() *MarketDataProcessor {
+		processor := NewMarketDataProcessorWithConfig(ProcessorConfig{OutputPath: t.TempDir(), Workers: 1, CacheDir: cacheDir})
+		processor.storages = map[string]betfair.ObjectStorage{"s3://markets/": storage}
+		if err := processor.ProcessPath("s3://markets/PRO/2025"); err != nil {
+			t.Fatalf("ProcessPath failed: %v", err)
+		}
+		return processor
+	}
+
+	run()
+	if storage.downloads != 2 {
+		t.Fatalf("Expected both objects downloaded, got %d downloads", storage.downloads)
+	}
+	etag := fmt.Sprintf("%x", md5.Sum([]byte(objects["PRO/2025/Sep/29/1/1.100.json"])))
+	if _, err := os.Stat(filepath.Join(cacheDir, "s3", "markets", "PRO", "2025", "Sep", "29", "1", "1.100.json."+etag)); err != nil {
+		t.Errorf("Expected the object cached by its ETag: %v", err)
+	}
+
+	if processor := run(); storage.downloads != 2 || len(processor.MarketStates) != 2 {
+		t.Errorf("Expected both markets read from the cache, got %d downloads and %d markets", storage.downloads, len(processor.MarketStates))
+	}
+
+	// A changed object has a new ETag and is downloaded again
+	objects["PRO/2025/Sep/29/1/1.101.json"] = historicMarket("1.102")
+	if processor := run(); storage.downloads != 3 || processor.MarketStates["1.102"] == nil {
+		t.Errorf("Expected only the changed object downloaded again, got %d downloads", storage.downloads)
+	}
+}
+
+func TestErrorPolicyAndQuarantine(t *testing.T) {
+	inputDir := t.TempDir()
+	for name, data := range map[string]string{
+		"a_garbage.json": "<html>not a market</html>\n",
+		"b_corrupt.bz2":  "BZh91AY&SY this is not bzip2",
+		"c_good.json":    historicMarket("1.good"),
+	} {
+		if err := os.WriteFile(filepath.Join(inputDir, name), []byte(data), 0644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+
+	quarantineDir := t.TempDir()
+	processor := NewMarketDataProcessorWithConfig(ProcessorConfig{OutputPath: t.TempDir(), Workers: 1, QuarantineDir: quarantineDir})
+	if err := processor.ProcessPath(inputDir); err != nil {
+		t.Fatalf("Expected the continue policy to process past failures, got %v", err)
+	}
+	if _, exists := processor.MarketStates["1.good"]; !exists {
+		t.Error("Expected the good file processed")
+	}
+	if len(processor.FileErrors) != 2 {
+		t.Fatalf("Expected two failed files, got %+v", processor.FileErrors)
+	}
+	for _, failure := range processor.FileErrors {
+		original, _ := os.ReadFile(failure.Path)
+		quarantined, err := os.ReadFile(failure.Quarantined)
+		if err != nil || !bytes.Equal(original, quarantined) || filepath.Dir(failure.Quarantined) != quarantineDir {
+			t.Errorf("Expected %s copied into quarantine, got %q: %v", failure.Path, failure.Quarantined, err)
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(quarantineDir, quarantineReportName))
+	if err != nil {
+		t.Fatalf("read quarantine report: %v", err)
+	}
+	var report struct{ Failures []FileError }
+	if err := json.Unmarshal(data, &report); err != nil || len(report.Failures) != 2 {
+		t.Errorf("Expected both failures in the report, got %s: %v", data, err)
+	}
+
+	// Quarantining a file of the same name again numbers the copy
+	if copied, err := processor.quarantine(filepath.Join(inputDir, "a_garbage.json")); err != nil || filepath.Base(copied) != "a_garbage-2.json" {
+		t.Errorf("Expected a numbered copy, got %q: %v", copied, err)
+	}
+
+	// Fail-fast stops at the first failure
+	processor = NewMarketDataProcessorWithConfig(ProcessorConfig{OutputPath: t.TempDir(), Workers: 1, ErrorPolicy: ErrorPolicyFailFast})
+	err = processor.ProcessPath(inputDir)
+	if err == nil || !strings.Contains(err.Error(), "a_garbage.json") {
+		t.Errorf("Expected the first failure returned, got %v", err)
+	}
+	if len(processor.FileErrors) != 1 || len(processor.MarketStates) != 0 {
+		t.Errorf("Expected processing to stop at the first failure, got %+v and %d markets", processor.FileErrors, len(processor.MarketStates))
+	}
+}
+
+func TestBzip2Decoders(t *testing.T) {
+	// Enough varied updates for several 100k blocks at level 1
+	var stream strings.Builder
+	stream.WriteString(historicMarket("1.bzip"))
+	for i := 0; i < 8000; i++ {
+		fmt.Fprintf(&stream, `{"op":"mcm","pt":%d,"mc":[{"id":"1.bzip","rc":[{"id":123,"ltp":%.2f,"tv":%d}]}]}`+"\n",
+			1633024802000+int64(i)*97, 1.01+float64(i%311)/100, i*7)
+	}
+	original := []byte(stream.String())
+
+	var compressed bytes.Buffer
+	writer, err := bzip2.NewWriter(&compressed, &bzip2.WriterConfig{Level: 1})
+	if err != nil {
+		t.Fatalf("create bzip2 writer: %v", err)
+	}
+	writer.Write(original)
+	writer.Close()
+	// Concatenated streams, as pbzip2 writes, decode as one
+	data := append(bytes.Clone(compressed.Bytes()), compressed.Bytes()...)
+
+	decoded, err := decodeBzip2Parallel(data, 4)
+	if err != nil {
+		t.Fatalf("decodeBzip2Parallel failed: %v", err)
+	}
+	if !bytes.Equal(decoded, append(bytes.Clone(original), original...)) {
+		t.Errorf("Parallel decode differs from the original: %d bytes, want %d", len(decoded), 2*len(original))
+	}
+	if _, err := decodeBzip2Parallel([]byte("BZh9 not bzip2"), 4); err == nil {
+		t.Error("Expected an error for data without blocks")
+	}
+
+	path := filepath.Join(t.TempDir(), "1.bzip.bz2")
+	if err := os.WriteFile(path, compressed.Bytes(), 0644); err != nil {
+		t.Fatalf("write test file: %v", err)
+	}
+	for _, decoder := range []Bzip2Decoder{Bzip2DecoderStdlib, Bzip2DecoderDsnet, Bzip2DecoderParallel} {
+		processor := NewMarketDataProcessorWithConfig(ProcessorConfig{OutputPath: t.TempDir(), Bzip2Decoder: decoder})
+		if err := processor.ProcessFile(path); err != nil {
+			t.Fatalf("%s: ProcessFile failed: %v", decoder, err)
+		}
+		if runner := processor.MarketStates["1.bzip"].Runners[123]; runner == nil || runner.MaxTV != 7999*7 {
+			t.Errorf("%s: Expected every update decoded, got %+v", decoder, runner)
+		}
+	}
+}
+
+func TestLongLines(t *testing.T) {
+	// A market definition past bufio.Scanner's 64KB limit, such as a huge SUB_IMAGE
+	var runners []string
+	for i := 1; i <= 1500; i++ {
+		runners = append(runners, fmt.Sprintf(`{"id":%d,"name":"%d. Runner With A Long Name","status":"ACTIVE"}`, i, i))
+	}
+	long := `{"op":"mcm","pt":1633024800000,"mc":[{"id":"1.long","marketDefinition":{"eventTypeId":"4339","marketType":"WIN","bettingType":"ODDS","eventName":"Test Track R1","marketTime":"2025-09-29T12:00:00Z","runners":[` +
+		strings.Join(runners, ",") + `]}}]}`
+	if len(long) <= 64<<10 {
+		t.Fatalf("Expected a line over 64KB, got %d bytes", len(long))
+	}
+	data := long + "\r\n" + `{"op":"mcm","pt":1633024801000,"mc":[{"id":"1.long","rc":[{"id":1500,"ltp":2.4,"tv":10}]}]}`
+
+	processor := NewMarketDataProcessorWithConfig(ProcessorConfig{OutputPath: t.TempDir()})
+	if err := processor.processReader(strings.NewReader(data), "long"); err != nil {
+		t.Fatalf("processReader failed: %v", err)
+	}
+	if runner := processor.MarketStates["1.long"].Runners[1500]; runner == nil || runner.LatestLTP != 2.4 {
+		t.Errorf("Expected the line after the long one read, got %+v", runner)
+	}
+
+	processor = NewMarketDataProcessorWithConfig(ProcessorConfig{OutputPath: t.TempDir(), MaxLineBytes: 1024})
+	err := processor.processReader(strings.NewReader(historicMarket("1.short")+data), "long")
+	if err == nil || !strings.Contains(err.Error(), "line 3 for market 1.long") {
+		t.Errorf("Expected an error naming the long line's market, got %v", err)
+	}
+}
+
+func TestDuplicateMarketsPreferTheirOwnFile(t *testing.T) {
+	own := historicMarket("1.500") +
+		`{"op":"mcm","pt":1633024802000,"mc":[{"id":"1.500","rc":[{"id":123,"ltp":3.5,"tv":200}]}]}` + "\n" +
+		`{"op":"mcm","pt":1633024802000,"mc":[{"id":"1.500","rc":[{"id":123,"ltp":3.5,"tv":200}]}]}` + "\n"
+	// 1.400's file also recorded 1.500, with a different price
+	contaminated := historicMarket("1.400") +
+		`{"op":"mcm","pt":1633024803000,"mc":[{"id":"1.500","rc":[{"id":123,"ltp":9.0,"tv":999}]}]}` + "\n"
+
+	inputDir := t.TempDir()
+	for name, data := range map[string]string{"1.400.json": contaminated, "1.500.json": own} {
+		if err := os.WriteFile(filepath.Join(inputDir, name), []byte(data), 0644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+
+	for run := 0; run < 5; run++ {
+		processor := NewMarketDataProcessorWithConfig(ProcessorConfig{OutputPath: t.TempDir(), Workers: 2})
+		if err := processor.ProcessPath(inputDir); err != nil {
+			t.Fatalf("ProcessPath failed: %v", err)
+		}
+		marketState := processor.MarketStates["1.500"]
+		if runner := marketState.Runners[123]; runner.LatestLTP != 3.5 || runner.MaxTV != 200 {
+			t.Fatalf("Expected 1.500 built from its own file only, got %+v", runner)
+		}
+		if marketState.RepeatedChanges != 1 {
+			t.Errorf("Expected the repeated change skipped, got %d", marketState.RepeatedChanges)
+		}
+
+		var duplicates []QualityIssue
+		for _, issue := range processor.QualityIssues {
+			if issue.Kind == IssueDuplicateMarket {
+				duplicates = append(duplicates, issue)
+			}
+		}
+		if len(duplicates) != 1 || duplicates[0].MarketID != "1.500" || filepath.Base(duplicates[0].Source) != "1.400.json" ||
+			!strings.Contains(duplicates[0].Detail, "1 changes skipped; kept "+filepath.Join(inputDir, "1.500.json")) {
+			t.Errorf("Expected 1.500's copy in 1.400.json reported, got %+v", duplicates)
+		}
+	}
+}
+
+func TestPerMarketOutputMirrorsInputLayout(t *testing.T) {
+	inputDir := t.TempDir()
+	for name, marketID := range map[string]string{"2025/Sep/29/1.400.json": "1.400", "2025/Sep/30/1.500.json": "1.500"} {
+		path := filepath.Join(inputDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("create %s: %v", name, err)
+		}
+		if err := os.WriteFile(path, []byte(historicMarket(marketID)), 0644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+
+	outputDir := t.TempDir()
+	processor := NewMarketDataProcessorWithConfig(ProcessorConfig{
+		OutputPath:   outputDir,
+		OutputFormat: OutputFormatParquet,
+		Mode:         OutputModeTicks,
+		PerMarket:    true,
+		Workers:      2,
+	})
+	if err := processor.ProcessPath(inputDir); err != nil {
+		t.Fatalf("ProcessPath failed: %v", err)
+	}
+	if err := processor.FinalizeProcessing(); err != nil {
+		t.Fatalf("FinalizeProcessing failed: %v", err)
+	}
+
+	for dir, marketID := range map[string]string{"2025/Sep/29": "1.400", "2025/Sep/30": "1.500"} {
+		base := filepath.Join(outputDir, filepath.FromSlash(dir), marketID)
+		summaries, err := parquet.ReadFile[SummaryRow](base + ".parquet")
+		if err != nil {
+			t.Fatalf("read %s summary: %v", marketID, err)
+		}
+		if len(summaries) != 1 || summaries[0].MarketID != marketID {
+			t.Errorf("Expected %s's summary row in its own file, got %+v", marketID, summaries)
+		}
+		ticks, err := parquet.ReadFile[TickRow](base + "_ticks.parquet")
+		if err != nil {
+			t.Fatalf("read %s ticks: %v", marketID, err)
+		}
+		if len(ticks) != 1 || ticks[0].MarketID != marketID || ticks[0].LTP != 2.4 {
+			t.Errorf("Expected %s's tick in its own file, got %+v", marketID, ticks)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, "greyhound_win_markets_ticks.parquet")); !os.IsNotExist(err) {
+		t.Errorf("Expected no combined ticks file, got %v", err)
+	}
+}
+
+func TestNameMapNormalisesVenuesAndRunners(t *testing.T) {
+	dir := t.TempDir()
+	csvPath := filepath.Join(dir, "names.csv")
+	csvData := "kind,from,to\n" +
+		"venue,ROMFORD,Romford Stadium\n" +
+		"runner_rule,\\s*\\(Res\\)$,\n" +
+		"runner,swift,SWIFT\n"
+	if err := os.WriteFile(csvPath, []byte(csvData), 0644); err != nil {
+		t.Fatalf("write name map: %v", err)
+	}
+
+	processor := NewMarketDataProcessorWithConfig(ProcessorConfig{OutputPath: t.TempDir(), Workers: 1, NameMapPath: csvPath})
+	line := `{"op":"mcm","pt":1759670400000,"mc":[{"id":"1.names","marketDefinition":{"eventTypeId":"4339","marketType":"WIN","bettingType":"ODDS","eventName":"Romford (GB) 5th Oct","marketTime":"2025-10-05T13:30:00Z","runners":[{"id":1,"name":"1. Swift (Res)","status":"ACTIVE"},{"id":2,"name":"2. Other","status":"ACTIVE"}]}}]}`
+	var message MCMMessage
+	if err := json.Unmarshal([]byte(line), &message); err != nil {
+		t.Fatalf("decode message: %v", err)
+	}
+	processor.processMCMMessage(&message)
+
+	marketState := processor.MarketStates["1.names"]
+	if marketState.Venue != "Romford Stadium" {
+		t.Errorf("Expected the venue alias applied, got %q", marketState.Venue)
+	}
+	if name := marketState.Runners[1].Name; name != "SWIFT" {
+		t.Errorf("Expected the runner rule and alias applied, got %q", name)
+	}
+	if name := marketState.Runners[2].Name; name != "Other" {
+		t.Errorf("Expected an unmapped runner left alone, got %q", name)
+	}
+
+	jsonPath := filepath.Join(dir, "names.json")
+	jsonData := `{"venues":{"the  meadows":"The Meadows"},"runnerRules":[{"pattern":"^(\\w+) Jnr$","replace":"$1 Junior"}]}`
+	if err := os.WriteFile(jsonPath, []byte(jsonData), 0644); err != nil {
+		t.Fatalf("write name map: %v", err)
+	}
+	names, err := LoadNameMap(jsonPath)
+	if err != nil {
+		t.Fatalf("LoadNameMap failed: %v", err)
+	}
+	if venue := names.venue("The Meadows "); venue != "The Meadows" {
+		t.Errorf("Expected venues matched ignoring case and spacing, got %q", venue)
+	}
+	if runner := names.runner("Bob Jnr"); runner != "Bob Junior" {
+		t.Errorf("Expected the runner rule's groups substituted, got %q", runner)
+	}
+
+	if err := os.WriteFile(csvPath, []byte("kind,from,to\ntrack,a,b\n"), 0644); err != nil {
+		t.Fatalf("write name map: %v", err)
+	}
+	if _, err := LoadNameMap(csvPath); err == nil || !strings.Contains(err.Error(), "unknown kind") {
+		t.Errorf("Expected an unknown kind rejected, got %v", err)
+	}
+}
+
+func TestBucketTimezone(t *testing.T) {
+	// 00:30 on the 6th in Sydney, the last race of an evening meeting that began on the 5th UTC
+	line := `{"op":"mcm","pt":1759670400000,"mc":[{"id":"1.tz","marketDefinition":{"eventTypeId":"4339","marketType":"WIN","bettingType":"ODDS","eventName":"Wentworth Park (AUS) 6th Oct","marketTime":"2025-10-05T13:30:00Z","timezone":"Australia/Sydney","runners":[{"id":1,"name":"1. Swift","status":"ACTIVE"}]}}]}`
+
+	tests := []struct {
+		timezone string
+		from     time.Time
+		day      int
+	}{
+		{"", time.Time{}, 5},
+		{"Australia/Sydney", time.Time{}, 6},
+		{BucketTimezoneMarket, time.Time{}, 6},
+		{"Mars/Olympus_Mons", time.Time{}, 5},
+		{"Australia/Sydney", time.Date(2025, 10, 6, 0, 0, 0, 0, time.UTC), 6},
+	}
+	for _, tt := range tests {
+		processor := NewMarketDataProcessorWithConfig(ProcessorConfig{OutputPath: t.TempDir(), Workers: 1, BucketTimezone: tt.timezone, From: tt.from})
+		var message MCMMessage
+		if err := json.Unmarshal([]byte(line), &message); err != nil {
+			t.Fatalf("decode message: %v", err)
+		}
+		processor.processMCMMessage(&message)
+
+		rows := processor.finalizeMarket("1.tz")
+		if len(rows) != 1 {
+			t.Fatalf("%q: expected one row, got %d", tt.timezone, len(rows))
+		}
+		if rows[0].Year != 2025 || rows[0].Month != 10 || rows[0].Day != tt.day {
+			t.Errorf("%q: expected 2025-10-%02d, got %d-%02d-%02d", tt.timezone, tt.day, rows[0].Year, rows[0].Month, rows[0].Day)
+		}
+		if !rows[0].MarketTime.Equal(time.Date(2025, 10, 5, 13, 30, 0, 0, time.UTC)) {
+			t.Errorf("%q: expected the market time left in UTC, got %v", tt.timezone, rows[0].MarketTime)
+		}
+	}
+
+	processor := NewMarketDataProcessorWithConfig(ProcessorConfig{OutputPath: t.TempDir(), BucketTimezone: "Australia/Sydney", From: time.Date(2025, 10, 6, 0, 0, 0, 0, time.UTC)})
+	if !processor.sourceInDateRange("s3://bucket/PRO/2025/Oct/5/1.tz.bz2") {
+		t.Error("Expected the previous UTC day's files kept when bucketing east of UTC")
+	}
+	if processor.sourceInDateRange("s3://bucket/PRO/2025/Oct/4/1.tz.bz2") {
+		t.Error("Expected files two days out skipped")
+	}
+}
+
+func TestMarketTable(t *testing.T) {
+	outputDir := t.TempDir()
+	processor := NewMarketDataProcessorWithConfig(ProcessorConfig{OutputPath: outputDir, Workers: 1, MarketTable: true})
+
+	// The off is 13:30:00, 1759671000000
+	lines := []string{
+		`{"op":"mcm","pt":1759670400000,"mc":[{"id":"1.mkt","marketDefinition":{"eventTypeId":"4339","marketType":"WIN","bettingType":"ODDS","eventName":"Romford (GB) 5th Oct","marketTime":"2025-10-05T13:30:00Z","runners":[{"id":1,"name":"1. Swift","status":"ACTIVE"},{"id":2,"name":"2. Steady","status":"ACTIVE"},{"id":3,"name":"3. Scratched","status":"REMOVED"}]}}]}`,
+		`{"op":"mcm","pt":1759670640000,"mc":[{"id":"1.mkt","rc":[{"id":1,"batb":[[0,2.0,10]],"tv":100},{"id":2,"batb":[[0,4.0,10]],"tv":50}]}]}`,
+		`{"op":"mcm","pt":1759670820000,"mc":[{"id":"1.mkt","rc":[{"id":1,"batb":[[0,2.5,10]]}]}]}`,
+		`{"op":"mcm","pt":1759670990000,"mc":[{"id":"1.mkt","rc":[{"id":2,"batb":[[0,5.0,5]]}]}]}`,
+		`{"op":"mcm","pt":1759671060000,"mc":[{"id":"1.mkt","marketDefinition":{"status":"CLOSED","runners":[{"id":1,"status":"WINNER","bsp":2.2},{"id":2,"status":"LOSER","bsp":4.5},{"id":3,"status":"REMOVED"}]}}]}`,
+	}
+	for _, line := range lines {
+		var message MCMMessage
+		if err := json.Unmarshal([]byte(line), &message); err != nil {
+			t.Fatalf("decode message: %v", err)
+		}
+		processor.processMCMMessage(&message)
+	}
+	if err := processor.FinalizeProcessing(); err != nil {
+		t.Fatalf("FinalizeProcessing failed: %v", err)
+	}
+
+	if len(processor.MarketData) != 1 {
+		t.Fatalf("Expected one market row, got %d", len(processor.MarketData))
+	}
+	row := processor.MarketData[0]
+	near := func(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+	// 5m before the off the book was 2.0 and 4.0; from 3m it was 2.5 and 4.0 until 10s before
+	if !near(row.Overround5m, 0.75) || !near(row.Overround1m, 0.65) || !near(row.Overround30s, 0.65) {
+		t.Errorf("Unexpected overrounds %g, %g, %g", row.Overround5m, row.Overround1m, row.Overround30s)
+	}
+	if row.Runners != 2 || row.Scratchings != 1 || row.TotalMatched != 150 {
+		t.Errorf("Unexpected counts %+v", row)
+	}
+	if row.FavouriteID != 1 || row.FavouriteBSP != 2.2 || row.WinnerID != 1 || row.WinnerBSP != 2.2 {
+		t.Errorf("Unexpected favourite and winner %+v", row)
+	}
+
+	data, err := os.ReadFile(filepath.Join(outputDir, "greyhound_win_markets_markets.csv"))
+	if err != nil {
+		t.Fatalf("read market table: %v", err)
+	}
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		t.Fatalf("parse market table: %v", err)
+	}
+	if len(records) != 2 || records[1][0] != "1.mkt" || records[1][9] != "2" || records[1][17] != "1" {
+		t.Errorf("Unexpected market table %v", records)
+	}
+}
+
+func TestScratchedRunners(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "summary.parquet")
+	processor := NewMarketDataProcessorWithConfig(ProcessorConfig{OutputPath: output, OutputFormat: OutputFormatParquet, Workers: 1})
+	lines := []string{
+		`{"op":"mcm","pt":1759670400000,"mc":[{"id":"1.scr","marketDefinition":{"eventTypeId":"4339","marketType":"WIN","bettingType":"ODDS","eventName":"Romford (GB) 5th Oct","marketTime":"2025-10-05T13:30:00Z","runners":[{"id":1,"name":"1. Swift","status":"ACTIVE","adjustmentFactor":60.5},{"id":2,"name":"2. Steady","status":"ACTIVE","adjustmentFactor":12.3}]}}]}`,
+		`{"op":"mcm","pt":1759670700000,"mc":[{"id":"1.scr","marketDefinition":{"runners":[{"id":1,"status":"ACTIVE","adjustmentFactor":68.9},{"id":2,"status":"REMOVED","removalDate":"2025-10-05T13:25:00.000Z","adjustmentFactor":12.3}]}}]}`,
+	}
+	for _, line := range lines {
+		var message MCMMessage
+		if err := json.Unmarshal([]byte(line), &message); err != nil {
+			t.Fatalf("decode message: %v", err)
+		}
+		processor.processMCMMessage(&message)
+	}
+
+	rows := processor.finalizeMarket("1.scr")
+	sort.Slice(rows, func(i, j int) bool { return rows[i].SelectionID < rows[j].SelectionID })
+	if len(rows) != 2 {
+		t.Fatalf("Expected both runners' rows, got %d", len(rows))
+	}
+	if rows[0].Scratched || !rows[0].RemovalDate.IsZero() || rows[0].AdjustmentFactor != 68.9 {
+		t.Errorf("Unexpected running runner %+v", rows[0])
+	}
+	removed := time.Date(2025, 10, 5, 13, 25, 0, 0, time.UTC)
+	if !rows[1].Scratched || !rows[1].RemovalDate.Equal(removed) || rows[1].AdjustmentFactor != 12.3 {
+		t.Errorf("Unexpected scratched runner %+v", rows[1])
+	}
+	record := make(map[string]string)
+	for i, value := range summaryRecord(rows[1]) {
+		record[summaryHeader[i]] = value
+	}
+	if record["scratched"] != "true" || record["removal_date"] != "2025-10-05T13:25:00Z" || record["adjustment_factor"] != "12.3" {
+		t.Errorf("Unexpected CSV columns %v", record)
+	}
+
+	processor.ProcessedData = rows
+	if err := processor.FinalizeProcessing(); err != nil {
+		t.Fatalf("FinalizeProcessing failed: %v", err)
+	}
+	written, err := parquet.ReadFile[SummaryRow](output)
+	if err != nil {
+		t.Fatalf("read summary: %v", err)
+	}
+	sort.Slice(written, func(i, j int) bool { return written[i].SelectionID < written[j].SelectionID })
+	if len(written) != 2 || !written[1].Scratched || !written[1].RemovalDate.Equal(removed) || written[1].AdjustmentFactor != 12.3 || !written[0].RemovalDate.IsZero() {
+		t.Errorf("Unexpected Parquet rows %+v", written)
+	}
+}
+
+func TestStreamingOutput(t *testing.T) {
+	inputDir := t.TempDir()
+	for _, marketID := range []string{"1.601", "1.602", "1.603"} {
+		data := historicMarket(marketID) +
+			`{"op":"mcm","pt":1633024803000,"mc":[{"id":"` + marketID + `","marketDefinition":{"status":"CLOSED","runners":[{"id":123,"status":"WINNER","bsp":2.5}]}}]}` + "\n"
+		if err := os.WriteFile(filepath.Join(inputDir, marketID+".json"), []byte(data), 0644); err != nil {
+			t.Fatalf("write %s: %v", marketID, err)
+		}
+	}
+
+	for _, format := range []OutputFormat{OutputFormatCSV, OutputFormatParquet} {
+		outputDir := t.TempDir()
+		processor := NewMarketDataProcessorWithConfig(ProcessorConfig{OutputPath: outputDir, OutputFormat: format, Workers: 2, Streaming: true})
+		if err := processor.ProcessPath(inputDir); err != nil {
+			t.Fatalf("%s: ProcessPath failed: %v", format, err)
+		}
+		if len(processor.ProcessedData) != 0 {
+			t.Errorf("%s: expected rows streamed rather than held, got %d held", format, len(processor.ProcessedData))
+		}
+		if err := processor.FinalizeProcessing(); err != nil {
+			t.Fatalf("%s: FinalizeProcessing failed: %v", format, err)
+		}
+
+		outputPath := filepath.Join(outputDir, "greyhound_win_markets."+string(format))
+		var marketIDs []string
+		if format == OutputFormatParquet {
+			rows, err := parquet.ReadFile[SummaryRow](outputPath)
+			if err != nil {
+				t.Fatalf("read streamed parquet: %v", err)
+			}
+			for _, row := range rows {
+				marketIDs = append(marketIDs, row.MarketID)
+			}
+		} else {
+			data, err := os.ReadFile(outputPath)
+			if err != nil {
+				t.Fatalf("read streamed csv: %v", err)
+			}
+			records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+			if err != nil {
+				t.Fatalf("parse streamed csv: %v", err)
+			}
+			for _, record := range records[1:] {
+				marketIDs = append(marketIDs, record[0])
+			}
+		}
+		sort.Strings(marketIDs)
+		if !reflect.DeepEqual(marketIDs, []string{"1.601", "1.602", "1.603"}) {
+			t.Errorf("%s: expected every market streamed once, got %v", format, marketIDs)
+		}
+	}
+
+	buffered := NewMarketDataProcessorWithConfig(ProcessorConfig{OutputPath: t.TempDir(), Streaming: true, JoinWinPlace: true})
+	if buffered.Config.Streaming {
+		t.Error("Expected streaming turned off for joined WIN and PLACE rows")
+	}
+}
+
+func TestProcessSendsRowsOnAChannel(t *testing.T) {
+	inputDir := t.TempDir()
+	for _, marketID := range []string{"1.701", "1.702", "1.703"} {
+		data := historicMarket(marketID) +
+			`{"op":"mcm","pt":1633024803000,"mc":[{"id":"` + marketID + `","marketDefinition":{"status":"CLOSED","runners":[{"id":123,"status":"WINNER","bsp":2.5}]}}]}` + "\n"
+		if err := os.WriteFile(filepath.Join(inputDir, marketID+".json"), []byte(data), 0644); err != nil {
+			t.Fatalf("write %s: %v", marketID, err)
+		}
+	}
+	// Left open, so finalized once every input is read
+	openFile := filepath.Join(t.TempDir(), "1.704.json")
+	if err := os.WriteFile(openFile, []byte(historicMarket("1.704")), 0644); err != nil {
+		t.Fatalf("write 1.704: %v", err)
+	}
+
+	outputDir := t.TempDir()
+	processor := NewMarketDataProcessorWithConfig(ProcessorConfig{OutputPath: outputDir, Workers: 2})
+	rows, errs := processor.Process(context.Background(), []string{inputDir, openFile})
+	var marketIDs []string
+	for row := range rows {
+		marketIDs = append(marketIDs, row.MarketID)
+	}
+	if err := <-errs; err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+	sort.Strings(marketIDs)
+	if !reflect.DeepEqual(marketIDs, []string{"1.701", "1.702", "1.703", "1.704"}) {
+		t.Errorf("Expected a row per market, got %v", marketIDs)
+	}
+	if len(processor.ProcessedData) != 0 || len(processor.MarketStates) != 0 {
+		t.Errorf("Expected nothing held after Process, got %d rows and %d markets", len(processor.ProcessedData), len(processor.MarketStates))
+	}
+	if entries, _ := os.ReadDir(outputDir); len(entries) != 0 {
+		t.Errorf("Expected no output files, got %d", len(entries))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	rows, errs = NewMarketDataProcessorWithConfig(ProcessorConfig{OutputPath: t.TempDir()}).Process(ctx, []string{inputDir})
+	for range rows {
+		t.Error("Expected no rows once cancelled")
+	}
+	if err := <-errs; !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the context's error, got %v", err)
+	}
+}
+
+func TestVerifyReportsFileIntegrity(t *testing.T) {
+	inputDir := t.TempDir()
+	files := map[string]string{
+		"1.801.json": `{"op":"mcm","clk":"100","pt":1633024800000,"mc":[{"id":"1.801","marketDefinition":{"status":"OPEN","runners":[{"id":1,"status":"ACTIVE"}]}}]}` + "\n" +
+			`{"op":"mcm","clk":"101","pt":1633024801000,"mc":[{"id":"1.801","rc":[{"id":1,"ltp":2.4}]}]}` + "\n" +
+			`{"op":"mcm","clk":"102","pt":1633024802000,"mc":[{"id":"1.801","marketDefinition":{"status":"CLOSED","runners":[{"id":1,"status":"WINNER"}]}}]}` + "\n",
+		"1.802.json": `{"op":"mcm","clk":"200","pt":1633024801000,"mc":[{"id":"1.802","marketDefinition":{"status":"OPEN","runners":[{"id":1,"status":"ACTIVE"}]}}]}` + "\n" +
+			`{"op":"mcm","clk":"199","pt":1633024800000,"mc":[{"id":"1.802","rc":[{"id":1,"ltp":2.4}]}]}` + "\n" +
+			`{"op":"mcm","pt":1633024802000,"mc":[{"id":"1.802","rc":[{"id":1,"ltp":2.5}]}]}` + "\n" +
+			`{"op":"mcm","clk":` + "\n",
+		"1.803.bz2": "BZh91AY&SY this is not bzip2",
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(inputDir, name), []byte(data), 0644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+
+	processor := NewMarketDataProcessorWithConfig(ProcessorConfig{OutputPath: t.TempDir(), Workers: 2})
+	report, err := processor.Verify(inputDir)
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if report.Files != 3 || report.Failed != 2 {
+		t.Fatalf("Expected 3 files with 2 failing, got %d with %d failing", report.Files, report.Failed)
+	}
+
+	checks := make(map[string]FileCheck)
+	for _, check := range report.Checks {
+		checks[filepath.Base(check.Path)] = check
+	}
+	good := checks["1.801.json"]
+	if !good.OK || good.Lines != 3 || good.FirstPt != 1633024800000 || good.LastPt != 1633024802000 ||
+		!reflect.DeepEqual(good.Markets, []string{"1.801"}) {
+		t.Errorf("Expected a clean file, got %+v", good)
+	}
+
+	gappy := checks["1.802.json"]
+	if gappy.OK || !gappy.Decompressed || gappy.Lines != 4 || gappy.BadLines != 1 || gappy.MissingClk != 1 ||
+		gappy.ClkRegressions != 1 || gappy.PtRegressions != 1 {
+		t.Errorf("Expected the gaps counted, got %+v", gappy)
+	}
+	if !reflect.DeepEqual(gappy.Unclosed, []string{"1.802"}) || !reflect.DeepEqual(gappy.Unsettled, []string{"1.802"}) {
+		t.Errorf("Expected 1.802 unclosed and unsettled, got %v and %v", gappy.Unclosed, gappy.Unsettled)
+	}
+
+	if corrupt := checks["1.803.bz2"]; corrupt.OK || corrupt.Decompressed || corrupt.Error == "" {
+		t.Errorf("Expected the corrupt archive reported, got %+v", corrupt)
+	}
+
+	data, err := json.Marshal(report)
+	if err != nil || !bytes.Contains(data, []byte(`"clkRegressions":1`)) {
+		t.Errorf("Expected a JSON report, got %s (%v)", data, err)
+	}
+}
+
+func TestEnrichedAndOfficialSchemas(t *testing.T) {
+	closed := func(marketID string) string {
+		return `{"op":"mcm","pt":1633024803000,"mc":[{"id":"` + marketID + `","marketDefinition":{"status":"CLOSED","runners":[{"id":123,"status":"WINNER","bsp":2.5}]}}]}` + "\n"
+	}
+	schemas := []struct {
+		name       string
+		definition string
+	}{
+		// Betfair's official files: the trap number is part of the name
+		{"official", `"eventTypeId":"4339","runners":[{"id":123,"name":"1. Test Dog","status":"ACTIVE"}]`},
+		// Enriched by the recorder, which writes the catalogue's runner name to name
+		{"enriched", `"eventTypeId":"4339","eventTypeName":"Greyhound Racing","runners":[{"id":123,"name":"Test Dog","sortPriority":1,"status":"ACTIVE"}]`},
+		// Enriched with the catalogue's field names and no event type ID
+		{"catalogue names", `"eventTypeName":"Greyhound Racing","runners":[{"id":123,"runnerName":"Test Dog","sortPriority":1,"status":"ACTIVE"}]`},
+		// Both names present, as a re-enriched official file would have
+		{"both names", `"eventTypeId":"4339","runners":[{"id":123,"name":"1. Test Dog","runnerName":"Catalogue Dog","status":"ACTIVE"}]`},
+	}
+
+	for i, schema := range schemas {
+		marketID := fmt.Sprintf("1.90%d", i)
+		data := `{"op":"mcm","pt":1633024800000,"mc":[{"id":"` + marketID + `","marketDefinition":{"marketType":"WIN","bettingType":"ODDS","eventName":"Test Track R1","marketTime":"2025-09-29T12:00:00Z",` + schema.definition + `}}]}` + "\n" +
+			`{"op":"mcm","pt":1633024801000,"mc":[{"id":"` + marketID + `","rc":[{"id":123,"ltp":2.4,"tv":100.5}]}]}` + "\n" + closed(marketID)
+		inputPath := filepath.Join(t.TempDir(), marketID+".json")
+		if err := os.WriteFile(inputPath, []byte(data), 0644); err != nil {
+			t.Fatalf("%s: write: %v", schema.name, err)
+		}
+
+		processor := NewMarketDataProcessorWithConfig(ProcessorConfig{OutputPath: t.TempDir()})
+		if err := processor.ProcessFile(inputPath); err != nil {
+			t.Fatalf("%s: ProcessFile failed: %v", schema.name, err)
+		}
+		if len(processor.ProcessedData) != 1 {
+			t.Fatalf("%s: expected the greyhound market summarised, got %d rows", schema.name, len(processor.ProcessedData))
+		}
+		row := processor.ProcessedData[0]
+		if row.GreyhoundName != "Test Dog" || row.EventTypeID != "4339" {
+			t.Errorf("%s: expected Test Dog in event type 4339, got %q in %q", schema.name, row.GreyhoundName, row.EventTypeID)
+		}
+	}
+
+	var unknown MarketDefinition
+	if err := json.Unmarshal([]byte(`{"eventTypeName":"Cricket","runners":[{"id":1,"runnerName":"Team A"}]}`), &unknown); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if unknown.EventTypeID != "" || unknown.Runners[0].Name != "Team A" {
+		t.Errorf("Expected an unknown event type left unset and the runner named, got %q and %q", unknown.EventTypeID, unknown.Runners[0].Name)
+	}
+}
+
+func TestStitchesAMarketAcrossFiles(t *testing.T) {
+	inputDir := t.TempDir()
+	reconnectDir := filepath.Join(inputDir, "reconnect")
+	if err := os.MkdirAll(reconnectDir, 0755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	repeated := `{"op":"mcm","pt":1633024801000,"mc":[{"id":"1.950","rc":[{"id":123,"ltp":2.4,"tv":100.5}]}]}` + "\n"
+	files := map[string]string{
+		// Rotated segments: the second repeats the last message of the first
+		filepath.Join(inputDir, "1.950.part001.json"): historicMarket("1.950"),
+		filepath.Join(inputDir, "1.950.part002.json"): repeated +
+			`{"op":"mcm","pt":1633024802000,"mc":[{"id":"1.950","rc":[{"id":123,"ltp":2.2,"tv":150}]}]}` + "\n",
+		// The final part, recorded after a reconnect in another folder
+		filepath.Join(reconnectDir, "1.950.json"): `{"op":"mcm","pt":1633024803000,"mc":[{"id":"1.950","marketDefinition":{"status":"CLOSED","runners":[{"id":123,"status":"WINNER","bsp":2.5}]}}]}` + "\n",
+	}
+	for path, data := range files {
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatalf("write %s: %v", path, err)
+		}
+	}
+
+	processor := NewMarketDataProcessorWithConfig(ProcessorConfig{OutputPath: t.TempDir(), Workers: 4})
+	if err := processor.ProcessPath(inputDir); err != nil {
+		t.Fatalf("ProcessPath failed: %v", err)
+	}
+	if len(processor.ProcessedData) != 1 || len(processor.MarketStates) != 0 {
+		t.Fatalf("Expected one finalized row for 1.950, got %d rows and %d open markets", len(processor.ProcessedData), len(processor.MarketStates))
+	}
+	row := processor.ProcessedData[0]
+	if !row.Win || row.BSP != 2.5 || row.LTP != 2.2 || row.TotalTradedVolume != 150 {
+		t.Errorf("Expected the row built from every part, got %+v", row)
+	}
+	if processor.FilesProcessed != 3 {
+		t.Errorf("Expected 3 files processed, got %d", processor.FilesProcessed)
+	}
+	for _, issue := range processor.QualityIssues {
+		if issue.Kind == IssueDuplicateMarket {
+			t.Errorf("Expected no parts skipped as duplicates, got %+v", issue)
+		}
+	}
+
+	groups := processor.stitchGroups([]string{"a/1.950.part001.bz2", "b/1.951.bz2", "a/1.950.bz2", "c/1.950.part002.bz2"})
+	if !reflect.DeepEqual(groups, [][]string{{"a/1.950.bz2", "a/1.950.part001.bz2", "c/1.950.part002.bz2"}, {"b/1.951.bz2"}}) {
+		t.Errorf("Expected a market's files grouped behind its unnumbered file, got %v", groups)
+	}
+}
+
+func TestGzipAndZstdInputs(t *testing.T) {
+	closedMarket := func(marketID string) []byte {
+		return []byte(historicMarket(marketID) +
+			`{"op":"mcm","pt":1633024803000,"mc":[{"id":"` + marketID + `","marketDefinition":{"status":"CLOSED","runners":[{"id":123,"status":"WINNER","bsp":2.5}]}}]}` + "\n")
+	}
+	gzipped := func(data []byte) []byte {
+		var compressed bytes.Buffer
+		writer := gzip.NewWriter(&compressed)
+		writer.Write(data)
+		writer.Close()
+		return compressed.Bytes()
+	}
+	zstded := func(data []byte) []byte {
+		encoder, err := zstd.NewWriter(nil)
+		if err != nil {
+			t.Fatalf("create zstd writer: %v", err)
+		}
+		defer encoder.Close()
+		return encoder.EncodeAll(data, nil)
+	}
+	tarred := func(entries map[string][]byte) []byte {
+		var archive bytes.Buffer
+		writer := tar.NewWriter(&archive)
+		for name, data := range entries {
+			writer.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), Typeflag: tar.TypeReg})
+			writer.Write(data)
+		}
+		writer.Close()
+		return archive.Bytes()
+	}
+
+	inputDir := t.TempDir()
+	files := map[string][]byte{
+		"1.961.gz":  gzipped(closedMarket("1.961")),
+		"1.962.zst": zstded(closedMarket("1.962")),
+		// A compressed archive holding a compressed entry and a further archive
+		"day.tar.gz": gzipped(tarred(map[string][]byte{
+			"PRO/1.963.zst": zstded(closedMarket("1.963")),
+			"venue.tar":     tarred(map[string][]byte{"PRO/1.964.gz": gzipped(closedMarket("1.964"))}),
+		})),
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(inputDir, name), data, 0644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+
+	processor := NewMarketDataProcessorWithConfig(ProcessorConfig{OutputPath: t.TempDir(), Workers: 2})
+	if err := processor.ProcessPath(inputDir); err != nil {
+		t.Fatalf("ProcessPath failed: %v", err)
+	}
+	var marketIDs []string
+	for _, row := range processor.ProcessedData {
+		marketIDs = append(marketIDs, row.MarketID)
+	}
+	sort.Strings(marketIDs)
+	if !reflect.DeepEqual(marketIDs, []string{"1.961", "1.962", "1.963", "1.964"}) {
+		t.Errorf("Expected every compressed market read, got %v", marketIDs)
+	}
+
+	report, err := processor.Verify(inputDir)
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if report.Files != 4 {
+		t.Errorf("Expected 4 files verified, got %d", report.Files)
+	}
+	for _, check := range report.Checks {
+		if !check.Decompressed || check.Lines != 3 || check.BadLines != 0 {
+			t.Errorf("Expected %s read cleanly, got %+v", check.Path, check)
+		}
+	}
+}
+
+func TestRunnerBoxNumbers(t *testing.T) {
+	data := `{"op":"mcm","pt":1633024800000,"mc":[{"id":"1.970","marketDefinition":{"eventTypeId":"4339","marketType":"WIN","bettingType":"ODDS","eventName":"Test Track R1","marketTime":"2025-09-29T12:00:00Z","runners":[` +
+		`{"id":1,"name":"1. Fast Dog","status":"ACTIVE"},{"id":8,"name":"8. 10 Bob Bet","status":"ACTIVE"},{"id":9,"name":"Reserve Dog","status":"ACTIVE"}]}}]}` + "\n" +
+		// An enriched definition renames the runners without their numbers
+		`{"op":"mcm","pt":1633024803000,"mc":[{"id":"1.970","marketDefinition":{"status":"CLOSED","runners":[{"id":1,"name":"Fast Dog","status":"WINNER","bsp":2.5},{"id":8,"status":"LOSER"},{"id":9,"status":"LOSER"}]}}]}` + "\n"
+	inputPath := filepath.Join(t.TempDir(), "1.970.json")
+	if err := os.WriteFile(inputPath, []byte(data), 0644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	outputDir := t.TempDir()
+	processor := NewMarketDataProcessorWithConfig(ProcessorConfig{OutputPath: outputDir})
+	if err := processor.ProcessFile(inputPath); err != nil {
+		t.Fatalf("ProcessFile failed: %v", err)
+	}
+	boxes := make(map[int64]int)
+	names := make(map[int64]string)
+	for _, row := range processor.ProcessedData {
+		boxes[row.SelectionID], names[row.SelectionID] = row.Box, row.GreyhoundName
+	}
+	if !reflect.DeepEqual(boxes, map[int64]int{1: 1, 8: 8, 9: 0}) {
+		t.Errorf("Expected boxes 1 and 8 and none for the reserve, got %v", boxes)
+	}
+	if names[8] != "10 Bob Bet" {
+		t.Errorf("Expected the number stripped from the name, got %q", names[8])
+	}
+
+	for _, row := range processor.ProcessedData {
+		record := make(map[string]string)
+		for i, value := range summaryRecord(row) {
+			record[summaryHeader[i]] = value
+		}
+		if want := map[int64]string{1: "1", 8: "8", 9: ""}[row.SelectionID]; record["box"] != want {
+			t.Errorf("Expected box %q in runner %d's CSV record, got %q", want, row.SelectionID, record["box"])
+		}
+	}
+
+	parquetPath := filepath.Join(outputDir, "boxes.parquet")
+	if err := writeParquetRows(processor, parquetPath, newSummaryParquetRows(processor.ProcessedData), summarySchema); err != nil {
+		t.Fatalf("write parquet: %v", err)
+	}
+	type boxColumn struct {
+		SelectionID int64 `parquet:"selection_id"`
+		Box         *int  `parquet:"box,optional"`
+	}
+	rows, err := parquet.ReadFile[boxColumn](parquetPath)
+	if err != nil {
+		t.Fatalf("read parquet: %v", err)
+	}
+	for _, row := range rows {
+		if (row.Box == nil) != (row.SelectionID == 9) || (row.Box != nil && int64(*row.Box) != row.SelectionID) {
+			t.Errorf("Unexpected box for runner %d: %v", row.SelectionID, row.Box)
+		}
+	}
+}
+
+func TestExtractDateFromVersion2Layout(t *testing.T) {
+	p := NewMarketDataProcessor("", 0, 0)
+	for _, path := range []string{
+		"s3://bucket/archive/v2/year=2025/month=09/day=30/event=34567/1.1.bz2",
+		"s3://bucket/archive/PRO/2025/Sep/30/34567/1.1.bz2",
+	} {
+		date, err := p.ExtractDateFromPath(path)
+		if err != nil || !date.Equal(time.Date(2025, 9, 30, 0, 0, 0, 0, time.UTC)) {
+			t.Errorf("ExtractDateFromPath(%q) = %v, %v", path, date, err)
+		}
+	}
+}
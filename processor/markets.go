@@ -0,0 +1,235 @@
+package processor
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// overroundOffsets are the times before the scheduled off a market's overround is taken at, in the
+// order they pass
+var overroundOffsets = [...]time.Duration{5 * time.Minute, time.Minute, 30 * time.Second}
+
+// MarketRow is a market-level summary, written alongside the runner-level summary rows when
+// Config.MarketTable is set
+type MarketRow struct {
+	MarketID     string    `parquet:"market_id"`
+	EventID      string    `parquet:"event_id"`
+	EventName    string    `parquet:"event_name"`
+	Venue        string    `parquet:"venue"`
+	MarketType   string    `parquet:"market_type"`
+	MarketTime   time.Time `parquet:"market_time,timestamp(microsecond)"`
+	Year         int       `parquet:"year"`
+	Month        int       `parquet:"month"`
+	Day          int       `parquet:"day"`
+	Runners      int       `parquet:"runners"`     // Runners not removed
+	Scratchings  int       `parquet:"scratchings"` // Runners removed
+	TotalMatched float64   `parquet:"total_matched"`
+	Overround5m  float64   `parquet:"overround_5m,optional"` // Sum of 1/best back price 5 minutes before the off
+	Overround1m  float64   `parquet:"overround_1m,optional"`
+	Overround30s float64   `parquet:"overround_30s,optional"`
+	FavouriteID  int64     `parquet:"favourite_id,optional"` // Runner with the shortest BSP
+	FavouriteBSP float64   `parquet:"favourite_bsp,optional"`
+	WinnerID     int64     `parquet:"winner_id,optional"` // Set when exactly one runner won
+	WinnerBSP    float64   `parquet:"winner_bsp,optional"`
+}
+
+var marketHeader = []string{
+	"market_id", "event_id", "event_name", "venue", "market_type", "market_time", "year", "month", "day",
+	"runners", "scratchings", "total_matched", "overround_5m", "overround_1m", "overround_30s",
+	"favourite_id", "favourite_bsp", "winner_id", "winner_bsp",
+}
+
+var marketsTable = sqlTable[MarketRow]{
+	name: "markets",
+	columns: []sqlColumn{
+		{"market_id", "VARCHAR"}, {"event_id", "VARCHAR"}, {"event_name", "VARCHAR"}, {"venue", "VARCHAR"},
+		{"market_type", "VARCHAR"}, {"market_time", "TIMESTAMPTZ"}, {"year", "INTEGER"}, {"month", "INTEGER"},
+		{"day", "INTEGER"}, {"runners", "INTEGER"}, {"scratchings", "INTEGER"},
+		{"total_matched", "DOUBLE PRECISION"}, {"overround_5m", "DOUBLE PRECISION"},
+		{"overround_1m", "DOUBLE PRECISION"}, {"overround_30s", "DOUBLE PRECISION"},
+		{"favourite_id", "BIGINT"}, {"favourite_bsp", "DOUBLE PRECISION"},
+		{"winner_id", "BIGINT"}, {"winner_bsp", "DOUBLE PRECISION"},
+	},
+	indexes: [][]string{{"market_id"}, {"market_time"}, {"venue"}},
+	values: func(row MarketRow) []any {
+		return []any{
+			row.MarketID, row.EventID, row.EventName, row.Venue, row.MarketType, row.MarketTime,
+			row.Year, row.Month, row.Day, row.Runners, row.Scratchings, row.TotalMatched,
+			optional(row.Overround5m, row.Overround5m != 0), optional(row.Overround1m, row.Overround1m != 0),
+			optional(row.Overround30s, row.Overround30s != 0), optional(row.FavouriteID, row.FavouriteID != 0),
+			optional(row.FavouriteBSP, row.FavouriteBSP != 0), optional(row.WinnerID, row.WinnerID != 0),
+			optional(row.WinnerBSP, row.WinnerBSP != 0),
+		}
+	},
+}
+
+// takeOverrounds records the market's overround at each offset before timestamp (epoch millis),
+// before the change published at timestamp is applied
+func (marketState *MarketState) takeOverrounds(timestamp int64) {
+	for marketState.overroundsTaken < len(overroundOffsets) {
+		at := marketState.MarketTime.Add(-overroundOffsets[marketState.overroundsTaken])
+		if marketState.MarketTime.IsZero() || timestamp <= at.UnixMilli() {
+			return
+		}
+		marketState.overrounds[marketState.overroundsTaken] = marketState.overround()
+		marketState.overroundsTaken++
+	}
+}
+
+// overround is the sum of the implied probabilities of the runners' best back prices
+func (marketState *MarketState) overround() float64 {
+	total := 0.0
+	for _, runner := range marketState.Runners {
+		if runner.Status == "REMOVED" {
+			continue
+		}
+		if levels := runner.book.backLevels(1); len(levels) > 0 && levels[0][0] > 1 {
+			total += 1 / levels[0][0]
+		}
+	}
+	return total
+}
+
+// marketRow summarises a market being finalized
+func (p *MarketDataProcessor) marketRow(marketID string, marketState *MarketState, raceDay time.Time) MarketRow {
+	// Offsets the recording ended before left the book as it finished
+	if marketState.lastPt > 0 {
+		for i := marketState.overroundsTaken; i < len(overroundOffsets); i++ {
+			marketState.overrounds[i] = marketState.overround()
+		}
+	}
+
+	row := MarketRow{
+		MarketID:     marketID,
+		EventID:      marketState.EventID,
+		EventName:    marketState.EventName,
+		Venue:        marketState.Venue,
+		MarketType:   marketState.MarketType,
+		MarketTime:   marketState.MarketTime,
+		Year:         raceDay.Year(),
+		Month:        int(raceDay.Month()),
+		Day:          raceDay.Day(),
+		Overround5m:  marketState.overrounds[0],
+		Overround1m:  marketState.overrounds[1],
+		Overround30s: marketState.overrounds[2],
+	}
+	winners := 0
+	for runnerID, runner := range marketState.Runners {
+		row.TotalMatched += runner.MaxTV
+		if runner.Status == "REMOVED" {
+			row.Scratchings++
+			continue
+		}
+		row.Runners++
+		if runner.BSP > 0 && (row.FavouriteBSP == 0 || runner.BSP < row.FavouriteBSP ||
+			(runner.BSP == row.FavouriteBSP && runnerID < row.FavouriteID)) {
+			row.FavouriteID, row.FavouriteBSP = runnerID, runner.BSP
+		}
+		if runner.Status == "WINNER" {
+			winners++
+			row.WinnerID, row.WinnerBSP = runnerID, runner.BSP
+		}
+	}
+	if winners != 1 {
+		row.WinnerID, row.WinnerBSP = 0, 0
+	}
+	return row
+}
+
+func marketRecord(row MarketRow) []string {
+	optionalID := func(id int64) string {
+		if id == 0 {
+			return ""
+		}
+		return strconv.FormatInt(id, 10)
+	}
+	return []string{
+		row.MarketID, row.EventID, row.EventName, row.Venue, row.MarketType,
+		row.MarketTime.Format(time.RFC3339), strconv.Itoa(row.Year), strconv.Itoa(row.Month),
+		strconv.Itoa(row.Day), strconv.Itoa(row.Runners), strconv.Itoa(row.Scratchings),
+		strconv.FormatFloat(row.TotalMatched, 'f', -1, 64), formatFloat(row.Overround5m, row.Overround5m != 0),
+		formatFloat(row.Overround1m, row.Overround1m != 0), formatFloat(row.Overround30s, row.Overround30s != 0),
+		optionalID(row.FavouriteID), formatFloat(row.FavouriteBSP, row.FavouriteBSP != 0),
+		optionalID(row.WinnerID), formatFloat(row.WinnerBSP, row.WinnerBSP != 0),
+	}
+}
+
+// saveMarketTable writes the market rows to the markets table of a DuckDB or database output, or
+// otherwise next to the summary output as <name>_markets.csv or .parquet
+func (p *MarketDataProcessor) saveMarketTable(data []MarketRow) error {
+	if len(data) == 0 {
+		return nil
+	}
+	switch p.Config.OutputFormat {
+	case OutputFormatDuckDB:
+		return saveDuckDB(p, marketsTable, data)
+	case OutputFormatDatabase:
+		return p.saveDatabaseMarkets(data)
+	}
+
+	ext := ".csv"
+	if p.Config.OutputFormat == OutputFormatParquet {
+		ext = ".parquet"
+	}
+	outputPath := joinOutputPath(p.OutputDir, p.Profile.FilePrefix+"_markets"+ext)
+	if p.OutputFile != "" {
+		outputPath = strings.TrimSuffix(p.OutputFile, filepath.Ext(p.OutputFile)) + "_markets" + ext
+	}
+
+	var err error
+	if ext == ".parquet" {
+		err = writeParquetRows(p, outputPath, data)
+	} else {
+		err = p.writeMarketCSV(outputPath, data)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write market table: %w", err)
+	}
+	p.logger.Info().Str("path", outputPath).Int("markets", len(data)).Msg("created market table")
+	return nil
+}
+
+func (p *MarketDataProcessor) writeMarketCSV(outputPath string, data []MarketRow) error {
+	remote := isRemotePath(outputPath)
+	var file *os.File
+	var err error
+	if remote {
+		file, err = os.CreateTemp("", "csv-*.csv")
+		if err == nil {
+			defer os.Remove(file.Name())
+		}
+	} else {
+		if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+			return err
+		}
+		file, err = os.Create(outputPath)
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	if err := writer.Write(marketHeader); err != nil {
+		return err
+	}
+	for _, row := range data {
+		if err := writer.Write(marketRecord(row)); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to flush CSV writer: %w", err)
+	}
+
+	if remote {
+		return p.uploadToStorage(outputPath, file.Name())
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package processor
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// MCMMessage is a market change message from the stream or a historical data file
+type MCMMessage struct {
+	Op string         `json:"op"`
+	Pt int64          `json:"pt"`
+	Mc []MarketChange `json:"mc"`
+}
+
+// MarketChange is one market's part of a market change message
+type MarketChange struct {
+	ID               string            `json:"id"`
+	MarketDefinition *MarketDefinition `json:"marketDefinition"`
+	RC               []RunnerChange    `json:"rc"`
+}
+
+// MarketDefinition holds the market definition fields the processor reads. Fields a definition
+// leaves out decode to their zero values, which the processor treats as unchanged.
+//
+// Betfair's official files and the stream only name runners in "name". Recordings enriched from
+// the market catalogue by this package may also carry the catalogue's names, such as
+// eventTypeName and a runner's runnerName; decoding resolves both schemas to the same fields.
+type MarketDefinition struct {
+	EventTypeID     string             `json:"eventTypeId"`
+	EventTypeName   string             `json:"eventTypeName"` // Enriched recordings only
+	EventID         string             `json:"eventId"`
+	EventName       string             `json:"eventName"`
+	Venue           *string            `json:"venue"`
+	MarketType      string             `json:"marketType"`
+	BettingType     string             `json:"bettingType"`
+	MarketTime      string             `json:"marketTime"`
+	Timezone        string             `json:"timezone"`
+	NumberOfWinners int                `json:"numberOfWinners"`
+	Status          string             `json:"status"`
+	InPlay          bool               `json:"inPlay"`
+	BSPReconciled   bool               `json:"bspReconciled"`
+	Runners         []RunnerDefinition `json:"runners"`
+}
+
+// eventTypeIDs are the IDs of the event types profiles select, by catalogue name in lower case
+var eventTypeIDs = map[string]string{
+	"greyhound racing": "4339",
+	"horse racing":     "7",
+}
+
+// UnmarshalJSON decodes a definition, taking a missing eventTypeId from an enriched eventTypeName
+func (marketDef *MarketDefinition) UnmarshalJSON(data []byte) error {
+	type plain MarketDefinition
+	if err := json.Unmarshal(data, (*plain)(marketDef)); err != nil {
+		return err
+	}
+	if marketDef.EventTypeID == "" && marketDef.EventTypeName != "" {
+		marketDef.EventTypeID = eventTypeIDs[strings.ToLower(strings.TrimSpace(marketDef.EventTypeName))]
+	}
+	return nil
+}
+
+// RunnerDefinition is a runner in a market definition
+type RunnerDefinition struct {
+	ID     int64         `json:"id"`
+	Name   string        `json:"name"`
+	BSP    optionalFloat `json:"bsp"`
+	Status string        `json:"status"`
+
+	RunnerName string `json:"runnerName"` // The catalogue's name for the runner, in enriched recordings only
+
+	RemovalDate      string        `json:"removalDate"`      // When a REMOVED runner was taken out
+	AdjustmentFactor optionalFloat `json:"adjustmentFactor"` // Reduction factor, in percent, applied to the other runners' prices if this one is removed
+}
+
+// UnmarshalJSON decodes a runner, naming it by runnerName when it has no name. A name is preferred
+// when both are present, as it is the one official files carry.
+func (runner *RunnerDefinition) UnmarshalJSON(data []byte) error {
+	type plain RunnerDefinition
+	if err := json.Unmarshal(data, (*plain)(runner)); err != nil {
+		return err
+	}
+	if runner.Name == "" {
+		runner.Name = runner.RunnerName
+	}
+	return nil
+}
+
+// RunnerChange is a runner's price and traded volume deltas
+type RunnerChange struct {
+	ID   int64         `json:"id"`
+	LTP  optionalFloat `json:"ltp"`
+	TV   optionalFloat `json:"tv"`
+	SPN  optionalFloat `json:"spn"`
+	SPF  optionalFloat `json:"spf"`
+	BATB [][]float64   `json:"batb"`
+	BATL [][]float64   `json:"batl"`
+	ATB  [][]float64   `json:"atb"`
+	ATL  [][]float64   `json:"atl"`
+	SPB  [][]float64   `json:"spb"`
+	TRD  [][]float64   `json:"trd"`
+}
+
+// optionalFloat is a number the stream may leave out, or send as a string such as "NaN" or
+// "Infinity" for projected SPs; only numbers are kept
+type optionalFloat struct {
+	Value float64
+	Set   bool
+}
+
+func (f *optionalFloat) UnmarshalJSON(data []byte) error {
+	var value float64
+	if err := json.Unmarshal(data, &value); err == nil && string(data) != "null" {
+		f.Value, f.Set = value, true
+	}
+	return nil
+}
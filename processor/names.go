@@ -0,0 +1,132 @@
+package processor
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// NameMap normalises venue and runner names so they line up with other sources, such as form
+// data. It is applied after the profile's regular expressions. A nil *NameMap leaves names as they are.
+type NameMap struct {
+	Venues      map[string]string `json:"venues"`      // Venue alias to the name used, matched ignoring case and spacing
+	Runners     map[string]string `json:"runners"`     // Runner alias to the name used, matched ignoring case and spacing
+	RunnerRules []NameRule        `json:"runnerRules"` // Applied to runner names in order, before the aliases
+}
+
+// NameRule rewrites the matches of a regular expression in a name
+type NameRule struct {
+	Pattern string `json:"pattern"`
+	Replace string `json:"replace"` // May refer to groups as $1
+
+	regex *regexp.Regexp
+}
+
+// LoadNameMap reads a name map from a JSON file, or from a CSV file with a kind,from,to header
+// where kind is venue, runner or runner_rule (from being a regular expression for a rule)
+func LoadNameMap(path string) (*NameMap, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("read name map: %w", err)
+	}
+	defer file.Close()
+
+	names := &NameMap{}
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		err = names.readCSV(file)
+	} else {
+		err = json.NewDecoder(file).Decode(names)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("decode name map %s: %w", path, err)
+	}
+	return names, names.compile()
+}
+
+func (names *NameMap) readCSV(r io.Reader) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 3
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return err
+	}
+	for i, record := range records {
+		kind, from, to := strings.ToLower(record[0]), record[1], record[2]
+		switch {
+		case i == 0 && kind == "kind":
+		case kind == "venue":
+			names.Venues = addAlias(names.Venues, from, to)
+		case kind == "runner":
+			names.Runners = addAlias(names.Runners, from, to)
+		case kind == "runner_rule":
+			names.RunnerRules = append(names.RunnerRules, NameRule{Pattern: from, Replace: to})
+		default:
+			return fmt.Errorf("line %d: unknown kind %q (must be venue, runner or runner_rule)", i+1, record[0])
+		}
+	}
+	return nil
+}
+
+func addAlias(aliases map[string]string, from, to string) map[string]string {
+	if aliases == nil {
+		aliases = make(map[string]string)
+	}
+	aliases[from] = to
+	return aliases
+}
+
+// compile compiles the runner rules and keys the aliases by their normalised form
+func (names *NameMap) compile() error {
+	for i := range names.RunnerRules {
+		regex, err := regexp.Compile(names.RunnerRules[i].Pattern)
+		if err != nil {
+			return fmt.Errorf("runner rule %d: %w", i+1, err)
+		}
+		names.RunnerRules[i].regex = regex
+	}
+	names.Venues = normaliseAliases(names.Venues)
+	names.Runners = normaliseAliases(names.Runners)
+	return nil
+}
+
+func normaliseAliases(aliases map[string]string) map[string]string {
+	normalised := make(map[string]string, len(aliases))
+	for from, to := range aliases {
+		normalised[nameKey(from)] = to
+	}
+	return normalised
+}
+
+// nameKey is the form names are matched in: lower case, with runs of spaces collapsed
+func nameKey(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+func (names *NameMap) venue(name string) string {
+	if names == nil || name == "" {
+		return name
+	}
+	if alias, ok := names.Venues[nameKey(name)]; ok {
+		return alias
+	}
+	return name
+}
+
+func (names *NameMap) runner(name string) string {
+	if names == nil || name == "" {
+		return name
+	}
+	for _, rule := range names.RunnerRules {
+		name = strings.TrimSpace(rule.regex.ReplaceAllString(name, rule.Replace))
+	}
+	if alias, ok := names.Runners[nameKey(name)]; ok {
+		return alias
+	}
+	return name
+}
@@ -0,0 +1,49 @@
+package processor
+
+import (
+	"fmt"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/compress"
+)
+
+// ParquetCompression is the codec Parquet column pages are compressed with
+type ParquetCompression string
+
+const (
+	ParquetCompressionSnappy ParquetCompression = "snappy"
+	ParquetCompressionZstd   ParquetCompression = "zstd"
+	ParquetCompressionGzip   ParquetCompression = "gzip"
+	ParquetCompressionNone   ParquetCompression = "none"
+)
+
+// codec returns the parquet-go codec for a compression name
+func (c ParquetCompression) codec() (compress.Codec, error) {
+	switch c {
+	case ParquetCompressionSnappy:
+		return &parquet.Snappy, nil
+	case ParquetCompressionZstd:
+		return &parquet.Zstd, nil
+	case ParquetCompressionGzip:
+		return &parquet.Gzip, nil
+	case ParquetCompressionNone:
+		return &parquet.Uncompressed, nil
+	}
+	return nil, fmt.Errorf("unknown parquet compression %q", string(c))
+}
+
+// parquetWriterOptions configures Parquet writers from the processor config
+func (p *MarketDataProcessor) parquetWriterOptions() []parquet.WriterOption {
+	var options []parquet.WriterOption
+	if codec, err := p.Config.ParquetCompression.codec(); err == nil {
+		options = append(options, parquet.Compression(codec))
+	}
+	if p.Config.ParquetRowGroupSize > 0 {
+		options = append(options, parquet.MaxRowsPerRowGroup(p.Config.ParquetRowGroupSize))
+	}
+	if p.Config.ParquetDictionary {
+		// String columns such as market_id, venue and runner names repeat on every row of a market
+		options = append(options, parquet.DefaultEncodingFor(parquet.ByteArray, &parquet.RLEDictionary))
+	}
+	return options
+}
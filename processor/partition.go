@@ -0,0 +1,66 @@
+package processor
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// hiveDefaultPartition is the partition value Hive, Athena and Spark use for a missing value
+const hiveDefaultPartition = "__HIVE_DEFAULT_PARTITION__"
+
+// partitionEscaper escapes the characters that would break a key=value directory name
+var partitionEscaper = strings.NewReplacer("%", "%25", "/", "%2F", "=", "%3D", "\\", "%5C")
+
+// partitionDir is the hive-style directory of a summary row, such as year=2025/month=09/day=30
+func (p *MarketDataProcessor) partitionDir(row SummaryRow) string {
+	dir := fmt.Sprintf("year=%d/month=%02d/day=%02d", row.Year, row.Month, row.Day)
+	if p.Config.PartitionByVenue {
+		venue := hiveDefaultPartition
+		if row.Venue != "" {
+			venue = partitionEscaper.Replace(row.Venue)
+		}
+		dir += "/venue=" + venue
+	}
+	return dir
+}
+
+// savePartitions writes summary rows as one Parquet file per partition under the output
+// directory. With a manifest each run writes new files alongside those of earlier runs.
+func (p *MarketDataProcessor) savePartitions(data []SummaryRow) error {
+	partitions := make(map[string][]SummaryRow)
+	for _, row := range data {
+		dir := p.partitionDir(row)
+		partitions[dir] = append(partitions[dir], row)
+	}
+
+	dirs := make([]string, 0, len(partitions))
+	for dir := range partitions {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+
+	fileName := p.Profile.FilePrefix + ".parquet"
+	if p.manifest != nil {
+		fileName = fmt.Sprintf("%s_%s.parquet", p.Profile.FilePrefix, time.Now().UTC().Format("20060102T150405Z"))
+	}
+	for _, dir := range dirs {
+		outputPath := joinOutputPath(p.OutputDir, dir+"/"+fileName)
+		if err := writeParquetRows(p, outputPath, newSummaryParquetRows(partitions[dir]), summarySchema); err != nil {
+			return fmt.Errorf("failed to write partition %s: %w", dir, err)
+		}
+	}
+
+	p.logger.Info().Int("partitions", len(dirs)).Int("records", len(data)).Str("path", p.OutputDir).Msg("created partitions")
+	return nil
+}
+
+// joinOutputPath joins a slash-separated name onto a local or object storage output directory
+func joinOutputPath(dir, name string) string {
+	if isRemotePath(dir) {
+		return strings.TrimSuffix(dir, "/") + "/" + name
+	}
+	return filepath.Join(dir, filepath.FromSlash(name))
+}
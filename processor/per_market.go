@@ -0,0 +1,126 @@
+package processor
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// noteInputRoot records a path given to ProcessPath, which per-market output mirrors the layout of
+func (p *MarketDataProcessor) noteInputRoot(inputPath string) {
+	if !isRemotePath(inputPath) {
+		if abs, err := filepath.Abs(inputPath); err == nil {
+			inputPath = abs
+		}
+	}
+	p.mu.Lock()
+	p.inputRoots = append(p.inputRoots, inputPath)
+	p.mu.Unlock()
+}
+
+// marketDir is the slash-separated directory of a market's source below the input path it was
+// found under, such as 2025/Sep/30. An archive entry's folders are added to the archive's own.
+func (p *MarketDataProcessor) marketDir(source string) string {
+	source, entry, inArchive := strings.Cut(source, "!")
+	if i := strings.LastIndex(entry, "!"); i >= 0 {
+		entry = entry[i+1:] // The entry of an archive within the archive
+	}
+	dir := ""
+	for _, root := range p.inputRoots {
+		var rel string
+		if isRemotePath(source) {
+			if !strings.HasPrefix(source, root) {
+				continue
+			}
+			rel = strings.TrimLeft(strings.TrimPrefix(source, root), "/")
+		} else {
+			abs, err := filepath.Abs(source)
+			if err != nil {
+				continue
+			}
+			if rel, err = filepath.Rel(root, abs); err != nil || strings.HasPrefix(rel, "..") {
+				continue
+			}
+			rel = filepath.ToSlash(rel)
+		}
+		dir = path.Dir(rel)
+		break
+	}
+	if inArchive {
+		dir = path.Join(dir, path.Dir(entry))
+	}
+	if dir == "." {
+		return ""
+	}
+	return dir
+}
+
+// savePerMarket writes each market's summary rows to a file of its own, named after the market,
+// with its ticks or ladder snapshots alongside in those modes. Files are placed under the output
+// directory in the folders of the market's source, so the output mirrors the input layout.
+func (p *MarketDataProcessor) savePerMarket(summaries []SummaryRow) error {
+	type marketRows struct {
+		summaries []SummaryRow
+		ticks     []TickRow
+		ladders   []LadderSnapshot
+	}
+	markets := make(map[string]*marketRows)
+	rows := func(marketID string) *marketRows {
+		if markets[marketID] == nil {
+			markets[marketID] = &marketRows{}
+		}
+		return markets[marketID]
+	}
+	for _, row := range summaries {
+		rows(row.MarketID).summaries = append(rows(row.MarketID).summaries, row)
+	}
+	for _, row := range p.TickData {
+		rows(row.MarketID).ticks = append(rows(row.MarketID).ticks, row)
+	}
+	for _, row := range p.LadderData {
+		rows(row.MarketID).ladders = append(rows(row.MarketID).ladders, row)
+	}
+	if len(markets) == 0 {
+		p.logger.Info().Msg("no data to save")
+		return nil
+	}
+
+	marketIDs := make([]string, 0, len(markets))
+	for marketID := range markets {
+		marketIDs = append(marketIDs, marketID)
+	}
+	sort.Strings(marketIDs)
+
+	p.claimsMu.Lock()
+	owners := p.claims.owners
+	p.claimsMu.Unlock()
+
+	outputDir := p.OutputDir
+	for _, marketID := range marketIDs {
+		market := markets[marketID]
+		base := joinOutputPath(outputDir, path.Join(p.marketDir(owners[marketID]), marketID))
+
+		var err error
+		switch {
+		case len(market.summaries) == 0:
+		case p.Config.OutputFormat == OutputFormatParquet:
+			err = writeParquetRows(p, base+".parquet", newSummaryParquetRows(market.summaries), summarySchema)
+		default:
+			err = p.saveSingleCSV(base+".csv", market.summaries)
+		}
+		if err == nil && len(market.ticks) > 0 {
+			err = writeParquetRows(p, base+"_ticks.parquet", market.ticks)
+		}
+		if err == nil && len(market.ladders) > 0 {
+			err = writeParquetRows(p, base+"_ladders.parquet", market.ladders)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to write market %s: %w", marketID, err)
+		}
+	}
+
+	p.logger.Info().Int("markets", len(marketIDs)).Str("path", outputDir).Msg("created per-market output")
+	return nil
+}
@@ -0,0 +1,197 @@
+package processor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+)
+
+const (
+	defaultDownloadConcurrency = 4
+	defaultPrefetchBytes       = 256 << 20
+)
+
+// prefetchEntry is an object the prefetcher downloads ahead of the parse workers
+type prefetchEntry struct {
+	index    int   // Position in download order; memory is reserved in this order
+	size     int64 // Listed size, reserved against the memory budget
+	ready    chan struct{}
+	data     []byte
+	err      error
+	reserved bool // Counted against the budget
+	done     bool // The worker is finished with it
+}
+
+// prefetcher downloads remote objects into memory ahead of the parse workers, with its own
+// concurrency, so parsing isn't stalled on each object's round trips. Memory is reserved in the
+// order the workers take files, so the objects held are always the next ones needed. Objects
+// larger than the budget, of unknown size or already in the cache are left for the workers to
+// read themselves. A nil *prefetcher prefetches nothing.
+type prefetcher struct {
+	p       *MarketDataProcessor
+	ctx     context.Context
+	cancel  context.CancelFunc
+	mu      sync.Mutex
+	cond    *sync.Cond
+	entries map[string]*prefetchEntry
+	budget  int64
+	held    int64
+	next    int // Index of the next entry allowed to reserve memory
+	stopped bool
+	wg      sync.WaitGroup
+}
+
+// startPrefetch starts downloading the remote files of paths, or returns nil when there are none
+func (p *MarketDataProcessor) startPrefetch(paths []string) *prefetcher {
+	var queue []string
+	entries := make(map[string]*prefetchEntry)
+	for _, path := range paths {
+		if !isRemotePath(path) {
+			continue
+		}
+		size := p.sources[path].size
+		if size <= 0 || size > p.Config.PrefetchBytes || p.cached(path) {
+			continue
+		}
+		entries[path] = &prefetchEntry{index: len(queue), size: size, ready: make(chan struct{})}
+		queue = append(queue, path)
+	}
+	if len(queue) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	pf := &prefetcher{p: p, ctx: ctx, cancel: cancel, entries: entries, budget: p.Config.PrefetchBytes}
+	pf.cond = sync.NewCond(&pf.mu)
+
+	jobs := make(chan string, len(queue))
+	for _, path := range queue {
+		jobs <- path
+	}
+	close(jobs)
+	for range min(p.Config.DownloadConcurrency, len(queue)) {
+		pf.wg.Add(1)
+		go func() {
+			defer pf.wg.Done()
+			for path := range jobs {
+				if !pf.download(path) {
+					return
+				}
+			}
+		}()
+	}
+	return pf
+}
+
+// download fetches one object once memory is reserved for it, returning false once stopped
+func (pf *prefetcher) download(path string) bool {
+	entry := pf.entries[path]
+	if !pf.reserve(entry) {
+		return false
+	}
+
+	data, err := pf.fetch(path, entry.size)
+
+	pf.mu.Lock()
+	defer pf.mu.Unlock()
+	entry.data, entry.err = data, err
+	if entry.done {
+		pf.release(entry)
+	}
+	close(entry.ready)
+	return true
+}
+
+func (pf *prefetcher) fetch(path string, size int64) ([]byte, error) {
+	body, err := pf.p.openRemote(pf.ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	buffer := bytes.NewBuffer(make([]byte, 0, size))
+	if _, err := buffer.ReadFrom(body); err != nil {
+		return nil, fmt.Errorf("failed to read object: %w", err)
+	}
+	return buffer.Bytes(), nil
+}
+
+// reserve waits for the entry's turn and room in the budget; an entry always fits when nothing
+// else is held. It returns false once the prefetcher is stopped.
+func (pf *prefetcher) reserve(entry *prefetchEntry) bool {
+	pf.mu.Lock()
+	defer pf.mu.Unlock()
+	for !pf.stopped && (entry.index != pf.next || (pf.held > 0 && pf.held+entry.size > pf.budget)) {
+		pf.cond.Wait()
+	}
+	if pf.stopped {
+		return false
+	}
+	pf.next++
+	pf.held += entry.size
+	entry.reserved = true
+	pf.cond.Broadcast()
+	return true
+}
+
+// release returns an entry's memory to the budget; pf.mu must be held
+func (pf *prefetcher) release(entry *prefetchEntry) {
+	if entry.reserved {
+		pf.held -= entry.size
+		entry.reserved = false
+		entry.data = nil
+		pf.cond.Broadcast()
+	}
+}
+
+// open returns the downloaded body of a prefetched object, waiting for it if needed, or false
+// when the object isn't prefetched and should be streamed
+func (pf *prefetcher) open(path string) (io.ReadCloser, bool, error) {
+	if pf == nil {
+		return nil, false, nil
+	}
+	entry, ok := pf.entries[path]
+	if !ok {
+		return nil, false, nil
+	}
+	<-entry.ready
+	if entry.err != nil {
+		return nil, true, entry.err
+	}
+	return io.NopCloser(bytes.NewReader(entry.data)), true, nil
+}
+
+// done frees the memory of a file the worker is finished with, or has skipped
+func (pf *prefetcher) done(path string) {
+	if pf == nil {
+		return
+	}
+	entry, ok := pf.entries[path]
+	if !ok {
+		return
+	}
+	pf.mu.Lock()
+	defer pf.mu.Unlock()
+	entry.done = true
+	select {
+	case <-entry.ready:
+		pf.release(entry)
+	default:
+		// Released by download when it finishes
+	}
+}
+
+// stop cancels the downloads still queued or running and waits for them to return
+func (pf *prefetcher) stop() {
+	if pf == nil {
+		return
+	}
+	pf.cancel()
+	pf.mu.Lock()
+	pf.stopped = true
+	pf.cond.Broadcast()
+	pf.mu.Unlock()
+	pf.wg.Wait()
+}
@@ -0,0 +1,80 @@
+package processor
+
+import (
+	"context"
+	"sort"
+)
+
+// processRowBuffer is how many rows Process queues before workers wait for the consumer
+const processRowBuffer = 256
+
+// Process processes inputs, local or object storage paths as ProcessPath takes, sending each
+// market's summary rows as the market is finalized rather than writing output files. Markets still
+// open once every input is read are finalized last. The rows channel is closed when processing
+// stops; the error channel then receives the error that stopped it, if any, and is closed.
+// Cancelling ctx stops processing with ctx's error. Rows are only sent in summary mode, and WIN
+// and PLACE rows aren't joined.
+func (p *MarketDataProcessor) Process(ctx context.Context, inputs []string) (<-chan SummaryRow, <-chan error) {
+	rows := make(chan SummaryRow, processRowBuffer)
+	errs := make(chan error, 1)
+	p.sink, p.sinkCtx = rows, ctx
+
+	go func() {
+		defer close(errs)
+		err := p.process(ctx, inputs)
+		p.sink, p.sinkCtx = nil, nil
+		close(rows)
+		if err != nil {
+			errs <- err
+		}
+	}()
+	return rows, errs
+}
+
+func (p *MarketDataProcessor) process(ctx context.Context, inputs []string) error {
+	for _, input := range inputs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := p.ProcessPath(input); err != nil {
+			if p.cancelled() {
+				return ctx.Err()
+			}
+			return err
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	marketIDs := make([]string, 0, len(p.MarketStates))
+	for marketID := range p.MarketStates {
+		marketIDs = append(marketIDs, marketID)
+	}
+	sort.Strings(marketIDs)
+	for _, marketID := range marketIDs {
+		if err := p.emitRows(p.finalizeMarket(marketID)); err != nil {
+			return err
+		}
+	}
+	return ctx.Err()
+}
+
+// sendRows sends rows to Process's channel, giving up when its context is done
+func (p *MarketDataProcessor) sendRows(rows []SummaryRow) error {
+	for _, row := range rows {
+		select {
+		case p.sink <- row:
+		case <-p.sinkCtx.Done():
+			return p.sinkCtx.Err()
+		}
+	}
+	return nil
+}
+
+// cancelled reports whether the context of a running Process is done
+func (p *MarketDataProcessor) cancelled() bool {
+	if p.parent != nil {
+		return p.parent.cancelled()
+	}
+	return p.sinkCtx != nil && p.sinkCtx.Err() != nil
+}
@@ -0,0 +1,91 @@
+package processor
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// SportProfile describes which markets the processor summarises and how it reads their names
+type SportProfile struct {
+	Name         string
+	EventTypeIDs []string       // Event types to keep; empty keeps every event type
+	MarketTypes  []string       // Market types to keep; empty keeps every market type
+	BettingTypes []string       // Betting types to keep; empty keeps every betting type
+	VenueRegex   *regexp.Regexp // Stripped from event names to leave the venue; nil leaves the venue to the market definition
+	RunnerRegex  *regexp.Regexp // Stripped from runner names to leave the runner's name; nil keeps raw names
+	FilePrefix   string         // Prefix of monthly summary files
+}
+
+// GreyhoundProfile summarises greyhound WIN markets, such as "Romford (GB) 1st Oct" and "1. Swift Sally"
+var GreyhoundProfile = SportProfile{
+	Name:         "greyhounds",
+	EventTypeIDs: []string{"4339"},
+	MarketTypes:  []string{"WIN"},
+	BettingTypes: []string{"ODDS"},
+	VenueRegex:   regexp.MustCompile(`\s*\([A-Z]{2,3}\)\s*\d+\w*\s*\w+`),
+	RunnerRegex:  regexp.MustCompile(`^\d+\.\s*`),
+	FilePrefix:   "greyhound_win_markets",
+}
+
+// HorseRacingProfile summarises horse racing WIN markets, whose event names are a venue and
+// date such as "Ascot 5th Oct" or "Flem (AUS) 1st Nov"
+var HorseRacingProfile = SportProfile{
+	Name:         "horse-racing",
+	EventTypeIDs: []string{"7"},
+	MarketTypes:  []string{"WIN"},
+	BettingTypes: []string{"ODDS"},
+	VenueRegex:   regexp.MustCompile(`\s+\d{1,2}(st|nd|rd|th)\s+[A-Za-z]{3}$`),
+	RunnerRegex:  regexp.MustCompile(`^\d+\.\s*`),
+	FilePrefix:   "horse_racing_win_markets",
+}
+
+// GenericProfile summarises every market of every sport, such as football or tennis, keeping
+// event and runner names as Betfair publishes them
+var GenericProfile = SportProfile{
+	Name:       "generic",
+	FilePrefix: "markets",
+}
+
+// ProfileByName looks up a profile by name; empty selects greyhounds
+func ProfileByName(name string) (SportProfile, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "greyhounds", "greyhound":
+		return GreyhoundProfile, nil
+	case "horse-racing", "horses", "horse":
+		return HorseRacingProfile, nil
+	case "generic", "all":
+		return GenericProfile, nil
+	default:
+		return SportProfile{}, fmt.Errorf("unknown sport profile %q", name)
+	}
+}
+
+// Matches reports whether a market definition passes the profile's event, market and betting type filter
+func (s SportProfile) Matches(marketDef *MarketDefinition) bool {
+	if marketDef.EventTypeID == "" || !matchesAny(s.EventTypeIDs, marketDef.EventTypeID) {
+		return false
+	}
+
+	if marketDef.MarketType == "" || !matchesAny(s.MarketTypes, marketDef.MarketType) {
+		return false
+	}
+
+	if marketDef.BettingType == "" || !matchesAny(s.BettingTypes, marketDef.BettingType) {
+		return false
+	}
+
+	return true
+}
+
+func matchesAny(allowed []string, value string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, candidate := range allowed {
+		if strings.EqualFold(candidate, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,139 @@
+package processor
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	progressLogInterval = 10 * time.Second       // Between progress log lines when stderr isn't a terminal
+	progressBarInterval = 200 * time.Millisecond // Between redraws of the progress bar
+	progressBarWidth    = 30
+)
+
+// progress reports how far processFilesParallel has got: files done of the total, rows emitted,
+// rates and an ETA. It redraws a bar on a terminal and logs periodically otherwise. A nil
+// *progress reports nothing.
+type progress struct {
+	mu     sync.Mutex
+	logger zerolog.Logger
+	bar    io.Writer // Set when drawing a bar
+	total  int
+	files  int
+	rows   int
+	start  time.Time
+	drawn  time.Time
+	now    func() time.Time
+	every  time.Duration
+}
+
+// startProgress returns the reporter for a run over total files, or nil when Config.Progress is off
+func (p *MarketDataProcessor) startProgress(total int) *progress {
+	if !p.Config.Progress {
+		return nil
+	}
+	var bar io.Writer
+	if isTerminal(os.Stderr) {
+		bar = os.Stderr
+	}
+	return newProgress(total, p.logger, bar, time.Now)
+}
+
+func newProgress(total int, logger zerolog.Logger, bar io.Writer, now func() time.Time) *progress {
+	every := progressLogInterval
+	if bar != nil {
+		every = progressBarInterval
+	}
+	start := now()
+	return &progress{logger: logger, bar: bar, total: total, start: start, drawn: start, now: now, every: every}
+}
+
+// fileDone records a finished file and the rows it emitted
+func (pr *progress) fileDone(rows int) {
+	if pr == nil {
+		return
+	}
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+
+	pr.files++
+	pr.rows += rows
+	if now := pr.now(); now.Sub(pr.drawn) >= pr.every {
+		pr.drawn = now
+		pr.report(now)
+	}
+}
+
+// finish reports the final totals, ending the bar's line
+func (pr *progress) finish() {
+	if pr == nil {
+		return
+	}
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+
+	now := pr.now()
+	if pr.bar != nil {
+		pr.report(now)
+		fmt.Fprintln(pr.bar)
+	}
+	elapsed := now.Sub(pr.start)
+	pr.logger.Info().Int("files", pr.files).Int("rows", pr.rows).Dur("elapsed", elapsed).
+		Float64("files_per_sec", rate(pr.files, elapsed)).Float64("rows_per_sec", rate(pr.rows, elapsed)).Msg("processing finished")
+}
+
+// report draws the bar or logs a progress line
+func (pr *progress) report(now time.Time) {
+	elapsed := now.Sub(pr.start)
+	eta := pr.eta(elapsed)
+	if pr.bar != nil {
+		fmt.Fprintf(pr.bar, "\r%s", pr.line(elapsed, eta))
+		return
+	}
+	pr.logger.Info().Int("files", pr.files).Int("total", pr.total).Int("rows", pr.rows).
+		Float64("files_per_sec", rate(pr.files, elapsed)).Float64("rows_per_sec", rate(pr.rows, elapsed)).
+		Dur("eta", eta).Msg("progress")
+}
+
+// eta estimates the time left from the rate files have been done at so far
+func (pr *progress) eta(elapsed time.Duration) time.Duration {
+	if pr.files == 0 || pr.files >= pr.total {
+		return 0
+	}
+	return time.Duration(float64(elapsed) / float64(pr.files) * float64(pr.total-pr.files))
+}
+
+// line renders the progress bar, such as "[=====>    ] 12/40 files 30% | 1234 rows | 2.1 files/s 580 rows/s | ETA 13s"
+func (pr *progress) line(elapsed, eta time.Duration) string {
+	fraction := 1.0
+	if pr.total > 0 {
+		fraction = min(float64(pr.files)/float64(pr.total), 1)
+	}
+	filled := int(fraction * progressBarWidth)
+	bar := strings.Repeat("=", filled)
+	if filled < progressBarWidth {
+		bar += ">" + strings.Repeat(" ", progressBarWidth-filled-1)
+	}
+	return fmt.Sprintf("[%s] %d/%d files %3.0f%% | %d rows | %.1f files/s %.0f rows/s | ETA %s",
+		bar, pr.files, pr.total, fraction*100, pr.rows, rate(pr.files, elapsed), rate(pr.rows, elapsed), eta.Round(time.Second))
+}
+
+// rate is count per second over elapsed
+func rate(count int, elapsed time.Duration) float64 {
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(count) / elapsed.Seconds()
+}
+
+// isTerminal reports whether f is a character device such as an interactive terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
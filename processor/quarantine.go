@@ -0,0 +1,115 @@
+package processor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrorPolicy is what processing does when a file fails
+type ErrorPolicy string
+
+const (
+	ErrorPolicyContinue ErrorPolicy = "continue"  // Process every other file and report the failures
+	ErrorPolicyFailFast ErrorPolicy = "fail-fast" // Stop at the first failure and return it
+)
+
+// quarantineReportName is the report of failed files written in Config.QuarantineDir
+const quarantineReportName = "failures.json"
+
+// FileError is a file that failed to process
+type FileError struct {
+	Path        string `json:"path"`
+	Error       string `json:"error"`
+	Quarantined string `json:"quarantined,omitempty"` // The copy in Config.QuarantineDir
+}
+
+// recordFailure notes a failed file, copying it into the quarantine directory when one is set
+func (p *MarketDataProcessor) recordFailure(filePath string, err error) {
+	failure := FileError{Path: filePath, Error: err.Error()}
+	if p.Config.QuarantineDir != "" {
+		if quarantined, err := p.quarantine(filePath); err != nil {
+			p.logger.Warn().Err(err).Str("file", filePath).Msg("failed to quarantine file")
+		} else {
+			failure.Quarantined = quarantined
+		}
+	}
+
+	p.mu.Lock()
+	p.FileErrors = append(p.FileErrors, failure)
+	p.mu.Unlock()
+}
+
+// quarantine copies a file into Config.QuarantineDir under its base name, numbering copies of
+// files that share one
+func (p *MarketDataProcessor) quarantine(filePath string) (string, error) {
+	var source io.ReadCloser
+	var err error
+	if isRemotePath(filePath) {
+		source, err = p.openRemote(context.Background(), filePath)
+	} else {
+		source, err = os.Open(filePath)
+	}
+	if err != nil {
+		return "", err
+	}
+	defer source.Close()
+
+	if err := os.MkdirAll(p.Config.QuarantineDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create quarantine directory: %w", err)
+	}
+
+	p.quarantineMu.Lock()
+	defer p.quarantineMu.Unlock()
+
+	base := path.Base(filepath.ToSlash(filePath))
+	ext := filepath.Ext(base)
+	target := filepath.Join(p.Config.QuarantineDir, base)
+	for n := 2; ; n++ {
+		if _, err := os.Stat(target); os.IsNotExist(err) {
+			break
+		}
+		target = filepath.Join(p.Config.QuarantineDir, fmt.Sprintf("%s-%d%s", strings.TrimSuffix(base, ext), n, ext))
+	}
+
+	file, err := os.Create(target)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(file, source); err != nil {
+		file.Close()
+		return "", fmt.Errorf("failed to copy %s: %w", filePath, err)
+	}
+	return target, file.Close()
+}
+
+// saveQuarantineReport writes the failed files to failures.json in Config.QuarantineDir
+func (p *MarketDataProcessor) saveQuarantineReport() error {
+	if err := os.MkdirAll(p.Config.QuarantineDir, 0755); err != nil {
+		return fmt.Errorf("failed to create quarantine directory: %w", err)
+	}
+
+	p.mu.RLock()
+	report := struct {
+		GeneratedAt time.Time   `json:"generatedAt"`
+		Failures    []FileError `json:"failures"`
+	}{time.Now().UTC(), p.FileErrors}
+	data, err := json.MarshalIndent(report, "", "  ")
+	p.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	reportPath := filepath.Join(p.Config.QuarantineDir, quarantineReportName)
+	if err := os.WriteFile(reportPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write quarantine report: %w", err)
+	}
+	p.logger.Info().Str("path", reportPath).Int("failures", len(report.Failures)).Msg("wrote quarantine report")
+	return nil
+}
@@ -0,0 +1,161 @@
+package processor
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// QualityIssue kinds
+const (
+	IssueMissingWinner    = "missing_winner"    // No runner was settled as a winner
+	IssueZeroVolume       = "zero_volume"       // Nothing was matched on the market
+	IssueMissingBSP       = "missing_bsp"       // Runners still running have no BSP
+	IssueContaminatedFile = "contaminated_file" // A market file holds other markets than the one it is named after
+)
+
+// QualityIssue is a data-quality problem found in a market or source file
+type QualityIssue struct {
+	Kind     string `json:"kind"`
+	MarketID string `json:"marketId,omitempty"`
+	Source   string `json:"source,omitempty"`
+	Detail   string `json:"detail,omitempty"`
+}
+
+// QualityReport summarises the data-quality issues found in a run
+type QualityReport struct {
+	GeneratedAt    time.Time      `json:"generatedAt"`
+	FilesProcessed int            `json:"filesProcessed"`
+	Counts         map[string]int `json:"counts"` // Issues by kind
+	Issues         []QualityIssue `json:"issues"`
+}
+
+// checkMarketQuality records the issues of a market as it is finalized
+func (p *MarketDataProcessor) checkMarketQuality(marketID string, marketState *MarketState) {
+	winners, missingBSP, running := 0, 0, 0
+	volume := 0.0
+	for _, runner := range marketState.Runners {
+		volume += runner.MaxTV
+		if runner.Status == "WINNER" {
+			winners++
+		}
+		if runner.Status != "REMOVED" {
+			running++
+			if runner.BSP == 0 {
+				missingBSP++
+			}
+		}
+	}
+
+	if winners == 0 {
+		p.QualityIssues = append(p.QualityIssues, QualityIssue{Kind: IssueMissingWinner, MarketID: marketID,
+			Detail: fmt.Sprintf("%d runners, none settled as WINNER", len(marketState.Runners))})
+	}
+	if volume == 0 {
+		p.QualityIssues = append(p.QualityIssues, QualityIssue{Kind: IssueZeroVolume, MarketID: marketID})
+	}
+	if marketState.RepeatedChanges > 0 {
+		p.QualityIssues = append(p.QualityIssues, QualityIssue{Kind: IssueRepeatedMessages, MarketID: marketID,
+			Detail: fmt.Sprintf("%d changes skipped", marketState.RepeatedChanges)})
+	}
+	if missingBSP > 0 {
+		p.QualityIssues = append(p.QualityIssues, QualityIssue{Kind: IssueMissingBSP, MarketID: marketID,
+			Detail: fmt.Sprintf("%d of %d runners", missingBSP, running)})
+	}
+}
+
+// qualityReport builds the report of the issues found so far
+func (p *MarketDataProcessor) qualityReport() QualityReport {
+	issues := append([]QualityIssue(nil), p.QualityIssues...)
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].Kind != issues[j].Kind {
+			return issues[i].Kind < issues[j].Kind
+		}
+		if issues[i].MarketID != issues[j].MarketID {
+			return issues[i].MarketID < issues[j].MarketID
+		}
+		return issues[i].Source < issues[j].Source
+	})
+
+	counts := make(map[string]int)
+	for _, issue := range issues {
+		counts[issue.Kind]++
+	}
+	return QualityReport{
+		GeneratedAt:    time.Now().UTC(),
+		FilesProcessed: p.FilesProcessed,
+		Counts:         counts,
+		Issues:         issues,
+	}
+}
+
+// saveQualityReport writes the data-quality report to Config.ReportPath: one row per issue for a
+// .csv path, and the whole report as JSON otherwise
+func (p *MarketDataProcessor) saveQualityReport() error {
+	reportPath := p.Config.ReportPath
+	report := p.qualityReport()
+
+	localPath := reportPath
+	if isRemotePath(reportPath) {
+		tmpFile, err := os.CreateTemp("", "report-*"+filepath.Ext(reportPath))
+		if err != nil {
+			return fmt.Errorf("failed to create temp file: %w", err)
+		}
+		tmpFile.Close()
+		defer os.Remove(tmpFile.Name())
+		localPath = tmpFile.Name()
+	} else if err := os.MkdirAll(filepath.Dir(reportPath), 0755); err != nil {
+		return err
+	}
+
+	var err error
+	if strings.EqualFold(filepath.Ext(reportPath), ".csv") {
+		err = writeQualityCSV(localPath, report.Issues)
+	} else {
+		err = writeQualityJSON(localPath, report)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write quality report: %w", err)
+	}
+
+	if isRemotePath(reportPath) {
+		if err := p.uploadToStorage(reportPath, localPath); err != nil {
+			return err
+		}
+	}
+	p.logger.Info().Str("path", reportPath).Int("issues", len(report.Issues)).Msg("wrote quality report")
+	return nil
+}
+
+func writeQualityJSON(path string, report QualityReport) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+func writeQualityCSV(path string, issues []QualityIssue) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	if err := writer.Write([]string{"kind", "market_id", "source", "detail"}); err != nil {
+		return err
+	}
+	for _, issue := range issues {
+		if err := writer.Write([]string{issue.Kind, issue.MarketID, issue.Source, issue.Detail}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,21 @@
+package processor
+
+import "time"
+
+// applyRemoval records a runner definition's adjustment factor and, for a REMOVED runner, when it
+// was removed. A runner reinstated after removal loses its removal date.
+func (runnerState *RunnerState) applyRemoval(runner RunnerDefinition) {
+	if runner.AdjustmentFactor.Set {
+		runnerState.AdjustmentFactor = runner.AdjustmentFactor.Value
+		runnerState.HasAdjustment = true
+	}
+	switch runner.Status {
+	case "":
+	case "REMOVED":
+		if removed, err := time.Parse(time.RFC3339, runner.RemovalDate); err == nil {
+			runnerState.RemovalDate = removed.UTC()
+		}
+	default:
+		runnerState.RemovalDate = time.Time{}
+	}
+}
@@ -0,0 +1,137 @@
+package processor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// partSuffix matches the numbered suffix the recorder gives a rotated segment of a market's file,
+// such as the .part001 of 1.234.part001.bz2
+var partSuffix = regexp.MustCompile(`\.part\d+$`)
+
+// stitchGroups groups the files named after the same market, such as a market's rotated segments
+// or the partial recordings reconnects leave in several folders, so the market is built from all
+// of them at once. A group's first file is the one without a segment number, when there is one.
+// Files not named after a market are left in groups of their own, in order.
+func (p *MarketDataProcessor) stitchGroups(paths []string) [][]string {
+	groups := make([][]string, 0, len(paths))
+	byMarket := make(map[string]int)
+	for _, path := range paths {
+		marketID := p.extractMarketIDFromPath(path)
+		i, ok := byMarket[marketID]
+		switch {
+		case marketID == "" || isTarFile(path):
+			groups = append(groups, []string{path})
+		case !ok:
+			byMarket[marketID] = len(groups)
+			groups = append(groups, []string{path})
+		case !isSegment(path) && isSegment(groups[i][0]):
+			groups[i] = append([]string{path}, groups[i]...)
+		default:
+			groups[i] = append(groups[i], path)
+		}
+	}
+	return groups
+}
+
+// isSegment reports whether a file is a numbered segment of a market's recording
+func isSegment(path string) bool {
+	name := trimCompression(filepath.Base(path))
+	return partSuffix.MatchString(strings.TrimSuffix(strings.TrimSuffix(name, ".json"), ".jsonl"))
+}
+
+// stitchedLine is a message of a stitched market and its publish time
+type stitchedLine struct {
+	pt   int64
+	line []byte
+}
+
+// processStitched processes the files of one market as a single source named after the first,
+// their messages merged in publish time order. The files are read into memory to be merged.
+// Where they overlap, as after a reconnect, repeats of a message are skipped as repeated changes.
+func (p *MarketDataProcessor) processStitched(paths []string) error {
+	if p.FileLimit > 0 && p.filesDone() >= p.FileLimit {
+		p.logger.Info().Int("limit", p.FileLimit).Str("file", paths[0]).Msg("file limit reached; skipping")
+		return nil
+	}
+	p.logger.Debug().Strs("files", paths).Msg("stitching market files")
+
+	var lines []stitchedLine
+	for _, path := range paths {
+		fileLines, err := p.readStitchedLines(path)
+		if err != nil {
+			return err
+		}
+		lines = append(lines, fileLines...)
+	}
+	sort.SliceStable(lines, func(i, j int) bool { return lines[i].pt < lines[j].pt })
+
+	var merged bytes.Buffer
+	for _, line := range lines {
+		merged.Write(line.line)
+		merged.WriteByte('\n')
+	}
+	for range paths[1:] {
+		p.countFile()
+	}
+	return p.processReader(&merged, paths[0])
+}
+
+// readStitchedLines reads the messages of a market file with their publish times. Lines that
+// aren't messages are kept, at publish time 0, to be reported when the market is processed.
+func (p *MarketDataProcessor) readStitchedLines(path string) ([]stitchedLine, error) {
+	reader, closer, err := p.openMarketFile(path)
+	if err != nil {
+		return nil, err
+	}
+	defer closer.Close()
+
+	var messages []stitchedLine
+	lines := newLineReader(reader, p.Config.MaxLineBytes)
+	for lines.next() {
+		if err := lines.truncated(len(messages) + 1); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		var message struct {
+			Pt int64 `json:"pt"`
+		}
+		json.Unmarshal(lines.bytes(), &message)
+		messages = append(messages, stitchedLine{pt: message.Pt, line: bytes.Clone(lines.bytes())})
+	}
+	if lines.err != nil {
+		return nil, fmt.Errorf("failed to read %s after %d lines: %w", path, len(messages), lines.err)
+	}
+	return messages, nil
+}
+
+// openMarketFile opens a local or remote market file, decompressing it by its .bz2, .gz or .zst suffix
+func (p *MarketDataProcessor) openMarketFile(path string) (io.Reader, io.Closer, error) {
+	var file io.ReadCloser
+	var err error
+	if isRemotePath(path) {
+		var prefetched bool
+		if file, prefetched, err = p.prefetch.open(path); !prefetched {
+			file, err = p.openRemote(context.Background(), path)
+		}
+	} else {
+		file, err = os.Open(path)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+
+	reader, err := p.decompress(file, path)
+	if err != nil {
+		file.Close()
+		return nil, nil, fmt.Errorf("failed to decompress %s: %w", path, err)
+	}
+	return reader, file, nil
+}
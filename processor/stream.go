@@ -0,0 +1,163 @@
+package processor
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// streamRowGroupRows bounds the rows a streamed Parquet file buffers before writing a row group,
+// unless Config.ParquetRowGroupSize sets another bound
+const streamRowGroupRows = 100_000
+
+// rowStream writes summary rows to the output file as markets are finalized, so they aren't held
+// in memory until FinalizeProcessing. Rows from every worker go to the root processor's stream.
+type rowStream struct {
+	mu      sync.Mutex
+	path    string   // Output path, local or object storage
+	file    *os.File // The output file, or a temporary file uploaded to path on close
+	csv     *csv.Writer
+	parquet *parquet.GenericWriter[summaryParquetRow]
+	rows    int
+}
+
+// canStream reports whether the configured output can be written as markets finalize. Output
+// needing every row at once, such as joined WIN and PLACE rows or partitions, is always buffered.
+func (config *ProcessorConfig) canStream() bool {
+	return config.Streaming && config.Mode == OutputModeSummary && !config.JoinWinPlace && !config.Partitioned &&
+		!config.PerMarket && (config.OutputFormat == OutputFormatCSV || config.OutputFormat == OutputFormatParquet)
+}
+
+// emitRows hands a finalized market's summary rows to Process's channel or the output stream, or
+// collects them in ProcessedData otherwise. p.mu must be held.
+func (p *MarketDataProcessor) emitRows(rows []SummaryRow) error {
+	root := p
+	if p.parent != nil {
+		root = p.parent
+	}
+	switch {
+	case root.sink != nil:
+		p.rowsStreamed += len(rows)
+		return root.sendRows(rows)
+	case p.Config.Streaming:
+		p.rowsStreamed += len(rows)
+		return root.writeStream(rows)
+	}
+	p.ProcessedData = append(p.ProcessedData, rows...)
+	return nil
+}
+
+// writeStream writes rows to the stream, opening it on the first rows
+func (p *MarketDataProcessor) writeStream(rows []SummaryRow) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	p.streamMu.Lock()
+	if p.stream == nil {
+		stream, err := p.openStream()
+		if err != nil {
+			p.streamMu.Unlock()
+			return err
+		}
+		p.stream = stream
+	}
+	stream := p.stream
+	p.streamMu.Unlock()
+
+	stream.mu.Lock()
+	defer stream.mu.Unlock()
+	stream.rows += len(rows)
+	if stream.parquet != nil {
+		if _, err := stream.parquet.Write(newSummaryParquetRows(rows)); err != nil {
+			return fmt.Errorf("failed to write parquet data: %w", err)
+		}
+		return nil
+	}
+	for _, row := range rows {
+		if err := stream.csv.Write(summaryRecord(row)); err != nil {
+			return err
+		}
+	}
+	stream.csv.Flush()
+	return stream.csv.Error()
+}
+
+// openStream creates the output file: OutputFile, or <prefix>.csv or .parquet in the output directory
+func (p *MarketDataProcessor) openStream() (*rowStream, error) {
+	ext := ".csv"
+	if p.Config.OutputFormat == OutputFormatParquet {
+		ext = ".parquet"
+	}
+	stream := &rowStream{path: p.OutputFile}
+	if stream.path == "" {
+		stream.path = joinOutputPath(p.OutputDir, p.Profile.FilePrefix+ext)
+	}
+
+	var err error
+	if isRemotePath(stream.path) {
+		stream.file, err = os.CreateTemp("", "stream-*"+ext)
+	} else {
+		if err := os.MkdirAll(filepath.Dir(stream.path), 0755); err != nil {
+			return nil, err
+		}
+		stream.file, err = os.Create(stream.path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create output file: %w", err)
+	}
+
+	if ext == ".parquet" {
+		options := append([]parquet.WriterOption{parquet.MaxRowsPerRowGroup(streamRowGroupRows)}, p.parquetWriterOptions()...)
+		stream.parquet = parquet.NewGenericWriter[summaryParquetRow](stream.file, append(options, summarySchema)...)
+		return stream, nil
+	}
+	stream.csv = csv.NewWriter(stream.file)
+	if err := stream.csv.Write(summaryHeader); err != nil {
+		stream.file.Close()
+		return nil, err
+	}
+	return stream, nil
+}
+
+// finishStream writes the rows of the markets finalized last and closes the stream, uploading it
+// when the output is in object storage
+func (p *MarketDataProcessor) finishStream(rows []SummaryRow) error {
+	if err := p.writeStream(rows); err != nil {
+		return err
+	}
+	p.streamMu.Lock()
+	stream := p.stream
+	p.stream = nil
+	p.streamMu.Unlock()
+	if stream == nil {
+		p.logger.Info().Msg("no data to save")
+		return nil
+	}
+
+	var err error
+	if stream.parquet != nil {
+		err = stream.parquet.Close()
+	} else {
+		stream.csv.Flush()
+		err = stream.csv.Error()
+	}
+	if closeErr := stream.file.Close(); err == nil {
+		err = closeErr
+	}
+	if isRemotePath(stream.path) {
+		defer os.Remove(stream.file.Name())
+		if err == nil {
+			err = p.uploadToStorage(stream.path, stream.file.Name())
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to finish %s: %w", stream.path, err)
+	}
+
+	p.logger.Info().Str("path", stream.path).Int("records", stream.rows).Msg("created output")
+	return nil
+}
@@ -0,0 +1,122 @@
+package processor
+
+import (
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// summarySchema is the Parquet schema of summary files, as declared on SummaryRow
+var summarySchema = parquet.SchemaOf(SummaryRow{})
+
+// summaryParquetRow is how a SummaryRow is written to Parquet, using summarySchema. Optional
+// columns are pointers set from the row's Has* flags, so a missing value is written as null and a
+// real zero as zero; with plain optional fields parquet-go writes every zero as null, and zero
+// times as year 1.
+type summaryParquetRow struct {
+	MarketID            string     `parquet:"market_id"`
+	SelectionID         int64      `parquet:"selection_id"`
+	EventID             string     `parquet:"event_id"`
+	EventName           string     `parquet:"event_name"`
+	Venue               string     `parquet:"venue"`
+	GreyhoundName       string     `parquet:"greyhound_name"`
+	MarketTime          time.Time  `parquet:"market_time,timestamp(microsecond)"`
+	BSP                 *float64   `parquet:"bsp,optional"`
+	LTP                 *float64   `parquet:"ltp,optional"`
+	Price30sBeforeStart *float64   `parquet:"price_30s_before_start,optional"`
+	TotalTradedVolume   float64    `parquet:"total_traded_volume"`
+	MaxTradedPrice      *float64   `parquet:"max_traded_price,optional"`
+	MinTradedPrice      *float64   `parquet:"min_traded_price,optional"`
+	Year                int        `parquet:"year"`
+	Month               int        `parquet:"month"`
+	Day                 int        `parquet:"day"`
+	Win                 bool       `parquet:"win"`
+	EventTypeID         string     `parquet:"event_type_id"`
+	MarketType          string     `parquet:"market_type"`
+	NumberOfWinners     int        `parquet:"number_of_winners"`
+	Placed              bool       `parquet:"placed"`
+	PlaceMarketID       *string    `parquet:"place_market_id,optional"`
+	PlaceBSP            *float64   `parquet:"place_bsp,optional"`
+	PlaceLTP            *float64   `parquet:"place_ltp,optional"`
+	NumberOfPlaces      *int       `parquet:"number_of_places,optional"`
+	VWAP                *float64   `parquet:"vwap,optional"`
+	VolumeLast60s       *float64   `parquet:"volume_last_60s,optional"`
+	VolumeLast5m        *float64   `parquet:"volume_last_5m,optional"`
+	InPlayVolumePct     *float64   `parquet:"in_play_volume_pct,optional"`
+	SPNear              *float64   `parquet:"sp_near,optional"`
+	SPFar               *float64   `parquet:"sp_far,optional"`
+	BSPReconciled       bool       `parquet:"bsp_reconciled"`
+	BSPReconciledTime   *time.Time `parquet:"bsp_reconciled_time,optional"` // Milliseconds, from summarySchema
+	InPlayHigh          *float64   `parquet:"in_play_high,optional"`
+	InPlayLow           *float64   `parquet:"in_play_low,optional"`
+	InPlayVolume        *float64   `parquet:"in_play_volume,optional"`
+	SecondsInPlay       *float64   `parquet:"seconds_in_play,optional"`
+	Scratched           bool       `parquet:"scratched"`
+	RemovalDate         *time.Time `parquet:"removal_date,optional"` // Milliseconds, from summarySchema
+	AdjustmentFactor    *float64   `parquet:"adjustment_factor,optional"`
+	Box                 *int       `parquet:"box,optional"`
+}
+
+// optional returns a pointer to value when it is present and nil otherwise
+func optional[T any](value T, present bool) *T {
+	if !present {
+		return nil
+	}
+	return &value
+}
+
+// newSummaryParquetRow converts a summary row for writing, nulling the values it does not have
+func newSummaryParquetRow(row SummaryRow) summaryParquetRow {
+	return summaryParquetRow{
+		MarketID:            row.MarketID,
+		SelectionID:         row.SelectionID,
+		EventID:             row.EventID,
+		EventName:           row.EventName,
+		Venue:               row.Venue,
+		GreyhoundName:       row.GreyhoundName,
+		MarketTime:          row.MarketTime,
+		BSP:                 optional(row.BSP, row.HasBSP),
+		LTP:                 optional(row.LTP, row.HasLTP),
+		Price30sBeforeStart: optional(row.Price30sBeforeStart, row.HasPrice30sBefore),
+		TotalTradedVolume:   row.TotalTradedVolume,
+		MaxTradedPrice:      optional(row.MaxTradedPrice, row.HasMaxTradedPrice),
+		MinTradedPrice:      optional(row.MinTradedPrice, row.HasMinTradedPrice),
+		Year:                row.Year,
+		Month:               row.Month,
+		Day:                 row.Day,
+		Win:                 row.Win,
+		EventTypeID:         row.EventTypeID,
+		MarketType:          row.MarketType,
+		NumberOfWinners:     row.NumberOfWinners,
+		Placed:              row.Placed,
+		PlaceMarketID:       optional(row.PlaceMarketID, row.PlaceMarketID != ""),
+		PlaceBSP:            optional(row.PlaceBSP, row.HasPlaceBSP),
+		PlaceLTP:            optional(row.PlaceLTP, row.HasPlaceLTP),
+		NumberOfPlaces:      optional(row.NumberOfPlaces, row.NumberOfPlaces > 0),
+		VWAP:                optional(row.VWAP, row.HasVolumeProfile),
+		VolumeLast60s:       optional(row.VolumeLast60s, row.HasVolumeProfile),
+		VolumeLast5m:        optional(row.VolumeLast5m, row.HasVolumeProfile),
+		InPlayVolumePct:     optional(row.InPlayVolumePct, row.HasVolumeProfile),
+		SPNear:              optional(row.SPNear, row.SPNear != 0), // Projected SPs are never zero when published
+		SPFar:               optional(row.SPFar, row.SPFar != 0),
+		BSPReconciled:       row.BSPReconciled,
+		BSPReconciledTime:   optional(row.BSPReconciledTime, row.BSPReconciled),
+		InPlayHigh:          optional(row.InPlayHigh, row.HasInPlayTrades),
+		InPlayLow:           optional(row.InPlayLow, row.HasInPlayTrades),
+		InPlayVolume:        optional(row.InPlayVolume, row.HasInPlayTrades),
+		SecondsInPlay:       optional(row.SecondsInPlay, row.HasSecondsInPlay),
+		Scratched:           row.Scratched,
+		RemovalDate:         optional(row.RemovalDate, !row.RemovalDate.IsZero()),
+		AdjustmentFactor:    optional(row.AdjustmentFactor, row.HasAdjustmentFactor),
+		Box:                 optional(row.Box, row.Box != 0),
+	}
+}
+
+// newSummaryParquetRows converts summary rows for writing
+func newSummaryParquetRows(data []SummaryRow) []summaryParquetRow {
+	rows := make([]summaryParquetRow, len(data))
+	for i, row := range data {
+		rows[i] = newSummaryParquetRow(row)
+	}
+	return rows
+}
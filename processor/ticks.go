@@ -0,0 +1,102 @@
+package processor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+type OutputMode string
+
+const (
+	OutputModeSummary OutputMode = "summary" // One row per runner per market
+	OutputModeTicks   OutputMode = "ticks"   // One row per runner update, written to Parquet
+	OutputModeLadder  OutputMode = "ladder"  // Best-N ladder snapshots at a fixed cadence before the off, written to Parquet
+)
+
+// TickRow is a runner's state after one stream update
+type TickRow struct {
+	MarketID     string    `parquet:"market_id"`
+	SelectionID  int64     `parquet:"selection_id"`
+	PublishTime  time.Time `parquet:"pt,timestamp(millisecond)"`
+	LTP          float64   `parquet:"ltp,optional"`
+	BackPrice    float64   `parquet:"best_back_price,optional"`
+	BackSize     float64   `parquet:"best_back_size,optional"`
+	LayPrice     float64   `parquet:"best_lay_price,optional"`
+	LaySize      float64   `parquet:"best_lay_size,optional"`
+	TradedVolume float64   `parquet:"traded_volume"`
+}
+
+// newTickRow records a runner's book after an update published at timestamp (epoch millis)
+func newTickRow(marketID string, selectionID int64, timestamp int64, runner *RunnerState) TickRow {
+	row := TickRow{
+		MarketID:     marketID,
+		SelectionID:  selectionID,
+		PublishTime:  time.UnixMilli(timestamp).UTC(),
+		LTP:          runner.LatestLTP,
+		TradedVolume: runner.book.tradedVolume(),
+	}
+	if runner.MaxTV > row.TradedVolume {
+		row.TradedVolume = runner.MaxTV
+	}
+	if back := runner.book.backLevels(1); len(back) > 0 {
+		row.BackPrice, row.BackSize = back[0][0], back[0][1]
+	}
+	if lay := runner.book.layLevels(1); len(lay) > 0 {
+		row.LayPrice, row.LaySize = lay[0][0], lay[0][1]
+	}
+	return row
+}
+
+// saveTicks writes tick rows to the output file, or to <prefix>_ticks.parquet in the output directory
+func (p *MarketDataProcessor) saveTicks(data []TickRow) error {
+	outputPath := p.OutputFile
+	if outputPath == "" {
+		outputPath = filepath.Join(p.OutputDir, p.Profile.FilePrefix+"_ticks.parquet")
+	}
+	if err := writeParquetRows(p, outputPath, data); err != nil {
+		return err
+	}
+	p.logger.Info().Str("path", outputPath).Int("ticks", len(data)).Msg("created ticks output")
+	return nil
+}
+
+// writeParquetRows writes rows to a local Parquet file or uploads them to object storage. options,
+// such as a schema, are applied after the processor's writer settings.
+func writeParquetRows[T any](p *MarketDataProcessor, outputPath string, data []T, options ...parquet.WriterOption) error {
+	remote := isRemotePath(outputPath)
+	var file *os.File
+	var err error
+	if remote {
+		file, err = os.CreateTemp("", "parquet-*.parquet")
+		if err == nil {
+			defer os.Remove(file.Name())
+		}
+	} else {
+		if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+			return err
+		}
+		file, err = os.Create(outputPath)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create parquet file: %w", err)
+	}
+	defer file.Close()
+
+	writer := parquet.NewGenericWriter[T](file, append(p.parquetWriterOptions(), options...)...)
+	if _, err := writer.Write(data); err != nil {
+		writer.Close()
+		return fmt.Errorf("failed to write parquet data: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("failed to close parquet writer: %w", err)
+	}
+
+	if remote {
+		return p.uploadToStorage(outputPath, file.Name())
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package processor
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// tracing reports whether detailed logging is enabled for a market by Config.TraceMarkets
+func (p *MarketDataProcessor) tracing(marketID string) bool {
+	return slices.Contains(p.Config.TraceMarkets, marketID)
+}
+
+// tracef logs a message about a traced market
+func (p *MarketDataProcessor) tracef(marketID string, format string, args ...any) {
+	if p.tracing(marketID) {
+		p.logger.Info().Str("market_id", marketID).Msgf("trace: "+format, args...)
+	}
+}
+
+// traceChange logs what a market change of a traced market carries
+func (p *MarketDataProcessor) traceChange(marketChange *MarketChange, timestamp int64, sourceName string, line int) {
+	if !p.tracing(marketChange.ID) {
+		return
+	}
+
+	p.tracef(marketChange.ID, "%s line %d, pt=%d, %d runner changes", sourceName, line, timestamp, len(marketChange.RC))
+	if marketDef := marketChange.MarketDefinition; marketDef != nil {
+		venue := ""
+		if marketDef.Venue != nil {
+			venue = *marketDef.Venue
+		}
+		p.tracef(marketChange.ID, "definition eventId=%s eventName=%q venue=%q marketType=%s status=%s inPlay=%t runners=%d",
+			marketDef.EventID, marketDef.EventName, venue, marketDef.MarketType, marketDef.Status, marketDef.InPlay, len(marketDef.Runners))
+	}
+	for _, rc := range marketChange.RC {
+		p.tracef(marketChange.ID, "runner %d %s", rc.ID, describeRunnerChange(rc))
+	}
+}
+
+// describeRunnerChange lists the fields a runner change sets
+func describeRunnerChange(rc RunnerChange) string {
+	var fields []string
+	for _, value := range []struct {
+		name  string
+		value optionalFloat
+	}{{"ltp", rc.LTP}, {"tv", rc.TV}, {"spn", rc.SPN}, {"spf", rc.SPF}} {
+		if value.value.Set {
+			fields = append(fields, fmt.Sprintf("%s=%g", value.name, value.value.Value))
+		}
+	}
+	for _, ladder := range []struct {
+		name   string
+		levels [][]float64
+	}{{"batb", rc.BATB}, {"batl", rc.BATL}, {"atb", rc.ATB}, {"atl", rc.ATL}, {"spb", rc.SPB}, {"trd", rc.TRD}} {
+		if len(ladder.levels) > 0 {
+			fields = append(fields, fmt.Sprintf("%s=%v", ladder.name, ladder.levels))
+		}
+	}
+	if len(fields) == 0 {
+		return "(no changes)"
+	}
+	return strings.Join(fields, " ")
+}
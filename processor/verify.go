@@ -0,0 +1,230 @@
+package processor
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// FileCheck is the integrity of one recorded file, or of one entry of a tar archive
+type FileCheck struct {
+	Path           string   `json:"path"` // An archive entry is named archive.tar!entry
+	OK             bool     `json:"ok"`   // Every check below passed
+	Decompressed   bool     `json:"decompressed"`
+	Error          string   `json:"error,omitempty"` // Why the file couldn't be read to the end
+	Lines          int      `json:"lines"`
+	BadLines       int      `json:"badLines"`            // Lines that aren't JSON messages, or are over MaxLineBytes
+	FirstPt        int64    `json:"firstPt,omitempty"`   // Publish time of the first message, in epoch millis
+	LastPt         int64    `json:"lastPt,omitempty"`    // Publish time of the last message, in epoch millis
+	MissingClk     int      `json:"missingClk"`          // Market change messages without a clk
+	ClkRegressions int      `json:"clkRegressions"`      // Numeric clks lower than the one before
+	PtRegressions  int      `json:"ptRegressions"`       // Publish times earlier than the one before
+	Markets        []string `json:"markets"`             // Markets the file has changes for
+	Unclosed       []string `json:"unclosed,omitempty"`  // Markets never defined as CLOSED
+	Unsettled      []string `json:"unsettled,omitempty"` // Markets with no runner settled as WINNER, LOSER or PLACED
+}
+
+// VerifyReport is the integrity of every recorded file under a path
+type VerifyReport struct {
+	GeneratedAt time.Time   `json:"generatedAt"`
+	Files       int         `json:"files"`
+	Failed      int         `json:"failed"` // Files with a check that didn't pass
+	Checks      []FileCheck `json:"checks"`
+}
+
+// verifyMessage holds the fields of a stream message the integrity checks read
+type verifyMessage struct {
+	Op  string `json:"op"`
+	Clk string `json:"clk"`
+	Pt  int64  `json:"pt"`
+	Mc  []struct {
+		ID               string `json:"id"`
+		MarketDefinition *struct {
+			Status  string `json:"status"`
+			Runners []struct {
+				Status string `json:"status"`
+			} `json:"runners"`
+		} `json:"marketDefinition"`
+	} `json:"mc"`
+}
+
+// Verify checks the recorded files of a local or object storage path, as ProcessPath would list
+// them, without processing their markets. Each file is checked to decompress and parse cleanly,
+// for gaps in its clk and publish times, and for its markets closing and being settled, so an
+// archive can be checked before it is relied on.
+func (p *MarketDataProcessor) Verify(inputPath string) (*VerifyReport, error) {
+	files, err := p.listPath(inputPath)
+	if err != nil {
+		return nil, err
+	}
+
+	checks := make([][]FileCheck, len(files))
+	indexes := make(chan int, len(files))
+	for i := range files {
+		indexes <- i
+	}
+	close(indexes)
+
+	var wg sync.WaitGroup
+	for range min(p.Workers, max(len(files), 1)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				checks[i] = p.verifyFile(files[i])
+			}
+		}()
+	}
+	wg.Wait()
+
+	report := &VerifyReport{GeneratedAt: time.Now().UTC()}
+	for _, fileChecks := range checks {
+		for _, check := range fileChecks {
+			report.Files++
+			if !check.OK {
+				report.Failed++
+			}
+			report.Checks = append(report.Checks, check)
+		}
+	}
+	p.logger.Info().Str("path", inputPath).Int("files", report.Files).Int("failed", report.Failed).Msg("verified files")
+	return report, nil
+}
+
+// verifyFile checks a file, or each entry of a tar archive
+func (p *MarketDataProcessor) verifyFile(filePath string) []FileCheck {
+	var file io.ReadCloser
+	var err error
+	if isRemotePath(filePath) {
+		file, err = p.openRemote(context.Background(), filePath)
+	} else {
+		file, err = os.Open(filePath)
+	}
+	if err != nil {
+		return []FileCheck{{Path: filePath, Error: err.Error()}}
+	}
+	defer file.Close()
+
+	if !isTarFile(filePath) {
+		return []FileCheck{p.verifyStream(file, filePath)}
+	}
+
+	return p.verifyTar(file, filePath)
+}
+
+// verifyTar checks each entry of a tar archive, plain or compressed, and of the archives within it
+func (p *MarketDataProcessor) verifyTar(reader io.Reader, sourceName string) []FileCheck {
+	archive, err := p.decompress(reader, sourceName)
+	if err != nil {
+		return []FileCheck{{Path: sourceName, Error: fmt.Sprintf("failed to decompress: %v", err)}}
+	}
+	var checks []FileCheck
+	tarReader := tar.NewReader(archive)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return checks
+		}
+		if err != nil {
+			return append(checks, FileCheck{Path: sourceName, Error: fmt.Sprintf("failed to read tar: %v", err)})
+		}
+		if header.Typeflag != tar.TypeReg || !p.isSupportedFile(header.Name) {
+			continue
+		}
+		if isTarFile(header.Name) {
+			checks = append(checks, p.verifyTar(tarReader, sourceName+"!"+header.Name)...)
+			continue
+		}
+		checks = append(checks, p.verifyStream(tarReader, sourceName+"!"+header.Name))
+	}
+}
+
+// verifyStream reads a file's messages, decompressing it by its .bz2, .gz or .zst suffix
+func (p *MarketDataProcessor) verifyStream(reader io.Reader, sourceName string) FileCheck {
+	check := FileCheck{Path: sourceName, Markets: []string{}}
+	reader, err := p.decompress(reader, sourceName)
+	if err != nil {
+		check.Error = fmt.Sprintf("failed to decompress: %v", err)
+		return check
+	}
+
+	closed := make(map[string]bool)
+	settled := make(map[string]bool)
+	var lastClk int64
+	lines := newLineReader(reader, p.Config.MaxLineBytes)
+	for lines.next() {
+		check.Lines++
+		var message verifyMessage
+		if lines.truncated(check.Lines) != nil || json.Unmarshal(lines.bytes(), &message) != nil {
+			check.BadLines++
+			continue
+		}
+		if message.Op != "mcm" {
+			continue
+		}
+
+		if message.Clk == "" {
+			check.MissingClk++
+		} else if clk, err := strconv.ParseInt(message.Clk, 10, 64); err == nil {
+			if clk < lastClk {
+				check.ClkRegressions++
+			}
+			lastClk = clk
+		}
+		if message.Pt > 0 {
+			if message.Pt < check.LastPt {
+				check.PtRegressions++
+			}
+			if check.FirstPt == 0 {
+				check.FirstPt = message.Pt
+			}
+			check.LastPt = message.Pt
+		}
+
+		for _, change := range message.Mc {
+			if change.ID == "" {
+				continue
+			}
+			if _, seen := closed[change.ID]; !seen {
+				closed[change.ID] = false
+				check.Markets = append(check.Markets, change.ID)
+			}
+			if change.MarketDefinition == nil {
+				continue
+			}
+			if change.MarketDefinition.Status == "CLOSED" {
+				closed[change.ID] = true
+			}
+			for _, runner := range change.MarketDefinition.Runners {
+				switch runner.Status {
+				case "WINNER", "LOSER", "PLACED":
+					settled[change.ID] = true
+				}
+			}
+		}
+	}
+	if lines.err != nil {
+		check.Error = fmt.Sprintf("failed after line %d: %v", check.Lines, lines.err)
+	}
+	check.Decompressed = lines.err == nil
+
+	sort.Strings(check.Markets)
+	for _, marketID := range check.Markets {
+		if !closed[marketID] {
+			check.Unclosed = append(check.Unclosed, marketID)
+		}
+		if !settled[marketID] {
+			check.Unsettled = append(check.Unsettled, marketID)
+		}
+	}
+	check.OK = check.Decompressed && check.BadLines == 0 && check.MissingClk == 0 && check.ClkRegressions == 0 &&
+		check.PtRegressions == 0 && len(check.Markets) > 0 && len(check.Unclosed) == 0 && len(check.Unsettled) == 0
+	return check
+}
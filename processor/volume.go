@@ -0,0 +1,140 @@
+package processor
+
+import "time"
+
+const (
+	volumeWindow60s = 60 * 1000     // millis
+	volumeWindow5m  = 5 * 60 * 1000 // millis
+)
+
+// VolumeProfile summarises how a runner's matched volume built up around the off
+type VolumeProfile struct {
+	VWAP            float64 // Volume-weighted average matched price
+	VolumeLast60s   float64 // Matched in the minute before the off
+	VolumeLast5m    float64 // Matched in the five minutes before the off
+	InPlayVolumePct float64 // Percentage of all matched volume traded after the off
+	HasVolume       bool
+}
+
+// volumeSample is a runner's total matched volume after an update
+type volumeSample struct {
+	timestamp int64
+	volume    float64
+}
+
+// offVolumes is a runner's matched volume at an off and at the windows before it
+type offVolumes struct {
+	atOff, at60s, at5m float64
+	set                bool
+}
+
+// volumeTracker follows a runner's matched volume, keeping only the samples needed to look back
+// five minutes from an off that has not yet been seen
+type volumeTracker struct {
+	history   []volumeSample
+	scheduled offVolumes // At the scheduled start, taken once an update passes it
+	inPlay    offVolumes // At the in-play turn
+}
+
+// observe records the volume after an update published at timestamp; scheduledOff is the
+// market's scheduled start in epoch millis
+func (t *volumeTracker) observe(timestamp int64, volume float64, scheduledOff int64) {
+	if !t.scheduled.set && timestamp > scheduledOff {
+		t.scheduled = t.at(scheduledOff)
+	}
+	t.history = append(t.history, volumeSample{timestamp: timestamp, volume: volume})
+
+	// Any later off is at or after timestamp, so only the last sample more than five minutes old is needed
+	drop := 0
+	for drop+1 < len(t.history) && t.history[drop+1].timestamp <= timestamp-volumeWindow5m {
+		drop++
+	}
+	if drop > 0 {
+		t.history = append(t.history[:0], t.history[drop:]...)
+	}
+}
+
+// turnInPlay records the volumes at the in-play turn
+func (t *volumeTracker) turnInPlay(inPlay time.Time) {
+	if !t.inPlay.set {
+		t.inPlay = t.at(inPlay.UnixMilli())
+	}
+}
+
+// at looks up the volume as of an off and the windows before it
+func (t *volumeTracker) at(off int64) offVolumes {
+	volumes := offVolumes{set: true}
+	for _, sample := range t.history {
+		if sample.timestamp <= off-volumeWindow5m {
+			volumes.at5m = sample.volume
+		}
+		if sample.timestamp <= off-volumeWindow60s {
+			volumes.at60s = sample.volume
+		}
+		if sample.timestamp <= off {
+			volumes.atOff = sample.volume
+		}
+	}
+	return volumes
+}
+
+// profile summarises the runner's volume around the off: the in-play turn if there was one,
+// otherwise the scheduled start
+func (t *volumeTracker) profile(book *runnerBook, scheduledOff int64) VolumeProfile {
+	var profile VolumeProfile
+	final := book.tradedVolume()
+	if final <= 0 {
+		return profile
+	}
+
+	volumes := t.inPlay
+	if !volumes.set {
+		volumes = t.scheduled
+	}
+	if !volumes.set {
+		volumes = t.at(scheduledOff)
+	}
+
+	weighted := 0.0
+	for price, volume := range book.traded {
+		weighted += price * volume
+	}
+	profile.VWAP = weighted / final
+	profile.VolumeLast60s = volumes.atOff - volumes.at60s
+	profile.VolumeLast5m = volumes.atOff - volumes.at5m
+	profile.InPlayVolumePct = (final - volumes.atOff) / final * 100
+	profile.HasVolume = true
+	return profile
+}
+
+// InPlayStats summarises a runner's trading after the market turned in-play
+type InPlayStats struct {
+	High      float64 // Highest price matched in-play
+	Low       float64 // Lowest price matched in-play
+	Volume    float64 // Volume matched in-play
+	HasTraded bool
+}
+
+// observe folds in-play trd deltas into the stats; a price traded when its cumulative matched
+// volume grew from what the book last held
+func (s *InPlayStats) observe(book *runnerBook, trades [][]float64) {
+	for _, trade := range trades {
+		if len(trade) < 2 {
+			continue
+		}
+		price := trade[0]
+		matched := trade[1] - book.traded[price]
+		if matched <= 0 {
+			continue
+		}
+
+		s.Volume += matched
+		if !s.HasTraded || price > s.High {
+			s.High = price
+		}
+		if !s.HasTraded || price < s.Low {
+			s.Low = price
+		}
+		s.HasTraded = true
+	}
+}
@@ -0,0 +1,78 @@
+package processor
+
+// newWorker returns a processor for one of processFilesParallel's workers. It has its own market
+// state and collected rows, and shares the file count and storage clients with p.
+func (p *MarketDataProcessor) newWorker() *MarketDataProcessor {
+	return &MarketDataProcessor{
+		Config:         p.Config,
+		OutputDir:      p.OutputDir,
+		OutputFile:     p.OutputFile,
+		FileLimit:      p.FileLimit,
+		Workers:        1,
+		MarketStates:   make(map[string]*MarketState),
+		VenueRegex:     p.VenueRegex,
+		GreyhoundRegex: p.GreyhoundRegex,
+		Profile:        p.Profile,
+		S3Client:       p.S3Client,
+		names:          p.names,
+		bucketZone:     p.bucketZone,
+		logger:         p.logger,
+		parent:         p,
+	}
+}
+
+// merge moves a finished worker's rows and unfinalized markets into p. Betfair's files each hold
+// one market, so a market is only seen by one worker; if it was split across workers the first
+// state merged is kept.
+func (p *MarketDataProcessor) merge(worker *MarketDataProcessor) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.ProcessedData = append(p.ProcessedData, worker.ProcessedData...)
+	p.TickData = append(p.TickData, worker.TickData...)
+	p.LadderData = append(p.LadderData, worker.LadderData...)
+	p.MarketData = append(p.MarketData, worker.MarketData...)
+	p.QualityIssues = append(p.QualityIssues, worker.QualityIssues...)
+	for marketID, marketState := range worker.MarketStates {
+		if _, exists := p.MarketStates[marketID]; exists {
+			p.logger.Warn().Str("market_id", marketID).Msg("market was split across workers; keeping the first part")
+			continue
+		}
+		p.MarketStates[marketID] = marketState
+	}
+}
+
+// filesDone is how many files have been processed, counting every worker
+func (p *MarketDataProcessor) filesDone() int {
+	if p.parent != nil {
+		return p.parent.filesDone()
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.FilesProcessed
+}
+
+// countFile records a processed file against the processor the workers share
+func (p *MarketDataProcessor) countFile() {
+	if p.parent != nil {
+		p.parent.countFile()
+		return
+	}
+	p.mu.Lock()
+	p.FilesProcessed++
+	p.mu.Unlock()
+}
+
+// rowsEmitted is how many summary, tick and ladder rows the processor has collected or streamed
+func (p *MarketDataProcessor) rowsEmitted() int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return len(p.ProcessedData) + p.rowsStreamed + len(p.TickData) + len(p.LadderData)
+}
+
+// summaryRows is how many summary rows the processor has finalized, whether collected or streamed
+func (p *MarketDataProcessor) summaryRows() int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return len(p.ProcessedData) + p.rowsStreamed
+}
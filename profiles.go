@@ -0,0 +1,121 @@
+package betfair
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// RecordingProfile is a named market filter with its own output location, letting one process
+// record several sports or regions side by side
+type RecordingProfile struct {
+	Name         string   `json:"name"`
+	EventTypeID  string   `json:"eventTypeId"`
+	CountryCodes []string `json:"countryCodes"`
+	MarketTypes  []string `json:"marketTypes"`
+	OutputPath   string   `json:"outputPath"`
+	S3BasePath   string   `json:"s3BasePath"`
+}
+
+// ParseRecordingProfiles decodes a JSON array of recording profiles
+func ParseRecordingProfiles(data string) ([]RecordingProfile, error) {
+	var profiles []RecordingProfile
+	if err := json.Unmarshal([]byte(data), &profiles); err != nil {
+		return nil, fmt.Errorf("decode recording profiles: %w", err)
+	}
+
+	seen := make(map[string]bool, len(profiles))
+	for i, profile := range profiles {
+		if profile.Name == "" {
+			return nil, fmt.Errorf("recording profile %d has no name", i)
+		}
+		if seen[profile.Name] {
+			return nil, fmt.Errorf("duplicate recording profile %q", profile.Name)
+		}
+		seen[profile.Name] = true
+		if profile.EventTypeID == "" {
+			return nil, fmt.Errorf("recording profile %q has no eventTypeId", profile.Name)
+		}
+	}
+	return profiles, nil
+}
+
+// profileConfig derives the configuration for a single profile from the process-wide config
+func (c *Config) profileConfig(profile RecordingProfile) *Config {
+	cfg := *c
+	cfg.Profiles = nil
+	cfg.MarketIDs = nil
+	cfg.EventTypeID = profile.EventTypeID
+	cfg.CountryCode = strings.Join(profile.CountryCodes, ",")
+	cfg.MarketType = strings.Join(profile.MarketTypes, ",")
+
+	cfg.OutputPath = profile.OutputPath
+	if cfg.OutputPath == "" {
+		base := c.OutputPath
+		if base == "" {
+			base = "market_files"
+		}
+		cfg.OutputPath = filepath.Join(base, profile.Name)
+	}
+	if profile.S3BasePath != "" {
+		cfg.S3BasePath = profile.S3BasePath
+	}
+	if c.CheckpointPath != "" {
+		ext := filepath.Ext(c.CheckpointPath)
+		cfg.CheckpointPath = strings.TrimSuffix(c.CheckpointPath, ext) + "." + profile.Name + ext
+	}
+
+	// Session upkeep, secret refreshes and account-wide actions are handled once by the parent recorder
+	cfg.ValidateAppKey = false
+	cfg.CancelAllOnShutdown = false
+	cfg.AppKeySecret = nil
+	cfg.PasswordSecret = nil
+	return &cfg
+}
+
+// newProfileRecorders creates one recorder per profile, all sharing the parent's session token
+func (r *MarketRecorder) newProfileRecorders() error {
+	for _, profile := range r.config.Profiles {
+		logger := r.logger.With().Str("profile", profile.Name).Logger()
+		child, err := NewMarketRecorder(r.config.profileConfig(profile), logger)
+		if err != nil {
+			return fmt.Errorf("create recorder for profile %s: %w", profile.Name, err)
+		}
+
+		child.tokens = r.tokens
+		child.streamClient.UseTokenStore(r.tokens)
+		child.restClient.UseTokenStore(r.tokens)
+		child.sessionManager = nil
+		r.profiles = append(r.profiles, child)
+	}
+	return nil
+}
+
+// runProfiles runs every profile recorder concurrently until ctx is cancelled or one fails
+func (r *MarketRecorder) runProfiles(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	errs := make([]error, len(r.profiles))
+	var wg sync.WaitGroup
+	for i, child := range r.profiles {
+		wg.Add(1)
+		go func(i int, child *MarketRecorder) {
+			defer wg.Done()
+			if err := child.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+				errs[i] = fmt.Errorf("profile %s: %w", r.config.Profiles[i].Name, err)
+				cancel()
+			}
+		}(i, child)
+	}
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+	return ctx.Err()
+}
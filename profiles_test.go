@@ -0,0 +1,164 @@
+package betfair
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestParseRecordingProfiles(t *testing.T) {
+	profiles, err := ParseRecordingProfiles(`[
+		{"name":"greyhounds","eventTypeId":"4339","countryCodes":["AU","NZ"],"marketTypes":["WIN","PLACE"]},
+		{"name":"horses","eventTypeId":"7","countryCodes":["AU"],"s3BasePath":"raw_horse_data"}
+	]`)
+	if err != nil {
+		t.Fatalf("ParseRecordingProfiles failed: %v", err)
+	}
+	if len(profiles) != 2 || profiles[1].S3BasePath != "raw_horse_data" {
+		t.Errorf("Unexpected profiles: %+v", profiles)
+	}
+
+	invalid := map[string]string{
+		"missing name":     `[{"eventTypeId":"7"}]`,
+		"missing event":    `[{"name":"horses"}]`,
+		"duplicate name":   `[{"name":"a","eventTypeId":"7"},{"name":"a","eventTypeId":"4339"}]`,
+		"not a json array": `greyhounds`,
+	}
+	for name, data := range invalid {
+		if _, err := ParseRecordingProfiles(data); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestConfigProfileConfig(t *testing.T) {
+	base := &Config{
+		AppKey:              "app-key",
+		MarketIDs:           []string{"1.1"},
+		EventTypeID:         "4339",
+		OutputPath:          "/data/markets",
+		S3BasePath:          "raw_greyhounds_data",
+		CheckpointPath:      "/data/checkpoint.json",
+		ValidateAppKey:      true,
+		CancelAllOnShutdown: true,
+		Profiles:            []RecordingProfile{{Name: "horses", EventTypeID: "7"}},
+	}
+
+	cfg := base.profileConfig(RecordingProfile{
+		Name:         "horses",
+		EventTypeID:  "7",
+		CountryCodes: []string{"AU", "NZ"},
+		MarketTypes:  []string{"WIN"},
+		S3BasePath:   "raw_horse_data",
+	})
+
+	filter := cfg.GetMarketFilter()
+	if len(filter.MarketIds) != 0 || filter.EventTypeIds[0] != "7" {
+		t.Errorf("Expected profile filter without market IDs, got %+v", filter)
+	}
+	if strings.Join(filter.MarketCountries, ",") != "AU,NZ" || strings.Join(filter.MarketTypeCodes, ",") != "WIN" {
+		t.Errorf("Unexpected countries/market types: %+v", filter)
+	}
+	if cfg.OutputPath != filepath.Join("/data/markets", "horses") {
+		t.Errorf("Expected per-profile output path, got %s", cfg.OutputPath)
+	}
+	if cfg.S3BasePath != "raw_horse_data" {
+		t.Errorf("Expected profile S3 prefix, got %s", cfg.S3BasePath)
+	}
+	if cfg.CheckpointPath != "/data/checkpoint.horses.json" {
+		t.Errorf("Expected per-profile checkpoint, got %s", cfg.CheckpointPath)
+	}
+	if cfg.ValidateAppKey || cfg.CancelAllOnShutdown || len(cfg.Profiles) != 0 {
+		t.Error("Expected process-wide duties to stay with the parent recorder")
+	}
+	if base.EventTypeID != "4339" || len(base.MarketIDs) != 1 {
+		t.Error("Expected the base config to be left untouched")
+	}
+}
+
+func TestNewMarketRecorderWithProfiles(t *testing.T) {
+	tempDir := t.TempDir()
+	cfg := &Config{
+		AppKey:       "app-key",
+		SessionToken: "token",
+		HeartbeatMs:  5000,
+		OutputPath:   tempDir,
+		Profiles: []RecordingProfile{
+			{Name: "greyhounds", EventTypeID: "4339"},
+			{Name: "horses", EventTypeID: "7"},
+		},
+	}
+
+	recorder, err := NewMarketRecorder(cfg, zerolog.New(zerolog.NewTestWriter(t)))
+	if err != nil {
+		t.Fatalf("NewMarketRecorder failed: %v", err)
+	}
+	if len(recorder.profiles) != 2 {
+		t.Fatalf("Expected 2 profile recorders, got %d", len(recorder.profiles))
+	}
+
+	recorder.tokens.Set("refreshed-token")
+	for _, child := range recorder.profiles {
+		if child.sessionManager != nil {
+			t.Error("Expected profile recorders not to run their own keep-alive")
+		}
+		if child.streamClient.SessionToken() != "refreshed-token" {
+			t.Error("Expected profile recorders to share the session token")
+		}
+	}
+	if recorder.profiles[1].config.OutputPath != filepath.Join(tempDir, "horses") {
+		t.Errorf("Unexpected output path %s", recorder.profiles[1].config.OutputPath)
+	}
+}
+
+func TestProfileRecordersReceiveRotatedPassword(t *testing.T) {
+	tempDir := t.TempDir()
+	secretPath := filepath.Join(tempDir, "password")
+	if err := os.WriteFile(secretPath, []byte("first-password"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	source, err := ParseSecretSource("file://" + secretPath)
+	if err != nil {
+		t.Fatalf("ParseSecretSource failed: %v", err)
+	}
+
+	cfg := &Config{
+		AppKey:         "app-key",
+		SessionToken:   "token",
+		HeartbeatMs:    5000,
+		OutputPath:     tempDir,
+		password:       "first-password",
+		PasswordSecret: source,
+		Profiles: []RecordingProfile{
+			{Name: "greyhounds", EventTypeID: "4339"},
+			{Name: "horses", EventTypeID: "7"},
+		},
+	}
+	recorder, err := NewMarketRecorder(cfg, zerolog.New(zerolog.NewTestWriter(t)))
+	if err != nil {
+		t.Fatalf("NewMarketRecorder failed: %v", err)
+	}
+	for _, child := range recorder.profiles {
+		if child.secretWatcher() != nil {
+			t.Error("Expected profile recorders not to poll secrets themselves")
+		}
+	}
+
+	if err := os.WriteFile(secretPath, []byte("second-password"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	recorder.secretWatcher().Check(context.Background())
+
+	for _, authenticator := range []*Authenticator{recorder.authenticator, recorder.profiles[0].authenticator, recorder.profiles[1].authenticator} {
+		authenticator.mu.Lock()
+		password := authenticator.password
+		authenticator.mu.Unlock()
+		if password != "second-password" {
+			t.Errorf("Expected every login to use the rotated password, got %q", password)
+		}
+	}
+}
@@ -7,22 +7,76 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/rs/zerolog"
 )
 
+const (
+	// IncompleteSuffix marks market files finalized before the market settled
+	IncompleteSuffix = ".incomplete"
+
+	idleCheckInterval = time.Minute
+)
+
 type MarketRecorder struct {
 	config          *Config
 	logger          zerolog.Logger
 	streamClient    *StreamClient
 	restClient      *RESTClient
 	fileManager     *FileManager
-	storage         *S3Storage
+	storage         ObjectStorage
+	uploadQueue     *UploadQueue
 	marketProcessor *MarketProcessor
 	authenticator   *Authenticator
+	sessionManager  *SessionManager
+	tokens          *TokenStore
+	discoverer      *MarketDiscoverer
+	streamMu        sync.Mutex
+	currentStream   *StreamConn
+	settledMarkets  map[string]bool
+	filterReloaded  bool
+	sinceCheckpoint int
+	enrichedImages  map[string]bool
+
+	profiles     []*MarketRecorder
+	sessionLog   *SessionLog
+	diskGuard    *DiskGuard
+	skipped      map[string]bool
+	routing      routingCounters
+	stats        recorderStats
+	lifecycle    lifecycle
+	hooks        MarketHooks
+	sinks        []Sink
+	marketInPlay map[string]bool
+	timelines    map[string]*MarketTimeline
+
+	marketTimes     map[string]time.Time
+	settledSeen     map[string]time.Time
+	imagesRequested bool
+
+	marketEventTypes map[string]string
+
+	segments        map[string]*marketSegment
+	lastDefinitions map[string][]byte
+	recordedCounts  map[string]*marketCounts
+
+	marketWriters  map[string]*marketWriter
+	openMarkets    sync.Map
+	marketActivity map[string]time.Time
+	lastIdleCheck  time.Time
+	settlements    sync.WaitGroup
+	checkpointer   *checkpointer
+	unsynced       map[*marketWriter]bool
+
+	catalogueFetcher     *CatalogueFetcher
+	catalogueBuffers     map[string]*catalogueBuffer
+	catalogueWaitExpired map[string]bool
 	initialClk      string
 	clk             string
 	maxRetries      int
@@ -31,22 +85,62 @@ type MarketRecorder struct {
 }
 
 func NewMarketRecorder(cfg *Config, logger zerolog.Logger) (*MarketRecorder, error) {
-	authenticator := NewAuthenticator(cfg.AppKey, os.Getenv("BETFAIR_USERNAME"), os.Getenv("BETFAIR_PASSWORD"))
+	authenticator := NewAuthenticator(cfg.AppKey, getenv("BETFAIR_USERNAME"), firstNonEmpty(cfg.password, getenv("BETFAIR_PASSWORD")))
 	streamClient := NewStreamClient(cfg.AppKey, cfg.SessionToken, cfg.HeartbeatMs, logger, authenticator)
-	restClient := NewRESTClient(cfg.AppKey, cfg.SessionToken, "en")
+	restClient := NewRESTClient(cfg.AppKey, cfg.SessionToken, firstNonEmpty(cfg.Locale, DefaultLocale))
+	restClient.SetCurrency(cfg.CurrencyCode)
+	tokens := NewTokenStore(cfg.SessionToken)
+	streamClient.UseTokenStore(tokens)
+	restClient.UseTokenStore(tokens)
+	sessionManager := NewSessionManager(cfg.AppKey, tokens, authenticator, cfg.KeepAliveInterval, logger)
+	if cfg.Jurisdiction != "" || cfg.StreamHost != "" {
+		endpoints := cfg.Endpoints()
+		authenticator.SetEndpoints(endpoints)
+		streamClient.SetEndpoints(endpoints)
+		restClient.SetEndpoints(endpoints)
+		sessionManager.SetEndpoints(endpoints)
+	}
+	tokens.Subscribe(func(token string) {
+		logger.Info().Msg("session token updated")
+	})
 	fileManager := NewFileManager(cfg.OutputPath)
+	if cfg.ExistingFilePolicy != "" {
+		fileManager.SetExistingFilePolicy(cfg.ExistingFilePolicy)
+	}
+	if cfg.Compression != "" {
+		fileManager.SetCompression(cfg.Compression, cfg.CompressionLevel)
+	}
+	fileManager.SetStreamCompression(cfg.StreamCompression)
+	if cfg.OutputLayout != "" {
+		fileManager.SetLayout(cfg.OutputLayout)
+	}
 	marketProcessor := NewMarketProcessor()
 
-	var storage *S3Storage
-	if cfg.S3Bucket != "" {
+	var catalogueFetcher *CatalogueFetcher
+	if cfg.enrichmentMode() != EnrichmentOff {
+		catalogueFetcher = NewCatalogueFetcher(restClient, cfg.CatalogueWorkers, logger)
+	}
+
+	var discoverer *MarketDiscoverer
+	if cfg.DiscoveryInterval > 0 && len(cfg.MarketIDs) == 0 {
+		discoverer = NewMarketDiscoverer(restClient, cfg.GetMarketFilter(), cfg.DiscoveryInterval, cfg.DiscoveryLookahead, logger)
+		discoverer.SetMaxMarkets(cfg.MaxConcurrentMarkets)
+		discoverer.SetStartWindow(cfg.MarketStartWindow)
+	}
+
+	var storage ObjectStorage
+	if location := cfg.storageLocation(); location != "" {
 		var err error
-		storage, err = NewS3Storage(context.Background(), cfg.S3Bucket, cfg.S3BasePath)
+		storage, err = NewObjectStorage(context.Background(), location, cfg.S3BasePath)
 		if err != nil {
-			return nil, fmt.Errorf("failed to initialize S3 storage: %w", err)
+			return nil, fmt.Errorf("failed to initialize storage: %w", err)
+		}
+		if s3Storage, ok := storage.(*S3Storage); ok {
+			s3Storage.SetUploadOptions(cfg.S3Upload)
 		}
 	}
 
-	return &MarketRecorder{
+	recorder := &MarketRecorder{
 		config:           cfg,
 		logger:           logger,
 		streamClient:     streamClient,
@@ -55,20 +149,109 @@ func NewMarketRecorder(cfg *Config, logger zerolog.Logger) (*MarketRecorder, err
 		storage:          storage,
 		marketProcessor:  marketProcessor,
 		authenticator:    authenticator,
+		sessionManager:   sessionManager,
+		tokens:           tokens,
+		discoverer:       discoverer,
+		catalogueFetcher: catalogueFetcher,
 		maxRetries:       5,
 		retryDelay:       30 * time.Second,
 		marketCatalogues: make(map[string]*MarketCatalogue),
-	}, nil
+	}
+
+	if len(cfg.Profiles) > 0 {
+		if err := recorder.newProfileRecorders(); err != nil {
+			return nil, err
+		}
+	}
+
+	return recorder, nil
 }
 
 func (r *MarketRecorder) Run(ctx context.Context) error {
+	if len(r.profiles) > 0 {
+		return r.runAllProfiles(ctx)
+	}
+
 	writers, files, closeFn, err := r.openWriters()
 	if err != nil {
 		return err
 	}
 	defer closeFn()
 
+	if r.config.SessionLog {
+		sessionLog, err := OpenSessionLog(r.fileManager.outputPath, time.Now())
+		if err != nil {
+			r.logger.Error().Err(err).Msg("failed to open session log")
+		} else {
+			r.logger.Info().Str("path", sessionLog.Path()).Msg("recording heartbeats and connection events")
+			r.sessionLog = sessionLog
+			defer sessionLog.Close()
+		}
+	}
+
+	defer r.logRoutingStats()
+	defer r.stopMarketWriters()
+
+	// The final checkpoint is saved once buffered messages are queued and before writers close
 	marketStatuses := make(map[string]string)
+	if r.config.CheckpointPath != "" {
+		r.restoreCheckpoint(marketStatuses)
+		r.checkpointer = startCheckpointer(r.config.CheckpointPath, r.logger)
+		defer r.saveCheckpoint(marketStatuses)
+	}
+	defer r.flushAllBuffered()
+
+	if r.catalogueFetcher != nil {
+		r.catalogueFetcher.Start(ctx)
+	}
+
+	if r.storage != nil {
+		r.uploadQueue = NewUploadQueue(filepath.Join(r.fileManager.outputPath, UploadManifestName), r.storage, r.logger)
+		if err := r.uploadQueue.Load(); err != nil {
+			r.logger.Error().Err(err).Msg("failed to load pending uploads")
+		} else if pending := len(r.uploadQueue.Pending()); pending > 0 {
+			r.logger.Info().Int("pending", pending).Msg("retrying uploads left from previous run")
+		}
+		go r.uploadQueue.Run(ctx)
+	}
+
+	if r.config.DiskUsageThreshold > 0 {
+		r.diskGuard = NewDiskGuard(r.fileManager.outputPath, r.config.DiskUsageThreshold, r.config.DiskCheckInterval, r.logger)
+		r.diskGuard.Check()
+		go r.diskGuard.Run(ctx)
+	}
+
+	// Uploaded files are removed locally, so retention only applies when recording to disk alone
+	if r.storage == nil && r.config.RetentionPeriod > 0 {
+		janitor := NewJanitor(r.fileManager.outputPath, r.config.RetentionPeriod, r.config.RetentionMode, 0, r.logger)
+		janitor.SetOpenMarkets(r.hasOpenMarketFile)
+		go janitor.Run(ctx)
+	}
+
+	if r.config.CancelAllOnShutdown {
+		defer r.cancelAllOrders()
+	}
+
+	r.recoverMarketFiles(ctx, writers, files, marketStatuses)
+
+	if r.sessionManager != nil && r.config.KeepAliveInterval > 0 {
+		go r.sessionManager.Run(ctx)
+	}
+	r.watchSecrets(ctx)
+
+	if r.config.ValidateAppKey {
+		r.validateAppKey(ctx)
+	}
+
+	if r.discoverer != nil {
+		if added, err := r.discoverer.Discover(ctx); err != nil {
+			r.logger.Error().Err(err).Msg("initial market discovery failed")
+		} else {
+			r.logger.Info().Strs("market_ids", added).Msg("discovered markets")
+		}
+		r.discoverer.SetPauseCheck(r.discoveryPaused)
+		go r.discoverer.Run(ctx, r.resubscribe)
+	}
 
 	for {
 		select {
@@ -92,6 +275,132 @@ func (r *MarketRecorder) Run(ctx context.Context) error {
 	}
 }
 
+// runAllProfiles handles the process-wide session and account duties once, then records every profile
+func (r *MarketRecorder) runAllProfiles(ctx context.Context) error {
+	if r.config.CancelAllOnShutdown {
+		defer r.cancelAllOrders()
+	}
+
+	if r.sessionManager != nil && r.config.KeepAliveInterval > 0 {
+		go r.sessionManager.Run(ctx)
+	}
+	r.watchSecrets(ctx)
+
+	if r.config.ValidateAppKey {
+		r.validateAppKey(ctx)
+	}
+
+	return r.runProfiles(ctx)
+}
+
+// validateAppKey warns when recording with a delayed app key, which silently produces delayed data
+func (r *MarketRecorder) validateAppKey(ctx context.Context) {
+	info, err := r.restClient.ValidateAppKey(ctx)
+	if err != nil {
+		r.logger.Warn().Err(err).Msg("could not validate app key")
+		return
+	}
+
+	r.config.AppKeyDelayed = info.Delayed
+	if info.Delayed {
+		r.logger.Warn().Str("app_name", info.AppName).Str("version", info.Version).Msg("using a DELAYED app key; recorded prices will be delayed")
+		return
+	}
+	r.logger.Info().Str("app_name", info.AppName).Str("version", info.Version).Bool("active", info.Active).Msg("using a live app key")
+}
+
+// cancelAllOrders is a dead-man switch for users who also trade: it cancels every
+// unmatched order when the recorder stops
+func (r *MarketRecorder) cancelAllOrders() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	report, err := r.restClient.CancelAllOrders(ctx, nil)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("failed to cancel all orders on shutdown")
+		return
+	}
+
+	r.logger.Info().Str("status", string(report.Status)).Msg("cancelled all orders on shutdown")
+}
+
+// restoreCheckpoint resumes the stream clocks and market statuses saved by a previous run
+func (r *MarketRecorder) restoreCheckpoint(marketStatuses map[string]string) {
+	checkpoint, err := LoadCheckpoint(r.config.CheckpointPath)
+	if err != nil {
+		r.logger.Warn().Err(err).Str("path", r.config.CheckpointPath).Msg("ignoring unreadable checkpoint")
+		return
+	}
+	if checkpoint == nil {
+		return
+	}
+
+	r.streamMu.Lock()
+	r.initialClk = checkpoint.InitialClk
+	r.clk = checkpoint.Clk
+	r.streamMu.Unlock()
+
+	for marketID, status := range checkpoint.MarketStatuses {
+		marketStatuses[marketID] = status
+	}
+
+	r.logger.Info().Time("saved_at", checkpoint.SavedAt).Int("markets", len(checkpoint.MarketStatuses)).Msg("restored stream checkpoint")
+}
+
+// takeCheckpoint captures the stream clocks and market statuses together with the writers written
+// to since the last checkpoint, whose queued lines must be on disk before the clocks are saved. It
+// reports false, taking nothing, while messages are still held back for their catalogue.
+func (r *MarketRecorder) takeCheckpoint(marketStatuses map[string]string) (checkpointRequest, bool) {
+	for _, buffer := range r.catalogueBuffers {
+		if len(buffer.messages) > 0 {
+			return checkpointRequest{}, false
+		}
+	}
+
+	writers := make([]*marketWriter, 0, len(r.unsynced))
+	for mw := range r.unsynced {
+		writers = append(writers, mw)
+	}
+	r.unsynced = nil
+
+	r.streamMu.Lock()
+	checkpoint := Checkpoint{
+		InitialClk:     r.initialClk,
+		Clk:            r.clk,
+		MarketStatuses: maps.Clone(marketStatuses),
+		SavedAt:        time.Now().UTC(),
+	}
+	r.streamMu.Unlock()
+
+	return checkpointRequest{checkpoint: checkpoint, writers: writers}, true
+}
+
+// saveCheckpoint stops background checkpointing and saves a final checkpoint, waiting until it is
+// on disk. It reports false, saving nothing, while messages are still held back for their catalogue.
+func (r *MarketRecorder) saveCheckpoint(marketStatuses map[string]string) bool {
+	req, ok := r.takeCheckpoint(marketStatuses)
+	if r.checkpointer != nil {
+		r.checkpointer.Stop()
+		r.checkpointer = nil
+	}
+	if !ok {
+		return false
+	}
+	req.save(r.config.CheckpointPath, r.logger)
+	return true
+}
+
+// markUnsynced remembers a writer whose lines the next checkpoint must sync
+func (r *MarketRecorder) markUnsynced(mw *marketWriter) {
+	if r.checkpointer == nil {
+		return
+	}
+	if r.unsynced == nil {
+		r.unsynced = make(map[*marketWriter]bool)
+	}
+	r.unsynced[mw] = true
+}
+
 func (r *MarketRecorder) runWithReconnect(ctx context.Context, writers map[string]*bufio.Writer, files map[string]*os.File, marketStatuses map[string]string) error {
 	var lastErr error
 
@@ -117,7 +426,9 @@ func (r *MarketRecorder) runWithReconnect(ctx context.Context, writers map[strin
 		r.logger.Info().Msg("connection established, starting stream processing")
 
 		err = r.processStream(ctx, stream, writers, files, marketStatuses)
+		r.stats.connected.Store(false)
 		if err != nil {
+			r.sessionLog.Event("disconnected", err)
 			lastErr = err
 			if r.isRetriableError(err) && attempt < r.maxRetries {
 				r.logger.Warn().Err(err).Int("attempt", attempt).Msg("retriable error, will retry")
@@ -138,15 +449,17 @@ func (r *MarketRecorder) runWithReconnect(ctx context.Context, writers map[strin
 func (r *MarketRecorder) establishConnection(ctx context.Context) (*StreamConn, error) {
 	stream, err := r.streamClient.Dial()
 	if err != nil {
+		r.sessionLog.Event("dial_failed", err)
 		return nil, fmt.Errorf("dial failed: %w", err)
 	}
+	r.sessionLog.Event("connected", nil)
+	if r.sessionLog != nil {
+		stream.SetTap(r.sessionLog.Record)
+	}
 
 	if err := r.streamClient.Authenticate(stream); err != nil {
+		r.sessionLog.Event("authentication_failed", err)
 		stream.Close()
-		if strings.Contains(err.Error(), "session refreshed") {
-			r.config.SessionToken = r.streamClient.sessionToken
-			r.restClient.UpdateSessionKey(r.streamClient.sessionToken)
-		}
 		return nil, fmt.Errorf("authentication failed: %w", err)
 	}
 
@@ -155,16 +468,116 @@ func (r *MarketRecorder) establishConnection(ctx context.Context) (*StreamConn,
 		return nil, fmt.Errorf("heartbeat request failed: %w", err)
 	}
 
-	marketFilter := r.config.GetMarketFilter()
-	if err := r.streamClient.Subscribe(stream, marketFilter, r.initialClk, r.clk); err != nil {
+	r.streamMu.Lock()
+	initialClk, clk := r.initialClk, r.clk
+	r.streamMu.Unlock()
+
+	if err := r.streamClient.Subscribe(stream, r.subscriptionFilter(), initialClk, clk); err != nil {
+		r.sessionLog.Event("subscription_failed", err)
 		stream.Close()
 		return nil, fmt.Errorf("subscription failed: %w", err)
 	}
 
+	r.streamMu.Lock()
+	r.currentStream = stream
+	r.streamMu.Unlock()
+	r.stats.connected.Store(true)
+
+	r.sessionLog.Event("subscribed", nil)
 	r.logger.Info().Msg("subscription established; recording stream")
 	return stream, nil
 }
 
+// subscriptionFilter returns the discovered markets when discovery is enabled, otherwise the configured
+// filter without any markets that have already settled. Discovery never falls back to the configured
+// filter: the stream cannot apply its start window, so with nothing discovered no markets are named.
+func (r *MarketRecorder) subscriptionFilter() MarketFilter {
+	r.streamMu.Lock()
+	filter := r.config.GetMarketFilter()
+	r.streamMu.Unlock()
+
+	if r.discoverer != nil && len(filter.MarketIds) == 0 {
+		return MarketFilter{MarketIds: r.discoverer.MarketIDs()}
+	}
+
+	if ids := r.unsettledMarketIDs(); len(ids) > 0 {
+		filter.MarketIds = ids
+	}
+	return filter
+}
+
+// unsettledMarketIDs returns the configured market IDs that have not settled yet
+func (r *MarketRecorder) unsettledMarketIDs() []string {
+	r.streamMu.Lock()
+	defer r.streamMu.Unlock()
+
+	ids := make([]string, 0, len(r.config.MarketIDs))
+	for _, id := range r.config.MarketIDs {
+		if !r.settledMarkets[id] {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// unsubscribeSettled drops a settled market from the subscription so the stream stops sending it
+// updates. Subscriptions by event type or country cannot exclude single markets and are left as is.
+func (r *MarketRecorder) unsubscribeSettled(marketID string) {
+	if r.discoverer != nil {
+		if r.discoverer.MarkSettled(marketID) {
+			r.resubscribe(r.discoverer.MarketIDs())
+		}
+		return
+	}
+
+	r.streamMu.Lock()
+	if len(r.config.MarketIDs) == 0 {
+		r.streamMu.Unlock()
+		return
+	}
+	if r.settledMarkets == nil {
+		r.settledMarkets = make(map[string]bool)
+	}
+	r.settledMarkets[marketID] = true
+	r.streamMu.Unlock()
+
+	r.resubscribe(r.unsettledMarketIDs())
+}
+
+// resubscribe replaces the live subscription with the given markets, clearing it when there are
+// none left so the stream stops sending updates for the stale set
+func (r *MarketRecorder) resubscribe(marketIDs []string) {
+	if len(marketIDs) == 0 {
+		r.replaceSubscription(r.streamClient.Unsubscribe)
+		return
+	}
+	r.resubscribeFilter(MarketFilter{MarketIds: marketIDs})
+}
+
+// resubscribeFilter replaces the live subscription with filter
+func (r *MarketRecorder) resubscribeFilter(filter MarketFilter) {
+	r.replaceSubscription(func(stream *StreamConn) error {
+		return r.streamClient.Resubscribe(stream, filter)
+	})
+}
+
+// replaceSubscription sends a new subscription on the live connection. Stored clocks belong to the
+// previous subscription, so they are cleared and repopulated from the fresh image.
+func (r *MarketRecorder) replaceSubscription(send func(stream *StreamConn) error) {
+	r.streamMu.Lock()
+	defer r.streamMu.Unlock()
+
+	if r.currentStream == nil {
+		return
+	}
+
+	r.initialClk = ""
+	r.clk = ""
+	if err := send(r.currentStream); err != nil {
+		r.logger.Error().Err(err).Msg("failed to resubscribe")
+	}
+}
+
 func (r *MarketRecorder) processStream(ctx context.Context, stream *StreamConn, writers map[string]*bufio.Writer, files map[string]*os.File, marketStatuses map[string]string) error {
 	for {
 		select {
@@ -174,6 +587,7 @@ func (r *MarketRecorder) processStream(ctx context.Context, stream *StreamConn,
 			if err := r.readMessage(ctx, stream, writers, files, marketStatuses); err != nil {
 				return err
 			}
+			r.stats.recording.Store(int64(len(r.marketWriters)))
 		}
 	}
 }
@@ -183,17 +597,27 @@ func (r *MarketRecorder) readMessage(ctx context.Context, stream *StreamConn, wr
 	if err != nil {
 		return err
 	}
+	r.stats.countMessage(time.Now())
 
 	initialClk, clk := ExtractAndStoreClock(payload)
+	r.streamMu.Lock()
 	if initialClk != "" {
 		r.initialClk = initialClk
 	}
 	if clk != "" {
 		r.clk = clk
 	}
+	r.streamMu.Unlock()
 
 	op := ExtractOp(payload)
 	if op == "mcm" {
+		r.flushReadyBuffers()
+		if time.Since(r.lastIdleCheck) >= idleCheckInterval {
+			r.finalizeIdleMarkets(ctx, time.Now(), writers, files)
+			r.lastIdleCheck = time.Now()
+		}
+		r.finalizeDroppedMarkets(ctx, writers, files)
+
 		changeType := ExtractChangeType(payload)
 		if changeType == "HEARTBEAT" {
 			return nil
@@ -217,15 +641,22 @@ func (r *MarketRecorder) readMessage(ctx context.Context, stream *StreamConn, wr
 				continue
 			}
 
-			marketID, ok := marketChange["id"].(string)
-			if !ok || marketID == "" {
+			marketID, _ := marketChange["id"].(string)
+			if !usableMarketID(marketID) {
+				r.routing.dropped.Add(1)
+				r.logger.Warn().Str("market_id", marketID).Msg("dropping market change without a usable market ID")
+				continue
+			}
+			if _, settled := r.settledSeen[marketID]; settled {
 				continue
 			}
+			if img, _ := marketChange["img"].(bool); img {
+				r.imagesRequested = false
+			}
 
 			// Fetch market catalogue if we don't have it yet
-			if err := r.fetchMarketCatalogue(ctx, marketID); err != nil {
-				r.logger.Error().Err(err).Str("market_id", marketID).Msg("failed to fetch market catalogue")
-				// Continue processing even if catalogue fetch fails
+			if r.config.enrichmentMode() != EnrichmentOff {
+				r.requestCatalogue(ctx, marketID)
 			}
 
 			// Extract status for this specific market
@@ -244,6 +675,45 @@ func (r *MarketRecorder) readMessage(ctx context.Context, stream *StreamConn, wr
 				marketJustSettled = !IsMarketSettled(oldStatus) && IsMarketSettled(newStatus)
 			}
 
+			if marketDef, ok := marketChange["marketDefinition"].(map[string]interface{}); ok && (!r.hooks.empty() || r.config.RecordTimeline) {
+				if definition, err := parseMarketDefinition(marketDef); err != nil {
+					r.logger.Error().Err(err).Str("market_id", marketID).Msg("failed to parse market definition")
+				} else {
+					at := publishTime(data["pt"])
+					r.fireHooks(marketID, at, definition, oldStatus)
+					r.observeTimeline(marketID, at, definition)
+				}
+			}
+
+			if marketDef, ok := marketChange["marketDefinition"].(map[string]interface{}); ok {
+				r.trackMarketTime(marketID, marketDef)
+				r.trackEventType(marketID, marketDef)
+			}
+
+			if _, exists := writers[marketID]; !exists && !r.inRecordingWindow(marketID, publishTime(data["pt"])) {
+				r.holdOutsideWindow(marketID)
+				if marketJustSettled {
+					r.forgetMarketWindow(marketID, time.Now())
+					r.unsubscribeSettled(marketID)
+				}
+				continue
+			}
+
+			if _, exists := writers[marketID]; !exists {
+				if reason := r.newMarketRefusal(writers); reason != "" {
+					r.refuseMarket(marketID, reason)
+					continue
+				}
+				// Deltas of a refused or held market apply to state its file would not have, so it waits for an image
+				if r.skipped[marketID] {
+					if img, _ := marketChange["img"].(bool); !img {
+						r.requestImages()
+						continue
+					}
+					delete(r.skipped, marketID)
+				}
+			}
+
 			if _, exists := writers[marketID]; !exists {
 				if err := r.createWriterForMarket(marketID, writers, files); err != nil {
 					r.logger.Error().Err(err).Str("market_id", marketID).Msg("failed to create writer for new market")
@@ -252,13 +722,23 @@ func (r *MarketRecorder) readMessage(ctx context.Context, stream *StreamConn, wr
 				}
 			}
 
-			if writer, exists := writers[marketID]; exists {
+			recordedChange, record := marketChange, true
+			if _, exists := writers[marketID]; exists {
+				r.ensureMarketWriter(marketID, writers, files)
+				r.marketActivity[marketID] = time.Now()
+
+				if r.config.recordingMode() == RecordingBSP {
+					recordedChange, record = compactForBSP(marketChange)
+				}
+			}
+
+			if _, exists := writers[marketID]; exists && record {
 				// Create a single-market message for this market only
 				singleMarketData := map[string]interface{}{
 					"op":  data["op"],
 					"pt":  data["pt"],
 					"clk": data["clk"],
-					"mc":  []interface{}{marketChange},
+					"mc":  []interface{}{recordedChange},
 				}
 
 				singleMarketPayload, err := json.Marshal(singleMarketData)
@@ -267,6 +747,16 @@ func (r *MarketRecorder) readMessage(ctx context.Context, stream *StreamConn, wr
 					continue
 				}
 
+				var filePath string
+				if file := files[marketID]; file != nil {
+					filePath = file.Name()
+				}
+				if err := validateRouting(marketID, singleMarketPayload, filePath, r.fileManager.GetMarketFilePath(marketID), r.fileManager.RecordingFilePath(marketID)); err != nil {
+					r.routing.misrouted.Add(1)
+					r.logger.Error().Err(err).Str("market_id", marketID).Msg("refusing to write misrouted market change")
+					continue
+				}
+
 				// Remove the ID field
 				filteredPayload, err := RemoveIDField(singleMarketPayload)
 				if err != nil {
@@ -274,23 +764,18 @@ func (r *MarketRecorder) readMessage(ctx context.Context, stream *StreamConn, wr
 					continue
 				}
 
-				// Enrich with market catalogue data
-				enrichedPayload, err := r.enrichMarketData(marketID, filteredPayload)
-				if err != nil {
-					r.logger.Error().Err(err).Str("market_id", marketID).Msg("failed to enrich market data")
-					// Use original filtered payload if enrichment fails
-					enrichedPayload = filteredPayload
-				}
+				r.countRecorded(marketID, publishTime(data["pt"]))
 
-				if _, err := writer.Write(append(enrichedPayload, '\n')); err != nil {
-					r.logger.Error().Err(err).Str("market_id", marketID).Msg("failed to write to file")
-					continue
+				// Hold messages back until the catalogue arrives so they can be enriched
+				if r.awaitingCatalogue(marketID) {
+					r.bufferMessage(marketID, recordedChange, filteredPayload)
+				} else {
+					r.writeMarketPayload(marketID, recordedChange, filteredPayload)
 				}
+			}
 
-				if err := writer.Flush(); err != nil {
-					r.logger.Error().Err(err).Str("market_id", marketID).Msg("failed to flush file")
-					continue
-				}
+			if !marketJustSettled {
+				r.maybeRotate(ctx, marketID, time.Now(), writers, files)
 			}
 
 			if marketJustSettled {
@@ -305,13 +790,32 @@ func (r *MarketRecorder) readMessage(ctx context.Context, stream *StreamConn, wr
 				}
 				singleMarketPayload, _ := json.Marshal(singleMarketData)
 
-				if err := r.handleMarketSettlement(ctx, marketID, singleMarketPayload, writers); err != nil {
-					r.logger.Error().Err(err).Str("market_id", marketID).Msg("failed to handle market settlement")
-				}
+				r.flushBuffered(marketID)
+				r.finalizeMarket(ctx, marketID, singleMarketPayload, writers, files)
 
 				// Clean up market catalogue cache for settled market
 				delete(r.marketCatalogues, marketID)
+				delete(r.enrichedImages, marketID)
+				delete(r.catalogueWaitExpired, marketID)
+				delete(r.marketEventTypes, marketID)
+				r.forgetMarketWindow(marketID, time.Now())
+				if r.catalogueFetcher != nil {
+					r.catalogueFetcher.Forget(marketID)
+				}
 				r.logger.Debug().Str("market_id", marketID).Msg("removed market catalogue from cache")
+
+				r.unsubscribeSettled(marketID)
+			}
+		}
+
+		// Checkpoints are saved in the background; one still being saved delays the next
+		if r.checkpointer != nil {
+			r.sinceCheckpoint++
+			if r.sinceCheckpoint >= r.config.checkpointEvery() && r.checkpointer.Ready() {
+				if req, ok := r.takeCheckpoint(marketStatuses); ok {
+					r.checkpointer.Submit(req)
+					r.sinceCheckpoint = 0
+				}
 			}
 		}
 	}
@@ -319,6 +823,237 @@ func (r *MarketRecorder) readMessage(ctx context.Context, stream *StreamConn, wr
 	return nil
 }
 
+// finalizeMarket hands a settled market's file over to its writer goroutine, which drains the queued
+// lines and then compresses and uploads the file without holding up the stream reader
+func (r *MarketRecorder) finalizeMarket(ctx context.Context, marketID string, payload []byte, writers map[string]*bufio.Writer, files map[string]*os.File) {
+	mw, exists := r.marketWriters[marketID]
+	if !exists {
+		if err := r.handleMarketSettlement(ctx, marketID, payload, writers); err != nil {
+			r.logger.Error().Err(err).Str("market_id", marketID).Msg("failed to handle market settlement")
+		}
+		return
+	}
+
+	r.releaseMarketWriter(marketID)
+	delete(r.marketActivity, marketID)
+	delete(writers, marketID)
+	delete(files, marketID)
+	r.forgetSegments(marketID)
+	timeline := r.takeTimeline(marketID)
+	counts := r.takeCounts(marketID)
+
+	// Uploads should finish even if the recorder is shutting down
+	settleCtx := context.WithoutCancel(ctx)
+	r.settlements.Add(1)
+	go func() {
+		defer r.settlements.Done()
+
+		err := mw.Close(true)
+		if err != nil {
+			r.logger.Error().Err(err).Str("market_id", marketID).Msg("failed to close market file")
+		}
+		if err := r.archiveMarketFile(settleCtx, marketID, payload, false, timeline, counts); err != nil {
+			r.logger.Error().Err(err).Str("market_id", marketID).Msg("failed to handle market settlement")
+		}
+		r.finalizeSinks(settleCtx, marketID, payload, false)
+	}()
+}
+
+// upload sends a file to S3 and removes it locally once verified, handing it to the retry queue on failure
+func (r *MarketRecorder) upload(ctx context.Context, marketID string, upload PendingUpload) {
+	if err := uploadVerified(ctx, r.storage, upload, r.fileManager.outputPath, time.Now()); err != nil {
+		r.logger.Error().Err(err).Str("market_id", marketID).Str("s3_key", upload.Key).Msg("failed to upload to S3")
+		if r.uploadQueue != nil {
+			r.uploadQueue.Enqueue(upload, err)
+		}
+		return
+	}
+
+	r.logger.Info().Str("market_id", marketID).Str("s3_key", upload.Key).Msg("uploaded and verified market file in S3")
+	for _, err := range r.fileManager.CleanupFiles(append(upload.Cleanup, upload.FilePath)...) {
+		r.logger.Warn().Err(err).Str("market_id", marketID).Msg("failed to remove uploaded file")
+	}
+}
+
+func (r *MarketRecorder) logRoutingStats() {
+	stats := r.RoutingStats()
+	if stats == (RoutingStats{}) {
+		return
+	}
+	r.logger.Warn().Uint64("dropped", stats.Dropped).Uint64("misrouted", stats.Misrouted).Uint64("duplicate_writers", stats.DuplicateWriters).Msg("market changes refused during recording")
+}
+
+// requestImages resubscribes so the stream sends fresh images, once until an image arrives. A
+// market refused earlier can only start its file from one.
+func (r *MarketRecorder) requestImages() {
+	if r.imagesRequested {
+		return
+	}
+	r.imagesRequested = true
+	r.logger.Info().Msg("resubscribing for images of markets waiting to be recorded")
+	r.resubscribeFilter(r.subscriptionFilter())
+}
+
+// newMarketRefusal explains why a writer cannot be opened for another market, or returns ""
+func (r *MarketRecorder) newMarketRefusal(writers map[string]*bufio.Writer) string {
+	if r.diskGuard.Exceeded() {
+		return "disk usage above threshold"
+	}
+	if r.config.MaxConcurrentMarkets > 0 && len(writers) >= r.config.MaxConcurrentMarkets {
+		return "concurrent market limit reached"
+	}
+	return ""
+}
+
+// refuseMarket records that a market was not admitted for recording, logging it once. It is only
+// admitted later from a full image.
+func (r *MarketRecorder) refuseMarket(marketID, reason string) {
+	if r.skipped[marketID] {
+		return
+	}
+	if r.skipped == nil {
+		r.skipped = make(map[string]bool)
+	}
+	r.skipped[marketID] = true
+	r.logger.Warn().Str("market_id", marketID).Msg(reason + "; not recording new market")
+}
+
+// finalizeIdleMarkets force-finalizes markets that have not received an update within the idle
+// timeout, such as abandoned races that never reach CLOSED
+func (r *MarketRecorder) finalizeIdleMarkets(ctx context.Context, now time.Time, writers map[string]*bufio.Writer, files map[string]*os.File) {
+	timeout := r.config.IdleMarketTimeout
+	if timeout <= 0 {
+		return
+	}
+
+	for marketID, lastSeen := range r.marketActivity {
+		if now.Sub(lastSeen) < timeout {
+			continue
+		}
+		delete(r.marketActivity, marketID)
+
+		if _, exists := r.marketWriters[marketID]; !exists {
+			continue
+		}
+
+		r.logger.Warn().Str("market_id", marketID).Time("last_update", lastSeen).Msg("market idle; finalizing incomplete recording")
+		r.finalizeIncomplete(ctx, marketID, writers, files)
+		r.unsubscribeSettled(marketID)
+	}
+}
+
+// finalizeIncomplete closes a market that stopped being recorded before it settled and archives
+// its file as incomplete
+func (r *MarketRecorder) finalizeIncomplete(ctx context.Context, marketID string, writers map[string]*bufio.Writer, files map[string]*os.File) {
+	mw, exists := r.marketWriters[marketID]
+	if !exists {
+		return
+	}
+
+	r.flushBuffered(marketID)
+	r.releaseMarketWriter(marketID)
+	delete(r.marketActivity, marketID)
+	delete(r.marketEventTypes, marketID)
+	delete(r.marketTimes, marketID)
+	delete(writers, marketID)
+	delete(files, marketID)
+	r.forgetSegments(marketID)
+
+	timeline := r.takeTimeline(marketID)
+	if timeline != nil {
+		timeline.Incomplete = true
+	}
+	counts := r.takeCounts(marketID)
+
+	archiveCtx := context.WithoutCancel(ctx)
+	r.settlements.Add(1)
+	go func() {
+		defer r.settlements.Done()
+
+		err := mw.Close(true)
+		if err != nil {
+			r.logger.Error().Err(err).Str("market_id", marketID).Msg("failed to close market file")
+		}
+		payload, err := r.fileManager.LastMarketDefinition(marketID)
+		if err != nil {
+			r.logger.Error().Err(err).Str("market_id", marketID).Msg("cannot finalize incomplete market file")
+			return
+		}
+		if err := r.archiveMarketFile(archiveCtx, marketID, payload, true, timeline, counts); err != nil {
+			r.logger.Error().Err(err).Str("market_id", marketID).Msg("failed to archive incomplete market file")
+		}
+		r.finalizeSinks(archiveCtx, marketID, payload, true)
+	}()
+}
+
+// observeTimeline adds a market definition to the market's status timeline when timelines are enabled
+func (r *MarketRecorder) observeTimeline(marketID string, at time.Time, definition *MarketDefinition) {
+	if !r.config.RecordTimeline {
+		return
+	}
+	timeline, exists := r.timelines[marketID]
+	if !exists {
+		if r.timelines == nil {
+			r.timelines = make(map[string]*MarketTimeline)
+		}
+		timeline = NewMarketTimeline(marketID)
+		r.timelines[marketID] = timeline
+	}
+	timeline.Observe(at, definition)
+}
+
+// takeTimeline removes and returns a market's timeline so it can be written by a finalizer
+func (r *MarketRecorder) takeTimeline(marketID string) *MarketTimeline {
+	timeline := r.timelines[marketID]
+	delete(r.timelines, marketID)
+	return timeline
+}
+
+// ensureMarketWriter starts the writer goroutine for a market whose file has been opened
+func (r *MarketRecorder) ensureMarketWriter(marketID string, writers map[string]*bufio.Writer, files map[string]*os.File) {
+	if _, exists := r.marketWriters[marketID]; exists {
+		return
+	}
+	if r.marketWriters == nil {
+		r.marketWriters = make(map[string]*marketWriter)
+	}
+	if r.marketActivity == nil {
+		r.marketActivity = make(map[string]time.Time)
+	}
+	r.marketActivity[marketID] = time.Now()
+	r.marketWriters[marketID] = startMarketWriter(marketID, writers[marketID], files[marketID], r.fileManager.takeMarketStream(marketID), r.config.WriterQueueSize, r.config.FlushPolicy(), r.logger)
+	r.openMarkets.Store(marketID, struct{}{})
+}
+
+// releaseMarketWriter forgets a market's writer once its file is handed over for closing
+func (r *MarketRecorder) releaseMarketWriter(marketID string) {
+	delete(r.marketWriters, marketID)
+	r.openMarkets.Delete(marketID)
+}
+
+// hasOpenMarketFile reports whether a file name belongs to a market whose writer is open. It is
+// safe to call from other goroutines, such as the retention janitor.
+func (r *MarketRecorder) hasOpenMarketFile(name string) bool {
+	open := false
+	r.openMarkets.Range(func(key, _ any) bool {
+		marketID := key.(string)
+		open = name == marketID || strings.HasPrefix(name, marketID+".")
+		return !open
+	})
+	return open
+}
+
+// stopMarketWriters drains every writer goroutine and waits for pending settlements to finish
+func (r *MarketRecorder) stopMarketWriters() {
+	for marketID, mw := range r.marketWriters {
+		if err := mw.Close(false); err != nil {
+			r.logger.Error().Err(err).Str("market_id", marketID).Msg("failed to close market file")
+		}
+		r.releaseMarketWriter(marketID)
+	}
+	r.settlements.Wait()
+}
+
 func (r *MarketRecorder) handleMarketSettlement(ctx context.Context, marketID string, payload []byte, writers map[string]*bufio.Writer) error {
 	if writer, exists := writers[marketID]; exists {
 		if err := writer.Flush(); err != nil {
@@ -327,36 +1062,116 @@ func (r *MarketRecorder) handleMarketSettlement(ctx context.Context, marketID st
 		delete(writers, marketID)
 	}
 
-	eventInfo, err := ExtractEventInfo(payload)
+	return r.archiveMarketFile(ctx, marketID, payload, false, r.takeTimeline(marketID), r.takeCounts(marketID))
+}
+
+// archiveMarketFile compresses a finished market file and uploads it. Incomplete recordings are
+// stored with an IncompleteSuffix so they are never mistaken for a full market. A status timeline,
+// when recorded, is stored alongside as a JSON sidecar, and the archive is added to the recording
+// index when enabled.
+func (r *MarketRecorder) archiveMarketFile(ctx context.Context, marketID string, payload []byte, incomplete bool, timeline *MarketTimeline, counts marketCounts) error {
+	name := marketID
+	if incomplete {
+		name += IncompleteSuffix
+	}
+
+	eventInfo, err := r.eventInfo(payload)
 	if err != nil {
 		r.logger.Error().Err(err).Str("market_id", marketID).Msg("failed to extract event info")
 		return nil
 	}
 
-	inputFile := r.fileManager.GetMarketFilePath(marketID)
-	compressedFile := r.fileManager.GetCompressedFilePath(marketID)
-
-	if err := r.fileManager.CompressToBzip2(inputFile, compressedFile); err != nil {
-		r.logger.Error().Err(err).Str("market_id", marketID).Msg("failed to compress file")
+	// A raw file left by a run without streaming compression is compressed as usual
+	source := r.fileManager.GetMarketFilePath(marketID)
+	streamed := r.fileManager.StreamsCompressed() && !r.fileManager.MarketFileExists(marketID)
+	if streamed {
+		source = r.fileManager.RecordingFilePath(marketID)
+	}
+	if !r.archiveRecording(ctx, marketID, source, streamed, name, eventInfo) {
 		return nil
 	}
 
-	r.logger.Info().Str("market_id", marketID).Str("file", compressedFile).Msg("compressed market file")
-
-	if r.storage != nil {
-		s3Key := r.storage.BuildS3Key(eventInfo, marketID+".bz2")
-		if err := r.storage.Upload(ctx, compressedFile, s3Key); err != nil {
-			r.logger.Error().Err(err).Str("market_id", marketID).Str("s3_key", s3Key).Msg("failed to upload to S3")
-			return nil
+	if r.config.RecordingIndex {
+		var s3Key string
+		if r.storage != nil {
+			s3Key = r.storage.BuildKey(eventInfo, name+r.fileManager.CompressedExtension())
 		}
+		r.indexRecording(marketID, payload, incomplete, r.fileManager.GetArchivePath(eventInfo, name), s3Key, counts)
+	}
 
-		r.logger.Info().Str("market_id", marketID).Str("s3_key", s3Key).Msg("uploaded market file to S3")
-		r.fileManager.CleanupFiles(inputFile, compressedFile)
+	if timeline != nil {
+		timelineFile := filepath.Join(r.fileManager.ArchiveDir(eventInfo), name+TimelineSuffix)
+		if err := timeline.WriteFile(timelineFile); err != nil {
+			r.logger.Error().Err(err).Str("market_id", marketID).Msg("failed to write status timeline")
+		} else if r.storage != nil {
+			timelineKey := r.storage.BuildKey(eventInfo, name+TimelineSuffix)
+			r.upload(ctx, marketID, PendingUpload{FilePath: timelineFile, Key: timelineKey, Tags: r.objectTags(eventInfo)})
+		}
 	}
 
 	return nil
 }
 
+// eventInfo extracts a market's event details and files them under the configured partition
+func (r *MarketRecorder) eventInfo(payload []byte) (*EventInfo, error) {
+	eventInfo, err := ExtractEventInfo(payload)
+	if err != nil {
+		return nil, err
+	}
+	r.config.Partition.Apply(eventInfo)
+	return eventInfo, nil
+}
+
+// archiveRecording turns a recorded file into the archive called name, compressing it unless it
+// was compressed while recording, and uploads it when S3 is configured. It reports whether the
+// archive was created.
+func (r *MarketRecorder) archiveRecording(ctx context.Context, marketID, source string, compressed bool, name string, eventInfo *EventInfo) bool {
+	if err := os.MkdirAll(r.fileManager.ArchiveDir(eventInfo), 0755); err != nil {
+		r.logger.Error().Err(err).Str("market_id", marketID).Msg("failed to create archive directory")
+		return false
+	}
+	archive := r.fileManager.GetArchivePath(eventInfo, name)
+	var cleanup []string
+
+	if compressed {
+		if source != archive {
+			if err := os.Rename(source, archive); err != nil {
+				r.logger.Error().Err(err).Str("market_id", marketID).Msg("failed to rename market file")
+				return false
+			}
+		}
+	} else {
+		if err := r.fileManager.CompressFile(source, archive); err != nil {
+			r.logger.Error().Err(err).Str("market_id", marketID).Msg("failed to compress file")
+			return false
+		}
+		cleanup = []string{source}
+
+		r.logger.Info().Str("market_id", marketID).Str("file", archive).Msg("compressed market file")
+	}
+
+	if r.storage != nil {
+		s3Key := r.storage.BuildKey(eventInfo, name+r.fileManager.CompressedExtension())
+		r.upload(ctx, marketID, PendingUpload{FilePath: archive, Key: s3Key, Cleanup: cleanup, Tags: r.objectTags(eventInfo)})
+	}
+	return true
+}
+
+// objectTags returns the sport, venue and date tags for an event's uploads when tagging is enabled
+func (r *MarketRecorder) objectTags(eventInfo *EventInfo) map[string]string {
+	if !r.config.S3ObjectTags {
+		return nil
+	}
+	tags := map[string]string{"date": eventInfo.Date.Format("2006-01-02")}
+	if sport := sportName(eventInfo.EventTypeID); sport != "" {
+		tags["sport"] = sport
+	}
+	if eventInfo.Venue != "" {
+		tags["venue"] = eventInfo.Venue
+	}
+	return tags
+}
+
 func (r *MarketRecorder) openWriters() (map[string]*bufio.Writer, map[string]*os.File, func(), error) {
 	writers := make(map[string]*bufio.Writer)
 	files := make(map[string]*os.File)
@@ -370,8 +1185,20 @@ func (r *MarketRecorder) openWriters() (map[string]*bufio.Writer, map[string]*os
 		}
 	}
 
-	if len(r.config.MarketIDs) > 0 {
-		for _, marketID := range r.config.MarketIDs {
+	r.streamMu.Lock()
+	marketIDs := r.config.MarketIDs
+	r.streamMu.Unlock()
+
+	if len(marketIDs) > 0 {
+		for _, marketID := range marketIDs {
+			// Files left by a previous run are resumed by recoverMarketFiles rather than truncated
+			if r.fileManager.MarketFileExists(marketID) {
+				continue
+			}
+			if reason := r.newMarketRefusal(writers); reason != "" {
+				r.refuseMarket(marketID, reason)
+				continue
+			}
 			if err := r.createWriterForMarket(marketID, writers, files); err != nil {
 				closer()
 				return nil, nil, nil, fmt.Errorf("open output file for market %s: %w", marketID, err)
@@ -382,7 +1209,88 @@ func (r *MarketRecorder) openWriters() (map[string]*bufio.Writer, map[string]*os
 	return writers, files, closer, nil
 }
 
+// recoverMarketFiles picks up uncompressed market files left behind by a previous run. Markets that
+// have settled since are finalized and uploaded; the rest are reopened for appending.
+func (r *MarketRecorder) recoverMarketFiles(ctx context.Context, writers map[string]*bufio.Writer, files map[string]*os.File, marketStatuses map[string]string) {
+	marketIDs, err := r.fileManager.ListMarketFiles()
+	if err != nil {
+		r.logger.Error().Err(err).Msg("failed to scan for unfinished market files")
+		return
+	}
+
+	var pending []string
+	for _, marketID := range marketIDs {
+		if _, exists := writers[marketID]; !exists {
+			pending = append(pending, marketID)
+		}
+	}
+	if len(pending) == 0 {
+		return
+	}
+
+	r.logger.Info().Strs("market_ids", pending).Msg("found unfinished market files from a previous run")
+
+	statuses := make(map[string]string, len(pending))
+	books, lookupErr := r.restClient.ListMarketBookBatched(ctx, pending, nil, nil, nil)
+	if lookupErr != nil {
+		// Without market status the safe option is to keep recording into every file
+		r.logger.Warn().Err(lookupErr).Msg("failed to look up status of unfinished markets; resuming all")
+	}
+	for _, book := range books {
+		statuses[book.MarketID] = book.Status
+	}
+
+	for _, marketID := range pending {
+		status, found := statuses[marketID]
+		// Markets missing from a successful lookup have closed and been purged by Betfair
+		settled := IsMarketSettled(status) || (lookupErr == nil && !found)
+
+		if !settled {
+			if err := r.resumeWriterForMarket(marketID, writers, files); err != nil {
+				r.logger.Error().Err(err).Str("market_id", marketID).Msg("failed to reopen market file")
+				continue
+			}
+			if status != "" {
+				marketStatuses[marketID] = status
+			}
+			r.logger.Info().Str("market_id", marketID).Str("status", status).Msg("resuming market file")
+			continue
+		}
+
+		payload, err := r.fileManager.LastMarketDefinition(marketID)
+		if err != nil {
+			r.logger.Error().Err(err).Str("market_id", marketID).Msg("cannot finalize unfinished market file")
+			continue
+		}
+		r.logger.Info().Str("market_id", marketID).Msg("finalizing market that settled while the recorder was down")
+		if err := r.handleMarketSettlement(ctx, marketID, payload, writers); err != nil {
+			r.logger.Error().Err(err).Str("market_id", marketID).Msg("failed to finalize market file")
+		}
+		marketStatuses[marketID] = "CLOSED"
+	}
+}
+
+func (r *MarketRecorder) resumeWriterForMarket(marketID string, writers map[string]*bufio.Writer, files map[string]*os.File) error {
+	writer, file, err := r.fileManager.AppendMarketWriter(marketID)
+	if err != nil {
+		return err
+	}
+
+	writers[marketID] = writer
+	files[marketID] = file
+	return nil
+}
+
 func (r *MarketRecorder) createWriterForMarket(marketID string, writers map[string]*bufio.Writer, files map[string]*os.File) error {
+	if _, exists := writers[marketID]; exists {
+		r.routing.duplicateWriters.Add(1)
+		return fmt.Errorf("create writer for %s: %w", marketID, errDuplicateMarketWriter)
+	}
+	if _, exists := r.marketWriters[marketID]; exists {
+		r.routing.duplicateWriters.Add(1)
+		return fmt.Errorf("create writer for %s: %w", marketID, errDuplicateMarketWriter)
+	}
+
 	writer, file, err := r.fileManager.CreateMarketWriter(marketID)
 	if err != nil {
 		return err
@@ -426,37 +1334,174 @@ func (r *MarketRecorder) fetchMarketCatalogue(ctx context.Context, marketID stri
 
 	r.logger.Info().Str("market_id", marketID).Msg("fetching market catalogue")
 
-	filter := CreateMarketFilter().WithMarketIDs([]string{marketID})
-	projection := []MarketProjection{
-		MarketProjectionEvent,
-		MarketProjectionMarketDescription,
-		MarketProjectionRunnerDescription,
-		MarketProjectionEventType,
-		MarketProjectionCompetition,
-	}
-
-	catalogues, err := r.restClient.ListMarketCatalogue(
-		ctx,
-		*filter,
-		projection,
-		MarketSortFirstToStart,
-		1,
-	)
+	catalogue, err := fetchCatalogue(ctx, r.restClient, marketID)
 	if err != nil {
-		return fmt.Errorf("failed to fetch market catalogue for %s: %w", marketID, err)
-	}
-
-	if len(catalogues) == 0 {
-		return fmt.Errorf("no market catalogue found for market %s", marketID)
+		return err
 	}
 
 	// Cache the market catalogue
-	r.marketCatalogues[marketID] = &catalogues[0]
-	r.logger.Info().Str("market_id", marketID).Str("market_name", catalogues[0].MarketName).Msg("cached market catalogue")
+	r.marketCatalogues[marketID] = catalogue
+	r.logger.Info().Str("market_id", marketID).Str("market_name", catalogue.MarketName).Msg("cached market catalogue")
 
 	return nil
 }
 
+// requestCatalogue makes a market's catalogue available for enrichment. With a background fetcher
+// the lookup is queued and picked up on a later message; otherwise it is fetched inline.
+func (r *MarketRecorder) requestCatalogue(ctx context.Context, marketID string) {
+	if r.catalogueFetcher == nil {
+		if err := r.fetchMarketCatalogue(ctx, marketID); err != nil {
+			r.logger.Error().Err(err).Str("market_id", marketID).Msg("failed to fetch market catalogue")
+			// Continue processing even if catalogue fetch fails
+		}
+		return
+	}
+
+	if _, exists := r.marketCatalogues[marketID]; exists {
+		return
+	}
+	if catalogue, ok := r.catalogueFetcher.Get(marketID); ok {
+		r.marketCatalogues[marketID] = catalogue
+		return
+	}
+	r.catalogueFetcher.Request(marketID)
+}
+
+// catalogueBuffer holds a market's messages while its catalogue is being fetched
+type catalogueBuffer struct {
+	since    time.Time
+	messages []bufferedMessage
+}
+
+type bufferedMessage struct {
+	marketChange map[string]interface{}
+	payload      []byte
+}
+
+// awaitingCatalogue reports whether a market's messages should be held back for enrichment
+func (r *MarketRecorder) awaitingCatalogue(marketID string) bool {
+	if r.catalogueFetcher == nil {
+		return false
+	}
+	if buffer, ok := r.catalogueBuffers[marketID]; ok && len(buffer.messages) > 0 {
+		// Keep messages in order behind those already waiting
+		return true
+	}
+	if _, cached := r.marketCatalogues[marketID]; cached {
+		return false
+	}
+	if r.catalogueWaitExpired[marketID] {
+		return false
+	}
+	return !r.catalogueFetcher.Resolved(marketID)
+}
+
+func (r *MarketRecorder) bufferMessage(marketID string, marketChange map[string]interface{}, payload []byte) {
+	if r.catalogueBuffers == nil {
+		r.catalogueBuffers = make(map[string]*catalogueBuffer)
+	}
+	buffer, ok := r.catalogueBuffers[marketID]
+	if !ok {
+		buffer = &catalogueBuffer{since: time.Now()}
+		r.catalogueBuffers[marketID] = buffer
+	}
+	buffer.messages = append(buffer.messages, bufferedMessage{marketChange: marketChange, payload: payload})
+}
+
+// flushReadyBuffers writes out buffered markets whose catalogue has been resolved or whose wait has expired
+func (r *MarketRecorder) flushReadyBuffers() {
+	wait := r.config.CatalogueWait
+	if wait <= 0 {
+		wait = DefaultCatalogueWait
+	}
+
+	for marketID, buffer := range r.catalogueBuffers {
+		if !r.catalogueFetcher.Resolved(marketID) {
+			if time.Since(buffer.since) < wait {
+				continue
+			}
+			r.logger.Warn().Str("market_id", marketID).Dur("waited", time.Since(buffer.since)).Msg("market catalogue not available; recording without enrichment")
+			if r.catalogueWaitExpired == nil {
+				r.catalogueWaitExpired = make(map[string]bool)
+			}
+			r.catalogueWaitExpired[marketID] = true
+		}
+		r.flushBuffered(marketID)
+	}
+}
+
+// flushBuffered writes every buffered message of a market, enriching them if the catalogue is available
+func (r *MarketRecorder) flushBuffered(marketID string) {
+	buffer, ok := r.catalogueBuffers[marketID]
+	if !ok {
+		return
+	}
+	delete(r.catalogueBuffers, marketID)
+
+	if r.catalogueFetcher != nil {
+		if catalogue, found := r.catalogueFetcher.Get(marketID); found {
+			r.marketCatalogues[marketID] = catalogue
+		}
+	}
+
+	for _, message := range buffer.messages {
+		r.writeMarketPayload(marketID, message.marketChange, message.payload)
+	}
+}
+
+func (r *MarketRecorder) flushAllBuffered() {
+	for marketID := range r.catalogueBuffers {
+		r.flushBuffered(marketID)
+	}
+}
+
+// writeMarketPayload enriches a single-market message as configured and queues it for the market's file
+func (r *MarketRecorder) writeMarketPayload(marketID string, marketChange map[string]interface{}, payload []byte) {
+	mw, exists := r.marketWriters[marketID]
+	if !exists {
+		r.logger.Error().Str("market_id", marketID).Msg("no writer for market message")
+		return
+	}
+
+	enrichedPayload := payload
+	if r.shouldEnrich(marketID, marketChange) {
+		var err error
+		enrichedPayload, err = r.enrichMarketData(marketID, payload)
+		if err != nil {
+			r.logger.Error().Err(err).Str("market_id", marketID).Msg("failed to enrich market data")
+			// Use original filtered payload if enrichment fails
+			enrichedPayload = payload
+		}
+	}
+
+	line := append(enrichedPayload, '\n')
+	mw.Write(line)
+	r.markUnsynced(mw)
+	r.trackSegment(marketID, marketChange, line, time.Now())
+	r.writeToSinks(marketID, enrichedPayload)
+}
+
+// shouldEnrich applies the configured enrichment mode to a single market change
+func (r *MarketRecorder) shouldEnrich(marketID string, marketChange map[string]interface{}) bool {
+	switch r.config.enrichmentMode() {
+	case EnrichmentOff:
+		return false
+	case EnrichmentFirstImage:
+		if img, _ := marketChange["img"].(bool); !img || r.enrichedImages[marketID] {
+			return false
+		}
+		if _, cached := r.marketCatalogues[marketID]; !cached {
+			return false
+		}
+		if r.enrichedImages == nil {
+			r.enrichedImages = make(map[string]bool)
+		}
+		r.enrichedImages[marketID] = true
+		return true
+	}
+	return true
+}
+
 func (r *MarketRecorder) enrichMarketData(marketID string, payload []byte) ([]byte, error) {
 	// Check if we have market catalogue data for this market
 	catalogue, exists := r.marketCatalogues[marketID]
@@ -488,23 +1533,27 @@ func (r *MarketRecorder) enrichMarketData(marketID string, payload []byte) ([]by
 	}
 
 	// Add market name and event information
-	marketDef["marketName"] = catalogue.MarketName
+	if r.config.enrichesField(EnrichMarketName) {
+		marketDef["marketName"] = catalogue.MarketName
+	}
 	if catalogue.Event != nil {
-		marketDef["eventName"] = catalogue.Event.Name
-		if catalogue.Event.Venue != "" {
+		if r.config.enrichesField(EnrichEventName) {
+			marketDef["eventName"] = catalogue.Event.Name
+		}
+		if catalogue.Event.Venue != "" && r.config.enrichesField(EnrichVenue) {
 			marketDef["venue"] = catalogue.Event.Venue
 		}
 	}
-	if catalogue.EventType != nil {
+	if catalogue.EventType != nil && r.config.enrichesField(EnrichEventType) {
 		marketDef["eventTypeName"] = catalogue.EventType.Name
 	}
-	if catalogue.Competition != nil {
+	if catalogue.Competition != nil && r.config.enrichesField(EnrichCompetition) {
 		marketDef["competitionName"] = catalogue.Competition.Name
 	}
 
 	// Enrich runner information
 	runners, ok := marketDef["runners"].([]interface{})
-	if ok && len(runners) > 0 {
+	if ok && len(runners) > 0 && r.config.enrichesField(EnrichRunnerNames) {
 		// Create a map of runner catalogue data for quick lookup
 		runnerMap := make(map[int64]RunnerCatalog)
 		for _, catalogueRunner := range catalogue.Runners {
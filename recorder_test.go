@@ -885,4 +885,182 @@ func TestValidateRecordedMarketFiles(t *testing.T) {
 	} else {
 		t.Logf("✅ All %d market files are clean - no contamination detected", totalFilesChecked)
 	}
-}
\ No newline at end of file
+}
+
+func TestMarketRecorderCancelAllOnShutdown(t *testing.T) {
+	logger := zerolog.New(zerolog.NewTestWriter(t))
+
+	cancelled := false
+	client := newTestRESTClient(t, func(method string, params map[string]interface{}) interface{} {
+		if method == "cancelOrders" {
+			cancelled = true
+		}
+		return map[string]interface{}{"status": "SUCCESS"}
+	})
+
+	recorder := &MarketRecorder{
+		config:      &Config{CancelAllOnShutdown: true},
+		logger:      logger,
+		restClient:  client,
+		retryDelay:  time.Millisecond,
+		fileManager: NewFileManager(t.TempDir()),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := recorder.Run(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	if !cancelled {
+		t.Error("Expected all orders to be cancelled on shutdown")
+	}
+}
+
+func TestMarketRecorderValidateAppKeyFlagsDelayedKey(t *testing.T) {
+	client := newTestRESTClient(t, developerAppKeysHandler(t))
+	client.appKey = "delayed-key"
+
+	recorder := &MarketRecorder{
+		config:     &Config{ValidateAppKey: true},
+		logger:     zerolog.New(zerolog.NewTestWriter(t)),
+		restClient: client,
+	}
+
+	recorder.validateAppKey(context.Background())
+
+	if !recorder.config.AppKeyDelayed {
+		t.Error("Expected delayed app key to be flagged on the config")
+	}
+}
+
+func TestMarketRecorderUnsubscribesSettledMarkets(t *testing.T) {
+	logger := zerolog.New(zerolog.NewTestWriter(t))
+	var sent strings.Builder
+
+	recorder := &MarketRecorder{
+		config:        &Config{MarketIDs: []string{"1.1", "1.2", "1.3"}},
+		logger:        logger,
+		streamClient:  NewStreamClient("app-key", "token", 500, logger, nil),
+		currentStream: &StreamConn{writer: bufio.NewWriter(&sent)},
+	}
+
+	recorder.unsubscribeSettled("1.2")
+
+	var sub struct {
+		Op           string `json:"op"`
+		MarketFilter struct {
+			MarketIds []string `json:"marketIds"`
+		} `json:"marketFilter"`
+	}
+	if err := json.Unmarshal([]byte(sent.String()), &sub); err != nil {
+		t.Fatalf("Failed to decode resubscription %q: %v", sent.String(), err)
+	}
+	if sub.Op != "marketSubscription" {
+		t.Errorf("Expected marketSubscription, got %s", sub.Op)
+	}
+	if got := strings.Join(sub.MarketFilter.MarketIds, ","); got != "1.1,1.3" {
+		t.Errorf("Expected settled market to be dropped, got %s", got)
+	}
+
+	// Reconnections keep the reduced filter
+	if got := strings.Join(recorder.subscriptionFilter().MarketIds, ","); got != "1.1,1.3" {
+		t.Errorf("Expected reconnect filter without settled market, got %s", got)
+	}
+}
+
+func TestMarketRecorderClearsSubscriptionWhenEveryMarketSettles(t *testing.T) {
+	logger := zerolog.New(zerolog.NewTestWriter(t))
+	var sent strings.Builder
+
+	recorder := &MarketRecorder{
+		config:        &Config{MarketIDs: []string{"1.1"}},
+		logger:        logger,
+		streamClient:  NewStreamClient("app-key", "token", 500, logger, nil),
+		currentStream: &StreamConn{writer: bufio.NewWriter(&sent)},
+		clk:           "stale-clk",
+	}
+
+	recorder.unsubscribeSettled("1.1")
+
+	var sub struct {
+		Op           string                     `json:"op"`
+		MarketFilter map[string]json.RawMessage `json:"marketFilter"`
+	}
+	if err := json.Unmarshal([]byte(sent.String()), &sub); err != nil {
+		t.Fatalf("Failed to decode resubscription %q: %v", sent.String(), err)
+	}
+	if sub.Op != "marketSubscription" {
+		t.Errorf("Expected marketSubscription, got %s", sub.Op)
+	}
+	if len(sub.MarketFilter) != 1 || string(sub.MarketFilter["marketIds"]) != "[]" {
+		t.Errorf("Expected a subscription naming no markets, got %s", sent.String())
+	}
+	if recorder.clk != "" {
+		t.Errorf("Expected the stored clock to be cleared, got %s", recorder.clk)
+	}
+}
+
+func TestMarketRecorderRecoverMarketFiles(t *testing.T) {
+	tempDir := t.TempDir()
+	definition := `{"op":"mcm","mc":[{"marketDefinition":{"eventId":"123","openDate":"2025-10-01T10:00:00Z","status":"OPEN"}}]}` + "\n"
+	for _, id := range []string{"1.1", "1.2", "1.3"} {
+		if err := os.WriteFile(filepath.Join(tempDir, id), []byte(definition), 0644); err != nil {
+			t.Fatalf("Failed to write market file: %v", err)
+		}
+	}
+
+	client := newTestRESTClient(t, func(method string, params map[string]interface{}) interface{} {
+		if method != "listMarketBook" {
+			t.Errorf("Unexpected method %s", method)
+		}
+		return []map[string]interface{}{
+			{"marketId": "1.1", "status": "OPEN"},
+			{"marketId": "1.2", "status": "CLOSED"},
+		}
+	})
+
+	recorder := &MarketRecorder{
+		config:      &Config{MarketIDs: []string{"1.1"}},
+		logger:      zerolog.New(zerolog.NewTestWriter(t)),
+		restClient:  client,
+		fileManager: NewFileManager(tempDir),
+	}
+
+	writers, files, closeFn, err := recorder.openWriters()
+	if err != nil {
+		t.Fatalf("openWriters failed: %v", err)
+	}
+	defer closeFn()
+
+	statuses := make(map[string]string)
+	recorder.recoverMarketFiles(context.Background(), writers, files, statuses)
+
+	writer, ok := writers["1.1"]
+	if !ok {
+		t.Fatal("Expected open market to be resumed")
+	}
+	writer.WriteString("next\n")
+	writer.Flush()
+
+	content, _ := os.ReadFile(filepath.Join(tempDir, "1.1"))
+	if !strings.HasPrefix(string(content), definition) || !strings.HasSuffix(string(content), "next\n") {
+		t.Errorf("Expected resumed file to keep earlier data, got %q", content)
+	}
+
+	// 1.2 settled and 1.3 is no longer known to Betfair, so both are finalized
+	for _, id := range []string{"1.2", "1.3"} {
+		if _, exists := writers[id]; exists {
+			t.Errorf("Expected settled market %s not to be resumed", id)
+		}
+		if _, err := os.Stat(filepath.Join(tempDir, id+".bz2")); err != nil {
+			t.Errorf("Expected settled market %s to be compressed: %v", id, err)
+		}
+		if statuses[id] != "CLOSED" {
+			t.Errorf("Expected %s to be marked closed, got %q", id, statuses[id])
+		}
+	}
+	if statuses["1.1"] != "OPEN" {
+		t.Errorf("Expected 1.1 status to be restored, got %q", statuses["1.1"])
+	}
+}
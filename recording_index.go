@@ -0,0 +1,186 @@
+package betfair
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// RecordingIndexName is the file in the output directory listing every archived market, one JSON
+// object per line, so tooling can find recordings without listing S3
+const RecordingIndexName = "recordings.jsonl"
+
+// recordingIndexMu serialises appends from concurrent market finalizers
+var recordingIndexMu sync.Mutex
+
+// RecordingEntry describes one archived market recording
+type RecordingEntry struct {
+	MarketID   string    `json:"marketId"`
+	EventID    string    `json:"eventId"`
+	Venue      string    `json:"venue,omitempty"`
+	MarketTime time.Time `json:"marketTime"`
+	Status     string    `json:"status"`
+	Incomplete bool      `json:"incomplete,omitempty"`
+	LocalPath  string    `json:"localPath"`
+	S3Key      string    `json:"s3Key,omitempty"`
+	Messages   int64     `json:"messages"`
+	FirstPT    time.Time `json:"firstPt"`
+	LastPT     time.Time `json:"lastPt"`
+	RecordedAt time.Time `json:"recordedAt"`
+	// LayoutVersion is the schema LocalPath and S3Key follow; entries written before it was
+	// recorded are LayoutV1
+	LayoutVersion LayoutVersion `json:"layoutVersion,omitempty"`
+}
+
+// RecordingQuery selects index entries; zero fields match everything. From and To bound the
+// market start time, From inclusive and To exclusive.
+type RecordingQuery struct {
+	From    time.Time
+	To      time.Time
+	EventID string
+	Venue   string
+	Status  string
+}
+
+func (q RecordingQuery) matches(entry RecordingEntry) bool {
+	if !q.From.IsZero() && entry.MarketTime.Before(q.From) {
+		return false
+	}
+	if !q.To.IsZero() && !entry.MarketTime.Before(q.To) {
+		return false
+	}
+	if q.EventID != "" && entry.EventID != q.EventID {
+		return false
+	}
+	if q.Venue != "" && entry.Venue != q.Venue {
+		return false
+	}
+	return q.Status == "" || entry.Status == q.Status
+}
+
+// AppendRecording adds an archived market to the index at path
+func AppendRecording(path string, entry RecordingEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("encode recording entry: %w", err)
+	}
+
+	recordingIndexMu.Lock()
+	defer recordingIndexMu.Unlock()
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("open recording index: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("write recording index: %w", err)
+	}
+	return nil
+}
+
+// QueryRecordings returns the index entries at path matching query, in the order they were
+// archived. A missing index holds no recordings.
+func QueryRecordings(path string, query RecordingQuery) ([]RecordingEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("open recording index: %w", err)
+	}
+	defer file.Close()
+
+	var entries []RecordingEntry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry RecordingEntry
+		// A line cut short by a crash is skipped rather than failing the whole query
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		if query.matches(entry) {
+			entries = append(entries, entry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read recording index: %w", err)
+	}
+	return entries, nil
+}
+
+// marketCounts tracks the messages recorded for a market until it is archived
+type marketCounts struct {
+	messages int64
+	firstPT  time.Time
+	lastPT   time.Time
+}
+
+// countRecorded notes a change recorded for a market when the recording index is enabled
+func (r *MarketRecorder) countRecorded(marketID string, at time.Time) {
+	if !r.config.RecordingIndex {
+		return
+	}
+	counts, exists := r.recordedCounts[marketID]
+	if !exists {
+		if r.recordedCounts == nil {
+			r.recordedCounts = make(map[string]*marketCounts)
+		}
+		counts = &marketCounts{firstPT: at}
+		r.recordedCounts[marketID] = counts
+	}
+	counts.messages++
+	counts.lastPT = at
+}
+
+// takeCounts removes and returns a market's counts so they can be indexed by a finalizer
+func (r *MarketRecorder) takeCounts(marketID string) marketCounts {
+	counts, exists := r.recordedCounts[marketID]
+	if !exists {
+		return marketCounts{}
+	}
+	delete(r.recordedCounts, marketID)
+	return *counts
+}
+
+// indexRecording adds an archived market to the recording index
+func (r *MarketRecorder) indexRecording(marketID string, payload []byte, incomplete bool, localPath, s3Key string, counts marketCounts) {
+	entry := RecordingEntry{
+		MarketID:   marketID,
+		Incomplete: incomplete,
+		LocalPath:  localPath,
+		S3Key:      s3Key,
+		Messages:   counts.messages,
+		FirstPT:    counts.firstPT,
+		LastPT:     counts.lastPT,
+		RecordedAt: time.Now().UTC(),
+
+		LayoutVersion: r.config.Partition.Layout.orDefault(),
+	}
+
+	var message struct {
+		MarketChanges []struct {
+			MarketDefinition *MarketDefinition `json:"marketDefinition"`
+		} `json:"mc"`
+	}
+	if err := json.Unmarshal(payload, &message); err == nil && len(message.MarketChanges) > 0 {
+		if definition := message.MarketChanges[0].MarketDefinition; definition != nil {
+			entry.EventID = definition.EventID
+			entry.Venue = definition.Venue
+			entry.MarketTime = definition.MarketTime
+			entry.Status = definition.Status
+		}
+	}
+
+	path := r.fileManager.RecordingIndexPath()
+	if err := AppendRecording(path, entry); err != nil {
+		r.logger.Error().Err(err).Str("market_id", marketID).Msg("failed to update recording index")
+	}
+}
@@ -0,0 +1,114 @@
+package betfair
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func TestQueryRecordings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), RecordingIndexName)
+	day := time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	entries := []RecordingEntry{
+		{MarketID: "1.1", EventID: "10", Venue: "Flemington", MarketTime: day.Add(12 * time.Hour), Status: "CLOSED"},
+		{MarketID: "1.2", EventID: "11", Venue: "Randwick", MarketTime: day.Add(13 * time.Hour), Status: "CLOSED"},
+		{MarketID: "1.3", EventID: "12", Venue: "Flemington", MarketTime: day.Add(36 * time.Hour), Status: "SUSPENDED", Incomplete: true},
+	}
+	for _, entry := range entries {
+		if err := AppendRecording(path, entry); err != nil {
+			t.Fatalf("AppendRecording failed: %v", err)
+		}
+	}
+
+	// A line cut short by a crash must not hide the rest of the index
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatalf("Failed to open index: %v", err)
+	}
+	file.WriteString(`{"marketId":"1.4","eventId`)
+	file.Close()
+
+	got, err := QueryRecordings(path, RecordingQuery{From: day, To: day.Add(24 * time.Hour)})
+	if err != nil {
+		t.Fatalf("QueryRecordings failed: %v", err)
+	}
+	if len(got) != 2 || got[0].MarketID != "1.1" || got[1].MarketID != "1.2" {
+		t.Fatalf("Expected the two markets starting that day, got %+v", got)
+	}
+
+	got, err = QueryRecordings(path, RecordingQuery{Venue: "Flemington", Status: "SUSPENDED"})
+	if err != nil {
+		t.Fatalf("QueryRecordings failed: %v", err)
+	}
+	if len(got) != 1 || got[0].MarketID != "1.3" || !got[0].Incomplete {
+		t.Fatalf("Expected the incomplete Flemington market, got %+v", got)
+	}
+
+	got, err = QueryRecordings(filepath.Join(t.TempDir(), RecordingIndexName), RecordingQuery{})
+	if err != nil || len(got) != 0 {
+		t.Fatalf("Expected missing index to hold no recordings, got %v, %v", got, err)
+	}
+}
+
+func TestMarketRecorderIndexesSettledMarket(t *testing.T) {
+	tempDir := t.TempDir()
+	recorder := &MarketRecorder{
+		config:           &Config{EnrichmentMode: EnrichmentOff, RecordingIndex: true},
+		logger:           zerolog.New(zerolog.NewTestWriter(t)),
+		fileManager:      NewFileManager(tempDir),
+		marketCatalogues: make(map[string]*MarketCatalogue),
+	}
+
+	definition := `"eventId":"99","venue":"Flemington","marketTime":"2025-10-01T12:00:00.000Z","openDate":"2025-10-01T11:00:00.000Z"`
+	messages := []string{
+		`{"op":"mcm","pt":1759316400000,"mc":[{"id":"1.1","img":true,"marketDefinition":{"status":"OPEN",` + definition + `}}]}`,
+		`{"op":"mcm","pt":1759316401000,"mc":[{"id":"1.1","rc":[{"id":7,"ltp":3.5}]}]}`,
+		`{"op":"mcm","pt":1759316402000,"mc":[{"id":"1.1","marketDefinition":{"status":"CLOSED",` + definition + `}}]}`,
+	}
+	stream := &StreamConn{reader: bufio.NewReader(strings.NewReader(strings.Join(messages, "\n") + "\n"))}
+	writers := make(map[string]*bufio.Writer)
+	files := make(map[string]*os.File)
+	statuses := make(map[string]string)
+
+	for range messages {
+		if err := recorder.readMessage(context.Background(), stream, writers, files, statuses); err != nil {
+			t.Fatalf("readMessage failed: %v", err)
+		}
+	}
+	recorder.stopMarketWriters()
+
+	got, err := QueryRecordings(recorder.fileManager.RecordingIndexPath(), RecordingQuery{})
+	if err != nil {
+		t.Fatalf("QueryRecordings failed: %v", err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("Expected one indexed market, got %+v", got)
+	}
+
+	entry := got[0]
+	if entry.MarketID != "1.1" || entry.EventID != "99" || entry.Venue != "Flemington" || entry.Status != "CLOSED" {
+		t.Errorf("Unexpected market details: %+v", entry)
+	}
+	if !entry.MarketTime.Equal(time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected market start time, got %v", entry.MarketTime)
+	}
+	if entry.Messages != 3 {
+		t.Errorf("Expected 3 recorded messages, got %d", entry.Messages)
+	}
+	if entry.FirstPT.UnixMilli() != 1759316400000 || entry.LastPT.UnixMilli() != 1759316402000 {
+		t.Errorf("Unexpected publish time range %v - %v", entry.FirstPT, entry.LastPT)
+	}
+	if entry.LocalPath != filepath.Join(tempDir, "1.1.bz2") {
+		t.Errorf("Expected local archive path, got %s", entry.LocalPath)
+	}
+	if _, err := os.Stat(entry.LocalPath); err != nil {
+		t.Errorf("Expected archive at indexed path: %v", err)
+	}
+}
@@ -0,0 +1,88 @@
+package betfair
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RecordingMode controls how much of each market change is written to the market file
+type RecordingMode string
+
+const (
+	// RecordingFull writes every market change as streamed
+	RecordingFull RecordingMode = "full"
+	// RecordingBSP keeps only market definitions and starting price projections, dropping ladder
+	// updates, for users who only need BSPs
+	RecordingBSP RecordingMode = "bsp"
+)
+
+// bspRunnerFields are the runner change fields kept in BSP mode: the runner ID and the near and
+// far projected starting prices
+var bspRunnerFields = []string{"id", "hc", "spn", "spf"}
+
+// ParseRecordingMode parses "full" or "bsp"
+func ParseRecordingMode(value string) (RecordingMode, error) {
+	mode := RecordingMode(strings.ToLower(strings.TrimSpace(value)))
+	switch mode {
+	case RecordingFull, RecordingBSP:
+		return mode, nil
+	case "bsp_only":
+		return RecordingBSP, nil
+	}
+	return "", fmt.Errorf("unknown recording mode %q", value)
+}
+
+func (c *Config) recordingMode() RecordingMode {
+	if c == nil || c.RecordingMode == "" {
+		return RecordingFull
+	}
+	return c.RecordingMode
+}
+
+// compactForBSP returns a copy of a market change holding only its definition and BSP projections.
+// It reports false when nothing worth recording is left.
+func compactForBSP(marketChange map[string]interface{}) (map[string]interface{}, bool) {
+	compact := make(map[string]interface{})
+	if id, ok := marketChange["id"]; ok {
+		compact["id"] = id
+	}
+	for _, key := range []string{"img", "con"} {
+		if value, ok := marketChange[key]; ok {
+			compact[key] = value
+		}
+	}
+
+	keep := false
+	if marketDef, ok := marketChange["marketDefinition"]; ok {
+		compact["marketDefinition"] = marketDef
+		keep = true
+	}
+
+	if runnerChanges, ok := marketChange["rc"].([]interface{}); ok {
+		var kept []interface{}
+		for _, raw := range runnerChanges {
+			runnerChange, ok := raw.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if _, hasNear := runnerChange["spn"]; !hasNear {
+				if _, hasFar := runnerChange["spf"]; !hasFar {
+					continue
+				}
+			}
+			runner := make(map[string]interface{}, len(bspRunnerFields))
+			for _, field := range bspRunnerFields {
+				if value, ok := runnerChange[field]; ok {
+					runner[field] = value
+				}
+			}
+			kept = append(kept, runner)
+		}
+		if len(kept) > 0 {
+			compact["rc"] = kept
+			keep = true
+		}
+	}
+
+	return compact, keep
+}
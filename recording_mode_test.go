@@ -0,0 +1,83 @@
+package betfair
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestParseRecordingMode(t *testing.T) {
+	for input, want := range map[string]RecordingMode{"full": RecordingFull, " BSP ": RecordingBSP, "bsp_only": RecordingBSP} {
+		got, err := ParseRecordingMode(input)
+		if err != nil || got != want {
+			t.Errorf("ParseRecordingMode(%q) = %q, %v; want %q", input, got, err, want)
+		}
+	}
+	if _, err := ParseRecordingMode("ladders"); err == nil {
+		t.Error("Expected unknown mode to fail")
+	}
+}
+
+func TestCompactForBSP(t *testing.T) {
+	var change map[string]interface{}
+	json.Unmarshal([]byte(`{"id":"1.1","rc":[{"id":7,"atb":[[3.5,10]],"ltp":3.5},{"id":8,"spn":4.2,"spb":[[4.2,5]],"trd":[[4.1,2]]}]}`), &change)
+
+	compact, keep := compactForBSP(change)
+	if !keep {
+		t.Fatal("Expected BSP projection to be kept")
+	}
+	data, _ := json.Marshal(compact)
+	if string(data) != `{"id":"1.1","rc":[{"id":8,"spn":4.2}]}` {
+		t.Errorf("Unexpected compact change %s", data)
+	}
+
+	json.Unmarshal([]byte(`{"id":"1.1","rc":[{"id":7,"atb":[[3.5,10]]}]}`), &change)
+	if _, keep := compactForBSP(change); keep {
+		t.Error("Expected ladder-only change to be dropped")
+	}
+}
+
+func TestMarketRecorderBSPMode(t *testing.T) {
+	tempDir := t.TempDir()
+	recorder := &MarketRecorder{
+		config:           &Config{EnrichmentMode: EnrichmentOff, RecordingMode: RecordingBSP},
+		logger:           zerolog.New(zerolog.NewTestWriter(t)),
+		fileManager:      NewFileManager(tempDir),
+		marketCatalogues: make(map[string]*MarketCatalogue),
+	}
+
+	messages := []string{
+		`{"op":"mcm","pt":1,"mc":[{"id":"1.1","img":true,"marketDefinition":{"status":"OPEN"},"rc":[{"id":7,"atb":[[3.5,10]]}]}]}`,
+		`{"op":"mcm","pt":2,"mc":[{"id":"1.1","rc":[{"id":7,"atb":[[3.6,10]],"ltp":3.6}]}]}`,
+		`{"op":"mcm","pt":3,"mc":[{"id":"1.1","rc":[{"id":7,"spf":3.9,"spl":[[1000,20]]}]}]}`,
+	}
+	stream := &StreamConn{reader: bufio.NewReader(strings.NewReader(strings.Join(messages, "\n") + "\n"))}
+	writers := make(map[string]*bufio.Writer)
+	files := make(map[string]*os.File)
+	for range messages {
+		if err := recorder.readMessage(context.Background(), stream, writers, files, make(map[string]string)); err != nil {
+			t.Fatalf("readMessage failed: %v", err)
+		}
+	}
+	recorder.stopMarketWriters()
+
+	data, err := os.ReadFile(recorder.fileManager.GetMarketFilePath("1.1"))
+	if err != nil {
+		t.Fatalf("read market file: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected definition and BSP projection only, got %d lines:\n%s", len(lines), data)
+	}
+	if strings.Contains(string(data), "atb") || strings.Contains(string(data), "spl") {
+		t.Errorf("Expected ladder updates to be dropped, got %s", data)
+	}
+	if !strings.Contains(lines[1], `"spf":3.9`) {
+		t.Errorf("Expected far projection to be recorded, got %s", lines[1])
+	}
+}
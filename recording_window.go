@@ -0,0 +1,66 @@
+package betfair
+
+import (
+	"time"
+)
+
+// settledMemory is how long a settled market's trailing changes are ignored. The stream stops
+// sending a closed market well within it.
+const settledMemory = time.Hour
+
+// trackMarketTime remembers a market's scheduled start from its definition
+func (r *MarketRecorder) trackMarketTime(marketID string, marketDef map[string]interface{}) {
+	raw, ok := marketDef["marketTime"].(string)
+	if !ok {
+		return
+	}
+	start, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return
+	}
+	if r.marketTimes == nil {
+		r.marketTimes = make(map[string]time.Time)
+	}
+	r.marketTimes[marketID] = start
+}
+
+// inRecordingWindow reports whether a change published at the given time falls inside the
+// configured window before the market's start. Markets with no known start are always recorded.
+func (r *MarketRecorder) inRecordingWindow(marketID string, at time.Time) bool {
+	if r.config.RecordBeforeStart <= 0 {
+		return true
+	}
+	start, ok := r.marketTimes[marketID]
+	if !ok {
+		return true
+	}
+	return !at.Before(start.Add(-r.config.RecordBeforeStart))
+}
+
+// holdOutsideWindow drops a change of a market that is not being recorded yet. Deltas from then on
+// apply to state its file would not have, so once the window opens it waits for a fresh image.
+func (r *MarketRecorder) holdOutsideWindow(marketID string) {
+	if r.skipped == nil {
+		r.skipped = make(map[string]bool)
+	}
+	r.skipped[marketID] = true
+}
+
+// forgetMarketWindow drops window state once a market is settled, remembering it for a while when
+// StopAfterSettlement is on so its trailing changes do not open a new file
+func (r *MarketRecorder) forgetMarketWindow(marketID string, now time.Time) {
+	delete(r.marketTimes, marketID)
+	delete(r.skipped, marketID)
+	if !r.config.StopAfterSettlement {
+		return
+	}
+	for id, settledAt := range r.settledSeen {
+		if now.Sub(settledAt) > settledMemory {
+			delete(r.settledSeen, id)
+		}
+	}
+	if r.settledSeen == nil {
+		r.settledSeen = make(map[string]time.Time)
+	}
+	r.settledSeen[marketID] = now
+}
@@ -0,0 +1,97 @@
+package betfair
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func TestMarketRecorderRecordingWindow(t *testing.T) {
+	tempDir := t.TempDir()
+	logger := zerolog.New(zerolog.NewTestWriter(t))
+	var sent strings.Builder
+	recorder := &MarketRecorder{
+		config:           &Config{EnrichmentMode: EnrichmentOff, RecordBeforeStart: 30 * time.Minute, StopAfterSettlement: true, MarketIDs: []string{"1.1"}},
+		logger:           logger,
+		fileManager:      NewFileManager(tempDir),
+		marketCatalogues: make(map[string]*MarketCatalogue),
+		streamClient:     NewStreamClient("app-key", "token", 500, logger, nil),
+		currentStream:    &StreamConn{writer: bufio.NewWriter(&sent)},
+	}
+
+	start := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+	ms := func(offset time.Duration) int64 { return start.Add(offset).UnixMilli() }
+
+	messages := []string{
+		`{"op":"mcm","pt":` + strconv.FormatInt(ms(-60*time.Minute), 10) + `,"mc":[{"id":"1.1","img":true,"marketDefinition":{"status":"OPEN","marketTime":"2025-10-01T12:00:00.000Z"}}]}`,
+		`{"op":"mcm","pt":` + strconv.FormatInt(ms(-45*time.Minute), 10) + `,"mc":[{"id":"1.1","rc":[{"id":7,"ltp":3.5}]}]}`,
+		`{"op":"mcm","pt":` + strconv.FormatInt(ms(-20*time.Minute), 10) + `,"mc":[{"id":"1.1","rc":[{"id":7,"ltp":3.4}]}]}`,
+		`{"op":"mcm","pt":` + strconv.FormatInt(ms(-19*time.Minute), 10) + `,"mc":[{"id":"1.1","img":true,"marketDefinition":{"status":"OPEN","marketTime":"2025-10-01T12:00:00.000Z"},"rc":[{"id":7,"ltp":3.3}]}]}`,
+	}
+	stream := &StreamConn{reader: bufio.NewReader(strings.NewReader(strings.Join(messages, "\n") + "\n"))}
+	writers := make(map[string]*bufio.Writer)
+	files := make(map[string]*os.File)
+	statuses := make(map[string]string)
+
+	for i := 0; i < 2; i++ {
+		if err := recorder.readMessage(context.Background(), stream, writers, files, statuses); err != nil {
+			t.Fatalf("readMessage failed: %v", err)
+		}
+	}
+	if len(writers) != 0 {
+		t.Fatal("Expected no recording before the window opens")
+	}
+
+	if err := recorder.readMessage(context.Background(), stream, writers, files, statuses); err != nil {
+		t.Fatalf("readMessage failed: %v", err)
+	}
+	if len(writers) != 0 || !recorder.imagesRequested || sent.Len() == 0 {
+		t.Fatal("Expected a delta entering the window to wait for a requested image")
+	}
+
+	if err := recorder.readMessage(context.Background(), stream, writers, files, statuses); err != nil {
+		t.Fatalf("readMessage failed: %v", err)
+	}
+	recorder.stopMarketWriters()
+
+	data, err := os.ReadFile(recorder.fileManager.GetMarketFilePath("1.1"))
+	if err != nil {
+		t.Fatalf("Expected market file once the window opened: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("Expected only the fresh image, got %d lines", len(lines))
+	}
+	if !strings.Contains(lines[0], `"img":true`) || !strings.Contains(lines[0], `"ltp":3.3`) {
+		t.Errorf("Expected the file to start from the fresh image, got %s", lines[0])
+	}
+}
+
+func TestMarketRecorderStopsAfterSettlement(t *testing.T) {
+	now := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+	recorder := &MarketRecorder{config: &Config{StopAfterSettlement: true}, marketTimes: map[string]time.Time{"1.1": now}}
+	recorder.forgetMarketWindow("1.1", now)
+	if _, ok := recorder.settledSeen["1.1"]; !ok {
+		t.Error("Expected settled market to be ignored from now on")
+	}
+	if _, ok := recorder.marketTimes["1.1"]; ok {
+		t.Error("Expected the settled market's start time to be dropped")
+	}
+
+	recorder.forgetMarketWindow("1.2", now.Add(settledMemory+time.Minute))
+	if _, ok := recorder.settledSeen["1.1"]; ok {
+		t.Error("Expected a market settled long ago to be pruned")
+	}
+
+	recorder = &MarketRecorder{config: &Config{}}
+	recorder.forgetMarketWindow("1.1", now)
+	if _, ok := recorder.settledSeen["1.1"]; ok {
+		t.Error("Expected settled market to stay recordable when StopAfterSettlement is off")
+	}
+}
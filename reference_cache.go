@@ -0,0 +1,103 @@
+package betfair
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// referenceCache holds reference-data responses (event types, competitions, venues, ...) for a fixed TTL
+type referenceCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]referenceCacheEntry
+	now     func() time.Time
+}
+
+type referenceCacheEntry struct {
+	result  interface{}
+	expires time.Time
+}
+
+func newReferenceCache(ttl time.Duration) *referenceCache {
+	return &referenceCache{
+		ttl:     ttl,
+		entries: make(map[string]referenceCacheEntry),
+		now:     time.Now,
+	}
+}
+
+func (rc *referenceCache) get(key string) (interface{}, bool) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	entry, ok := rc.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if rc.now().After(entry.expires) {
+		delete(rc.entries, key)
+		return nil, false
+	}
+	return entry.result, true
+}
+
+func (rc *referenceCache) set(key string, result interface{}) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	rc.entries[key] = referenceCacheEntry{
+		result:  result,
+		expires: rc.now().Add(rc.ttl),
+	}
+}
+
+func (rc *referenceCache) clear() {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	rc.entries = make(map[string]referenceCacheEntry)
+}
+
+// EnableReferenceCache caches listEventTypes, listCompetitions, listEvents, listMarketTypes,
+// listCountries and listVenues responses in memory for ttl. A non-positive ttl disables caching.
+func (c *RESTClient) EnableReferenceCache(ttl time.Duration) {
+	if ttl <= 0 {
+		c.refCache = nil
+		return
+	}
+	c.refCache = newReferenceCache(ttl)
+}
+
+// ClearReferenceCache drops all cached reference-data responses
+func (c *RESTClient) ClearReferenceCache() {
+	if c.refCache != nil {
+		c.refCache.clear()
+	}
+}
+
+func (c *RESTClient) makeReferenceDataRequest(ctx context.Context, method string, params interface{}) (*JSONRPCResponse, error) {
+	if c.refCache == nil {
+		return c.makeBettingAPIRequest(ctx, method, params)
+	}
+
+	keyBytes, err := json.Marshal(params)
+	if err != nil {
+		return nil, fmt.Errorf("marshal cache key: %w", err)
+	}
+	key := method + ":" + string(keyBytes)
+
+	if result, ok := c.refCache.get(key); ok {
+		return &JSONRPCResponse{JSONRPC: "2.0", Result: result}, nil
+	}
+
+	resp, err := c.makeBettingAPIRequest(ctx, method, params)
+	if err != nil {
+		return nil, err
+	}
+
+	c.refCache.set(key, resp.Result)
+	return resp, nil
+}
@@ -0,0 +1,90 @@
+package betfair
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestReferenceCacheServesRepeatedRequests(t *testing.T) {
+	calls := 0
+	client := newTestRESTClient(t, func(method string, params map[string]interface{}) interface{} {
+		calls++
+		return []map[string]interface{}{
+			{"eventType": map[string]interface{}{"id": "4339", "name": "Greyhound Racing"}, "marketCount": 10},
+		}
+	})
+	client.EnableReferenceCache(time.Minute)
+
+	filter := *CreateMarketFilter().WithMarketCountries([]string{"AU"})
+	for i := 0; i < 3; i++ {
+		results, err := client.ListEventTypes(context.Background(), filter)
+		if err != nil {
+			t.Fatalf("ListEventTypes failed: %v", err)
+		}
+		if len(results) != 1 || results[0].EventType.ID != "4339" {
+			t.Fatalf("Unexpected event types: %+v", results)
+		}
+	}
+
+	if calls != 1 {
+		t.Errorf("Expected 1 API call with caching enabled, got %d", calls)
+	}
+
+	// A different filter is cached separately
+	otherFilter := *CreateMarketFilter().WithMarketCountries([]string{"GB"})
+	if _, err := client.ListEventTypes(context.Background(), otherFilter); err != nil {
+		t.Fatalf("ListEventTypes failed: %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("Expected separate cache entry per filter, got %d calls", calls)
+	}
+
+	client.ClearReferenceCache()
+	if _, err := client.ListEventTypes(context.Background(), filter); err != nil {
+		t.Fatalf("ListEventTypes failed: %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("Expected cache miss after clear, got %d calls", calls)
+	}
+}
+
+func TestReferenceCacheExpiry(t *testing.T) {
+	calls := 0
+	client := newTestRESTClient(t, func(method string, params map[string]interface{}) interface{} {
+		calls++
+		return []map[string]interface{}{{"venue": "Sandown Park", "marketCount": 2}}
+	})
+	client.EnableReferenceCache(time.Minute)
+
+	now := time.Now()
+	client.refCache.now = func() time.Time { return now }
+
+	filter := *CreateMarketFilter()
+	client.ListVenues(context.Background(), filter)
+	client.ListVenues(context.Background(), filter)
+	if calls != 1 {
+		t.Fatalf("Expected 1 call before expiry, got %d", calls)
+	}
+
+	now = now.Add(2 * time.Minute)
+	client.ListVenues(context.Background(), filter)
+	if calls != 2 {
+		t.Errorf("Expected refetch after TTL expiry, got %d calls", calls)
+	}
+}
+
+func TestReferenceCacheDisabledByDefault(t *testing.T) {
+	calls := 0
+	client := newTestRESTClient(t, func(method string, params map[string]interface{}) interface{} {
+		calls++
+		return []interface{}{}
+	})
+
+	filter := *CreateMarketFilter()
+	client.ListMarketTypes(context.Background(), filter)
+	client.ListMarketTypes(context.Background(), filter)
+	if calls != 2 {
+		t.Errorf("Expected no caching by default, got %d calls", calls)
+	}
+}
@@ -0,0 +1,161 @@
+package betfair
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+)
+
+var (
+	errReloadWithProfiles   = errors.New("filter reload is not supported with recording profiles")
+	errReloadNeedsDiscovery = errors.New("filter reload to an event type with a market start window needs discovery, which only starts without MARKET_IDS")
+)
+
+// FilterUpdate is a new market selection applied while the recorder runs
+type FilterUpdate struct {
+	MarketIDs   []string
+	EventTypeID string
+}
+
+// FilterUpdateFromEnv reads MARKET_IDS and EVENT_TYPE_ID for a runtime reload
+func FilterUpdateFromEnv() FilterUpdate {
+	return NewConfig().FilterUpdate()
+}
+
+// FilterUpdate reads MARKET_IDS and EVENT_TYPE_ID again for a runtime reload, with the
+// configuration's overrides still taking precedence
+func (c *Config) FilterUpdate() FilterUpdate {
+	return FilterUpdate{
+		MarketIDs:   splitAndClean(c.getenv("MARKET_IDS")),
+		EventTypeID: strings.TrimSpace(c.getenv("EVENT_TYPE_ID")),
+	}
+}
+
+// ReloadFilter replaces the market selection and resubscribes on the live connection with the
+// stored clocks, so markets that stay selected continue without a fresh image. Markets that drop
+// out of the filter are finalized as incomplete by the stream loop.
+func (r *MarketRecorder) ReloadFilter(ctx context.Context, update FilterUpdate) error {
+	if len(r.profiles) > 0 {
+		return errReloadWithProfiles
+	}
+	if len(update.MarketIDs) == 0 && update.EventTypeID == "" {
+		return errors.New("filter reload needs market IDs or an event type")
+	}
+	// The stream cannot apply a start window, so only discovered market IDs may honour it
+	if len(update.MarketIDs) == 0 && r.discoverer == nil && r.config.MarketStartWindow.IsSet() {
+		return errReloadNeedsDiscovery
+	}
+
+	r.streamMu.Lock()
+	r.config.MarketIDs = update.MarketIDs
+	r.config.EventTypeID = update.EventTypeID
+	r.filterReloaded = true
+	filter := r.config.GetMarketFilter()
+	r.streamMu.Unlock()
+
+	if r.discoverer != nil && len(update.MarketIDs) == 0 {
+		r.discoverer.SetFilter(filter)
+		if added, err := r.discoverer.Discover(ctx); err != nil {
+			r.logger.Error().Err(err).Msg("market discovery after filter reload failed")
+		} else {
+			r.logger.Info().Strs("market_ids", added).Msg("discovered markets")
+		}
+	}
+
+	r.logger.Info().Strs("market_ids", update.MarketIDs).Str("event_type_id", update.EventTypeID).Msg("reloaded market filter")
+	r.resubscribeKeepingClocks(r.subscriptionFilter())
+	return nil
+}
+
+// ReloadOnSignal reloads the market filter each time the process receives SIGHUP, until ctx is
+// done. update is called on every signal for the new selection, such as after re-reading a .env file.
+func (r *MarketRecorder) ReloadOnSignal(ctx context.Context, update func() FilterUpdate) {
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	go func() {
+		defer signal.Stop(reload)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-reload:
+				if err := r.ReloadFilter(ctx, update()); err != nil {
+					r.logger.Error().Err(err).Msg("failed to reload market filter")
+				}
+			}
+		}
+	}()
+}
+
+// resubscribeKeepingClocks replaces the live subscription, sending the stored clocks
+func (r *MarketRecorder) resubscribeKeepingClocks(filter MarketFilter) {
+	r.streamMu.Lock()
+	defer r.streamMu.Unlock()
+
+	if r.currentStream == nil {
+		return
+	}
+	if err := r.streamClient.ResubscribeFrom(r.currentStream, filter, r.initialClk, r.clk); err != nil {
+		r.logger.Error().Err(err).Msg("failed to resubscribe")
+	}
+}
+
+// finalizeDroppedMarkets finalizes the markets that a filter reload left out, once per reload.
+// It runs on the stream loop, which owns the market writers.
+func (r *MarketRecorder) finalizeDroppedMarkets(ctx context.Context, writers map[string]*bufio.Writer, files map[string]*os.File) {
+	r.streamMu.Lock()
+	reloaded := r.filterReloaded
+	r.filterReloaded = false
+	r.streamMu.Unlock()
+	if !reloaded {
+		return
+	}
+
+	filter := r.subscriptionFilter()
+	for marketID := range writers {
+		if r.inSubscription(marketID, filter) {
+			continue
+		}
+		r.logger.Info().Str("market_id", marketID).Msg("market dropped from filter; finalizing incomplete recording")
+		r.ensureMarketWriter(marketID, writers, files)
+		r.finalizeIncomplete(ctx, marketID, writers, files)
+	}
+}
+
+// inSubscription reports whether a recorded market is still selected by filter. Only market IDs
+// and event types are compared; markets whose event type is not known yet are kept.
+func (r *MarketRecorder) inSubscription(marketID string, filter MarketFilter) bool {
+	if len(filter.MarketIds) > 0 {
+		return slices.Contains(filter.MarketIds, marketID)
+	}
+	eventType, known := r.marketEventTypes[marketID]
+	if !known || len(filter.EventTypeIds) == 0 {
+		return true
+	}
+	return slices.Contains(filter.EventTypeIds, eventType)
+}
+
+// trackEventType remembers a market's event type from its definition
+func (r *MarketRecorder) trackEventType(marketID string, marketDef map[string]interface{}) {
+	eventType, ok := marketDef["eventTypeId"].(string)
+	if !ok {
+		return
+	}
+	if r.marketEventTypes == nil {
+		r.marketEventTypes = make(map[string]string)
+	}
+	r.marketEventTypes[marketID] = eventType
+}
+
+// discoveryPaused stops discovery from overriding an explicit market list or filling a full disk
+func (r *MarketRecorder) discoveryPaused() bool {
+	r.streamMu.Lock()
+	explicit := len(r.config.MarketIDs) > 0
+	r.streamMu.Unlock()
+	return explicit || r.diskGuard.Exceeded()
+}
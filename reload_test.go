@@ -0,0 +1,195 @@
+package betfair
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func TestMarketRecorderReloadFilter(t *testing.T) {
+	logger := zerolog.New(zerolog.NewTestWriter(t))
+	var sent strings.Builder
+
+	recorder := &MarketRecorder{
+		config:        &Config{MarketIDs: []string{"1.1"}},
+		logger:        logger,
+		streamClient:  NewStreamClient("app-key", "token", 500, logger, nil),
+		currentStream: &StreamConn{writer: bufio.NewWriter(&sent)},
+		initialClk:    "abc",
+		clk:           "def",
+	}
+
+	if err := recorder.ReloadFilter(context.Background(), FilterUpdate{}); err == nil {
+		t.Error("Expected empty filter to be rejected")
+	}
+
+	if err := recorder.ReloadFilter(context.Background(), FilterUpdate{EventTypeID: "4339"}); err != nil {
+		t.Fatalf("ReloadFilter failed: %v", err)
+	}
+
+	var sub struct {
+		InitialClk   string `json:"initialClk"`
+		Clk          string `json:"clk"`
+		MarketFilter struct {
+			MarketIds    []string `json:"marketIds"`
+			EventTypeIds []string `json:"eventTypeIds"`
+		} `json:"marketFilter"`
+	}
+	if err := json.Unmarshal([]byte(sent.String()), &sub); err != nil {
+		t.Fatalf("Failed to decode resubscription %q: %v", sent.String(), err)
+	}
+	if len(sub.MarketFilter.MarketIds) != 0 || strings.Join(sub.MarketFilter.EventTypeIds, ",") != "4339" {
+		t.Errorf("Expected event type subscription, got %+v", sub.MarketFilter)
+	}
+	if sub.InitialClk != "abc" || sub.Clk != "def" {
+		t.Errorf("Expected the stored clocks to be sent, got %q and %q", sub.InitialClk, sub.Clk)
+	}
+	if !recorder.filterReloaded {
+		t.Error("Expected the reload to be flagged for the stream loop")
+	}
+	if len(recorder.config.MarketIDs) != 0 || recorder.config.EventTypeID != "4339" {
+		t.Errorf("Expected config to hold the new filter, got %+v", recorder.config)
+	}
+
+	recorder.profiles = []*MarketRecorder{{}}
+	if err := recorder.ReloadFilter(context.Background(), FilterUpdate{EventTypeID: "7"}); !errors.Is(err, errReloadWithProfiles) {
+		t.Errorf("Expected profiles to reject reload, got %v", err)
+	}
+}
+
+func TestReloadFilterFinalizesDroppedMarkets(t *testing.T) {
+	tempDir := t.TempDir()
+	recorder := &MarketRecorder{
+		config:           &Config{EnrichmentMode: EnrichmentOff, MarketIDs: []string{"1.1", "1.2"}},
+		logger:           zerolog.New(zerolog.NewTestWriter(t)),
+		fileManager:      NewFileManager(tempDir),
+		marketCatalogues: make(map[string]*MarketCatalogue),
+	}
+
+	message := `{"op":"mcm","pt":1,"mc":[{"id":"1.1","img":true,"marketDefinition":{"status":"OPEN","eventId":"9","eventTypeId":"7","openDate":"2025-10-01T12:00:00.000Z"}},{"id":"1.2","img":true,"marketDefinition":{"status":"OPEN","eventId":"9","eventTypeId":"7","openDate":"2025-10-01T12:00:00.000Z"}}]}`
+	heartbeat := `{"op":"mcm","pt":2,"ct":"HEARTBEAT"}`
+	stream := &StreamConn{reader: bufio.NewReader(strings.NewReader(message + "\n" + heartbeat + "\n"))}
+	writers := make(map[string]*bufio.Writer)
+	files := make(map[string]*os.File)
+
+	if err := recorder.readMessage(context.Background(), stream, writers, files, make(map[string]string)); err != nil {
+		t.Fatalf("readMessage failed: %v", err)
+	}
+	if err := recorder.ReloadFilter(context.Background(), FilterUpdate{MarketIDs: []string{"1.2"}}); err != nil {
+		t.Fatalf("ReloadFilter failed: %v", err)
+	}
+	if err := recorder.readMessage(context.Background(), stream, writers, files, make(map[string]string)); err != nil {
+		t.Fatalf("readMessage failed: %v", err)
+	}
+	recorder.settlements.Wait()
+
+	if _, ok := writers["1.1"]; ok {
+		t.Error("Expected the dropped market's writer to be closed")
+	}
+	if _, ok := writers["1.2"]; !ok {
+		t.Error("Expected the market still in the filter to keep recording")
+	}
+	if _, err := os.Stat(recorder.fileManager.GetCompressedFilePath("1.1" + IncompleteSuffix)); err != nil {
+		t.Errorf("Expected the dropped market to be archived as incomplete: %v", err)
+	}
+	recorder.stopMarketWriters()
+}
+
+func TestReloadFilterDropsDiscoveredMarketsOfOldEventType(t *testing.T) {
+	logger := zerolog.New(zerolog.NewTestWriter(t))
+	client := newTestRESTClient(t, func(method string, params map[string]interface{}) interface{} {
+		eventTypes := params["filter"].(map[string]interface{})["eventTypeIds"].([]interface{})
+		if eventTypes[0] == "7" {
+			return []map[string]interface{}{{"marketId": "1.7", "eventType": map[string]interface{}{"id": "7"}}}
+		}
+		return []map[string]interface{}{
+			{"marketId": "1.1", "eventType": map[string]interface{}{"id": "4339"}},
+			{"marketId": "1.2", "eventType": map[string]interface{}{"id": "4339"}},
+		}
+	})
+
+	var sent strings.Builder
+	cfg := &Config{EventTypeID: "4339", CountryCode: "AU"}
+	recorder := &MarketRecorder{
+		config:        cfg,
+		logger:        logger,
+		discoverer:    NewMarketDiscoverer(client, cfg.GetMarketFilter(), time.Minute, time.Hour, logger),
+		streamClient:  NewStreamClient("app-key", "token", 500, logger, nil),
+		currentStream: &StreamConn{writer: bufio.NewWriter(&sent)},
+	}
+	if _, err := recorder.discoverer.Discover(context.Background()); err != nil {
+		t.Fatalf("Discover failed: %v", err)
+	}
+
+	if err := recorder.ReloadFilter(context.Background(), FilterUpdate{EventTypeID: "7"}); err != nil {
+		t.Fatalf("ReloadFilter failed: %v", err)
+	}
+
+	var sub struct {
+		MarketFilter struct {
+			MarketIds []string `json:"marketIds"`
+		} `json:"marketFilter"`
+	}
+	if err := json.Unmarshal([]byte(sent.String()), &sub); err != nil {
+		t.Fatalf("Failed to decode resubscription %q: %v", sent.String(), err)
+	}
+	if strings.Join(sub.MarketFilter.MarketIds, ",") != "1.7" {
+		t.Errorf("Expected only the new event type's market, got %v", sub.MarketFilter.MarketIds)
+	}
+	if ids := recorder.discoverer.MarketIDs(); strings.Join(ids, ",") != "1.7" {
+		t.Errorf("Expected the old event type's markets to be dropped from discovery, got %v", ids)
+	}
+	if recorder.inSubscription("1.1", recorder.subscriptionFilter()) {
+		t.Error("Expected the old event type's market to be finalized as dropped")
+	}
+}
+
+func TestFilterUpdateFromEnv(t *testing.T) {
+	t.Setenv("MARKET_IDS", "1.1, 1.2")
+	t.Setenv("EVENT_TYPE_ID", " 4339 ")
+
+	update := FilterUpdateFromEnv()
+	if strings.Join(update.MarketIDs, ",") != "1.1,1.2" || update.EventTypeID != "4339" {
+		t.Errorf("Unexpected update %+v", update)
+	}
+}
+
+func TestMarketRecorderReloadOnSignal(t *testing.T) {
+	recorder := &MarketRecorder{
+		config: &Config{MarketIDs: []string{"1.1"}},
+		logger: zerolog.New(zerolog.NewTestWriter(t)),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	updated := make(chan struct{}, 1)
+	recorder.ReloadOnSignal(ctx, func() FilterUpdate {
+		updated <- struct{}{}
+		return FilterUpdate{MarketIDs: []string{"1.2"}}
+	})
+
+	process, _ := os.FindProcess(os.Getpid())
+	if err := process.Signal(syscall.SIGHUP); err != nil {
+		t.Skipf("cannot send SIGHUP: %v", err)
+	}
+
+	select {
+	case <-updated:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected SIGHUP to reload the filter")
+	}
+	waitFor(t, func() bool {
+		recorder.streamMu.Lock()
+		defer recorder.streamMu.Unlock()
+		return strings.Join(recorder.config.MarketIDs, ",") == "1.2"
+	})
+}
@@ -21,26 +21,63 @@ const (
 	AccountURLAccounts     = "https://api.betfair.com/exchange/account/json-rpc/v1"
 )
 
+// DefaultLocale is the language the recorder has always requested names in
+const DefaultLocale = "en"
+
+// ParseCurrencyCode parses an ISO 4217 currency code such as "AUD" or "gbp"
+func ParseCurrencyCode(value string) (string, error) {
+	code := strings.ToUpper(strings.TrimSpace(value))
+	if len(code) != 3 || strings.Trim(code, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") != "" {
+		return "", fmt.Errorf("unknown currency code %q", value)
+	}
+	return code, nil
+}
+
 type RESTClient struct {
 	appKey     string
-	sessionKey string
+	tokens     *TokenStore
 	locale     string
+	currency   string
 	httpClient *http.Client
+	endpoints  Endpoints
+	refCache   *referenceCache
 }
 
 func NewRESTClient(appKey, sessionKey, locale string) *RESTClient {
 	return &RESTClient{
 		appKey:     appKey,
-		sessionKey: sessionKey,
+		tokens:     NewTokenStore(sessionKey),
 		locale:     locale,
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
+		endpoints: DefaultEndpoints(),
 	}
 }
 
+// SetEndpoints points the client at a different jurisdiction's API hosts
+func (c *RESTClient) SetEndpoints(endpoints Endpoints) {
+	c.endpoints = endpoints
+}
+
+// SetLocale changes the language of names and descriptions in responses, such as "en" or "es"
+func (c *RESTClient) SetLocale(locale string) {
+	c.locale = locale
+}
+
+// SetCurrency sets the currency market book amounts are returned in when a call doesn't name one,
+// such as "AUD"; empty leaves it to the account's currency
+func (c *RESTClient) SetCurrency(currencyCode string) {
+	c.currency = currencyCode
+}
+
 func (c *RESTClient) UpdateSessionKey(sessionKey string) {
-	c.sessionKey = sessionKey
+	c.tokens.Set(sessionKey)
+}
+
+// UseTokenStore shares a token store with the client so it always sends the latest session token
+func (c *RESTClient) UseTokenStore(store *TokenStore) {
+	c.tokens = store
 }
 
 type JSONRPCRequest struct {
@@ -93,8 +130,8 @@ func (c *RESTClient) makeRequest(ctx context.Context, requestURL, method string,
 	if c.appKey != "" {
 		req.Header.Set("X-Application", c.appKey)
 	}
-	if c.sessionKey != "" {
-		req.Header.Set("X-Authentication", c.sessionKey)
+	if sessionKey := c.tokens.Get(); sessionKey != "" {
+		req.Header.Set("X-Authentication", sessionKey)
 	}
 
 	return c.httpClient.Do(req)
@@ -108,7 +145,7 @@ func (c *RESTClient) makeBettingAPIRequest(ctx context.Context, method string, p
 		ID:      time.Now().UnixNano(),
 	}
 
-	resp, err := c.makeRequest(ctx, BettingURLExchange, "POST", requestPayload)
+	resp, err := c.makeRequest(ctx, c.endpoints.Betting, "POST", requestPayload)
 	if err != nil {
 		return nil, fmt.Errorf("make request: %w", err)
 	}
@@ -138,7 +175,7 @@ func (c *RESTClient) makeAccountAPIRequest(ctx context.Context, method string, p
 		ID:      time.Now().UnixNano(),
 	}
 
-	resp, err := c.makeRequest(ctx, AccountURLAccounts, "POST", requestPayload)
+	resp, err := c.makeRequest(ctx, c.endpoints.Account, "POST", requestPayload)
 	if err != nil {
 		return nil, fmt.Errorf("make request: %w", err)
 	}
@@ -314,7 +351,7 @@ func (c *RESTClient) ListEventTypes(ctx context.Context, filter MarketFilter) ([
 		"locale": c.locale,
 	}
 
-	resp, err := c.makeBettingAPIRequest(ctx, "listEventTypes", params)
+	resp, err := c.makeReferenceDataRequest(ctx, "listEventTypes", params)
 	if err != nil {
 		return nil, err
 	}
@@ -338,7 +375,7 @@ func (c *RESTClient) ListCompetitions(ctx context.Context, filter MarketFilter)
 		"locale": c.locale,
 	}
 
-	resp, err := c.makeBettingAPIRequest(ctx, "listCompetitions", params)
+	resp, err := c.makeReferenceDataRequest(ctx, "listCompetitions", params)
 	if err != nil {
 		return nil, err
 	}
@@ -362,7 +399,7 @@ func (c *RESTClient) ListEvents(ctx context.Context, filter MarketFilter) ([]Eve
 		"locale": c.locale,
 	}
 
-	resp, err := c.makeBettingAPIRequest(ctx, "listEvents", params)
+	resp, err := c.makeReferenceDataRequest(ctx, "listEvents", params)
 	if err != nil {
 		return nil, err
 	}
@@ -386,7 +423,7 @@ func (c *RESTClient) ListMarketTypes(ctx context.Context, filter MarketFilter) (
 		"locale": c.locale,
 	}
 
-	resp, err := c.makeBettingAPIRequest(ctx, "listMarketTypes", params)
+	resp, err := c.makeReferenceDataRequest(ctx, "listMarketTypes", params)
 	if err != nil {
 		return nil, err
 	}
@@ -410,7 +447,7 @@ func (c *RESTClient) ListCountries(ctx context.Context, filter MarketFilter) ([]
 		"locale": c.locale,
 	}
 
-	resp, err := c.makeBettingAPIRequest(ctx, "listCountries", params)
+	resp, err := c.makeReferenceDataRequest(ctx, "listCountries", params)
 	if err != nil {
 		return nil, err
 	}
@@ -434,7 +471,7 @@ func (c *RESTClient) ListVenues(ctx context.Context, filter MarketFilter) ([]Ven
 		"locale": c.locale,
 	}
 
-	resp, err := c.makeBettingAPIRequest(ctx, "listVenues", params)
+	resp, err := c.makeReferenceDataRequest(ctx, "listVenues", params)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,317 @@
+package betfair
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	DefaultRetentionCheckInterval = time.Hour
+
+	// RetentionArchivePrefix names the daily tars old files are moved into
+	RetentionArchivePrefix = "archive_"
+)
+
+// RetentionMode decides what the janitor does with local files past the retention period
+type RetentionMode string
+
+const (
+	// RetentionDelete removes old files
+	RetentionDelete RetentionMode = "delete"
+	// RetentionTar moves old files into one tar per day
+	RetentionTar RetentionMode = "tar"
+)
+
+// ParseRetentionMode parses "delete" or "tar"
+func ParseRetentionMode(value string) (RetentionMode, error) {
+	mode := RetentionMode(strings.ToLower(strings.TrimSpace(value)))
+	switch mode {
+	case RetentionDelete, RetentionTar:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown retention mode %q", value)
+}
+
+// retainedSuffixes are the finished files the janitor manages. Raw market files are never touched,
+// and compressed files still being recorded are skipped by inProgress.
+var retainedSuffixes = []string{
+	CompressionBzip2.Extension(),
+	CompressionGzip.Extension(),
+	CompressionZstd.Extension(),
+	TimelineSuffix,
+}
+
+// Janitor keeps a recorder that is not uploading to S3 from filling its disk by deleting or
+// tarring up compressed market files older than the retention period
+type Janitor struct {
+	root     string
+	maxAge   time.Duration
+	mode     RetentionMode
+	interval time.Duration
+	logger   zerolog.Logger
+	now      func() time.Time
+
+	openMarket func(name string) bool
+}
+
+func NewJanitor(root string, maxAge time.Duration, mode RetentionMode, interval time.Duration, logger zerolog.Logger) *Janitor {
+	if interval <= 0 {
+		interval = DefaultRetentionCheckInterval
+	}
+	if mode == "" {
+		mode = RetentionDelete
+	}
+	return &Janitor{
+		root:     root,
+		maxAge:   maxAge,
+		mode:     mode,
+		interval: interval,
+		logger:   logger,
+		now:      time.Now,
+	}
+}
+
+// SetOpenMarkets makes the janitor skip files for which open reports true, such as files of
+// markets that still have a writer open
+func (j *Janitor) SetOpenMarkets(open func(name string) bool) {
+	j.openMarket = open
+}
+
+// Sweep deletes or tars every managed file last modified before the retention period
+func (j *Janitor) Sweep() {
+	expired, err := j.expiredFiles()
+	if err != nil {
+		j.logger.Error().Err(err).Str("path", j.root).Msg("failed to scan for expired market files")
+		return
+	}
+	if len(expired) == 0 {
+		return
+	}
+
+	if j.mode == RetentionTar {
+		j.tarExpired(expired)
+		return
+	}
+
+	removed := 0
+	for _, file := range expired {
+		if err := os.Remove(file.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			j.logger.Error().Err(err).Str("file", file.path).Msg("failed to remove expired market file")
+			continue
+		}
+		removed++
+	}
+	j.logger.Info().Int("files", removed).Dur("retention", j.maxAge).Msg("removed expired market files")
+}
+
+// Run sweeps every interval until ctx is cancelled
+func (j *Janitor) Run(ctx context.Context) {
+	j.Sweep()
+
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			j.Sweep()
+		}
+	}
+}
+
+type expiredFile struct {
+	path    string
+	name    string
+	modTime time.Time
+}
+
+func (j *Janitor) expiredFiles() ([]expiredFile, error) {
+	cutoff := j.now().Add(-j.maxAge)
+
+	var expired []expiredFile
+	err := filepath.WalkDir(j.root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if entry.IsDir() || !retained(entry.Name()) || j.inProgress(path) {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil
+		}
+		if !info.ModTime().Before(cutoff) {
+			return nil
+		}
+		name, err := filepath.Rel(j.root, path)
+		if err != nil {
+			return err
+		}
+		expired = append(expired, expiredFile{path: path, name: filepath.ToSlash(name), modTime: info.ModTime()})
+		return nil
+	})
+	return expired, err
+}
+
+// inProgress reports whether a file may still be written to: a compressed recording in the output
+// root, or a file of a market that is open
+func (j *Janitor) inProgress(path string) bool {
+	name := filepath.Base(path)
+	if filepath.Dir(path) == filepath.Clean(j.root) && strings.Contains(name, RecordingSuffix+".") {
+		return true
+	}
+	return j.openMarket != nil && j.openMarket(name)
+}
+
+func retained(name string) bool {
+	for _, suffix := range retainedSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// RetentionArchivePath returns the tar that files last modified on the given UTC day are moved into
+func RetentionArchivePath(root string, day time.Time) string {
+	return filepath.Join(root, RetentionArchivePrefix+day.UTC().Format("2006-01-02")+".tar")
+}
+
+func (j *Janitor) tarExpired(expired []expiredFile) {
+	byDay := make(map[string][]expiredFile)
+	for _, file := range expired {
+		path := RetentionArchivePath(j.root, file.modTime)
+		byDay[path] = append(byDay[path], file)
+	}
+
+	days := make([]string, 0, len(byDay))
+	for path := range byDay {
+		days = append(days, path)
+	}
+	sort.Strings(days)
+
+	for _, path := range days {
+		files := byDay[path]
+		if err := appendToTar(path, files); err != nil {
+			j.logger.Error().Err(err).Str("archive", path).Msg("failed to archive expired market files")
+			continue
+		}
+		for _, file := range files {
+			if err := os.Remove(file.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				j.logger.Error().Err(err).Str("file", file.path).Msg("failed to remove archived market file")
+			}
+		}
+		j.logger.Info().Int("files", len(files)).Str("archive", path).Msg("archived expired market files")
+	}
+}
+
+// appendToTar adds files to the tar at path, creating it if needed. The tar is rewritten to a
+// temporary file and renamed into place so a crash never leaves a damaged archive.
+func appendToTar(path string, files []expiredFile) error {
+	tmpPath := path + ".tmp"
+	output, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("create archive: %w", err)
+	}
+
+	if err := writeTar(output, path, files); err != nil {
+		output.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := output.Sync(); err != nil {
+		output.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("sync archive: %w", err)
+	}
+	if err := output.Close(); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("close archive: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("rename archive: %w", err)
+	}
+	return nil
+}
+
+// writeTar copies the entries of the existing tar at path, if any, followed by files
+func writeTar(output io.Writer, path string, files []expiredFile) error {
+	writer := tar.NewWriter(output)
+
+	existing, err := os.Open(path)
+	switch {
+	case err == nil:
+		defer existing.Close()
+		reader := tar.NewReader(existing)
+		for {
+			header, err := reader.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return fmt.Errorf("read existing archive: %w", err)
+			}
+			if err := writer.WriteHeader(header); err != nil {
+				return fmt.Errorf("copy archive entry: %w", err)
+			}
+			if _, err := io.Copy(writer, reader); err != nil {
+				return fmt.Errorf("copy archive entry: %w", err)
+			}
+		}
+	case !errors.Is(err, os.ErrNotExist):
+		return fmt.Errorf("open existing archive: %w", err)
+	}
+
+	for _, file := range files {
+		if err := addTarFile(writer, file); err != nil {
+			return err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("finish archive: %w", err)
+	}
+	return nil
+}
+
+func addTarFile(writer *tar.Writer, file expiredFile) error {
+	input, err := os.Open(file.path)
+	if err != nil {
+		return fmt.Errorf("open %s: %w", file.name, err)
+	}
+	defer input.Close()
+
+	info, err := input.Stat()
+	if err != nil {
+		return fmt.Errorf("stat %s: %w", file.name, err)
+	}
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return fmt.Errorf("build header for %s: %w", file.name, err)
+	}
+	header.Name = file.name
+
+	if err := writer.WriteHeader(header); err != nil {
+		return fmt.Errorf("write header for %s: %w", file.name, err)
+	}
+	if _, err := io.Copy(writer, input); err != nil {
+		return fmt.Errorf("archive %s: %w", file.name, err)
+	}
+	return nil
+}
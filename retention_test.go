@@ -0,0 +1,164 @@
+package betfair
+
+import (
+	"archive/tar"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func writeAgedFile(t *testing.T, path string, modTime time.Time) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(filepath.Base(path)), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatalf("Failed to age %s: %v", path, err)
+	}
+}
+
+func TestJanitorDeletesExpiredFiles(t *testing.T) {
+	root := t.TempDir()
+	now := time.Date(2025, 10, 10, 12, 0, 0, 0, time.UTC)
+	old := now.Add(-8 * 24 * time.Hour)
+
+	expired := []string{
+		filepath.Join(root, "1.1.bz2"),
+		filepath.Join(root, "1.1"+TimelineSuffix),
+		filepath.Join(root, "PRO", "2025", "Oct", "2", "99", "1.2.gz"),
+	}
+	kept := []string{
+		filepath.Join(root, "1.3.bz2"),
+		filepath.Join(root, "1.4"),
+		filepath.Join(root, RecordingIndexName),
+	}
+	for _, path := range expired {
+		writeAgedFile(t, path, old)
+	}
+	writeAgedFile(t, kept[0], now.Add(-time.Hour))
+	writeAgedFile(t, kept[1], old)
+	writeAgedFile(t, kept[2], old)
+
+	janitor := NewJanitor(root, 7*24*time.Hour, RetentionDelete, 0, zerolog.New(zerolog.NewTestWriter(t)))
+	janitor.now = func() time.Time { return now }
+	janitor.Sweep()
+
+	for _, path := range expired {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be removed", path)
+		}
+	}
+	for _, path := range kept {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("Expected %s to be kept: %v", path, err)
+		}
+	}
+}
+
+func TestJanitorTarsExpiredFilesByDay(t *testing.T) {
+	root := t.TempDir()
+	now := time.Date(2025, 10, 10, 12, 0, 0, 0, time.UTC)
+	day := time.Date(2025, 10, 1, 9, 0, 0, 0, time.UTC)
+
+	janitor := NewJanitor(root, 7*24*time.Hour, RetentionTar, 0, zerolog.New(zerolog.NewTestWriter(t)))
+	janitor.now = func() time.Time { return now }
+
+	writeAgedFile(t, filepath.Join(root, "1.1.bz2"), day)
+	janitor.Sweep()
+
+	// A later sweep adds to the same day's archive rather than replacing it
+	writeAgedFile(t, filepath.Join(root, "PRO", "99", "1.2.bz2"), day.Add(time.Hour))
+	writeAgedFile(t, filepath.Join(root, "1.3.bz2"), day.Add(24*time.Hour))
+	janitor.Sweep()
+
+	if entries := tarEntries(t, RetentionArchivePath(root, day)); len(entries) != 2 || entries["1.1.bz2"] != "1.1.bz2" || entries["PRO/99/1.2.bz2"] != "1.2.bz2" {
+		t.Errorf("Unexpected entries for first day: %v", entries)
+	}
+	if entries := tarEntries(t, RetentionArchivePath(root, day.Add(24*time.Hour))); len(entries) != 1 || entries["1.3.bz2"] != "1.3.bz2" {
+		t.Errorf("Unexpected entries for second day: %v", entries)
+	}
+
+	for _, name := range []string{"1.1.bz2", "PRO/99/1.2.bz2", "1.3.bz2"} {
+		if _, err := os.Stat(filepath.Join(root, filepath.FromSlash(name))); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be moved into an archive", name)
+		}
+	}
+}
+
+func TestJanitorSkipsFilesStillBeingRecorded(t *testing.T) {
+	root := t.TempDir()
+	now := time.Date(2025, 10, 10, 12, 0, 0, 0, time.UTC)
+	old := now.Add(-8 * 24 * time.Hour)
+
+	fm := NewFileManager(root)
+	fm.SetCompression(CompressionGzip, 0)
+	fm.SetStreamCompression(true)
+	recorder := &MarketRecorder{fileManager: fm}
+	recorder.openMarkets.Store("1.5", struct{}{})
+
+	kept := []string{
+		fm.RecordingFilePath("1.4"),
+		filepath.Join(root, "1.5.part001.gz"),
+		filepath.Join(root, "PRO", "99", "1.5.part002.gz"),
+	}
+	expired := filepath.Join(root, "1.50.gz")
+	for _, path := range append(kept, expired) {
+		writeAgedFile(t, path, old)
+	}
+
+	janitor := NewJanitor(root, 7*24*time.Hour, RetentionTar, 0, zerolog.New(zerolog.NewTestWriter(t)))
+	janitor.now = func() time.Time { return now }
+	janitor.SetOpenMarkets(recorder.hasOpenMarketFile)
+	janitor.Sweep()
+
+	for _, path := range kept {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("Expected %s to be kept while recording: %v", path, err)
+		}
+	}
+	if entries := tarEntries(t, RetentionArchivePath(root, old)); len(entries) != 1 || entries["1.50.gz"] != "1.50.gz" {
+		t.Errorf("Expected only the finished archive to be tarred, got %v", entries)
+	}
+}
+
+func TestParseRetentionMode(t *testing.T) {
+	if mode, err := ParseRetentionMode(" TAR "); err != nil || mode != RetentionTar {
+		t.Errorf("Expected tar, got %q, %v", mode, err)
+	}
+	if _, err := ParseRetentionMode("shred"); err == nil {
+		t.Error("Expected unknown retention mode to fail")
+	}
+}
+
+func tarEntries(t *testing.T, path string) map[string]string {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Expected archive %s: %v", path, err)
+	}
+	defer file.Close()
+
+	entries := make(map[string]string)
+	reader := tar.NewReader(file)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return entries
+		}
+		if err != nil {
+			t.Fatalf("Failed to read archive: %v", err)
+		}
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			t.Fatalf("Failed to read archive entry: %v", err)
+		}
+		entries[header.Name] = string(data)
+	}
+}
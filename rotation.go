@@ -0,0 +1,135 @@
+package betfair
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"time"
+)
+
+// marketSegment tracks how much has been written to the current file of a market that is rotated
+// while recording
+type marketSegment struct {
+	index   int
+	bytes   int64
+	started time.Time
+}
+
+// segmentName names the numbered archive of a rotated segment, such as 1.234.part001. The final
+// segment of a market keeps the plain market ID so settled markets archive as before.
+func segmentName(marketID string, index int) string {
+	return fmt.Sprintf("%s.part%03d", marketID, index)
+}
+
+func (c *Config) rotates() bool {
+	return c != nil && (c.RotateBytes > 0 || c.RotateInterval > 0)
+}
+
+// trackSegment counts a line written to a market's file and remembers the last line carrying a
+// market definition, which starts the next segment
+func (r *MarketRecorder) trackSegment(marketID string, marketChange map[string]interface{}, line []byte, now time.Time) {
+	if !r.config.rotates() {
+		return
+	}
+	segment, exists := r.segments[marketID]
+	if !exists {
+		if r.segments == nil {
+			r.segments = make(map[string]*marketSegment)
+		}
+		segment = &marketSegment{index: 1, started: now}
+		r.segments[marketID] = segment
+	}
+	segment.bytes += int64(len(line))
+
+	if _, ok := marketChange["marketDefinition"]; ok {
+		if r.lastDefinitions == nil {
+			r.lastDefinitions = make(map[string][]byte)
+		}
+		r.lastDefinitions[marketID] = line
+	}
+}
+
+// segmentDue reports whether a market's current file has reached the size or age limit
+func (r *MarketRecorder) segmentDue(marketID string, now time.Time) bool {
+	segment, exists := r.segments[marketID]
+	if !exists || segment.bytes == 0 {
+		return false
+	}
+	if r.config.RotateBytes > 0 && segment.bytes >= r.config.RotateBytes {
+		return true
+	}
+	return r.config.RotateInterval > 0 && now.Sub(segment.started) >= r.config.RotateInterval
+}
+
+// maybeRotate closes a market's file once it is due, archiving it as the next numbered segment, and
+// continues recording into a fresh file that opens with the market's last definition
+func (r *MarketRecorder) maybeRotate(ctx context.Context, marketID string, now time.Time, writers map[string]*bufio.Writer, files map[string]*os.File) {
+	if !r.config.rotates() || !r.segmentDue(marketID, now) {
+		return
+	}
+	mw, exists := r.marketWriters[marketID]
+	if !exists {
+		return
+	}
+	definition, ok := r.lastDefinitions[marketID]
+	if !ok {
+		return
+	}
+	eventInfo, err := r.eventInfo(definition)
+	if err != nil {
+		r.logger.Error().Err(err).Str("market_id", marketID).Msg("cannot rotate market file without event info")
+		return
+	}
+
+	segment := r.segments[marketID]
+	name := segmentName(marketID, segment.index)
+	compressed := r.fileManager.StreamsCompressed()
+	source := r.fileManager.RecordingFilePath(marketID)
+	rotated := r.fileManager.GetMarketFilePath(name)
+	if compressed {
+		rotated += r.fileManager.CompressedExtension()
+	}
+
+	// The writer goroutine keeps writing to the renamed file until it is closed below
+	if err := os.Rename(source, rotated); err != nil {
+		r.logger.Error().Err(err).Str("market_id", marketID).Msg("failed to rotate market file")
+		return
+	}
+
+	r.releaseMarketWriter(marketID)
+	delete(writers, marketID)
+	delete(files, marketID)
+
+	archiveCtx := context.WithoutCancel(ctx)
+	r.settlements.Add(1)
+	go func() {
+		defer r.settlements.Done()
+
+		err := mw.Close(true)
+		if err != nil {
+			r.logger.Error().Err(err).Str("market_id", marketID).Msg("failed to close market file segment")
+		}
+		r.archiveRecording(archiveCtx, marketID, rotated, compressed, name, eventInfo)
+	}()
+
+	r.logger.Info().Str("market_id", marketID).Str("segment", name).Int64("bytes", segment.bytes).Msg("rotated market file")
+	segment.index++
+	segment.bytes = 0
+	segment.started = now
+
+	if err := r.createWriterForMarket(marketID, writers, files); err != nil {
+		r.logger.Error().Err(err).Str("market_id", marketID).Msg("failed to open next market file segment")
+		return
+	}
+	r.ensureMarketWriter(marketID, writers, files)
+	r.marketWriters[marketID].Write(definition)
+	r.markUnsynced(r.marketWriters[marketID])
+	segment.bytes += int64(len(definition))
+}
+
+// forgetSegments drops rotation state once a market is finished
+func (r *MarketRecorder) forgetSegments(marketID string) {
+	delete(r.segments, marketID)
+	delete(r.lastDefinitions, marketID)
+}
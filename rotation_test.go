@@ -0,0 +1,104 @@
+package betfair
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func TestMarketRecorderRotatesBySize(t *testing.T) {
+	tempDir := t.TempDir()
+	recorder := &MarketRecorder{
+		config:           &Config{EnrichmentMode: EnrichmentOff, RotateBytes: 1},
+		logger:           zerolog.New(zerolog.NewTestWriter(t)),
+		fileManager:      NewFileManager(tempDir),
+		marketCatalogues: make(map[string]*MarketCatalogue),
+	}
+
+	messages := []string{
+		`{"op":"mcm","pt":1,"mc":[{"id":"1.1","img":true,"marketDefinition":{"status":"OPEN","eventId":"99","openDate":"2025-10-01T12:00:00.000Z"}}]}`,
+		`{"op":"mcm","pt":2,"mc":[{"id":"1.1","rc":[{"id":7,"ltp":3.5}]}]}`,
+	}
+	stream := &StreamConn{reader: bufio.NewReader(strings.NewReader(strings.Join(messages, "\n") + "\n"))}
+	writers := make(map[string]*bufio.Writer)
+	files := make(map[string]*os.File)
+	statuses := make(map[string]string)
+
+	for range messages {
+		if err := recorder.readMessage(context.Background(), stream, writers, files, statuses); err != nil {
+			t.Fatalf("readMessage failed: %v", err)
+		}
+	}
+	recorder.stopMarketWriters()
+
+	first := readSegment(t, recorder.fileManager.GetCompressedFilePath(segmentName("1.1", 1)))
+	if len(first) != 1 || !strings.Contains(first[0], `"marketDefinition"`) {
+		t.Fatalf("Expected first segment to hold the definition, got %v", first)
+	}
+
+	second := readSegment(t, recorder.fileManager.GetCompressedFilePath(segmentName("1.1", 2)))
+	if len(second) != 2 || !strings.Contains(second[0], `"marketDefinition"`) || !strings.Contains(second[1], `"ltp":3.5`) {
+		t.Fatalf("Expected second segment to open with the last definition, got %v", second)
+	}
+
+	current, err := os.ReadFile(recorder.fileManager.GetMarketFilePath("1.1"))
+	if err != nil {
+		t.Fatalf("Expected recording to continue in a fresh file: %v", err)
+	}
+	if lines := strings.Split(strings.TrimSpace(string(current)), "\n"); len(lines) != 1 || !strings.Contains(lines[0], `"marketDefinition"`) {
+		t.Errorf("Expected fresh file to start with the definition, got %v", lines)
+	}
+}
+
+func TestMarketRecorderSegmentDue(t *testing.T) {
+	now := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+	recorder := &MarketRecorder{config: &Config{RotateInterval: time.Hour}}
+
+	if recorder.segmentDue("1.1", now) {
+		t.Error("Expected market with nothing written not to be due")
+	}
+
+	recorder.trackSegment("1.1", map[string]interface{}{}, []byte("{}\n"), now)
+	if recorder.segmentDue("1.1", now.Add(59*time.Minute)) {
+		t.Error("Expected segment younger than the interval not to be due")
+	}
+	if !recorder.segmentDue("1.1", now.Add(time.Hour)) {
+		t.Error("Expected segment to be due once the interval has passed")
+	}
+
+	recorder.forgetSegments("1.1")
+	if recorder.segmentDue("1.1", now.Add(2*time.Hour)) {
+		t.Error("Expected forgotten market not to be due")
+	}
+}
+
+func TestSegmentName(t *testing.T) {
+	if got := segmentName("1.234", 7); got != "1.234.part007" {
+		t.Errorf("Expected 1.234.part007, got %s", got)
+	}
+}
+
+func readSegment(t *testing.T, path string) []string {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Expected segment archive %s: %v", path, err)
+	}
+	defer file.Close()
+
+	reader, err := newDecompressor(file, CompressionBzip2)
+	if err != nil {
+		t.Fatalf("Failed to open segment archive: %v", err)
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("Failed to read segment archive: %v", err)
+	}
+	return strings.Split(strings.TrimSpace(string(data)), "\n")
+}
@@ -0,0 +1,74 @@
+package betfair
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+)
+
+var (
+	errDuplicateMarketWriter = errors.New("market already has a writer")
+	errMisrouted             = errors.New("market change routed to the wrong market")
+)
+
+// RoutingStats counts market changes the recorder refused to write
+type RoutingStats struct {
+	// Dropped counts changes with a missing or unusable market ID
+	Dropped uint64
+	// Misrouted counts changes that would have been written into another market's file
+	Misrouted uint64
+	// DuplicateWriters counts attempts to open a second writer for a market
+	DuplicateWriters uint64
+}
+
+type routingCounters struct {
+	dropped          atomic.Uint64
+	misrouted        atomic.Uint64
+	duplicateWriters atomic.Uint64
+}
+
+func (c *routingCounters) stats() RoutingStats {
+	return RoutingStats{
+		Dropped:          c.dropped.Load(),
+		Misrouted:        c.misrouted.Load(),
+		DuplicateWriters: c.duplicateWriters.Load(),
+	}
+}
+
+// RoutingStats reports how many market changes were dropped or refused as misrouted
+func (r *MarketRecorder) RoutingStats() RoutingStats {
+	return r.routing.stats()
+}
+
+// usableMarketID rejects IDs that could escape the output directory when used as a filename
+func usableMarketID(marketID string) bool {
+	return marketID != "" && marketID != "." && marketID != ".." && !strings.ContainsAny(marketID, `/\`)
+}
+
+// validateRouting checks that a single-market payload only carries the given market and that it
+// is headed for one of that market's own files
+func validateRouting(marketID string, payload []byte, filePath string, marketPaths ...string) error {
+	var message struct {
+		MarketChanges []struct {
+			ID string `json:"id"`
+		} `json:"mc"`
+	}
+	if err := json.Unmarshal(payload, &message); err != nil {
+		return fmt.Errorf("decode routed payload: %w", err)
+	}
+	if len(message.MarketChanges) != 1 || message.MarketChanges[0].ID != marketID {
+		return fmt.Errorf("payload for %s carries %d market changes: %w", marketID, len(message.MarketChanges), errMisrouted)
+	}
+	if filePath == "" {
+		return nil
+	}
+	for _, path := range marketPaths {
+		if filepath.Clean(filePath) == filepath.Clean(path) {
+			return nil
+		}
+	}
+	return fmt.Errorf("market %s headed for %s: %w", marketID, filepath.Base(filePath), errMisrouted)
+}
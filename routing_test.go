@@ -0,0 +1,62 @@
+package betfair
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestValidateRouting(t *testing.T) {
+	payload := []byte(`{"op":"mcm","mc":[{"id":"1.1","rc":[]}]}`)
+	if err := validateRouting("1.1", payload, "/data/1.1", "/data/1.1"); err != nil {
+		t.Errorf("Expected matching payload to pass, got %v", err)
+	}
+	if err := validateRouting("1.1", payload, "/data/1.2", "/data/1.1"); !errors.Is(err, errMisrouted) {
+		t.Errorf("Expected wrong file to be misrouted, got %v", err)
+	}
+	if err := validateRouting("1.2", payload, "", "/data/1.2"); !errors.Is(err, errMisrouted) {
+		t.Errorf("Expected wrong market to be misrouted, got %v", err)
+	}
+
+	mixed := []byte(`{"op":"mcm","mc":[{"id":"1.1"},{"id":"1.2"}]}`)
+	if err := validateRouting("1.1", mixed, "", "/data/1.1"); !errors.Is(err, errMisrouted) {
+		t.Errorf("Expected multi-market payload to be misrouted, got %v", err)
+	}
+}
+
+func TestMarketRecorderRoutingGuards(t *testing.T) {
+	tempDir := t.TempDir()
+	recorder := &MarketRecorder{
+		config:           &Config{EnrichmentMode: EnrichmentOff},
+		logger:           zerolog.New(zerolog.NewTestWriter(t)),
+		fileManager:      NewFileManager(tempDir),
+		marketCatalogues: make(map[string]*MarketCatalogue),
+	}
+
+	message := `{"op":"mcm","pt":1,"mc":[{"id":"../escape","rc":[]},{"id":"","rc":[]},{"id":"1.1","rc":[]}]}` + "\n"
+	stream := &StreamConn{reader: bufio.NewReader(strings.NewReader(message))}
+	writers := make(map[string]*bufio.Writer)
+	files := make(map[string]*os.File)
+
+	if err := recorder.readMessage(context.Background(), stream, writers, files, make(map[string]string)); err != nil {
+		t.Fatalf("readMessage failed: %v", err)
+	}
+
+	if err := recorder.createWriterForMarket("1.1", writers, files); !errors.Is(err, errDuplicateMarketWriter) {
+		t.Errorf("Expected duplicate writer to be refused, got %v", err)
+	}
+	recorder.stopMarketWriters()
+
+	stats := recorder.RoutingStats()
+	if stats.Dropped != 2 || stats.Misrouted != 0 || stats.DuplicateWriters != 1 {
+		t.Errorf("Unexpected routing stats %+v", stats)
+	}
+	if len(writers) != 1 {
+		t.Errorf("Expected only 1.1 to be recorded, got %d writers", len(writers))
+	}
+}
@@ -0,0 +1,304 @@
+package betfair
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/rs/zerolog"
+)
+
+// DefaultSecretRefreshInterval is how often secrets are re-read to pick up rotated credentials
+const DefaultSecretRefreshInterval = 15 * time.Minute
+
+// SecretSource supplies a credential kept outside the environment
+type SecretSource interface {
+	Secret(ctx context.Context) (string, error)
+}
+
+// ParseSecretSource parses a secret reference:
+//
+//	file:///run/secrets/betfair_password   a file's contents, without the trailing newline
+//	secretsmanager://betfair/prod#password an AWS Secrets Manager secret, or a key of a JSON secret
+//	ssm:///betfair/prod/password           an AWS SSM parameter, decrypted
+//
+// AWS references load the default AWS configuration when parsed, failing without a region.
+func ParseSecretSource(ref string) (SecretSource, error) {
+	ref = strings.TrimSpace(ref)
+	scheme, rest, ok := strings.Cut(ref, "://")
+	if !ok || rest == "" {
+		return nil, fmt.Errorf("unknown secret reference %q, want file://, secretsmanager:// or ssm://", ref)
+	}
+	switch scheme {
+	case "file":
+		return fileSecret(rest), nil
+	case "secretsmanager":
+		name, key, _ := strings.Cut(rest, "#")
+		return newAWSSecret("secretsmanager", name, key)
+	case "ssm":
+		return newAWSSecret("ssm", rest, "")
+	}
+	return nil, fmt.Errorf("unknown secret reference %q, want file://, secretsmanager:// or ssm://", ref)
+}
+
+// fileSecret reads a secret from a file, such as a mounted Docker or Kubernetes secret
+type fileSecret string
+
+func (f fileSecret) Secret(context.Context) (string, error) {
+	data, err := os.ReadFile(string(f))
+	if err != nil {
+		return "", fmt.Errorf("read secret file: %w", err)
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// awsSecret reads a secret from AWS Secrets Manager or SSM Parameter Store through their JSON APIs,
+// with credentials and region from the default AWS configuration
+type awsSecret struct {
+	service string // secretsmanager or ssm
+	name    string
+	key     string // The key of a JSON Secrets Manager secret, when set
+
+	region      string
+	endpoint    string
+	credentials aws.CredentialsProvider
+}
+
+// newAWSSecret loads the default AWS configuration once, so every refresh reuses its cached
+// credentials. AWS_ENDPOINT_URL points requests elsewhere, such as at LocalStack.
+func newAWSSecret(service, name, key string) (*awsSecret, error) {
+	awsCfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("load AWS config: %w", err)
+	}
+	if awsCfg.Region == "" {
+		return nil, fmt.Errorf("no AWS region configured")
+	}
+	endpoint := "https://" + service + "." + awsCfg.Region + ".amazonaws.com/"
+	if awsCfg.BaseEndpoint != nil {
+		endpoint = *awsCfg.BaseEndpoint
+	}
+	return &awsSecret{
+		service:     service,
+		name:        name,
+		key:         key,
+		region:      awsCfg.Region,
+		endpoint:    endpoint,
+		credentials: awsCfg.Credentials,
+	}, nil
+}
+
+func (s *awsSecret) Secret(ctx context.Context) (string, error) {
+	var target string
+	var input any
+	switch s.service {
+	case "secretsmanager":
+		target, input = "secretsmanager.GetSecretValue", map[string]any{"SecretId": s.name}
+	default:
+		target, input = "AmazonSSM.GetParameter", map[string]any{"Name": s.name, "WithDecryption": true}
+	}
+
+	var output struct {
+		SecretString string
+		Parameter    struct{ Value string }
+	}
+	if err := s.call(ctx, target, input, &output); err != nil {
+		return "", fmt.Errorf("read %s secret %s: %w", s.service, s.name, err)
+	}
+	if s.service == "ssm" {
+		return output.Parameter.Value, nil
+	}
+	if s.key == "" {
+		return output.SecretString, nil
+	}
+
+	var values map[string]any
+	if err := json.Unmarshal([]byte(output.SecretString), &values); err != nil {
+		return "", fmt.Errorf("decode secret %s: %w", s.name, err)
+	}
+	value, ok := values[s.key].(string)
+	if !ok {
+		return "", fmt.Errorf("secret %s has no %q key", s.name, s.key)
+	}
+	return value, nil
+}
+
+// call makes a signed request to the service's JSON API
+func (s *awsSecret) call(ctx context.Context, target string, input, output any) error {
+	credentials, err := s.credentials.Retrieve(ctx)
+	if err != nil {
+		return fmt.Errorf("retrieve AWS credentials: %w", err)
+	}
+
+	body, err := json.Marshal(input)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", target)
+
+	if err := signAWSRequest(ctx, req, body, credentials, s.region, s.service, time.Now()); err != nil {
+		return fmt.Errorf("sign request: %w", err)
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	return json.Unmarshal(data, output)
+}
+
+// signAWSRequest signs a request with Signature Version 4 as of now
+func signAWSRequest(ctx context.Context, req *http.Request, body []byte, credentials aws.Credentials, region, service string, now time.Time) error {
+	hash := sha256.Sum256(body)
+	if req.URL.Path == "" {
+		req.URL.Path = "/"
+	}
+	return v4.NewSigner().SignHTTP(ctx, credentials, req, hex.EncodeToString(hash[:]), service, region, now)
+}
+
+// loadSecret parses a secret reference and reads the secret
+func loadSecret(ref string) (SecretSource, string, error) {
+	source, err := ParseSecretSource(ref)
+	if err != nil {
+		return nil, "", err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	value, err := source.Secret(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	if value == "" {
+		return nil, "", fmt.Errorf("secret is empty")
+	}
+	return source, value, nil
+}
+
+// SecretWatcher re-reads secrets on an interval and calls back with the values that changed, so
+// rotated credentials are used without a restart
+type SecretWatcher struct {
+	interval time.Duration
+	logger   zerolog.Logger
+
+	mu      sync.Mutex
+	watches []*secretWatch
+}
+
+type secretWatch struct {
+	name     string
+	source   SecretSource
+	value    string
+	onRotate func(value string)
+}
+
+func NewSecretWatcher(interval time.Duration, logger zerolog.Logger) *SecretWatcher {
+	if interval <= 0 {
+		interval = DefaultSecretRefreshInterval
+	}
+	return &SecretWatcher{interval: interval, logger: logger}
+}
+
+// Watch calls onRotate with the secret's new value whenever it differs from current
+func (w *SecretWatcher) Watch(name string, source SecretSource, current string, onRotate func(value string)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.watches = append(w.watches, &secretWatch{name: name, source: source, value: current, onRotate: onRotate})
+}
+
+// Run checks the secrets every interval until ctx is cancelled
+func (w *SecretWatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.Check(ctx)
+		}
+	}
+}
+
+// Check re-reads every secret once, calling back for those that changed
+func (w *SecretWatcher) Check(ctx context.Context) {
+	w.mu.Lock()
+	watches := append([]*secretWatch(nil), w.watches...)
+	w.mu.Unlock()
+
+	for _, watch := range watches {
+		value, err := watch.source.Secret(ctx)
+		if err != nil {
+			w.logger.Warn().Err(err).Str("secret", watch.name).Msg("failed to refresh secret")
+			continue
+		}
+		if value == "" || value == watch.value {
+			continue
+		}
+		watch.value = value
+		w.logger.Info().Str("secret", watch.name).Msg("secret rotated")
+		watch.onRotate(value)
+	}
+}
+
+// watchSecrets re-reads the credentials loaded from secrets until ctx is cancelled
+func (r *MarketRecorder) watchSecrets(ctx context.Context) {
+	if watcher := r.secretWatcher(); watcher != nil {
+		go watcher.Run(ctx)
+	}
+}
+
+// secretWatcher watches the credentials loaded from secrets, handing a rotated password to this
+// recorder's and every profile recorder's next login. It returns nil when there are none.
+func (r *MarketRecorder) secretWatcher() *SecretWatcher {
+	if r.config.PasswordSecret == nil && r.config.AppKeySecret == nil {
+		return nil
+	}
+	watcher := NewSecretWatcher(r.config.SecretRefreshInterval, r.logger)
+	if r.config.PasswordSecret != nil {
+		watcher.Watch("BETFAIR_PASSWORD", r.config.PasswordSecret, r.config.password, r.setPassword)
+	}
+	if r.config.AppKeySecret != nil {
+		watcher.Watch("BETFAIR_APP_KEY", r.config.AppKeySecret, r.config.AppKey, func(string) {
+			r.logger.Warn().Msg("app key rotated; restart the recorder to use it")
+		})
+	}
+	return watcher
+}
+
+// setPassword hands a rotated password to the authenticators of this recorder and its profiles
+func (r *MarketRecorder) setPassword(password string) {
+	if r.authenticator != nil {
+		r.authenticator.SetPassword(password)
+	}
+	for _, child := range r.profiles {
+		child.setPassword(password)
+	}
+}
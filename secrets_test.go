@@ -0,0 +1,138 @@
+package betfair
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/rs/zerolog"
+)
+
+func TestAWSSecretSources(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256") {
+			http.Error(w, "unsigned request", http.StatusForbidden)
+			return
+		}
+		var input map[string]any
+		json.NewDecoder(r.Body).Decode(&input)
+		switch r.Header.Get("X-Amz-Target") {
+		case "secretsmanager.GetSecretValue":
+			if input["SecretId"] != "betfair/prod" {
+				http.Error(w, "no such secret", http.StatusBadRequest)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]any{"SecretString": `{"password":"from-secrets-manager"}`})
+		case "AmazonSSM.GetParameter":
+			if input["Name"] != "/betfair/app-key" || input["WithDecryption"] != true {
+				http.Error(w, "no such parameter", http.StatusBadRequest)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]any{"Parameter": map[string]any{"Value": "from-ssm"}})
+		default:
+			http.Error(w, "unexpected target", http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test-secret")
+	t.Setenv("AWS_REGION", "ap-southeast-2")
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(t.TempDir(), "config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(t.TempDir(), "credentials"))
+	t.Setenv("AWS_ENDPOINT_URL", server.URL)
+
+	for ref, want := range map[string]string{
+		"secretsmanager://betfair/prod#password": "from-secrets-manager",
+		"ssm:///betfair/app-key":                 "from-ssm",
+	} {
+		source, err := ParseSecretSource(ref)
+		if err != nil {
+			t.Fatalf("ParseSecretSource(%q): %v", ref, err)
+		}
+		value, err := source.Secret(context.Background())
+		if err != nil || value != want {
+			t.Errorf("%s: expected %q, got %q, %v", ref, want, value, err)
+		}
+	}
+
+	source, _ := ParseSecretSource("secretsmanager://betfair/prod#username")
+	if _, err := source.Secret(context.Background()); err == nil {
+		t.Errorf("expected an error for a missing JSON key")
+	}
+	if _, err := ParseSecretSource("vault://betfair"); err == nil {
+		t.Errorf("expected an error for an unknown secret reference")
+	}
+}
+
+func TestPasswordFromSecretFileAndRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(path, []byte("first-password\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("BETFAIR_APP_KEY", "test-app-key")
+	t.Setenv("BETFAIR_SESSION_TOKEN", "test-session-token")
+	t.Setenv("BETFAIR_PASSWORD", "")
+	t.Setenv("BETFAIR_PASSWORD_SECRET", "file://"+path)
+	t.Setenv("MARKET_IDS", "1.100")
+
+	cfg := NewConfig()
+	if err := cfg.LoadFromEnv(); err != nil {
+		t.Fatalf("LoadFromEnv: %v", err)
+	}
+	if cfg.password != "first-password" || cfg.PasswordSecret == nil {
+		t.Fatalf("expected the password from the secret file, got %q", cfg.password)
+	}
+
+	var rotated []string
+	watcher := NewSecretWatcher(time.Minute, zerolog.Nop())
+	watcher.Watch("BETFAIR_PASSWORD", cfg.PasswordSecret, cfg.password, func(value string) { rotated = append(rotated, value) })
+
+	watcher.Check(context.Background())
+	if len(rotated) != 0 {
+		t.Fatalf("expected no rotation for an unchanged secret, got %v", rotated)
+	}
+	if err := os.WriteFile(path, []byte("second-password\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	watcher.Check(context.Background())
+	watcher.Check(context.Background())
+	if len(rotated) != 1 || rotated[0] != "second-password" {
+		t.Errorf("expected one rotation to the new password, got %v", rotated)
+	}
+
+	t.Setenv("BETFAIR_PASSWORD_SECRET", "file://"+filepath.Join(t.TempDir(), "missing"))
+	if err := NewConfig().LoadFromEnv(); err == nil || !strings.Contains(err.Error(), "BETFAIR_PASSWORD_SECRET") {
+		t.Errorf("expected an unreadable secret to be reported, got %v", err)
+	}
+}
+
+func TestSignAWSRequestKnownAnswer(t *testing.T) {
+	// post-vanilla from the AWS Signature Version 4 test suite
+	req, err := http.NewRequest(http.MethodPost, "https://example.amazonaws.com/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	credentials := aws.Credentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}
+	now := time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+
+	if err := signAWSRequest(context.Background(), req, nil, credentials, "us-east-1", "service", now); err != nil {
+		t.Fatalf("signAWSRequest: %v", err)
+	}
+
+	want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=5da7c1a2acd57cee7505fc6676e4e544621c30862966e37dddb68e92efbe5d6b"
+	if got := req.Header.Get("Authorization"); got != want {
+		t.Errorf("unexpected signature:\n got %s\nwant %s", got, want)
+	}
+	if got := req.Header.Get("X-Amz-Date"); got != "20150830T123600Z" {
+		t.Errorf("unexpected X-Amz-Date %q", got)
+	}
+}
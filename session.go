@@ -0,0 +1,151 @@
+package betfair
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const DefaultKeepAliveInterval = 30 * time.Minute
+
+// SessionManager keeps the session token in a TokenStore alive, falling back to a fresh login
+// when keepAlive fails
+type SessionManager struct {
+	appKey        string
+	authenticator *Authenticator
+	interval      time.Duration
+	keepAliveURL  string
+	httpClient    *http.Client
+	logger        zerolog.Logger
+	tokens        *TokenStore
+}
+
+func NewSessionManager(appKey string, tokens *TokenStore, auth *Authenticator, interval time.Duration, logger zerolog.Logger) *SessionManager {
+	if interval <= 0 {
+		interval = DefaultKeepAliveInterval
+	}
+	return &SessionManager{
+		appKey:        appKey,
+		authenticator: auth,
+		interval:      interval,
+		keepAliveURL:  DefaultEndpoints().KeepAlive,
+		httpClient:    &http.Client{Timeout: 10 * time.Second},
+		logger:        logger,
+		tokens:        tokens,
+	}
+}
+
+// SetEndpoints switches keepAlive calls to a jurisdiction's identity SSO host
+func (m *SessionManager) SetEndpoints(endpoints Endpoints) {
+	m.keepAliveURL = endpoints.KeepAlive
+}
+
+// SetPassword hands a rotated password to the authenticator used when keepAlive fails
+func (m *SessionManager) SetPassword(password string) {
+	if m.authenticator != nil {
+		m.authenticator.SetPassword(password)
+	}
+}
+
+// OnTokenRefresh registers fn to be called with the new token whenever it changes
+func (m *SessionManager) OnTokenRefresh(fn func(token string)) {
+	m.tokens.Subscribe(fn)
+}
+
+// Token returns the current session token
+func (m *SessionManager) Token() string {
+	return m.tokens.Get()
+}
+
+// Run calls keepAlive every interval until ctx is cancelled
+func (m *SessionManager) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	m.logger.Info().Dur("interval", m.interval).Msg("session keep-alive started")
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := m.Refresh(ctx); err != nil {
+				m.logger.Error().Err(err).Msg("failed to keep session alive")
+			}
+		}
+	}
+}
+
+// Refresh extends the current session, logging in again if the session can no longer be extended
+func (m *SessionManager) Refresh(ctx context.Context) error {
+	err := m.KeepAlive(ctx)
+	if err == nil {
+		m.logger.Debug().Msg("session keep-alive succeeded")
+		return nil
+	}
+
+	m.logger.Warn().Err(err).Msg("keep-alive failed, logging in again")
+	if m.authenticator == nil {
+		return fmt.Errorf("keep alive: %w", err)
+	}
+
+	token, loginErr := m.authenticator.Login()
+	if loginErr != nil {
+		return fmt.Errorf("re-login after keep-alive failure: %w", loginErr)
+	}
+
+	m.tokens.Set(token)
+	m.logger.Info().Msg("obtained new session token after keep-alive failure")
+	return nil
+}
+
+// KeepAlive extends the current session via the identity SSO keepAlive endpoint
+func (m *SessionManager) KeepAlive(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.keepAliveURL, nil)
+	if err != nil {
+		return fmt.Errorf("create keep alive request: %w", err)
+	}
+
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("X-Application", m.appKey)
+	req.Header.Set("X-Authentication", m.Token())
+
+	resp, err := m.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("perform keep alive request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("read keep alive response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("keep alive failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var kr struct {
+		Token  string `json:"token"`
+		Status string `json:"status"`
+		Error  string `json:"error"`
+	}
+	if err := json.Unmarshal(body, &kr); err != nil {
+		return fmt.Errorf("decode keep alive response: %w (body=%s)", err, strings.TrimSpace(string(body)))
+	}
+
+	if strings.ToUpper(kr.Status) != "SUCCESS" {
+		return fmt.Errorf("keep alive %s: %s", firstNonEmpty(kr.Status, "FAIL"), firstNonEmpty(kr.Error, "unknown error"))
+	}
+
+	if kr.Token != "" {
+		m.tokens.Set(kr.Token)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package betfair
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// SessionLogSuffix names the per-run file holding heartbeats and connection events
+const SessionLogSuffix = "_session.log"
+
+// SessionLog is a debug log of heartbeats, connection and status messages plus local connection
+// events, each stamped with the local receive time, for diagnosing gaps in recordings.
+// A nil *SessionLog discards everything.
+type SessionLog struct {
+	mu     sync.Mutex
+	file   *os.File
+	writer *bufio.Writer
+	now    func() time.Time
+}
+
+type sessionLogEntry struct {
+	Received time.Time       `json:"recv"`
+	Event    string          `json:"event,omitempty"`
+	Error    string          `json:"error,omitempty"`
+	Message  json.RawMessage `json:"msg,omitempty"`
+}
+
+// OpenSessionLog creates the session log for a run started at the given time
+func OpenSessionLog(dir string, started time.Time) (*SessionLog, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("create session log directory: %w", err)
+	}
+
+	path := filepath.Join(dir, started.UTC().Format("20060102T150405Z")+SessionLogSuffix)
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("open session log: %w", err)
+	}
+
+	return &SessionLog{
+		file:   file,
+		writer: bufio.NewWriter(file),
+		now:    time.Now,
+	}, nil
+}
+
+// Path returns the session log's file path
+func (l *SessionLog) Path() string {
+	if l == nil {
+		return ""
+	}
+	return l.file.Name()
+}
+
+// Record logs a stream message if it is a heartbeat, connection or status message
+func (l *SessionLog) Record(payload []byte) {
+	if l == nil {
+		return
+	}
+	if op := ExtractOp(payload); op == "mcm" && ExtractChangeType(payload) != "HEARTBEAT" {
+		return
+	}
+	l.write(sessionLogEntry{Message: json.RawMessage(payload)})
+}
+
+// Event logs a local connection event such as a dial, subscription or disconnect
+func (l *SessionLog) Event(event string, err error) {
+	if l == nil {
+		return
+	}
+	entry := sessionLogEntry{Event: event}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	l.write(entry)
+}
+
+func (l *SessionLog) write(entry sessionLogEntry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry.Received = l.now().UTC()
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	l.writer.Write(append(line, '\n'))
+	l.writer.Flush()
+}
+
+func (l *SessionLog) Close() error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := l.writer.Flush(); err != nil {
+		l.file.Close()
+		return err
+	}
+	return l.file.Close()
+}
@@ -0,0 +1,78 @@
+package betfair
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSessionLogRecordsHeartbeatsAndConnectionEvents(t *testing.T) {
+	tempDir := t.TempDir()
+	started := time.Date(2025, 10, 1, 9, 30, 0, 0, time.UTC)
+
+	sessionLog, err := OpenSessionLog(tempDir, started)
+	if err != nil {
+		t.Fatalf("OpenSessionLog failed: %v", err)
+	}
+	if sessionLog.Path() != filepath.Join(tempDir, "20251001T093000Z"+SessionLogSuffix) {
+		t.Errorf("Unexpected session log path %s", sessionLog.Path())
+	}
+
+	messages := strings.Join([]string{
+		`{"op":"connection","connectionId":"abc-123"}`,
+		`{"op":"status","id":1,"statusCode":"SUCCESS"}`,
+		`{"op":"mcm","clk":"1","mc":[{"id":"1.1","rc":[]}]}`,
+		`{"op":"mcm","ct":"HEARTBEAT","clk":"2"}`,
+	}, "\n") + "\n"
+	stream := &StreamConn{reader: bufio.NewReader(strings.NewReader(messages))}
+	stream.SetTap(sessionLog.Record)
+
+	sessionLog.Event("connected", nil)
+	for i := 0; i < 4; i++ {
+		if _, err := stream.ReadMessage(); err != nil {
+			t.Fatalf("ReadMessage failed: %v", err)
+		}
+	}
+	sessionLog.Event("disconnected", errors.New("connection reset"))
+
+	if err := sessionLog.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	content, err := os.ReadFile(sessionLog.Path())
+	if err != nil {
+		t.Fatalf("Failed to read session log: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("Expected 2 events and 3 messages without market data, got %d:\n%s", len(lines), content)
+	}
+
+	var last struct {
+		Received time.Time `json:"recv"`
+		Event    string    `json:"event"`
+		Error    string    `json:"error"`
+	}
+	if err := json.Unmarshal([]byte(lines[4]), &last); err != nil {
+		t.Fatalf("Failed to decode entry: %v", err)
+	}
+	if last.Event != "disconnected" || last.Error != "connection reset" || last.Received.IsZero() {
+		t.Errorf("Unexpected disconnect entry: %s", lines[4])
+	}
+	if strings.Contains(string(content), `"rc"`) {
+		t.Error("Expected market data to be left out of the session log")
+	}
+
+	// A nil session log is a no-op
+	var disabled *SessionLog
+	disabled.Record([]byte(`{"op":"connection"}`))
+	disabled.Event("connected", nil)
+	if err := disabled.Close(); err != nil {
+		t.Errorf("Expected nil session log to close cleanly: %v", err)
+	}
+}
@@ -0,0 +1,87 @@
+package betfair
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestSessionManagerKeepAlive(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Authentication") != "old-token" {
+			t.Errorf("Expected current token in request, got %q", r.Header.Get("X-Authentication"))
+		}
+		w.Write([]byte(`{"token":"old-token","product":"app","status":"SUCCESS","error":""}`))
+	}))
+	defer server.Close()
+
+	manager := NewSessionManager("app-key", NewTokenStore("old-token"), nil, 0, zerolog.New(zerolog.NewTestWriter(t)))
+	manager.SetEndpoints(Endpoints{KeepAlive: server.URL})
+
+	notified := false
+	manager.OnTokenRefresh(func(token string) { notified = true })
+
+	if err := manager.Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+	if notified {
+		t.Error("Listeners should not be notified when the token is unchanged")
+	}
+	if manager.Token() != "old-token" {
+		t.Errorf("Expected token to be unchanged, got %s", manager.Token())
+	}
+}
+
+func TestSessionManagerReloginOnFailure(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/keepAlive", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"token":"","status":"FAIL","error":"NO_SESSION"}`))
+	})
+	mux.HandleFunc("/api/login", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"token":"new-token","status":"SUCCESS"}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	endpoints := Endpoints{
+		InteractiveLogin: server.URL + "/api/login",
+		KeepAlive:        server.URL + "/api/keepAlive",
+	}
+
+	auth := NewAuthenticator("app-key", "user", "pass")
+	auth.SetEndpoints(endpoints)
+
+	manager := NewSessionManager("app-key", NewTokenStore("expired-token"), auth, 0, zerolog.New(zerolog.NewTestWriter(t)))
+	manager.SetEndpoints(endpoints)
+
+	var received []string
+	manager.OnTokenRefresh(func(token string) { received = append(received, token) })
+
+	if err := manager.Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+
+	if manager.Token() != "new-token" {
+		t.Errorf("Expected new token after re-login, got %s", manager.Token())
+	}
+	if len(received) != 1 || received[0] != "new-token" {
+		t.Errorf("Expected listeners to receive new token, got %v", received)
+	}
+}
+
+func TestSessionManagerFailureWithoutAuthenticator(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	manager := NewSessionManager("app-key", NewTokenStore("token"), nil, 0, zerolog.New(zerolog.NewTestWriter(t)))
+	manager.SetEndpoints(Endpoints{KeepAlive: server.URL})
+
+	if err := manager.Refresh(context.Background()); err == nil {
+		t.Error("Expected error when keep-alive fails and no authenticator is available")
+	}
+}
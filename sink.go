@@ -0,0 +1,41 @@
+package betfair
+
+import (
+	"context"
+)
+
+// Sink receives every recorded market message alongside the market files, letting callers feed
+// recordings into a database or message queue. WriteMessage is called from the stream reader in
+// stream order; Finalize runs once per market on a background goroutine, so it may overlap with
+// WriteMessage calls for other markets.
+type Sink interface {
+	// WriteMessage receives a single-market stream message exactly as written to the market file
+	WriteMessage(marketID string, payload []byte) error
+	// Finalize is called once a market has settled, or with incomplete set when it was given up
+	// on after going idle. settlement is the last message carrying the market definition.
+	Finalize(ctx context.Context, marketID string, settlement []byte, incomplete bool) error
+}
+
+// AddSink registers a sink that receives every recorded message
+func (r *MarketRecorder) AddSink(sink Sink) {
+	r.sinks = append(r.sinks, sink)
+	for _, profile := range r.profiles {
+		profile.AddSink(sink)
+	}
+}
+
+func (r *MarketRecorder) writeToSinks(marketID string, payload []byte) {
+	for _, sink := range r.sinks {
+		if err := sink.WriteMessage(marketID, payload); err != nil {
+			r.logger.Error().Err(err).Str("market_id", marketID).Msg("sink failed to write message")
+		}
+	}
+}
+
+func (r *MarketRecorder) finalizeSinks(ctx context.Context, marketID string, settlement []byte, incomplete bool) {
+	for _, sink := range r.sinks {
+		if err := sink.Finalize(ctx, marketID, settlement, incomplete); err != nil {
+			r.logger.Error().Err(err).Str("market_id", marketID).Msg("sink failed to finalize market")
+		}
+	}
+}
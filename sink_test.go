@@ -0,0 +1,78 @@
+package betfair
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+type memorySink struct {
+	mu        sync.Mutex
+	messages  map[string][]string
+	finalized map[string]bool
+}
+
+func (s *memorySink) WriteMessage(marketID string, payload []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.messages == nil {
+		s.messages = make(map[string][]string)
+	}
+	s.messages[marketID] = append(s.messages[marketID], string(payload))
+	return nil
+}
+
+func (s *memorySink) Finalize(ctx context.Context, marketID string, settlement []byte, incomplete bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.finalized == nil {
+		s.finalized = make(map[string]bool)
+	}
+	s.finalized[marketID] = incomplete
+	return nil
+}
+
+func TestMarketRecorderSink(t *testing.T) {
+	tempDir := t.TempDir()
+	recorder := &MarketRecorder{
+		config:           &Config{EnrichmentMode: EnrichmentOff},
+		logger:           zerolog.New(zerolog.NewTestWriter(t)),
+		fileManager:      NewFileManager(tempDir),
+		marketCatalogues: make(map[string]*MarketCatalogue),
+	}
+	sink := &memorySink{}
+	recorder.AddSink(sink)
+
+	messages := []string{
+		`{"op":"mcm","pt":1,"mc":[{"id":"1.1","marketDefinition":{"status":"OPEN","eventId":"100","marketTime":"2025-10-01T12:00:00.000Z"}}]}`,
+		`{"op":"mcm","pt":2,"mc":[{"id":"1.1","rc":[{"id":7,"ltp":3.5}]}]}`,
+		`{"op":"mcm","pt":3,"mc":[{"id":"1.1","marketDefinition":{"status":"CLOSED","eventId":"100","marketTime":"2025-10-01T12:00:00.000Z"}}]}`,
+	}
+	stream := &StreamConn{reader: bufio.NewReader(strings.NewReader(strings.Join(messages, "\n") + "\n"))}
+	writers := make(map[string]*bufio.Writer)
+	files := make(map[string]*os.File)
+	statuses := make(map[string]string)
+	for range messages {
+		if err := recorder.readMessage(context.Background(), stream, writers, files, statuses); err != nil {
+			t.Fatalf("readMessage failed: %v", err)
+		}
+	}
+	recorder.stopMarketWriters()
+
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	if len(sink.messages["1.1"]) != 3 {
+		t.Fatalf("Expected sink to receive 3 messages, got %v", sink.messages["1.1"])
+	}
+	if !strings.Contains(sink.messages["1.1"][1], `"ltp":3.5`) {
+		t.Errorf("Expected sink to receive the message as written to file, got %s", sink.messages["1.1"][1])
+	}
+	if incomplete, ok := sink.finalized["1.1"]; !ok || incomplete {
+		t.Errorf("Expected settled market to be finalized as complete, got %v %v", incomplete, ok)
+	}
+}
@@ -0,0 +1,80 @@
+package betfair
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultStartWindowDiscoveryInterval is how often markets are discovered when a start window is
+// set without DISCOVERY_INTERVAL_SECONDS, as the stream can't filter markets by start time
+const DefaultStartWindowDiscoveryInterval = time.Minute
+
+// StartTimeBound is one end of a market start window: an offset from now, such as -30m or 2h, or
+// a fixed time
+type StartTimeBound struct {
+	Offset time.Duration
+	At     time.Time
+	set    bool
+}
+
+// ParseStartTimeBound parses a Go duration relative to now, an RFC 3339 time or a YYYY-MM-DD day
+// (midnight UTC). An empty value is an unset bound.
+func ParseStartTimeBound(value string) (StartTimeBound, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return StartTimeBound{}, nil
+	}
+	if offset, err := time.ParseDuration(value); err == nil {
+		return StartTimeBound{Offset: offset, set: true}, nil
+	}
+	if at, err := time.Parse(time.RFC3339, value); err == nil {
+		return StartTimeBound{At: at, set: true}, nil
+	}
+	if at, err := time.Parse(time.DateOnly, value); err == nil {
+		return StartTimeBound{At: at, set: true}, nil
+	}
+	return StartTimeBound{}, fmt.Errorf("unknown start time %q, want a duration such as -30m or an RFC 3339 time", value)
+}
+
+// IsSet reports whether the bound limits the window
+func (b StartTimeBound) IsSet() bool {
+	return b.set
+}
+
+// Resolve returns the bound's time as of now
+func (b StartTimeBound) Resolve(now time.Time) time.Time {
+	if !b.At.IsZero() {
+		return b.At
+	}
+	return now.Add(b.Offset)
+}
+
+// StartWindow limits recorded markets to those starting between two bounds, either of which may
+// be unset. Relative bounds roll forward with the clock.
+type StartWindow struct {
+	From StartTimeBound
+	To   StartTimeBound
+}
+
+// IsSet reports whether either end of the window is set
+func (w StartWindow) IsSet() bool {
+	return w.From.IsSet() || w.To.IsSet()
+}
+
+// Range returns the window as of now for a market filter, or nil when the window is unset
+func (w StartWindow) Range(now time.Time) *TimeRange {
+	if !w.IsSet() {
+		return nil
+	}
+	window := &TimeRange{}
+	if w.From.IsSet() {
+		from := w.From.Resolve(now)
+		window.From = &from
+	}
+	if w.To.IsSet() {
+		to := w.To.Resolve(now)
+		window.To = &to
+	}
+	return window
+}
@@ -0,0 +1,124 @@
+package betfair
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func TestParseStartTimeBound(t *testing.T) {
+	now := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Time
+	}{
+		{"-30m", now.Add(-30 * time.Minute)},
+		{"2h", now.Add(2 * time.Hour)},
+		{"2025-10-02T08:00:00+10:00", time.Date(2025, 10, 1, 22, 0, 0, 0, time.UTC)},
+		{"2025-10-03", time.Date(2025, 10, 3, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		bound, err := ParseStartTimeBound(tt.value)
+		if err != nil {
+			t.Fatalf("ParseStartTimeBound(%q): %v", tt.value, err)
+		}
+		if got := bound.Resolve(now); !got.Equal(tt.want) {
+			t.Errorf("ParseStartTimeBound(%q) resolved to %v, want %v", tt.value, got, tt.want)
+		}
+	}
+
+	if bound, err := ParseStartTimeBound(""); err != nil || bound.IsSet() {
+		t.Errorf("expected an empty value to be an unset bound, got %+v, %v", bound, err)
+	}
+	if _, err := ParseStartTimeBound("tomorrow"); err == nil {
+		t.Errorf("expected an error for an unknown start time")
+	}
+}
+
+func TestMarketDiscovererUsesStartWindow(t *testing.T) {
+	now := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	var lastFilter map[string]interface{}
+	client := newTestRESTClient(t, func(method string, params map[string]interface{}) interface{} {
+		lastFilter = params["filter"].(map[string]interface{})
+		return []map[string]interface{}{}
+	})
+
+	discoverer := NewMarketDiscoverer(client, MarketFilter{EventTypeIds: []string{"4339"}}, time.Minute, time.Hour, zerolog.Nop())
+	discoverer.now = func() time.Time { return now }
+	from, _ := ParseStartTimeBound("-15m")
+	to, _ := ParseStartTimeBound("3h")
+	discoverer.SetStartWindow(StartWindow{From: from, To: to})
+
+	if _, err := discoverer.Discover(context.Background()); err != nil {
+		t.Fatalf("Discover failed: %v", err)
+	}
+	startTime := lastFilter["marketStartTime"].(map[string]interface{})
+	if startTime["from"] != now.Add(-15*time.Minute).Format(time.RFC3339) || startTime["to"] != now.Add(3*time.Hour).Format(time.RFC3339) {
+		t.Errorf("expected the start window to replace the lookahead, got %v", startTime)
+	}
+
+	// The window rolls forward with the clock
+	now = now.Add(time.Hour)
+	if _, err := discoverer.Discover(context.Background()); err != nil {
+		t.Fatalf("Discover failed: %v", err)
+	}
+	startTime = lastFilter["marketStartTime"].(map[string]interface{})
+	if startTime["from"] != now.Add(-15*time.Minute).Format(time.RFC3339) {
+		t.Errorf("expected the window to roll forward, got %v", startTime)
+	}
+}
+
+func TestStartWindowNeverSubscribesByEventType(t *testing.T) {
+	client := newTestRESTClient(t, func(method string, params map[string]interface{}) interface{} {
+		return []map[string]interface{}{}
+	})
+	from, _ := ParseStartTimeBound("-15m")
+	to, _ := ParseStartTimeBound("1h")
+	window := StartWindow{From: from, To: to}
+
+	cfg := &Config{EventTypeID: "4339", MarketStartWindow: window}
+	discoverer := NewMarketDiscoverer(client, cfg.GetMarketFilter(), time.Minute, time.Hour, zerolog.Nop())
+	discoverer.SetStartWindow(window)
+
+	var sent strings.Builder
+	recorder := &MarketRecorder{
+		config:        cfg,
+		logger:        zerolog.New(zerolog.NewTestWriter(t)),
+		streamClient:  NewStreamClient("app-key", "token", 500, zerolog.Nop(), nil),
+		currentStream: &StreamConn{writer: bufio.NewWriter(&sent)},
+		discoverer:    discoverer,
+	}
+
+	if _, err := discoverer.Discover(context.Background()); err != nil {
+		t.Fatalf("Discover failed: %v", err)
+	}
+	if err := recorder.ReloadFilter(context.Background(), FilterUpdate{EventTypeID: "7"}); err != nil {
+		t.Fatalf("ReloadFilter failed: %v", err)
+	}
+
+	var sub struct {
+		MarketFilter map[string]json.RawMessage `json:"marketFilter"`
+	}
+	if err := json.Unmarshal([]byte(sent.String()), &sub); err != nil {
+		t.Fatalf("Failed to decode subscription %q: %v", sent.String(), err)
+	}
+	if _, ok := sub.MarketFilter["eventTypeIds"]; ok {
+		t.Errorf("Expected no event type subscription with a start window, got %s", sent.String())
+	}
+	if string(sub.MarketFilter["marketIds"]) != "[]" {
+		t.Errorf("Expected an empty market ID list, got %s", sent.String())
+	}
+
+	// Without discovery a start window cannot be honoured, so the reload is refused
+	recorder.discoverer = nil
+	if err := recorder.ReloadFilter(context.Background(), FilterUpdate{EventTypeID: "7"}); !errors.Is(err, errReloadNeedsDiscovery) {
+		t.Errorf("Expected a reload without discovery to be refused, got %v", err)
+	}
+}
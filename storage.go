@@ -2,19 +2,236 @@ package betfair
 
 import (
 	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
+	"io"
+	"net/url"
 	"os"
-	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+const defaultBasePath = "raw_greyhounds_data"
+
+// ObjectStorage is a bucket that recorded market files are uploaded to and read back from
+type ObjectStorage interface {
+	Upload(ctx context.Context, filePath, key string) error
+	Download(ctx context.Context, key string) (io.ReadCloser, error)
+	List(ctx context.Context, prefix string) ([]ObjectInfo, error)
+	Head(ctx context.Context, key string) (ObjectInfo, error)
+	// BuildKey returns the object key for a file belonging to an event
+	BuildKey(eventInfo *EventInfo, filename string) string
+}
+
+// ObjectInfo describes a stored object. MD5 is hex encoded and empty when the backend does not
+// expose a plain MD5 for the object. ETag, without quotes, changes whenever the object does.
+type ObjectInfo struct {
+	Key  string
+	Size int64
+	MD5  string
+	ETag string
+}
+
+// NewObjectStorage opens the bucket named by location: gs://bucket for Google Cloud Storage,
+// az://container or https://account.blob.core.windows.net/container for Azure Blob Storage, and
+// s3://bucket or a bare bucket name for S3. A path after the bucket is used when basePath is empty.
+func NewObjectStorage(ctx context.Context, location, basePath string) (ObjectStorage, error) {
+	parsed, err := ParseStorageLocation(location)
+	if err != nil {
+		return nil, err
+	}
+	if basePath == "" {
+		basePath = parsed.Path
+	}
+
+	switch parsed.Scheme {
+	case "gs":
+		return NewGCSStorage(ctx, parsed.Bucket, basePath)
+	case "az":
+		return NewAzureStorage(ctx, parsed.Account, parsed.Bucket, basePath)
+	default:
+		return NewS3Storage(ctx, parsed.Bucket, basePath)
+	}
+}
+
+// StorageLocation is a parsed storage URL. Scheme is "s3", "gs" or "az"; Account is the Azure
+// storage account when the URL names one.
+type StorageLocation struct {
+	Scheme  string
+	Account string
+	Bucket  string
+	Path    string
+}
+
+// azureBlobHostSuffix identifies Azure Blob Storage https URLs
+const azureBlobHostSuffix = ".blob.core.windows.net"
+
+// IsStorageURL reports whether path refers to object storage rather than the local filesystem
+func IsStorageURL(path string) bool {
+	for _, prefix := range []string{"s3://", "gs://", "az://"} {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	if parsed, err := url.Parse(path); err == nil && parsed.Scheme == "https" {
+		return strings.HasSuffix(parsed.Host, azureBlobHostSuffix)
+	}
+	return false
+}
+
+// ParseStorageLocation splits a storage location into its scheme, bucket and path. A location
+// without a scheme is an S3 bucket name.
+func ParseStorageLocation(location string) (StorageLocation, error) {
+	location = strings.TrimSpace(location)
+	if !strings.Contains(location, "://") {
+		return StorageLocation{Scheme: "s3", Bucket: location}, nil
+	}
+
+	parsed, err := url.Parse(location)
+	if err != nil {
+		return StorageLocation{}, fmt.Errorf("parse storage URL: %w", err)
+	}
+	result := StorageLocation{Scheme: strings.ToLower(parsed.Scheme), Bucket: parsed.Host}
+	path := strings.Trim(parsed.Path, "/")
+
+	switch result.Scheme {
+	case "s3", "gs", "az":
+	case "https":
+		if !strings.HasSuffix(parsed.Host, azureBlobHostSuffix) {
+			return StorageLocation{}, fmt.Errorf("unsupported storage host %q", parsed.Host)
+		}
+		// https://account.blob.core.windows.net/container/path
+		result.Scheme = "az"
+		result.Account = strings.TrimSuffix(parsed.Host, azureBlobHostSuffix)
+		result.Bucket, path, _ = strings.Cut(path, "/")
+	default:
+		return StorageLocation{}, fmt.Errorf("unsupported storage scheme %q", parsed.Scheme)
+	}
+
+	if result.Bucket == "" {
+		return StorageLocation{}, fmt.Errorf("storage URL %q has no bucket", location)
+	}
+	result.Path = path
+	return result, nil
+}
+
+// ObjectURL returns the URL of an object in the location's bucket, in the same form as the
+// location itself
+func (l StorageLocation) ObjectURL(key string) string {
+	if l.Scheme == "az" && l.Account != "" {
+		return "https://" + l.Account + azureBlobHostSuffix + "/" + l.Bucket + "/" + key
+	}
+	return l.Scheme + "://" + l.Bucket + "/" + key
+}
+
+// buildObjectKey lays out an event's files under basePath in the event's layout version, by
+// default the way Betfair's historical data is organised. Keys are always joined with "/",
+// whatever the local path separator.
+func buildObjectKey(basePath string, eventInfo *EventInfo, filename string) string {
+	if basePath == "" {
+		basePath = defaultBasePath
+	}
+	segments := append([]string{basePath}, eventPathSegments(eventInfo)...)
+	return NormalizeKey(strings.Join(append(segments, filename), "/"))
+}
+
+// NormalizeKey cleans an object key or prefix: backslashes become "/", leading slashes are removed
+// and empty or "." segments are dropped. A trailing "/" is kept so prefixes still name a folder.
+func NormalizeKey(key string) string {
+	key = strings.ReplaceAll(key, `\`, "/")
+	segments := strings.Split(key, "/")
+	kept := segments[:0]
+	for _, segment := range segments {
+		if segment != "" && segment != "." {
+			kept = append(kept, segment)
+		}
+	}
+
+	normalized := strings.Join(kept, "/")
+	if normalized != "" && strings.HasSuffix(key, "/") {
+		normalized += "/"
+	}
+	return normalized
+}
+
+// verifyObject compares a stored object with the size and hex MD5 of the local file it came from
+func verifyObject(info ObjectInfo, size int64, md5Hex string) error {
+	if info.Size != size {
+		return fmt.Errorf("size %d, expected %d: %w", info.Size, size, errVerificationMismatch)
+	}
+	if info.MD5 != "" && !strings.EqualFold(info.MD5, md5Hex) {
+		return fmt.Errorf("md5 %s, expected %s: %w", info.MD5, md5Hex, errVerificationMismatch)
+	}
+	return nil
+}
+
+const (
+	// s3PartSize is the multipart chunk size; files no larger than one part are sent with a single PutObject
+	s3PartSize = 16 << 20
+	// s3UploadConcurrency is how many parts of one file are uploaded at once
+	s3UploadConcurrency = 4
+	// s3MaxAttempts is how many times each request, including every part, is tried
+	s3MaxAttempts = 5
 )
 
 type S3Storage struct {
-	client   *s3.Client
-	bucket   string
-	basePath string
+	client      *s3.Client
+	bucket      string
+	basePath    string
+	partSize    int64
+	concurrency int
+	options     S3UploadOptions
+}
+
+// S3UploadOptions are applied to every object S3Storage uploads. Empty fields keep the bucket's defaults.
+type S3UploadOptions struct {
+	// ServerSideEncryption is AES256 (SSE-S3) or aws:kms (SSE-KMS)
+	ServerSideEncryption string
+	// KMSKeyID is the KMS key used with aws:kms; empty uses the account's default S3 key
+	KMSKeyID     string
+	StorageClass string
+}
+
+// ParseS3UploadOptions validates the encryption and storage class settings. sse accepts AES256
+// or sse-s3, and aws:kms or sse-kms; a KMS key on its own implies aws:kms.
+func ParseS3UploadOptions(sse, kmsKeyID, storageClass string) (S3UploadOptions, error) {
+	options := S3UploadOptions{KMSKeyID: strings.TrimSpace(kmsKeyID)}
+
+	switch strings.ToLower(strings.TrimSpace(sse)) {
+	case "", "none":
+		if options.KMSKeyID != "" {
+			options.ServerSideEncryption = string(types.ServerSideEncryptionAwsKms)
+		}
+	case "aes256", "sse-s3":
+		options.ServerSideEncryption = string(types.ServerSideEncryptionAes256)
+	case "aws:kms", "sse-kms", "kms":
+		options.ServerSideEncryption = string(types.ServerSideEncryptionAwsKms)
+	default:
+		return S3UploadOptions{}, fmt.Errorf("unknown server-side encryption %q", sse)
+	}
+	if options.KMSKeyID != "" && options.ServerSideEncryption != string(types.ServerSideEncryptionAwsKms) {
+		return S3UploadOptions{}, fmt.Errorf("KMS key requires aws:kms encryption, not %s", options.ServerSideEncryption)
+	}
+
+	if storageClass = strings.ToUpper(strings.TrimSpace(storageClass)); storageClass != "" {
+		known := false
+		for _, class := range types.StorageClass("").Values() {
+			known = known || string(class) == storageClass
+		}
+		if !known {
+			return S3UploadOptions{}, fmt.Errorf("unknown storage class %q", storageClass)
+		}
+		options.StorageClass = storageClass
+	}
+	return options, nil
 }
 
 func NewS3Storage(ctx context.Context, bucket, basePath string) (*S3Storage, error) {
@@ -22,29 +239,101 @@ func NewS3Storage(ctx context.Context, bucket, basePath string) (*S3Storage, err
 		return nil, fmt.Errorf("S3_BUCKET not configured")
 	}
 
-	awsCfg, err := config.LoadDefaultConfig(ctx)
+	client, err := NewS3Client(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewS3StorageWithClient(client, bucket, basePath), nil
+}
+
+// NewS3Client builds an S3 client from the default AWS configuration. S3_ENDPOINT points it at an
+// S3-compatible service such as MinIO or Cloudflare R2, S3_FORCE_PATH_STYLE puts the bucket in the
+// URL path instead of the host name, and S3_REGION overrides the configured region.
+func NewS3Client(ctx context.Context) (*s3.Client, error) {
+	var loadOptions []func(*config.LoadOptions) error
+	if region := strings.TrimSpace(getenv("S3_REGION")); region != "" {
+		loadOptions = append(loadOptions, config.WithRegion(region))
+	}
+	awsCfg, err := config.LoadDefaultConfig(ctx, loadOptions...)
 	if err != nil {
 		return nil, fmt.Errorf("load AWS config: %w", err)
 	}
 
+	endpoint := strings.TrimSpace(getenv("S3_ENDPOINT"))
+	if endpoint != "" && awsCfg.Region == "" {
+		// Requests are still signed for a region; S3-compatible services accept the default one
+		awsCfg.Region = "us-east-1"
+	}
+
+	return s3.NewFromConfig(awsCfg, func(o *s3.Options) {
+		if endpoint != "" {
+			o.BaseEndpoint = aws.String(endpoint)
+		}
+		if parsed, err := strconv.ParseBool(getenv("S3_FORCE_PATH_STYLE")); err == nil {
+			o.UsePathStyle = parsed
+		}
+		if awsCfg.RetryMaxAttempts == 0 {
+			o.RetryMaxAttempts = s3MaxAttempts
+		}
+	}), nil
+}
+
+// NewS3StorageWithClient uses an existing S3 client, such as one shared with other code
+func NewS3StorageWithClient(client *s3.Client, bucket, basePath string) *S3Storage {
 	return &S3Storage{
-		client:   s3.NewFromConfig(awsCfg),
-		bucket:   bucket,
-		basePath: basePath,
-	}, nil
+		client:      client,
+		bucket:      bucket,
+		basePath:    basePath,
+		partSize:    s3PartSize,
+		concurrency: s3UploadConcurrency,
+	}
+}
+
+// SetUploadOptions sets the encryption and storage class of uploaded objects
+func (s *S3Storage) SetUploadOptions(options S3UploadOptions) {
+	s.options = options
 }
 
+// Upload sends a file with PutObject, or as a concurrent multipart upload when it is larger than
+// one part. Failed requests are retried by the client.
 func (s *S3Storage) Upload(ctx context.Context, filePath, s3Key string) error {
+	return s.UploadWithTags(ctx, filePath, s3Key, nil)
+}
+
+// UploadWithTags uploads a file like Upload and tags the object
+func (s *S3Storage) UploadWithTags(ctx context.Context, filePath, s3Key string, tags map[string]string) error {
+	s3Key = NormalizeKey(s3Key)
 	file, err := os.Open(filePath)
 	if err != nil {
 		return fmt.Errorf("open file: %w", err)
 	}
 	defer file.Close()
 
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("stat file: %w", err)
+	}
+	if info.Size() > s.partSize {
+		if err := s.uploadMultipart(ctx, file, info.Size(), s3Key, tags); err != nil {
+			return fmt.Errorf("upload to S3: %w", err)
+		}
+		return nil
+	}
+
+	sum, err := contentMD5(file)
+	if err != nil {
+		return fmt.Errorf("hash file: %w", err)
+	}
 	_, err = s.client.PutObject(ctx, &s3.PutObjectInput{
-		Bucket: aws.String(s.bucket),
-		Key:    aws.String(s3Key),
-		Body:   file,
+		Bucket:               aws.String(s.bucket),
+		Key:                  aws.String(s3Key),
+		Body:                 file,
+		ContentMD5:           sum,
+		ServerSideEncryption: types.ServerSideEncryption(s.options.ServerSideEncryption),
+		SSEKMSKeyId:          optionalString(s.options.KMSKeyID),
+		StorageClass:         types.StorageClass(s.options.StorageClass),
+		Tagging:              encodeTags(tags),
 	})
 	if err != nil {
 		return fmt.Errorf("upload to S3: %w", err)
@@ -53,10 +342,241 @@ func (s *S3Storage) Upload(ctx context.Context, filePath, s3Key string) error {
 	return nil
 }
 
-func (s *S3Storage) BuildS3Key(eventInfo *EventInfo, filename string) string {
-	basePath := s.basePath
-	if basePath == "" {
-		basePath = "raw_greyhounds_data"
+// uploadMultipart uploads a file in parts, aborting the upload if any part fails so S3 does not
+// keep the parts already stored
+func (s *S3Storage) uploadMultipart(ctx context.Context, file *os.File, size int64, key string, tags map[string]string) error {
+	created, err := s.client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket:               aws.String(s.bucket),
+		Key:                  aws.String(key),
+		ServerSideEncryption: types.ServerSideEncryption(s.options.ServerSideEncryption),
+		SSEKMSKeyId:          optionalString(s.options.KMSKeyID),
+		StorageClass:         types.StorageClass(s.options.StorageClass),
+		Tagging:              encodeTags(tags),
+	})
+	if err != nil {
+		return fmt.Errorf("create multipart upload: %w", err)
+	}
+
+	parts, err := s.uploadParts(ctx, file, size, key, created.UploadId)
+	if err == nil {
+		_, err = s.client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+			Bucket:          aws.String(s.bucket),
+			Key:             aws.String(key),
+			UploadId:        created.UploadId,
+			MultipartUpload: &types.CompletedMultipartUpload{Parts: parts},
+		})
+		if err == nil {
+			return nil
+		}
+		err = fmt.Errorf("complete multipart upload: %w", err)
+	}
+
+	_, abortErr := s.client.AbortMultipartUpload(context.WithoutCancel(ctx), &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(s.bucket),
+		Key:      aws.String(key),
+		UploadId: created.UploadId,
+	})
+	if abortErr != nil {
+		return fmt.Errorf("%w (abort multipart upload: %v)", err, abortErr)
+	}
+	return err
+}
+
+// uploadParts uploads every part of a file with up to s.concurrency requests in flight, stopping
+// at the first failure
+func (s *S3Storage) uploadParts(ctx context.Context, file *os.File, size int64, key string, uploadID *string) ([]types.CompletedPart, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	count := int((size + s.partSize - 1) / s.partSize)
+	parts := make([]types.CompletedPart, count)
+	indexes := make(chan int)
+
+	var (
+		wg       sync.WaitGroup
+		failOnce sync.Once
+		failure  error
+	)
+	for i := 0; i < max(s.concurrency, 1); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				offset := int64(index) * s.partSize
+				length := min(s.partSize, size-offset)
+				number := aws.Int32(int32(index + 1))
+
+				part, err := s.uploadPart(ctx, io.NewSectionReader(file, offset, length), key, uploadID, number)
+				if err != nil {
+					failOnce.Do(func() {
+						failure = fmt.Errorf("upload part %d: %w", *number, err)
+						cancel()
+					})
+					continue
+				}
+				parts[index] = part
+			}
+		}()
+	}
+
+feed:
+	for index := 0; index < count; index++ {
+		select {
+		case indexes <- index:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(indexes)
+	wg.Wait()
+
+	if failure != nil {
+		return nil, failure
 	}
-	return filepath.Join(basePath, "PRO", eventInfo.Year, eventInfo.Month, eventInfo.Day, eventInfo.EventID, filename)
-}
\ No newline at end of file
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return parts, nil
+}
+
+// Download opens an object for reading; the caller closes it
+func (s *S3Storage) Download(ctx context.Context, key string) (io.ReadCloser, error) {
+	result, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(NormalizeKey(key)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get S3 object: %w", err)
+	}
+	return result.Body, nil
+}
+
+// List returns every object whose key starts with prefix
+func (s *S3Storage) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	var objects []ObjectInfo
+	paginator := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(NormalizeKey(prefix)),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("list S3 objects: %w", err)
+		}
+		for _, object := range page.Contents {
+			if object.Key == nil {
+				continue
+			}
+			objects = append(objects, ObjectInfo{
+				Key:  aws.ToString(object.Key),
+				Size: aws.ToInt64(object.Size),
+				MD5:  etagMD5(aws.ToString(object.ETag), types.ServerSideEncryption(s.options.ServerSideEncryption)),
+				ETag: strings.Trim(aws.ToString(object.ETag), `"`),
+			})
+		}
+	}
+	return objects, nil
+}
+
+func (s *S3Storage) Head(ctx context.Context, key string) (ObjectInfo, error) {
+	key = NormalizeKey(key)
+	head, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return ObjectInfo{}, fmt.Errorf("head S3 object: %w", err)
+	}
+	return ObjectInfo{
+		Key:  key,
+		Size: aws.ToInt64(head.ContentLength),
+		MD5:  etagMD5(aws.ToString(head.ETag), head.ServerSideEncryption),
+		ETag: strings.Trim(aws.ToString(head.ETag), `"`),
+	}, nil
+}
+
+// Verify checks the stored object's size, and its MD5 where the ETag is a plain MD5. SSE-KMS and
+// multipart objects have other ETags; their content was checked against the Content-MD5 sent
+// with each upload request instead.
+func (s *S3Storage) Verify(ctx context.Context, key string, size int64, md5Hex string) error {
+	info, err := s.Head(ctx, key)
+	if err != nil {
+		return err
+	}
+	return verifyObject(info, size, md5Hex)
+}
+
+// encodeTags formats object tags as the URL query string S3 expects, or nil when there are none
+func encodeTags(tags map[string]string) *string {
+	if len(tags) == 0 {
+		return nil
+	}
+	values := url.Values{}
+	for key, value := range tags {
+		values.Set(key, value)
+	}
+	return aws.String(values.Encode())
+}
+
+func optionalString(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return aws.String(value)
+}
+
+// uploadPart sends one part with its Content-MD5, so S3 rejects a part that arrives corrupted
+func (s *S3Storage) uploadPart(ctx context.Context, body *io.SectionReader, key string, uploadID *string, number *int32) (types.CompletedPart, error) {
+	sum, err := contentMD5(body)
+	if err != nil {
+		return types.CompletedPart{}, fmt.Errorf("hash part: %w", err)
+	}
+	result, err := s.client.UploadPart(ctx, &s3.UploadPartInput{
+		Bucket:        aws.String(s.bucket),
+		Key:           aws.String(key),
+		UploadId:      uploadID,
+		PartNumber:    number,
+		Body:          body,
+		ContentLength: aws.Int64(body.Size()),
+		ContentMD5:    sum,
+	})
+	if err != nil {
+		return types.CompletedPart{}, err
+	}
+	return types.CompletedPart{ETag: result.ETag, PartNumber: number}, nil
+}
+
+// contentMD5 returns the base64 MD5 of body for a Content-MD5 header, which S3 checks the
+// received data against, and rewinds body so it can be sent
+func contentMD5(body io.ReadSeeker) (*string, error) {
+	hash := md5.New()
+	if _, err := io.Copy(hash, body); err != nil {
+		return nil, err
+	}
+	if _, err := body.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return aws.String(base64.StdEncoding.EncodeToString(hash.Sum(nil))), nil
+}
+
+// etagMD5 returns the MD5 an S3 ETag carries, or "" when it isn't one: multipart ETags end in
+// -<parts>, and SSE-KMS objects have ETags that are not an MD5 of their content
+func etagMD5(etag string, sse types.ServerSideEncryption) string {
+	if strings.HasPrefix(string(sse), "aws:kms") {
+		return ""
+	}
+	etag = strings.Trim(etag, `"`)
+	if _, err := hex.DecodeString(etag); err != nil || len(etag) != 32 {
+		return ""
+	}
+	return etag
+}
+
+func (s *S3Storage) BuildKey(eventInfo *EventInfo, filename string) string {
+	return buildObjectKey(s.basePath, eventInfo, filename)
+}
+
+// BuildS3Key is BuildKey, kept for existing callers
+func (s *S3Storage) BuildS3Key(eventInfo *EventInfo, filename string) string {
+	return s.BuildKey(eventInfo, filename)
+}
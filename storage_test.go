@@ -1,8 +1,27 @@
 package betfair
 
 import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 )
 
 func TestS3StorageBuildS3Key(t *testing.T) {
@@ -47,11 +66,8 @@ func TestS3StorageBuildS3Key(t *testing.T) {
 			}
 
 			result := storage.BuildS3Key(eventInfo, tt.filename)
-			// Normalize path separators for cross-platform compatibility
-			result = filepath.ToSlash(result)
-			expected := filepath.ToSlash(tt.expected)
-			if result != expected {
-				t.Errorf("Expected '%s', got '%s'", expected, result)
+			if result != tt.expected {
+				t.Errorf("Expected '%s', got '%s'", tt.expected, result)
 			}
 		})
 	}
@@ -74,10 +90,6 @@ func TestS3StorageDefaultBasePath(t *testing.T) {
 	result := storage.BuildS3Key(eventInfo, "test.bz2")
 	expected := "raw_greyhounds_data/PRO/2024/Dec/31/12345/test.bz2"
 
-	// Normalize path separators for cross-platform compatibility
-	result = filepath.ToSlash(result)
-	expected = filepath.ToSlash(expected)
-
 	if result != expected {
 		t.Errorf("Expected default base path usage: '%s', got '%s'", expected, result)
 	}
@@ -122,11 +134,8 @@ func TestS3StorageKeyWithSpecialCharacters(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			result := storage.BuildS3Key(eventInfo, tt.filename)
-			// Normalize path separators for cross-platform compatibility
-			result = filepath.ToSlash(result)
-			expected := filepath.ToSlash(tt.expected)
-			if result != expected {
-				t.Errorf("Expected '%s', got '%s'", expected, result)
+			if result != tt.expected {
+				t.Errorf("Expected '%s', got '%s'", tt.expected, result)
 			}
 		})
 	}
@@ -158,10 +167,6 @@ func TestS3StorageKeyConsistency(t *testing.T) {
 	}
 
 	expectedPattern := "consistent-test/PRO/2025/Sep/26/34773181/1.248231892.bz2"
-	// Normalize path separators for cross-platform compatibility
-	key1 = filepath.ToSlash(key1)
-	expectedPattern = filepath.ToSlash(expectedPattern)
-
 	if key1 != expectedPattern {
 		t.Errorf("Expected pattern '%s', got '%s'", expectedPattern, key1)
 	}
@@ -218,11 +223,8 @@ func TestS3StorageKeyWithDifferentEventTypes(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			result := storage.BuildS3Key(tt.eventInfo, tt.filename)
-			// Normalize path separators for cross-platform compatibility
-			result = filepath.ToSlash(result)
-			expected := filepath.ToSlash(tt.expected)
-			if result != expected {
-				t.Errorf("Expected '%s', got '%s'", expected, result)
+			if result != tt.expected {
+				t.Errorf("Expected '%s', got '%s'", tt.expected, result)
 			}
 		})
 	}
@@ -285,11 +287,8 @@ func TestS3StorageKeyHierarchy(t *testing.T) {
 	}
 
 	result := storage.BuildS3Key(eventInfo, "test-file.bz2")
-	// Normalize path separators for cross-platform compatibility
-	result = filepath.ToSlash(result)
-
 	expectedParts := []string{"test", "hierarchy", "PRO", "2025", "Mar", "10", "987654321", "test-file.bz2"}
-	expectedKey := filepath.ToSlash(filepath.Join(expectedParts...))
+	expectedKey := strings.Join(expectedParts, "/")
 
 	if result != expectedKey {
 		t.Errorf("Expected hierarchical key '%s', got '%s'", expectedKey, result)
@@ -317,4 +316,412 @@ func findSubstring(s, substr string) bool {
 		}
 	}
 	return false
-}
\ No newline at end of file
+}
+
+func TestParseStorageLocation(t *testing.T) {
+	tests := []struct {
+		location string
+		expected StorageLocation
+		url      string
+		wantErr  bool
+	}{
+		{location: "my-bucket", expected: StorageLocation{Scheme: "s3", Bucket: "my-bucket"}, url: "s3://my-bucket/k"},
+		{location: "s3://my-bucket/raw/", expected: StorageLocation{Scheme: "s3", Bucket: "my-bucket", Path: "raw"}, url: "s3://my-bucket/k"},
+		{location: "gs://my-bucket", expected: StorageLocation{Scheme: "gs", Bucket: "my-bucket"}, url: "gs://my-bucket/k"},
+		{location: "az://markets/raw", expected: StorageLocation{Scheme: "az", Bucket: "markets", Path: "raw"}, url: "az://markets/k"},
+		{
+			location: "https://acct.blob.core.windows.net/markets/raw/2025",
+			expected: StorageLocation{Scheme: "az", Account: "acct", Bucket: "markets", Path: "raw/2025"},
+			url:      "https://acct.blob.core.windows.net/markets/k",
+		},
+		{location: "https://example.com/markets", wantErr: true},
+		{location: "ftp://my-bucket", wantErr: true},
+		{location: "gs:///raw", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseStorageLocation(tt.location)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("Expected %s to fail", tt.location)
+			}
+			continue
+		}
+		if err != nil || got != tt.expected {
+			t.Errorf("ParseStorageLocation(%s) = %+v, %v", tt.location, got, err)
+			continue
+		}
+		if url := got.ObjectURL("k"); url != tt.url {
+			t.Errorf("Expected object URL %s, got %s", tt.url, url)
+		}
+		if tt.location != "my-bucket" && !IsStorageURL(tt.location) {
+			t.Errorf("Expected %s to be a storage URL", tt.location)
+		}
+	}
+
+	if IsStorageURL("/var/data/2025/Oct/1") || IsStorageURL("https://example.com/file") {
+		t.Error("Expected local paths and other URLs not to be storage URLs")
+	}
+}
+
+func TestEtagMD5(t *testing.T) {
+	if got := etagMD5(`"5d41402abc4b2a76b9719d911017c592"`, ""); got != "5d41402abc4b2a76b9719d911017c592" {
+		t.Errorf("Expected single-part ETag to be an MD5, got %q", got)
+	}
+	if got := etagMD5(`"5d41402abc4b2a76b9719d911017c592-3"`, ""); got != "" {
+		t.Errorf("Expected multipart ETag to carry no MD5, got %q", got)
+	}
+	if got := etagMD5(`"0c2dd1b37a1bd4ab62bd8b5b4a1d6c7e"`, types.ServerSideEncryptionAwsKms); got != "" {
+		t.Errorf("Expected SSE-KMS ETag to carry no MD5, got %q", got)
+	}
+}
+
+func TestS3StorageCustomEndpoint(t *testing.T) {
+	var mu sync.Mutex
+	var requests []string
+	objects := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests = append(requests, r.Method+" "+r.Host+r.URL.Path)
+		if !strings.Contains(r.Header.Get("Authorization"), "/eu-central-1/s3/") {
+			http.Error(w, "wrong signing region", http.StatusForbidden)
+			return
+		}
+		switch r.Method {
+		case http.MethodPut:
+			data, _ := io.ReadAll(r.Body)
+			objects[r.URL.Path] = len(data)
+			w.Header().Set("ETag", `"5d41402abc4b2a76b9719d911017c592"`)
+		case http.MethodHead:
+			if _, ok := objects[r.URL.Path]; !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Header().Set("Content-Length", "11")
+			w.Header().Set("ETag", `"5d41402abc4b2a76b9719d911017c592"`)
+		default:
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	t.Setenv("AWS_ACCESS_KEY_ID", "minio")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "minio-secret")
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(t.TempDir(), "config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(t.TempDir(), "credentials"))
+	t.Setenv("S3_ENDPOINT", server.URL)
+	t.Setenv("S3_FORCE_PATH_STYLE", "true")
+	t.Setenv("S3_REGION", "eu-central-1")
+
+	storage, err := NewS3Storage(context.Background(), "markets", "")
+	if err != nil {
+		t.Fatalf("NewS3Storage failed: %v", err)
+	}
+
+	file := filepath.Join(t.TempDir(), "1.1.bz2")
+	if err := os.WriteFile(file, []byte("market data"), 0644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	if err := storage.Upload(context.Background(), file, "PRO/1.1.bz2"); err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+	info, err := storage.Head(context.Background(), "PRO/1.1.bz2")
+	if err != nil {
+		t.Fatalf("Head failed: %v", err)
+	}
+	if info.Size != 11 || info.MD5 != "5d41402abc4b2a76b9719d911017c592" || info.ETag != "5d41402abc4b2a76b9719d911017c592" {
+		t.Errorf("Unexpected object info %+v", info)
+	}
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	for _, request := range requests {
+		if !strings.HasSuffix(request, host+"/markets/PRO/1.1.bz2") {
+			t.Errorf("Expected a path-style request to the custom endpoint, got %s", request)
+		}
+	}
+}
+
+// fakeMultipartS3 serves S3's multipart upload API, rejecting parts whose Content-MD5 doesn't match
+// and failing the first attempt at each part listed in flaky and every attempt at each part listed in broken
+type fakeMultipartS3 struct {
+	mu        sync.Mutex
+	parts     map[string][]byte
+	attempts  map[string]int
+	flaky     map[string]bool
+	broken    map[string]bool
+	completed []byte
+	aborted   bool
+}
+
+func (f *fakeMultipartS3) serve(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	query := r.URL.Query()
+	switch {
+	case r.Method == http.MethodPost && query.Has("uploads"):
+		fmt.Fprint(w, `<InitiateMultipartUploadResult><Bucket>markets</Bucket><Key>big.bz2</Key><UploadId>upload-1</UploadId></InitiateMultipartUploadResult>`)
+
+	case r.Method == http.MethodPut && query.Get("uploadId") == "upload-1":
+		number := query.Get("partNumber")
+		f.attempts[number]++
+		if f.broken[number] || (f.flaky[number] && f.attempts[number] == 1) {
+			http.Error(w, "<Error><Code>InternalError</Code></Error>", http.StatusInternalServerError)
+			return
+		}
+		data, _ := io.ReadAll(r.Body)
+		if sum := md5.Sum(data); r.Header.Get("Content-Md5") != base64.StdEncoding.EncodeToString(sum[:]) {
+			http.Error(w, "<Error><Code>BadDigest</Code></Error>", http.StatusBadRequest)
+			return
+		}
+		f.parts[number] = data
+		w.Header().Set("ETag", `"etag-`+number+`"`)
+
+	case r.Method == http.MethodPost && query.Get("uploadId") == "upload-1":
+		body, _ := io.ReadAll(r.Body)
+		var assembled []byte
+		for number := 1; bytes.Contains(body, []byte(fmt.Sprintf("<PartNumber>%d</PartNumber>", number))); number++ {
+			assembled = append(assembled, f.parts[fmt.Sprint(number)]...)
+		}
+		f.completed = assembled
+		fmt.Fprint(w, `<CompleteMultipartUploadResult><Key>big.bz2</Key><ETag>"multipart-3"</ETag></CompleteMultipartUploadResult>`)
+
+	case r.Method == http.MethodDelete && query.Get("uploadId") == "upload-1":
+		f.aborted = true
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "unexpected request", http.StatusBadRequest)
+	}
+}
+
+func newMultipartTestStorage(t *testing.T, fake *fakeMultipartS3) *S3Storage {
+	server := httptest.NewServer(http.HandlerFunc(fake.serve))
+	t.Cleanup(server.Close)
+
+	client := s3.New(s3.Options{
+		BaseEndpoint: aws.String(server.URL),
+		UsePathStyle: true,
+		Region:       "us-east-1",
+		Credentials:  credentials.NewStaticCredentialsProvider("key", "secret", ""),
+		Retryer: retry.NewStandard(func(o *retry.StandardOptions) {
+			o.MaxAttempts = 3
+			o.Backoff = retry.BackoffDelayerFunc(func(int, error) (time.Duration, error) { return 0, nil })
+		}),
+	})
+	storage := NewS3StorageWithClient(client, "markets", "")
+	storage.partSize = 4
+	storage.concurrency = 2
+	return storage
+}
+
+func TestS3StorageMultipartUpload(t *testing.T) {
+	fake := &fakeMultipartS3{
+		parts:    make(map[string][]byte),
+		attempts: make(map[string]int),
+		flaky:    map[string]bool{"2": true},
+	}
+	storage := newMultipartTestStorage(t, fake)
+
+	file := filepath.Join(t.TempDir(), "big.bz2")
+	if err := os.WriteFile(file, []byte("market data"), 0644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	if err := storage.Upload(context.Background(), file, "big.bz2"); err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+
+	if len(fake.parts) != 3 {
+		t.Errorf("Expected 11 bytes in 3 parts of 4, got %d parts", len(fake.parts))
+	}
+	if string(fake.completed) != "market data" {
+		t.Errorf("Expected parts to reassemble the file, got %q", fake.completed)
+	}
+	if fake.attempts["2"] != 2 {
+		t.Errorf("Expected the failed part to be retried once, got %d attempts", fake.attempts["2"])
+	}
+	if fake.aborted {
+		t.Error("Expected a successful upload not to be aborted")
+	}
+}
+
+func TestS3StorageMultipartUploadAbortsOnFailure(t *testing.T) {
+	fake := &fakeMultipartS3{
+		parts:    make(map[string][]byte),
+		attempts: make(map[string]int),
+		broken:   map[string]bool{"3": true},
+	}
+	storage := newMultipartTestStorage(t, fake)
+
+	file := filepath.Join(t.TempDir(), "big.bz2")
+	if err := os.WriteFile(file, []byte("market data"), 0644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	err := storage.Upload(context.Background(), file, "big.bz2")
+	if err == nil || !strings.Contains(err.Error(), "upload part 3") {
+		t.Fatalf("Expected part 3 to fail the upload, got %v", err)
+	}
+	if fake.attempts["3"] != 3 {
+		t.Errorf("Expected the part to be tried 3 times, got %d", fake.attempts["3"])
+	}
+	if !fake.aborted || fake.completed != nil {
+		t.Error("Expected the upload to be aborted rather than completed")
+	}
+}
+
+func TestParseS3UploadOptions(t *testing.T) {
+	tests := []struct {
+		sse, kmsKeyID, storageClass string
+		expected                    S3UploadOptions
+		wantErr                     bool
+	}{
+		{expected: S3UploadOptions{}},
+		{sse: "sse-s3", storageClass: "standard_ia", expected: S3UploadOptions{ServerSideEncryption: "AES256", StorageClass: "STANDARD_IA"}},
+		{sse: "aws:kms", kmsKeyID: "alias/recorder", expected: S3UploadOptions{ServerSideEncryption: "aws:kms", KMSKeyID: "alias/recorder"}},
+		{kmsKeyID: "alias/recorder", expected: S3UploadOptions{ServerSideEncryption: "aws:kms", KMSKeyID: "alias/recorder"}},
+		{sse: "AES256", kmsKeyID: "alias/recorder", wantErr: true},
+		{sse: "rot13", wantErr: true},
+		{storageClass: "COLD", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseS3UploadOptions(tt.sse, tt.kmsKeyID, tt.storageClass)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("Expected %q/%q/%q to be rejected", tt.sse, tt.kmsKeyID, tt.storageClass)
+			}
+			continue
+		}
+		if err != nil || got != tt.expected {
+			t.Errorf("ParseS3UploadOptions(%q, %q, %q) = %+v, %v", tt.sse, tt.kmsKeyID, tt.storageClass, got, err)
+		}
+	}
+}
+
+func TestS3StorageUploadOptionsAndTags(t *testing.T) {
+	headers := make(chan http.Header, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		headers <- r.Header.Clone()
+	}))
+	defer server.Close()
+
+	client := s3.New(s3.Options{
+		BaseEndpoint: aws.String(server.URL),
+		UsePathStyle: true,
+		Region:       "us-east-1",
+		Credentials:  credentials.NewStaticCredentialsProvider("key", "secret", ""),
+	})
+	storage := NewS3StorageWithClient(client, "markets", "")
+	storage.SetUploadOptions(S3UploadOptions{ServerSideEncryption: "aws:kms", KMSKeyID: "alias/recorder", StorageClass: "STANDARD_IA"})
+
+	file := filepath.Join(t.TempDir(), "1.1.bz2")
+	if err := os.WriteFile(file, []byte("market data"), 0644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	tags := map[string]string{"sport": "greyhound-racing", "venue": "Sandown Park", "date": "2025-10-01"}
+	if err := storage.UploadWithTags(context.Background(), file, "PRO/1.1.bz2", tags); err != nil {
+		t.Fatalf("UploadWithTags failed: %v", err)
+	}
+
+	header := <-headers
+	expected := map[string]string{
+		"X-Amz-Server-Side-Encryption":                "aws:kms",
+		"X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id": "alias/recorder",
+		"X-Amz-Storage-Class":                         "STANDARD_IA",
+		"X-Amz-Tagging":                               "date=2025-10-01&sport=greyhound-racing&venue=Sandown+Park",
+	}
+	for name, value := range expected {
+		if got := header.Get(name); got != value {
+			t.Errorf("Expected %s %q, got %q", name, value, got)
+		}
+	}
+}
+
+func TestNormalizeKey(t *testing.T) {
+	tests := []struct {
+		key      string
+		expected string
+	}{
+		{key: "PRO/2025/Oct/1/99/1.1.bz2", expected: "PRO/2025/Oct/1/99/1.1.bz2"},
+		{key: "/PRO//2025/./Oct/1.1.bz2", expected: "PRO/2025/Oct/1.1.bz2"},
+		{key: `raw\PRO\2025\1.1.bz2`, expected: "raw/PRO/2025/1.1.bz2"},
+		{key: "PRO/2025/", expected: "PRO/2025/"},
+		{key: "//", expected: ""},
+		{key: "", expected: ""},
+	}
+	for _, tt := range tests {
+		if got := NormalizeKey(tt.key); got != tt.expected {
+			t.Errorf("NormalizeKey(%q) = %q, expected %q", tt.key, got, tt.expected)
+		}
+	}
+}
+
+func TestBuildObjectKeyIsNormalized(t *testing.T) {
+	eventInfo := NewEventInfo("99", time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC))
+	for _, basePath := range []string{"/raw/data/", `raw\data`, "raw//data"} {
+		if key := buildObjectKey(basePath, eventInfo, "1.1.bz2"); key != "raw/data/PRO/2025/Oct/1/99/1.1.bz2" {
+			t.Errorf("Expected base path %q to give a clean key, got %s", basePath, key)
+		}
+	}
+}
+
+func TestS3StorageVerifiesKMSEncryptedUploads(t *testing.T) {
+	// S3 gives SSE-KMS objects an ETag that is not the MD5 of their content, and rejects a body
+	// that doesn't match its Content-MD5
+	const kmsETag = `"0c2dd1b37a1bd4ab62bd8b5b4a1d6c7e"`
+	objects := make(map[string][]byte)
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.Method {
+		case http.MethodPut:
+			data, _ := io.ReadAll(r.Body)
+			sum := md5.Sum(data)
+			if r.Header.Get("Content-Md5") != base64.StdEncoding.EncodeToString(sum[:]) {
+				http.Error(w, "<Error><Code>BadDigest</Code></Error>", http.StatusBadRequest)
+				return
+			}
+			objects[r.URL.Path] = data
+			w.Header().Set("ETag", kmsETag)
+			w.Header().Set("X-Amz-Server-Side-Encryption", "aws:kms")
+		case http.MethodHead:
+			data, ok := objects[r.URL.Path]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Header().Set("Content-Length", fmt.Sprint(len(data)))
+			w.Header().Set("ETag", kmsETag)
+			w.Header().Set("X-Amz-Server-Side-Encryption", "aws:kms")
+		default:
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	client := s3.New(s3.Options{
+		BaseEndpoint: aws.String(server.URL),
+		UsePathStyle: true,
+		Region:       "us-east-1",
+		Credentials:  credentials.NewStaticCredentialsProvider("key", "secret", ""),
+	})
+	storage := NewS3StorageWithClient(client, "markets", "")
+	storage.SetUploadOptions(S3UploadOptions{ServerSideEncryption: "aws:kms", KMSKeyID: "alias/recorder"})
+
+	tempDir := t.TempDir()
+	file := filepath.Join(tempDir, "1.1.bz2")
+	if err := os.WriteFile(file, []byte("market data"), 0644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	now := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+	if err := uploadVerified(context.Background(), storage, PendingUpload{FilePath: file, Key: "PRO/1.1.bz2"}, tempDir, now); err != nil {
+		t.Fatalf("Expected the KMS upload to verify, got %v", err)
+	}
+	if _, err := os.Stat(VerifiedManifestPath(tempDir, now)); err != nil {
+		t.Errorf("Expected the verified upload in the manifest: %v", err)
+	}
+
+	if err := storage.Verify(context.Background(), "PRO/1.1.bz2", 12, "5d41402abc4b2a76b9719d911017c592"); !errors.Is(err, errVerificationMismatch) {
+		t.Errorf("Expected a size mismatch to still fail verification, got %v", err)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"io"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/rs/zerolog"
@@ -18,12 +19,17 @@ const (
 	BetfairStreamHost    = "stream-api.betfair.com"
 	BetfairStreamPort    = "443"
 	BetfairStreamAddress = BetfairStreamHost + ":" + BetfairStreamPort
+
+	// BetfairIntegrationStreamHost is Betfair's stream for testing against, with delayed data
+	BetfairIntegrationStreamHost = "stream-api-integration.betfair.com"
 )
 
 type StreamConn struct {
-	conn   *tls.Conn
-	reader *bufio.Reader
-	writer *bufio.Writer
+	conn    *tls.Conn
+	reader  *bufio.Reader
+	writer  *bufio.Writer
+	writeMu sync.Mutex
+	tap     func(payload []byte)
 }
 
 func NewStreamConn(conn *tls.Conn) *StreamConn {
@@ -40,13 +46,30 @@ func (s *StreamConn) WriteJSON(v any) error {
 		return err
 	}
 	data = append(data, '\n')
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
 	if _, err := s.writer.Write(data); err != nil {
 		return err
 	}
 	return s.writer.Flush()
 }
 
+// SetTap registers fn to see every message read from the stream, including those consumed
+// during authentication and subscription
+func (s *StreamConn) SetTap(fn func(payload []byte)) {
+	s.tap = fn
+}
+
 func (s *StreamConn) ReadMessage() ([]byte, error) {
+	payload, err := s.readMessage()
+	if err == nil && s.tap != nil {
+		s.tap(payload)
+	}
+	return payload, err
+}
+
+func (s *StreamConn) readMessage() ([]byte, error) {
 	for {
 		line, err := s.reader.ReadBytes('\n')
 		if err != nil {
@@ -77,30 +100,53 @@ func (s *StreamConn) SetReadDeadline(t time.Time) error {
 
 type StreamClient struct {
 	appKey       string
-	sessionToken string
+	tokens       *TokenStore
 	heartbeatMs  int
 	logger       zerolog.Logger
 	authenticator *Authenticator
+	streamHost   string
 }
 
 func NewStreamClient(appKey, sessionToken string, heartbeatMs int, logger zerolog.Logger, auth *Authenticator) *StreamClient {
 	return &StreamClient{
 		appKey:       appKey,
-		sessionToken: sessionToken,
+		tokens:       NewTokenStore(sessionToken),
 		heartbeatMs:  heartbeatMs,
 		logger:       logger,
 		authenticator: auth,
+		streamHost:   BetfairStreamHost,
 	}
 }
 
+// UpdateSessionToken sets the session token used for subsequent authentications
+func (sc *StreamClient) UpdateSessionToken(sessionToken string) {
+	sc.tokens.Set(sessionToken)
+}
+
+// SessionToken returns the session token used for authentication
+func (sc *StreamClient) SessionToken() string {
+	return sc.tokens.Get()
+}
+
+// UseTokenStore shares a token store with the client so it always authenticates with the latest session token
+func (sc *StreamClient) UseTokenStore(store *TokenStore) {
+	sc.tokens = store
+}
+
+// SetEndpoints switches the stream connection to a jurisdiction's stream host
+func (sc *StreamClient) SetEndpoints(endpoints Endpoints) {
+	sc.streamHost = endpoints.StreamHost
+}
+
 func (sc *StreamClient) Dial() (*StreamConn, error) {
 	tlsConf := &tls.Config{
-		ServerName: BetfairStreamHost,
+		ServerName: sc.streamHost,
 		MinVersion: tls.VersionTLS12,
 	}
 
-	sc.logger.Debug().Str("address", BetfairStreamAddress).Msg("connecting to Betfair stream")
-	conn, err := tls.Dial("tcp", BetfairStreamAddress, tlsConf)
+	address := sc.streamHost + ":" + BetfairStreamPort
+	sc.logger.Debug().Str("address", address).Msg("connecting to Betfair stream")
+	conn, err := tls.Dial("tcp", address, tlsConf)
 	if err != nil {
 		return nil, fmt.Errorf("dial betfair stream: %w", err)
 	}
@@ -114,7 +160,7 @@ func (sc *StreamClient) Authenticate(stream *StreamConn) error {
 		"op":      "authentication",
 		"id":      1,
 		"appKey":  sc.appKey,
-		"session": sc.sessionToken,
+		"session": sc.tokens.Get(),
 	}
 
 	sc.logger.Debug().Msg("sending authentication request")
@@ -155,7 +201,7 @@ func (sc *StreamClient) Authenticate(stream *StreamConn) error {
 				if refreshErr != nil {
 					return fmt.Errorf("failed to refresh session token: %w", refreshErr)
 				}
-				sc.sessionToken = newToken
+				sc.tokens.Set(newToken)
 				return fmt.Errorf("session refreshed, retry connection: %w", err)
 			}
 			return err
@@ -181,6 +227,59 @@ func (sc *StreamClient) RequestHeartbeat(stream *StreamConn) error {
 // MarketFilter is defined in rest_api.go to avoid duplication
 
 func (sc *StreamClient) Subscribe(stream *StreamConn, filter MarketFilter, initialClk, clk string) error {
+	subscription := sc.buildSubscription(filter)
+
+	if initialClk != "" {
+		subscription["initialClk"] = initialClk
+		sc.logger.Info().Str("initialClk", initialClk).Msg("using stored initialClk for fast recovery")
+	}
+	if clk != "" {
+		subscription["clk"] = clk
+		sc.logger.Info().Str("clk", clk).Msg("using stored clk for fast recovery")
+	}
+
+	if err := stream.WriteJSON(subscription); err != nil {
+		return fmt.Errorf("send subscription: %w", err)
+	}
+
+	return sc.waitForSubscriptionAck(stream)
+}
+
+// Resubscribe replaces the subscription on an active stream without waiting for the ack, which
+// arrives on the stream's read loop as a status message. The new subscription starts from a fresh image.
+func (sc *StreamClient) Resubscribe(stream *StreamConn, filter MarketFilter) error {
+	return sc.ResubscribeFrom(stream, filter, "", "")
+}
+
+// ResubscribeFrom replaces the subscription like Resubscribe but passes the given clocks, so markets
+// that stay subscribed continue with deltas instead of a fresh image
+func (sc *StreamClient) ResubscribeFrom(stream *StreamConn, filter MarketFilter, initialClk, clk string) error {
+	subscription := sc.buildSubscription(filter)
+	if initialClk != "" {
+		subscription["initialClk"] = initialClk
+	}
+	if clk != "" {
+		subscription["clk"] = clk
+	}
+	if err := stream.WriteJSON(subscription); err != nil {
+		return fmt.Errorf("send resubscription: %w", err)
+	}
+	sc.logger.Info().Strs("market_ids", filter.MarketIds).Msg("sent market resubscription")
+	return nil
+}
+
+// Unsubscribe replaces the subscription on an active stream with one naming no markets. The stream
+// API has no unsubscribe operation.
+func (sc *StreamClient) Unsubscribe(stream *StreamConn) error {
+	subscription := sc.buildSubscription(MarketFilter{})
+	if err := stream.WriteJSON(subscription); err != nil {
+		return fmt.Errorf("send unsubscription: %w", err)
+	}
+	sc.logger.Info().Msg("cleared market subscription")
+	return nil
+}
+
+func (sc *StreamClient) buildSubscription(filter MarketFilter) map[string]any {
 	marketFilter := map[string]any{}
 
 	if len(filter.MarketIds) > 0 {
@@ -195,6 +294,10 @@ func (sc *StreamClient) Subscribe(stream *StreamConn, filter MarketFilter, initi
 	if len(filter.MarketTypeCodes) > 0 {
 		marketFilter["marketTypes"] = filter.MarketTypeCodes
 	}
+	// An empty filter would subscribe to every market, so it names none instead
+	if len(marketFilter) == 0 {
+		marketFilter["marketIds"] = []string{}
+	}
 
 	subscription := map[string]any{
 		"op":           "marketSubscription",
@@ -213,20 +316,7 @@ func (sc *StreamClient) Subscribe(stream *StreamConn, filter MarketFilter, initi
 		},
 	}
 
-	if initialClk != "" {
-		subscription["initialClk"] = initialClk
-		sc.logger.Info().Str("initialClk", initialClk).Msg("using stored initialClk for fast recovery")
-	}
-	if clk != "" {
-		subscription["clk"] = clk
-		sc.logger.Info().Str("clk", clk).Msg("using stored clk for fast recovery")
-	}
-
-	if err := stream.WriteJSON(subscription); err != nil {
-		return fmt.Errorf("send subscription: %w", err)
-	}
-
-	return sc.waitForSubscriptionAck(stream)
+	return subscription
 }
 
 func (sc *StreamClient) waitForSubscriptionAck(stream *StreamConn) error {
@@ -0,0 +1,81 @@
+package betfair
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// TimelineSuffix names the status timeline sidecar written next to a market's archive
+const TimelineSuffix = ".timeline.json"
+
+// StatusChange is a point in a market's life where its status or in-play flag changed
+type StatusChange struct {
+	Time    time.Time `json:"time"`
+	Status  string    `json:"status"`
+	InPlay  bool      `json:"inPlay"`
+	Version int64     `json:"version,omitempty"`
+}
+
+// MarketTimeline records a market's status transitions for data-quality auditing
+type MarketTimeline struct {
+	MarketID        string         `json:"marketId"`
+	Changes         []StatusChange `json:"changes"`
+	InPlayAt        *time.Time     `json:"inPlayAt,omitempty"`
+	BspReconciledAt *time.Time     `json:"bspReconciledAt,omitempty"`
+	SettledAt       *time.Time     `json:"settledAt,omitempty"`
+	Incomplete      bool           `json:"incomplete,omitempty"`
+}
+
+func NewMarketTimeline(marketID string) *MarketTimeline {
+	return &MarketTimeline{MarketID: marketID}
+}
+
+// Observe adds a change to the timeline when the definition's status or in-play flag differs
+// from the last recorded one
+func (t *MarketTimeline) Observe(at time.Time, definition *MarketDefinition) {
+	if definition.Status == "" {
+		return
+	}
+
+	if n := len(t.Changes); n == 0 || t.Changes[n-1].Status != definition.Status || t.Changes[n-1].InPlay != definition.InPlay {
+		t.Changes = append(t.Changes, StatusChange{
+			Time:    at,
+			Status:  definition.Status,
+			InPlay:  definition.InPlay,
+			Version: definition.Version,
+		})
+	}
+
+	if definition.InPlay && t.InPlayAt == nil {
+		t.InPlayAt = timePtr(at)
+	}
+	if definition.BspReconciled && t.BspReconciledAt == nil {
+		t.BspReconciledAt = timePtr(at)
+	}
+	if IsMarketSettled(definition.Status) && t.SettledAt == nil {
+		t.SettledAt = timePtr(at)
+	}
+}
+
+// WriteFile stores the timeline as indented JSON
+func (t *MarketTimeline) WriteFile(path string) error {
+	data, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode timeline: %w", err)
+	}
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("write timeline: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("rename timeline: %w", err)
+	}
+	return nil
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
@@ -0,0 +1,92 @@
+package betfair
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func TestMarketTimelineObserve(t *testing.T) {
+	start := time.Date(2025, 10, 1, 10, 0, 0, 0, time.UTC)
+	timeline := NewMarketTimeline("1.1")
+
+	steps := []MarketDefinition{
+		{Status: "OPEN"},
+		{Status: "OPEN"},
+		{Status: "SUSPENDED"},
+		{Status: "OPEN", InPlay: true},
+		{Status: "SUSPENDED", InPlay: true},
+		{Status: "SUSPENDED", InPlay: true, BspReconciled: true},
+		{Status: "CLOSED", InPlay: true, BspReconciled: true},
+	}
+	for i := range steps {
+		timeline.Observe(start.Add(time.Duration(i)*time.Minute), &steps[i])
+	}
+
+	var statuses []string
+	for _, change := range timeline.Changes {
+		statuses = append(statuses, change.Status)
+	}
+	if got := strings.Join(statuses, ","); got != "OPEN,SUSPENDED,OPEN,SUSPENDED,CLOSED" {
+		t.Errorf("Unexpected timeline %s", got)
+	}
+	if !timeline.InPlayAt.Equal(start.Add(3 * time.Minute)) {
+		t.Errorf("Unexpected in-play time %v", timeline.InPlayAt)
+	}
+	if !timeline.BspReconciledAt.Equal(start.Add(5 * time.Minute)) {
+		t.Errorf("Unexpected BSP reconciliation time %v", timeline.BspReconciledAt)
+	}
+	if !timeline.SettledAt.Equal(start.Add(6 * time.Minute)) {
+		t.Errorf("Unexpected settled time %v", timeline.SettledAt)
+	}
+}
+
+func TestMarketRecorderWritesTimelineSidecar(t *testing.T) {
+	tempDir := t.TempDir()
+	recorder := &MarketRecorder{
+		config:           &Config{EnrichmentMode: EnrichmentOff, RecordTimeline: true},
+		logger:           zerolog.New(zerolog.NewTestWriter(t)),
+		fileManager:      NewFileManager(tempDir),
+		marketCatalogues: make(map[string]*MarketCatalogue),
+	}
+
+	messages := strings.Join([]string{
+		`{"op":"mcm","pt":1759312800000,"mc":[{"id":"1.1","marketDefinition":{"eventId":"1","openDate":"2025-10-01T10:00:00Z","status":"OPEN"}}]}`,
+		`{"op":"mcm","pt":1759312860000,"mc":[{"id":"1.1","marketDefinition":{"eventId":"1","openDate":"2025-10-01T10:00:00Z","status":"OPEN","inPlay":true}}]}`,
+		`{"op":"mcm","pt":1759312920000,"mc":[{"id":"1.1","marketDefinition":{"eventId":"1","openDate":"2025-10-01T10:00:00Z","status":"CLOSED","inPlay":true}}]}`,
+	}, "\n") + "\n"
+	stream := &StreamConn{reader: bufio.NewReader(strings.NewReader(messages))}
+
+	writers := make(map[string]*bufio.Writer)
+	files := make(map[string]*os.File)
+	statuses := make(map[string]string)
+	for i := 0; i < 3; i++ {
+		if err := recorder.readMessage(context.Background(), stream, writers, files, statuses); err != nil {
+			t.Fatalf("readMessage failed: %v", err)
+		}
+	}
+	recorder.stopMarketWriters()
+
+	data, err := os.ReadFile(filepath.Join(tempDir, "1.1"+TimelineSuffix))
+	if err != nil {
+		t.Fatalf("Expected timeline sidecar: %v", err)
+	}
+
+	var timeline MarketTimeline
+	if err := json.Unmarshal(data, &timeline); err != nil {
+		t.Fatalf("Failed to decode timeline: %v", err)
+	}
+	if len(timeline.Changes) != 3 || timeline.SettledAt == nil || timeline.InPlayAt == nil {
+		t.Errorf("Unexpected timeline: %s", data)
+	}
+	if _, tracked := recorder.timelines["1.1"]; tracked {
+		t.Error("Expected timeline to be released after settlement")
+	}
+}
@@ -0,0 +1,45 @@
+package betfair
+
+import "sync"
+
+// TokenStore holds the current session token so every client sharing it always uses the latest value.
+// Subscribers are notified each time the token changes.
+type TokenStore struct {
+	mu          sync.RWMutex
+	token       string
+	subscribers []func(token string)
+}
+
+func NewTokenStore(token string) *TokenStore {
+	return &TokenStore{token: token}
+}
+
+// Get returns the current session token
+func (s *TokenStore) Get() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.token
+}
+
+// Set replaces the session token and notifies subscribers if it changed
+func (s *TokenStore) Set(token string) {
+	s.mu.Lock()
+	if token == s.token {
+		s.mu.Unlock()
+		return
+	}
+	s.token = token
+	subscribers := append([]func(string){}, s.subscribers...)
+	s.mu.Unlock()
+
+	for _, fn := range subscribers {
+		fn(token)
+	}
+}
+
+// Subscribe registers fn to be called with the new token after every change
+func (s *TokenStore) Subscribe(fn func(token string)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.subscribers = append(s.subscribers, fn)
+}
@@ -0,0 +1,69 @@
+package betfair
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestTokenStoreNotifiesOnChange(t *testing.T) {
+	store := NewTokenStore("initial")
+
+	var received []string
+	store.Subscribe(func(token string) { received = append(received, token) })
+
+	store.Set("initial")
+	store.Set("refreshed")
+	store.Set("refreshed")
+
+	if store.Get() != "refreshed" {
+		t.Errorf("Expected token 'refreshed', got %s", store.Get())
+	}
+	if len(received) != 1 || received[0] != "refreshed" {
+		t.Errorf("Expected a single notification for the changed token, got %v", received)
+	}
+}
+
+func TestTokenStoreSharedAcrossClients(t *testing.T) {
+	store := NewTokenStore("initial")
+
+	restClient := NewRESTClient("app-key", "", "en")
+	restClient.UseTokenStore(store)
+	streamClient := NewStreamClient("app-key", "", 5000, zerolog.Nop(), nil)
+	streamClient.UseTokenStore(store)
+
+	streamClient.UpdateSessionToken("from-stream")
+
+	if restClient.tokens.Get() != "from-stream" {
+		t.Errorf("Expected REST client to see token set via stream client, got %s", restClient.tokens.Get())
+	}
+
+	restClient.UpdateSessionKey("from-rest")
+	if streamClient.SessionToken() != "from-rest" {
+		t.Errorf("Expected stream client to see token set via REST client, got %s", streamClient.SessionToken())
+	}
+}
+
+func TestTokenStoreConcurrentAccess(t *testing.T) {
+	store := NewTokenStore("")
+	store.Subscribe(func(string) {})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			store.Set(string(rune('a' + i)))
+		}(i)
+		go func() {
+			defer wg.Done()
+			_ = store.Get()
+		}()
+	}
+	wg.Wait()
+
+	if store.Get() == "" {
+		t.Error("Expected a token to be set")
+	}
+}
@@ -0,0 +1,203 @@
+package betfair
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	UploadManifestName = "pending_uploads.json"
+
+	uploadRetryBase     = 5 * time.Second
+	uploadRetryMax      = 10 * time.Minute
+	uploadQueueInterval = time.Second
+)
+
+type uploader interface {
+	Upload(ctx context.Context, filePath, key string) error
+}
+
+// PendingUpload is a file waiting to be uploaded. Cleanup lists local files removed once the
+// upload succeeds, usually the uncompressed recording alongside the archive.
+type PendingUpload struct {
+	FilePath    string            `json:"filePath"`
+	Key         string            `json:"key"`
+	Cleanup     []string          `json:"cleanup,omitempty"`
+	Tags        map[string]string `json:"tags,omitempty"`
+	Attempts    int               `json:"attempts"`
+	NextAttempt time.Time         `json:"nextAttempt"`
+	LastError   string            `json:"lastError,omitempty"`
+}
+
+// UploadQueue retries failed uploads with exponential backoff. The queue is persisted to a small
+// JSON manifest after every change so uploads still pending at shutdown are retried on the next start.
+type UploadQueue struct {
+	manifestPath string
+	storage      uploader
+	logger       zerolog.Logger
+	now          func() time.Time
+
+	mu      sync.Mutex
+	pending []PendingUpload
+}
+
+func NewUploadQueue(manifestPath string, storage uploader, logger zerolog.Logger) *UploadQueue {
+	return &UploadQueue{
+		manifestPath: manifestPath,
+		storage:      storage,
+		logger:       logger,
+		now:          time.Now,
+	}
+}
+
+// Load reads uploads left pending by a previous run and makes them due immediately
+func (q *UploadQueue) Load() error {
+	data, err := os.ReadFile(q.manifestPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("read upload manifest: %w", err)
+	}
+
+	var pending []PendingUpload
+	if err := json.Unmarshal(data, &pending); err != nil {
+		return fmt.Errorf("decode upload manifest: %w", err)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for i := range pending {
+		pending[i].NextAttempt = time.Time{}
+	}
+	q.pending = pending
+	return nil
+}
+
+// Enqueue adds a failed upload to the queue and persists it
+func (q *UploadQueue) Enqueue(upload PendingUpload, cause error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	upload.Attempts++
+	if cause != nil {
+		upload.LastError = cause.Error()
+	}
+	upload.NextAttempt = q.now().Add(uploadBackoff(upload.Attempts))
+	q.pending = append(q.pending, upload)
+	q.saveLocked()
+}
+
+// Pending returns a copy of the uploads waiting to be retried
+func (q *UploadQueue) Pending() []PendingUpload {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]PendingUpload(nil), q.pending...)
+}
+
+// Run retries due uploads until ctx is cancelled, starting with a sweep of anything already queued
+func (q *UploadQueue) Run(ctx context.Context) {
+	q.RetryDue(ctx)
+
+	ticker := time.NewTicker(uploadQueueInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			q.RetryDue(ctx)
+		}
+	}
+}
+
+// RetryDue attempts every upload whose backoff has elapsed
+func (q *UploadQueue) RetryDue(ctx context.Context) {
+	q.mu.Lock()
+	now := q.now()
+	var due, waiting []PendingUpload
+	for _, upload := range q.pending {
+		if upload.NextAttempt.After(now) {
+			waiting = append(waiting, upload)
+		} else {
+			due = append(due, upload)
+		}
+	}
+	q.pending = waiting
+	q.mu.Unlock()
+
+	if len(due) == 0 {
+		return
+	}
+
+	var failed []PendingUpload
+	for _, upload := range due {
+		if ctx.Err() != nil {
+			failed = append(failed, upload)
+			continue
+		}
+		if err := uploadVerified(ctx, q.storage, upload, filepath.Dir(q.manifestPath), q.now()); err != nil {
+			upload.Attempts++
+			upload.LastError = err.Error()
+			upload.NextAttempt = q.now().Add(uploadBackoff(upload.Attempts))
+			q.logger.Warn().Err(err).Str("file", upload.FilePath).Str("s3_key", upload.Key).Int("attempts", upload.Attempts).Time("next_attempt", upload.NextAttempt).Msg("upload retry failed")
+			failed = append(failed, upload)
+			continue
+		}
+
+		q.logger.Info().Str("file", upload.FilePath).Str("s3_key", upload.Key).Int("attempts", upload.Attempts+1).Msg("uploaded queued file")
+		for _, path := range append(upload.Cleanup, upload.FilePath) {
+			if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				q.logger.Warn().Err(err).Str("file", path).Msg("failed to remove uploaded file")
+			}
+		}
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.pending = append(q.pending, failed...)
+	q.saveLocked()
+}
+
+// saveLocked must be called with q.mu held; an empty queue removes the manifest
+func (q *UploadQueue) saveLocked() {
+	if len(q.pending) == 0 {
+		if err := os.Remove(q.manifestPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			q.logger.Error().Err(err).Msg("failed to remove upload manifest")
+		}
+		return
+	}
+
+	data, err := json.Marshal(q.pending)
+	if err == nil {
+		if err = os.MkdirAll(filepath.Dir(q.manifestPath), 0755); err == nil {
+			tmpPath := q.manifestPath + ".tmp"
+			if err = os.WriteFile(tmpPath, data, 0644); err == nil {
+				err = os.Rename(tmpPath, q.manifestPath)
+			}
+		}
+	}
+	if err != nil {
+		q.logger.Error().Err(err).Msg("failed to save upload manifest")
+	}
+}
+
+func uploadBackoff(attempts int) time.Duration {
+	backoff := uploadRetryBase
+	for i := 1; i < attempts && backoff < uploadRetryMax; i++ {
+		backoff *= 2
+	}
+	if backoff > uploadRetryMax {
+		backoff = uploadRetryMax
+	}
+	return backoff
+}
@@ -0,0 +1,93 @@
+package betfair
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+type fakeUploader struct {
+	fail    bool
+	uploads []string
+}
+
+func (u *fakeUploader) Upload(ctx context.Context, filePath, key string) error {
+	if u.fail {
+		return errors.New("connection reset")
+	}
+	u.uploads = append(u.uploads, key)
+	return nil
+}
+
+func TestUploadQueueRetriesAndPersists(t *testing.T) {
+	tempDir := t.TempDir()
+	manifest := filepath.Join(tempDir, UploadManifestName)
+	archive := filepath.Join(tempDir, "1.1.bz2")
+	recording := filepath.Join(tempDir, "1.1")
+	for _, path := range []string{archive, recording} {
+		if err := os.WriteFile(path, []byte("data"), 0644); err != nil {
+			t.Fatalf("write %s: %v", path, err)
+		}
+	}
+
+	now := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+	storage := &fakeUploader{fail: true}
+	queue := NewUploadQueue(manifest, storage, zerolog.Nop())
+	queue.now = func() time.Time { return now }
+
+	queue.Enqueue(PendingUpload{FilePath: archive, Key: "PRO/1.1.bz2", Cleanup: []string{recording}}, errors.New("timeout"))
+	if _, err := os.Stat(manifest); err != nil {
+		t.Fatalf("Expected manifest to be written: %v", err)
+	}
+
+	// Not due yet
+	queue.RetryDue(context.Background())
+	if pending := queue.Pending(); len(pending) != 1 || pending[0].Attempts != 1 {
+		t.Fatalf("Expected upload to wait for its backoff, got %+v", pending)
+	}
+
+	now = now.Add(uploadBackoff(1))
+	queue.RetryDue(context.Background())
+	pending := queue.Pending()
+	if len(pending) != 1 || pending[0].Attempts != 2 || pending[0].LastError != "connection reset" {
+		t.Fatalf("Expected failed retry to be requeued, got %+v", pending)
+	}
+	if !pending[0].NextAttempt.Equal(now.Add(2 * uploadRetryBase)) {
+		t.Errorf("Expected backoff to double, next attempt %v", pending[0].NextAttempt)
+	}
+
+	// A restarted recorder picks the upload up from the manifest and retries it straight away
+	storage.fail = false
+	restarted := NewUploadQueue(manifest, storage, zerolog.Nop())
+	restarted.now = func() time.Time { return now }
+	if err := restarted.Load(); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	restarted.RetryDue(context.Background())
+
+	if len(storage.uploads) != 1 || storage.uploads[0] != "PRO/1.1.bz2" {
+		t.Errorf("Expected queued upload to succeed, got %v", storage.uploads)
+	}
+	if len(restarted.Pending()) != 0 {
+		t.Error("Expected queue to be empty")
+	}
+	for _, path := range []string{archive, recording, manifest} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be removed", path)
+		}
+	}
+}
+
+func TestUploadBackoffIsCapped(t *testing.T) {
+	if got := uploadBackoff(1); got != uploadRetryBase {
+		t.Errorf("Expected first retry after %v, got %v", uploadRetryBase, got)
+	}
+	if got := uploadBackoff(50); got != uploadRetryMax {
+		t.Errorf("Expected backoff to be capped at %v, got %v", uploadRetryMax, got)
+	}
+}
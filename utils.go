@@ -2,6 +2,7 @@ package betfair
 
 import (
 	"fmt"
+	"math"
 	"regexp"
 	"strings"
 	"time"
@@ -59,6 +60,14 @@ func ValidateMarketID(marketID string) bool {
 	return matched
 }
 
+// MinimumStake is the smallest stake accepted without a bet target (AUD)
+const MinimumStake = 5.0
+
+// RoundStake rounds a stake to the two decimal places Betfair accepts
+func RoundStake(size float64) float64 {
+	return math.Round(size*100) / 100
+}
+
 // CalculateBackProfit calculates potential profit for a back bet
 func CalculateBackProfit(stake, odds float64) float64 {
 	return stake * (odds - 1)
@@ -0,0 +1,110 @@
+package betfair
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const VerifiedManifestPrefix = "verified_"
+
+var errVerificationMismatch = errors.New("uploaded object does not match local file")
+
+// objectVerifier is implemented by storage backends that can confirm an uploaded object
+// matches the local file it came from
+type objectVerifier interface {
+	Verify(ctx context.Context, key string, size int64, md5Hex string) error
+}
+
+// taggedUploader is implemented by storage backends that can tag an object as it is uploaded
+type taggedUploader interface {
+	UploadWithTags(ctx context.Context, filePath, key string, tags map[string]string) error
+}
+
+// VerifiedUpload is an entry in the daily manifest of uploads confirmed to match their local file
+type VerifiedUpload struct {
+	Key        string    `json:"key"`
+	Size       int64     `json:"size"`
+	MD5        string    `json:"md5"`
+	VerifiedAt time.Time `json:"verifiedAt"`
+}
+
+// VerifiedManifestPath returns the manifest file for uploads verified on the given UTC day
+func VerifiedManifestPath(dir string, day time.Time) string {
+	return filepath.Join(dir, VerifiedManifestPrefix+day.UTC().Format("2006-01-02")+".jsonl")
+}
+
+// AppendVerifiedUpload records a verified upload in the manifest for the day it was verified
+func AppendVerifiedUpload(dir string, entry VerifiedUpload) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("encode verified upload: %w", err)
+	}
+
+	file, err := os.OpenFile(VerifiedManifestPath(dir, entry.VerifiedAt), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("open verified manifest: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("write verified manifest: %w", err)
+	}
+	return nil
+}
+
+// fileChecksum returns the size and hex MD5 of a file
+func fileChecksum(path string) (int64, string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, "", fmt.Errorf("open file: %w", err)
+	}
+	defer file.Close()
+
+	hash := md5.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return 0, "", fmt.Errorf("hash file: %w", err)
+	}
+	return size, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// uploadVerified uploads a file and, when the storage supports it, checks the stored object
+// against the local checksum and records it in the daily manifest. Local files are left in
+// place; callers remove them only once this returns nil.
+func uploadVerified(ctx context.Context, storage uploader, upload PendingUpload, manifestDir string, now time.Time) error {
+	size, sum, err := fileChecksum(upload.FilePath)
+	if err != nil {
+		return err
+	}
+
+	if tagged, ok := storage.(taggedUploader); ok && len(upload.Tags) > 0 {
+		err = tagged.UploadWithTags(ctx, upload.FilePath, upload.Key, upload.Tags)
+	} else {
+		err = storage.Upload(ctx, upload.FilePath, upload.Key)
+	}
+	if err != nil {
+		return err
+	}
+
+	verifier, ok := storage.(objectVerifier)
+	if !ok {
+		return nil
+	}
+	if err := verifier.Verify(ctx, upload.Key, size, sum); err != nil {
+		return fmt.Errorf("verify %s: %w", upload.Key, err)
+	}
+
+	entry := VerifiedUpload{Key: upload.Key, Size: size, MD5: sum, VerifiedAt: now}
+	if err := AppendVerifiedUpload(manifestDir, entry); err != nil {
+		return err
+	}
+	return nil
+}
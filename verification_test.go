@@ -0,0 +1,109 @@
+package betfair
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type verifyingUploader struct {
+	fakeUploader
+	remoteSize int64
+	remoteMD5  string
+}
+
+func (u *verifyingUploader) Verify(ctx context.Context, key string, size int64, md5Hex string) error {
+	if u.remoteSize != size || u.remoteMD5 != md5Hex {
+		return errVerificationMismatch
+	}
+	return nil
+}
+
+func TestUploadVerifiedRecordsManifest(t *testing.T) {
+	tempDir := t.TempDir()
+	archive := filepath.Join(tempDir, "1.1.bz2")
+	if err := os.WriteFile(archive, []byte("hello"), 0644); err != nil {
+		t.Fatalf("write archive: %v", err)
+	}
+
+	size, sum, err := fileChecksum(archive)
+	if err != nil {
+		t.Fatalf("fileChecksum failed: %v", err)
+	}
+	if size != 5 || sum != "5d41402abc4b2a76b9719d911017c592" {
+		t.Fatalf("Unexpected checksum %d %s", size, sum)
+	}
+
+	now := time.Date(2025, 10, 1, 23, 30, 0, 0, time.UTC)
+	storage := &verifyingUploader{remoteSize: 4, remoteMD5: sum}
+	err = uploadVerified(context.Background(), storage, PendingUpload{FilePath: archive, Key: "PRO/1.1.bz2"}, tempDir, now)
+	if !errors.Is(err, errVerificationMismatch) {
+		t.Fatalf("Expected size mismatch to fail verification, got %v", err)
+	}
+	if _, err := os.Stat(VerifiedManifestPath(tempDir, now)); !os.IsNotExist(err) {
+		t.Error("Expected no manifest entry for an unverified upload")
+	}
+
+	storage.remoteSize = 5
+	if err := uploadVerified(context.Background(), storage, PendingUpload{FilePath: archive, Key: "PRO/1.1.bz2"}, tempDir, now); err != nil {
+		t.Fatalf("uploadVerified failed: %v", err)
+	}
+
+	manifest, err := os.Open(filepath.Join(tempDir, "verified_2025-10-01.jsonl"))
+	if err != nil {
+		t.Fatalf("Expected daily manifest: %v", err)
+	}
+	defer manifest.Close()
+
+	scanner := bufio.NewScanner(manifest)
+	if !scanner.Scan() {
+		t.Fatal("Expected a manifest entry")
+	}
+	var entry VerifiedUpload
+	if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+		t.Fatalf("decode manifest entry: %v", err)
+	}
+	if entry.Key != "PRO/1.1.bz2" || entry.Size != 5 || entry.MD5 != sum || !entry.VerifiedAt.Equal(now) {
+		t.Errorf("Unexpected manifest entry %+v", entry)
+	}
+}
+
+type taggingUploader struct {
+	fakeUploader
+	tags map[string]map[string]string
+}
+
+func (u *taggingUploader) UploadWithTags(ctx context.Context, filePath, key string, tags map[string]string) error {
+	u.tags[key] = tags
+	return u.Upload(ctx, filePath, key)
+}
+
+func TestUploadVerifiedTagsObjects(t *testing.T) {
+	tempDir := t.TempDir()
+	archive := filepath.Join(tempDir, "1.1.bz2")
+	if err := os.WriteFile(archive, []byte("hello"), 0644); err != nil {
+		t.Fatalf("write archive: %v", err)
+	}
+
+	storage := &taggingUploader{tags: make(map[string]map[string]string)}
+	now := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+	tags := map[string]string{"sport": "greyhound-racing", "venue": "Sandown Park", "date": "2025-10-01"}
+	if err := uploadVerified(context.Background(), storage, PendingUpload{FilePath: archive, Key: "PRO/1.1.bz2", Tags: tags}, tempDir, now); err != nil {
+		t.Fatalf("uploadVerified failed: %v", err)
+	}
+	if err := uploadVerified(context.Background(), storage, PendingUpload{FilePath: archive, Key: "PRO/1.2.bz2"}, tempDir, now); err != nil {
+		t.Fatalf("uploadVerified failed: %v", err)
+	}
+
+	if got := storage.tags["PRO/1.1.bz2"]; got["venue"] != "Sandown Park" || len(got) != 3 {
+		t.Errorf("Expected tags to reach the backend, got %v", got)
+	}
+	if _, tagged := storage.tags["PRO/1.2.bz2"]; tagged || len(storage.uploads) != 2 {
+		t.Errorf("Expected an untagged upload to use Upload, got tags %v and uploads %v", storage.tags, storage.uploads)
+	}
+}